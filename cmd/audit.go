@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/user"
+)
+
+// loadAuditActor attributes the audit entries of this process to the
+// whoami profile, OS user and host.
+func loadAuditActor() {
+	registry.SetDefaultActor(registry.LocalActor(user.Label()))
+}
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Show and verify the audit log of changes and runs",
+	Long: `Every change to a command set (create, update, rollback, tags, delete,
+imports, version deletions, history redaction, schedules, webhooks, watches,
+breakpoints) and every run is appended to an audit log with the whoami
+profile, OS user and host that made it. Entries cannot be updated or
+deleted through SQLite, and each is hash-chained to the one before, so
+'krnr audit verify' detects entries edited or removed by other means.`,
+}
+
+var auditListCmd = &cobra.Command{
+	Use:   "list [name]",
+	Short: "List audit entries, newest first",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		limit, _ := cmd.Flags().GetInt("limit")
+		output, _ := cmd.Flags().GetString("output")
+		if output != "text" && output != "json" {
+			return fmt.Errorf("invalid --output %q (expected text or json)", output)
+		}
+		set := ""
+		if len(args) == 1 {
+			set = args[0]
+		}
+
+		dbConn, err := db.InitDB()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = dbConn.Close() }()
+		r := registry.NewRepository(dbConn)
+		entries, err := r.ListAudit(set, limit)
+		if err != nil {
+			return err
+		}
+		if output == "json" {
+			if entries == nil {
+				entries = []registry.AuditEntry{}
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(entries)
+		}
+		if len(entries) == 0 {
+			fmt.Println("no audit entries")
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "ID\tTIME (UTC)\tACTION\tSET\tBY\tDETAIL")
+		for _, e := range entries {
+			name := e.Set
+			if name == "" {
+				name = "-"
+			}
+			_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", e.ID, e.At, e.Action, name, e.Actor, e.Detail)
+		}
+		return tw.Flush()
+	},
+}
+
+var auditVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check that no audit entry was edited or deleted",
+	Long: `Recompute the hash chain of the audit log and report entries that were
+edited, deleted or inserted. Exits non-zero when the log was tampered with.
+
+The printed head hash identifies the whole log: keep a copy elsewhere to
+detect a log rewritten from scratch later.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		output, _ := cmd.Flags().GetString("output")
+		if output != "text" && output != "json" {
+			return fmt.Errorf("invalid --output %q (expected text or json)", output)
+		}
+		dbConn, err := db.InitDB()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = dbConn.Close() }()
+		r := registry.NewRepository(dbConn)
+		rep, err := r.VerifyAudit()
+		if err != nil {
+			return err
+		}
+		if output == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(rep); err != nil {
+				return err
+			}
+		} else {
+			for _, p := range rep.Problems {
+				fmt.Printf("entry %d %s\n", p.ID, p.Problem)
+			}
+			if len(rep.Problems) == 0 {
+				fmt.Printf("audit log intact: %d entries, head %s\n", rep.Entries, rep.Head)
+			}
+		}
+		if len(rep.Problems) > 0 {
+			return fmt.Errorf("audit log was tampered with: %d problem(s) in %d entries", len(rep.Problems), rep.Entries)
+		}
+		return nil
+	},
+}
+
+func init() {
+	auditListCmd.Flags().Int("limit", 50, "Show at most this many entries (0 for all)")
+	auditListCmd.Flags().String("output", "text", "Output format: text or json")
+	auditVerifyCmd.Flags().String("output", "text", "Output format: text or json")
+	auditCmd.AddCommand(auditListCmd)
+	auditCmd.AddCommand(auditVerifyCmd)
+	rootCmd.AddCommand(auditCmd)
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/user"
+)
+
+func TestAuditListAndVerify(t *testing.T) {
+	_ = setupTempDB(t)
+	if err := user.SetProfile(user.Profile{Name: "Ada", Email: "ada@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		registry.SetDefaultActor(registry.LocalActor(""))
+		_ = auditListCmd.Flags().Set("output", "text")
+		_ = saveCmd.Flags().Lookup("command").Value.(interface{ Replace([]string) error }).Replace(nil)
+	})
+	run := func(args ...string) (string, error) {
+		var err error
+		out, _ := captureOutput(func() {
+			rootCmd.SetArgs(args)
+			err = rootCmd.Execute()
+		})
+		return out, err
+	}
+	if _, err := run("save", "audited", "-c", "echo hi"); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := run("audit", "list", "audited")
+	if err != nil || !strings.Contains(out, "create") || !strings.Contains(out, "Ada <ada@example.com> (") {
+		t.Fatalf("audit list: %q %v", out, err)
+	}
+	out, err = run("audit", "verify")
+	if err != nil || !strings.Contains(out, "audit log intact: 1 entries") {
+		t.Fatalf("audit verify: %q %v", out, err)
+	}
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = dbConn.Close() }()
+	for _, q := range []string{"DROP TRIGGER audit_log_no_update", "UPDATE audit_log SET detail = 'nothing'"} {
+		if _, err := dbConn.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out, err = run("audit", "verify")
+	if err == nil || !strings.Contains(out, "entry 1 was edited") {
+		t.Fatalf("expected verify to fail: %q %v", out, err)
+	}
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/registry"
+)
+
+var breakpointCmd = &cobra.Command{
+	Use:   "breakpoint",
+	Short: "Manage stored breakpoints on command set steps",
+	Long:  "Manage stored breakpoints: add, remove, list. A run pauses before every step that has a breakpoint (except in --ci mode).",
+}
+
+// parseSteps converts step arguments to 1-based step numbers.
+func parseSteps(args []string) ([]int, error) {
+	steps := make([]int, 0, len(args))
+	for _, a := range args {
+		n, err := strconv.Atoi(a)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid step %q (expected a step number starting at 1)", a)
+		}
+		steps = append(steps, n)
+	}
+	return steps, nil
+}
+
+var breakpointAddCmd = &cobra.Command{
+	Use:   "add <set-name> <step>...",
+	Short: "Pause runs before the given steps",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(_ *cobra.Command, args []string) error {
+		name := args[0]
+		steps, err := parseSteps(args[1:])
+		if err != nil {
+			return err
+		}
+
+		dbConn, err := db.InitDB()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = dbConn.Close() }()
+
+		r := registry.NewRepository(dbConn)
+		for _, s := range steps {
+			if err := r.SetBreakpoint(name, s); err != nil {
+				return err
+			}
+			fmt.Printf("added breakpoint at step %d of '%s'\n", s, name)
+		}
+		return nil
+	},
+}
+
+var breakpointRemoveCmd = &cobra.Command{
+	Use:   "remove <set-name> [step]...",
+	Short: "Remove breakpoints (all of them when no step is given)",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		name := args[0]
+		steps, err := parseSteps(args[1:])
+		if err != nil {
+			return err
+		}
+
+		dbConn, err := db.InitDB()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = dbConn.Close() }()
+
+		r := registry.NewRepository(dbConn)
+		if len(steps) == 0 {
+			if err := r.ClearBreakpoint(name, 0); err != nil {
+				return err
+			}
+			fmt.Printf("removed all breakpoints from '%s'\n", name)
+			return nil
+		}
+		for _, s := range steps {
+			if err := r.ClearBreakpoint(name, s); err != nil {
+				return err
+			}
+			fmt.Printf("removed breakpoint at step %d of '%s'\n", s, name)
+		}
+		return nil
+	},
+}
+
+var breakpointListCmd = &cobra.Command{
+	Use:   "list <set-name>",
+	Short: "List breakpoints of a command set",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		name := args[0]
+
+		dbConn, err := db.InitDB()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = dbConn.Close() }()
+
+		r := registry.NewRepository(dbConn)
+		cs, err := r.GetCommandSetByName(name)
+		if err != nil {
+			return err
+		}
+		if cs == nil {
+			return fmt.Errorf("command set not found: %s", name)
+		}
+		steps, err := r.ListBreakpoints(name)
+		if err != nil {
+			return err
+		}
+		for _, s := range steps {
+			fmt.Printf("- step %d: %s\n", s, cs.Commands[s-1].Command)
+		}
+		return nil
+	},
+}
+
+func init() {
+	breakpointCmd.AddCommand(breakpointAddCmd)
+	breakpointCmd.AddCommand(breakpointRemoveCmd)
+	breakpointCmd.AddCommand(breakpointListCmd)
+	rootCmd.AddCommand(breakpointCmd)
+}
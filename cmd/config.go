@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/VoxDroid/krnr/internal/config"
+	interactive "github.com/VoxDroid/krnr/internal/utils"
+)
+
+// settings are the resolved defaults from the config files and environment,
+// loaded before every command. Flags given on the command line win.
+var settings = config.Defaults()
+
+// loadSettings resolves settings. Config commands tolerate an invalid
+// config file so that it can be fixed with them.
+func loadSettings(cmd *cobra.Command) error {
+	s, err := config.LoadSettings()
+	if err != nil {
+		if isConfigCommand(cmd) {
+			settings = config.Defaults()
+			return nil
+		}
+		return fmt.Errorf("config: %w (fix it with 'krnr config edit')", err)
+	}
+	settings = s
+	return nil
+}
+
+// flagOrSetting returns the named duration flag when it was given and the
+// setting otherwise.
+func flagOrSetting(cmd *cobra.Command, flag string, setting time.Duration) time.Duration {
+	if cmd.Flags().Changed(flag) {
+		d, _ := cmd.Flags().GetDuration(flag)
+		return d
+	}
+	return setting
+}
+
+func isConfigCommand(cmd *cobra.Command) bool {
+	return commandIn(cmd, configCmd)
+}
+
+// commandIn reports whether cmd is one of cmds or a subcommand of one.
+func commandIn(cmd *cobra.Command, cmds ...*cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		for _, want := range cmds {
+			if c == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// configFile returns the file `config set` and `config edit` change: the
+// user config, or with --project the nearest project config (a new
+// .krnr.toml in the working directory when there is none).
+func configFile(cmd *cobra.Command) (string, error) {
+	if project, _ := cmd.Flags().GetBool("project"); !project {
+		return config.UserConfigPath()
+	}
+	p, err := config.ProjectConfigPath()
+	if err != nil || p != "" {
+		return p, err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(wd, config.ProjectConfigName+".toml"), nil
+}
+
+// readConfigFile reads path, treating a missing file as empty.
+func readConfigFile(path string) (map[string]string, error) {
+	values, err := config.ReadConfigFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	return values, err
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Show and change krnr settings",
+	Long: `Settings provide defaults for the shell, run timeout, run confirmation,
+TUI theme and editor. They are read from, in increasing precedence:
+
+  built-in defaults
+  the user config     KRNR_HOME/config.toml (or config.yaml)
+  the project config  the nearest .krnr.toml (or .krnr.yaml) in the working
+                      directory or its parents; it may only set timeout,
+                      confirm and theme
+  the environment     KRNR_SHELL, KRNR_TIMEOUT, KRNR_CONFIRM, KRNR_THEME, KRNR_EDITOR
+
+Command-line flags such as 'krnr run --timeout' override them all.`,
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List every setting with its value and source",
+	Args:  cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		s, err := config.LoadSettings()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "KEY\tVALUE\tSOURCE\tDESCRIPTION")
+		for _, k := range config.Keys {
+			v, _ := s.Get(k.Name)
+			source := v.Source
+			switch v.Source {
+			case config.SourceEnv:
+				source = "env " + k.Env
+			case config.SourceUser, config.SourceProject:
+				source = v.Source + " " + v.Path
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", k.Name, v.Value, source, k.Help)
+		}
+		return w.Flush()
+	},
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the value of a setting",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		s, err := config.LoadSettings()
+		if err != nil {
+			return err
+		}
+		v, err := s.Get(args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Println(v.Value)
+		return nil
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Change a setting in the user (or --project) config",
+	Example: `  krnr config set shell pwsh
+  krnr config set timeout 5m --project`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		k, err := config.LookupKey(args[0])
+		if err != nil {
+			return err
+		}
+		v, err := k.Normalize(args[1])
+		if err != nil {
+			return err
+		}
+		if project, _ := cmd.Flags().GetBool("project"); project {
+			if err := config.CheckProjectKey(k); err != nil {
+				return err
+			}
+		}
+		path, err := configFile(cmd)
+		if err != nil {
+			return err
+		}
+		values, err := readConfigFile(path)
+		if err != nil {
+			return err
+		}
+		values[k.Name] = v
+		if err := config.WriteConfigFile(path, values); err != nil {
+			return err
+		}
+		fmt.Printf("set %s = %s in %s\n", k.Name, v, path)
+		if env := os.Getenv(k.Env); env != "" {
+			fmt.Printf("note: %s=%s overrides it in this environment\n", k.Env, env)
+		}
+		return nil
+	},
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "Remove a setting from the user (or --project) config",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		k, err := config.LookupKey(args[0])
+		if err != nil {
+			return err
+		}
+		path, err := configFile(cmd)
+		if err != nil {
+			return err
+		}
+		values, err := readConfigFile(path)
+		if err != nil {
+			return err
+		}
+		if _, ok := values[k.Name]; !ok {
+			fmt.Printf("%s is not set in %s\n", k.Name, path)
+			return nil
+		}
+		delete(values, k.Name)
+		if err := config.WriteConfigFile(path, values); err != nil {
+			return err
+		}
+		fmt.Printf("unset %s in %s\n", k.Name, path)
+		return nil
+	},
+}
+
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Open the user (or --project) config in the editor",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		path, err := configFile(cmd)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				return err
+			}
+			if err := os.WriteFile(path, []byte(config.Template(path)), 0o644); err != nil {
+				return err
+			}
+		}
+		if err := interactive.OpenEditorWith(settings.Editor, path); err != nil {
+			return err
+		}
+		if _, err := config.ReadConfigFile(path); err != nil {
+			return fmt.Errorf("%w\nthe file was saved; run 'krnr config edit' again to fix it", err)
+		}
+		fmt.Printf("saved %s\n", path)
+		return nil
+	},
+}
+
+func init() {
+	for _, c := range []*cobra.Command{configSetCmd, configUnsetCmd, configEditCmd} {
+		c.Flags().Bool("project", false, "Change the project config (.krnr.toml) instead of the user config")
+	}
+	configCmd.AddCommand(configListCmd, configGetCmd, configSetCmd, configUnsetCmd, configEditCmd)
+	rootCmd.AddCommand(configCmd)
+}
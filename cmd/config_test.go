@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VoxDroid/krnr/internal/config"
+)
+
+func TestConfigCommands(t *testing.T) {
+	home := setupTempDB(t)
+	for _, k := range config.Keys {
+		t.Setenv(k.Env, "")
+	}
+	t.Cleanup(func() { settings = config.Defaults() })
+	run := func(args ...string) (string, error) {
+		var err error
+		out, _ := captureOutput(func() {
+			rootCmd.SetArgs(args)
+			err = rootCmd.Execute()
+		})
+		return out, err
+	}
+
+	if out, err := run("config", "set", "timeout", "90s"); err != nil || !strings.Contains(out, "set timeout = 1m30s") {
+		t.Fatalf("config set: %q %v", out, err)
+	}
+	if out, err := run("config", "get", "timeout"); err != nil || out != "1m30s\n" {
+		t.Fatalf("config get: %q %v", out, err)
+	}
+	if settings.Timeout != 90*time.Second {
+		t.Fatalf("expected commands to see the configured timeout, got %s", settings.Timeout)
+	}
+	out, err := run("config", "list")
+	if err != nil || !strings.Contains(out, "user "+filepath.Join(home, "config.toml")) || !strings.Contains(out, "default") {
+		t.Fatalf("config list: %q %v", out, err)
+	}
+	if _, err := run("config", "set", "timout", "1m"); err == nil || !strings.Contains(err.Error(), `did you mean "timeout"`) {
+		t.Fatalf("expected a suggestion for a misspelt key, got %v", err)
+	}
+	if _, err := run("config", "set", "confirm", "maybe"); err == nil || !strings.Contains(err.Error(), "not a boolean") {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	if out, err := run("config", "unset", "timeout"); err != nil || !strings.Contains(out, "unset timeout") {
+		t.Fatalf("config unset: %q %v", out, err)
+	}
+	if out, _ := run("config", "get", "timeout"); out != "30s\n" {
+		t.Fatalf("expected the default after unset, got %q", out)
+	}
+
+	// an invalid config stops other commands but not the config commands
+	if err := os.WriteFile(filepath.Join(home, "config.toml"), []byte("theme = \"dark\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run("list"); err == nil || !strings.Contains(err.Error(), "krnr config edit") {
+		t.Fatalf("expected the invalid config to be reported, got %v", err)
+	}
+	if out, err := run("config", "set", "theme", "high-contrast"); err == nil || !strings.Contains(err.Error(), "not one of") {
+		t.Fatalf("expected set to report the invalid file, got %q %v", out, err)
+	}
+	if err := os.WriteFile(filepath.Join(home, "config.toml"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := run("config", "set", "theme", "high-contrast"); err != nil || !strings.Contains(out, "set theme") {
+		t.Fatalf("config set: %q %v", out, err)
+	}
+}
+
+func TestFlagOrSetting(t *testing.T) {
+	setupTempDB(t)
+	t.Cleanup(func() {
+		settings = config.Defaults()
+		_ = runCmd.Flags().Set("timeout", "30s")
+		runCmd.Flags().Lookup("timeout").Changed = false
+	})
+	settings.Timeout = time.Minute
+	if got := flagOrSetting(runCmd, "timeout", settings.Timeout); got != time.Minute {
+		t.Fatalf("expected the setting without the flag, got %s", got)
+	}
+	_ = runCmd.Flags().Set("timeout", "5s")
+	if got := flagOrSetting(runCmd, "timeout", settings.Timeout); got != 5*time.Second {
+		t.Fatalf("expected the flag to win, got %s", got)
+	}
+}
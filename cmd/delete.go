@@ -5,9 +5,8 @@ import (
 
 	"github.com/spf13/cobra"
 
-	"github.com/VoxDroid/krnr/internal/db"
-	"github.com/VoxDroid/krnr/internal/registry"
 	interactive "github.com/VoxDroid/krnr/internal/utils"
+	"github.com/VoxDroid/krnr/pkg/krnr"
 )
 
 var deleteCmd = &cobra.Command{
@@ -18,13 +17,15 @@ var deleteCmd = &cobra.Command{
 		name := args[0]
 		yesFlag, _ := cmd.Flags().GetBool("yes")
 
-		dbConn, err := db.InitDB()
+		reg, err := krnr.Open(krnr.Options{})
 		if err != nil {
 			return err
 		}
-		defer func() { _ = dbConn.Close() }()
+		defer func() { _ = reg.Close() }()
 
-		r := registry.NewRepository(dbConn)
+		if _, err := reg.Get(name); err != nil {
+			return err
+		}
 		if !yesFlag {
 			// prompt interactively; read from the command's input so tests can script it
 			if !interactive.ConfirmReader(fmt.Sprintf("Delete '%s' permanently?", name), cmd.InOrStdin()) {
@@ -32,7 +33,7 @@ var deleteCmd = &cobra.Command{
 				return nil
 			}
 		}
-		if err := r.DeleteCommandSet(name); err != nil {
+		if err := reg.Delete(name); err != nil {
 			return err
 		}
 		fmt.Printf("deleted '%s'\n", name)
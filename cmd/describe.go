@@ -5,8 +5,7 @@ import (
 
 	"github.com/spf13/cobra"
 
-	"github.com/VoxDroid/krnr/internal/db"
-	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/pkg/krnr"
 )
 
 var describeCmd = &cobra.Command{
@@ -15,28 +14,27 @@ var describeCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	RunE: func(_ *cobra.Command, args []string) error {
 		name := args[0]
-		dbConn, err := db.InitDB()
+		reg, err := krnr.Open(krnr.Options{})
 		if err != nil {
 			return err
 		}
-		defer func() { _ = dbConn.Close() }()
+		defer func() { _ = reg.Close() }()
 
-		r := registry.NewRepository(dbConn)
-		cs, err := r.GetCommandSetByName(name)
+		cs, err := reg.Get(name)
 		if err != nil {
 			return err
 		}
-		if cs == nil {
-			return fmt.Errorf("command set not found: %s", name)
-		}
 		fmt.Printf("Name: %s\n", cs.Name)
-		if cs.Description.Valid {
-			fmt.Printf("Description: %s\n", cs.Description.String)
+		if cs.Description != "" {
+			fmt.Printf("Description: %s\n", cs.Description)
 		}
 		fmt.Printf("Created: %s\n", cs.CreatedAt)
+		if cs.Exclusive {
+			fmt.Println("Exclusive: yes")
+		}
 		fmt.Println("Commands:")
-		for _, c := range cs.Commands {
-			fmt.Printf("%d: %s\n", c.Position, c.Command)
+		for i, c := range cs.Commands {
+			fmt.Printf("%d: %s\n", i+1, c)
 		}
 		return nil
 	},
@@ -42,6 +42,7 @@ var editCmd = &cobra.Command{
 				return err
 			}
 			fmt.Printf("updated '%s' with %d commands\n", name, len(cmdsFlags))
+			reportSaved(r, name)
 			return nil
 		}
 
@@ -59,7 +60,7 @@ var editCmd = &cobra.Command{
 		_ = w.Flush()
 		_ = tmpf.Close()
 
-		if err := interactive.OpenEditor(tmpf.Name()); err != nil {
+		if err := interactive.OpenEditorWith(settings.Editor, tmpf.Name()); err != nil {
 			return err
 		}
 
@@ -84,6 +85,7 @@ var editCmd = &cobra.Command{
 			return err
 		}
 		fmt.Printf("updated '%s' with %d commands\n", name, len(lines))
+		reportSaved(r, name)
 		return nil
 	},
 }
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/VoxDroid/krnr/internal/crypt"
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/registry"
+)
+
+func TestEncryptedExportAndImport(t *testing.T) {
+	home := setupTempDB(t)
+	tmpDir := t.TempDir()
+	t.Setenv("TMPDIR", tmpDir)
+	origCost := crypt.Cost
+	crypt.Cost.LogN = 10
+	t.Cleanup(func() {
+		crypt.Cost = origCost
+		_ = exportSetCmd.Flags().Set("encrypt", "false")
+		_ = exportSetCmd.Flags().Set("passphrase-file", "")
+		_ = exportSetCmd.Flags().Set("dst", "")
+		_ = importSetCmd.Flags().Set("passphrase-file", "")
+		_ = importSetCmd.Flags().Set("on-conflict", "rename")
+		_ = saveCmd.Flags().Lookup("command").Value.(interface{ Replace([]string) error }).Replace(nil)
+	})
+	run := func(args ...string) (string, error) {
+		var err error
+		out, _ := captureOutput(func() {
+			rootCmd.SetArgs(args)
+			err = rootCmd.Execute()
+		})
+		return out, err
+	}
+	if _, err := run("save", "deploy", "-c", "ssh deploy@db.internal"); err != nil {
+		t.Fatal(err)
+	}
+	passFile := filepath.Join(home, "pass")
+	wrongFile := filepath.Join(home, "wrong")
+	_ = os.WriteFile(passFile, []byte("s3cret\n"), 0o600)
+	_ = os.WriteFile(wrongFile, []byte("nope\n"), 0o600)
+
+	enc := filepath.Join(home, "deploy.db")
+	if _, err := run("export", "set", "deploy", "--dst", enc, "--passphrase-file", passFile); err == nil || !strings.Contains(err.Error(), "--encrypt") {
+		t.Fatalf("expected --passphrase-file to need --encrypt, got %v", err)
+	}
+	out, err := run("export", "set", "deploy", "--dst", enc, "--encrypt", "--passphrase-file", passFile)
+	if err != nil || !strings.Contains(out, "(encrypted)") {
+		t.Fatalf("export --encrypt: %q %v", out, err)
+	}
+	data, err := os.ReadFile(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !crypt.IsEncrypted(data) || bytes.Contains(data, []byte("db.internal")) {
+		t.Fatal("expected the export to be encrypted")
+	}
+
+	if _, err := run("import", "set", enc); err == nil || !strings.Contains(err.Error(), "--passphrase-file") {
+		t.Fatalf("expected the import to need a passphrase, got %v", err)
+	}
+	if _, err := run("import", "set", enc, "--passphrase-file", wrongFile); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Fatalf("expected a wrong passphrase to be refused, got %v", err)
+	}
+	if _, err := run("import", "set", enc, "--passphrase-file", passFile, "--on-conflict", "overwrite"); err != nil {
+		t.Fatalf("import of an encrypted bundle: %v", err)
+	}
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = dbConn.Close() }()
+	p, err := registry.NewRepository(dbConn).GetProvenance("deploy")
+	if err != nil || p == nil || p.Source != enc {
+		t.Fatalf("expected the provenance to name the encrypted file, got %+v %v", p, err)
+	}
+	tmps, _ := filepath.Glob(filepath.Join(tmpDir, "krnr-bundle-*"))
+	if len(tmps) != 0 {
+		t.Fatalf("expected decrypted temp files to be removed, found %v", tmps)
+	}
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/runner"
+)
+
+var exclusiveCmd = &cobra.Command{
+	Use:   "exclusive <set-name> [on|off]",
+	Short: "Allow only one run of a command set at a time",
+	Long:  "Show or change whether a command set is exclusive. While an exclusive set runs, its lease (owner, PID, host, heartbeat) blocks other 'krnr run' invocations; they fail fast or queue with --wait.",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(_ *cobra.Command, args []string) error {
+		name := args[0]
+
+		dbConn, err := db.InitDB()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = dbConn.Close() }()
+
+		r := registry.NewRepository(dbConn)
+		if len(args) == 2 {
+			var on bool
+			switch args[1] {
+			case "on":
+				on = true
+			case "off":
+			default:
+				return fmt.Errorf("invalid value %q (expected on or off)", args[1])
+			}
+			if err := r.SetExclusive(name, on); err != nil {
+				return err
+			}
+			fmt.Printf("'%s' exclusive: %s\n", name, args[1])
+			return nil
+		}
+		cs, err := r.GetCommandSetByName(name)
+		if err != nil {
+			return err
+		}
+		if cs == nil {
+			return fmt.Errorf("command set not found: %s", name)
+		}
+		state := "off"
+		if cs.Exclusive {
+			state = "on"
+		}
+		fmt.Printf("'%s' exclusive: %s\n", name, state)
+		l, err := r.GetLease(name)
+		if err != nil {
+			return err
+		}
+		if l != nil {
+			fmt.Printf("lease held by %s\n", runner.DescribeLease(l))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exclusiveCmd)
+}
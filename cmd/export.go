@@ -12,8 +12,96 @@ import (
 
 	"github.com/VoxDroid/krnr/internal/db"
 	"github.com/VoxDroid/krnr/internal/exporter"
+	"github.com/VoxDroid/krnr/internal/signing"
+	"github.com/VoxDroid/krnr/internal/user"
 )
 
+// exportSigningKey returns the key --sign asks for, or nil without --sign.
+// It is resolved before exporting so a missing key exports nothing.
+func exportSigningKey(cmd *cobra.Command) (*signing.Key, error) {
+	if sign, _ := cmd.Flags().GetBool("sign"); !sign {
+		return nil, nil
+	}
+	k, err := signing.LoadKeyring()
+	if err != nil {
+		return nil, err
+	}
+	name, _ := cmd.Flags().GetString("key")
+	key, err := k.SigningKey(name)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// askEncrypt asks whether to encrypt an interactive export and, if so, for
+// the passphrase.
+func askEncrypt(cmd *cobra.Command, rdr *bufio.Reader) ([]byte, error) {
+	cmd.Print("Encrypt with a passphrase? [y/N]: ")
+	raw, err := rdr.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if a := strings.ToLower(strings.TrimSpace(raw)); a != "y" && a != "yes" {
+		return nil, nil
+	}
+	return readPassphrase("", true)
+}
+
+// exportPassphrase returns the passphrase to encrypt with, or nil without
+// --encrypt.
+func exportPassphrase(cmd *cobra.Command) ([]byte, error) {
+	file, _ := cmd.Flags().GetString("passphrase-file")
+	if encrypt, _ := cmd.Flags().GetBool("encrypt"); !encrypt {
+		if file != "" {
+			return nil, fmt.Errorf("--passphrase-file needs --encrypt")
+		}
+		return nil, nil
+	}
+	return readPassphrase(file, true)
+}
+
+// exportBundle calls export to write a bundle to dst, signed with key and
+// encrypted with passphrase when they are set. An encrypted bundle is only
+// written in plaintext to a temporary file only the user can read.
+func exportBundle(dst string, key *signing.Key, passphrase []byte, export func(path string) error) error {
+	write := func(path string) error {
+		if err := export(path); err != nil {
+			return err
+		}
+		return signExport(key, path)
+	}
+	if passphrase == nil {
+		return write(dst)
+	}
+	return exporter.ExportEncrypted(dst, passphrase, write)
+}
+
+func encryptedNote(passphrase []byte) string {
+	if passphrase == nil {
+		return ""
+	}
+	return " (encrypted)"
+}
+
+// signExport signs the bundle exported to dst with key, when set, on
+// behalf of the whoami profile.
+func signExport(key *signing.Key, dst string) error {
+	if key == nil {
+		return nil
+	}
+	signer := user.Label()
+	if signer == "" {
+		signer = key.Name
+	}
+	s, err := exporter.Sign(dst, *key, signer)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("signed by %s with key %s\n", s.Signer, s.KeyID())
+	return nil
+}
+
 var exportCmd = &cobra.Command{
 	Use:   "export",
 	Short: "Export database or command sets to portable files",
@@ -49,16 +137,20 @@ var exportCmd = &cobra.Command{
 					si++
 				}
 			}
+			pass, err := askEncrypt(cmd, rdr)
+			if err != nil {
+				return err
+			}
 			// ensure DB is reachable
 			dbConn, err := db.InitDB()
 			if err != nil {
 				return err
 			}
 			_ = dbConn.Close()
-			if err := exporter.ExportDatabase(dst); err != nil {
+			if err := exportBundle(dst, nil, pass, exporter.ExportDatabase); err != nil {
 				return err
 			}
-			cmd.Printf("exported database to %s\n", dst)
+			cmd.Printf("exported database to %s%s\n", dst, encryptedNote(pass))
 			return nil
 		case "2":
 			cmd.Print("Name of set to export: ")
@@ -79,15 +171,21 @@ var exportCmd = &cobra.Command{
 			if dst == "" {
 				return fmt.Errorf("destination required")
 			}
+			pass, err := askEncrypt(cmd, rdr)
+			if err != nil {
+				return err
+			}
 			dbConn, err := db.InitDB()
 			if err != nil {
 				return err
 			}
 			defer func() { _ = dbConn.Close() }()
-			if err := exporter.ExportCommandSet(dbConn, name, dst); err != nil {
+			if err := exportBundle(dst, nil, pass, func(path string) error {
+				return exporter.ExportCommandSet(dbConn, name, path)
+			}); err != nil {
 				return err
 			}
-			cmd.Printf("exported command set '%s' to %s\n", name, dst)
+			cmd.Printf("exported command set '%s' to %s%s\n", name, dst, encryptedNote(pass))
 			return nil
 		default:
 			return fmt.Errorf("invalid choice: %s", choice)
@@ -115,16 +213,24 @@ var exportDbCmd = &cobra.Command{
 				si++
 			}
 		}
+		key, err := exportSigningKey(cmd)
+		if err != nil {
+			return err
+		}
+		pass, err := exportPassphrase(cmd)
+		if err != nil {
+			return err
+		}
 		// ensure DB is reachable (exporter will checkpoint itself)
 		dbConn, err := db.InitDB()
 		if err != nil {
 			return err
 		}
 		_ = dbConn.Close()
-		if err := exporter.ExportDatabase(dst); err != nil {
+		if err := exportBundle(dst, key, pass, exporter.ExportDatabase); err != nil {
 			return err
 		}
-		fmt.Printf("exported database to %s\n", dst)
+		fmt.Printf("exported database to %s%s\n", dst, encryptedNote(pass))
 		return nil
 	},
 }
@@ -139,15 +245,25 @@ var exportSetCmd = &cobra.Command{
 		if dst == "" {
 			return fmt.Errorf("--dst is required")
 		}
+		key, err := exportSigningKey(cmd)
+		if err != nil {
+			return err
+		}
+		pass, err := exportPassphrase(cmd)
+		if err != nil {
+			return err
+		}
 		dbConn, err := db.InitDB()
 		if err != nil {
 			return err
 		}
 		defer func() { _ = dbConn.Close() }()
-		if err := exporter.ExportCommandSet(dbConn, name, dst); err != nil {
+		if err := exportBundle(dst, key, pass, func(path string) error {
+			return exporter.ExportCommandSet(dbConn, name, path)
+		}); err != nil {
 			return err
 		}
-		fmt.Printf("exported command set '%s' to %s\n", name, dst)
+		fmt.Printf("exported command set '%s' to %s%s\n", name, dst, encryptedNote(pass))
 		return nil
 	},
 }
@@ -155,6 +271,12 @@ var exportSetCmd = &cobra.Command{
 func init() {
 	exportDbCmd.Flags().String("dst", "", "Destination file path for exported DB (required)")
 	exportSetCmd.Flags().String("dst", "", "Destination file path for exported command set (required)")
+	for _, c := range []*cobra.Command{exportDbCmd, exportSetCmd} {
+		c.Flags().Bool("sign", false, "Sign the exported sets with a key from 'krnr keys'")
+		c.Flags().String("key", "", "Signing key to use with --sign (default: your only signing key)")
+		c.Flags().Bool("encrypt", false, "Encrypt the export with a passphrase (asked for unless --passphrase-file is given)")
+		c.Flags().String("passphrase-file", "", "Read the --encrypt passphrase from the first line of this file")
+	}
 	exportCmd.AddCommand(exportDbCmd)
 	exportCmd.AddCommand(exportSetCmd)
 	rootCmd.AddCommand(exportCmd)
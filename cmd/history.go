@@ -2,6 +2,9 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -12,9 +15,9 @@ import (
 var historyCmd = &cobra.Command{
 	Use:   "history <name>",
 	Short: "Show version history for a named command set",
-	Long:  "Show version history for a named command set (versions, timestamps, author, operation)",
+	Long:  "Show version history for a named command set (versions, timestamps, author, operation). With --runs, show its run history instead: manual, scheduled and missed runs with status, exit code and duration.",
 	Args:  cobra.ExactArgs(1),
-	RunE: func(_ *cobra.Command, args []string) error {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		name := args[0]
 		dbConn, err := db.InitDB()
 		if err != nil {
@@ -23,6 +26,10 @@ var historyCmd = &cobra.Command{
 		defer func() { _ = dbConn.Close() }()
 
 		r := registry.NewRepository(dbConn)
+		if runs, _ := cmd.Flags().GetBool("runs"); runs {
+			limit, _ := cmd.Flags().GetInt("limit")
+			return printRunHistory(r, name, limit)
+		}
 		vers, err := r.ListVersionsByName(name)
 		if err != nil {
 			return err
@@ -42,6 +49,33 @@ var historyCmd = &cobra.Command{
 	},
 }
 
+// printRunHistory lists the most recent runs of name, newest first.
+func printRunHistory(r *registry.Repository, name string, limit int) error {
+	runs, err := r.ListRuns(name, limit)
+	if err != nil {
+		return err
+	}
+	if len(runs) == 0 {
+		fmt.Printf("no runs for %s\n", name)
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "STARTED\tSTATUS\tEXIT\tDURATION\tTRIGGER\tRUN")
+	for _, rr := range runs {
+		exit, dur := "-", "-"
+		if rr.ExitCode.Valid {
+			exit = fmt.Sprint(rr.ExitCode.Int64)
+		}
+		if rr.FinishedAt.Valid {
+			dur = rr.Duration.Round(time.Millisecond).String()
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", rr.StartedAt, rr.Status, exit, dur, rr.Trigger, rr.RunID)
+	}
+	return tw.Flush()
+}
+
 func init() {
+	historyCmd.Flags().Bool("runs", false, "Show run history (manual and scheduled runs) instead of versions")
+	historyCmd.Flags().Int("limit", 20, "With --runs: maximum number of runs to show (0 for all)")
 	rootCmd.AddCommand(historyCmd)
 }
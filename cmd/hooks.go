@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/VoxDroid/krnr/internal/lifecycle"
+	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/pkg/krnr"
+)
+
+// userHooks are the lifecycle hooks from KRNR_HOME/hooks.toml, loaded before
+// every command. Nil means none.
+var userHooks *lifecycle.Hooks
+
+// loadUserHooks loads the hooks file and installs its on-save and on-delete
+// hooks on every repository opened afterwards. An invalid file stops the
+// commands that fire hooks; the others warn and carry on without hooks.
+func loadUserHooks(cmd *cobra.Command) error {
+	userHooks = nil
+	registry.SetDefaultChangeHook(nil)
+	h, err := lifecycle.Load()
+	if err != nil {
+		err = fmt.Errorf("hooks: %w", err)
+		if firesHooks(cmd) {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "krnr: %v (sets cannot run or change until it is fixed)\n", err)
+		return nil
+	}
+	h.RunSet = runHookSet
+	h.Shell = settings.Shell
+	userHooks = h
+	if len(h.List()) > 0 {
+		registry.SetDefaultChangeHook(h)
+	}
+	return nil
+}
+
+// firesHooks reports whether cmd runs or changes command sets, and so may
+// fire hooks.
+func firesHooks(cmd *cobra.Command) bool {
+	return runsSets(cmd) || commandIn(cmd, tuiCmd, saveCmd, recordCmd, editCmd, tagCmd, rollbackCmd, deleteCmd, importCmd)
+}
+
+// quietHooks returns the user hooks with their output discarded, for front
+// ends that own the terminal.
+func quietHooks() *lifecycle.Hooks {
+	if userHooks == nil {
+		return nil
+	}
+	h := *userHooks
+	h.Out = io.Discard
+	return &h
+}
+
+// runHookSet runs a set hook through the SDK, without firing hooks again.
+func runHookSet(ctx context.Context, name string, params map[string]string, env []string, stdin io.Reader, out io.Writer) error {
+	reg, err := krnr.Open(krnr.Options{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = reg.Close() }()
+	_, err = reg.Run(ctx, name, krnr.RunOptions{Params: params, Env: env, Stdin: stdin, Stdout: out, Stderr: out, Shell: settings.Shell, NoHooks: true})
+	return err
+}
+
+// importWithHooks runs an import of src between the on-import hooks.
+func importWithHooks(src string, do func() error) error {
+	if abs, err := filepath.Abs(src); err == nil {
+		src = abs
+	}
+	c := lifecycle.Context{Event: lifecycle.OnImport, Source: src}
+	if err := userHooks.FireBefore(context.Background(), c); err != nil {
+		return err
+	}
+	if err := do(); err != nil {
+		return err
+	}
+	userHooks.FireAfter(context.Background(), c)
+	return nil
+}
+
+var hooksCmd = &cobra.Command{
+	Use:   "hooks",
+	Short: "Show lifecycle hooks configured in KRNR_HOME/hooks.toml",
+	Long: `Lifecycle hooks run a shell command or another krnr set when a set runs
+or changes. They are configured as [[hook]] entries in KRNR_HOME/hooks.toml:
+
+  [[hook]]
+  event = "on-save"             # pre-run, post-run, on-failure, on-save, on-delete, on-import
+  run = "./check-conventions"   # a shell command, or
+  # set = "notify"              # a krnr set, run with params hook_event, hook_set,
+                                # hook_run_id and hook_exit_code
+  sets = ["deploy-*"]           # optional: only for sets matching these globs
+  blocking = true               # refuse the save/delete/import/run when the hook fails
+  timeout = "10s"               # default 30s
+
+Every hook reads a JSON document on stdin with the event, set, run_id,
+exit_code, changed fields and more. Blocking hooks run before the change
+and their failure refuses it; a failing blocking post-run or on-failure
+hook fails the run. Other hooks run afterwards and only print a warning.
+Hooks are not fired for dry runs, nor from commands that hooks start.`,
+}
+
+var hooksListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List configured hooks",
+	Args:  cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		hooks := userHooks.List()
+		if len(hooks) == 0 {
+			p, _ := lifecycle.Path()
+			fmt.Printf("no hooks configured (add [[hook]] entries to %s)\n", p)
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "EVENT\tHOOK\tSETS\tBLOCKING")
+		for _, h := range hooks {
+			sets := "*"
+			if len(h.Sets) > 0 {
+				sets = strings.Join(h.Sets, ",")
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%t\n", h.Event, h.Label(), sets, h.Blocking)
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	hooksCmd.AddCommand(hooksListCmd)
+	rootCmd.AddCommand(hooksCmd)
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/executor"
+	"github.com/VoxDroid/krnr/internal/registry"
+)
+
+func TestLifecycleHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses POSIX shell commands")
+	}
+	home := setupTempDB(t)
+	log := filepath.Join(home, "hooks.log")
+	hooks := `
+[[hook]]
+event = "on-save"
+run = "exit 1"
+sets = ["bad*"]
+blocking = true
+
+[[hook]]
+event = "pre-run"
+run = "exit 2"
+sets = ["blocked"]
+blocking = true
+
+[[hook]]
+event = "post-run"
+run = "cat >> ` + log + ` && echo >> ` + log + `"
+
+[[hook]]
+event = "on-delete"
+run = "cat >> ` + log + ` && echo >> ` + log + `"
+`
+	if err := os.WriteFile(filepath.Join(home, "hooks.toml"), []byte(hooks), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		registry.SetDefaultChangeHook(nil)
+		userHooks = nil
+		_ = deleteCmd.Flags().Set("yes", "false")
+	})
+	origFactory := execFactory
+	defer func() { execFactory = origFactory }()
+	fake := &fakeRunner{}
+	execFactory = func(_, _ bool) executor.Runner { return fake }
+
+	out, _ := captureOutput(func() {
+		rootCmd.SetArgs([]string{"hooks", "list"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("hooks list: %v", err)
+		}
+	})
+	if !strings.Contains(out, "on-save") || !strings.Contains(out, "bad*") || !strings.Contains(out, "true") {
+		t.Fatalf("unexpected hooks list output: %s", out)
+	}
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	defer func() { _ = dbConn.Close() }()
+	r := registry.NewRepository(dbConn)
+	if _, err := r.CreateCommandSet("bad-name", nil, nil, nil, []string{"echo hi"}); err == nil {
+		t.Fatalf("expected the blocking on-save hook to refuse the save")
+	}
+	for _, name := range []string{"ok", "blocked"} {
+		if _, err := r.CreateCommandSet(name, nil, nil, nil, []string{"echo " + name}); err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+	}
+
+	captureOutput(func() {
+		rootCmd.SetArgs([]string{"run", "blocked"})
+		if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "pre-run hook") {
+			t.Fatalf("expected the pre-run hook to refuse the run, got %v", err)
+		}
+	})
+	if fake.lastCmd != "" {
+		t.Fatalf("a refused run must not execute, ran %q", fake.lastCmd)
+	}
+	captureOutput(func() {
+		rootCmd.SetArgs([]string{"run", "ok"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("run ok: %v", err)
+		}
+	})
+	captureOutput(func() {
+		rootCmd.SetArgs([]string{"delete", "ok", "--yes"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("delete ok: %v", err)
+		}
+	})
+
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatalf("hooks did not write their log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 ||
+		!strings.Contains(lines[0], `"event":"post-run","set":"ok"`) || !strings.Contains(lines[0], `"exit_code":0`) ||
+		!strings.Contains(lines[1], `"event":"on-delete","set":"ok","op":"delete"`) {
+		t.Fatalf("unexpected hook contexts:\n%s", data)
+	}
+}
+
+func TestInvalidHooksFile(t *testing.T) {
+	home := setupTempDB(t)
+	if err := os.WriteFile(filepath.Join(home, "hooks.toml"), []byte("[[hook]]\nevent = \"nope\"\nrun = \"true\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		registry.SetDefaultChangeHook(nil)
+		userHooks = nil
+		_ = deleteCmd.Flags().Set("yes", "false")
+		_ = saveCmd.Flags().Lookup("command").Value.(interface{ Replace([]string) error }).Replace(nil)
+	})
+	run := func(args ...string) (string, error) {
+		var err error
+		_, errOut := captureOutput(func() {
+			rootCmd.SetArgs(args)
+			err = rootCmd.Execute()
+		})
+		return errOut, err
+	}
+
+	// commands that never fire hooks warn and carry on
+	for _, args := range [][]string{{"list"}, {"hooks", "list"}, {"config", "list"}} {
+		if errOut, err := run(args...); err != nil || !strings.Contains(errOut, "krnr: hooks:") {
+			t.Fatalf("expected %v to warn about the invalid hooks file, got %q %v", args, errOut, err)
+		}
+	}
+	// commands that run or change sets stop
+	for _, args := range [][]string{{"save", "x", "-c", "echo x"}, {"run", "x"}, {"delete", "x", "--yes"}} {
+		if _, err := run(args...); err == nil || !strings.Contains(err.Error(), "hooks:") {
+			t.Fatalf("expected %v to stop on the invalid hooks file, got %v", args, err)
+		}
+	}
+}
@@ -10,8 +10,26 @@ import (
 
 	"github.com/VoxDroid/krnr/internal/db"
 	"github.com/VoxDroid/krnr/internal/importer"
+	"github.com/VoxDroid/krnr/internal/signing"
 )
 
+// importPassphrase supplies the passphrase of an encrypted bundle from
+// --passphrase-file, or asks for it.
+func importPassphrase(cmd *cobra.Command) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		file := ""
+		if f := cmd.Flags().Lookup("passphrase-file"); f != nil {
+			file = f.Value.String()
+		}
+		return readPassphrase(file, false)
+	}
+}
+
+// printSignature shows who signed a bundle about to be imported.
+func printSignature(v signing.Verification) {
+	fmt.Printf("bundle %s\n", v)
+}
+
 var importCmd = &cobra.Command{
 	Use:   "import",
 	Short: "Import a database file or exported command set into the active environment",
@@ -44,7 +62,9 @@ var importCmd = &cobra.Command{
 			}
 			over := strings.ToLower(strings.TrimSpace(overRaw))
 			overwrite := over == "y" || over == "yes"
-			if err := importer.ImportDatabase(src, overwrite, importer.ImportOptions{}); err != nil {
+			if err := importWithHooks(src, func() error {
+				return importer.ImportDatabase(src, overwrite, importer.ImportOptions{Verified: printSignature, Passphrase: importPassphrase(cmd)})
+			}); err != nil {
 				return err
 			}
 			cmd.Printf("imported database from %s\n", src)
@@ -82,7 +102,9 @@ var importCmd = &cobra.Command{
 			}
 			ded := strings.ToLower(strings.TrimSpace(dedRaw))
 			dedupe := ded == "y" || ded == "yes"
-			if err := importer.ImportCommandSet(src, importer.ImportOptions{OnConflict: oc, Dedupe: dedupe}); err != nil {
+			if err := importWithHooks(src, func() error {
+				return importer.ImportCommandSet(src, importer.ImportOptions{OnConflict: oc, Dedupe: dedupe, Verified: printSignature, Passphrase: importPassphrase(cmd)})
+			}); err != nil {
 				return err
 			}
 			cmd.Printf("imported command set(s) from %s\n", src)
@@ -106,7 +128,9 @@ var importDbCmd = &cobra.Command{
 		if _, err := os.Stat(src); err != nil {
 			return fmt.Errorf("source DB not found: %w", err)
 		}
-		if err := importer.ImportDatabase(src, overwrite, importer.ImportOptions{OnConflict: onConflict, Dedupe: dedupe}); err != nil {
+		if err := importWithHooks(src, func() error {
+			return importer.ImportDatabase(src, overwrite, importer.ImportOptions{OnConflict: onConflict, Dedupe: dedupe, Verified: printSignature, Passphrase: importPassphrase(cmd)})
+		}); err != nil {
 			return err
 		}
 		fmt.Printf("imported database from %s\n", src)
@@ -131,7 +155,9 @@ var importSetCmd = &cobra.Command{
 		_ = dbConn.Close()
 		onConflict, _ := cmd.Flags().GetString("on-conflict")
 		dedupe, _ := cmd.Flags().GetBool("dedupe")
-		if err := importer.ImportCommandSet(src, importer.ImportOptions{OnConflict: onConflict, Dedupe: dedupe}); err != nil {
+		if err := importWithHooks(src, func() error {
+			return importer.ImportCommandSet(src, importer.ImportOptions{OnConflict: onConflict, Dedupe: dedupe, Verified: printSignature, Passphrase: importPassphrase(cmd)})
+		}); err != nil {
 			return err
 		}
 		fmt.Printf("imported command set(s) from %s\n", src)
@@ -146,6 +172,9 @@ func init() {
 
 	importSetCmd.Flags().String("on-conflict", "rename", "Conflict policy for importing sets: rename|skip|overwrite|merge")
 	importSetCmd.Flags().Bool("dedupe", false, "When merging, dedupe identical commands")
+	for _, c := range []*cobra.Command{importDbCmd, importSetCmd} {
+		c.Flags().String("passphrase-file", "", "Read the passphrase of an encrypted bundle from the first line of this file")
+	}
 
 	importCmd.AddCommand(importDbCmd)
 	importCmd.AddCommand(importSetCmd)
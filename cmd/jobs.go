@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/jobs"
+	"github.com/VoxDroid/krnr/internal/registry"
+)
+
+// refreshJob marks a running job whose process has disappeared as lost.
+func refreshJob(r *registry.Repository, j *registry.Job) {
+	if j.Status != registry.JobStatusRunning || j.PID == 0 || jobs.Alive(j.PID) {
+		return
+	}
+	if ok, err := r.MarkJobFinished(j.ID, registry.JobStatusLost); err == nil && ok {
+		j.Status = registry.JobStatusLost
+	}
+}
+
+// getJob loads a job by id and refreshes its liveness.
+func getJob(r *registry.Repository, id string) (*registry.Job, error) {
+	j, err := r.GetJob(id)
+	if err != nil {
+		return nil, err
+	}
+	if j == nil {
+		return nil, fmt.Errorf("job not found: %s", id)
+	}
+	refreshJob(r, j)
+	return j, nil
+}
+
+var jobsCmd = &cobra.Command{
+	Use:   "jobs",
+	Short: "List background jobs",
+	Long:  "List running background jobs (started with 'krnr run --detach') and the most recent finished ones.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		limit, _ := cmd.Flags().GetInt("limit")
+
+		dbConn, err := db.InitDB()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = dbConn.Close() }()
+
+		r := registry.NewRepository(dbConn)
+		list, err := r.ListJobs(limit)
+		if err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			fmt.Println("no jobs")
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "ID\tSET\tSTATUS\tPID\tSTARTED\tFINISHED\tEXIT")
+		for i := range list {
+			j := &list[i]
+			refreshJob(r, j)
+			finished, exit := "-", "-"
+			if j.FinishedAt.Valid {
+				finished = j.FinishedAt.String
+			}
+			if j.ExitCode.Valid {
+				exit = fmt.Sprint(j.ExitCode.Int64)
+			}
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n", j.ID, j.SetName, j.Status, j.PID, j.StartedAt, finished, exit)
+		}
+		return tw.Flush()
+	},
+}
+
+var attachCmd = &cobra.Command{
+	Use:   "attach <job>",
+	Short: "Stream a background job's output and forward input to it",
+	Long:  "Print a background job's output so far and follow it live. While the job runs, typed input is forwarded to its stdin (e.g., for prompts or step decisions). Press Ctrl+C to detach; the job keeps running.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		dbConn, err := db.InitDB()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = dbConn.Close() }()
+
+		r := registry.NewRepository(dbConn)
+		j, err := getJob(r, args[0])
+		if err != nil {
+			return err
+		}
+		p, err := jobs.PathsFor(j.ID)
+		if err != nil {
+			return err
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if j.Status == registry.JobStatusRunning {
+			go forwardInput(ctx, os.Stdin, p)
+		}
+		running := func() bool {
+			cur, err := getJob(r, j.ID)
+			return err == nil && cur.Status == registry.JobStatusRunning
+		}
+		if err := jobs.Follow(ctx, p.Output, os.Stdout, running); err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			fmt.Printf("\ndetached from job %s (still running)\n", j.ID)
+			return nil
+		}
+		j, err = getJob(r, j.ID)
+		if err != nil {
+			return err
+		}
+		msg := fmt.Sprintf("job %s %s", j.ID, j.Status)
+		if j.ExitCode.Valid {
+			msg += fmt.Sprintf(" (exit %d)", j.ExitCode.Int64)
+		}
+		fmt.Println(msg)
+		return nil
+	},
+}
+
+// forwardInput copies typed input into the job's stdin file until ctx ends.
+func forwardInput(ctx context.Context, in io.Reader, p jobs.Paths) {
+	buf := make([]byte, 1024)
+	for ctx.Err() == nil {
+		n, err := in.Read(buf)
+		if n > 0 {
+			if _, werr := jobs.WriteInput(p, buf[:n]); werr != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+var killCmd = &cobra.Command{
+	Use:   "kill <job>",
+	Short: "Stop a running background job",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		dbConn, err := db.InitDB()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = dbConn.Close() }()
+
+		r := registry.NewRepository(dbConn)
+		j, err := getJob(r, args[0])
+		if err != nil {
+			return err
+		}
+		if j.Status != registry.JobStatusRunning {
+			return fmt.Errorf("job %s is not running (status %s)", j.ID, j.Status)
+		}
+		// record the kill first so the job's own exit does not overwrite it
+		if _, err := r.MarkJobFinished(j.ID, registry.JobStatusKilled); err != nil {
+			return err
+		}
+		if err := jobs.Terminate(j.PID); err != nil && jobs.Alive(j.PID) {
+			return fmt.Errorf("kill job %s: %w", j.ID, err)
+		}
+		fmt.Printf("killed job %s\n", j.ID)
+		return nil
+	},
+}
+
+func init() {
+	jobsCmd.Flags().Int("limit", 20, "Number of finished jobs to show (0 = all)")
+	rootCmd.AddCommand(jobsCmd)
+	rootCmd.AddCommand(attachCmd)
+	rootCmd.AddCommand(killCmd)
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/jobs"
+	"github.com/VoxDroid/krnr/internal/registry"
+)
+
+func TestRunDetachStartsJob(t *testing.T) {
+	setupTempDB(t)
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	defer func() { _ = dbConn.Close() }()
+	r := registry.NewRepository(dbConn)
+	if _, err := r.CreateCommandSet("maint", nil, nil, nil, []string{"echo {{env}}"}); err != nil {
+		t.Fatalf("CreateCommandSet: %v", err)
+	}
+
+	var gotArgs []string
+	orig := spawnJob
+	defer func() { spawnJob = orig }()
+	spawnJob = func(p jobs.Paths, args []string) (int, error) {
+		gotArgs = args
+		// simulate the background process having produced output and finished
+		_ = os.WriteFile(p.Output, []byte("-> echo prod\nprod\n"), 0o600)
+		return 0, nil
+	}
+
+	keepParamFlag(t)
+	resetRunFlags()
+	defer resetRunFlags()
+	out, _ := captureOutput(func() {
+		rootCmd.SetArgs([]string{"run", "maint", "--detach", "--param", "env=prod", "--show-stderr"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("run --detach failed: %v", err)
+		}
+	})
+	list, err := r.ListJobs(0)
+	if err != nil || len(list) != 1 {
+		t.Fatalf("expected one job, got %v %v", list, err)
+	}
+	id := list[0].ID
+	if !strings.Contains(out, "started job "+id) {
+		t.Fatalf("unexpected output: %s", out)
+	}
+	joined := strings.Join(gotArgs, " ")
+	if !strings.HasPrefix(joined, "run maint --job-id "+id) || !strings.Contains(joined, "--param=env=prod") ||
+		!strings.Contains(joined, "--show-stderr=true") || strings.Contains(joined, "--detach") {
+		t.Fatalf("unexpected background args: %v", gotArgs)
+	}
+
+	// the (simulated) job finishes; attach replays its output and status
+	if err := r.FinishJob(id, registry.JobStatusOK, 0); err != nil {
+		t.Fatalf("FinishJob: %v", err)
+	}
+	out, _ = captureOutput(func() {
+		rootCmd.SetArgs([]string{"attach", id})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("attach failed: %v", err)
+		}
+	})
+	if !strings.Contains(out, "prod\n") || !strings.Contains(out, "job "+id+" ok (exit 0)") {
+		t.Fatalf("unexpected attach output: %s", out)
+	}
+	out, _ = captureOutput(func() {
+		rootCmd.SetArgs([]string{"jobs"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("jobs failed: %v", err)
+		}
+	})
+	if !strings.Contains(out, id) || !strings.Contains(out, "maint") {
+		t.Fatalf("unexpected jobs output: %s", out)
+	}
+	rootCmd.SetArgs([]string{"kill", id})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "not running") {
+		t.Fatalf("expected kill of finished job to fail, got %v", err)
+	}
+}
+
+func TestDetachedJobsHaveNoDefaultTimeout(t *testing.T) {
+	newCmd := func(args ...string) *cobra.Command {
+		c := &cobra.Command{}
+		c.Flags().String("job-id", "", "")
+		c.Flags().Duration("timeout", 30*time.Second, "")
+		if err := c.Flags().Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	for _, c := range []struct {
+		args []string
+		want time.Duration
+	}{
+		{nil, settings.Timeout},
+		{[]string{"--timeout=5m"}, 5 * time.Minute},
+		{[]string{"--job-id=abc"}, 0},
+		{[]string{"--job-id=abc", "--timeout=5m"}, 5 * time.Minute},
+	} {
+		if got, err := runTimeout(newCmd(c.args...)); err != nil || got != c.want {
+			t.Errorf("runTimeout(%v) = %v %v, want %v", c.args, got, err, c.want)
+		}
+	}
+	if _, err := runTimeout(newCmd("--timeout=0s")); err == nil {
+		t.Error("expected a non-positive --timeout to be rejected")
+	}
+}
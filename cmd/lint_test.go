@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/registry"
+)
+
+func TestLintCommand(t *testing.T) {
+	_ = setupTempDB(t)
+	t.Cleanup(func() {
+		_ = lintCmd.Flags().Set("all", "false")
+		_ = lintCmd.Flags().Set("output", "text")
+	})
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = dbConn.Close() }()
+	r := registry.NewRepository(dbConn)
+	if _, err := r.CreateCommandSet("deploy", nil, nil, nil, []string{"cd /srv/app", "./deploy.sh {{env}}"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.CreateCommandSet("clean", nil, nil, nil, []string{"echo ok"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.AddSchedule("deploy", "@daily", nil, 0, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	run := func(args ...string) (string, error) {
+		var err error
+		out, _ := captureOutput(func() {
+			rootCmd.SetArgs(args)
+			err = rootCmd.Execute()
+		})
+		return out, err
+	}
+
+	out, err := run("lint", "deploy")
+	if err == nil || !strings.Contains(err.Error(), "lint found 1 error(s)") {
+		t.Fatalf("expected the unsupplied parameter to fail lint, got %v", err)
+	}
+	if !strings.Contains(out, "deploy: step 1: warning: 'cd /srv/app' has no effect") || !strings.Contains(out, "[param.unsupplied]") ||
+		!strings.Contains(out, "  ./deploy.sh {{env}}\n              ^^^^^^^") {
+		t.Fatalf("unexpected output %q", out)
+	}
+	if out, err := run("lint", "clean"); err != nil || !strings.Contains(out, "no problems found in 1 set(s)") {
+		t.Fatalf("lint clean: %q %v", out, err)
+	}
+
+	out, _ = run("lint", "--all", "--output", "json")
+	var res struct {
+		Sets []struct {
+			Set      string `json:"set"`
+			Findings []struct {
+				Rule     string `json:"rule"`
+				Severity string `json:"severity"`
+				Step     int    `json:"step"`
+			} `json:"findings"`
+		} `json:"sets"`
+		Errors   int `json:"errors"`
+		Warnings int `json:"warnings"`
+	}
+	if err := json.Unmarshal([]byte(out), &res); err != nil {
+		t.Fatalf("bad json %q: %v", out, err)
+	}
+	if len(res.Sets) != 2 || res.Errors != 1 || res.Warnings != 1 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	_ = lintCmd.Flags().Set("all", "false")
+	if _, err := run("lint"); err == nil || !strings.Contains(err.Error(), "pass a set name or --all") {
+		t.Fatal("expected lint without a name or --all to fail")
+	}
+}
@@ -5,8 +5,7 @@ import (
 
 	"github.com/spf13/cobra"
 
-	"github.com/VoxDroid/krnr/internal/db"
-	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/pkg/krnr"
 )
 
 var listCmd = &cobra.Command{
@@ -14,40 +13,19 @@ var listCmd = &cobra.Command{
 	Short: "List saved command sets",
 	Long:  "List saved command sets. Example:\n  krnr list",
 	RunE: func(cmd *cobra.Command, _ []string) error {
-		dbConn, err := db.InitDB()
+		reg, err := krnr.Open(krnr.Options{})
 		if err != nil {
 			return err
 		}
-		defer func() { _ = dbConn.Close() }()
+		defer func() { _ = reg.Close() }()
 
-		r := registry.NewRepository(dbConn)
 		// check flags
 		tagFilter, _ := cmd.Flags().GetString("tag")
 		textFilter, _ := cmd.Flags().GetString("filter")
 		fuzzyFlag, _ := cmd.Flags().GetBool("fuzzy")
-		var sets []registry.CommandSet
-		if tagFilter != "" {
-			sets, err = r.ListCommandSetsByTag(tagFilter)
-			if err != nil {
-				return err
-			}
-		} else if textFilter != "" {
-			if fuzzyFlag {
-				sets, err = r.FuzzySearchCommandSets(textFilter)
-				if err != nil {
-					return err
-				}
-			} else {
-				sets, err = r.SearchCommandSets(textFilter)
-				if err != nil {
-					return err
-				}
-			}
-		} else {
-			sets, err = r.ListCommandSets()
-			if err != nil {
-				return err
-			}
+		sets, err := reg.List(krnr.ListOptions{Tag: tagFilter, Query: textFilter, Fuzzy: fuzzyFlag})
+		if err != nil {
+			return err
 		}
 
 		for _, s := range sets {
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"regexp"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/VoxDroid/krnr/internal/runlog"
+)
+
+var logsCmd = &cobra.Command{
+	Use:   "logs <name>",
+	Short: "Show persisted output of previous runs",
+	Long: `Show the persisted output of previous runs of a command set, or with
+--prune delete old run logs of the set (of every set without a name).
+Examples:
+  krnr logs deploy                 # latest run
+  krnr logs deploy --list          # list stored runs
+  krnr logs deploy --run <id> --step 2
+  krnr logs deploy --follow        # stream a run that is still in progress
+  krnr logs deploy --grep 'error|warn'
+  krnr logs --prune                # apply the configured retention to every set
+  krnr logs deploy --prune --max-age 168h --keep 20 --max-size 50MB`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if prune, _ := cmd.Flags().GetBool("prune"); prune {
+			return cobra.MaximumNArgs(1)(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if prune, _ := cmd.Flags().GetBool("prune"); prune {
+			return pruneLogs(cmd, args)
+		}
+		for _, f := range []string{"max-age", "keep", "max-size"} {
+			if cmd.Flags().Changed(f) {
+				return fmt.Errorf("--%s only applies with --prune", f)
+			}
+		}
+		name := args[0]
+		list, _ := cmd.Flags().GetBool("list")
+		if list {
+			return printRunList(cmd, name)
+		}
+		runID, _ := cmd.Flags().GetString("run")
+		follow, _ := cmd.Flags().GetBool("follow")
+		step, _ := cmd.Flags().GetInt("step")
+		grep, _ := cmd.Flags().GetString("grep")
+
+		f := runlog.Filter{Step: step}
+		if grep != "" {
+			re, err := regexp.Compile(grep)
+			if err != nil {
+				return fmt.Errorf("invalid --grep pattern: %w", err)
+			}
+			f.Grep = re
+		}
+		run, err := runlog.FindRun(name, runID)
+		if err != nil {
+			return err
+		}
+		if follow {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stop()
+			return runlog.Follow(ctx, cmd.OutOrStdout(), run, f)
+		}
+		return runlog.Copy(cmd.OutOrStdout(), run, f)
+	},
+}
+
+func printRunList(cmd *cobra.Command, name string) error {
+	runs, err := runlog.ListRuns(name)
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	if len(runs) == 0 {
+		_, _ = fmt.Fprintf(out, "no logs for %s\n", name)
+		return nil
+	}
+	for _, r := range runs {
+		state := "done"
+		if r.Active {
+			state = "running"
+		}
+		_, _ = fmt.Fprintf(out, "%s\t%s\t%s\t%d bytes\n", r.ID, r.ModTime.Local().Format(time.RFC3339), state, r.Size)
+	}
+	return nil
+}
+
+// pruneLogs deletes finished run logs outside the retention given by the
+// flags, which default to the log_retention and log_keep settings. Without
+// a name all sets are pruned; limits apply per set and 0 disables one.
+func pruneLogs(cmd *cobra.Command, args []string) error {
+	ret := runlog.Configured(settings)
+	ret.MaxAge = flagOrSetting(cmd, "max-age", ret.MaxAge)
+	if cmd.Flags().Changed("keep") {
+		ret.MaxRuns, _ = cmd.Flags().GetInt("keep")
+	}
+	if sizeFlag, _ := cmd.Flags().GetString("max-size"); sizeFlag != "" {
+		n, err := runlog.ParseSize(sizeFlag)
+		if err != nil {
+			return err
+		}
+		ret.MaxBytes = n
+	}
+	out := cmd.OutOrStdout()
+	if len(args) == 1 {
+		removed, err := runlog.Prune(args[0], ret)
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(out, "removed %d run log(s) for %s\n", len(removed), args[0])
+		return nil
+	}
+	removed, err := runlog.PruneAll(ret)
+	if err != nil {
+		return err
+	}
+	total := 0
+	for _, ids := range removed {
+		total += len(ids)
+	}
+	_, _ = fmt.Fprintf(out, "removed %d run log(s) across %d set(s)\n", total, len(removed))
+	return nil
+}
+
+func init() {
+	logsCmd.Flags().String("run", "", "Run ID to show (default: most recent run)")
+	logsCmd.Flags().BoolP("follow", "f", false, "Stream new output while the run is in progress")
+	logsCmd.Flags().Int("step", 0, "Only show output of step N (1-based)")
+	logsCmd.Flags().String("grep", "", "Only show lines matching this regular expression")
+	logsCmd.Flags().Bool("list", false, "List stored runs instead of showing output")
+
+	logsCmd.Flags().Bool("prune", false, "Delete old run logs of the set, or of every set without a name")
+	logsCmd.Flags().Duration("max-age", 720*time.Hour, "With --prune, delete logs older than this (default from config 'log_retention')")
+	logsCmd.Flags().Int("keep", 100, "With --prune, keep at most this many runs per set (default from config 'log_keep')")
+	logsCmd.Flags().String("max-size", "", "With --prune, keep at most this much log data per set (e.g., 50MB)")
+
+	rootCmd.AddCommand(logsCmd)
+}
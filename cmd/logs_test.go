@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/VoxDroid/krnr/internal/config"
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/executor"
+	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/runlog"
+)
+
+func TestRunPersistsLogViewableWithLogsCmd(t *testing.T) {
+	setupTempDB(t)
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	defer func() { _ = dbConn.Close() }()
+
+	r := registry.NewRepository(dbConn)
+	if _, err := r.CreateCommandSet("logged", nil, nil, nil, []string{"echo one", "echo two"}); err != nil {
+		t.Fatalf("CreateCommandSet: %v", err)
+	}
+
+	origFactory := execFactory
+	defer func() { execFactory = origFactory }()
+	execFactory = func(_, _ bool) executor.Runner { return &fakeRunner{} }
+
+	_ = runCmd.Flags().Set("dry-run", "false")
+	_ = runCmd.Flags().Set("confirm", "false")
+	captureOutput(func() {
+		rootCmd.SetArgs([]string{"run", "logged"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+	})
+
+	out, _ := captureOutput(func() {
+		rootCmd.SetArgs([]string{"logs", "logged"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("logs failed: %v", err)
+		}
+	})
+	for _, want := range []string{"[step 1] -> echo one", "[step 2] cmd output", "[run] finished ok"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in logs output, got:\n%s", want, out)
+		}
+	}
+
+	out, _ = captureOutput(func() {
+		rootCmd.SetArgs([]string{"logs", "logged", "--step", "2"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("logs --step failed: %v", err)
+		}
+	})
+	_ = logsCmd.Flags().Set("step", "0")
+	if strings.Contains(out, "echo one") || !strings.Contains(out, "echo two") {
+		t.Fatalf("expected only step 2 lines, got:\n%s", out)
+	}
+}
+
+func TestLogsPruneFlagAndConfiguredRetention(t *testing.T) {
+	setupTempDB(t)
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	defer func() { _ = dbConn.Close() }()
+	// a set named like the old subcommand must stay viewable
+	if _, err := registry.NewRepository(dbConn).CreateCommandSet("prune", nil, nil, nil, []string{"echo hi"}); err != nil {
+		t.Fatalf("CreateCommandSet: %v", err)
+	}
+	origFactory := execFactory
+	defer func() { execFactory = origFactory }()
+	execFactory = func(_, _ bool) executor.Runner { return &fakeRunner{} }
+	t.Cleanup(func() {
+		for _, f := range []string{"prune", "keep"} {
+			_ = logsCmd.Flags().Lookup(f).Value.Set(logsCmd.Flags().Lookup(f).DefValue)
+			logsCmd.Flags().Lookup(f).Changed = false
+		}
+	})
+	execute := func(args ...string) (string, error) {
+		var err error
+		out, _ := captureOutput(func() {
+			rootCmd.SetArgs(args)
+			err = rootCmd.Execute()
+		})
+		return out, err
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := execute("run", "prune"); err != nil {
+			t.Fatalf("run: %v", err)
+		}
+	}
+	if out, err := execute("logs", "prune"); err != nil || !strings.Contains(out, "[run] finished ok") {
+		t.Fatalf("expected the logs of the set named prune, got %q %v", out, err)
+	}
+	if _, err := execute("logs", "prune", "--keep", "1"); err == nil || !strings.Contains(err.Error(), "--keep only applies with --prune") {
+		t.Fatalf("expected --keep without --prune to be refused, got %v", err)
+	}
+	logsCmd.Flags().Lookup("keep").Changed = false
+	if out, err := execute("logs", "prune", "--prune", "--keep", "2"); err != nil || !strings.Contains(out, "removed 1 run log(s) for prune") {
+		t.Fatalf("logs --prune: %q %v", out, err)
+	}
+	_ = logsCmd.Flags().Set("prune", "false")
+
+	// runs prune their set's logs to the configured log_keep
+	t.Setenv("KRNR_LOG_KEEP", "1")
+	if _, err := execute("run", "prune"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if runs, err := runlog.ListRuns("prune"); err != nil || len(runs) != 1 {
+		t.Fatalf("expected log_keep = 1 to leave one log, got %d %v", len(runs), err)
+	}
+	if s, err := config.LoadSettings(); err != nil || runlog.Configured(s).MaxRuns != 1 {
+		t.Fatalf("unexpected configured retention %+v %v", s, err)
+	}
+}
@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/VoxDroid/krnr/internal/config"
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/mcp"
+	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/runlog"
+	"github.com/VoxDroid/krnr/internal/tui/adapters"
+)
+
+// mcpTrigger is recorded in run history for runs started by MCP tool calls.
+const mcpTrigger = "mcp"
+
+// mcpMaxOutput bounds the output returned for one tool call; the start of
+// longer output is dropped (the run log keeps all of it).
+const mcpMaxOutput = 256 << 10
+
+// mcpBackend offers the sets carrying one of the allowed tags and runs them
+// through a backgroundRunner, collecting each run's output.
+type mcpBackend struct {
+	r      *registry.Repository
+	runner *backgroundRunner
+	tags   []string
+
+	mu   sync.Mutex
+	runs map[string]*mcpRun
+}
+
+type mcpRun struct {
+	out  strings.Builder
+	code int
+	done chan struct{}
+}
+
+func newMCPBackend(r *registry.Repository, ea adapters.ExecutorAdapter, tags []string, timeout time.Duration) *mcpBackend {
+	b := &mcpBackend{r: r, tags: tags, runs: map[string]*mcpRun{}}
+	b.runner = newBackgroundRunner(r, ea, mcpTrigger, timeout)
+	b.runner.sink = b.collect
+	return b
+}
+
+// run returns the collector for runID; events can arrive before start
+// has returned the ID.
+func (b *mcpBackend) run(runID string) *mcpRun {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	mr, ok := b.runs[runID]
+	if !ok {
+		mr = &mcpRun{done: make(chan struct{})}
+		b.runs[runID] = mr
+	}
+	return mr
+}
+
+func (b *mcpBackend) collect(ev adapters.RunEvent) {
+	mr := b.run(ev.RunID)
+	switch ev.Type {
+	case adapters.EventOutput:
+		mr.out.WriteString(strings.TrimSuffix(ev.Line, "\n"))
+		mr.out.WriteByte('\n')
+	case adapters.EventRunFinished:
+		mr.code = ev.ExitCode
+		if ev.Err != nil && ev.ExitCode == 0 {
+			mr.code = 1
+		}
+		close(mr.done)
+	}
+}
+
+// Sets returns the sets carrying an allowed tag.
+func (b *mcpBackend) Sets() ([]mcp.Set, error) {
+	seen := map[string]bool{}
+	var out []mcp.Set
+	for _, tag := range b.tags {
+		tagged, err := b.r.ListCommandSetsByTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tagged {
+			if seen[t.Name] {
+				continue
+			}
+			seen[t.Name] = true
+			cs, err := b.r.GetCommandSetByName(t.Name)
+			if err != nil {
+				return nil, err
+			}
+			if cs == nil {
+				continue
+			}
+			s := mcp.Set{Name: cs.Name, Description: cs.Description.String, Params: []string{}}
+			declared := map[string]bool{}
+			for _, c := range cs.Commands {
+				s.Commands = append(s.Commands, c.Command)
+				for _, p := range registry.FindParams(c.Command) {
+					if !declared[p] {
+						declared[p] = true
+						s.Params = append(s.Params, p)
+					}
+				}
+			}
+			out = append(out, s)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// Run starts the set and waits for it to finish. Sets without an allowed
+// tag are refused, even if they were offered earlier.
+func (b *mcpBackend) Run(ctx context.Context, set string, params map[string]string) (mcp.Result, error) {
+	sets, err := b.Sets()
+	if err != nil {
+		return mcp.Result{}, err
+	}
+	allowed := false
+	for _, s := range sets {
+		allowed = allowed || s.Name == set
+	}
+	if !allowed {
+		return mcp.Result{}, fmt.Errorf("%w: %s is not tagged %s", errRunRefused, set, strings.Join(b.tags, " or "))
+	}
+	runID, err := b.runner.start(ctx, set, params)
+	if err != nil {
+		return mcp.Result{}, err
+	}
+	mr := b.run(runID)
+	<-mr.done
+	b.mu.Lock()
+	delete(b.runs, runID)
+	b.mu.Unlock()
+	out := mr.out.String()
+	if len(out) > mcpMaxOutput {
+		out = "[output truncated; see 'krnr logs " + set + " --run " + runID + "']\n" + out[len(out)-mcpMaxOutput:]
+	}
+	return mcp.Result{Output: out, ExitCode: mr.code}, nil
+}
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Serve tagged command sets as tools over the Model Context Protocol",
+	Long: `Serve command sets as tools to AI agents over the Model Context Protocol
+(stdio transport: newline-delimited JSON-RPC on stdin/stdout).
+
+Only sets carrying one of the --tag tags (default "mcp") are offered, so
+you decide which workflows an agent may run:
+  krnr tag add deploy mcp
+
+Each tool is named after its set, described by the set's description and
+commands, and takes the set's parameters as required string arguments.
+Tool calls run like 'krnr run' without prompts or --force: commands are
+safety-checked, exclusive sets refuse concurrent runs, --timeout bounds
+each run, and output is kept in the run log with trigger "mcp".
+
+Example client configuration:
+  {"mcpServers": {"krnr": {"command": "krnr", "args": ["mcp"]}}}`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		tags, _ := cmd.Flags().GetStringArray("tag")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		if v, _ := settings.Get("timeout"); v.Source != config.SourceDefault {
+			// a configured timeout replaces the longer default for tool calls
+			timeout = flagOrSetting(cmd, "timeout", settings.Timeout)
+		}
+		if timeout <= 0 {
+			return fmt.Errorf("--timeout must be positive")
+		}
+		if len(tags) == 0 {
+			return fmt.Errorf("at least one --tag is required")
+		}
+
+		dbConn, err := db.InitDB()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = dbConn.Close() }()
+
+		r := registry.NewRepository(dbConn)
+		b := newMCPBackend(r, adapters.NewLoggingExecutorAdapter(execFactory(false, false), runlog.Configured(settings)), tags, timeout)
+		err = mcp.NewServer(b).Serve(context.Background(), os.Stdin, os.Stdout)
+		b.runner.wait()
+		return err
+	},
+}
+
+func init() {
+	mcpCmd.Flags().StringArray("tag", []string{"mcp"}, "Offer sets with this tag as tools (repeatable)")
+	mcpCmd.Flags().Duration("timeout", 10*time.Minute, "Abort a tool call's run that takes longer than this (config 'timeout' replaces the default)")
+	rootCmd.AddCommand(mcpCmd)
+}
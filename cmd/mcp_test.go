@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/executor"
+	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/tui/adapters"
+)
+
+func TestMCPBackendOffersAndRunsTaggedSets(t *testing.T) {
+	setupTempDB(t)
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	defer func() { _ = dbConn.Close() }()
+	r := registry.NewRepository(dbConn)
+	desc := "Deploy a branch"
+	for _, s := range []struct{ name, cmd, tag string }{
+		{"deploy", "echo {{branch}}", "mcp"},
+		{"wipe", "rm -rf /", "agents"},
+		{"private", "echo secret", ""},
+	} {
+		id, err := r.CreateCommandSet(s.name, &desc, nil, nil, []string{s.cmd})
+		if err != nil {
+			t.Fatalf("CreateCommandSet: %v", err)
+		}
+		if s.tag != "" {
+			_ = r.AddTagToCommandSet(id, s.tag)
+		}
+	}
+
+	fake := &fakeRunner{}
+	b := newMCPBackend(r, adapters.NewExecutorAdapter(executor.Runner(fake)), []string{"mcp", "agents"}, time.Minute)
+	sets, err := b.Sets()
+	if err != nil {
+		t.Fatalf("Sets: %v", err)
+	}
+	if len(sets) != 2 || sets[0].Name != "deploy" || sets[1].Name != "wipe" {
+		t.Fatalf("unexpected sets %+v", sets)
+	}
+	if sets[0].Description != desc || sets[0].Params[0] != "branch" || sets[0].Commands[0] != "echo {{branch}}" {
+		t.Fatalf("unexpected set %+v", sets[0])
+	}
+
+	ctx := context.Background()
+	res, err := b.Run(ctx, "deploy", map[string]string{"branch": "main"})
+	if err != nil || res.ExitCode != 0 || !strings.Contains(res.Output, "cmd output") {
+		t.Fatalf("Run = %+v %v", res, err)
+	}
+	if fake.lastCmd != "echo main" {
+		t.Fatalf("unexpected command: %q", fake.lastCmd)
+	}
+	if _, err := b.Run(ctx, "deploy", nil); !errors.Is(err, errBadParams) {
+		t.Fatalf("expected errBadParams, got %v", err)
+	}
+	if _, err := b.Run(ctx, "wipe", nil); !errors.Is(err, errRunRefused) {
+		t.Fatalf("expected dangerous command to be refused, got %v", err)
+	}
+	if _, err := b.Run(ctx, "private", nil); !errors.Is(err, errRunRefused) {
+		t.Fatalf("expected untagged set to be refused, got %v", err)
+	}
+	runs, err := r.ListRuns("deploy", 10)
+	if err != nil || len(runs) != 1 || runs[0].Trigger != mcpTrigger {
+		t.Fatalf("expected one mcp run recorded, got %+v %v", runs, err)
+	}
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/VoxDroid/krnr/internal/executor"
+)
+
+// readPassword reads a line from the terminal without echoing it.
+var readPassword = func() ([]byte, error) { return term.ReadPassword(int(os.Stdin.Fd())) }
+
+// readPassphrase returns the first line of file or, without a file, asks
+// for the passphrase on the terminal; confirm asks twice, for a new one.
+func readPassphrase(file string, confirm bool) ([]byte, error) {
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("read passphrase: %w", err)
+		}
+		line, _, _ := strings.Cut(string(data), "\n")
+		p := strings.TrimRight(line, "\r")
+		if p == "" {
+			return nil, fmt.Errorf("%s: passphrase is empty", file)
+		}
+		return []byte(p), nil
+	}
+	if !executor.IsTerminal(os.Stdin.Fd()) {
+		return nil, errors.New("no terminal to ask for the passphrase: use --passphrase-file")
+	}
+	p, err := askPassphrase("Passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if confirm {
+		again, err := askPassphrase("Repeat passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(p, again) {
+			return nil, errors.New("passphrases do not match")
+		}
+	}
+	return p, nil
+}
+
+func askPassphrase(prompt string) ([]byte, error) {
+	_, _ = fmt.Fprint(os.Stderr, prompt)
+	p, err := readPassword()
+	_, _ = fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("read passphrase: %w", err)
+	}
+	if len(p) == 0 {
+		return nil, errors.New("passphrase cannot be empty")
+	}
+	return p, nil
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/VoxDroid/krnr/internal/plugins"
+)
+
+// Help groups, used once plugins are listed alongside built-in commands.
+const (
+	builtinGroup = "builtin"
+	pluginGroup  = "plugins"
+)
+
+// isBuiltin reports whether name is a built-in command; built-ins always
+// win over plugins of the same name.
+func isBuiltin(root *cobra.Command, name string) bool {
+	if name == "help" || name == "completion" {
+		return true
+	}
+	for _, c := range root.Commands() {
+		if c.GroupID != pluginGroup && (c.Name() == name || c.HasAlias(name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// registerPlugins adds a command for every usable plugin so that cobra
+// dispatches to it and lists it in help.
+func registerPlugins(root *cobra.Command) {
+	var usable []plugins.Plugin
+	for _, p := range plugins.Discover() {
+		if !p.Shadowed && !isBuiltin(root, p.Name) {
+			usable = append(usable, p)
+		}
+	}
+	if len(usable) == 0 {
+		return
+	}
+	root.AddGroup(&cobra.Group{ID: builtinGroup, Title: "Available Commands:"}, &cobra.Group{ID: pluginGroup, Title: "Plugin Commands:"})
+	for _, c := range root.Commands() {
+		if c.GroupID == "" {
+			c.GroupID = builtinGroup
+		}
+	}
+	root.SetHelpCommandGroupID(builtinGroup)
+	root.SetCompletionCommandGroupID(builtinGroup)
+	for _, p := range usable {
+		root.AddCommand(pluginCommand(p))
+	}
+}
+
+func pluginCommand(p plugins.Plugin) *cobra.Command {
+	return &cobra.Command{
+		Use:     p.Name,
+		Short:   "Plugin: " + p.Path,
+		GroupID: pluginGroup,
+		// everything after the name, --help included, belongs to the plugin
+		DisableFlagParsing: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPlugin(cmd, p, args)
+		},
+	}
+}
+
+// runPlugin runs p in the foreground and exits with its exit code. Ctrl-C
+// reaches the plugin directly, so krnr ignores it meanwhile.
+func runPlugin(cmd *cobra.Command, p plugins.Plugin, args []string) error {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	defer signal.Stop(sig)
+	err := plugins.Run(context.Background(), p, args, cmd.InOrStdin(), cmd.OutOrStdout(), cmd.ErrOrStderr())
+	var ee *exec.ExitError
+	if errors.As(err, &ee) {
+		os.Exit(ee.ExitCode())
+	}
+	if err != nil {
+		return fmt.Errorf("plugin %s: %w", p.Name, err)
+	}
+	return nil
+}
+
+var pluginsCmd = &cobra.Command{
+	Use:   "plugins",
+	Short: "Manage external krnr-<name> plugins",
+	Long: `Plugins are executables named krnr-<name> in KRNR_HOME/plugins or on
+PATH; 'krnr <name> [args]' runs them with the arguments unchanged. Plugins
+in KRNR_HOME/plugins win over those on PATH, earlier PATH entries over later
+ones, and built-in commands over any plugin.
+
+Plugins receive these environment variables:
+  KRNR_DB       path of the registry database
+  KRNR_HOME     krnr data directory
+  KRNR_VERSION  krnr version
+  KRNR_BIN      path of the krnr executable`,
+}
+
+var pluginsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List discovered plugins",
+	Args:  cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		found := plugins.Discover()
+		if len(found) == 0 {
+			dir, _ := plugins.Dir()
+			fmt.Printf("no plugins found (put krnr-<name> executables in %s or on PATH)\n", dir)
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "NAME\tPATH\tSTATUS")
+		for _, p := range found {
+			status := "ok"
+			switch {
+			case isBuiltin(rootCmd, p.Name):
+				status = "shadowed by built-in command"
+			case p.Shadowed:
+				status = "shadowed by an earlier plugin"
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", p.Name, p.Path, status)
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	pluginsCmd.AddCommand(pluginsListCmd)
+	rootCmd.AddCommand(pluginsCmd)
+}
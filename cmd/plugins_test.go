@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestRegisterPluginsSkipsBuiltins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts")
+	}
+	setupTempDB(t)
+	bin := t.TempDir()
+	for _, name := range []string{"krnr-hello", "krnr-list"} {
+		if err := os.WriteFile(filepath.Join(bin, name), []byte("#!/bin/sh\necho hi\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", bin)
+
+	root := &cobra.Command{Use: "krnr"}
+	root.AddCommand(&cobra.Command{Use: "list", Run: func(*cobra.Command, []string) {}})
+	registerPlugins(root)
+	hello, _, err := root.Find([]string{"hello"})
+	if err != nil || hello.GroupID != pluginGroup || !hello.DisableFlagParsing {
+		t.Fatalf("expected a plugin command for krnr-hello, got %v %v", hello, err)
+	}
+	list, _, _ := root.Find([]string{"list"})
+	if list.GroupID != builtinGroup {
+		t.Fatalf("expected the built-in list command to win, got group %q", list.GroupID)
+	}
+
+	out, _ := captureOutput(func() {
+		rootCmd.SetArgs([]string{"plugins", "list"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("plugins list failed: %v", err)
+		}
+	})
+	if !strings.Contains(out, "hello") || !strings.Contains(out, "shadowed by built-in command") {
+		t.Fatalf("unexpected plugins list output: %s", out)
+	}
+}
@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/runner"
+	"github.com/VoxDroid/krnr/internal/security"
+)
+
+// userPolicy is the security policy from KRNR_HOME/policy.toml, loaded
+// before every command.
+var userPolicy = security.DefaultPolicy()
+
+// policyErr is why policy.toml could not be loaded by a command that
+// tolerates it; userPolicy is then the default policy.
+var policyErr error
+
+// loadPolicy loads the policy file. An invalid file stops commands that
+// run sets; the others, such as the policy, config and plugins commands,
+// warn and carry on with the default policy so the file can be fixed.
+func loadPolicy(cmd *cobra.Command) error {
+	userPolicy, policyErr = security.DefaultPolicy(), nil
+	p, err := security.LoadPolicy()
+	if err != nil {
+		err = fmt.Errorf("policy: %w", err)
+		if runsSets(cmd) {
+			return err
+		}
+		policyErr = err
+		fmt.Fprintf(os.Stderr, "krnr: %v (command sets cannot run until it is fixed)\n", err)
+		return nil
+	}
+	userPolicy = p
+	return nil
+}
+
+// runsSets reports whether cmd runs command sets. The TUI is not included:
+// it starts with a broken policy and refuses runs instead (see tuiGate).
+func runsSets(cmd *cobra.Command) bool {
+	return commandIn(cmd, runCmd, watchCmd, rpcCmd, serveCmd, mcpCmd, schedulerCmd)
+}
+
+// reportPolicy prints what the policy will do with each command of cs that
+// it does not simply allow.
+func reportPolicy(cs *registry.CommandSet) {
+	for i, c := range cs.Commands {
+		d := userPolicy.Evaluate(runner.Subject(cs, c.Command))
+		if d.Action == security.ActionAllow {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "policy: step %d of '%s' %s: %s\n%s\n", i+1, cs.Name, d.Action.Outcome(), d.Reason(), d.Highlight())
+	}
+}
+
+var policyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Inspect the security policy applied to commands before they run",
+	Long: `The security policy decides what happens to each command before it runs:
+allow it, warn about it, ask for confirmation, or block it. Runs that cannot
+ask (CI, background, scheduled and API runs) refuse commands that need
+confirmation; --force overrides confirm and block rules where available.
+
+The policy is KRNR_HOME/policy.toml. Its rules are checked first, in order,
+then the rules of the enabled built-in packs; the first matching rule
+decides and commands no rule matches are allowed:
+
+  packs = ["core", "git", "packages"]   # the default; add "strict" to opt in
+  require_signatures = true             # only import bundles signed by 'krnr keys'
+
+  [[rule]]
+  id = "ops-force-push"
+  action = "allow"                      # allow, warn, confirm or block
+  match = 'git push'                    # regular expression on the command
+  tags = ["ops"]                        # optional scopes: set tags,
+  # sets = ["release-*"]                # set name globs,
+  # authors = ["*@example.com"]         # author name or email globs
+  reason = "release branches are force-pushed by the ops team"
+
+Use 'krnr policy test' to see which rule decides a command.`,
+}
+
+var policyTestCmd = &cobra.Command{
+	Use:   "test <command>",
+	Short: "Show how the policy decides a command",
+	Example: `  krnr policy test "git push --force origin main"
+  krnr policy test "kubectl delete ns prod" --set deploy
+  krnr policy test "rm -rf build" --tag ci --author ops@example.com`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		subject := security.Subject{Command: args[0]}
+		if name, _ := cmd.Flags().GetString("set"); name != "" {
+			dbConn, err := db.InitDB()
+			if err != nil {
+				return err
+			}
+			defer func() { _ = dbConn.Close() }()
+			cs, err := registry.NewRepository(dbConn).GetCommandSetByName(name)
+			if err != nil {
+				return err
+			}
+			if cs == nil {
+				return fmt.Errorf("command set not found: %s", name)
+			}
+			subject = runner.Subject(cs, args[0])
+		}
+		if tags, _ := cmd.Flags().GetStringSlice("tag"); len(tags) > 0 {
+			subject.Tags = tags
+		}
+		if author, _ := cmd.Flags().GetString("author"); author != "" {
+			subject.Author, subject.AuthorEmail = author, author
+		}
+
+		d := userPolicy.Evaluate(subject)
+		fmt.Printf("decision: %s\n", d.Action)
+		if d.Rule == nil {
+			fmt.Println("rule:     none (commands no rule matches are allowed)")
+		} else {
+			fmt.Printf("rule:     %s (%s)\n", d.Rule.ID, d.Rule.Source())
+			fmt.Printf("reason:   %s\n", d.Rule.Reason)
+			fmt.Println(d.Highlight())
+		}
+		for _, r := range d.Shadowed {
+			fmt.Printf("also matched: %s %s (%s)\n", r.ID, r.Action, r.Source())
+		}
+		if userPolicy.Path != "" {
+			fmt.Printf("policy:   %s\n", userPolicy.Path)
+		}
+		return nil
+	},
+}
+
+var policyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the policy's rules in the order they are checked",
+	Args:  cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		if userPolicy.Path == "" {
+			p, _ := security.PolicyPath()
+			fmt.Printf("no policy file (%s); using the default packs\n", p)
+		}
+		if userPolicy.RequireSignatures {
+			fmt.Println("imports: bundles must be signed by a key in your keyring")
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "ID\tACTION\tSOURCE\tSCOPE\tREASON")
+		for _, r := range userPolicy.Rules {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.ID, r.Action, r.Source(), ruleScope(r), r.Reason)
+		}
+		return w.Flush()
+	},
+}
+
+var policyPacksCmd = &cobra.Command{
+	Use:   "packs",
+	Short: "List the built-in rule packs",
+	Args:  cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		enabled := map[string]bool{}
+		for _, p := range userPolicy.Packs {
+			enabled[p] = true
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "PACK\tENABLED\tRULES\tDESCRIPTION")
+		for _, p := range security.Packs {
+			_, _ = fmt.Fprintf(w, "%s\t%t\t%d\t%s\n", p.Name, enabled[p.Name], len(p.Rules), p.Description)
+		}
+		return w.Flush()
+	},
+}
+
+// ruleScope summarises a rule's tag, set and author scopes.
+func ruleScope(r *security.Rule) string {
+	var parts []string
+	if len(r.Tags) > 0 {
+		parts = append(parts, "tags="+strings.Join(r.Tags, ","))
+	}
+	if len(r.Sets) > 0 {
+		parts = append(parts, "sets="+strings.Join(r.Sets, ","))
+	}
+	if len(r.Authors) > 0 {
+		parts = append(parts, "authors="+strings.Join(r.Authors, ","))
+	}
+	if len(parts) == 0 {
+		return "*"
+	}
+	return strings.Join(parts, " ")
+}
+
+func init() {
+	policyTestCmd.Flags().String("set", "", "Evaluate as a command of this set (its name, tags and author)")
+	policyTestCmd.Flags().StringSlice("tag", nil, "Evaluate as a command of a set with these tags")
+	policyTestCmd.Flags().String("author", "", "Evaluate as a command of a set by this author (name or email)")
+	policyCmd.AddCommand(policyTestCmd, policyListCmd, policyPacksCmd)
+	rootCmd.AddCommand(policyCmd)
+}
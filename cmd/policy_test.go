@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/executor"
+	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/security"
+)
+
+func TestPolicyCommands(t *testing.T) {
+	home := setupTempDB(t)
+	policy := `
+[[rule]]
+id = "ops-push"
+action = "allow"
+match = 'git push'
+tags = ["ops"]
+reason = "ops releases force-push"
+
+[[rule]]
+id = "echo-warn"
+action = "warn"
+match = '^echo loud'
+reason = "is loud"
+`
+	if err := os.WriteFile(filepath.Join(home, "policy.toml"), []byte(policy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		userPolicy, policyErr = security.DefaultPolicy(), nil
+		_ = runCmd.Flags().Set("force", "false")
+		_ = policyTestCmd.Flags().Set("tag", "")
+		policyTestCmd.Flags().Lookup("tag").Changed = false
+	})
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = dbConn.Close() }()
+	r := registry.NewRepository(dbConn)
+	for name, c := range map[string]string{"push": "git push --force", "loud": "echo loud"} {
+		id, err := r.CreateCommandSet(name, nil, nil, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.AddCommand(id, 1, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	origFactory := execFactory
+	t.Cleanup(func() { execFactory = origFactory })
+	fake := &fakeRunner{}
+	execFactory = func(_, _ bool) executor.Runner { return fake }
+	run := func(args ...string) (string, string, error) {
+		var err error
+		out, errOut := captureOutput(func() {
+			rootCmd.SetArgs(args)
+			err = rootCmd.Execute()
+		})
+		return out, errOut, err
+	}
+
+	out, _, err := run("policy", "test", "git push --force")
+	if err != nil || !strings.Contains(out, "decision: confirm") || !strings.Contains(out, "rule:     git.force-push (pack git)") {
+		t.Fatalf("policy test: %q %v", out, err)
+	}
+	out, _, err = run("policy", "test", "git push --force", "--tag", "ops")
+	if err != nil || !strings.Contains(out, "decision: allow") || !strings.Contains(out, "also matched: git.force-push confirm") {
+		t.Fatalf("policy test --tag: %q %v", out, err)
+	}
+	if out, _, err = run("policy", "list"); err != nil || !strings.Contains(out, "ops-push") || !strings.Contains(out, "tags=ops") {
+		t.Fatalf("policy list: %q %v", out, err)
+	}
+
+	// a run that cannot prompt refuses commands needing confirmation
+	if _, _, err := run("run", "push"); err == nil || !strings.Contains(err.Error(), "needs confirmation by policy") {
+		t.Fatalf("expected the confirm rule to refuse, got %v", err)
+	}
+	if _, errOut, err := run("run", "push", "--force"); err != nil || !strings.Contains(errOut, "warning: forced:") || fake.lastCmd != "git push --force" {
+		t.Fatalf("expected --force to run with a warning, got %q %v", errOut, err)
+	}
+	_ = runCmd.Flags().Set("force", "false")
+	if _, errOut, err := run("run", "loud"); err != nil || !strings.Contains(errOut, "warning: 'echo loud' is loud [echo-warn]") {
+		t.Fatalf("expected the warning, got %q %v", errOut, err)
+	}
+	if _, err := (&backgroundRunner{r: r}).start(t.Context(), "push", nil); !errors.Is(err, errRunRefused) {
+		t.Fatalf("expected the background runner to refuse, got %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(home, "policy.toml"), []byte("[[rule]]\naction = \"nope\"\nmatch = \"x\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// an invalid policy stops runs but only warns commands that run nothing
+	if _, _, err := run("run", "loud"); err == nil || !strings.Contains(err.Error(), "policy:") {
+		t.Fatalf("expected an invalid policy to stop the run, got %v", err)
+	}
+	for _, args := range [][]string{{"list"}, {"policy", "list"}, {"config", "list"}} {
+		if _, errOut, err := run(args...); err != nil || !strings.Contains(errOut, "krnr: policy:") {
+			t.Fatalf("expected %v to warn about the invalid policy, got %q %v", args, errOut, err)
+		}
+	}
+	if _, err := tuiGate().Admit(t.Context(), r, "loud", nil, false); err == nil || !strings.Contains(err.Error(), "policy:") {
+		t.Fatalf("expected the TUI to refuse runs with an invalid policy, got %v", err)
+	}
+}
+
+func TestPolicySaveReportAndHighlight(t *testing.T) {
+	_ = setupTempDB(t)
+	origFactory := execFactory
+	t.Cleanup(func() {
+		execFactory = origFactory
+		// -c accumulates across Execute calls; later tests save their own commands
+		_ = saveCmd.Flags().Lookup("command").Value.(interface{ Replace([]string) error }).Replace(nil)
+	})
+	_ = saveCmd.Flags().Lookup("command").Value.(interface{ Replace([]string) error }).Replace(nil)
+	fake := &fakeRunner{}
+	execFactory = func(_, _ bool) executor.Runner { return fake }
+	run := func(args ...string) (string, error) {
+		var err error
+		_, errOut := captureOutput(func() {
+			rootCmd.SetArgs(args)
+			err = rootCmd.Execute()
+		})
+		return errOut, err
+	}
+
+	// saving is never refused but reports what the policy will do
+	errOut, err := run("save", "wipe", "-c", "sudo env X=1 rm -rf /")
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if !strings.Contains(errOut, "policy: step 1 of 'wipe' will be refused") || !strings.Contains(errOut, "  sudo env X=1 rm -rf /\n               ^^^^^^^^") {
+		t.Fatalf("expected a highlighted save-time report, got %q", errOut)
+	}
+
+	_, err = run("run", "wipe")
+	if err == nil || !strings.Contains(err.Error(), "[core.rm-root]") || !strings.Contains(err.Error(), "^^^^^^^^") {
+		t.Fatalf("expected a highlighted refusal, got %v", err)
+	}
+	if fake.lastCmd != "" {
+		t.Fatalf("refused command ran: %q", fake.lastCmd)
+	}
+}
@@ -76,6 +76,7 @@ var recordCmd = &cobra.Command{
 			return err
 		}
 		fmt.Printf("saved '%s' with %d commands\n", name, len(cmds))
+		reportSaved(r, name)
 		return nil
 	},
 }
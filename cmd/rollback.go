@@ -5,8 +5,7 @@ import (
 
 	"github.com/spf13/cobra"
 
-	"github.com/VoxDroid/krnr/internal/db"
-	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/pkg/krnr"
 )
 
 var rollbackCmd = &cobra.Command{
@@ -19,14 +18,13 @@ var rollbackCmd = &cobra.Command{
 		if vnum <= 0 {
 			return fmt.Errorf("--version must be a positive integer")
 		}
-		dbConn, err := db.InitDB()
+		reg, err := krnr.Open(krnr.Options{})
 		if err != nil {
 			return err
 		}
-		defer func() { _ = dbConn.Close() }()
+		defer func() { _ = reg.Close() }()
 
-		r := registry.NewRepository(dbConn)
-		if err := r.ApplyVersionByName(name, vnum); err != nil {
+		if _, err := reg.Rollback(name, vnum); err != nil {
 			return err
 		}
 		fmt.Printf("rolled back %s to v%d\n", name, vnum)
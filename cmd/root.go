@@ -12,7 +12,7 @@ var rootCmd = &cobra.Command{
 	Use:   "krnr",
 	Short: "krnr is a global, SQLite-backed command runner",
 	Long:  "krnr provides a global registry of named terminal workflows",
-	PersistentPreRun: func(cmd *cobra.Command, _ []string) {
+	PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
 		// Easter egg flag: --whoami
 		who, _ := cmd.Flags().GetBool("whoami")
 		if who {
@@ -20,6 +20,15 @@ var rootCmd = &cobra.Command{
 			fmt.Println("I'm @VoxDroid — https://github.com/VoxDroid")
 			os.Exit(0)
 		}
+		if err := loadSettings(cmd); err != nil {
+			return err
+		}
+		loadSecretScanner(cmd)
+		loadAuditActor()
+		if err := loadPolicy(cmd); err != nil {
+			return err
+		}
+		return loadUserHooks(cmd)
 	},
 	Run: func(_ *cobra.Command, _ []string) {
 		fmt.Println("krnr: run 'krnr --help' to see available commands")
@@ -34,6 +43,7 @@ func init() {
 
 // Execute executes the root command
 func Execute() {
+	registerPlugins(rootCmd)
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
 	}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/rpc"
+	"github.com/VoxDroid/krnr/internal/runlog"
+	"github.com/VoxDroid/krnr/internal/runner"
+	"github.com/VoxDroid/krnr/internal/tui/adapters"
+	modelpkg "github.com/VoxDroid/krnr/internal/tui/model"
+)
+
+var rpcCmd = &cobra.Command{
+	Use:   "rpc --stdio",
+	Short: "Serve JSON-RPC 2.0 over stdin/stdout for editor integrations",
+	Long: `Serve JSON-RPC 2.0 over stdin/stdout with LSP-style framing: every message
+is preceded by a "Content-Length: N" header and an empty line.
+
+Methods:
+  initialize                       server info and the supported methods
+  sets/list      {query?, tag?}    list sets (fuzzy query on name/description)
+  sets/get       {name}            a set with its commands and parameters
+  sets/params    {name}            parameters referenced by the set
+  sets/versions  {name}            version history, newest first
+  sets/rollback  {name, version}   roll back and return the set
+  runs/start     {name, params}    start a run; returns {runId}
+  runs/cancel    {runId}           cancel a run
+  shutdown, exit                   cancel runs / stop the server
+
+Run output arrives as "runs/event" notifications whose event field has the
+form of 'krnr run --output jsonl' lines, from run_started to run_finished.
+Runs never prompt: every parameter must be supplied, and the safety checks
+apply as for 'krnr run' without --force. Runs that take longer than
+--timeout are aborted.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		if stdio, _ := cmd.Flags().GetBool("stdio"); !stdio {
+			return fmt.Errorf("--stdio is required (it is the only supported transport)")
+		}
+		timeout := flagOrSetting(cmd, "timeout", settings.Timeout)
+		if timeout <= 0 {
+			return fmt.Errorf("--timeout must be positive")
+		}
+
+		dbConn, err := db.InitDB()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = dbConn.Close() }()
+
+		r := registry.NewRepository(dbConn)
+		m := modelpkg.New(adapters.NewRegistryAdapter(r), adapters.NewLoggingExecutorAdapter(execFactory(false, false), runlog.Configured(settings)), nil, nil)
+		m.SetGate(&runner.Gate{Options: runner.Options{Trigger: "rpc", Policy: userPolicy, Hooks: userHooks}, Timeout: timeout})
+		return rpc.NewServer(m, runlog.NewRunID).Serve(context.Background(), os.Stdin, os.Stdout)
+	},
+}
+
+func init() {
+	rpcCmd.Flags().Bool("stdio", false, "Use stdin/stdout as the transport")
+	rpcCmd.Flags().Duration("timeout", 30*time.Second, "Abort a run that takes longer than this (default from config 'timeout')")
+	rootCmd.AddCommand(rpcCmd)
+}
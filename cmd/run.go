@@ -2,9 +2,11 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"strings"
 	"time"
 
@@ -12,13 +14,21 @@ import (
 
 	"github.com/VoxDroid/krnr/internal/db"
 	"github.com/VoxDroid/krnr/internal/executor"
+	"github.com/VoxDroid/krnr/internal/progress"
 	"github.com/VoxDroid/krnr/internal/registry"
-	"github.com/VoxDroid/krnr/internal/security"
+	"github.com/VoxDroid/krnr/internal/report"
+	"github.com/VoxDroid/krnr/internal/runlog"
+	"github.com/VoxDroid/krnr/internal/runner"
+	"github.com/VoxDroid/krnr/internal/tui/adapters"
 	interactive "github.com/VoxDroid/krnr/internal/utils"
 )
 
 var execFactory = func(dry, verbose bool) executor.Runner {
-	return executor.New(dry, verbose)
+	r := executor.New(dry, verbose)
+	if ex, ok := r.(*executor.Executor); ok {
+		ex.Shell = settings.Shell
+	}
+	return r
 }
 
 var runCmd = &cobra.Command{
@@ -31,9 +41,36 @@ var runCmd = &cobra.Command{
 		dry, _ := cmd.Flags().GetBool("dry-run")
 		confirmFlag, _ := cmd.Flags().GetBool("confirm")
 		verbose, _ := cmd.Flags().GetBool("verbose")
-		force, _ := cmd.Flags().GetBool("force")
-		suppress, _ := cmd.Flags().GetBool("suppress-command")
-		showStderr, _ := cmd.Flags().GetBool("show-stderr")
+		noLog, _ := cmd.Flags().GetBool("no-log")
+		output, _ := cmd.Flags().GetString("output")
+		if output != "text" && output != "jsonl" {
+			return fmt.Errorf("invalid --output %q (expected text or jsonl)", output)
+		}
+		showProgress, _ := cmd.Flags().GetBool("progress")
+		if showProgress && output == "jsonl" {
+			return fmt.Errorf("--progress cannot be used with --output jsonl")
+		}
+		ciProvider, err := ciProviderFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		stepAll, _ := cmd.Flags().GetBool("step")
+		if ciProvider != "" && confirmFlag {
+			return fmt.Errorf("--confirm cannot be used with --ci (CI mode never prompts)")
+		}
+		if !cmd.Flags().Changed("confirm") {
+			// the configured default only applies to interactive runs;
+			// CI, background and scheduled runs never prompt
+			jobID, _ := cmd.Flags().GetString("job-id")
+			confirmFlag = settings.Confirm && ciProvider == "" && jobID == "" && executor.IsTerminal(os.Stdin.Fd())
+		}
+		if ciProvider != "" && stepAll {
+			return fmt.Errorf("--step cannot be used with --ci (CI mode never prompts)")
+		}
+		reports, err := parseReportFlags(cmd)
+		if err != nil {
+			return err
+		}
 
 		dbConn, err := db.InitDB()
 		if err != nil {
@@ -41,13 +78,34 @@ var runCmd = &cobra.Command{
 		}
 		defer func() { _ = dbConn.Close() }()
 
+		// collect parameters from flags
+		paramVals, _ := cmd.Flags().GetStringArray("param")
+		rp, err := parseParamFlags(paramVals)
+		if err != nil {
+			return err
+		}
+		if ciProvider != "" {
+			// CI mode: fail fast on missing params instead of prompting
+			rp.noPrompt = true
+		}
+		force, _ := cmd.Flags().GetBool("force")
+		wait, _ := cmd.Flags().GetBool("wait")
+		trigger, _ := cmd.Flags().GetString("trigger")
+		jobID, _ := cmd.Flags().GetString("job-id")
+		opts := runner.Options{Trigger: trigger, Policy: userPolicy, Force: force, ForceFlag: "--force",
+			Confirm: askPolicy(dry, rp.noPrompt || jobID != ""), DryRun: dry, Hooks: userHooks, Wait: wait, Stderr: os.Stderr}
+		rp.apply(&opts)
+
 		r := registry.NewRepository(dbConn)
-		cs, err := r.GetCommandSetByName(name)
+		run, err := runner.Prepare(r, name, opts)
 		if err != nil {
 			return err
 		}
-		if cs == nil {
-			return fmt.Errorf("command set not found: %s", name)
+		cs := run.Set
+		if ciProvider != "" {
+			if err := rp.checkComplete(cs); err != nil {
+				return err
+			}
 		}
 
 		if confirmFlag {
@@ -56,113 +114,305 @@ var runCmd = &cobra.Command{
 				return nil
 			}
 		}
+		if detach, _ := cmd.Flags().GetBool("detach"); detach {
+			return startDetached(cmd, r, cs.Name)
+		}
 
 		// Create executor via factory so tests can inject a fake Runner.
 		e := execFactory(dry, verbose)
 		// Allow user to override the shell used to execute commands (e.g., pwsh, bash, cmd)
-		shellFlag, _ := cmd.Flags().GetString("shell")
-		if ex, ok := e.(*executor.Executor); ok {
-			ex.Shell = shellFlag
+		if ex, ok := e.(*executor.Executor); ok && cmd.Flags().Changed("shell") {
+			ex.Shell, _ = cmd.Flags().GetString("shell")
 		}
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
-
-		// collect parameters from flags
-		paramVals, _ := cmd.Flags().GetStringArray("param")
-		params := map[string]string{}
-		paramEnvBound := map[string]bool{}
-		for _, p := range paramVals {
-			parts := strings.SplitN(p, "=", 2)
-			if len(parts) != 2 {
-				return fmt.Errorf("invalid --param value: %s (expected name=value)", p)
+		timeout, err := runTimeout(cmd)
+		if err != nil {
+			return err
+		}
+		var st *stepper
+		if ciProvider == "" {
+			// breakpoints never pause CI runs
+			bps, err := r.ListBreakpoints(cs.Name)
+			if err != nil {
+				return err
 			}
-			name := parts[0]
-			val := parts[1]
-			// env:NAME syntax reads from environment
-			if strings.HasPrefix(val, "env:") {
-				envKey := strings.TrimPrefix(val, "env:")
-				params[name] = os.Getenv(envKey)
-				paramEnvBound[name] = true
-			} else {
-				params[name] = val
+			if st = newStepper(stepAll, bps); !st.active() {
+				st = nil
 			}
 		}
 
-		for _, c := range cs.Commands {
-			cmdText := c.Command
-			// Apply parameter substitution if present
-			if len(registry.FindParams(cmdText)) > 0 {
-				// gather missing params and prompt interactively if needed
-				required := registry.FindParams(cmdText)
-				for _, rname := range required {
-					if _, ok := params[rname]; !ok {
-						// interactive prompt
-						val := interactive.Prompt(fmt.Sprintf("Value for parameter %s", rname))
-						if val == "" {
-							return fmt.Errorf("missing value for parameter %s", rname)
-						}
-						params[rname] = val
-						// prompted values are not marked env-bound; they may still be secrets
-					}
-				}
-				sub, err := registry.ApplyParams(cmdText, params)
-				if err != nil {
-					return err
-				}
-				cmdText = sub
-			}
+		// Take the lease of an exclusive set, record the run and fire the
+		// pre-run hooks; Ctrl-C gives up waiting for the lease.
+		startCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		err = run.Start(startCtx)
+		stop()
+		if errors.Is(err, runner.ErrBusy) {
+			return fmt.Errorf("%w (use --wait to queue)", err)
+		}
+		if err != nil {
+			return err
+		}
 
-			// Build a redacted command for logging and dry-run/verbose output
-			redactedParams := map[string]string{}
-			for k, v := range params {
-				if security.IsSecretParamName(k) || paramEnvBound[k] {
-					redactedParams[k] = "<redacted>"
-				} else {
-					redactedParams[k] = v
-				}
-			}
-			redactedCmd := cmdText
-			if len(registry.FindParams(c.Command)) > 0 {
-				if rsub, err := registry.ApplyParams(c.Command, redactedParams); err == nil {
-					redactedCmd = rsub
-				}
+		ctx := context.Background()
+		var job *jobRun
+		if jobID != "" {
+			if ctx, job, err = startJobRun(ctx, r, jobID); err != nil {
+				return run.Finish(context.Background(), err, nil)
 			}
+			_ = r.SetJobRunID(job.id, run.ID)
+		}
 
-			// Security: check if command is allowed (use real substituted command)
-			if err := security.CheckAllowed(cmdText); err != nil && !force {
-				return fmt.Errorf("refusing to run potentially dangerous command '%s': %v (use --force to override)", redactedCmd, err)
-			}
-			if !suppress {
-				fmt.Printf("-> %s\n", redactedCmd)
+		// Persist combined output for later inspection via `krnr logs`.
+		// Dry runs execute nothing, so there is nothing worth keeping.
+		var lw *runlog.Writer
+		if !dry && !noLog {
+			if lw, err = runlog.CreateWithID(cs.Name, run.ID); err != nil {
+				cmd.PrintErrf("warning: run log disabled: %v\n", err)
+				lw = nil
 			}
-			stderr := io.Discard
-			if showStderr {
-				stderr = os.Stderr
+		}
+		sess := &runSession{cmd: cmd, exec: e, set: cs, pipeline: run, log: lw, events: adapters.NewEventEmitter(run.ID), jsonl: output == "jsonl", stepper: st, timeout: timeout}
+		sess.attachConsole()
+		if sess.jsonl {
+			sess.events.AddSink(adapters.JSONLSink(os.Stdout))
+		}
+		if ciProvider != "" && !sess.jsonl {
+			sess.events.AddSink(report.NewCIMarkers(ciProvider, cs.Name, os.Stdout).Handle)
+		}
+		if showProgress {
+			sess.attachProgress(r, executor.IsTerminal(os.Stdout.Fd()))
+		}
+		writeReports := sess.attachReports(reports)
+		runErr := sess.run(ctx)
+		if job != nil {
+			job.finish(runErr)
+		}
+		if err := writeReports(); err != nil {
+			cmd.PrintErrf("warning: %v\n", err)
+		}
+		if lw != nil {
+			if err := lw.Close(runErr); err != nil {
+				cmd.PrintErrf("warning: finalize run log: %v\n", err)
 			}
-			// For dry-run, pass redacted command to the executor so verbose dry-run output doesn't leak secrets
-			if dry {
-				if err := e.Execute(ctx, redactedCmd, "", os.Stdin, os.Stdout, stderr); err != nil {
-					return err
-				}
-			} else {
-				if err := e.Execute(ctx, cmdText, "", os.Stdin, os.Stdout, stderr); err != nil {
-					return err
-				}
+			_, _ = runlog.Prune(cs.Name, runlog.Configured(settings))
+		}
+		return runErr
+	},
+}
+
+// runTimeout returns how long the run's commands may take, 0 meaning no
+// limit.
+// Detached jobs are meant for long work and are stopped with `krnr kill`,
+// so they ignore the configured default and are only bounded by an
+// explicit --timeout.
+func runTimeout(cmd *cobra.Command) (time.Duration, error) {
+	if jobID, _ := cmd.Flags().GetString("job-id"); jobID != "" && !cmd.Flags().Changed("timeout") {
+		return 0, nil
+	}
+	timeout := flagOrSetting(cmd, "timeout", settings.Timeout)
+	if timeout <= 0 {
+		return 0, fmt.Errorf("--timeout must be positive")
+	}
+	return timeout, nil
+}
+
+// runParams holds parameter values supplied for a run and tracks which of
+// them were bound from the environment (and must therefore be redacted).
+type runParams struct {
+	values   map[string]string
+	envBound map[string]bool
+	// noPrompt makes missing parameters an error instead of prompting.
+	noPrompt bool
+	// quote shell-quotes values (see registry.ApplyParamsQuoted); set for
+	// values that come from outside, such as HTTP requests.
+	quote bool
+}
+
+// checkComplete verifies that every parameter referenced by cs has a value.
+func (rp *runParams) checkComplete(cs *registry.CommandSet) error {
+	var missing []string
+	seen := map[string]bool{}
+	for _, c := range cs.Commands {
+		for _, p := range registry.FindParams(c.Command) {
+			if _, ok := rp.values[p]; !ok && !seen[p] {
+				seen[p] = true
+				missing = append(missing, p)
 			}
 		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing value for parameter(s) %s (pass --param name=value)", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// parseParamFlags parses repeated --param name=value flags. The env:NAME
+// syntax reads the value from the environment.
+func parseParamFlags(paramVals []string) (*runParams, error) {
+	rp := &runParams{values: map[string]string{}, envBound: map[string]bool{}}
+	for _, p := range paramVals {
+		parts := strings.SplitN(p, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid --param value: %s (expected name=value)", p)
+		}
+		name := parts[0]
+		val := parts[1]
+		// env:NAME syntax reads from environment
+		if strings.HasPrefix(val, "env:") {
+			envKey := strings.TrimPrefix(val, "env:")
+			rp.values[name] = os.Getenv(envKey)
+			rp.envBound[name] = true
+		} else {
+			rp.values[name] = val
+		}
+	}
+	return rp, nil
+}
+
+// apply sets the parameter options of a run from rp. Missing parameters
+// are prompted for unless noPrompt is set.
+func (rp *runParams) apply(opts *runner.Options) {
+	opts.Params, opts.Secret, opts.Quote = rp.values, rp.envBound, rp.quote
+	if !rp.noPrompt {
+		opts.Prompt = promptParam
+	}
+}
 
+// promptParam asks for the value of a parameter; prompted values are not
+// marked env-bound, they may still be secrets.
+func promptParam(name string) string {
+	return interactive.Prompt(fmt.Sprintf("Value for parameter %s", name))
+}
+
+// runSession holds the state of a single CLI run: the run going through
+// the pipeline, the executor, optional run log and the structured event
+// emitter shared with the TUI event vocabulary.
+type runSession struct {
+	cmd  *cobra.Command
+	exec executor.Runner
+	set  *registry.CommandSet
+	// pipeline applies the checks, lease, history and hooks of the run.
+	pipeline *runner.Run
+	log      *runlog.Writer
+	events   *adapters.EventEmitter
+	// jsonl switches console output from human-readable text to JSON events.
+	jsonl bool
+	// captureStderr emits stderr output events even when stderr is not shown
+	// (reports need the full output tail).
+	captureStderr bool
+	// progress, when set, draws step progress and replaces the "-> cmd" lines.
+	progress *progress.Renderer
+	// stepper, when set, pauses before steps for a decision.
+	stepper *stepper
+	// timeout bounds the time the commands run, not counting pauses and
+	// prompts; 0 means no limit.
+	timeout time.Duration
+}
+
+// run executes each command of the set in order, stopping at the first error.
+func (s *runSession) run(ctx context.Context) error {
+	eo := runner.ExecOptions{Runner: s.exec, Events: s.events, Stdin: os.Stdin, Log: s.log, Writers: s.stepWriters, Timeout: s.timeout}
+	if s.stepper != nil {
+		eo.Stepper = s.stepper
+	}
+	return s.pipeline.Exec(ctx, eo)
+}
+
+// attachConsole prints each step's command before it runs, unless
+// suppressed or replaced by JSON events or progress.
+func (s *runSession) attachConsole() {
+	suppress, _ := s.cmd.Flags().GetBool("suppress-command")
+	s.events.AddSink(func(ev adapters.RunEvent) {
+		if ev.Type == adapters.EventStepStarted && !suppress && !s.jsonl && s.progress == nil {
+			fmt.Printf("-> %s\n", ev.Command)
+		}
+	})
+}
+
+// askPolicy returns how a policy rule asking for confirmation is answered:
+// by the user when the run may prompt and stdin is a terminal, not at all
+// (refused) otherwise. Dry runs only report it.
+func askPolicy(dry, noPrompt bool) func(string) bool {
+	if dry {
+		return func(prompt string) bool {
+			fmt.Fprintf(os.Stderr, "dry run: would ask: %s\n", prompt)
+			return true
+		}
+	}
+	if noPrompt || !executor.IsTerminal(os.Stdin.Fd()) {
 		return nil
-	},
+	}
+	return interactive.Confirm
+}
+
+// stepWriters returns the stdout/stderr writers handed to the executor for a
+// step. Output always becomes output events; in text mode it is also printed
+// (stderr only with --show-stderr), in jsonl mode the JSONL sink prints it.
+// The run log mirrors what is shown.
+func (s *runSession) stepWriters(step int) (io.Writer, io.Writer) {
+	showStderr, _ := s.cmd.Flags().GetBool("show-stderr")
+	stdout := []io.Writer{s.events.OutputWriter(step, adapters.StreamStdout)}
+	var stderr []io.Writer
+	switch {
+	case s.progress != nil:
+		// route console output through the renderer so it can keep the
+		// status line below it
+		stdout = append(stdout, s.progress.Writer())
+		if showStderr {
+			stderr = append(stderr, s.progress.Writer())
+		}
+	case !s.jsonl:
+		stdout = append(stdout, os.Stdout)
+		if showStderr {
+			stderr = append(stderr, os.Stderr)
+		}
+	}
+	stderrShown := showStderr || s.jsonl
+	if stderrShown || s.captureStderr {
+		stderr = append(stderr, s.events.OutputWriter(step, adapters.StreamStderr))
+	}
+	if s.log != nil {
+		stdout = append(stdout, s.log)
+		if stderrShown {
+			stderr = append(stderr, s.log)
+		}
+	}
+	return combineWriters(stdout), combineWriters(stderr)
+}
+
+// combineWriters returns io.Discard for no writers, the writer itself for
+// one, and an io.MultiWriter otherwise.
+func combineWriters(ws []io.Writer) io.Writer {
+	switch len(ws) {
+	case 0:
+		return io.Discard
+	case 1:
+		return ws[0]
+	}
+	return io.MultiWriter(ws...)
 }
 
 func init() {
 	runCmd.Flags().Bool("dry-run", false, "Do not actually execute commands")
-	runCmd.Flags().Bool("confirm", false, "Ask for confirmation before running")
+	runCmd.Flags().Bool("confirm", false, "Ask for confirmation before running (default from config 'confirm')")
 	runCmd.Flags().Bool("verbose", false, "Verbose output (prints dry-run messages)")
 	runCmd.Flags().Bool("force", false, "Override safety checks and force execution")
 	runCmd.Flags().Bool("suppress-command", false, "Suppress printing the written command before execution")
 	runCmd.Flags().Bool("show-stderr", false, "Show command stderr output instead of omitting it")
-	runCmd.Flags().String("shell", "", "Override shell to execute commands (e.g., pwsh, bash, cmd)")
+	runCmd.Flags().String("shell", "", "Override shell to execute commands (e.g., pwsh, bash, cmd; default from config 'shell')")
 	runCmd.Flags().StringArray("param", []string{}, "Parameter values as name=value (repeatable). Use env:VAR to load from environment, e.g. --param user=env:USER")
+	runCmd.Flags().String("output", "text", "Console output format: text or jsonl (structured run events)")
+	runCmd.Flags().Bool("no-log", false, "Do not persist this run's output under KRNR_HOME/logs")
+	runCmd.Flags().StringArray("report", []string{}, "Write a report after the run as format=path (repeatable), e.g. --report junit=results.xml")
+	runCmd.Flags().String("ci", "", "CI mode: never prompt, fail fast on missing params and emit CI markers (auto|github|gitlab|plain)")
+	runCmd.Flags().Lookup("ci").NoOptDefVal = "auto"
+	runCmd.Flags().Bool("detach", false, "Run in a supervised background job (see 'krnr jobs', 'krnr attach', 'krnr kill')")
+	runCmd.Flags().String("job-id", "", "Internal: run as the background process of the given job")
+	_ = runCmd.Flags().MarkHidden("job-id")
+	runCmd.Flags().Bool("wait", false, "For exclusive sets: wait for the running holder to finish instead of failing")
+	runCmd.Flags().Bool("step", false, "Pause before every step to run, skip, edit once, open a shell or abort")
+	runCmd.Flags().Duration("timeout", 30*time.Second, "Abort the run when its commands take longer than this in total (e.g. 90s, 10m; default from config 'timeout')")
+	runCmd.Flags().String("trigger", "manual", "Internal: what started the run, as recorded in run history")
+	_ = runCmd.Flags().MarkHidden("trigger")
+	runCmd.Flags().Bool("progress", false, "Show live step progress with elapsed time and ETA, followed by a per-step summary")
 	rootCmd.AddCommand(runCmd)
 }
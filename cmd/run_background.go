@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/VoxDroid/krnr/internal/lifecycle"
+	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/runner"
+	"github.com/VoxDroid/krnr/internal/tui/adapters"
+)
+
+// runRedacted starts commands through ea, showing the redacted forms in
+// events and run logs when the executor supports it.
+func runRedacted(ctx context.Context, ea adapters.ExecutorAdapter, name string, cmds, redacted []string) (adapters.RunHandle, error) {
+	if ra, ok := ea.(adapters.RedactingExecutorAdapter); ok {
+		return ra.RunRedacted(ctx, name, cmds, redacted)
+	}
+	return ea.Run(ctx, name, cmds)
+}
+
+// Reasons a background run is not started; servers map them to responses.
+var (
+	errSetNotFound = runner.ErrNotFound
+	errBadParams   = errors.New("invalid parameters")
+	errRunRefused  = errors.New("run refused")
+	errRunConflict = errors.New("set is already running")
+)
+
+// backgroundRunner starts runs of command sets inside a long-lived krnr
+// process (the hook and API servers). Runs never prompt: parameters must
+// all be supplied, and since they come from outside they are shell-quoted
+// so they cannot change the commands. Output goes to the run log and the result to run
+// history under the runner's trigger.
+type backgroundRunner struct {
+	r       *registry.Repository
+	exec    adapters.ExecutorAdapter
+	trigger string
+	timeout time.Duration
+	// sink, when set, receives every run's events stamped with its run ID,
+	// framed by run_started and run_finished.
+	sink func(adapters.RunEvent)
+
+	wg sync.WaitGroup
+}
+
+func newBackgroundRunner(r *registry.Repository, ea adapters.ExecutorAdapter, trigger string, timeout time.Duration) *backgroundRunner {
+	return &backgroundRunner{r: r, exec: ea, trigger: trigger, timeout: timeout}
+}
+
+// start validates values against the parameters the set declares, applies
+// the safety checks and starts the run. It returns the run ID. Cancelling
+// ctx aborts the run.
+func (b *backgroundRunner) start(ctx context.Context, name string, values map[string]string) (string, error) {
+	opts := runner.Options{Trigger: b.trigger, Params: values, Quote: true, Policy: userPolicy, Hooks: userHooks}
+	run, err := runner.Prepare(b.r, name, opts)
+	if err != nil {
+		return "", backgroundError(err, nil)
+	}
+	declared := map[string]bool{}
+	for _, c := range run.Set.Commands {
+		for _, p := range registry.FindParams(c.Command) {
+			declared[p] = true
+		}
+	}
+	for k := range values {
+		if !declared[k] {
+			return "", fmt.Errorf("%w: set %s has no parameter %q", errBadParams, name, k)
+		}
+	}
+	if err := (&runParams{values: values}).checkComplete(run.Set); err != nil {
+		return "", fmt.Errorf("%w: %v", errBadParams, err)
+	}
+	cmds, redacted, err := run.Commands()
+	if err != nil {
+		return "", backgroundError(err, errBadParams)
+	}
+	if err := run.Start(ctx); err != nil {
+		return "", backgroundError(err, nil)
+	}
+	ctx, cancel := context.WithTimeout(adapters.WithRunID(ctx, run.ID), b.timeout)
+	h, err := runRedacted(ctx, b.exec, name, cmds, redacted)
+	if err != nil {
+		cancel()
+		return "", run.Finish(context.Background(), err, nil)
+	}
+	em := adapters.NewEventEmitter(run.ID)
+	if b.sink != nil {
+		em.AddSink(b.sink)
+	}
+	em.Emit(adapters.RunEvent{Type: adapters.EventRunStarted, Set: name, Steps: len(cmds)})
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		defer cancel()
+		var runErr error
+		for ev := range h.Events() {
+			if ev.Err != nil {
+				runErr = ev.Err
+			}
+			em.Emit(ev)
+		}
+		runErr = run.Finish(context.Background(), runErr, nil)
+		em.Emit(adapters.RunEvent{Type: adapters.EventRunFinished, Set: name, ExitCode: runner.ExitCode(runErr), Duration: run.Elapsed(), Err: runErr})
+	}()
+	return run.ID, nil
+}
+
+// backgroundError maps why the pipeline did not start a run to the reasons
+// servers respond with. Other errors are wrapped in otherwise when set.
+func backgroundError(err, otherwise error) error {
+	switch {
+	case errors.Is(err, errSetNotFound):
+		return err
+	case errors.Is(err, runner.ErrBusy):
+		return fmt.Errorf("%w: %v", errRunConflict, err)
+	case errors.Is(err, runner.ErrRefused), errors.Is(err, lifecycle.ErrHookFailed):
+		return fmt.Errorf("%w: %v", errRunRefused, err)
+	case otherwise != nil:
+		return fmt.Errorf("%w: %v", otherwise, err)
+	}
+	return err
+}
+
+// wait blocks until every started run has finished.
+func (b *backgroundRunner) wait() { b.wg.Wait() }
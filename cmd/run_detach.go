@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/VoxDroid/krnr/internal/jobs"
+	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/runner"
+)
+
+// spawnJob starts the background process for a job; tests replace it.
+var spawnJob = jobs.Spawn
+
+// startDetached records a job and re-executes `krnr run` for set in a
+// supervised background process that writes to the job's output file.
+func startDetached(cmd *cobra.Command, r *registry.Repository, set string) error {
+	id := jobs.NewID()
+	p, err := jobs.Prepare(id)
+	if err != nil {
+		return err
+	}
+	if err := r.CreateJob(id, set, p.Output); err != nil {
+		return err
+	}
+	args := append([]string{"run", set, "--job-id", id}, passthroughFlags(cmd)...)
+	pid, err := spawnJob(p, args)
+	if err != nil {
+		_, _ = r.MarkJobFinished(id, registry.JobStatusFailed)
+		return err
+	}
+	if err := r.SetJobPID(id, pid); err != nil {
+		return err
+	}
+	fmt.Printf("started job %s (pid %d)\n", id, pid)
+	if !cmd.Flags().Changed("timeout") {
+		fmt.Println("the job runs without a timeout; pass --timeout to limit it")
+	}
+	fmt.Printf("follow with 'krnr attach %s', stop with 'krnr kill %s'\n", id, id)
+	return nil
+}
+
+// passthroughFlags re-renders the flags set on this invocation for the
+// background process, minus the ones handled by the launcher.
+func passthroughFlags(cmd *cobra.Command) []string {
+	var out []string
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		switch f.Name {
+		case "detach", "job-id", "confirm":
+			return
+		}
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			for _, v := range sv.GetSlice() {
+				out = append(out, "--"+f.Name+"="+v)
+			}
+			return
+		}
+		out = append(out, "--"+f.Name+"="+f.Value.String())
+	})
+	return out
+}
+
+// jobRun binds a background job process: stdin is fed from the job's input
+// file (written by `krnr attach`), SIGTERM/interrupt cancel the run, and the
+// result is recorded when the run ends.
+type jobRun struct {
+	r      *registry.Repository
+	id     string
+	cancel context.CancelFunc
+}
+
+func startJobRun(ctx context.Context, r *registry.Repository, id string) (context.Context, *jobRun, error) {
+	p, err := jobs.PathsFor(id)
+	if err != nil {
+		return ctx, nil, err
+	}
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		stop()
+		return ctx, nil, err
+	}
+	go func() {
+		_ = jobs.FeedInput(ctx, p.Input, pw)
+		_ = pw.Close()
+	}()
+	// prompts, step decisions and commands all read os.Stdin
+	os.Stdin = pr
+	stepInput = pr
+	return ctx, &jobRun{r: r, id: id, cancel: stop}, nil
+}
+
+// finish records the job result.
+func (j *jobRun) finish(runErr error) {
+	defer j.cancel()
+	status := registry.JobStatusOK
+	if runErr != nil {
+		status = registry.JobStatusFailed
+	}
+	if err := j.r.FinishJob(j.id, status, runner.ExitCode(runErr)); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: record job result: %v\n", err)
+	}
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/executor"
+	"github.com/VoxDroid/krnr/internal/registry"
+)
+
+func TestRunOutputJSONLEmitsEvents(t *testing.T) {
+	setupTempDB(t)
+
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	defer func() { _ = dbConn.Close() }()
+
+	r := registry.NewRepository(dbConn)
+	if _, err := r.CreateCommandSet("events", nil, nil, nil, []string{"echo one", "echo two"}); err != nil {
+		t.Fatalf("CreateCommandSet: %v", err)
+	}
+
+	origFactory := execFactory
+	defer func() { execFactory = origFactory }()
+	execFactory = func(_, _ bool) executor.Runner { return &fakeRunner{} }
+
+	_ = runCmd.Flags().Set("dry-run", "false")
+	_ = runCmd.Flags().Set("confirm", "false")
+	defer func() { _ = runCmd.Flags().Set("output", "text") }()
+	out, errOut := captureOutput(func() {
+		rootCmd.SetArgs([]string{"run", "events", "--output", "jsonl"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+	})
+	if errOut != "" {
+		t.Fatalf("expected no stderr in jsonl mode, got %q", errOut)
+	}
+
+	var types []string
+	var runID string
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		var ev map[string]any
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			t.Fatalf("non-JSON line %q: %v", line, err)
+		}
+		if runID == "" {
+			runID, _ = ev["run_id"].(string)
+		}
+		if ev["run_id"] != runID {
+			t.Fatalf("run_id changed within a run: %v", ev)
+		}
+		typ := ev["type"].(string)
+		if typ == "output" {
+			typ += ":" + ev["stream"].(string)
+		}
+		types = append(types, typ)
+	}
+	want := "run_started step_started output:stdout output:stderr step_finished step_started output:stdout output:stderr step_finished run_finished"
+	if got := strings.Join(types, " "); got != want {
+		t.Fatalf("unexpected event sequence:\n got: %s\nwant: %s", got, want)
+	}
+}
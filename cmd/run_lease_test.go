@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/executor"
+	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/runner"
+)
+
+func TestRunExclusiveSetLease(t *testing.T) {
+	setupTempDB(t)
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	defer func() { _ = dbConn.Close() }()
+	r := registry.NewRepository(dbConn)
+	if _, err := r.CreateCommandSet("deploy", nil, nil, nil, []string{"echo deploy"}); err != nil {
+		t.Fatalf("CreateCommandSet: %v", err)
+	}
+	if err := r.SetExclusive("deploy", true); err != nil {
+		t.Fatalf("SetExclusive: %v", err)
+	}
+
+	rec := &recordingRunner{}
+	origFactory, origRetry := execFactory, runner.LeaseRetry
+	defer func() { execFactory, runner.LeaseRetry = origFactory, origRetry }()
+	execFactory = func(_, _ bool) executor.Runner { return rec }
+	runner.LeaseRetry = 20 * time.Millisecond
+	resetRunFlags()
+	defer resetRunFlags()
+
+	other := registry.Lease{SetName: "deploy", Token: "other", Owner: "alice <alice@example.com>", PID: 4242, Host: "build-01"}
+	if ok, _, err := r.AcquireLease(other, time.Minute); err != nil || !ok {
+		t.Fatalf("AcquireLease: %v %v", ok, err)
+	}
+
+	// fail fast, naming the holder
+	rootCmd.SetArgs([]string{"run", "deploy"})
+	err = rootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "alice <alice@example.com> (pid 4242 on build-01)") {
+		t.Fatalf("expected lease error naming holder, got %v", err)
+	}
+	if len(rec.cmds) != 0 {
+		t.Fatalf("locked set must not run, ran %v", rec.cmds)
+	}
+
+	// --wait queues until the holder releases
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_ = r.ReleaseLease("deploy", "other")
+	}()
+	var runErr error
+	_, errOut := captureOutput(func() {
+		rootCmd.SetArgs([]string{"run", "deploy", "--wait"})
+		runErr = rootCmd.Execute()
+	})
+	if runErr != nil {
+		t.Fatalf("run --wait failed: %v", runErr)
+	}
+	if !strings.Contains(errOut, "waiting for lease on deploy held by alice") || len(rec.cmds) != 1 {
+		t.Fatalf("expected queued run, stderr %q ran %v", errOut, rec.cmds)
+	}
+	if l, _ := r.GetLease("deploy"); l != nil {
+		t.Fatalf("lease must be released after the run, got %+v", l)
+	}
+
+	// a lease left by a dead process on this host is reclaimed
+	host, _ := os.Hostname()
+	dead := registry.Lease{SetName: "deploy", Token: "dead", Owner: "bob", PID: 999999, Host: host}
+	if ok, _, _ := r.AcquireLease(dead, time.Minute); !ok {
+		t.Fatalf("expected to plant dead lease")
+	}
+	_ = runCmd.Flags().Set("wait", "false")
+	rootCmd.SetArgs([]string{"run", "deploy"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("expected dead local lease to be reclaimed, got %v", err)
+	}
+}
@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/VoxDroid/krnr/internal/progress"
+	"github.com/VoxDroid/krnr/internal/registry"
+)
+
+// attachProgress adds a progress renderer for the run. ETA estimates come
+// from previous successful runs of the set.
+func (s *runSession) attachProgress(r *registry.Repository, tty bool) {
+	commands := make([]string, len(s.set.Commands))
+	for i, c := range s.set.Commands {
+		commands[i] = c.Command
+	}
+	estimates, _ := r.EstimateStepDurations(s.set.Name, len(commands))
+	s.progress = progress.New(os.Stdout, tty, commands, estimates)
+	s.events.AddSink(s.progress.Handle)
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/executor"
+	"github.com/VoxDroid/krnr/internal/registry"
+)
+
+func TestRunProgressRecordsHistoryAndPrintsSummary(t *testing.T) {
+	setupTempDB(t)
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	defer func() { _ = dbConn.Close() }()
+	r := registry.NewRepository(dbConn)
+	if _, err := r.CreateCommandSet("deploy", nil, nil, nil, []string{"echo one", "echo two"}); err != nil {
+		t.Fatalf("CreateCommandSet: %v", err)
+	}
+
+	origFactory := execFactory
+	defer func() { execFactory = origFactory }()
+	execFactory = func(_, _ bool) executor.Runner { return &fakeRunner{} }
+
+	resetRunFlags()
+	defer resetRunFlags()
+	out, _ := captureOutput(func() {
+		rootCmd.SetArgs([]string{"run", "deploy", "--progress"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+	})
+	for _, want := range []string{"[1/2] -> echo one", "[2/2] ok in", "STEP", "COMMAND"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in output:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "\n-> echo") {
+		t.Fatalf("progress mode should replace the -> lines:\n%s", out)
+	}
+
+	runs, err := r.ListRuns("deploy", 0)
+	if err != nil {
+		t.Fatalf("ListRuns: %v", err)
+	}
+	if len(runs) != 1 || runs[0].Status != registry.RunStatusOK || len(runs[0].StepDurations) != 2 {
+		t.Fatalf("expected one recorded run with 2 steps, got %+v", runs)
+	}
+
+	resetRunFlags()
+	rootCmd.SetArgs([]string{"run", "deploy", "--progress", "--output", "jsonl"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "--progress") {
+		t.Fatalf("expected --progress/jsonl conflict error, got %v", err)
+	}
+}
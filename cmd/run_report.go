@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/VoxDroid/krnr/internal/report"
+)
+
+// reportSpec is a parsed --report format=path flag.
+type reportSpec struct {
+	format string
+	path   string
+}
+
+// parseReportFlags parses the repeatable --report flag.
+func parseReportFlags(cmd *cobra.Command) ([]reportSpec, error) {
+	vals, _ := cmd.Flags().GetStringArray("report")
+	var out []reportSpec
+	for _, v := range vals {
+		parts := strings.SplitN(v, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return nil, fmt.Errorf("invalid --report value: %s (expected format=path, e.g. junit=results.xml)", v)
+		}
+		if parts[0] != "junit" {
+			return nil, fmt.Errorf("unsupported report format %q (supported: junit)", parts[0])
+		}
+		out = append(out, reportSpec{format: parts[0], path: parts[1]})
+	}
+	return out, nil
+}
+
+// ciProviderFromFlags returns the CI provider selected by --ci, or "" when CI
+// mode is off. A bare --ci means "auto".
+func ciProviderFromFlags(cmd *cobra.Command) (string, error) {
+	v, _ := cmd.Flags().GetString("ci")
+	if v == "" {
+		return "", nil
+	}
+	return report.ResolveCI(v)
+}
+
+// attachReports registers collectors for the requested reports and returns a
+// function that writes them once the run has finished.
+func (s *runSession) attachReports(specs []reportSpec) func() error {
+	if len(specs) == 0 {
+		return func() error { return nil }
+	}
+	s.captureStderr = true
+	commands := make([]string, len(s.set.Commands))
+	for i, c := range s.set.Commands {
+		commands[i] = c.Command
+	}
+	junit := report.NewJUnit(s.set.Name, commands)
+	s.events.AddSink(junit.Handle)
+	return func() error {
+		for _, spec := range specs {
+			if err := junit.WriteFile(spec.path); err != nil {
+				return fmt.Errorf("write %s report: %w", spec.format, err)
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/executor"
+	"github.com/VoxDroid/krnr/internal/registry"
+)
+
+// failingRunner fails every command containing "fail".
+type failingRunner struct{ ran []string }
+
+func (f *failingRunner) Execute(_ context.Context, command, _ string, _ io.Reader, stdout io.Writer, stderr io.Writer) error {
+	f.ran = append(f.ran, command)
+	_, _ = fmt.Fprintf(stdout, "ran %s\n", command)
+	if strings.Contains(command, "fail") {
+		_, _ = fmt.Fprintln(stderr, "something broke")
+		return errors.New("command failed: exit status 1")
+	}
+	return nil
+}
+
+func resetRunFlags() {
+	for name, v := range map[string]string{"dry-run": "false", "confirm": "false", "ci": "", "output": "text", "show-stderr": "false", "suppress-command": "false", "progress": "false", "step": "false", "detach": "false", "wait": "false", "timeout": "30s", "trigger": "manual"} {
+		_ = runCmd.Flags().Set(name, v)
+	}
+	_ = runCmd.Flags().Lookup("report").Value.(interface{ Replace([]string) error }).Replace(nil)
+}
+
+// keepParamFlag restores the --param values after the test; other tests
+// depend on values left behind by earlier runs.
+func keepParamFlag(t *testing.T) {
+	v := runCmd.Flags().Lookup("param").Value.(interface {
+		GetSlice() []string
+		Replace([]string) error
+	})
+	old := v.GetSlice()
+	t.Cleanup(func() { _ = v.Replace(old) })
+}
+
+func TestRunWritesJUnitReportAndCIMarkers(t *testing.T) {
+	home := setupTempDB(t)
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	defer func() { _ = dbConn.Close() }()
+	r := registry.NewRepository(dbConn)
+	if _, err := r.CreateCommandSet("smoke", nil, nil, nil, []string{"echo ok", "do fail", "echo never"}); err != nil {
+		t.Fatalf("CreateCommandSet: %v", err)
+	}
+
+	origFactory := execFactory
+	defer func() { execFactory = origFactory }()
+	fr := &failingRunner{}
+	execFactory = func(_, _ bool) executor.Runner { return fr }
+
+	resetRunFlags()
+	defer resetRunFlags()
+	reportPath := filepath.Join(home, "junit.xml")
+	out, _ := captureOutput(func() {
+		rootCmd.SetArgs([]string{"run", "smoke", "--ci=github", "--report", "junit=" + reportPath})
+		if err := rootCmd.Execute(); err == nil {
+			t.Fatalf("expected run to fail")
+		}
+	})
+	if !strings.Contains(out, "::group::step 2: do fail") || !strings.Contains(out, "::error title=krnr smoke::step 2 failed") {
+		t.Fatalf("expected github markers, got:\n%s", out)
+	}
+	b, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("expected junit report: %v", err)
+	}
+	xml := string(b)
+	if !strings.Contains(xml, `failures="1"`) || !strings.Contains(xml, `skipped="1"`) || !strings.Contains(xml, "something broke") {
+		t.Fatalf("unexpected report:\n%s", xml)
+	}
+}
+
+func TestRunCIModeFailsFastOnMissingParams(t *testing.T) {
+	setupTempDB(t)
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	defer func() { _ = dbConn.Close() }()
+	r := registry.NewRepository(dbConn)
+	if _, err := r.CreateCommandSet("deploy", nil, nil, nil, []string{"echo start", "deploy {{env}}"}); err != nil {
+		t.Fatalf("CreateCommandSet: %v", err)
+	}
+
+	origFactory := execFactory
+	defer func() { execFactory = origFactory }()
+	fr := &failingRunner{}
+	execFactory = func(_, _ bool) executor.Runner { return fr }
+
+	resetRunFlags()
+	defer resetRunFlags()
+	rootCmd.SetArgs([]string{"run", "deploy", "--ci"})
+	err = rootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "missing value for parameter(s) env") {
+		t.Fatalf("expected missing param error, got %v", err)
+	}
+	if len(fr.ran) != 0 {
+		t.Fatalf("expected nothing to run in CI mode with missing params, ran %v", fr.ran)
+	}
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/VoxDroid/krnr/internal/tui/adapters"
+	interactive "github.com/VoxDroid/krnr/internal/utils"
+)
+
+// stepInput is the source of step-mode answers; tests replace it.
+var stepInput io.Reader = os.Stdin
+
+// openShell drops the user into an interactive shell; tests replace it.
+var openShell = interactive.OpenShell
+
+// stepper pauses a run before steps (every step with --step, otherwise only
+// at stored breakpoints) and asks what to do.
+type stepper struct {
+	all         bool
+	breakpoints map[int]bool
+	in          *bufio.Reader
+	out         io.Writer
+}
+
+func newStepper(all bool, breakpoints []int) *stepper {
+	bp := map[int]bool{}
+	for _, b := range breakpoints {
+		bp[b] = true
+	}
+	return &stepper{all: all, breakpoints: bp, in: bufio.NewReader(stepInput), out: os.Stderr}
+}
+
+// active reports whether the run can pause at all.
+func (st *stepper) active() bool { return st != nil && (st.all || len(st.breakpoints) > 0) }
+
+// ShouldPause reports whether the run pauses before step.
+func (st *stepper) ShouldPause(step int) bool {
+	return st != nil && (st.all || st.breakpoints[step])
+}
+
+// Pause shows the substituted step and loops until the user decides. It
+// returns the command to run (changed by "edit once"), its display form and
+// whether the step should be skipped. Aborting returns adapters.ErrRunAborted.
+func (st *stepper) Pause(step, total int, cmdText, redacted string) (string, string, bool, error) {
+	label := "step"
+	if st.breakpoints[step] {
+		label = "breakpoint"
+	}
+	_, _ = fmt.Fprintf(st.out, "== %s %d/%d ==\n   %s\n", label, step, total, redacted)
+	for {
+		_, _ = fmt.Fprint(st.out, "[r]un, [s]kip, [e]dit once, s[h]ell, [a]bort: ")
+		line, err := st.in.ReadString('\n')
+		if err != nil && strings.TrimSpace(line) == "" {
+			// no more input (e.g., stdin is not a terminal): never run unapproved
+			_, _ = fmt.Fprintln(st.out)
+			return "", "", false, fmt.Errorf("%w at step %d: no input", adapters.ErrRunAborted, step)
+		}
+		action, ok := adapters.ParseStepAction(line)
+		if !ok {
+			continue
+		}
+		switch action {
+		case adapters.StepRun:
+			return cmdText, redacted, false, nil
+		case adapters.StepSkip:
+			_, _ = fmt.Fprintf(st.out, "skipped step %d\n", step)
+			return "", "", true, nil
+		case adapters.StepEdit:
+			_, _ = fmt.Fprint(st.out, "command for this run: ")
+			edited, _ := st.in.ReadString('\n')
+			if edited = strings.TrimSpace(edited); edited == "" {
+				continue
+			}
+			return edited, edited, false, nil
+		case adapters.StepShell:
+			if err := openShell(); err != nil {
+				var ee *exec.ExitError
+				if !errors.As(err, &ee) {
+					_, _ = fmt.Fprintf(st.out, "shell: %v\n", err)
+				}
+			}
+			_, _ = fmt.Fprintf(st.out, "== back at %s %d/%d ==\n   %s\n", label, step, total, redacted)
+		case adapters.StepAbort:
+			return "", "", false, fmt.Errorf("%w at step %d", adapters.ErrRunAborted, step)
+		}
+	}
+}
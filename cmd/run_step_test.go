@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/executor"
+	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/tui/adapters"
+)
+
+// recordingRunner records every command it is asked to execute.
+type recordingRunner struct{ cmds []string }
+
+func (r *recordingRunner) Execute(_ context.Context, command, _ string, _ io.Reader, _ io.Writer, _ io.Writer) error {
+	r.cmds = append(r.cmds, command)
+	return nil
+}
+
+func TestRunStepModeActionsAndBreakpoints(t *testing.T) {
+	setupTempDB(t)
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	defer func() { _ = dbConn.Close() }()
+	r := registry.NewRepository(dbConn)
+	if _, err := r.CreateCommandSet("risky", nil, nil, nil, []string{"echo one", "echo {{who}}", "echo three", "echo four"}); err != nil {
+		t.Fatalf("CreateCommandSet: %v", err)
+	}
+
+	rec := &recordingRunner{}
+	origFactory, origInput, origShell := execFactory, stepInput, openShell
+	defer func() { execFactory, stepInput, openShell = origFactory, origInput, origShell }()
+	execFactory = func(_, _ bool) executor.Runner { return rec }
+	shellOpened := false
+	openShell = func() error { shellOpened = true; return nil }
+
+	// step 1: run; step 2: shell then edit; step 3: skip; step 4: abort
+	stepInput = strings.NewReader("r\nh\ne\necho edited\ns\nx\na\n")
+	keepParamFlag(t)
+	resetRunFlags()
+	defer resetRunFlags()
+	var runErr error
+	_, errOut := captureOutput(func() {
+		rootCmd.SetArgs([]string{"run", "risky", "--step", "--param", "who=world"})
+		runErr = rootCmd.Execute()
+	})
+	if !errors.Is(runErr, adapters.ErrRunAborted) {
+		t.Fatalf("expected aborted run, got %v", runErr)
+	}
+	if got := strings.Join(rec.cmds, "|"); got != "echo one|echo edited" {
+		t.Fatalf("unexpected executed commands: %s", got)
+	}
+	if !shellOpened {
+		t.Fatalf("expected shell to be opened")
+	}
+	if !strings.Contains(errOut, "== step 2/4 ==\n   echo world") || !strings.Contains(errOut, "skipped step 3") {
+		t.Fatalf("unexpected step prompts:\n%s", errOut)
+	}
+
+	// breakpoints pause normal runs only at the stored steps
+	if err := r.SetBreakpoint("risky", 3); err != nil {
+		t.Fatalf("SetBreakpoint: %v", err)
+	}
+	rec.cmds = nil
+	stepInput = strings.NewReader("s\n")
+	resetRunFlags()
+	_, errOut = captureOutput(func() {
+		rootCmd.SetArgs([]string{"run", "risky", "--param", "who=world"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+	})
+	if got := strings.Join(rec.cmds, "|"); got != "echo one|echo world|echo four" {
+		t.Fatalf("unexpected executed commands with breakpoint: %s", got)
+	}
+	if !strings.Contains(errOut, "== breakpoint 3/4 ==") {
+		t.Fatalf("expected breakpoint prompt, got:\n%s", errOut)
+	}
+
+	// CI mode never pauses and rejects --step
+	rec.cmds = nil
+	resetRunFlags()
+	rootCmd.SetArgs([]string{"run", "risky", "--ci=plain", "--param", "who=world"})
+	_, _ = captureOutput(func() {
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("ci run failed: %v", err)
+		}
+	})
+	if len(rec.cmds) != 4 {
+		t.Fatalf("expected CI run to ignore breakpoints, ran %v", rec.cmds)
+	}
+	resetRunFlags()
+	rootCmd.SetArgs([]string{"run", "risky", "--ci=plain", "--step"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "--step") {
+		t.Fatalf("expected --step/--ci conflict, got %v", err)
+	}
+}
+
+// slowRunner takes d to run each command unless its context ends first.
+type slowRunner struct {
+	d    time.Duration
+	cmds []string
+}
+
+func (s *slowRunner) Execute(ctx context.Context, command, _ string, _ io.Reader, _ io.Writer, _ io.Writer) error {
+	select {
+	case <-time.After(s.d):
+		s.cmds = append(s.cmds, command)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// slowReader answers after a delay, like someone thinking at a prompt.
+type slowReader struct {
+	delay time.Duration
+	r     io.Reader
+}
+
+func (s *slowReader) Read(p []byte) (int, error) {
+	time.Sleep(s.delay)
+	return s.r.Read(p)
+}
+
+func TestRunTimeoutExcludesPauses(t *testing.T) {
+	setupTempDB(t)
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	defer func() { _ = dbConn.Close() }()
+	r := registry.NewRepository(dbConn)
+	if _, err := r.CreateCommandSet("slow", nil, nil, nil, []string{"echo one", "echo two"}); err != nil {
+		t.Fatalf("CreateCommandSet: %v", err)
+	}
+
+	sr := &slowRunner{d: 20 * time.Millisecond}
+	origFactory, origInput := execFactory, stepInput
+	defer func() { execFactory, stepInput = origFactory, origInput }()
+	execFactory = func(_, _ bool) executor.Runner { return sr }
+	resetRunFlags()
+	defer resetRunFlags()
+
+	// a pause longer than --timeout does not count against it
+	stepInput = &slowReader{delay: 300 * time.Millisecond, r: strings.NewReader("r\nr\n")}
+	_, _ = captureOutput(func() {
+		rootCmd.SetArgs([]string{"run", "slow", "--step", "--timeout", "200ms"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+	})
+	if got := strings.Join(sr.cmds, "|"); got != "echo one|echo two" {
+		t.Fatalf("unexpected executed commands: %s", got)
+	}
+
+	// the commands share the budget
+	sr.d, sr.cmds = 150*time.Millisecond, nil
+	resetRunFlags()
+	_, _ = captureOutput(func() {
+		rootCmd.SetArgs([]string{"run", "slow", "--timeout", "200ms"})
+		err = rootCmd.Execute()
+	})
+	if err == nil || !strings.Contains(err.Error(), "run timed out after 200ms") || len(sr.cmds) != 1 {
+		t.Fatalf("expected the second command to time out, got %v (ran %v)", err, sr.cmds)
+	}
+}
@@ -109,6 +109,7 @@ Note: When using Windows shells, be sure to properly quote embedded double-quote
 		}
 
 		fmt.Printf("saved '%s' with %d commands\n", name, len(cmds))
+		reportSaved(r, name)
 		return nil
 	},
 }
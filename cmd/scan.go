@@ -0,0 +1,284 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/executor"
+	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/security"
+	interactive "github.com/VoxDroid/krnr/internal/utils"
+)
+
+// secretScanner is the registry's CommandScanner: it finds secrets in
+// commands about to be saved and, depending on the secrets setting, asks
+// to extract them into a parameter or vault reference, warns, or refuses
+// the save.
+type secretScanner struct {
+	mode, vault string
+	// prompt asks a question; nil when the command cannot ask.
+	prompt func(msg string) string
+	out    io.Writer
+	// kept are secrets the user chose to keep; they are not asked about
+	// again.
+	kept map[string]bool
+}
+
+func newSecretScanner(prompt func(string) string, out io.Writer) *secretScanner {
+	return &secretScanner{mode: settings.Secrets, vault: settings.Vault, prompt: prompt, out: out, kept: map[string]bool{}}
+}
+
+// loadSecretScanner installs the secret scanner for cmd. Only commands that
+// save what the user typed ask what to do, and only on a terminal; the
+// others warn.
+func loadSecretScanner(cmd *cobra.Command) {
+	var prompt func(string) string
+	if asksAboutSecrets(cmd) && executor.IsTerminal(os.Stdin.Fd()) {
+		prompt = interactive.Prompt
+	}
+	registry.SetDefaultCommandScanner(newSecretScanner(prompt, os.Stderr).scan)
+}
+
+func asksAboutSecrets(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c == saveCmd || c == recordCmd || c == editCmd || c == importCmd {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *secretScanner) scan(set string, commands []string) ([]string, error) {
+	out := append([]string(nil), commands...)
+	for i := range out {
+		for {
+			sec, ok := s.next(out[i])
+			if !ok {
+				break
+			}
+			switch {
+			case s.mode == "block":
+				return nil, fmt.Errorf("step %d of '%s' contains %s; replace it with a parameter such as {{%s}} (the secrets setting is 'block')", i+1, set, describeSecret(sec), sec.ParamName())
+			case s.mode == "warn" || s.prompt == nil:
+				_, _ = fmt.Fprintf(s.out, "warning: step %d of '%s' stores %s in plain text; replace it with a parameter such as {{%s}} (see 'krnr scan')\n%s\n", i+1, set, describeSecret(sec), sec.ParamName(), maskedHighlight(out[i], sec))
+				s.kept[out[i][sec.Start:sec.End]] = true
+			default:
+				c, err := s.ask(set, i, out[i], sec)
+				if err != nil {
+					return nil, err
+				}
+				out[i] = c
+			}
+		}
+	}
+	return out, nil
+}
+
+// next returns the first secret in c the user did not choose to keep.
+func (s *secretScanner) next(c string) (security.Secret, bool) {
+	for _, sec := range security.FindSecrets(c) {
+		if !s.kept[c[sec.Start:sec.End]] {
+			return sec, true
+		}
+	}
+	return security.Secret{}, false
+}
+
+// ask offers to extract sec from step i of set and returns the command to
+// save.
+func (s *secretScanner) ask(set string, i int, c string, sec security.Secret) (string, error) {
+	_, _ = fmt.Fprintf(s.out, "step %d of '%s' contains %s:\n%s\n", i+1, set, describeSecret(sec), maskedHighlight(c, sec))
+	for {
+		switch strings.ToLower(s.prompt("Extract it into a [p]arameter, a [v]ault reference, [k]eep it or [a]bort? [p]")) {
+		case "", "p", "parameter":
+			name := s.askName(sec)
+			_, _ = fmt.Fprintf(s.out, "extracted into {{%s}}; supply it with --param %s=env:VAR or when asked\n", name, name)
+			return c[:sec.Start] + "{{" + name + "}}" + c[sec.End:], nil
+		case "v", "vault":
+			if s.vault == "" {
+				_, _ = fmt.Fprintln(s.out, "no vault command is configured; set one with: krnr config set vault 'pass show krnr/{name}'")
+				continue
+			}
+			name := s.askName(sec)
+			ref := vaultReference(s.vault, name)
+			_, _ = fmt.Fprintf(s.out, "extracted into %s; store the secret in your vault as %q\n", ref, name)
+			if strings.Count(c[:sec.Start], "'")%2 == 1 {
+				_, _ = fmt.Fprintln(s.out, "warning: the reference is inside single quotes and will not be expanded; use double quotes")
+			}
+			return c[:sec.Start] + ref + c[sec.End:], nil
+		case "k", "keep":
+			s.kept[c[sec.Start:sec.End]] = true
+			return c, nil
+		case "a", "abort":
+			return "", fmt.Errorf("save aborted: step %d of '%s' contains %s", i+1, set, describeSecret(sec))
+		}
+	}
+}
+
+var secretParamNameRe = regexp.MustCompile(`^[a-zA-Z0-9_-][a-zA-Z0-9_.-]*$`)
+
+func (s *secretScanner) askName(sec security.Secret) string {
+	for {
+		name := s.prompt(fmt.Sprintf("Name [%s]", sec.ParamName()))
+		if name == "" {
+			return sec.ParamName()
+		}
+		if secretParamNameRe.MatchString(name) {
+			if !security.IsSecretParamName(name) {
+				_, _ = fmt.Fprintf(s.out, "note: values of %q are not redacted in logs; names containing token, secret, key or password are\n", name)
+			}
+			return name
+		}
+		_, _ = fmt.Fprintln(s.out, "use letters, digits, '_', '-' and '.'")
+	}
+}
+
+// vaultReference is the shell command substitution that reads the secret
+// name with the vault command.
+func vaultReference(vault, name string) string {
+	if strings.Contains(vault, "{name}") {
+		return "$(" + strings.ReplaceAll(vault, "{name}", name) + ")"
+	}
+	return "$(" + vault + " " + name + ")"
+}
+
+func describeSecret(sec security.Secret) string {
+	if strings.ContainsRune("aeiouAEIOU", rune(sec.Kind[0])) {
+		return "an " + sec.Kind
+	}
+	return "a " + sec.Kind
+}
+
+// maskedHighlight underlines sec in c with all but its first characters
+// hidden.
+func maskedHighlight(c string, sec security.Secret) string {
+	v := c[sec.Start:sec.End]
+	mask := "…"
+	if len(v) > 8 {
+		mask = v[:4] + "…"
+	}
+	return security.Underline(c[:sec.Start]+mask+c[sec.End:], sec.Start, sec.Start+len(mask))
+}
+
+var scanCmd = &cobra.Command{
+	Use:   "scan [name]",
+	Short: "Find secrets in command sets and their version history",
+	Long: `Find secrets (tokens, keys, passwords and random-looking strings) in the
+commands of a set, or of every set without a name, and in their version
+history. scan exits non-zero when it finds any.
+
+Saving a command with a secret asks to extract it into a {{parameter}} or a
+vault reference (see the 'secrets' and 'vault' settings), but older versions
+keep it: --purge-history replaces the secrets in version snapshots with
+<redacted>. Without a name it also purges snapshots of deleted sets.
+  krnr scan
+  krnr scan deploy --purge-history --yes`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		purge, _ := cmd.Flags().GetBool("purge-history")
+		yes, _ := cmd.Flags().GetBool("yes")
+
+		dbConn, err := db.InitDB()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = dbConn.Close() }()
+		r := registry.NewRepository(dbConn)
+
+		var sets []registry.CommandSet
+		if len(args) == 1 {
+			cs, err := r.GetCommandSetByName(args[0])
+			if err != nil {
+				return err
+			}
+			if cs == nil {
+				return fmt.Errorf("command set not found: %s", args[0])
+			}
+			sets = append(sets, *cs)
+		} else {
+			list, err := r.ListCommandSets()
+			if err != nil {
+				return err
+			}
+			for _, l := range list {
+				cs, err := r.GetCommandSetByName(l.Name)
+				if err != nil {
+					return err
+				}
+				if cs != nil {
+					sets = append(sets, *cs)
+				}
+			}
+		}
+
+		current, history := 0, 0
+		for _, cs := range sets {
+			for i, c := range cs.Commands {
+				for _, sec := range security.FindSecrets(c.Command) {
+					current++
+					fmt.Printf("%s step %d: %s\n%s\n", cs.Name, i+1, sec.Kind, maskedHighlight(c.Command, sec))
+				}
+			}
+			versions, err := r.ListVersions(cs.ID)
+			if err != nil {
+				return err
+			}
+			for _, v := range versions {
+				for i, c := range v.Commands {
+					for _, sec := range security.FindSecrets(c) {
+						history++
+						fmt.Printf("%s version %d step %d: %s\n", cs.Name, v.Version, i+1, sec.Kind)
+					}
+				}
+			}
+		}
+		if current+history == 0 {
+			fmt.Printf("no secrets found in %d set(s)\n", len(sets))
+		} else {
+			fmt.Printf("found %d secret(s) in commands and %d in version history\n", current, history)
+		}
+
+		if purge {
+			if !yes {
+				if !executor.IsTerminal(os.Stdin.Fd()) {
+					return fmt.Errorf("--purge-history rewrites version history; pass --yes to confirm")
+				}
+				if !interactive.Confirm("Replace secrets in version snapshots with <redacted>? This cannot be undone") {
+					return fmt.Errorf("aborted")
+				}
+			}
+			var id int64
+			if len(sets) == 1 && len(args) == 1 {
+				id = sets[0].ID
+			}
+			n, err := r.RewriteVersions(id, func(c string) string {
+				redacted, _ := security.RedactSecrets(c)
+				return redacted
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Printf("redacted secrets in %d version snapshot(s)\n", n)
+			history = 0
+		}
+		if current > 0 {
+			return fmt.Errorf("%d command(s) contain secrets; extract them with 'krnr edit <name>'", current)
+		}
+		if history > 0 {
+			return fmt.Errorf("version history contains %d secret(s); remove them with --purge-history", history)
+		}
+		return nil
+	},
+}
+
+func init() {
+	scanCmd.Flags().Bool("purge-history", false, "Replace secrets in version snapshots with <redacted>")
+	scanCmd.Flags().Bool("yes", false, "Do not ask before purging history")
+	rootCmd.AddCommand(scanCmd)
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/registry"
+)
+
+func TestSecretScanner(t *testing.T) {
+	answers := func(a ...string) func(string) string {
+		return func(string) string {
+			if len(a) == 0 {
+				t.Fatal("unexpected prompt")
+			}
+			next := a[0]
+			a = a[1:]
+			return next
+		}
+	}
+	cmds := []string{"mysql --password=hunter22 -e 'select 1'", "echo ok"}
+
+	var out bytes.Buffer
+	s := &secretScanner{mode: "ask", prompt: answers("", ""), out: &out, kept: map[string]bool{}}
+	got, err := s.scan("db", cmds)
+	if err != nil || got[0] != "mysql --password={{password}} -e 'select 1'" || got[1] != "echo ok" {
+		t.Fatalf("extract into a parameter: %q %v", got, err)
+	}
+	if !strings.Contains(out.String(), "step 1 of 'db' contains a literal value of password") || strings.Contains(out.String(), "hunter22") {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+
+	s = &secretScanner{mode: "ask", vault: "pass show krnr/{name}", prompt: answers("v", "db_pass"), out: &out, kept: map[string]bool{}}
+	if got, err = s.scan("db", cmds); err != nil || got[0] != "mysql --password=$(pass show krnr/db_pass) -e 'select 1'" {
+		t.Fatalf("extract into a vault reference: %q %v", got, err)
+	}
+
+	s = &secretScanner{mode: "ask", prompt: answers("k"), out: &out, kept: map[string]bool{}}
+	if got, err = s.scan("db", cmds); err != nil || got[0] != cmds[0] {
+		t.Fatalf("keep: %q %v", got, err)
+	}
+	s = &secretScanner{mode: "ask", prompt: answers("a"), out: &out, kept: map[string]bool{}}
+	if _, err = s.scan("db", cmds); err == nil || !strings.Contains(err.Error(), "save aborted") {
+		t.Fatalf("expected abort, got %v", err)
+	}
+
+	out.Reset()
+	s = &secretScanner{mode: "ask", out: &out, kept: map[string]bool{}}
+	if got, err = s.scan("db", cmds); err != nil || got[0] != cmds[0] || !strings.Contains(out.String(), "warning: step 1 of 'db' stores") {
+		t.Fatalf("warn without a terminal: %q %v %q", got, err, out.String())
+	}
+	s = &secretScanner{mode: "block", out: &out, kept: map[string]bool{}}
+	if _, err = s.scan("db", cmds); err == nil || !strings.Contains(err.Error(), "{{password}}") {
+		t.Fatalf("expected block to refuse, got %v", err)
+	}
+}
+
+func TestScanPurgeHistory(t *testing.T) {
+	_ = setupTempDB(t)
+	t.Cleanup(func() {
+		_ = scanCmd.Flags().Set("purge-history", "false")
+		_ = scanCmd.Flags().Set("yes", "false")
+	})
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = dbConn.Close() }()
+	r := registry.NewRepository(dbConn)
+	r.SetCommandScanner(nil)
+	if _, err := r.CreateCommandSet("db", nil, nil, nil, []string{"mysql --password=hunter22 db"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ReplaceCommands(mustSetID(t, r, "db"), []string{"mysql --password={{db_password}} db"}); err != nil {
+		t.Fatal(err)
+	}
+	run := func(args ...string) (string, error) {
+		var err error
+		out, _ := captureOutput(func() {
+			rootCmd.SetArgs(args)
+			err = rootCmd.Execute()
+		})
+		return out, err
+	}
+
+	out, err := run("scan", "db")
+	if err == nil || !strings.Contains(err.Error(), "--purge-history") || !strings.Contains(out, "db version 1 step 1: literal value of password") {
+		t.Fatalf("expected the old version to be reported: %q %v", out, err)
+	}
+	out, err = run("scan", "db", "--purge-history", "--yes")
+	if err != nil || !strings.Contains(out, "redacted secrets in 1 version snapshot(s)") {
+		t.Fatalf("purge: %q %v", out, err)
+	}
+	versions, err := r.ListVersions(mustSetID(t, r, "db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range versions {
+		for _, c := range v.Commands {
+			if strings.Contains(c, "hunter22") {
+				t.Fatalf("version %d still has the secret: %q", v.Version, c)
+			}
+		}
+	}
+	_ = scanCmd.Flags().Set("purge-history", "false")
+	if out, err = run("scan", "db"); err != nil || !strings.Contains(out, "no secrets found in 1 set(s)") {
+		t.Fatalf("scan after purge: %q %v", out, err)
+	}
+}
+
+func mustSetID(t *testing.T, r *registry.Repository, name string) int64 {
+	t.Helper()
+	cs, err := r.GetCommandSetByName(name)
+	if err != nil || cs == nil {
+		t.Fatalf("get %s: %v", name, err)
+	}
+	return cs.ID
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/schedule"
+)
+
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Manage cron schedules for command sets",
+	Long:  "Manage cron schedules: add, list, rm. Schedules are stored in the registry and executed by 'krnr scheduler'.",
+}
+
+var scheduleAddCmd = &cobra.Command{
+	Use:   "add <set-name> <cron>",
+	Short: "Run a command set on a cron schedule",
+	Long: `Run a command set on a five-field cron schedule (minute hour day-of-month month day-of-week), evaluated in local time.
+
+Examples:
+  krnr schedule add backup "0 3 * * *" --param target=/mnt/backup
+  krnr schedule add sync "*/15 9-17 * * mon-fri" --timeout 5m
+  krnr schedule add report @weekly`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, expr := args[0], args[1]
+		params, _ := cmd.Flags().GetStringArray("param")
+		timeout := flagOrSetting(cmd, "timeout", settings.Timeout)
+		if timeout <= 0 {
+			return fmt.Errorf("--timeout must be positive")
+		}
+		c, err := schedule.ParseCron(expr)
+		if err != nil {
+			return err
+		}
+		if _, err := parseParamFlags(params); err != nil {
+			return err
+		}
+		next := c.Next(time.Now())
+		if next.IsZero() {
+			return fmt.Errorf("cron expression %q never matches", expr)
+		}
+
+		dbConn, err := db.InitDB()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = dbConn.Close() }()
+
+		r := registry.NewRepository(dbConn)
+		cs, err := r.GetCommandSetByName(name)
+		if err != nil {
+			return err
+		}
+		if cs == nil {
+			return fmt.Errorf("command set not found: %s", name)
+		}
+		id, err := r.AddSchedule(name, expr, params, timeout, next)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("added schedule #%d for '%s' (%s), next run %s\n", id, name, expr, next.Format("2006-01-02 15:04"))
+		return nil
+	},
+}
+
+var scheduleListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List cron schedules",
+	Args:  cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		dbConn, err := db.InitDB()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = dbConn.Close() }()
+
+		r := registry.NewRepository(dbConn)
+		list, err := r.ListSchedules()
+		if err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			fmt.Println("no schedules")
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "ID\tSET\tCRON\tNEXT\tLAST\tTIMEOUT\tPARAMS")
+		for _, s := range list {
+			_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", s.ID, s.SetName, s.Cron,
+				scheduleTime(s.NextRunAt), scheduleTime(s.LastRunAt), s.Timeout, strings.Join(s.Params, " "))
+		}
+		return tw.Flush()
+	},
+}
+
+// scheduleTime renders a stored schedule time in local time.
+func scheduleTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+var scheduleRemoveCmd = &cobra.Command{
+	Use:     "rm <id>",
+	Aliases: []string{"remove"},
+	Short:   "Remove a cron schedule",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid schedule id %q", args[0])
+		}
+
+		dbConn, err := db.InitDB()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = dbConn.Close() }()
+
+		r := registry.NewRepository(dbConn)
+		if err := r.RemoveSchedule(id); err != nil {
+			return err
+		}
+		fmt.Printf("removed schedule #%d\n", id)
+		return nil
+	},
+}
+
+func init() {
+	scheduleAddCmd.Flags().StringArray("param", []string{}, "Parameter values as name=value (repeatable), passed to every scheduled run")
+	scheduleAddCmd.Flags().Duration("timeout", 30*time.Second, "Abort a scheduled run that takes longer than this (default from config 'timeout')")
+	scheduleCmd.AddCommand(scheduleAddCmd)
+	scheduleCmd.AddCommand(scheduleListCmd)
+	scheduleCmd.AddCommand(scheduleRemoveCmd)
+	rootCmd.AddCommand(scheduleCmd)
+}
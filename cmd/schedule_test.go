@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/executor"
+	"github.com/VoxDroid/krnr/internal/registry"
+)
+
+func TestScheduleAddListRemove(t *testing.T) {
+	setupTempDB(t)
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	defer func() { _ = dbConn.Close() }()
+	r := registry.NewRepository(dbConn)
+	if _, err := r.CreateCommandSet("backup", nil, nil, nil, []string{"echo {{target}}"}); err != nil {
+		t.Fatalf("CreateCommandSet: %v", err)
+	}
+
+	out, _ := captureOutput(func() {
+		rootCmd.SetArgs([]string{"schedule", "add", "backup", "0 3 * * *", "--param", "target=/mnt", "--timeout", "5m"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("schedule add failed: %v", err)
+		}
+	})
+	if !strings.Contains(out, "added schedule #1 for 'backup'") {
+		t.Fatalf("unexpected output: %s", out)
+	}
+	for _, args := range [][]string{
+		{"schedule", "add", "backup", "61 * * * *"},
+		{"schedule", "add", "missing", "@daily"},
+	} {
+		rootCmd.SetArgs(args)
+		if err := rootCmd.Execute(); err == nil {
+			t.Fatalf("expected %v to fail", args)
+		}
+	}
+
+	out, _ = captureOutput(func() {
+		rootCmd.SetArgs([]string{"schedule", "list"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("schedule list failed: %v", err)
+		}
+	})
+	if !strings.Contains(out, "0 3 * * *") || !strings.Contains(out, "5m0s") || !strings.Contains(out, "target=/mnt") {
+		t.Fatalf("unexpected list output: %s", out)
+	}
+
+	list, _ := r.ListSchedules()
+	args := strings.Join(scheduledRunArgs(list[0]), " ")
+	if args != "run backup --ci=plain --trigger schedule --timeout 5m0s --param=target=/mnt" {
+		t.Fatalf("unexpected run args: %s", args)
+	}
+
+	out, _ = captureOutput(func() {
+		rootCmd.SetArgs([]string{"schedule", "rm", "1"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("schedule rm failed: %v", err)
+		}
+	})
+	if !strings.Contains(out, "removed schedule #1") {
+		t.Fatalf("unexpected output: %s", out)
+	}
+}
+
+func TestHistoryRunsShowsScheduledRuns(t *testing.T) {
+	setupTempDB(t)
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	defer func() { _ = dbConn.Close() }()
+	r := registry.NewRepository(dbConn)
+	if _, err := r.CreateCommandSet("nightly", nil, nil, nil, []string{"echo hi"}); err != nil {
+		t.Fatalf("CreateCommandSet: %v", err)
+	}
+	if err := r.RecordMissedRun("nightly", time.Date(2026, 3, 14, 3, 0, 0, 0, time.UTC), "schedule"); err != nil {
+		t.Fatalf("RecordMissedRun: %v", err)
+	}
+
+	orig := execFactory
+	defer func() { execFactory = orig }()
+	execFactory = func(_, _ bool) executor.Runner { return &fakeRunner{} }
+	keepParamFlag(t)
+	resetRunFlags()
+	defer resetRunFlags()
+	_, _ = captureOutput(func() {
+		rootCmd.SetArgs([]string{"run", "nightly", "--trigger", "schedule", "--timeout", "1m"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+	})
+
+	defer func() { _ = historyCmd.Flags().Set("runs", "false") }()
+	out, _ := captureOutput(func() {
+		rootCmd.SetArgs([]string{"history", "nightly", "--runs"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("history --runs failed: %v", err)
+		}
+	})
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[1], "ok") || !strings.Contains(lines[1], "schedule") ||
+		!strings.Contains(lines[2], "missed") {
+		t.Fatalf("unexpected history output: %s", out)
+	}
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/schedule"
+)
+
+// scheduledRunArgs builds the `krnr run` arguments for a scheduled run.
+func scheduledRunArgs(s registry.Schedule) []string {
+	args := []string{"run", s.SetName, "--ci=plain", "--trigger", schedule.Trigger}
+	if s.Timeout > 0 {
+		args = append(args, "--timeout", s.Timeout.String())
+	}
+	for _, p := range s.Params {
+		args = append(args, "--param="+p)
+	}
+	return args
+}
+
+// launchScheduled runs a scheduled set in a child `krnr run` process so every
+// run gets its own history entry, log and timeout. Tests replace it.
+var launchScheduled = func(ctx context.Context, s registry.Schedule) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if s.Timeout > 0 {
+		// backstop in case the child ignores its own --timeout
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout+time.Minute)
+		defer cancel()
+	}
+	c := exec.CommandContext(ctx, exe, scheduledRunArgs(s)...)
+	if err := c.Run(); err != nil {
+		var ee *exec.ExitError
+		if errors.As(err, &ee) {
+			return fmt.Errorf("exit %d (see 'krnr logs %s')", ee.ExitCode(), s.SetName)
+		}
+		return err
+	}
+	return nil
+}
+
+var schedulerCmd = &cobra.Command{
+	Use:   "scheduler",
+	Short: "Run the local scheduler in the foreground",
+	Long: `Run the local scheduler in the foreground. It starts scheduled command sets
+(see 'krnr schedule') when they are due, one 'krnr run' process per run, and
+records occurrences that were missed while it was not running (or while the
+previous run of the same schedule was still in progress). Scheduled runs
+appear in 'krnr history <name> --runs' with trigger "schedule".
+
+Stop it with Ctrl-C; runs in progress are allowed to finish.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		interval, _ := cmd.Flags().GetDuration("interval")
+		grace, _ := cmd.Flags().GetDuration("grace")
+		if interval <= 0 {
+			return fmt.Errorf("--interval must be positive")
+		}
+
+		dbConn, err := db.InitDB()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = dbConn.Close() }()
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		d := &schedule.Daemon{
+			Store:  registry.NewRepository(dbConn),
+			Launch: launchScheduled,
+			Log:    cmd.OutOrStdout(),
+			Grace:  grace,
+		}
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "scheduler started (checking every %s, Ctrl-C to stop)\n", interval)
+		return d.Run(ctx, interval)
+	},
+}
+
+func init() {
+	schedulerCmd.Flags().Duration("interval", 15*time.Second, "How often to check for due schedules")
+	schedulerCmd.Flags().Duration("grace", time.Minute, "How late a run may still start before it is recorded as missed")
+	rootCmd.AddCommand(schedulerCmd)
+}
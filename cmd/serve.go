@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/VoxDroid/krnr/internal/httpjson"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run krnr servers (webhooks, REST API)",
+	Long:  "Run long-lived krnr servers that let other local tools start command sets over HTTP.",
+}
+
+// statusFor maps background run start errors to HTTP statuses.
+func statusFor(err error) error {
+	code := 0
+	switch {
+	case errors.Is(err, errSetNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, errBadParams):
+		code = http.StatusBadRequest
+	case errors.Is(err, errRunRefused):
+		code = http.StatusForbidden
+	case errors.Is(err, errRunConflict):
+		code = http.StatusConflict
+	default:
+		return err
+	}
+	return &httpjson.StatusError{Code: code, Err: err}
+}
+
+// serverRunner adapts backgroundRunner to the servers' Start methods.
+type serverRunner struct {
+	*backgroundRunner
+}
+
+func (s serverRunner) Start(set string, params map[string]string) (string, error) {
+	id, err := s.start(context.Background(), set, params)
+	return id, statusFor(err)
+}
+
+// serveUntilSignal serves h on ln until interrupted, then shuts down
+// gracefully and waits for started runs via wait.
+func serveUntilSignal(ln net.Listener, h http.Handler, wait func()) error {
+	srv := &http.Server{Handler: h, ReadHeaderTimeout: 10 * time.Second}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Serve(ln) }()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := srv.Shutdown(shutdownCtx)
+	wait()
+	return err
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+}
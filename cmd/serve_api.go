@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/VoxDroid/krnr/internal/api"
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/runlog"
+	"github.com/VoxDroid/krnr/internal/tui/adapters"
+)
+
+// apiTrigger is recorded in run history for runs started through the API.
+const apiTrigger = "api"
+
+// apiListener opens the API's listener: a Unix socket when socket is set,
+// otherwise a TCP address that must be on a loopback interface.
+func apiListener(listen, socket string) (net.Listener, error) {
+	if socket != "" {
+		// a socket file left behind by a previous server would make Listen fail
+		if fi, err := os.Lstat(socket); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			_ = os.Remove(socket)
+		}
+		ln, err := net.Listen("unix", socket)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.Chmod(socket, 0o600); err != nil {
+			_ = ln.Close()
+			return nil, err
+		}
+		return ln, nil
+	}
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return nil, err
+	}
+	if host != "localhost" {
+		ip := net.ParseIP(host)
+		if ip == nil || !ip.IsLoopback() {
+			return nil, fmt.Errorf("refusing to listen on %s: the API only listens on loopback addresses or a Unix socket (--socket)", listen)
+		}
+	}
+	return net.Listen("tcp", listen)
+}
+
+var serveAPICmd = &cobra.Command{
+	Use:   "api",
+	Short: "Serve the registry as a versioned JSON REST API",
+	Long: `Serve the registry as a versioned JSON REST API for editor plugins and
+dashboards. Routes live under /v1; the OpenAPI document is at
+/v1/openapi.json (or printed with --openapi).
+
+The server listens on a loopback TCP address (--listen) or a Unix socket
+(--socket). Every request except the OpenAPI document needs the header
+  Authorization: Bearer <token>
+where the token is KRNR_API_TOKEN or, when that is unset, the contents of
+KRNR_HOME/api-token (created on first start, readable only by you).
+
+Runs started with POST /v1/sets/<name>/runs go through the same parameter
+and safety checks as hook runs (no prompts, no --force) and are streamed as
+server-sent events from GET /v1/runs/<id>/events.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		listen, _ := cmd.Flags().GetString("listen")
+		socket, _ := cmd.Flags().GetString("socket")
+		timeout := flagOrSetting(cmd, "timeout", settings.Timeout)
+		printSpec, _ := cmd.Flags().GetBool("openapi")
+		if timeout <= 0 {
+			return fmt.Errorf("--timeout must be positive")
+		}
+
+		if printSpec {
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent("", "  ")
+			return enc.Encode(api.OpenAPI())
+		}
+
+		dbConn, err := db.InitDB()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = dbConn.Close() }()
+
+		token, err := api.LoadOrCreateToken()
+		if err != nil {
+			return err
+		}
+		r := registry.NewRepository(dbConn)
+		events := api.NewBroker()
+		runner := newBackgroundRunner(r, adapters.NewLoggingExecutorAdapter(execFactory(false, false), runlog.Configured(settings)), apiTrigger, timeout)
+		runner.sink = events.Publish
+		srv, err := api.NewServer(api.Options{Repo: r, Runner: serverRunner{runner}, Events: events, Token: token, Log: cmd.OutOrStdout()})
+		if err != nil {
+			return err
+		}
+		ln, err := apiListener(listen, socket)
+		if err != nil {
+			return err
+		}
+		where := "http://" + ln.Addr().String()
+		if socket != "" {
+			where = "unix:" + socket
+		}
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "serving API %s on %s (Ctrl-C to stop)\n", api.Version, where)
+		if os.Getenv(api.TokenEnv) == "" {
+			p, _ := api.TokenPath()
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "token: %s\n", p)
+		}
+		return serveUntilSignal(ln, srv, runner.wait)
+	},
+}
+
+func init() {
+	serveAPICmd.Flags().String("listen", "127.0.0.1:8788", "Loopback address to listen on")
+	serveAPICmd.Flags().String("socket", "", "Listen on this Unix socket instead of TCP")
+	serveAPICmd.Flags().Duration("timeout", 30*time.Second, "Abort a run started through the API that takes longer than this (default from config 'timeout')")
+	serveAPICmd.Flags().Bool("openapi", false, "Print the OpenAPI document and exit")
+	serveCmd.AddCommand(serveAPICmd)
+}
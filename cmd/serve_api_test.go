@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/VoxDroid/krnr/internal/api"
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/executor"
+	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/tui/adapters"
+)
+
+func TestAPIListenerRequiresLoopback(t *testing.T) {
+	for _, addr := range []string{"0.0.0.0:0", "192.0.2.1:0", "example.com:0"} {
+		if ln, err := apiListener(addr, ""); err == nil {
+			_ = ln.Close()
+			t.Fatalf("expected %s to be refused", addr)
+		}
+	}
+	ln, err := apiListener("127.0.0.1:0", "")
+	if err != nil {
+		t.Fatalf("loopback listen: %v", err)
+	}
+	_ = ln.Close()
+	if runtime.GOOS == "windows" {
+		return
+	}
+	sock := filepath.Join(t.TempDir(), "api.sock")
+	for i := 0; i < 2; i++ { // the second listen replaces the stale socket file
+		ln, err := apiListener("", sock)
+		if err != nil {
+			t.Fatalf("socket listen #%d: %v", i+1, err)
+		}
+		_ = ln.Close()
+	}
+}
+
+func TestBackgroundRunnerPublishesEvents(t *testing.T) {
+	setupTempDB(t)
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	defer func() { _ = dbConn.Close() }()
+	r := registry.NewRepository(dbConn)
+	if _, err := r.CreateCommandSet("build", nil, nil, nil, []string{"make {{target}}"}); err != nil {
+		t.Fatalf("CreateCommandSet: %v", err)
+	}
+
+	events := api.NewBroker()
+	runner := newBackgroundRunner(r, adapters.NewExecutorAdapter(executor.Runner(&fakeRunner{})), apiTrigger, time.Minute)
+	runner.sink = events.Publish
+	id, err := serverRunner{runner}.Start("build", map[string]string{"target": "all"})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	runner.wait()
+	past, live, cancel, ok := events.Subscribe(id)
+	defer cancel()
+	if !ok || live != nil || len(past) < 3 {
+		t.Fatalf("expected a finished run with events, got ok=%v live=%v %d events", ok, live != nil, len(past))
+	}
+	if past[0].Type != adapters.EventRunStarted || past[0].Steps != 1 || past[len(past)-1].Type != adapters.EventRunFinished {
+		t.Fatalf("unexpected framing: first %+v last %+v", past[0], past[len(past)-1])
+	}
+	if rr, _ := r.GetRun(id); rr == nil || rr.Trigger != apiTrigger || rr.Status != registry.RunStatusOK {
+		t.Fatalf("unexpected run record %+v", rr)
+	}
+}
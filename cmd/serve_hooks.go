@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/hooks"
+	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/runlog"
+	"github.com/VoxDroid/krnr/internal/tui/adapters"
+)
+
+// hookBackend connects the webhook server to the registry and runs sets in
+// this process.
+type hookBackend struct {
+	serverRunner
+	r *registry.Repository
+}
+
+func (b *hookBackend) Secret(set string) (string, bool, error) { return b.r.WebhookSecret(set) }
+
+func (b *hookBackend) Run(runID string) (*registry.RunRecord, error) { return b.r.GetRun(runID) }
+
+var serveHooksCmd = &cobra.Command{
+	Use:   "hooks",
+	Short: "Serve webhook endpoints for hook-enabled command sets",
+	Long: `Serve webhook endpoints for command sets enabled with 'krnr webhook enable'.
+
+  POST /hooks/<name>  start a run; the JSON object body supplies parameters,
+                      e.g. {"branch": "main"}. Answers 202 with the run ID.
+  GET  /runs/<id>     status of a run started through a hook
+
+Every request must carry the headers
+  X-Krnr-Timestamp: <Unix seconds>
+  X-Krnr-Signature: sha256=<hex HMAC-SHA256 of "<timestamp>.<body>">
+keyed with the set's webhook secret (for GET the body is empty). Requests
+more than 5 minutes old are refused, and a signed trigger is only accepted
+once. Body keys
+must be parameters the set declares and every parameter must be supplied;
+runs never prompt. Commands are safety-checked as for 'krnr run' (there is
+no --force), exclusive sets answer 409 while they run, and output is kept
+in the run log ('krnr logs <name>').`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		listen, _ := cmd.Flags().GetString("listen")
+		timeout := flagOrSetting(cmd, "timeout", settings.Timeout)
+		if timeout <= 0 {
+			return fmt.Errorf("--timeout must be positive")
+		}
+
+		dbConn, err := db.InitDB()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = dbConn.Close() }()
+
+		r := registry.NewRepository(dbConn)
+		enabled, err := r.ListWebhooks()
+		if err != nil {
+			return err
+		}
+		runner := newBackgroundRunner(r, adapters.NewLoggingExecutorAdapter(execFactory(false, false), runlog.Configured(settings)), hooks.Trigger, timeout)
+		srv := hooks.NewServer(&hookBackend{serverRunner: serverRunner{runner}, r: r}, cmd.OutOrStdout())
+		ln, err := net.Listen("tcp", listen)
+		if err != nil {
+			return err
+		}
+		names := make([]string, len(enabled))
+		for i, w := range enabled {
+			names[i] = w.SetName
+		}
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "serving hooks on http://%s (Ctrl-C to stop)\n", ln.Addr())
+		if len(names) == 0 {
+			_, _ = fmt.Fprintln(cmd.OutOrStdout(), "no hook-enabled sets yet; enable one with 'krnr webhook enable <name>'")
+		} else {
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "hook-enabled sets: %s\n", strings.Join(names, ", "))
+		}
+		return serveUntilSignal(ln, srv, runner.wait)
+	},
+}
+
+func init() {
+	serveHooksCmd.Flags().String("listen", "127.0.0.1:8787", "Address to listen on")
+	serveHooksCmd.Flags().Duration("timeout", 30*time.Second, "Abort a triggered run that takes longer than this (default from config 'timeout')")
+	serveCmd.AddCommand(serveHooksCmd)
+}
@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/executor"
+	"github.com/VoxDroid/krnr/internal/hooks"
+	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/tui/adapters"
+)
+
+func TestWebhookEnableListDisable(t *testing.T) {
+	setupTempDB(t)
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	defer func() { _ = dbConn.Close() }()
+	r := registry.NewRepository(dbConn)
+	if _, err := r.CreateCommandSet("deploy", nil, nil, nil, []string{"echo {{branch}}"}); err != nil {
+		t.Fatalf("CreateCommandSet: %v", err)
+	}
+
+	out, _ := captureOutput(func() {
+		rootCmd.SetArgs([]string{"webhook", "enable", "deploy"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("webhook enable failed: %v", err)
+		}
+	})
+	secret, ok, _ := r.WebhookSecret("deploy")
+	if !ok || !strings.Contains(out, "secret: "+secret) || !strings.Contains(out, "/hooks/deploy") {
+		t.Fatalf("unexpected enable output: %s", out)
+	}
+	rootCmd.SetArgs([]string{"webhook", "enable", "deploy"})
+	if err := rootCmd.Execute(); err == nil {
+		t.Fatalf("expected enabling twice without --rotate to fail")
+	}
+	_, _ = captureOutput(func() {
+		rootCmd.SetArgs([]string{"webhook", "enable", "deploy", "--rotate"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("webhook enable --rotate failed: %v", err)
+		}
+	})
+	_ = webhookEnableCmd.Flags().Set("rotate", "false")
+	if rotated, _, _ := r.WebhookSecret("deploy"); rotated == secret {
+		t.Fatalf("expected --rotate to replace the secret")
+	}
+
+	out, _ = captureOutput(func() {
+		rootCmd.SetArgs([]string{"webhook", "list"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("webhook list failed: %v", err)
+		}
+	})
+	if !strings.Contains(out, "POST /hooks/deploy") {
+		t.Fatalf("unexpected list output: %s", out)
+	}
+
+	_, _ = captureOutput(func() {
+		rootCmd.SetArgs([]string{"webhook", "disable", "deploy"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("webhook disable failed: %v", err)
+		}
+	})
+	if _, ok, _ := r.WebhookSecret("deploy"); ok {
+		t.Fatalf("expected webhook to be disabled")
+	}
+}
+
+func TestServeHooksRunsEnabledSets(t *testing.T) {
+	setupTempDB(t)
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	defer func() { _ = dbConn.Close() }()
+	r := registry.NewRepository(dbConn)
+	for name, cmd := range map[string]string{"deploy": "echo {{branch}}", "wipe": "rm -rf /", "plain": "echo hi"} {
+		if _, err := r.CreateCommandSet(name, nil, nil, nil, []string{cmd}); err != nil {
+			t.Fatalf("CreateCommandSet: %v", err)
+		}
+	}
+	_ = r.EnableWebhook("deploy", "k1")
+	_ = r.EnableWebhook("wipe", "k2")
+
+	fake := &fakeRunner{}
+	runner := newBackgroundRunner(r, adapters.NewExecutorAdapter(executor.Runner(fake)), hooks.Trigger, time.Minute)
+	srv := httptest.NewServer(hooks.NewServer(&hookBackend{serverRunner: serverRunner{runner}, r: r}, nil))
+	defer srv.Close()
+
+	post := func(set, secret, body string) (int, hooks.TriggerResponse) {
+		req, _ := http.NewRequest("POST", srv.URL+"/hooks/"+set, strings.NewReader(body))
+		hooks.SignRequest(req, secret, []byte(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST: %v", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		var tr hooks.TriggerResponse
+		_ = json.NewDecoder(resp.Body).Decode(&tr)
+		return resp.StatusCode, tr
+	}
+
+	for _, c := range []struct {
+		set, secret, body string
+		want              int
+	}{
+		{"plain", "k1", "{}", http.StatusNotFound},
+		{"deploy", "k1", `{}`, http.StatusBadRequest},
+		{"deploy", "k1", `{"branch":"main","extra":"x"}`, http.StatusBadRequest},
+		{"wipe", "k2", `{}`, http.StatusForbidden},
+	} {
+		if code, _ := post(c.set, c.secret, c.body); code != c.want {
+			t.Errorf("POST %s %s: got %d, want %d", c.set, c.body, code, c.want)
+		}
+	}
+
+	code, tr := post("deploy", "k1", `{"branch":"main"}`)
+	if code != http.StatusAccepted || tr.RunID == "" {
+		t.Fatalf("trigger = %d %+v", code, tr)
+	}
+	runner.wait()
+	if fake.lastCmd != "echo main" {
+		t.Fatalf("unexpected command: %q", fake.lastCmd)
+	}
+
+	req, _ := http.NewRequest("GET", srv.URL+tr.StatusURL, nil)
+	hooks.SignRequest(req, "k1", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var st hooks.RunStatus
+	_ = json.NewDecoder(resp.Body).Decode(&st)
+	if resp.StatusCode != http.StatusOK || st.Status != registry.RunStatusOK || st.ExitCode == nil || *st.ExitCode != 0 {
+		t.Fatalf("status = %d %+v", resp.StatusCode, st)
+	}
+}
+
+func TestBackgroundRunQuotesParams(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("parameter quoting targets POSIX shells")
+	}
+	setupTempDB(t)
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	defer func() { _ = dbConn.Close() }()
+	r := registry.NewRepository(dbConn)
+	for name, cmd := range map[string]string{"bare": "echo {{msg}}", "single": "echo '{{msg}}'", "double": `echo "{{msg}}"`} {
+		if _, err := r.CreateCommandSet(name, nil, nil, nil, []string{cmd}); err != nil {
+			t.Fatalf("CreateCommandSet: %v", err)
+		}
+	}
+	dir := t.TempDir()
+	pwned := filepath.Join(dir, "pwned")
+	runner := newBackgroundRunner(r, adapters.NewExecutorAdapter(&executor.Executor{}), hooks.Trigger, time.Minute)
+	for _, set := range []string{"bare", "single", "double"} {
+		for _, v := range []string{"x; touch " + pwned, "x' ; touch " + pwned + " ; '", `x"; touch ` + pwned + `; "`, "$(touch " + pwned + ")", "`touch " + pwned + "`"} {
+			if _, err := runner.start(t.Context(), set, map[string]string{"msg": v}); err != nil {
+				t.Fatalf("start %s: %v", set, err)
+			}
+			runner.wait()
+			if _, err := os.Stat(pwned); err == nil {
+				t.Fatalf("parameter %q injected a command into set %s", v, set)
+			}
+		}
+	}
+}
@@ -5,8 +5,7 @@ import (
 
 	"github.com/spf13/cobra"
 
-	"github.com/VoxDroid/krnr/internal/db"
-	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/pkg/krnr"
 )
 
 var tagCmd = &cobra.Command{
@@ -23,21 +22,13 @@ var tagAddCmd = &cobra.Command{
 		name := args[0]
 		tag := args[1]
 
-		dbConn, err := db.InitDB()
+		reg, err := krnr.Open(krnr.Options{})
 		if err != nil {
 			return err
 		}
-		defer func() { _ = dbConn.Close() }()
+		defer func() { _ = reg.Close() }()
 
-		r := registry.NewRepository(dbConn)
-		cs, err := r.GetCommandSetByName(name)
-		if err != nil {
-			return err
-		}
-		if cs == nil {
-			return fmt.Errorf("command set not found: %s", name)
-		}
-		if err := r.AddTagToCommandSet(cs.ID, tag); err != nil {
+		if err := reg.AddTag(name, tag); err != nil {
 			return err
 		}
 		fmt.Printf("added tag '%s' to '%s'\n", tag, name)
@@ -53,21 +44,13 @@ var tagRemoveCmd = &cobra.Command{
 		name := args[0]
 		tag := args[1]
 
-		dbConn, err := db.InitDB()
+		reg, err := krnr.Open(krnr.Options{})
 		if err != nil {
 			return err
 		}
-		defer func() { _ = dbConn.Close() }()
+		defer func() { _ = reg.Close() }()
 
-		r := registry.NewRepository(dbConn)
-		cs, err := r.GetCommandSetByName(name)
-		if err != nil {
-			return err
-		}
-		if cs == nil {
-			return fmt.Errorf("command set not found: %s", name)
-		}
-		if err := r.RemoveTagFromCommandSet(cs.ID, tag); err != nil {
+		if err := reg.RemoveTag(name, tag); err != nil {
 			return err
 		}
 		fmt.Printf("removed tag '%s' from '%s'\n", tag, name)
@@ -82,21 +65,13 @@ var tagListCmd = &cobra.Command{
 	RunE: func(_ *cobra.Command, args []string) error {
 		name := args[0]
 
-		dbConn, err := db.InitDB()
+		reg, err := krnr.Open(krnr.Options{})
 		if err != nil {
 			return err
 		}
-		defer func() { _ = dbConn.Close() }()
+		defer func() { _ = reg.Close() }()
 
-		r := registry.NewRepository(dbConn)
-		cs, err := r.GetCommandSetByName(name)
-		if err != nil {
-			return err
-		}
-		if cs == nil {
-			return fmt.Errorf("command set not found: %s", name)
-		}
-		tags, err := r.ListTagsForCommandSet(cs.ID)
+		tags, err := reg.Tags(name)
 		if err != nil {
 			return err
 		}
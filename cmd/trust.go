@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/registry"
+)
+
+var trustCmd = &cobra.Command{
+	Use:   "trust <name>",
+	Short: "Allow an imported command set to run",
+	Long: `Sets imported with 'krnr import' are untrusted: they are shell commands from
+someone else's machine. 'krnr run' refuses them and shows their commands, or
+what changed since you last trusted them; 'krnr run --dry-run' also shows
+them. After reviewing a set, 'krnr trust <name>' allows it to run. An import
+that changes its commands makes it untrusted again; your own edits do not.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		dbConn, err := db.InitDB()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = dbConn.Close() }()
+		r := registry.NewRepository(dbConn)
+		changed, err := r.Trust(args[0])
+		if err != nil {
+			return err
+		}
+		if !changed {
+			fmt.Printf("%s is already trusted\n", args[0])
+			return nil
+		}
+		fmt.Printf("trusted %s\n", args[0])
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(trustCmd)
+}
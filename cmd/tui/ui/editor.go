@@ -14,6 +14,7 @@ import (
 	"github.com/charmbracelet/lipgloss"
 
 	"github.com/VoxDroid/krnr/internal/executor"
+	"github.com/VoxDroid/krnr/internal/lint"
 	"github.com/VoxDroid/krnr/internal/nameutil"
 	"github.com/VoxDroid/krnr/internal/tui/adapters"
 )
@@ -366,10 +367,48 @@ func (m *TuiModel) createCommandSet(newCS adapters.CommandSetSummary) error {
 	m.detail = formatCSFullScreen(newCS, m.width, m.height)
 	m.vp.SetContent(m.detail)
 	m.clearNotification()
+	m.reportSaved(newCS)
 	m.editor.lastSavedAt = time.Now()
 	return nil
 }
 
+// reportSaved notifies the lint findings of the saved set and what the
+// policy will do with the commands it does not simply allow; saving is
+// never refused.
+func (m *TuiModel) reportSaved(cs adapters.CommandSetSummary) {
+	var findings []string
+	for _, f := range lint.Check(lint.Set{Name: cs.Name, Tags: cs.Tags, Commands: cs.Commands}) {
+		findings = append(findings, "lint: "+f.String())
+	}
+	if pf, ok := m.uiModel.(interface {
+		PolicyFindings(adapters.CommandSetSummary) []string
+	}); ok {
+		for _, f := range pf.PolicyFindings(cs) {
+			findings = append(findings, "policy: "+f)
+		}
+	}
+	m.logs = append(m.logs, findings...)
+	switch len(findings) {
+	case 0:
+	case 1:
+		m.setNotification(findings[0])
+	default:
+		m.setNotification(fmt.Sprintf("%s (+%d more)", findings[0], len(findings)-1))
+	}
+}
+
+// editorLint checks the commands being edited; findings are numbered like
+// the editor's command list.
+func (m *TuiModel) editorLint() []lint.Finding {
+	s := lint.Set{Name: m.editor.name, Commands: m.editor.commands}
+	for _, t := range strings.Split(m.editor.tags, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			s.Tags = append(s.Tags, t)
+		}
+	}
+	return lint.Check(s)
+}
+
 func (m *TuiModel) updateCommandSet(newCS adapters.CommandSetSummary) error {
 	if err := m.uiModel.UpdateCommandSetAndReplaceCommands(context.Background(), m.detailName, newCS); err != nil {
 		m.setNotification(err.Error())
@@ -383,6 +422,7 @@ func (m *TuiModel) updateCommandSet(newCS adapters.CommandSetSummary) error {
 	}
 	m.editor.lastSavedAt = time.Now()
 	m.clearNotification()
+	m.reportSaved(newCS)
 	return nil
 }
 
@@ -443,6 +483,10 @@ func (m *TuiModel) renderEditorFieldGeneric(label, value string, index int, focu
 func (m *TuiModel) renderEditorCommands() string {
 	var b strings.Builder
 	b.WriteString("Commands:\n")
+	findings := map[int][]lint.Finding{}
+	for _, f := range m.editorLint() {
+		findings[f.Step] = append(findings[f.Step], f)
+	}
 	for i, c := range m.editor.commands {
 		prefix := fmt.Sprintf("%d) ", i+1)
 		if m.editor.field == 5 && i == m.editor.cmdIndex {
@@ -451,10 +495,22 @@ func (m *TuiModel) renderEditorCommands() string {
 		} else {
 			b.WriteString("  " + prefix + c + "\n")
 		}
+		for _, f := range findings[i+1] {
+			style := lintWarningStyle
+			if f.Severity == lint.SeverityError {
+				style = lintErrorStyle
+			}
+			b.WriteString("     " + style.Render(fmt.Sprintf("⚠ %s: %s [%s]", f.Severity, f.Message, f.Rule)) + "\n")
+		}
 	}
 	return b.String()
 }
 
+var (
+	lintWarningStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#d97706"))
+	lintErrorStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#dc2626"))
+)
+
 // getSaveDelay returns the configured save delay duration.
 func getSaveDelay() time.Duration {
 	if v := os.Getenv("KRNR_SAVE_DELAY_MS"); v != "" {
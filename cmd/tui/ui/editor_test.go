@@ -107,3 +107,40 @@ func TestCtrlAFromOtherFieldsAddsCommand(t *testing.T) {
 		t.Fatalf("expected updated command in ReplaceCommands, got %#v", reg.lastCommands)
 	}
 }
+
+func TestEditorShowsLintFindings(t *testing.T) {
+	full := adapters.CommandSetSummary{Name: "one", Description: "First", Commands: []string{"echo hi"}}
+	reg := &replaceFakeRegistry{items: []adapters.CommandSetSummary{{Name: "one", Description: "First"}}, full: full}
+	ui := modelpkg.New(reg, &fakeExec{}, nil, nil)
+	_ = ui.RefreshList(context.Background())
+	m := NewModel(ui)
+	m = initTestModel(m)
+	m1, _ := m.Update(tea.WindowSizeMsg{Width: 80, Height: 20})
+	m = m1.(*TuiModel)
+	m2, _ := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = m2.(*TuiModel)
+	m3, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'e'}})
+	m = m3.(*TuiModel)
+	if !m.editingMeta {
+		t.Fatalf("expected editor to be open")
+	}
+	m.editor.commands = []string{"cd /srv/app", "make"}
+	if out := m.renderEditorCommands(); !strings.Contains(out, "[cd.no-effect]") {
+		t.Fatalf("expected the lint finding below the command, got %q", out)
+	}
+
+	// saving reports the finding without refusing the save
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlS})
+	if len(reg.lastCommands) != 2 {
+		t.Fatalf("expected ReplaceCommands to be called, got %#v", reg.lastCommands)
+	}
+	found := false
+	for _, l := range m.logs {
+		if strings.HasPrefix(l, "lint: step 1: warning:") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected a lint log after saving, got %#v", m.logs)
+	}
+}
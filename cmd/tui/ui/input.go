@@ -2,6 +2,7 @@ package ui
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -12,6 +13,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 
 	"github.com/VoxDroid/krnr/internal/tui/adapters"
+	modelpkg "github.com/VoxDroid/krnr/internal/tui/model"
 )
 
 // dispatchKey routes KeyMsg to the appropriate handler based on current UI state.
@@ -324,6 +326,8 @@ func handleGlobalRunToggleThemeAndPane(m *TuiModel, s string, _ tea.KeyMsg) (tea
 	switch s {
 	case "r":
 		return handleRun(m)
+	case "S":
+		return handleStepRun(m)
 	case "T", "t":
 		m.themeHighContrast = !m.themeHighContrast
 		return m, nil, true
@@ -336,7 +340,7 @@ func handleGlobalRunToggleThemeAndPane(m *TuiModel, s string, _ tea.KeyMsg) (tea
 // helper: show help
 func handleHelp(m *TuiModel) (tea.Model, tea.Cmd, bool) {
 	m.setShowDetail(true)
-	m.detail = "Help:\n\n? show help\nq or Esc to quit\nEnter to view details\n(C) Create new entry\n/ to filter\n← → or Tab to switch pane focus\n↑ ↓ to scroll focused pane"
+	m.detail = "Help:\n\n? show help\nq or Esc to quit\nEnter to view details\nr run selected set\nS step-through run (pause before each step)\n(C) Create new entry\n/ to filter\n← → or Tab to switch pane focus\n↑ ↓ to scroll focused pane"
 	return m, nil, true
 }
 
@@ -518,7 +522,7 @@ func handleMenu(m *TuiModel) (tea.Model, tea.Cmd, bool) {
 	m.showMenu = true
 	m.menuIndex = 0
 	if len(m.menuItems) == 0 {
-		m.menuItems = []string{"Export database", "Import database", "Import set", "Install", "Uninstall", "Status", "Close"}
+		m.menuItems = []string{"Export database", "Export encrypted database", "Import database", "Import set", "Install", "Uninstall", "Status", "Close"}
 	}
 	return m, nil, true
 }
@@ -570,6 +574,10 @@ func handleExport(m *TuiModel) (tea.Model, tea.Cmd, bool) {
 
 // helper: confirm (y/Y)
 func handleConfirmYes(m *TuiModel) (tea.Model, tea.Cmd, bool) {
+	if m.pendingRun {
+		m.pendingRun = false
+		return launchRun(m, m.pendingRunName, m.pendingRunStepAll, true)
+	}
 	if m.pendingRollback {
 		return confirmRollbackYes(m)
 	}
@@ -666,6 +674,12 @@ func confirmExportYes(m *TuiModel) (tea.Model, tea.Cmd, bool) {
 
 // helper: cancel confirm (n/N)
 func handleConfirmNo(m *TuiModel) (tea.Model, tea.Cmd, bool) {
+	if m.pendingRun {
+		m.pendingRun = false
+		m.logs = append(m.logs, fmt.Sprintf("run of '%s' cancelled", m.pendingRunName))
+		m.vp.SetContent(strings.Join(m.logs, "\n"))
+		return m, nil, true
+	}
 	if m.pendingRollback {
 		return confirmRollbackNo(m)
 	}
@@ -792,6 +806,15 @@ func confirmExportNo(m *TuiModel) (tea.Model, tea.Cmd, bool) {
 
 // helper: run a command set
 func handleRun(m *TuiModel) (tea.Model, tea.Cmd, bool) {
+	return startRun(m, false)
+}
+
+// helper: run a command set pausing before every step
+func handleStepRun(m *TuiModel) (tea.Model, tea.Cmd, bool) {
+	return startRun(m, true)
+}
+
+func startRun(m *TuiModel, stepAll bool) (tea.Model, tea.Cmd, bool) {
 	if m.runInProgress {
 		return m, nil, true
 	}
@@ -806,13 +829,40 @@ func handleRun(m *TuiModel) (tea.Model, tea.Cmd, bool) {
 	if name == "" {
 		return m, nil, true
 	}
+	return launchRun(m, name, stepAll, false)
+}
+
+// launchRun starts a run of name. A run the policy asks confirmation for
+// waits for (y) to launch it again as confirmed.
+func launchRun(m *TuiModel, name string, stepAll, confirmed bool) (tea.Model, tea.Cmd, bool) {
 	m.logs = nil
 	m.runInProgress = true
 	m.focusRight = false
 	m.runCapturesInput = true
 	ctx, cancel := context.WithCancel(context.Background())
 	m.cancelRun = cancel
-	h, err := m.uiModel.Run(ctx, name, nil)
+	runCtx := ctx
+	if confirmed {
+		runCtx = modelpkg.Confirmed(ctx)
+	}
+	var h adapters.RunHandle
+	var err error
+	if stepAll {
+		h, err = m.uiModel.RunStepped(runCtx, name, true)
+	} else {
+		h, err = m.uiModel.Run(runCtx, name, nil)
+	}
+	if errors.Is(err, modelpkg.ErrNeedsConfirm) {
+		cancel()
+		m.runInProgress = false
+		m.runCapturesInput = false
+		m.pendingRun = true
+		m.pendingRunName = name
+		m.pendingRunStepAll = stepAll
+		m.logs = append(m.logs, "policy: "+err.Error(), "Press (y) to run anyway, (n) to cancel")
+		m.vp.SetContent(strings.Join(m.logs, "\n"))
+		return m, nil, true
+	}
 	if err != nil {
 		m.logs = append(m.logs, "run error: "+err.Error())
 		m.runInProgress = false
@@ -824,6 +874,14 @@ func handleRun(m *TuiModel) (tea.Model, tea.Cmd, bool) {
 	if wi, ok := h.(interface{ WriteInput([]byte) (int, error) }); ok {
 		m.runInputWriter = wi
 	}
+	if sc, ok := h.(adapters.StepController); ok {
+		m.runStepper = sc
+	}
+	if w, ok := m.uiModel.(interface{ Warnings() []string }); ok {
+		for _, warning := range w.Warnings() {
+			m.logs = append(m.logs, "policy warning: "+warning)
+		}
+	}
 	ch := make(chan adapters.RunEvent)
 	m.runCh = ch
 	go func() {
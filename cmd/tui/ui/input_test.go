@@ -2,9 +2,14 @@ package ui
 
 import (
 	"context"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/VoxDroid/krnr/internal/config"
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/runner"
 	"github.com/VoxDroid/krnr/internal/tui/adapters"
 	modelpkg "github.com/VoxDroid/krnr/internal/tui/model"
 	tea "github.com/charmbracelet/bubbletea"
@@ -122,3 +127,35 @@ func TestHandleListFiltering_DelegatedAndUpdatesFilter(t *testing.T) {
 		t.Fatalf("expected listFilter to be empty after backspace, got %q", m.listFilter)
 	}
 }
+
+func TestRunNeedsPolicyConfirmation(t *testing.T) {
+	t.Setenv(config.EnvKRNRDB, filepath.Join(t.TempDir(), "krnr.db"))
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB(): %v", err)
+	}
+	defer func() { _ = dbConn.Close() }()
+	r := registry.NewRepository(dbConn)
+	// the default policy asks confirmation for force pushes
+	if _, err := r.CreateCommandSet("release", nil, nil, nil, []string{"git push --force"}); err != nil {
+		t.Fatal(err)
+	}
+	ui := modelpkg.New(adapters.NewRegistryAdapter(r), &fakeExecAdapter{lines: []string{"pushed"}}, nil, nil)
+	ui.SetGate(&runner.Gate{})
+	_ = ui.RefreshList(context.Background())
+	m := initTestModel(NewModel(ui))
+
+	m.processKeyMsg(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	if !m.pendingRun || m.runInProgress || !strings.Contains(strings.Join(m.logs, "\n"), "git.force-push") {
+		t.Fatalf("expected the run to wait for confirmation, logs: %v", m.logs)
+	}
+	m.processKeyMsg(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	if m.pendingRun || m.runInProgress {
+		t.Fatalf("expected (n) to cancel the run")
+	}
+	m.processKeyMsg(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	m.processKeyMsg(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	if m.pendingRun || !m.runInProgress {
+		t.Fatalf("expected (y) to start the run, logs: %v", m.logs)
+	}
+}
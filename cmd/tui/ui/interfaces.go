@@ -36,6 +36,9 @@ type Model interface {
 	// and records a single 'update' version representing the final state.
 	UpdateCommandSetAndReplaceCommands(ctx context.Context, oldName string, cs adapters.CommandSetSummary) error
 	Run(ctx context.Context, name string, _ []string) (adapters.RunHandle, error)
+	// RunStepped starts a run that pauses before every step (stepAll) or at
+	// stored breakpoints, emitting adapters.EventStepPaused.
+	RunStepped(ctx context.Context, name string, stepAll bool) (adapters.RunHandle, error)
 	Save(ctx context.Context, cs adapters.CommandSetSummary) error
 	Install(ctx context.Context, opts install.Options) ([]string, error)
 	Uninstall(ctx context.Context) ([]string, error)
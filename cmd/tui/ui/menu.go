@@ -10,7 +10,9 @@ import (
 	"unicode/utf8"
 
 	"github.com/VoxDroid/krnr/internal/config"
+	"github.com/VoxDroid/krnr/internal/crypt"
 	"github.com/VoxDroid/krnr/internal/install"
+	"github.com/VoxDroid/krnr/internal/tui/adapters"
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -60,6 +62,7 @@ func (m *TuiModel) processMenuInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.menuInputMode = false
 			m.menuInput = ""
 			m.menuAction = ""
+			m.menuPassphrase = nil
 			m.showMenu = false
 		}
 		return m, nil
@@ -68,15 +71,19 @@ func (m *TuiModel) processMenuInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	// Enter pressed — validate/perform the current action
 	var notifyCmd tea.Cmd
 	actions := map[string]func() tea.Cmd{
-		"import-db":           m.handleMenuInputImportDB,
-		"import-db-overwrite": m.handleMenuInputImportDBOverwrite,
-		"import-set":          m.handleMenuInputImportSet,
-		"import-set-policy":   m.handleMenuInputImportSetPolicy,
-		"import-set-dedupe":   m.handleMenuInputImportSetDedupe,
-		"install-scope":       m.handleMenuInputInstallScope,
-		"install-addpath":     m.handleMenuInputInstallAddPath,
-		"uninstall-confirm":   m.handleMenuInputUninstallConfirm,
-		"export-db":           m.handleMenuInputExportDB,
+		"import-db":             m.handleMenuInputImportDB,
+		"import-db-passphrase":  m.handleMenuInputImportDBPassphrase,
+		"import-db-overwrite":   m.handleMenuInputImportDBOverwrite,
+		"import-set":            m.handleMenuInputImportSet,
+		"import-set-passphrase": m.handleMenuInputImportSetPassphrase,
+		"import-set-policy":     m.handleMenuInputImportSetPolicy,
+		"import-set-dedupe":     m.handleMenuInputImportSetDedupe,
+		"install-scope":         m.handleMenuInputInstallScope,
+		"install-addpath":       m.handleMenuInputInstallAddPath,
+		"uninstall-confirm":     m.handleMenuInputUninstallConfirm,
+		"export-db":             m.handleMenuInputExportDB,
+		"export-db-encrypted":   m.handleMenuInputExportDBEncrypted,
+		"export-db-passphrase":  m.handleMenuInputExportDBPassphrase,
 	}
 	if f, ok := actions[m.menuAction]; ok {
 		prior := m.menuAction
@@ -107,6 +114,12 @@ func (m *TuiModel) activateMenuItem() (tea.Model, tea.Cmd) {
 		m.menuAction = "export-db"
 		m.menuInput = pref
 		return m, nil
+	case "Export encrypted database":
+		cwd, _ := os.Getwd()
+		m.menuInputMode = true
+		m.menuAction = "export-db-encrypted"
+		m.menuInput = filepath.Join(cwd, fmt.Sprintf("krnr-%s.db", time.Now().Format("2006-01-02")))
+		return m, nil
 	case "Import database":
 		m.menuInputMode = true
 		m.menuAction = "import-db"
@@ -152,28 +165,52 @@ func (m *TuiModel) handleMenuInputImportDB() tea.Cmd {
 		return tea.Tick(3*time.Second, func(time.Time) tea.Msg { return clearNotificationMsg{} })
 	}
 	m.menuPendingSrc = m.menuInput
+	if enc, _ := crypt.IsEncryptedFile(m.menuPendingSrc); enc {
+		m.menuAction = "import-db-passphrase"
+		m.menuInput = ""
+		return nil
+	}
+	m.menuAction = "import-db-overwrite"
+	m.menuInput = "n"
+	return nil
+}
+
+func (m *TuiModel) handleMenuInputImportDBPassphrase() tea.Cmd {
+	m.menuPassphrase = []byte(m.menuInput)
 	m.menuAction = "import-db-overwrite"
 	m.menuInput = "n"
 	return nil
 }
 
+// bundleCtx carries the passphrase entered for an encrypted bundle, if
+// any, to the import/export adapter.
+func (m *TuiModel) bundleCtx() context.Context {
+	ctx := context.Background()
+	if len(m.menuPassphrase) > 0 {
+		ctx = adapters.WithPassphrase(ctx, m.menuPassphrase)
+	}
+	return ctx
+}
+
 func (m *TuiModel) handleMenuInputImportDBOverwrite() tea.Cmd {
 	ov := strings.ToLower(strings.TrimSpace(m.menuInput))
 	overwrite := ov == "y" || ov == "yes"
 	if overwrite {
 		_ = m.uiModel.Close()
 	}
-	if err := m.uiModel.ImportDB(context.Background(), m.menuPendingSrc, overwrite); err != nil {
+	if err := m.uiModel.ImportDB(m.bundleCtx(), m.menuPendingSrc, overwrite); err != nil {
 		if overwrite {
 			_ = m.uiModel.ReopenDB(context.Background())
 		}
 		m.logs = append(m.logs, "import error: "+err.Error())
 		m.setNotification("import error: " + err.Error())
 		m.menuPendingSrc = ""
+		m.menuPassphrase = nil
 		return tea.Tick(3*time.Second, func(time.Time) tea.Msg { return clearNotificationMsg{} })
 	}
-	m.logs = append(m.logs, "imported database from "+m.menuPendingSrc)
-	m.setNotification("imported database from " + m.menuPendingSrc)
+	msg := m.importedMessage("database")
+	m.logs = append(m.logs, msg)
+	m.setNotification(msg)
 	if err := m.uiModel.ReopenDB(context.Background()); err != nil {
 		m.logs = append(m.logs, "warning: failed to reopen DB: "+err.Error())
 		m.setNotification("warning: failed to reopen DB")
@@ -181,9 +218,26 @@ func (m *TuiModel) handleMenuInputImportDBOverwrite() tea.Cmd {
 	_ = m.uiModel.RefreshList(context.Background())
 	m.updateListFromCache()
 	m.menuPendingSrc = ""
+	m.menuPassphrase = nil
 	return tea.Tick(3*time.Second, func(time.Time) tea.Msg { return clearNotificationMsg{} })
 }
 
+// importedMessage reports that what was imported from the pending source,
+// with who signed it.
+func (m *TuiModel) importedMessage(what string) string {
+	msg := "imported " + what + " from " + m.menuPendingSrc
+	bs, ok := m.uiModel.(interface {
+		BundleSigner(ctx context.Context, src string) string
+	})
+	if !ok {
+		return msg
+	}
+	if s := bs.BundleSigner(m.bundleCtx(), m.menuPendingSrc); s != "" {
+		msg += " (" + s + ")"
+	}
+	return msg
+}
+
 func (m *TuiModel) handleMenuInputImportSet() tea.Cmd {
 	if _, err := os.Stat(m.menuInput); err != nil {
 		m.logs = append(m.logs, "import error: source not found or inaccessible")
@@ -191,6 +245,18 @@ func (m *TuiModel) handleMenuInputImportSet() tea.Cmd {
 		return tea.Tick(3*time.Second, func(time.Time) tea.Msg { return clearNotificationMsg{} })
 	}
 	m.menuPendingSrc = m.menuInput
+	if enc, _ := crypt.IsEncryptedFile(m.menuPendingSrc); enc {
+		m.menuAction = "import-set-passphrase"
+		m.menuInput = ""
+		return nil
+	}
+	m.menuAction = "import-set-policy"
+	m.menuInput = "rename"
+	return nil
+}
+
+func (m *TuiModel) handleMenuInputImportSetPassphrase() tea.Cmd {
+	m.menuPassphrase = []byte(m.menuInput)
 	m.menuAction = "import-set-policy"
 	m.menuInput = "rename"
 	return nil
@@ -206,34 +272,40 @@ func (m *TuiModel) handleMenuInputImportSetPolicy() tea.Cmd {
 		m.menuInput = "n"
 		return nil
 	}
-	if err := m.uiModel.ImportSet(context.Background(), m.menuPendingSrc, policy, false); err != nil {
+	if err := m.uiModel.ImportSet(m.bundleCtx(), m.menuPendingSrc, policy, false); err != nil {
 		m.logs = append(m.logs, "import error: "+err.Error())
 		m.setNotification("import error: " + err.Error())
 		m.menuPendingSrc = ""
+		m.menuPassphrase = nil
 		return tea.Tick(3*time.Second, func(time.Time) tea.Msg { return clearNotificationMsg{} })
 	}
-	m.logs = append(m.logs, "imported command set(s) from "+m.menuPendingSrc)
-	m.setNotification("imported command set(s) from " + m.menuPendingSrc)
+	msg := m.importedMessage("command set(s)")
+	m.logs = append(m.logs, msg)
+	m.setNotification(msg)
 	_ = m.uiModel.RefreshList(context.Background())
 	m.updateListFromCache()
 	m.menuPendingSrc = ""
+	m.menuPassphrase = nil
 	return tea.Tick(3*time.Second, func(time.Time) tea.Msg { return clearNotificationMsg{} })
 }
 
 func (m *TuiModel) handleMenuInputImportSetDedupe() tea.Cmd {
 	ded := strings.ToLower(strings.TrimSpace(m.menuInput))
 	dedupe := ded == "y" || ded == "yes"
-	if err := m.uiModel.ImportSet(context.Background(), m.menuPendingSrc, "merge", dedupe); err != nil {
+	if err := m.uiModel.ImportSet(m.bundleCtx(), m.menuPendingSrc, "merge", dedupe); err != nil {
 		m.logs = append(m.logs, "import error: "+err.Error())
 		m.setNotification("import error: " + err.Error())
 		m.menuPendingSrc = ""
+		m.menuPassphrase = nil
 		return tea.Tick(3*time.Second, func(time.Time) tea.Msg { return clearNotificationMsg{} })
 	}
-	m.logs = append(m.logs, "imported command set(s) from "+m.menuPendingSrc)
-	m.setNotification("imported command set(s) from " + m.menuPendingSrc)
+	msg := m.importedMessage("command set(s)")
+	m.logs = append(m.logs, msg)
+	m.setNotification(msg)
 	_ = m.uiModel.RefreshList(context.Background())
 	m.updateListFromCache()
 	m.menuPendingSrc = ""
+	m.menuPassphrase = nil
 	return tea.Tick(3*time.Second, func(time.Time) tea.Msg { return clearNotificationMsg{} })
 }
 
@@ -295,7 +367,32 @@ func (m *TuiModel) handleMenuInputUninstallConfirm() tea.Cmd {
 }
 
 func (m *TuiModel) handleMenuInputExportDB() tea.Cmd {
-	dst := m.menuInput
+	return m.exportDB(m.menuInput)
+}
+
+func (m *TuiModel) handleMenuInputExportDBEncrypted() tea.Cmd {
+	m.menuPendingSrc = m.menuInput
+	m.menuAction = "export-db-passphrase"
+	m.menuInput = ""
+	return nil
+}
+
+func (m *TuiModel) handleMenuInputExportDBPassphrase() tea.Cmd {
+	dst := m.menuPendingSrc
+	m.menuPendingSrc = ""
+	if m.menuInput == "" {
+		m.logs = append(m.logs, "export error: passphrase cannot be empty")
+		m.setNotification("export error: passphrase cannot be empty")
+		return tea.Tick(3*time.Second, func(time.Time) tea.Msg { return clearNotificationMsg{} })
+	}
+	m.menuPassphrase = []byte(m.menuInput)
+	defer func() { m.menuPassphrase = nil }()
+	return m.exportDB(dst)
+}
+
+// exportDB exports the database to dst, encrypted when a passphrase was
+// entered, falling back to the data dir when dst's directory is missing.
+func (m *TuiModel) exportDB(dst string) tea.Cmd {
 	parent := filepath.Dir(dst)
 	if stat, err := os.Stat(parent); err != nil || !stat.IsDir() {
 		if d, err2 := config.EnsureDataDir(); err2 == nil {
@@ -308,13 +405,17 @@ func (m *TuiModel) handleMenuInputExportDB() tea.Cmd {
 		}
 	}
 	dst = uniqueDestPath(dst)
-	if err := m.uiModel.Export(context.Background(), "", dst); err != nil {
+	if err := m.uiModel.Export(m.bundleCtx(), "", dst); err != nil {
 		m.logs = append(m.logs, "export error: "+err.Error())
 		m.setNotification("export error: " + err.Error())
 		return tea.Tick(3*time.Second, func(time.Time) tea.Msg { return clearNotificationMsg{} })
 	}
-	m.logs = append(m.logs, "exported database to "+dst)
-	m.setNotification("exported database to " + dst)
+	msg := "exported database to " + dst
+	if len(m.menuPassphrase) > 0 {
+		msg += " (encrypted)"
+	}
+	m.logs = append(m.logs, msg)
+	m.setNotification(msg)
 	return tea.Tick(3*time.Second, func(time.Time) tea.Msg { return clearNotificationMsg{} })
 }
 
@@ -380,6 +481,10 @@ func (m *TuiModel) renderMenuInputPrompt() string {
 	switch m.menuAction {
 	case "import-db":
 		return "Path: " + m.menuInput
+	case "import-db-passphrase", "import-set-passphrase":
+		return "Passphrase of the encrypted bundle: " + strings.Repeat("*", utf8.RuneCountInString(m.menuInput))
+	case "export-db-passphrase":
+		return "Passphrase to encrypt with: " + strings.Repeat("*", utf8.RuneCountInString(m.menuInput))
 	case "import-db-overwrite":
 		return "Overwrite destination DB if it exists? [y/N]: " + m.menuInput
 	case "import-set":
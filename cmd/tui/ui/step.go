@@ -0,0 +1,116 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/VoxDroid/krnr/internal/tui/adapters"
+	"github.com/VoxDroid/krnr/internal/tui/sanitize"
+	interactive "github.com/VoxDroid/krnr/internal/utils"
+)
+
+// stepHint is shown below a paused step.
+const stepHint = "[r]un  [s]kip  [e]dit once  s[h]ell  [a]bort"
+
+// stepShellDoneMsg is sent when the shell opened from a paused step exits.
+type stepShellDoneMsg struct{ err error }
+
+// handleStepPaused records a paused step and shows the available actions.
+func (m *TuiModel) handleStepPaused(ev adapters.RunEvent) {
+	m.stepPaused = true
+	m.stepCommand = ev.Command
+	m.logs = append(m.logs, sanitize.RunOutput(ev.Line), stepHint)
+	m.refreshRunView()
+}
+
+// refreshRunView renders run logs (plus the edit prompt while editing a
+// paused step) into the viewport and scrolls to the bottom.
+func (m *TuiModel) refreshRunView() {
+	content := strings.Join(m.logs, "\n")
+	if m.stepEditing {
+		content += "\nedit> " + m.stepEditBuf
+	}
+	m.vp.SetContent(content)
+	m.vp.GotoBottom()
+}
+
+// resetStepState clears step-through state when a run ends.
+func (m *TuiModel) resetStepState() {
+	m.stepPaused = false
+	m.stepEditing = false
+	m.stepEditBuf = ""
+	m.stepCommand = ""
+	m.runStepper = nil
+}
+
+// handleStepPauseKeys consumes keys while a run waits for a step decision so
+// they are neither forwarded to the process nor treated as global bindings.
+func (m *TuiModel) handleStepPauseKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
+	if !m.stepPaused || m.runStepper == nil {
+		return m, nil, false
+	}
+	if m.stepEditing {
+		return m.handleStepEditKey(msg)
+	}
+	action, ok := adapters.ParseStepAction(msg.String())
+	if !ok {
+		return m, nil, true
+	}
+	switch action {
+	case adapters.StepEdit:
+		m.stepEditing = true
+		m.stepEditBuf = m.stepCommand
+		m.refreshRunView()
+		return m, nil, true
+	case adapters.StepShell:
+		m.logs = append(m.logs, "opening shell (exit to return)")
+		m.refreshRunView()
+		return m, tea.ExecProcess(interactive.ShellCommand(), func(err error) tea.Msg { return stepShellDoneMsg{err: err} }), true
+	}
+	m.decideStep(adapters.StepDecision{Action: action})
+	return m, nil, true
+}
+
+func (m *TuiModel) handleStepEditKey(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
+	switch msg.Type {
+	case tea.KeyRunes, tea.KeySpace:
+		m.stepEditBuf += msg.String()
+	case tea.KeyBackspace, tea.KeyDelete:
+		m.stepEditBuf = trimLastRune(m.stepEditBuf)
+	case tea.KeyEsc:
+		m.stepEditing = false
+	case tea.KeyEnter:
+		m.stepEditing = false
+		if strings.TrimSpace(m.stepEditBuf) != "" {
+			m.logs = append(m.logs, "-> edited for this run: "+m.stepEditBuf)
+			m.decideStep(adapters.StepDecision{Action: adapters.StepEdit, Command: m.stepEditBuf})
+			return m, nil, true
+		}
+	}
+	m.refreshRunView()
+	return m, nil, true
+}
+
+// decideStep sends the decision for the paused step.
+func (m *TuiModel) decideStep(d adapters.StepDecision) {
+	if err := m.runStepper.Decide(d); err != nil {
+		m.logs = append(m.logs, "err: "+err.Error())
+	} else {
+		m.stepPaused = false
+	}
+	m.stepEditBuf = ""
+	m.refreshRunView()
+}
+
+// handleStepShellDone returns from the shell to the paused step.
+func (m *TuiModel) handleStepShellDone(msg stepShellDoneMsg) (tea.Model, tea.Cmd) {
+	line := "back from shell"
+	if msg.err != nil {
+		line = fmt.Sprintf("shell exited: %v", msg.err)
+	}
+	m.logs = append(m.logs, line, stepHint)
+	m.refreshRunView()
+	return m, nil
+}
@@ -0,0 +1,59 @@
+package ui
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/VoxDroid/krnr/internal/tui/adapters"
+	modelpkg "github.com/VoxDroid/krnr/internal/tui/model"
+)
+
+type fakeStepController struct{ got []adapters.StepDecision }
+
+func (f *fakeStepController) Decide(d adapters.StepDecision) error {
+	f.got = append(f.got, d)
+	return nil
+}
+
+func TestPausedStepKeys(t *testing.T) {
+	reg := &fakeRegistry{items: []adapters.CommandSetSummary{{Name: "one"}}}
+	ui := modelpkg.New(reg, &fakeExec{}, nil, nil)
+	_ = ui.RefreshList(context.Background())
+	m := initTestModel(NewModel(ui))
+	sc := &fakeStepController{}
+	m.runInProgress = true
+	m.runCapturesInput = true
+	m.runStepper = sc
+
+	m.handleRunEvent(adapters.RunEvent{Type: adapters.EventStepPaused, Step: 2, Command: "rm -rf build", Line: "|| paused before step 2: rm -rf build"})
+	if !m.stepPaused || !strings.Contains(strings.Join(m.logs, "\n"), stepHint) {
+		t.Fatalf("expected paused step with hint, logs: %v", m.logs)
+	}
+	// unrelated keys are swallowed while paused (no quit, no forwarding)
+	if _, cmd, handled := m.processKeyMsg(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")}); !handled || cmd != nil {
+		t.Fatalf("expected key to be swallowed while paused")
+	}
+
+	// edit once: prefilled with the command, then changed and submitted
+	m.processKeyMsg(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("e")})
+	if !m.stepEditing || m.stepEditBuf != "rm -rf build" {
+		t.Fatalf("expected edit mode with current command, got %q", m.stepEditBuf)
+	}
+	for i := 0; i < len("build"); i++ {
+		m.processKeyMsg(tea.KeyMsg{Type: tea.KeyBackspace})
+	}
+	m.processKeyMsg(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("dist")})
+	m.processKeyMsg(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.stepPaused || len(sc.got) != 1 || sc.got[0] != (adapters.StepDecision{Action: adapters.StepEdit, Command: "rm -rf dist"}) {
+		t.Fatalf("unexpected decisions: %+v", sc.got)
+	}
+
+	m.handleRunEvent(adapters.RunEvent{Type: adapters.EventStepPaused, Step: 3, Command: "deploy", Line: "|| paused before step 3: deploy"})
+	m.processKeyMsg(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("s")})
+	if len(sc.got) != 2 || sc.got[1].Action != adapters.StepSkip {
+		t.Fatalf("expected skip decision, got %+v", sc.got)
+	}
+}
@@ -34,6 +34,7 @@ type TuiModel struct {
 	menuIndex      int
 	menuInput      string // used when an item requires a path input (e.g., import db)
 	menuPendingSrc string // holds the source path while we prompt for additional options
+	menuPassphrase []byte // passphrase of an encrypted bundle being imported or exported
 	menuInputMode  bool   // whether the menu is in input mode
 	menuAction     string // action to perform on input confirmation
 
@@ -54,10 +55,20 @@ type TuiModel struct {
 	pendingExport     bool
 	pendingExportName string
 	pendingExportDest string
+	// run confirmation state, when the policy asks before a run
+	pendingRun        bool
+	pendingRunName    string
+	pendingRunStepAll bool
 	runInProgress     bool
 	logs              []string
 	cancelRun         func()
 	runCh             chan adapters.RunEvent
+	// step-through/breakpoint state: set while a run waits for a decision
+	runStepper  adapters.StepController
+	stepPaused  bool
+	stepCommand string
+	stepEditing bool
+	stepEditBuf string
 	// accessibility / theme
 	themeHighContrast bool
 	// track last selected name so we can detect changes and update preview
@@ -231,7 +242,10 @@ func (m *TuiModel) handleUpdateMsg(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.runCh = nil
 		m.runCapturesInput = false
 		m.runInputWriter = nil
+		m.resetStepState()
 		return m, nil
+	case stepShellDoneMsg:
+		return m.handleStepShellDone(msg)
 	case tea.WindowSizeMsg:
 		return m.handleWindowSizeWrapped(msg)
 	}
@@ -302,6 +316,14 @@ func (m *TuiModel) handleRunEvent(ev adapters.RunEvent) (tea.Model, tea.Cmd) {
 		m.runCh = nil
 		m.runCapturesInput = false
 		m.runInputWriter = nil
+		m.resetStepState()
+		return m, nil
+	}
+	if ev.Type == adapters.EventStepPaused {
+		m.handleStepPaused(ev)
+		if m.runCh != nil {
+			return m, readLoop(m.runCh)
+		}
 		return m, nil
 	}
 	// Sanitize run output to ensure control sequences cannot escape the
@@ -322,6 +344,8 @@ func (m *TuiModel) handleRunEvent(ev adapters.RunEvent) (tea.Model, tea.Cmd) {
 func (m *TuiModel) processKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
 	// handlers in prioritized order
 	handlers := []func(tea.KeyMsg) (tea.Model, tea.Cmd, bool){
+		// A paused step owns the keyboard until the user decides.
+		m.handleStepPauseKeys,
 		// If a run is in progress and we're capturing input, prioritize
 		// forwarding keys into the running process.
 		func(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) { return m.handleRunInputKeys(msg) },
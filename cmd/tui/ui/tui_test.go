@@ -727,16 +727,19 @@ type fakeImpExp struct {
 	lastPolicy    string
 	lastDedupe    bool
 	lastOverwrite bool
+	lastPass      string
 	reg           *replaceFakeRegistry
 }
 
-func (f *fakeImpExp) Export(_ context.Context, name string, dest string) error {
+func (f *fakeImpExp) Export(ctx context.Context, name string, dest string) error {
 	f.lastName = name
 	f.lastDest = dest
+	f.lastPass = string(adapters.Passphrase(ctx))
 	return nil
 }
-func (f *fakeImpExp) ImportSet(_ context.Context, src string, policy string, dedupe bool) error {
+func (f *fakeImpExp) ImportSet(ctx context.Context, src string, policy string, dedupe bool) error {
 	f.lastSrc = src
+	f.lastPass = string(adapters.Passphrase(ctx))
 	f.lastPolicy = policy
 	f.lastDedupe = dedupe
 	// simulate registry mutation by appending an imported set so UI can refresh
@@ -745,8 +748,9 @@ func (f *fakeImpExp) ImportSet(_ context.Context, src string, policy string, ded
 	}
 	return nil
 }
-func (f *fakeImpExp) ImportDB(_ context.Context, src string, overwrite bool) error {
+func (f *fakeImpExp) ImportDB(ctx context.Context, src string, overwrite bool) error {
 	f.lastSrc = src
+	f.lastPass = string(adapters.Passphrase(ctx))
 	f.lastOverwrite = overwrite
 	// simulate DB replace: set registry items to a DB-imported set
 	if f.reg != nil {
@@ -0,0 +1,105 @@
+package ui
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/VoxDroid/krnr/internal/tui/adapters"
+	modelpkg "github.com/VoxDroid/krnr/internal/tui/model"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func newEncryptTestModel(t *testing.T) (*TuiModel, *fakeImpExp) {
+	t.Helper()
+	reg := &replaceFakeRegistry{items: []adapters.CommandSetSummary{{Name: "one", Description: "First"}}}
+	imp := &fakeImpExp{reg: reg}
+	ui := modelpkg.New(reg, &fakeExec{}, imp, nil)
+	_ = ui.RefreshList(context.Background())
+	m := NewModel(ui)
+	m = initTestModel(m)
+	m1, _ := m.Update(tea.WindowSizeMsg{Width: 80, Height: 20})
+	return m1.(*TuiModel), imp
+}
+
+func enter(m *TuiModel, input string) *TuiModel {
+	m.menuInput = input
+	m2, _ := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	return m2.(*TuiModel)
+}
+
+func TestMenuExportEncryptedDatabase(t *testing.T) {
+	m, imp := newEncryptTestModel(t)
+	dst := filepath.Join(t.TempDir(), "out.db")
+
+	m = selectMenuItem(t, m, "Export encrypted database")
+	if !m.menuInputMode || m.menuAction != "export-db-encrypted" {
+		t.Fatalf("expected export-db-encrypted prompt mode, got %v %q", m.menuInputMode, m.menuAction)
+	}
+	m = enter(m, dst)
+	if m.menuAction != "export-db-passphrase" {
+		t.Fatalf("expected passphrase prompt stage, got %q", m.menuAction)
+	}
+	m.menuInput = "s3cret"
+	if p := m.renderMenuInputPrompt(); strings.Contains(p, "s3cret") || !strings.Contains(p, "******") {
+		t.Fatalf("expected the passphrase to be masked, got %q", p)
+	}
+	m = enter(m, "s3cret")
+	if imp.lastDest != dst || imp.lastPass != "s3cret" {
+		t.Fatalf("unexpected export params: %q %q", imp.lastDest, imp.lastPass)
+	}
+	if !strings.Contains(m.notification, "(encrypted)") || m.menuPassphrase != nil {
+		t.Fatalf("expected an encrypted export notification, got %q", m.notification)
+	}
+
+	// an empty passphrase is refused rather than exporting in plaintext
+	imp.lastDest = ""
+	m = selectMenuItem(t, m, "Export encrypted database")
+	m = enter(m, dst)
+	m = enter(m, "")
+	if imp.lastDest != "" || !strings.Contains(m.notification, "passphrase cannot be empty") {
+		t.Fatalf("expected an empty passphrase to be refused, got %q %q", imp.lastDest, m.notification)
+	}
+}
+
+func TestMenuImportEncryptedBundleAsksPassphrase(t *testing.T) {
+	m, imp := newEncryptTestModel(t)
+	src := filepath.Join(t.TempDir(), "enc.db")
+	_ = os.WriteFile(src, []byte("KRNRENC1 ciphertext"), 0o644)
+
+	m = selectMenuItem(t, m, "Import set")
+	m = enter(m, src)
+	if m.menuAction != "import-set-passphrase" {
+		t.Fatalf("expected passphrase prompt stage, got %q", m.menuAction)
+	}
+	m = enter(m, "s3cret")
+	if m.menuAction != "import-set-policy" {
+		t.Fatalf("expected import-set-policy stage, got %q", m.menuAction)
+	}
+	m = enter(m, "rename")
+	if imp.lastSrc != src || imp.lastPass != "s3cret" || m.menuPassphrase != nil {
+		t.Fatalf("unexpected import params: %q %q", imp.lastSrc, imp.lastPass)
+	}
+
+	m = selectMenuItem(t, m, "Import database")
+	m = enter(m, src)
+	if m.menuAction != "import-db-passphrase" {
+		t.Fatalf("expected passphrase prompt stage, got %q", m.menuAction)
+	}
+	m = enter(m, "other")
+	m = enter(m, "n")
+	if imp.lastPass != "other" || !strings.Contains(m.notification, "imported database") {
+		t.Fatalf("unexpected import: %q %q", imp.lastPass, m.notification)
+	}
+
+	// plaintext bundles skip the passphrase prompt
+	plain := filepath.Join(t.TempDir(), "plain.db")
+	_ = os.WriteFile(plain, []byte("x"), 0o644)
+	m = selectMenuItem(t, m, "Import database")
+	m = enter(m, plain)
+	if m.menuAction != "import-db-overwrite" {
+		t.Fatalf("expected no passphrase prompt for a plaintext bundle, got %q", m.menuAction)
+	}
+}
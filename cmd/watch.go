@@ -0,0 +1,271 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/executor"
+	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/runlog"
+	"github.com/VoxDroid/krnr/internal/runner"
+	"github.com/VoxDroid/krnr/internal/tui/adapters"
+	"github.com/VoxDroid/krnr/internal/watch"
+)
+
+// watchContext returns the context that ends `krnr watch`; tests replace it.
+var watchContext = func() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt)
+}
+
+// watchSpec combines the set's saved watch definition with the flags given
+// on this invocation; flags override the saved fields they name.
+func watchSpec(cmd *cobra.Command, saved *registry.Watch) watch.Spec {
+	var spec watch.Spec
+	if saved != nil {
+		spec = watch.Spec{Paths: saved.Paths, Include: saved.Include, Exclude: saved.Exclude, Debounce: saved.Debounce}
+	}
+	if cmd.Flags().Changed("path") {
+		spec.Paths, _ = cmd.Flags().GetStringArray("path")
+	}
+	if cmd.Flags().Changed("include") {
+		spec.Include, _ = cmd.Flags().GetStringArray("include")
+	}
+	if cmd.Flags().Changed("exclude") {
+		spec.Exclude, _ = cmd.Flags().GetStringArray("exclude")
+	}
+	if cmd.Flags().Changed("debounce") {
+		spec.Debounce, _ = cmd.Flags().GetDuration("debounce")
+	}
+	return spec
+}
+
+// watchRun is one run started by `krnr watch`.
+type watchRun struct {
+	handle    adapters.RunHandle
+	done      chan struct{}
+	cancelled atomic.Bool
+}
+
+// stop cancels the run (through RunHandle.Cancel, like the TUI) and waits
+// for it to wind down. It reports whether the run was still in progress.
+func (wr *watchRun) stop() bool {
+	select {
+	case <-wr.done:
+		return false
+	default:
+	}
+	wr.cancelled.Store(true)
+	wr.handle.Cancel()
+	<-wr.done
+	return true
+}
+
+// watchLoop re-runs a set whenever the watched files change.
+type watchLoop struct {
+	r      *registry.Repository
+	name   string
+	exec   adapters.ExecutorAdapter
+	params *runParams
+	force  bool
+	// clear clears the terminal before each run instead of printing a
+	// separator line.
+	clear bool
+	n     int
+}
+
+// start loads the set (picking up edits made since the previous run),
+// resolves its parameters and starts it.
+func (l *watchLoop) start(ctx context.Context, reason string) (*watchRun, error) {
+	l.n++
+	if l.clear {
+		fmt.Print("\x1b[H\x1b[2J")
+	}
+	fmt.Printf("== run #%d: %s ==\n", l.n, reason)
+	opts := runner.Options{Trigger: "watch", Policy: userPolicy, Force: l.force, ForceFlag: "--force", Hooks: userHooks, Stderr: os.Stderr}
+	l.params.apply(&opts)
+	run, err := runner.Prepare(l.r, l.name, opts)
+	if err != nil {
+		return nil, err
+	}
+	cmds, redacted, err := run.Commands()
+	if err != nil {
+		return nil, err
+	}
+	if err := run.Start(ctx); err != nil {
+		return nil, err
+	}
+	h, err := runRedacted(adapters.WithRunID(ctx, run.ID), l.exec, l.name, cmds, redacted)
+	if err != nil {
+		return nil, run.Finish(ctx, err, nil)
+	}
+	wr := &watchRun{handle: h, done: make(chan struct{})}
+	n := l.n
+	go func() {
+		defer close(wr.done)
+		var runErr error
+		for ev := range h.Events() {
+			switch {
+			case ev.Err != nil:
+				runErr = ev.Err
+			case ev.Type == adapters.EventStepStarted && ev.Step >= 1 && ev.Step <= len(redacted):
+				fmt.Printf("-> %s\n", redacted[ev.Step-1])
+			case ev.Line != "":
+				fmt.Println(ev.Line)
+			}
+		}
+		// on-failure hooks fire for cancelled runs too
+		runErr = run.Finish(context.WithoutCancel(ctx), runErr, nil)
+		elapsed := run.Elapsed()
+		switch {
+		case wr.cancelled.Load():
+			fmt.Printf("== run #%d cancelled ==\n", n)
+		case runErr != nil:
+			fmt.Printf("== run #%d failed after %s: %v ==\n", n, elapsed.Round(time.Millisecond), runErr)
+		default:
+			fmt.Printf("== run #%d ok in %s, watching for changes ==\n", n, elapsed.Round(time.Millisecond))
+		}
+	}()
+	return wr, nil
+}
+
+// changeReason summarises a batch of changed files for the run header.
+func changeReason(changed []string) string {
+	if len(changed) == 1 {
+		return changed[0] + " changed"
+	}
+	shown := changed
+	if len(shown) > 3 {
+		shown = shown[:3]
+	}
+	more := ""
+	if len(changed) > len(shown) {
+		more = ", ..."
+	}
+	return fmt.Sprintf("%d files changed (%s%s)", len(changed), strings.Join(shown, ", "), more)
+}
+
+var watchCmd = &cobra.Command{
+	Use:   "watch <name>",
+	Short: "Re-run a command set whenever files change",
+	Long: `Run a command set, then re-run it whenever matching files change. A change
+while a run is in progress cancels that run and starts a new one.
+
+--path (default "."), --include and --exclude select the files; globs match
+the file name or its path relative to the watched directory. Hidden
+directories such as .git are skipped. --save stores the definition on the
+set so that 'krnr watch <name>' alone reuses it; flags given later override
+the saved fields they name.
+
+Examples:
+  krnr watch test --path src --include '*.go' --debounce 300ms --save
+  krnr watch test
+  krnr watch test --forget`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		save, _ := cmd.Flags().GetBool("save")
+		forget, _ := cmd.Flags().GetBool("forget")
+		clearScreen, _ := cmd.Flags().GetBool("clear")
+		force, _ := cmd.Flags().GetBool("force")
+		noLog, _ := cmd.Flags().GetBool("no-log")
+		paramVals, _ := cmd.Flags().GetStringArray("param")
+
+		dbConn, err := db.InitDB()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = dbConn.Close() }()
+
+		r := registry.NewRepository(dbConn)
+		if forget {
+			if err := r.DeleteWatch(name); err != nil {
+				return err
+			}
+			fmt.Printf("removed saved watch definition from '%s'\n", name)
+			return nil
+		}
+		saved, err := r.GetWatch(name)
+		if err != nil {
+			return err
+		}
+		w, err := watch.New(watchSpec(cmd, saved))
+		if err != nil {
+			return err
+		}
+		spec := w.Spec()
+		if save {
+			if err := r.SaveWatch(name, registry.Watch{Paths: spec.Paths, Include: spec.Include, Exclude: spec.Exclude, Debounce: spec.Debounce}); err != nil {
+				return err
+			}
+			fmt.Printf("saved watch definition on '%s'\n", name)
+		}
+		rp, err := parseParamFlags(paramVals)
+		if err != nil {
+			return err
+		}
+
+		runner := execFactory(false, false)
+		if ex, ok := runner.(*executor.Executor); ok && cmd.Flags().Changed("shell") {
+			ex.Shell, _ = cmd.Flags().GetString("shell")
+		}
+		ea := adapters.NewLoggingExecutorAdapter(runner, runlog.Configured(settings))
+		if noLog {
+			ea = adapters.NewExecutorAdapter(runner)
+		}
+		l := &watchLoop{r: r, name: name, exec: ea, params: rp, force: force,
+			clear: clearScreen && executor.IsTerminal(os.Stdout.Fd())}
+
+		ctx, stop := watchContext()
+		defer stop()
+		fmt.Printf("watching %d file(s) in %s (Ctrl-C to stop)\n", w.Len(), strings.Join(spec.Paths, ", "))
+		changes := w.Changes(ctx)
+		cur, err := l.start(ctx, "initial run")
+		if err != nil {
+			// a set that fails to start may be fixed before the next change
+			fmt.Printf("== run #%d could not start: %v ==\n", l.n, err)
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				if cur != nil {
+					cur.stop()
+				}
+				return nil
+			case changed, ok := <-changes:
+				if !ok {
+					changes = nil
+					continue
+				}
+				if cur != nil && cur.stop() {
+					fmt.Println("change detected, restarting")
+				}
+				if cur, err = l.start(ctx, changeReason(changed)); err != nil {
+					fmt.Printf("== run #%d could not start: %v ==\n", l.n, err)
+				}
+			}
+		}
+	},
+}
+
+func init() {
+	watchCmd.Flags().StringArray("path", []string{}, "File or directory to watch, recursively (repeatable; default \".\")")
+	watchCmd.Flags().StringArray("include", []string{}, "Only react to files matching this glob, e.g. '*.go' (repeatable)")
+	watchCmd.Flags().StringArray("exclude", []string{}, "Ignore files and directories matching this glob (repeatable)")
+	watchCmd.Flags().Duration("debounce", watch.DefaultDebounce, "Wait until changes settle for this long before re-running")
+	watchCmd.Flags().Bool("save", false, "Save the watch definition on the set for later 'krnr watch <name>'")
+	watchCmd.Flags().Bool("forget", false, "Remove the set's saved watch definition and exit")
+	watchCmd.Flags().Bool("clear", false, "Clear the terminal before each run instead of printing a separator")
+	watchCmd.Flags().StringArray("param", []string{}, "Parameter values as name=value (repeatable); prompted once when missing")
+	watchCmd.Flags().Bool("force", false, "Override safety checks and force execution")
+	watchCmd.Flags().Bool("no-log", false, "Do not persist run output under KRNR_HOME/logs")
+	watchCmd.Flags().String("shell", "", "Override shell to execute commands (e.g., pwsh, bash, cmd; default from config 'shell')")
+	rootCmd.AddCommand(watchCmd)
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/executor"
+	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/runner"
+)
+
+// blockingRunner reports each command it starts and then runs until its
+// context is cancelled.
+type blockingRunner struct{ started chan string }
+
+func (b *blockingRunner) Execute(ctx context.Context, command, _ string, _ io.Reader, stdout io.Writer, _ io.Writer) error {
+	_, _ = io.WriteString(stdout, "building\n")
+	b.started <- command
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func resetWatchFlags() {
+	for _, name := range []string{"path", "include", "exclude", "param"} {
+		_ = watchCmd.Flags().Lookup(name).Value.(interface{ Replace([]string) error }).Replace(nil)
+	}
+	for _, name := range []string{"save", "no-log"} {
+		_ = watchCmd.Flags().Set(name, "false")
+	}
+}
+
+func TestWatchRestartsRunOnChange(t *testing.T) {
+	setupTempDB(t)
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	defer func() { _ = dbConn.Close() }()
+	r := registry.NewRepository(dbConn)
+	if _, err := r.CreateCommandSet("build", nil, nil, nil, []string{"make {{target}}"}); err != nil {
+		t.Fatalf("CreateCommandSet: %v", err)
+	}
+	src := t.TempDir()
+	file := filepath.Join(src, "main.go")
+	if err := os.WriteFile(file, []byte("package main"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	runner := &blockingRunner{started: make(chan string)}
+	origExec, origCtx := execFactory, watchContext
+	defer func() { execFactory, watchContext = origExec, origCtx }()
+	execFactory = func(_, _ bool) executor.Runner { return runner }
+	ctx, cancel := context.WithCancel(context.Background())
+	watchContext = func() (context.Context, context.CancelFunc) { return ctx, cancel }
+	defer resetWatchFlags()
+
+	go func() {
+		if got := <-runner.started; got != "make all" {
+			t.Errorf("unexpected command: %s", got)
+		}
+		later := time.Now().Add(time.Minute)
+		_ = os.WriteFile(file, []byte("package main // edited"), 0o600)
+		_ = os.Chtimes(file, later, later)
+		<-runner.started
+		cancel()
+	}()
+	out, _ := captureOutput(func() {
+		rootCmd.SetArgs([]string{"watch", "build", "--path", src, "--include", "*.go", "--debounce", "20ms", "--save", "--no-log", "--param", "target=all"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("watch failed: %v", err)
+		}
+	})
+	for _, want := range []string{
+		"saved watch definition on 'build'",
+		"== run #1: initial run ==",
+		"-> make all",
+		"building",
+		"== run #1 cancelled ==",
+		"change detected, restarting",
+		"== run #2: " + file + " changed ==",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %q in output:\n%s", want, out)
+		}
+	}
+
+	w, err := r.GetWatch("build")
+	if err != nil || w == nil || len(w.Paths) != 1 || w.Paths[0] != src || w.Include[0] != "*.go" || w.Debounce != 20*time.Millisecond {
+		t.Fatalf("unexpected saved watch: %+v %v", w, err)
+	}
+	runs, _ := r.ListRuns("build", 0)
+	if len(runs) != 2 || runs[0].Trigger != "watch" {
+		t.Fatalf("unexpected run history: %+v", runs)
+	}
+}
+
+func TestWatchTakesExclusiveLease(t *testing.T) {
+	setupTempDB(t)
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	defer func() { _ = dbConn.Close() }()
+	r := registry.NewRepository(dbConn)
+	if _, err := r.CreateCommandSet("deploy", nil, nil, nil, []string{"echo deploy"}); err != nil {
+		t.Fatalf("CreateCommandSet: %v", err)
+	}
+	if err := r.SetExclusive("deploy", true); err != nil {
+		t.Fatalf("SetExclusive: %v", err)
+	}
+
+	blocking := &blockingRunner{started: make(chan string)}
+	origExec, origCtx := execFactory, watchContext
+	defer func() { execFactory, watchContext = origExec, origCtx }()
+	execFactory = func(_, _ bool) executor.Runner { return blocking }
+	ctx, cancel := context.WithCancel(context.Background())
+	watchContext = func() (context.Context, context.CancelFunc) { return ctx, cancel }
+	defer resetWatchFlags()
+
+	var busy error
+	go func() {
+		<-blocking.started
+		// another run of the set is refused while the watch run holds the lease
+		run, err := runner.Prepare(r, "deploy", runner.Options{})
+		if err == nil {
+			busy = run.Start(context.Background())
+		}
+		cancel()
+	}()
+	_, _ = captureOutput(func() {
+		rootCmd.SetArgs([]string{"watch", "deploy", "--path", t.TempDir(), "--no-log"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("watch failed: %v", err)
+		}
+	})
+	if !errors.Is(busy, runner.ErrBusy) {
+		t.Fatalf("expected the watch run to hold the lease, got %v", busy)
+	}
+	if l, _ := r.GetLease("deploy"); l != nil {
+		t.Fatalf("expected the lease to be released when the watch stops, got %+v", l)
+	}
+
+	// a watch run of a set another run holds fails to start
+	other := registry.Lease{SetName: "deploy", Token: "other", Owner: "alice", PID: 4242, Host: "build-01"}
+	if ok, _, err := r.AcquireLease(other, time.Minute); err != nil || !ok {
+		t.Fatalf("AcquireLease: %v %v", ok, err)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	out, _ := captureOutput(func() {
+		rootCmd.SetArgs([]string{"watch", "deploy", "--path", t.TempDir(), "--no-log"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("watch failed: %v", err)
+		}
+	})
+	if !strings.Contains(out, "== run #1 could not start: set deploy is exclusive and already running: lease held by alice") {
+		t.Fatalf("expected the run to be refused, got:\n%s", out)
+	}
+}
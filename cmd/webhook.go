@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/hooks"
+	"github.com/VoxDroid/krnr/internal/registry"
+)
+
+var webhookCmd = &cobra.Command{
+	Use:   "webhook",
+	Short: "Manage which command sets can be triggered by webhooks",
+	Long:  "Manage hook-enabled command sets: enable, disable, list, secret. Enabled sets are served by 'krnr serve hooks'.",
+}
+
+var webhookEnableCmd = &cobra.Command{
+	Use:   "enable <set-name>",
+	Short: "Allow a command set to be triggered by 'krnr serve hooks'",
+	Long: `Allow a command set to be triggered by 'krnr serve hooks' and print its new
+HMAC secret. Requests must be signed with the secret; see 'krnr serve hooks --help'.
+--rotate replaces the secret of a set that is already enabled.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		rotate, _ := cmd.Flags().GetBool("rotate")
+
+		dbConn, err := db.InitDB()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = dbConn.Close() }()
+
+		r := registry.NewRepository(dbConn)
+		_, enabled, err := r.WebhookSecret(name)
+		if err != nil {
+			return err
+		}
+		if enabled && !rotate {
+			return fmt.Errorf("webhook for '%s' is already enabled (use --rotate to replace its secret)", name)
+		}
+		secret, err := hooks.NewSecret()
+		if err != nil {
+			return err
+		}
+		if err := r.EnableWebhook(name, secret); err != nil {
+			return err
+		}
+		fmt.Printf("enabled webhook for '%s'\nsecret: %s\n\n", name, secret)
+		fmt.Printf("example:\n  body='{}'\n  ts=$(date +%%s)\n  sig=$(printf '%%s.%%s' \"$ts\" \"$body\" | openssl dgst -sha256 -hmac '%s' | sed 's/^.* //')\n", secret)
+		fmt.Printf("  curl -X POST -H \"%s: $ts\" -H \"%s: sha256=$sig\" -d \"$body\" http://127.0.0.1:8787/hooks/%s\n", hooks.TimestampHeader, hooks.SignatureHeader, name)
+		return nil
+	},
+}
+
+var webhookDisableCmd = &cobra.Command{
+	Use:   "disable <set-name>",
+	Short: "Stop a command set from being triggered by webhooks",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		dbConn, err := db.InitDB()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = dbConn.Close() }()
+
+		r := registry.NewRepository(dbConn)
+		if err := r.DisableWebhook(args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("disabled webhook for '%s'\n", args[0])
+		return nil
+	},
+}
+
+var webhookListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List hook-enabled command sets",
+	Args:  cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		dbConn, err := db.InitDB()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = dbConn.Close() }()
+
+		r := registry.NewRepository(dbConn)
+		list, err := r.ListWebhooks()
+		if err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			fmt.Println("no hook-enabled sets")
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "SET\tENDPOINT\tENABLED")
+		for _, w := range list {
+			_, _ = fmt.Fprintf(tw, "%s\tPOST /hooks/%s\t%s\n", w.SetName, w.SetName, w.CreatedAt)
+		}
+		return tw.Flush()
+	},
+}
+
+var webhookSecretCmd = &cobra.Command{
+	Use:   "secret <set-name>",
+	Short: "Print the HMAC secret of a hook-enabled command set",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		dbConn, err := db.InitDB()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = dbConn.Close() }()
+
+		r := registry.NewRepository(dbConn)
+		secret, ok, err := r.WebhookSecret(args[0])
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("no webhook enabled for '%s'", args[0])
+		}
+		fmt.Println(secret)
+		return nil
+	},
+}
+
+func init() {
+	webhookEnableCmd.Flags().Bool("rotate", false, "Replace the secret of an already enabled webhook")
+	webhookCmd.AddCommand(webhookEnableCmd)
+	webhookCmd.AddCommand(webhookDisableCmd)
+	webhookCmd.AddCommand(webhookListCmd)
+	webhookCmd.AddCommand(webhookSecretCmd)
+	rootCmd.AddCommand(webhookCmd)
+}
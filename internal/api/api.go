@@ -0,0 +1,168 @@
+// Package api implements the versioned JSON REST API behind `krnr serve api`.
+// Routes are declared once in a table that drives both the HTTP mux and the
+// generated OpenAPI document, so the two cannot drift apart.
+package api
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/VoxDroid/krnr/internal/config"
+	"github.com/VoxDroid/krnr/internal/httpjson"
+	"github.com/VoxDroid/krnr/internal/registry"
+)
+
+// Version is the API version; it prefixes every route ("/v1/...").
+const Version = "v1"
+
+// TokenEnv overrides the token file when set.
+const TokenEnv = "KRNR_API_TOKEN"
+
+// maxBody bounds request bodies.
+const maxBody = 1 << 20
+
+// Runner starts runs of command sets in the background.
+type Runner interface {
+	// Start validates params and starts a run of set, returning its run ID.
+	// Errors may implement `HTTPStatus() int` to choose the response status.
+	Start(set string, params map[string]string) (string, error)
+}
+
+// Options configures a Server.
+type Options struct {
+	Repo   *registry.Repository
+	Runner Runner
+	// Events receives the events of runs started by Runner; nil disables
+	// the event stream endpoint.
+	Events *Broker
+	// Token is the bearer token clients must present.
+	Token string
+	// Log receives one line per request; nil discards.
+	Log io.Writer
+}
+
+// Server answers API requests.
+type Server struct {
+	opts Options
+	mux  *http.ServeMux
+}
+
+// NewServer returns a server for opts. It fails when no token is configured.
+func NewServer(opts Options) (*Server, error) {
+	if opts.Token == "" {
+		return nil, errors.New("api: a token is required")
+	}
+	if opts.Log == nil {
+		opts.Log = io.Discard
+	}
+	s := &Server{opts: opts, mux: http.NewServeMux()}
+	for _, rt := range s.routes() {
+		s.mux.Handle(rt.Method+" "+rt.Path, s.handler(rt))
+	}
+	return s, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	httpjson.ServeLogged(s.mux, s.opts.Log, w, r)
+}
+
+// handler wraps a route with authentication and JSON encoding.
+func (s *Server) handler(rt route) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !rt.Public && !s.authorized(r) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="krnr"`)
+			httpjson.WriteError(w, &httpjson.StatusError{Code: http.StatusUnauthorized, Err: errors.New("missing or invalid bearer token")})
+			return
+		}
+		if rt.Stream != nil {
+			if err := rt.Stream(w, r); err != nil {
+				httpjson.WriteError(w, err)
+			}
+			return
+		}
+		v, err := rt.Handle(r)
+		if err != nil {
+			httpjson.WriteError(w, err)
+			return
+		}
+		if v == nil {
+			w.WriteHeader(rt.status())
+			return
+		}
+		httpjson.WriteJSON(w, rt.status(), v)
+	})
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(strings.TrimSpace(got)), []byte(s.opts.Token)) == 1
+}
+
+func notFound(format string, args ...any) error {
+	return &httpjson.StatusError{Code: http.StatusNotFound, Err: fmt.Errorf(format, args...)}
+}
+
+func badRequest(err error) error {
+	return &httpjson.StatusError{Code: http.StatusBadRequest, Err: err}
+}
+
+// decode reads a JSON request body into v, rejecting unknown fields.
+func decode(r *http.Request, v any) error {
+	dec := json.NewDecoder(io.LimitReader(r.Body, maxBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil && err != io.EOF {
+		return badRequest(fmt.Errorf("invalid request body: %v", err))
+	}
+	return nil
+}
+
+// TokenPath returns the file holding the API token (KRNR_HOME/api-token).
+func TokenPath() (string, error) {
+	d, err := config.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(d, "api-token"), nil
+}
+
+// LoadOrCreateToken returns the API token: KRNR_API_TOKEN when set,
+// otherwise the contents of the token file, which is created with a random
+// token (readable only by the owner) on first use.
+func LoadOrCreateToken() (string, error) {
+	if v := strings.TrimSpace(os.Getenv(TokenEnv)); v != "" {
+		return v, nil
+	}
+	p, err := TokenPath()
+	if err != nil {
+		return "", err
+	}
+	if b, err := os.ReadFile(p); err == nil {
+		if tok := strings.TrimSpace(string(b)); tok != "" {
+			return tok, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	tok := hex.EncodeToString(buf)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(p, []byte(tok+"\n"), 0o600); err != nil {
+		return "", err
+	}
+	return tok, nil
+}
@@ -0,0 +1,304 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VoxDroid/krnr/internal/config"
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/httpjson"
+	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/tui/adapters"
+)
+
+const testToken = "t0ken"
+
+// fakeRunner publishes a short run to the broker; release lets the test
+// decide when the run finishes.
+type fakeRunner struct {
+	events  *Broker
+	release chan struct{}
+	params  map[string]string
+}
+
+func (f *fakeRunner) Start(set string, params map[string]string) (string, error) {
+	if set == "refused" {
+		return "", &httpjson.StatusError{Code: http.StatusForbidden, Err: errors.New("refused")}
+	}
+	f.params = params
+	em := adapters.NewEventEmitter("run-1", f.events.Publish)
+	em.Emit(adapters.RunEvent{Type: adapters.EventRunStarted, Set: set, Steps: 1})
+	go func() {
+		<-f.release
+		em.Emit(adapters.RunEvent{Type: adapters.EventOutput, Step: 1, Line: "hello"})
+		em.Emit(adapters.RunEvent{Type: adapters.EventRunFinished, Set: set})
+	}()
+	return "run-1", nil
+}
+
+func newTestServer(t *testing.T) (*registry.Repository, *fakeRunner, *httptest.Server) {
+	t.Helper()
+	tmp := t.TempDir()
+	old := os.Getenv(config.EnvKRNRDB)
+	_ = os.Setenv(config.EnvKRNRDB, filepath.Join(tmp, "krnr_test.db"))
+	t.Cleanup(func() { _ = os.Setenv(config.EnvKRNRDB, old) })
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB(): %v", err)
+	}
+	t.Cleanup(func() { _ = dbConn.Close() })
+	repo := registry.NewRepository(dbConn)
+	events := NewBroker()
+	fr := &fakeRunner{events: events, release: make(chan struct{})}
+	srv, err := NewServer(Options{Repo: repo, Runner: fr, Events: events, Token: testToken})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	return repo, fr, ts
+}
+
+func call(t *testing.T, ts *httptest.Server, method, path, body string, out any) int {
+	t.Helper()
+	req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if out != nil {
+		_ = json.NewDecoder(resp.Body).Decode(out)
+	}
+	return resp.StatusCode
+}
+
+func TestNewServerRequiresToken(t *testing.T) {
+	if _, err := NewServer(Options{}); err == nil {
+		t.Fatalf("expected an error without a token")
+	}
+}
+
+func TestAuth(t *testing.T) {
+	_, _, ts := newTestServer(t)
+	for _, auth := range []string{"", "Bearer wrong", testToken} {
+		req, _ := http.NewRequest("GET", ts.URL+"/v1/sets", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET: %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("Authorization %q: got %d, want 401", auth, resp.StatusCode)
+		}
+	}
+	resp, err := http.Get(ts.URL + "/v1/openapi.json")
+	if err != nil {
+		t.Fatalf("GET openapi: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("openapi document should be public, got %d", resp.StatusCode)
+	}
+}
+
+func TestSetLifecycle(t *testing.T) {
+	_, _, ts := newTestServer(t)
+
+	var set Set
+	if code := call(t, ts, "POST", "/v1/sets", `{"name":"deploy","description":"ship it","tags":["ops"],"commands":["echo {{branch}}","make"]}`, &set); code != http.StatusCreated {
+		t.Fatalf("create: %d", code)
+	}
+	if set.Name != "deploy" || len(set.Commands) != 2 || len(set.Params) != 1 || set.Params[0] != "branch" || set.Tags[0] != "ops" {
+		t.Fatalf("unexpected set: %+v", set)
+	}
+	if code := call(t, ts, "POST", "/v1/sets", `{"name":"deploy","commands":["x"]}`, nil); code != http.StatusConflict {
+		t.Fatalf("duplicate create: got %d, want 409", code)
+	}
+	if code := call(t, ts, "POST", "/v1/sets", `{"name":"x","bogus":1}`, nil); code != http.StatusBadRequest {
+		t.Fatalf("unknown field: got %d, want 400", code)
+	}
+
+	var list []SetSummary
+	if code := call(t, ts, "GET", "/v1/sets?tag=ops", "", &list); code != http.StatusOK || len(list) != 1 {
+		t.Fatalf("list by tag: %d %+v", code, list)
+	}
+	if code := call(t, ts, "GET", "/v1/search?q=dply&fuzzy=true", "", &list); code != http.StatusOK || len(list) != 1 {
+		t.Fatalf("fuzzy search: %d %+v", code, list)
+	}
+	if code := call(t, ts, "GET", "/v1/search?q=nothing-like-it", "", &list); code != http.StatusOK || len(list) != 0 {
+		t.Fatalf("search: %d %+v", code, list)
+	}
+
+	if code := call(t, ts, "PATCH", "/v1/sets/deploy", `{"commands":["echo v2"]}`, &set); code != http.StatusOK {
+		t.Fatalf("update: %d", code)
+	}
+	if set.Description != "ship it" || len(set.Commands) != 1 || set.Commands[0] != "echo v2" || set.Tags[0] != "ops" {
+		t.Fatalf("update should keep omitted fields: %+v", set)
+	}
+	if code := call(t, ts, "PUT", "/v1/sets/deploy/tags/prod", "", &set); code != http.StatusOK || len(set.Tags) != 2 {
+		t.Fatalf("add tag: %d %+v", code, set.Tags)
+	}
+	if code := call(t, ts, "DELETE", "/v1/sets/deploy/tags/ops", "", &set); code != http.StatusOK || len(set.Tags) != 1 || set.Tags[0] != "prod" {
+		t.Fatalf("remove tag: %d %+v", code, set.Tags)
+	}
+
+	var versions []SetVersion
+	if code := call(t, ts, "GET", "/v1/sets/deploy/versions", "", &versions); code != http.StatusOK || len(versions) < 2 {
+		t.Fatalf("versions: %d %+v", code, versions)
+	}
+	first := versions[len(versions)-1]
+	if code := call(t, ts, "POST", "/v1/sets/deploy/rollback", `{"version":`+strconv.Itoa(first.Version)+`}`, &set); code != http.StatusOK || len(set.Commands) != 2 {
+		t.Fatalf("rollback: %d %+v", code, set)
+	}
+	if code := call(t, ts, "POST", "/v1/sets/deploy/rollback", `{"version":999}`, nil); code != http.StatusNotFound {
+		t.Fatalf("rollback to unknown version: got %d, want 404", code)
+	}
+
+	if code := call(t, ts, "PATCH", "/v1/sets/deploy", `{"name":"release"}`, &set); code != http.StatusOK || set.Name != "release" {
+		t.Fatalf("rename: %d %+v", code, set)
+	}
+	if code := call(t, ts, "DELETE", "/v1/sets/release", "", nil); code != http.StatusNoContent {
+		t.Fatalf("delete: %d", code)
+	}
+	if code := call(t, ts, "GET", "/v1/sets/release", "", nil); code != http.StatusNotFound {
+		t.Fatalf("get deleted: got %d, want 404", code)
+	}
+}
+
+func TestRunAndStreamEvents(t *testing.T) {
+	_, fr, ts := newTestServer(t)
+
+	var started RunStarted
+	if code := call(t, ts, "POST", "/v1/sets/deploy/runs", `{"params":{"branch":"main"}}`, &started); code != http.StatusAccepted {
+		t.Fatalf("start run: %d", code)
+	}
+	if started.RunID != "run-1" || started.EventsURL != "/v1/runs/run-1/events" || fr.params["branch"] != "main" {
+		t.Fatalf("unexpected start: %+v %v", started, fr.params)
+	}
+	if code := call(t, ts, "POST", "/v1/sets/refused/runs", `{}`, nil); code != http.StatusForbidden {
+		t.Fatalf("refused run: got %d, want 403", code)
+	}
+
+	req, _ := http.NewRequest("GET", ts.URL+started.EventsURL, nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET events: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	close(fr.release)
+	done := make(chan []string)
+	go func() {
+		var types []string
+		sc := bufio.NewScanner(resp.Body)
+		for sc.Scan() {
+			if v, ok := strings.CutPrefix(sc.Text(), "event: "); ok {
+				types = append(types, v)
+			}
+		}
+		done <- types
+	}()
+	select {
+	case types := <-done:
+		if strings.Join(types, ",") != "run_started,output,run_finished" {
+			t.Fatalf("unexpected events: %v", types)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("event stream did not end after run_finished")
+	}
+
+	// a finished run replays its events and ends immediately
+	resp2, err := http.DefaultClient.Do(req.Clone(req.Context()))
+	if err != nil {
+		t.Fatalf("GET events again: %v", err)
+	}
+	b, _ := io.ReadAll(resp2.Body)
+	_ = resp2.Body.Close()
+	if strings.Count(string(b), "event: ") != 3 || !strings.Contains(string(b), "id: run-1-3") {
+		t.Fatalf("unexpected replay: %s", b)
+	}
+	if code := call(t, ts, "GET", "/v1/runs/other/events", "", nil); code != http.StatusNotFound {
+		t.Fatalf("unknown run events: got %d, want 404", code)
+	}
+}
+
+func TestRunHistoryEndpoints(t *testing.T) {
+	repo, _, ts := newTestServer(t)
+	_ = repo.StartRun("r1", "deploy", "api")
+	_ = repo.FinishRun("r1", 2, time.Second, nil)
+
+	var run Run
+	if code := call(t, ts, "GET", "/v1/runs/r1", "", &run); code != http.StatusOK || run.Status != registry.RunStatusFailed || run.ExitCode == nil || *run.ExitCode != 2 {
+		t.Fatalf("get run: %d %+v", code, run)
+	}
+	var runs []Run
+	if code := call(t, ts, "GET", "/v1/runs?set=deploy", "", &runs); code != http.StatusOK || len(runs) != 1 {
+		t.Fatalf("list runs: %d %+v", code, runs)
+	}
+	if code := call(t, ts, "GET", "/v1/runs", "", nil); code != http.StatusBadRequest {
+		t.Fatalf("list runs without set: got %d, want 400", code)
+	}
+}
+
+func TestOpenAPIDescribesEveryRoute(t *testing.T) {
+	doc := OpenAPI()
+	paths := doc["paths"].(map[string]map[string]any)
+	for _, rt := range (&Server{}).routes() {
+		op, ok := paths[rt.Path][strings.ToLower(rt.Method)].(map[string]any)
+		if !ok {
+			t.Fatalf("route %s %s missing from the OpenAPI document", rt.Method, rt.Path)
+		}
+		if op["operationId"] != rt.ID {
+			t.Fatalf("unexpected operation %v for %s %s", op["operationId"], rt.Method, rt.Path)
+		}
+	}
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+	set := schemas["Set"].(map[string]any)["properties"].(map[string]any)
+	for _, f := range []string{"name", "tags", "commands", "params"} {
+		if _, ok := set[f]; !ok {
+			t.Fatalf("Set schema lacks %q: %v", f, set)
+		}
+	}
+	if _, err := json.Marshal(doc); err != nil {
+		t.Fatalf("document does not encode: %v", err)
+	}
+}
+
+func TestLoadOrCreateToken(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv(config.EnvKRNRHome, home)
+	t.Setenv(TokenEnv, "")
+	tok, err := LoadOrCreateToken()
+	if err != nil || len(tok) != 64 {
+		t.Fatalf("LoadOrCreateToken = %q %v", tok, err)
+	}
+	if again, _ := LoadOrCreateToken(); again != tok {
+		t.Fatalf("expected the stored token to be reused")
+	}
+	fi, err := os.Stat(filepath.Join(home, "api-token"))
+	if err != nil || (fi.Mode().Perm()&0o077 != 0 && os.PathSeparator == '/') {
+		t.Fatalf("token file should be private: %v %v", fi.Mode(), err)
+	}
+	t.Setenv(TokenEnv, "from-env")
+	if tok, _ := LoadOrCreateToken(); tok != "from-env" {
+		t.Fatalf("expected %s to win, got %q", TokenEnv, tok)
+	}
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"sync"
+
+	"github.com/VoxDroid/krnr/internal/tui/adapters"
+)
+
+// Limits on what the broker keeps in memory.
+const (
+	maxRetainedRuns = 100
+	maxRunEvents    = 10000
+)
+
+// Broker keeps the events of runs started by the server so that clients can
+// subscribe to a run at any point and still receive it from the beginning.
+// Events of the most recent finished runs are retained.
+type Broker struct {
+	mu       sync.Mutex
+	runs     map[string]*runEvents
+	finished []string
+}
+
+type runEvents struct {
+	events []adapters.RunEvent
+	subs   map[chan adapters.RunEvent]bool
+	done   bool
+}
+
+// NewBroker returns an empty broker.
+func NewBroker() *Broker {
+	return &Broker{runs: map[string]*runEvents{}}
+}
+
+// Publish records ev (identified by its RunID) and delivers it to the run's
+// subscribers. A run_finished event ends the run's subscriptions.
+func (b *Broker) Publish(ev adapters.RunEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	re := b.runs[ev.RunID]
+	if re == nil {
+		re = &runEvents{subs: map[chan adapters.RunEvent]bool{}}
+		b.runs[ev.RunID] = re
+	}
+	if re.done {
+		return
+	}
+	if len(re.events) < maxRunEvents || ev.Type == adapters.EventRunFinished {
+		re.events = append(re.events, ev)
+	}
+	for ch := range re.subs {
+		// subscribers that cannot keep up lose events rather than stalling
+		// the run; they still see run_finished when the channel closes
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+	if ev.Type != adapters.EventRunFinished {
+		return
+	}
+	re.done = true
+	for ch := range re.subs {
+		close(ch)
+	}
+	re.subs = nil
+	b.finished = append(b.finished, ev.RunID)
+	if len(b.finished) > maxRetainedRuns {
+		delete(b.runs, b.finished[0])
+		b.finished = b.finished[1:]
+	}
+}
+
+// Subscribe returns the events of runID published so far and, while the run
+// is in progress, a channel delivering the rest; the channel is nil for
+// finished runs. ok is false when the broker has no record of the run.
+// Call cancel when done listening.
+func (b *Broker) Subscribe(runID string) (past []adapters.RunEvent, live <-chan adapters.RunEvent, cancel func(), ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	re := b.runs[runID]
+	if re == nil {
+		return nil, nil, func() {}, false
+	}
+	past = append([]adapters.RunEvent(nil), re.events...)
+	if re.done {
+		return past, nil, func() {}, true
+	}
+	ch := make(chan adapters.RunEvent, 256)
+	re.subs[ch] = true
+	return past, ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if re.subs[ch] {
+			delete(re.subs, ch)
+			close(ch)
+		}
+	}, true
+}
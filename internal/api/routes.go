@@ -0,0 +1,76 @@
+package api
+
+import "net/http"
+
+// route declares one endpoint. Request and Response are zero values of the
+// body types; the OpenAPI document is generated from them.
+type route struct {
+	Method  string
+	Path    string
+	ID      string
+	Summary string
+	Query   []queryParam
+	// Request is the JSON body type, if any.
+	Request any
+	// Response is the JSON body type of a successful response; nil means
+	// no body.
+	Response any
+	// Status of a successful response; defaults to 200.
+	Status int
+	// Public routes do not require the token.
+	Public bool
+
+	Handle func(r *http.Request) (any, error)
+	// Stream, when set, writes the response itself (server-sent events).
+	Stream func(w http.ResponseWriter, r *http.Request) error
+}
+
+type queryParam struct {
+	Name, Type, Description string
+}
+
+func (rt route) status() int {
+	if rt.Status != 0 {
+		return rt.Status
+	}
+	return http.StatusOK
+}
+
+func (s *Server) routes() []route {
+	p := "/" + Version
+	return []route{
+		{Method: "GET", Path: p + "/sets", ID: "listSets", Summary: "List command sets, newest first",
+			Query:    []queryParam{{"tag", "string", "Only sets with this tag"}},
+			Response: []SetSummary{}, Handle: s.listSets},
+		{Method: "GET", Path: p + "/search", ID: "searchSets", Summary: "Search command sets by name, description, commands and tags",
+			Query:    []queryParam{{"q", "string", "Search text"}, {"fuzzy", "boolean", "Fuzzy matching, as in 'krnr list --fuzzy'"}},
+			Response: []SetSummary{}, Handle: s.searchSets},
+		{Method: "POST", Path: p + "/sets", ID: "createSet", Summary: "Create a command set",
+			Request: CreateSetRequest{}, Response: Set{}, Status: http.StatusCreated, Handle: s.createSet},
+		{Method: "GET", Path: p + "/sets/{name}", ID: "getSet", Summary: "Get a command set with its commands and parameters",
+			Response: Set{}, Handle: s.getSet},
+		{Method: "PATCH", Path: p + "/sets/{name}", ID: "updateSet", Summary: "Update a command set; omitted fields are kept. Records one version.",
+			Request: UpdateSetRequest{}, Response: Set{}, Handle: s.updateSet},
+		{Method: "DELETE", Path: p + "/sets/{name}", ID: "deleteSet", Summary: "Delete a command set",
+			Status: http.StatusNoContent, Handle: s.deleteSet},
+		{Method: "GET", Path: p + "/sets/{name}/versions", ID: "listVersions", Summary: "List a command set's versions, newest first",
+			Response: []SetVersion{}, Handle: s.listVersions},
+		{Method: "POST", Path: p + "/sets/{name}/rollback", ID: "rollbackSet", Summary: "Roll a command set back to an earlier version",
+			Request: RollbackRequest{}, Response: Set{}, Handle: s.rollbackSet},
+		{Method: "PUT", Path: p + "/sets/{name}/tags/{tag}", ID: "addTag", Summary: "Add a tag to a command set",
+			Response: Set{}, Handle: s.addTag},
+		{Method: "DELETE", Path: p + "/sets/{name}/tags/{tag}", ID: "removeTag", Summary: "Remove a tag from a command set",
+			Response: Set{}, Handle: s.removeTag},
+		{Method: "POST", Path: p + "/sets/{name}/runs", ID: "startRun", Summary: "Start a run in the background; follow it at events_url",
+			Request: RunRequest{}, Response: RunStarted{}, Status: http.StatusAccepted, Handle: s.startRun},
+		{Method: "GET", Path: p + "/runs", ID: "listRuns", Summary: "List recorded runs of a command set, newest first",
+			Query:    []queryParam{{"set", "string", "Command set name (required)"}, {"limit", "integer", "Maximum number of runs (default 20)"}},
+			Response: []Run{}, Handle: s.listRuns},
+		{Method: "GET", Path: p + "/runs/{id}", ID: "getRun", Summary: "Get a run's status",
+			Response: Run{}, Handle: s.getRun},
+		{Method: "GET", Path: p + "/runs/{id}/events", ID: "streamRun", Summary: "Stream a run's events as server-sent events, from the beginning",
+			Stream: s.streamRun},
+		{Method: "GET", Path: p + "/openapi.json", ID: "openapi", Summary: "This document", Public: true,
+			Handle: func(*http.Request) (any, error) { return OpenAPI(), nil }},
+	}
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/VoxDroid/krnr/internal/httpjson"
+	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/tui/adapters"
+)
+
+// RunRequest is the body of POST /v1/sets/{name}/runs.
+type RunRequest struct {
+	// Params supplies every parameter the set declares; runs never prompt.
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// RunStarted is the response to starting a run.
+type RunStarted struct {
+	RunID     string `json:"run_id"`
+	Set       string `json:"set"`
+	Status    string `json:"status"`
+	StatusURL string `json:"status_url"`
+	EventsURL string `json:"events_url"`
+}
+
+// Run is a recorded run.
+type Run struct {
+	RunID      string `json:"run_id"`
+	Set        string `json:"set"`
+	Status     string `json:"status"`
+	Trigger    string `json:"trigger"`
+	ExitCode   *int64 `json:"exit_code,omitempty"`
+	StartedAt  string `json:"started_at"`
+	FinishedAt string `json:"finished_at,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+func runOf(rr registry.RunRecord) Run {
+	out := Run{RunID: rr.RunID, Set: rr.SetName, Status: rr.Status, Trigger: rr.Trigger, StartedAt: rr.StartedAt,
+		FinishedAt: rr.FinishedAt.String, DurationMS: rr.Duration.Milliseconds()}
+	if rr.ExitCode.Valid {
+		code := rr.ExitCode.Int64
+		out.ExitCode = &code
+	}
+	return out
+}
+
+func (s *Server) startRun(r *http.Request) (any, error) {
+	if s.opts.Runner == nil {
+		return nil, &httpjson.StatusError{Code: http.StatusNotImplemented, Err: errors.New("this server does not run command sets")}
+	}
+	var req RunRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	name := r.PathValue("name")
+	id, err := s.opts.Runner.Start(name, req.Params)
+	if err != nil {
+		return nil, err
+	}
+	base := "/" + Version + "/runs/" + id
+	return RunStarted{RunID: id, Set: name, Status: registry.RunStatusRunning, StatusURL: base, EventsURL: base + "/events"}, nil
+}
+
+func (s *Server) listRuns(r *http.Request) (any, error) {
+	set := r.URL.Query().Get("set")
+	if set == "" {
+		return nil, badRequest(errors.New("the set query parameter is required"))
+	}
+	limit := 20
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, badRequest(fmt.Errorf("invalid limit %q", v))
+		}
+		limit = n
+	}
+	list, err := s.opts.Repo.ListRuns(set, limit)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Run, 0, len(list))
+	for _, rr := range list {
+		out = append(out, runOf(rr))
+	}
+	return out, nil
+}
+
+func (s *Server) getRun(r *http.Request) (any, error) {
+	rr, err := s.opts.Repo.GetRun(r.PathValue("id"))
+	if err != nil {
+		return nil, err
+	}
+	if rr == nil {
+		return nil, notFound("run not found: %s", r.PathValue("id"))
+	}
+	return runOf(*rr), nil
+}
+
+// streamRun sends a run's events as server-sent events: each event's "id" is
+// the run event ID, "event" its type and "data" its JSON form (the same
+// objects as `krnr run --output jsonl`). The stream ends after run_finished.
+func (s *Server) streamRun(w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+	if s.opts.Events == nil {
+		return notFound("no events for run %s", id)
+	}
+	past, live, cancel, ok := s.opts.Events.Subscribe(id)
+	defer cancel()
+	if !ok {
+		return notFound("no events for run %s (only runs started by this server can be streamed)", id)
+	}
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	send := func(ev adapters.RunEvent) {
+		b, _ := json.Marshal(ev)
+		t := ev.Type
+		if t == "" {
+			t = adapters.EventOutput
+		}
+		_, _ = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", ev.ID(), t, b)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	for _, ev := range past {
+		send(ev)
+	}
+	if live == nil {
+		return nil
+	}
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case ev, open := <-live:
+			if !open {
+				return nil
+			}
+			send(ev)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/VoxDroid/krnr/internal/httpjson"
+	"github.com/VoxDroid/krnr/internal/registry"
+)
+
+// SetSummary describes a command set in listings.
+type SetSummary struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	AuthorName  string   `json:"author_name,omitempty"`
+	AuthorEmail string   `json:"author_email,omitempty"`
+	Tags        []string `json:"tags"`
+	CreatedAt   string   `json:"created_at"`
+	LastRun     string   `json:"last_run,omitempty"`
+}
+
+// Set is a command set with its commands and the parameters they use.
+type Set struct {
+	SetSummary
+	Commands  []string `json:"commands"`
+	Params    []string `json:"params"`
+	Exclusive bool     `json:"exclusive"`
+}
+
+// CreateSetRequest is the body of POST /v1/sets.
+type CreateSetRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	AuthorName  string   `json:"author_name,omitempty"`
+	AuthorEmail string   `json:"author_email,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Commands    []string `json:"commands"`
+}
+
+// UpdateSetRequest is the body of PATCH /v1/sets/{name}; omitted fields keep
+// their current value.
+type UpdateSetRequest struct {
+	Name        *string   `json:"name,omitempty"`
+	Description *string   `json:"description,omitempty"`
+	AuthorName  *string   `json:"author_name,omitempty"`
+	AuthorEmail *string   `json:"author_email,omitempty"`
+	Tags        *[]string `json:"tags,omitempty"`
+	Commands    *[]string `json:"commands,omitempty"`
+}
+
+// SetVersion is one entry of a command set's version history.
+type SetVersion struct {
+	Version     int      `json:"version"`
+	CreatedAt   string   `json:"created_at"`
+	Operation   string   `json:"operation"`
+	AuthorName  string   `json:"author_name,omitempty"`
+	AuthorEmail string   `json:"author_email,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Commands    []string `json:"commands"`
+}
+
+// RollbackRequest is the body of POST /v1/sets/{name}/rollback.
+type RollbackRequest struct {
+	Version int `json:"version"`
+}
+
+func summaryOf(cs registry.CommandSet) SetSummary {
+	tags := cs.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return SetSummary{
+		Name: cs.Name, Description: cs.Description.String, AuthorName: cs.AuthorName.String,
+		AuthorEmail: cs.AuthorEmail.String, Tags: tags, CreatedAt: cs.CreatedAt, LastRun: cs.LastRun.String,
+	}
+}
+
+func setOf(cs *registry.CommandSet) Set {
+	out := Set{SetSummary: summaryOf(*cs), Commands: []string{}, Params: []string{}, Exclusive: cs.Exclusive}
+	seen := map[string]bool{}
+	for _, c := range cs.Commands {
+		out.Commands = append(out.Commands, c.Command)
+		for _, p := range registry.FindParams(c.Command) {
+			if !seen[p] {
+				seen[p] = true
+				out.Params = append(out.Params, p)
+			}
+		}
+	}
+	return out
+}
+
+func summaries(list []registry.CommandSet) []SetSummary {
+	out := make([]SetSummary, 0, len(list))
+	for _, cs := range list {
+		out = append(out, summaryOf(cs))
+	}
+	return out
+}
+
+// lookup loads the set named in the request path.
+func (s *Server) lookup(r *http.Request) (*registry.CommandSet, error) {
+	name := r.PathValue("name")
+	cs, err := s.opts.Repo.GetCommandSetByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if cs == nil {
+		return nil, notFound("command set not found: %s", name)
+	}
+	return cs, nil
+}
+
+// reload returns the current state of the named set.
+func (s *Server) reload(name string) (any, error) {
+	cs, err := s.opts.Repo.GetCommandSetByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if cs == nil {
+		return nil, notFound("command set not found: %s", name)
+	}
+	return setOf(cs), nil
+}
+
+func (s *Server) listSets(r *http.Request) (any, error) {
+	var list []registry.CommandSet
+	var err error
+	if tag := r.URL.Query().Get("tag"); tag != "" {
+		list, err = s.opts.Repo.ListCommandSetsByTag(tag)
+	} else {
+		list, err = s.opts.Repo.ListCommandSets()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return summaries(list), nil
+}
+
+func (s *Server) searchSets(r *http.Request) (any, error) {
+	q := r.URL.Query().Get("q")
+	var list []registry.CommandSet
+	var err error
+	if r.URL.Query().Get("fuzzy") == "true" {
+		list, err = s.opts.Repo.FuzzySearchCommandSets(q)
+	} else {
+		list, err = s.opts.Repo.SearchCommandSets(q)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return summaries(list), nil
+}
+
+func (s *Server) getSet(r *http.Request) (any, error) {
+	cs, err := s.lookup(r)
+	if err != nil {
+		return nil, err
+	}
+	return setOf(cs), nil
+}
+
+// optional returns a pointer to v, or nil for the empty string.
+func optional(v string) *string {
+	if v == "" {
+		return nil
+	}
+	return &v
+}
+
+func (s *Server) createSet(r *http.Request) (any, error) {
+	var req CreateSetRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	if existing, err := s.opts.Repo.GetCommandSetByName(strings.TrimSpace(req.Name)); err != nil {
+		return nil, err
+	} else if existing != nil {
+		return nil, &httpjson.StatusError{Code: http.StatusConflict, Err: fmt.Errorf("name %q already in use", req.Name)}
+	}
+	id, err := s.opts.Repo.CreateCommandSet(req.Name, optional(req.Description), optional(req.AuthorName), optional(req.AuthorEmail), req.Commands)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+	for _, t := range req.Tags {
+		if err := s.opts.Repo.AddTagToCommandSet(id, t); err != nil {
+			return nil, err
+		}
+	}
+	return s.reload(strings.TrimSpace(req.Name))
+}
+
+func (s *Server) updateSet(r *http.Request) (any, error) {
+	cs, err := s.lookup(r)
+	if err != nil {
+		return nil, err
+	}
+	var req UpdateSetRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	cur := setOf(cs)
+	name, desc, author, email, tags, cmds := cur.Name, cur.Description, cur.AuthorName, cur.AuthorEmail, cur.Tags, cur.Commands
+	if req.Name != nil {
+		name = strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, badRequest(errors.New("invalid name: name cannot be empty"))
+		}
+	}
+	if req.Description != nil {
+		desc = *req.Description
+	}
+	if req.AuthorName != nil {
+		author = *req.AuthorName
+	}
+	if req.AuthorEmail != nil {
+		email = *req.AuthorEmail
+	}
+	if req.Tags != nil {
+		tags = *req.Tags
+	}
+	if req.Commands != nil {
+		cmds = *req.Commands
+	}
+	if err := s.opts.Repo.UpdateCommandSetAndReplaceCommands(cs.ID, name, optional(desc), optional(author), optional(email), tags, cmds); err != nil {
+		return nil, &httpjson.StatusError{Code: http.StatusConflict, Err: err}
+	}
+	return s.reload(name)
+}
+
+func (s *Server) deleteSet(r *http.Request) (any, error) {
+	cs, err := s.lookup(r)
+	if err != nil {
+		return nil, err
+	}
+	return nil, s.opts.Repo.DeleteCommandSet(cs.Name)
+}
+
+func (s *Server) listVersions(r *http.Request) (any, error) {
+	cs, err := s.lookup(r)
+	if err != nil {
+		return nil, err
+	}
+	vs, err := s.opts.Repo.ListVersions(cs.ID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]SetVersion, 0, len(vs))
+	for _, v := range vs {
+		cmds := v.Commands
+		if cmds == nil {
+			cmds = []string{}
+		}
+		out = append(out, SetVersion{
+			Version: v.Version, CreatedAt: v.CreatedAt, Operation: v.Operation, AuthorName: v.AuthorName.String,
+			AuthorEmail: v.AuthorEmail.String, Description: v.Description.String, Commands: cmds,
+		})
+	}
+	return out, nil
+}
+
+func (s *Server) rollbackSet(r *http.Request) (any, error) {
+	cs, err := s.lookup(r)
+	if err != nil {
+		return nil, err
+	}
+	var req RollbackRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	v, err := s.opts.Repo.GetVersion(cs.ID, req.Version)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, notFound("version %d not found for %s", req.Version, cs.Name)
+	}
+	if err := s.opts.Repo.ApplyVersionByName(cs.Name, req.Version); err != nil {
+		return nil, err
+	}
+	return s.reload(cs.Name)
+}
+
+func (s *Server) addTag(r *http.Request) (any, error) {
+	cs, err := s.lookup(r)
+	if err != nil {
+		return nil, err
+	}
+	tag := strings.TrimSpace(r.PathValue("tag"))
+	if tag == "" {
+		return nil, badRequest(errors.New("tag cannot be empty"))
+	}
+	if err := s.opts.Repo.AddTagToCommandSet(cs.ID, tag); err != nil {
+		return nil, err
+	}
+	return s.reload(cs.Name)
+}
+
+func (s *Server) removeTag(r *http.Request) (any, error) {
+	cs, err := s.lookup(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.opts.Repo.RemoveTagFromCommandSet(cs.ID, r.PathValue("tag")); err != nil {
+		return nil, err
+	}
+	return s.reload(cs.Name)
+}
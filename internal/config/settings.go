@@ -0,0 +1,447 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Config file names. The user config lives in the data directory; the
+// project config is the nearest .krnr.* file in the working directory or
+// one of its parents. TOML is preferred when several formats exist.
+const (
+	UserConfigName    = "config"
+	ProjectConfigName = ".krnr"
+)
+
+// configExts are the supported config formats, in order of preference.
+var configExts = []string{".toml", ".yaml", ".yml"}
+
+// Sources of a setting's value, lowest precedence first. Command-line
+// flags override them all.
+const (
+	SourceDefault = "default"
+	SourceUser    = "user"
+	SourceProject = "project"
+	SourceEnv     = "env"
+)
+
+// Themes are the TUI themes.
+var Themes = []string{"default", "high-contrast"}
+
+// SecretModes are what saving a command that contains a secret does: ask
+// to extract it (warn when there is no terminal to ask on), warn, or
+// refuse the save.
+var SecretModes = []string{"ask", "warn", "block"}
+
+type kind int
+
+const (
+	kindString kind = iota
+	kindDuration
+	kindBool
+	kindInt
+)
+
+// Key describes one setting.
+type Key struct {
+	Name string
+	// Env is the environment variable that overrides the config files.
+	Env     string
+	Default string
+	Help    string
+	// Values restricts the setting to these values when set.
+	Values []string
+	// Project is true for settings a project config may set. The others
+	// choose programs krnr runs (shell, editor, vault) or weaken a safety
+	// check, so a checked-out repository must not be able to change them.
+	Project bool
+	kind    kind
+}
+
+// Keys lists every setting.
+var Keys = []Key{
+	{Name: "shell", Env: "KRNR_SHELL", Help: "shell commands run in (bash, sh, pwsh, powershell, cmd, ...); empty means the platform default"},
+	{Name: "timeout", Env: "KRNR_TIMEOUT", Default: "30s", Help: "abort runs that take longer than this (e.g. 90s, 10m)", Project: true, kind: kindDuration},
+	{Name: "confirm", Env: "KRNR_CONFIRM", Default: "false", Help: "ask for confirmation before interactive runs", Project: true, kind: kindBool},
+	{Name: "theme", Env: "KRNR_THEME", Default: "default", Help: "TUI theme", Values: Themes, Project: true},
+	{Name: "editor", Env: "KRNR_EDITOR", Help: "editor for 'krnr edit' and 'krnr config edit'; empty means $EDITOR, then vi (notepad on Windows)"},
+	{Name: "secrets", Env: "KRNR_SECRETS", Default: "ask", Help: "when a saved command contains a secret: ask to extract it, warn, or block the save", Values: SecretModes},
+	{Name: "vault", Env: "KRNR_VAULT", Help: "command that prints a stored secret, with {name} for its name (e.g. 'pass show krnr/{name}'); secrets can be extracted into $(...) references to it"},
+	{Name: "log_retention", Env: "KRNR_LOG_RETENTION", Default: "720h", Help: "delete a set's run logs older than this after each run", kind: kindDuration},
+	{Name: "log_keep", Env: "KRNR_LOG_KEEP", Default: "100", Help: "keep at most this many run logs per set; 0 keeps them all", kind: kindInt},
+}
+
+// ProjectKeys returns the names of the settings a project config may set.
+func ProjectKeys() []string {
+	var names []string
+	for _, k := range Keys {
+		if k.Project {
+			names = append(names, k.Name)
+		}
+	}
+	return names
+}
+
+// CheckProjectKey returns an error when k may not be set in a project
+// config.
+func CheckProjectKey(k Key) error {
+	if k.Project {
+		return nil
+	}
+	return fmt.Errorf("%s can only be set in the user config or the environment; a project config may set %s", k.Name, strings.Join(ProjectKeys(), ", "))
+}
+
+// LookupKey returns the named setting, suggesting a close name when there
+// is no such setting.
+func LookupKey(name string) (Key, error) {
+	for _, k := range Keys {
+		if k.Name == name {
+			return k, nil
+		}
+	}
+	names := make([]string, len(Keys))
+	best, bestDist := "", 3
+	for i, k := range Keys {
+		names[i] = k.Name
+		if d := editDistance(name, k.Name); d < bestDist {
+			best, bestDist = k.Name, d
+		}
+	}
+	if best != "" {
+		return Key{}, fmt.Errorf("unknown setting %q (did you mean %q?)", name, best)
+	}
+	return Key{}, fmt.Errorf("unknown setting %q (known settings: %s)", name, strings.Join(names, ", "))
+}
+
+// Normalize validates v as a value of k and returns its canonical form.
+func (k Key) Normalize(v string) (string, error) {
+	v = strings.TrimSpace(v)
+	switch k.kind {
+	case kindDuration:
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			if _, nerr := strconv.Atoi(v); nerr == nil {
+				return "", fmt.Errorf("%s: %q has no unit (use e.g. %ss or %sm)", k.Name, v, v, v)
+			}
+			return "", fmt.Errorf("%s: %q is not a positive duration (e.g. 30s, 10m, 1h)", k.Name, v)
+		}
+		return d.String(), nil
+	case kindInt:
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return "", fmt.Errorf("%s: %q is not a whole number of 0 or more", k.Name, v)
+		}
+		return strconv.Itoa(n), nil
+	case kindBool:
+		switch strings.ToLower(v) {
+		case "true", "yes", "on", "1":
+			return "true", nil
+		case "false", "no", "off", "0":
+			return "false", nil
+		}
+		return "", fmt.Errorf("%s: %q is not a boolean (use true or false)", k.Name, v)
+	}
+	if len(k.Values) > 0 {
+		for _, allowed := range k.Values {
+			if v == allowed {
+				return v, nil
+			}
+		}
+		return "", fmt.Errorf("%s: %q is not one of %s", k.Name, v, strings.Join(k.Values, ", "))
+	}
+	return v, nil
+}
+
+// Value is a setting's resolved value and where it came from.
+type Value struct {
+	Value  string
+	Source string
+	// Path is the config file of user and project values.
+	Path string
+}
+
+// Settings are krnr's configurable defaults, resolved from the built-in
+// defaults, the user config, the project config and the environment.
+type Settings struct {
+	Shell   string
+	Timeout time.Duration
+	Confirm bool
+	Theme   string
+	Editor  string
+	Secrets string
+	Vault   string
+	// LogRetention and LogKeep bound the run logs kept per set.
+	LogRetention time.Duration
+	LogKeep      int
+
+	values map[string]Value
+}
+
+// Defaults returns the built-in settings.
+func Defaults() *Settings {
+	s := &Settings{values: map[string]Value{}}
+	for _, k := range Keys {
+		s.values[k.Name] = Value{Value: k.Default, Source: SourceDefault}
+	}
+	s.apply()
+	return s
+}
+
+// Get returns a setting's value and source.
+func (s *Settings) Get(name string) (Value, error) {
+	if _, err := LookupKey(name); err != nil {
+		return Value{}, err
+	}
+	return s.values[name], nil
+}
+
+// apply fills the typed fields from the validated values.
+func (s *Settings) apply() {
+	s.Shell = s.values["shell"].Value
+	s.Timeout, _ = time.ParseDuration(s.values["timeout"].Value)
+	s.Confirm = s.values["confirm"].Value == "true"
+	s.Theme = s.values["theme"].Value
+	s.Editor = s.values["editor"].Value
+	s.Secrets = s.values["secrets"].Value
+	s.Vault = s.values["vault"].Value
+	s.LogRetention, _ = time.ParseDuration(s.values["log_retention"].Value)
+	s.LogKeep, _ = strconv.Atoi(s.values["log_keep"].Value)
+}
+
+// LoadSettings resolves the settings. Later sources win: built-in defaults,
+// the user config, the project config, then environment variables. A
+// project config setting anything but the ProjectKeys is an error.
+func LoadSettings() (*Settings, error) {
+	s := Defaults()
+	user, err := UserConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	project, err := ProjectConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range []struct{ path, source string }{{user, SourceUser}, {project, SourceProject}} {
+		if f.path == "" {
+			continue
+		}
+		values, err := ReadConfigFile(f.path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for name, v := range values {
+			if f.source == SourceProject {
+				k, _ := LookupKey(name)
+				if err := CheckProjectKey(k); err != nil {
+					return nil, fmt.Errorf("%s: %w", f.path, err)
+				}
+			}
+			s.values[name] = Value{Value: v, Source: f.source, Path: f.path}
+		}
+	}
+	for _, k := range Keys {
+		raw, ok := os.LookupEnv(k.Env)
+		if !ok || raw == "" {
+			continue
+		}
+		v, err := k.Normalize(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", k.Env, err)
+		}
+		s.values[k.Name] = Value{Value: v, Source: SourceEnv}
+	}
+	s.apply()
+	return s, nil
+}
+
+// findConfig returns the first existing dir/base.{toml,yaml,yml}.
+func findConfig(dir, base string) (string, bool) {
+	for _, ext := range configExts {
+		p := filepath.Join(dir, base+ext)
+		if fi, err := os.Stat(p); err == nil && !fi.IsDir() {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// UserConfigPath returns the user config file in the data directory: the
+// existing one, otherwise config.toml.
+func UserConfigPath() (string, error) {
+	d, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	if p, ok := findConfig(d, UserConfigName); ok {
+		return p, nil
+	}
+	return filepath.Join(d, UserConfigName+".toml"), nil
+}
+
+// ProjectConfigPath returns the nearest .krnr.toml (or .yaml/.yml) in the
+// working directory or its parents, or "" when there is none.
+func ProjectConfigPath() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		if p, ok := findConfig(dir, ProjectConfigName); ok {
+			return p, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+func isYAML(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// ReadConfigFile reads and validates a config file, TOML or YAML by its
+// extension. It returns the canonical value of each setting it sets.
+func ReadConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	raw := map[string]any{}
+	if isYAML(path) {
+		err = yaml.Unmarshal(data, &raw)
+	} else {
+		err = toml.Unmarshal(data, &raw)
+	}
+	if err != nil {
+		var de *toml.DecodeError
+		if errors.As(err, &de) {
+			row, col := de.Position()
+			return nil, fmt.Errorf("%s:%d:%d: %v", path, row, col, de)
+		}
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	names := make([]string, 0, len(raw))
+	for name := range raw {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := map[string]string{}
+	for _, name := range names {
+		k, err := LookupKey(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		var text string
+		switch v := raw[name].(type) {
+		case string:
+			text = v
+		case bool:
+			text = strconv.FormatBool(v)
+		case int, int64, uint64, float64:
+			text = fmt.Sprint(v)
+		case nil:
+			continue
+		default:
+			return nil, fmt.Errorf("%s: %s: expected a single value, got %T", path, name, v)
+		}
+		if out[name], err = k.Normalize(text); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return out, nil
+}
+
+// WriteConfigFile writes values to path, TOML or YAML by its extension,
+// creating its directory when needed. Booleans and numbers are written as such.
+func WriteConfigFile(path string, values map[string]string) error {
+	typed := map[string]any{}
+	for name, v := range values {
+		k, err := LookupKey(name)
+		if err != nil {
+			return err
+		}
+		switch k.kind {
+		case kindBool:
+			typed[name] = v == "true"
+		case kindInt:
+			typed[name], _ = strconv.Atoi(v)
+		default:
+			typed[name] = v
+		}
+	}
+	var buf bytes.Buffer
+	if isYAML(path) {
+		enc := yaml.NewEncoder(&buf)
+		if err := enc.Encode(typed); err != nil {
+			return err
+		}
+		_ = enc.Close()
+	} else if err := toml.NewEncoder(&buf).Encode(typed); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// Template is the content of a new config file: every setting commented
+// out with its help and default.
+func Template(path string) string {
+	var b strings.Builder
+	b.WriteString("# krnr settings; uncomment and change as needed.\n")
+	for _, k := range Keys {
+		fmt.Fprintf(&b, "\n# %s", k.Help)
+		if k.Env != "" {
+			fmt.Fprintf(&b, " (env %s)", k.Env)
+		}
+		b.WriteString("\n")
+		v := strconv.Quote(k.Default)
+		if k.kind == kindBool || k.kind == kindInt {
+			v = k.Default
+		}
+		if isYAML(path) {
+			fmt.Fprintf(&b, "# %s: %s\n", k.Name, v)
+		} else {
+			fmt.Fprintf(&b, "# %s = %s\n", k.Name, v)
+		}
+	}
+	return b.String()
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// chdir changes the working directory for the rest of the test.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	old, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(old) })
+}
+
+func write(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSettingsPrecedence(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv(EnvKRNRHome, home)
+	for _, k := range Keys {
+		t.Setenv(k.Env, "")
+	}
+	project := t.TempDir()
+	sub := filepath.Join(project, "a", "b")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	chdir(t, sub)
+
+	s, err := LoadSettings()
+	if err != nil {
+		t.Fatalf("LoadSettings: %v", err)
+	}
+	if s.Timeout != 30*time.Second || s.Confirm || s.Theme != "default" || s.Shell != "" {
+		t.Fatalf("unexpected defaults %+v", s)
+	}
+
+	write(t, filepath.Join(home, "config.toml"), "shell = \"bash\"\ntimeout = \"1m\"\nconfirm = true\n")
+	write(t, filepath.Join(project, ".krnr.yaml"), "timeout: 2m\ntheme: high-contrast\n")
+	t.Setenv("KRNR_THEME", "default")
+
+	s, err = LoadSettings()
+	if err != nil {
+		t.Fatalf("LoadSettings: %v", err)
+	}
+	if s.Shell != "bash" || s.Timeout != 2*time.Minute || !s.Confirm || s.Theme != "default" {
+		t.Fatalf("unexpected settings %+v", s)
+	}
+	for name, source := range map[string]string{"shell": SourceUser, "timeout": SourceProject, "theme": SourceEnv, "editor": SourceDefault} {
+		if v, _ := s.Get(name); v.Source != source {
+			t.Errorf("%s: expected source %s, got %+v", name, source, v)
+		}
+	}
+	if v, _ := s.Get("timeout"); v.Path != filepath.Join(project, ".krnr.yaml") || v.Value != "2m0s" {
+		t.Errorf("unexpected timeout value %+v", v)
+	}
+}
+
+func TestSettingsValidation(t *testing.T) {
+	dir := t.TempDir()
+	cases := map[string]string{
+		"timout = \"1m\"":           `unknown setting "timout" (did you mean "timeout"?)`,
+		"colour = \"x\"":            "known settings: shell, timeout",
+		"timeout = 30":              `"30" has no unit`,
+		"timeout = \"soon\"":        "not a positive duration",
+		"confirm = \"maybe\"":       "not a boolean",
+		"theme = \"dark\"":          "not one of default, high-contrast",
+		"log_keep = -1":             "not a whole number",
+		"shell = [\"a\"]":           "expected a single value",
+		"shell = \"bash\"\nx = = 1": "config.toml:2",
+	}
+	for content, want := range cases {
+		p := filepath.Join(dir, "config.toml")
+		write(t, p, content)
+		if _, err := ReadConfigFile(p); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected error containing %q, got %v", content, want, err)
+		}
+	}
+
+	t.Setenv(EnvKRNRHome, dir)
+	chdir(t, dir)
+	write(t, filepath.Join(dir, "config.toml"), "")
+	for key, value := range map[string]string{"shell": "sh", "editor": "vi", "vault": "pass show {name}", "secrets": "warn", "log_keep": "1"} {
+		write(t, filepath.Join(dir, ".krnr.toml"), key+" = \""+value+"\"\n")
+		if _, err := LoadSettings(); err == nil || !strings.Contains(err.Error(), key+" can only be set in the user config") {
+			t.Errorf("expected a project config setting %s to be refused, got %v", key, err)
+		}
+	}
+	write(t, filepath.Join(dir, ".krnr.toml"), "timeout = \"1m\"\nconfirm = true\ntheme = \"default\"\n")
+	if _, err := LoadSettings(); err != nil {
+		t.Fatalf("project configs may set timeout, confirm and theme: %v", err)
+	}
+	t.Setenv("KRNR_CONFIRM", "sometimes")
+	if _, err := LoadSettings(); err == nil || !strings.Contains(err.Error(), "KRNR_CONFIRM") {
+		t.Fatalf("expected the bad environment value to be reported, got %v", err)
+	}
+}
+
+func TestWriteConfigFileRoundTrip(t *testing.T) {
+	for _, name := range []string{"config.toml", "config.yaml"} {
+		p := filepath.Join(t.TempDir(), "sub", name)
+		values := map[string]string{"confirm": "true", "timeout": "5m0s", "editor": "code --wait", "log_keep": "20"}
+		if err := WriteConfigFile(p, values); err != nil {
+			t.Fatalf("%s: write: %v", name, err)
+		}
+		data, _ := os.ReadFile(p)
+		if strings.Contains(string(data), `"true"`) || strings.Contains(string(data), `"20"`) {
+			t.Errorf("%s: booleans and numbers must be written as such:\n%s", name, data)
+		}
+		got, err := ReadConfigFile(p)
+		if err != nil {
+			t.Fatalf("%s: read: %v", name, err)
+		}
+		if len(got) != 4 || got["confirm"] != "true" || got["timeout"] != "5m0s" || got["editor"] != "code --wait" || got["log_keep"] != "20" {
+			t.Errorf("%s: round trip gave %v", name, got)
+		}
+
+		tp := filepath.Join(filepath.Dir(p), "template"+filepath.Ext(name))
+		write(t, tp, Template(tp))
+		if got, err := ReadConfigFile(tp); err != nil || len(got) != 0 {
+			t.Errorf("%s: the template must be valid and set nothing, got %v %v", name, got, err)
+		}
+	}
+}
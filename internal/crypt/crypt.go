@@ -0,0 +1,179 @@
+// Package crypt encrypts exported bundles with a passphrase: the key is
+// derived with scrypt and the bundle sealed with XChaCha20-Poly1305, so a
+// wrong passphrase and a modified file are both detected.
+package crypt
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+// magic starts every encrypted bundle; the rest of the header is the
+// scrypt cost (log2 N, r, p), the salt and the nonce. The whole header is
+// authenticated along with the ciphertext.
+const magic = "KRNRENC1"
+
+const (
+	saltSize   = 16
+	headerSize = len(magic) + 3 + saltSize + chacha20poly1305.NonceSizeX
+	// maxLogN, maxR and maxP bound the scrypt cost a bundle may ask for so
+	// a crafted header cannot exhaust memory or CPU; maxMemory caps the
+	// 128·N·r bytes scrypt allocates (2^20 with r = 8 is exactly 1 GiB).
+	maxLogN   = 20
+	maxR      = 32
+	maxP      = 16
+	maxMemory = 1 << 30
+)
+
+// Cost is the scrypt cost of new bundles: N = 2^LogN, r and p. Tests lower
+// it.
+var Cost = struct{ LogN, R, P uint8 }{LogN: 15, R: 8, P: 1}
+
+// ErrDecrypt is returned for a wrong passphrase or a modified bundle.
+var ErrDecrypt = errors.New("cannot decrypt bundle: wrong passphrase or the file was modified")
+
+func deriveKey(passphrase, salt []byte, logN, r, p uint8) ([]byte, error) {
+	return scrypt.Key(passphrase, salt, 1<<logN, int(r), int(p), chacha20poly1305.KeySize)
+}
+
+// Encrypt seals plaintext with a key derived from passphrase.
+func Encrypt(plaintext, passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New("passphrase cannot be empty")
+	}
+	header := make([]byte, headerSize)
+	copy(header, magic)
+	header[len(magic)], header[len(magic)+1], header[len(magic)+2] = Cost.LogN, Cost.R, Cost.P
+	if _, err := rand.Read(header[len(magic)+3:]); err != nil {
+		return nil, err
+	}
+	salt := header[len(magic)+3 : len(magic)+3+saltSize]
+	nonce := header[len(magic)+3+saltSize:]
+	key, err := deriveKey(passphrase, salt, Cost.LogN, Cost.R, Cost.P)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(header, nonce, plaintext, header), nil
+}
+
+// Decrypt opens a bundle sealed by Encrypt.
+func Decrypt(data, passphrase []byte) ([]byte, error) {
+	if !IsEncrypted(data) || len(data) < headerSize {
+		return nil, errors.New("not an encrypted krnr bundle")
+	}
+	header := data[:headerSize]
+	logN, r, p := header[len(magic)], header[len(magic)+1], header[len(magic)+2]
+	if logN == 0 || logN > maxLogN || r == 0 || r > maxR || p == 0 || p > maxP ||
+		128*(uint64(1)<<logN)*uint64(r) > maxMemory {
+		return nil, ErrDecrypt
+	}
+	salt := header[len(magic)+3 : len(magic)+3+saltSize]
+	nonce := header[len(magic)+3+saltSize:]
+	key, err := deriveKey(passphrase, salt, logN, r, p)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := aead.Open(nil, nonce, data[headerSize:], header)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plain, nil
+}
+
+// IsEncrypted reports whether data starts like an encrypted bundle.
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(magic))
+}
+
+// IsEncryptedFile reports whether the file at path is an encrypted bundle.
+func IsEncryptedFile(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = f.Close() }()
+	buf := make([]byte, len(magic))
+	if _, err := io.ReadFull(f, buf); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return false, nil
+		}
+		return false, err
+	}
+	return IsEncrypted(buf), nil
+}
+
+// TempFile returns the path of a plaintext bundle inside a new directory
+// only the user can access, so the bundle and any SQLite journal created
+// next to it stay private. The cleanup func removes the whole directory.
+func TempFile() (string, func(), error) {
+	dir, err := os.MkdirTemp("", "krnr-bundle-*")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { _ = os.RemoveAll(dir) }
+	if err := os.Chmod(dir, 0o700); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	path := filepath.Join(dir, "bundle.db")
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return path, cleanup, nil
+}
+
+// EncryptFile writes the bundle at plainPath, encrypted with passphrase,
+// to dstPath.
+func EncryptFile(plainPath, dstPath string, passphrase []byte) error {
+	plain, err := os.ReadFile(plainPath)
+	if err != nil {
+		return err
+	}
+	data, err := Encrypt(plain, passphrase)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(dstPath, data, 0o644); err != nil {
+		return fmt.Errorf("write encrypted bundle: %w", err)
+	}
+	return nil
+}
+
+// DecryptToTemp decrypts the bundle at path into a TempFile and returns
+// its path and cleanup func.
+func DecryptToTemp(path string, passphrase []byte) (string, func(), error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, err
+	}
+	plain, err := Decrypt(data, passphrase)
+	if err != nil {
+		return "", nil, err
+	}
+	tmp, cleanup, err := TempFile()
+	if err != nil {
+		return "", nil, err
+	}
+	if err := os.WriteFile(tmp, plain, 0o600); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return tmp, cleanup, nil
+}
@@ -0,0 +1,110 @@
+package crypt
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func init() {
+	// keep scrypt cheap in tests
+	Cost.LogN = 10
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	plain := []byte("SQLite format 3\x00 ssh deploy@db.internal")
+	data, err := Encrypt(plain, []byte("s3cret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(data) || bytes.Contains(data, []byte("db.internal")) {
+		t.Fatal("expected an encrypted bundle without the plaintext")
+	}
+	got, err := Decrypt(data, []byte("s3cret"))
+	if err != nil || !bytes.Equal(got, plain) {
+		t.Fatalf("round trip: %q %v", got, err)
+	}
+	if _, err := Decrypt(data, []byte("wrong")); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("expected ErrDecrypt for a wrong passphrase, got %v", err)
+	}
+	for _, i := range []int{len(magic), len(magic) + 5, headerSize + 2} {
+		tampered := append([]byte(nil), data...)
+		tampered[i] ^= 1
+		if _, err := Decrypt(tampered, []byte("s3cret")); !errors.Is(err, ErrDecrypt) {
+			t.Fatalf("expected ErrDecrypt with byte %d modified, got %v", i, err)
+		}
+	}
+	if _, err := Encrypt(plain, nil); err == nil {
+		t.Fatal("expected an empty passphrase to be refused")
+	}
+	if _, err := Decrypt(plain, []byte("s3cret")); err == nil || errors.Is(err, ErrDecrypt) {
+		t.Fatalf("expected a plaintext bundle to be rejected as not encrypted, got %v", err)
+	}
+}
+
+func TestDecryptRejectsExcessiveCost(t *testing.T) {
+	data, err := Encrypt([]byte("bundle"), []byte("s3cret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct{ logN, r, p uint8 }{
+		{maxLogN + 1, 8, 1},
+		{10, maxR + 1, 1},
+		{10, 8, maxP + 1},
+		{10, 255, 255},
+		{maxLogN, 16, 1}, // 2 GiB
+	} {
+		crafted := append([]byte(nil), data...)
+		crafted[len(magic)], crafted[len(magic)+1], crafted[len(magic)+2] = c.logN, c.r, c.p
+		if _, err := Decrypt(crafted, []byte("s3cret")); !errors.Is(err, ErrDecrypt) {
+			t.Fatalf("expected ErrDecrypt for cost %+v, got %v", c, err)
+		}
+	}
+}
+
+func TestEncryptFileAndDecryptToTemp(t *testing.T) {
+	dir := t.TempDir()
+	plainPath := filepath.Join(dir, "plain.db")
+	encPath := filepath.Join(dir, "enc.db")
+	if err := os.WriteFile(plainPath, []byte("bundle"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if enc, err := IsEncryptedFile(plainPath); err != nil || enc {
+		t.Fatalf("IsEncryptedFile(plain) = %v %v", enc, err)
+	}
+	if err := EncryptFile(plainPath, encPath, []byte("s3cret")); err != nil {
+		t.Fatal(err)
+	}
+	if enc, err := IsEncryptedFile(encPath); err != nil || !enc {
+		t.Fatalf("IsEncryptedFile(enc) = %v %v", enc, err)
+	}
+
+	tmp, cleanup, err := DecryptToTemp(encPath, []byte("s3cret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(tmp)
+	if err != nil || (runtime.GOOS != "windows" && fi.Mode().Perm() != 0o600) {
+		t.Fatalf("decrypted bundle should be private: %v %v", fi, err)
+	}
+	di, err := os.Stat(filepath.Dir(tmp))
+	if err != nil || (runtime.GOOS != "windows" && di.Mode().Perm() != 0o700) {
+		t.Fatalf("decrypted bundle directory should be private: %v %v", di, err)
+	}
+	if got, _ := os.ReadFile(tmp); string(got) != "bundle" {
+		t.Fatalf("decrypted %q", got)
+	}
+	_ = os.WriteFile(tmp+"-journal", nil, 0o600)
+	cleanup()
+	for _, p := range []string{tmp, tmp + "-journal", filepath.Dir(tmp)} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Fatalf("expected %s to be removed, got %v", p, err)
+		}
+	}
+	if _, _, err := DecryptToTemp(encPath, []byte("wrong")); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("expected ErrDecrypt, got %v", err)
+	}
+}
@@ -20,6 +20,11 @@ func InitDB() (*sql.DB, error) {
 	if err != nil {
 		return nil, err
 	}
+	return Open(dbPath)
+}
+
+// Open is like InitDB for the database at dbPath.
+func Open(dbPath string) (*sql.DB, error) {
 	if err := os.MkdirAll(filepath.Dir(dbPath), 0o755); err != nil {
 		return nil, fmt.Errorf("create data dir: %w", err)
 	}
@@ -22,6 +22,9 @@ func ApplyMigrations(db *sql.DB) error {
 	if err := ensureCommandSetColumns(db); err != nil {
 		return err
 	}
+	if err := ensureSchedulesBySetID(db); err != nil {
+		return err
+	}
 
 	// Validate existing data integrity: no empty trimmed names and no duplicate trimmed names.
 	// This prevents silent acceptance of invalid rows from older DBs or imports.
@@ -86,5 +89,37 @@ func ensureCommandSetColumns(db *sql.DB) error {
 			return err
 		}
 	}
+	if !cols["exclusive"] {
+		if _, err := db.Exec("ALTER TABLE command_sets ADD COLUMN exclusive INTEGER NOT NULL DEFAULT 0"); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+// ensureSchedulesBySetID moves schedules of databases that keyed them by set
+// name onto the set's id, dropping those of sets that no longer exist.
+func ensureSchedulesBySetID(db *sql.DB) error {
+	var n int
+	if err := db.QueryRow("SELECT count(*) FROM pragma_table_info('schedules') WHERE name = 'set_name'").Scan(&n); err != nil || n == 0 {
+		return err
+	}
+	trx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = trx.Rollback() }()
+	if _, err := trx.Exec("ALTER TABLE schedules RENAME TO schedules_by_name"); err != nil {
+		return fmt.Errorf("migrate schedules: %w", err)
+	}
+	if _, err := trx.Exec(schemaSQL); err != nil {
+		return fmt.Errorf("migrate schedules: %w", err)
+	}
+	if _, err := trx.Exec(`INSERT INTO schedules (id, command_set_id, cron, params, timeout_ms, created_at, last_run_at, next_run_at)
+		SELECT s.id, cs.id, s.cron, s.params, s.timeout_ms, s.created_at, s.last_run_at, s.next_run_at
+		FROM schedules_by_name s JOIN command_sets cs ON cs.name = s.set_name;
+		DROP TABLE schedules_by_name`); err != nil {
+		return fmt.Errorf("migrate schedules: %w", err)
+	}
+	return trx.Commit()
+}
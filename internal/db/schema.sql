@@ -78,3 +78,131 @@ WHEN EXISTS(SELECT 1 FROM command_sets WHERE TRIM(name) = TRIM(NEW.name) AND id
 BEGIN
     SELECT RAISE(ABORT, 'invalid name: duplicate trimmed name');
 END;
+
+-- Run history: one row per `krnr run` of a command set. step_durations
+-- holds a JSON array of per-step durations in milliseconds and feeds progress
+-- ETAs. Rows are keyed by the run ID shared with run logs and JSON events.
+CREATE TABLE IF NOT EXISTS runs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id TEXT UNIQUE NOT NULL,
+    set_name TEXT NOT NULL,
+    started_at DATETIME NOT NULL,
+    finished_at DATETIME,
+    status TEXT NOT NULL, -- 'running','ok','failed'
+    exit_code INTEGER,
+    duration_ms INTEGER,
+    step_durations TEXT,
+    triggered_by TEXT NOT NULL DEFAULT 'manual'
+);
+
+CREATE INDEX IF NOT EXISTS idx_runs_set_started ON runs (set_name, started_at);
+
+-- Breakpoints: steps (1-based positions) that pause every run of a set.
+CREATE TABLE IF NOT EXISTS breakpoints (
+    command_set_id INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    PRIMARY KEY (command_set_id, position),
+    FOREIGN KEY(command_set_id) REFERENCES command_sets(id)
+);
+
+-- Background jobs started with `krnr run --detach`.
+CREATE TABLE IF NOT EXISTS jobs (
+    id TEXT PRIMARY KEY,
+    set_name TEXT NOT NULL,
+    pid INTEGER NOT NULL DEFAULT 0,
+    status TEXT NOT NULL, -- 'running','ok','failed','killed','lost'
+    started_at DATETIME NOT NULL,
+    finished_at DATETIME,
+    exit_code INTEGER,
+    run_id TEXT,
+    output_path TEXT NOT NULL
+);
+
+-- Leases for exclusive command sets: at most one row (holder) per set.
+CREATE TABLE IF NOT EXISTS leases (
+    set_name TEXT PRIMARY KEY,
+    token TEXT NOT NULL,
+    owner TEXT NOT NULL,
+    pid INTEGER NOT NULL,
+    host TEXT NOT NULL,
+    acquired_at DATETIME NOT NULL,
+    heartbeat_at DATETIME NOT NULL
+);
+
+-- Cron schedules executed by `krnr scheduler`. params is a JSON array of
+-- name=value strings; times are UTC 'YYYY-MM-DD HH:MM:SS'.
+CREATE TABLE IF NOT EXISTS schedules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    command_set_id INTEGER NOT NULL,
+    cron TEXT NOT NULL,
+    params TEXT NOT NULL DEFAULT '[]',
+    timeout_ms INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL,
+    last_run_at DATETIME,
+    next_run_at DATETIME,
+    FOREIGN KEY(command_set_id) REFERENCES command_sets(id)
+);
+
+-- Saved `krnr watch` definitions, one per command set. paths, include and
+-- exclude are JSON arrays.
+CREATE TABLE IF NOT EXISTS watches (
+    command_set_id INTEGER PRIMARY KEY,
+    paths TEXT NOT NULL DEFAULT '[]',
+    include TEXT NOT NULL DEFAULT '[]',
+    exclude TEXT NOT NULL DEFAULT '[]',
+    debounce_ms INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY(command_set_id) REFERENCES command_sets(id)
+);
+
+-- Sets that `krnr serve hooks` may trigger, with each hook's HMAC secret.
+CREATE TABLE IF NOT EXISTS webhooks (
+    command_set_id INTEGER PRIMARY KEY,
+    secret TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    FOREIGN KEY(command_set_id) REFERENCES command_sets(id)
+);
+
+-- Audit trail: one row per change to a command set, its history or its
+-- settings, and per run, with who made it. Rows are hash-chained (hash covers
+-- prev_hash and the row's fields) and the triggers below refuse updates and
+-- deletes; `krnr audit verify` detects rows edited or removed regardless.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    at TEXT NOT NULL, -- UTC 'YYYY-MM-DD HH:MM:SS'
+    action TEXT NOT NULL,
+    set_name TEXT NOT NULL DEFAULT '',
+    detail TEXT NOT NULL DEFAULT '',
+    profile TEXT NOT NULL DEFAULT '', -- whoami name <email>
+    os_user TEXT NOT NULL DEFAULT '',
+    host TEXT NOT NULL DEFAULT '',
+    prev_hash TEXT NOT NULL DEFAULT '',
+    hash TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_set ON audit_log (set_name, id);
+
+CREATE TRIGGER IF NOT EXISTS audit_log_no_update
+BEFORE UPDATE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit log is append-only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_log_no_delete
+BEFORE DELETE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit log is append-only');
+END;
+
+-- Provenance of imported sets: where they came from and whether the user
+-- trusted their current commands with `krnr trust`. trusted_commands is a
+-- JSON array of the commands last trusted (NULL if never), for diffs. Sets
+-- without a row were created locally and are trusted.
+CREATE TABLE IF NOT EXISTS provenance (
+    command_set_id INTEGER PRIMARY KEY,
+    source TEXT NOT NULL,
+    original_author TEXT NOT NULL DEFAULT '',
+    imported_at DATETIME NOT NULL,
+    trusted INTEGER NOT NULL DEFAULT 0,
+    trusted_commands TEXT,
+    FOREIGN KEY(command_set_id) REFERENCES command_sets(id)
+);
@@ -34,3 +34,39 @@ func TestTriggersRejectEmptyAndDuplicateInserts(t *testing.T) {
 		t.Fatalf("expected duplicate trimmed insert to be rejected by trigger")
 	}
 }
+
+func TestMigrateSchedulesToSetID(t *testing.T) {
+	db, err := sql.Open("sqlite", "file:test_schedules?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	if err := ApplyMigrations(db); err != nil {
+		t.Fatalf("apply migrations: %v", err)
+	}
+	// schedules as they were keyed by set name
+	for _, q := range []string{
+		"DROP TABLE schedules",
+		`CREATE TABLE schedules (id INTEGER PRIMARY KEY AUTOINCREMENT, set_name TEXT NOT NULL, cron TEXT NOT NULL,
+		params TEXT NOT NULL DEFAULT '[]', timeout_ms INTEGER NOT NULL DEFAULT 0, created_at DATETIME NOT NULL,
+		last_run_at DATETIME, next_run_at DATETIME)`,
+		"INSERT INTO command_sets (name, created_at) VALUES ('backup', datetime('now'))",
+		"INSERT INTO schedules (set_name, cron, created_at) VALUES ('backup', '@daily', datetime('now')), ('gone', '@hourly', datetime('now'))",
+	} {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+	if err := ApplyMigrations(db); err != nil {
+		t.Fatalf("apply migrations: %v", err)
+	}
+	var n int
+	var cron string
+	if err := db.QueryRow(`SELECT count(*), max(s.cron) FROM schedules s JOIN command_sets cs ON cs.id = s.command_set_id
+		WHERE cs.name = 'backup'`).Scan(&n, &cron); err != nil || n != 1 || cron != "@daily" {
+		t.Fatalf("expected the backup schedule to be kept, got %d %q %v", n, cron, err)
+	}
+	if err := db.QueryRow("SELECT count(*) FROM schedules").Scan(&n); err != nil || n != 1 {
+		t.Fatalf("expected the schedule of the missing set to be dropped, got %d %v", n, err)
+	}
+}
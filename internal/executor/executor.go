@@ -6,6 +6,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
 	"runtime"
 	"strings"
@@ -18,6 +19,8 @@ type Executor struct {
 	DryRun  bool
 	Verbose bool
 	Shell   string // optional override (e.g., "pwsh")
+	// Env holds extra KEY=VALUE variables added to the inherited environment.
+	Env []string
 }
 
 // unescapeWriter wraps an io.Writer and normalizes output produced by some
@@ -74,6 +77,13 @@ type Runner interface {
 	Execute(ctx context.Context, command string, cwd string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error
 }
 
+// IsTerminal reports whether fd refers to a terminal, using the same
+// detection the executor applies to decide on PTY-backed execution. It always
+// reports false on Windows.
+func IsTerminal(fd uintptr) bool {
+	return isTerminal(fd)
+}
+
 // New returns a Runner backed by the real Executor implementation.
 func New(dry, verbose bool) Runner {
 	return &Executor{DryRun: dry, Verbose: verbose}
@@ -137,7 +147,7 @@ func (e *Executor) Execute(ctx context.Context, command string, cwd string, stdi
 		return err
 	}
 
-	bout, berr, streamed, err := runShellCommand(ctx, shell, args, cwd, stdin, stdout, stderr)
+	bout, berr, streamed, err := runShellCommand(ctx, shell, args, cwd, e.Env, stdin, stdout, stderr)
 
 	// If the child was run in a PTY, output has already been streamed
 	// directly to `stdout`/`stderr` and we should avoid re-writing it.
@@ -284,11 +294,14 @@ func reportPipelineResult(bout *bytes.Buffer, berr *bytes.Buffer, findErr error,
 // It accepts explicit stdin/stdout/stderr writers so it can stream output
 // live when running the child in a PTY (interactive flows). It returns a
 // boolean indicating whether streaming to the provided writers occurred.
-func runShellCommand(ctx context.Context, shell string, args []string, cwd string, stdin io.Reader, stdout io.Writer, stderr io.Writer) (*bytes.Buffer, *bytes.Buffer, bool, error) {
+func runShellCommand(ctx context.Context, shell string, args []string, cwd string, env []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) (*bytes.Buffer, *bytes.Buffer, bool, error) {
 	cmd := exec.CommandContext(ctx, shell, args...)
 	if cwd != "" {
 		cmd.Dir = cwd
 	}
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
 
 	// If stdin looks like a terminal and we're on Unix-like platforms, use
 	// the PTY starter (which can be simulated in tests).
@@ -1,11 +1,13 @@
 package exporter
 
 import (
+	"database/sql"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/registry"
 )
 
 func TestExportDatabase(t *testing.T) {
@@ -17,6 +19,13 @@ func TestExportDatabase(t *testing.T) {
 	if err != nil {
 		t.Fatalf("InitDB(): %v", err)
 	}
+	r := registry.NewRepository(dbConn)
+	if _, err := r.CreateCommandSet("deploy", nil, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.EnableWebhook("deploy", "s3cret"); err != nil {
+		t.Fatal(err)
+	}
 	_ = dbConn.Close()
 
 	dst := filepath.Join(tmp, "exported.db")
@@ -26,4 +35,13 @@ func TestExportDatabase(t *testing.T) {
 	if _, err := os.Stat(dst); err != nil {
 		t.Fatalf("exported file not found: %v", err)
 	}
+	exp, err := sql.Open("sqlite", dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = exp.Close() }()
+	var secret string
+	if err := exp.QueryRow("SELECT secret FROM webhooks").Scan(&secret); err != nil || secret != "" {
+		t.Fatalf("expected the webhook secret to be stripped from the export, got %q %v", secret, err)
+	}
 }
@@ -12,7 +12,9 @@ import (
 	_ "modernc.org/sqlite"
 
 	"github.com/VoxDroid/krnr/internal/config"
+	"github.com/VoxDroid/krnr/internal/crypt"
 	dbpkg "github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/signing"
 )
 
 func fetchCommandSetAndCommands(srcDB *sql.DB, name string) (struct {
@@ -89,6 +91,8 @@ func createAndPopulateDst(dstDB *sql.DB, name string, description sql.NullString
 
 // ExportDatabase copies the active krnr database to dstPath. It checkpoints WAL to
 // ensure recent transactions are flushed into the main DB file before copying.
+// Webhook secrets, and a signature the active database kept from an
+// imported bundle, are removed from the copy.
 func ExportDatabase(dstPath string) error {
 	src, err := config.DBPath()
 	if err != nil {
@@ -117,7 +121,29 @@ func ExportDatabase(dstPath string) error {
 	if _, err := io.Copy(out, in); err != nil {
 		return fmt.Errorf("copy db: %w", err)
 	}
-	return nil
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("copy db: %w", err)
+	}
+	dstDB, err := sql.Open("sqlite", dstPath)
+	if err != nil {
+		return fmt.Errorf("open dst db: %w", err)
+	}
+	defer func() { _ = dstDB.Close() }()
+	if _, err := dstDB.Exec("UPDATE webhooks SET secret = ''"); err != nil {
+		return fmt.Errorf("strip webhook secrets: %w", err)
+	}
+	return signing.Unsign(dstDB)
+}
+
+// Sign embeds a signature made with key of the sets in the bundle at path,
+// exported by ExportDatabase or ExportCommandSet.
+func Sign(path string, key signing.Key, signer string) (signing.Signature, error) {
+	dstDB, err := sql.Open("sqlite", path)
+	if err != nil {
+		return signing.Signature{}, fmt.Errorf("open bundle: %w", err)
+	}
+	defer func() { _ = dstDB.Close() }()
+	return signing.Sign(dstDB, key, signer)
 }
 
 // ExportCommandSet exports a single named command set into a standalone SQLite DB
@@ -147,3 +173,24 @@ func ExportCommandSet(srcDB *sql.DB, name string, dstPath string) error {
 	}
 	return nil
 }
+
+// ExportEncrypted calls export to write a bundle into a temporary file only
+// the user can read, then writes it to dstPath encrypted with passphrase
+// and removes the temporary file.
+func ExportEncrypted(dstPath string, passphrase []byte, export func(path string) error) error {
+	if len(passphrase) == 0 {
+		return fmt.Errorf("passphrase cannot be empty")
+	}
+	tmp, cleanup, err := crypt.TempFile()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	if err := export(tmp); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0o755); err != nil {
+		return fmt.Errorf("create dst dir: %w", err)
+	}
+	return crypt.EncryptFile(tmp, dstPath, passphrase)
+}
@@ -0,0 +1,277 @@
+// Package hooks implements the HTTP webhook server behind `krnr serve hooks`:
+// signed POST requests start runs of hook-enabled command sets and a status
+// endpoint reports how they went.
+package hooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VoxDroid/krnr/internal/httpjson"
+	"github.com/VoxDroid/krnr/internal/registry"
+)
+
+// SignatureHeader carries the request signature: "sha256=" followed by the
+// hex HMAC-SHA256, keyed with the hook's secret, of the TimestampHeader
+// value, a '.', and the request body.
+const SignatureHeader = "X-Krnr-Signature"
+
+// TimestampHeader carries the time the request was signed, in Unix seconds.
+// It is covered by the signature, so a captured request cannot be replayed
+// once it falls outside MaxSkew.
+const TimestampHeader = "X-Krnr-Timestamp"
+
+// MaxSkew is how far a request's timestamp may be from the server clock.
+const MaxSkew = 5 * time.Minute
+
+// Trigger is recorded in run history for runs started by a webhook.
+const Trigger = "hook"
+
+// maxBody bounds request bodies; hook payloads are small parameter maps.
+const maxBody = 1 << 20
+
+// NewSecret returns a random hook secret.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Sign returns the signature header value for body signed at timestamp
+// (Unix seconds).
+func Sign(secret string, timestamp int64, body []byte) string {
+	m := hmac.New(sha256.New, []byte(secret))
+	_, _ = fmt.Fprintf(m, "%d.", timestamp)
+	_, _ = m.Write(body)
+	return "sha256=" + hex.EncodeToString(m.Sum(nil))
+}
+
+// SignRequest sets the timestamp and signature headers of req for body.
+func SignRequest(req *http.Request, secret string, body []byte) {
+	ts := time.Now().Unix()
+	req.Header.Set(TimestampHeader, strconv.FormatInt(ts, 10))
+	req.Header.Set(SignatureHeader, Sign(secret, ts, body))
+}
+
+// Verify checks that signature is a valid signature of body made at
+// timestamp, and that timestamp is within MaxSkew of now.
+func Verify(secret string, body []byte, timestamp, signature string, now time.Time) error {
+	ts, err := strconv.ParseInt(strings.TrimSpace(timestamp), 10, 64)
+	if err != nil {
+		return errors.New("missing or invalid " + TimestampHeader)
+	}
+	if skew := now.Sub(time.Unix(ts, 0)); skew > MaxSkew || skew < -MaxSkew {
+		return errors.New("stale request: " + TimestampHeader + " is outside the allowed window")
+	}
+	if !hmac.Equal([]byte(Sign(secret, ts, body)), []byte(strings.TrimSpace(signature))) {
+		return errors.New("missing or invalid " + SignatureHeader)
+	}
+	return nil
+}
+
+// Backend is what the server needs from the rest of krnr.
+type Backend interface {
+	// Secret returns the HMAC secret of a hook-enabled set; ok is false for
+	// sets that do not exist or are not hook-enabled.
+	Secret(set string) (secret string, ok bool, err error)
+	// Start validates params and starts a run of set, returning its run ID.
+	// Errors may be *httpjson.StatusError to choose the response status.
+	Start(set string, params map[string]string) (string, error)
+	// Run returns the run with the given ID, or nil.
+	Run(runID string) (*registry.RunRecord, error)
+}
+
+// Server answers webhook requests:
+//
+//	POST /hooks/{set}  start a run; the JSON object body maps parameter names to values
+//	GET  /runs/{id}    run status
+//
+// Every request must be signed with the set's secret (see SignatureHeader);
+// for GET the signed body is empty. A signed trigger is accepted once: the
+// server remembers signatures until they fall outside MaxSkew.
+type Server struct {
+	backend Backend
+	log     io.Writer
+	mux     *http.ServeMux
+	now     func() time.Time
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// NewServer returns a server backed by b. Each request is logged to log
+// (nil discards).
+func NewServer(b Backend, log io.Writer) *Server {
+	if log == nil {
+		log = io.Discard
+	}
+	s := &Server{backend: b, log: log, mux: http.NewServeMux(), now: time.Now, seen: map[string]time.Time{}}
+	s.mux.HandleFunc("POST /hooks/{set}", s.handleTrigger)
+	s.mux.HandleFunc("GET /runs/{id}", s.handleStatus)
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	httpjson.ServeLogged(s.mux, s.log, w, r)
+}
+
+// TriggerResponse is the body of a successful POST /hooks/{set}.
+type TriggerResponse struct {
+	RunID     string `json:"run_id"`
+	Set       string `json:"set"`
+	Status    string `json:"status"`
+	StatusURL string `json:"status_url"`
+}
+
+// RunStatus is the body of GET /runs/{id}.
+type RunStatus struct {
+	RunID      string `json:"run_id"`
+	Set        string `json:"set"`
+	Status     string `json:"status"`
+	ExitCode   *int64 `json:"exit_code,omitempty"`
+	StartedAt  string `json:"started_at"`
+	FinishedAt string `json:"finished_at,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// NewRunStatus converts a run history record to its wire form.
+func NewRunStatus(rr *registry.RunRecord) RunStatus {
+	st := RunStatus{RunID: rr.RunID, Set: rr.SetName, Status: rr.Status, StartedAt: rr.StartedAt, DurationMS: rr.Duration.Milliseconds()}
+	if rr.ExitCode.Valid {
+		code := rr.ExitCode.Int64
+		st.ExitCode = &code
+	}
+	if rr.FinishedAt.Valid {
+		st.FinishedAt = rr.FinishedAt.String
+	}
+	return st
+}
+
+func (s *Server) handleTrigger(w http.ResponseWriter, r *http.Request) {
+	set := r.PathValue("set")
+	body, ok := s.authenticate(w, r, set)
+	if !ok {
+		return
+	}
+	if !s.firstUse(r.Header.Get(SignatureHeader)) {
+		httpjson.WriteError(w, &httpjson.StatusError{Code: http.StatusUnauthorized, Err: errors.New("replayed request: signature already used")})
+		return
+	}
+	params, err := ParseParams(body)
+	if err != nil {
+		httpjson.WriteError(w, &httpjson.StatusError{Code: http.StatusBadRequest, Err: err})
+		return
+	}
+	runID, err := s.backend.Start(set, params)
+	if err != nil {
+		httpjson.WriteError(w, err)
+		return
+	}
+	httpjson.Note(w, " run "+runID)
+	httpjson.WriteJSON(w, http.StatusAccepted, TriggerResponse{RunID: runID, Set: set, Status: registry.RunStatusRunning, StatusURL: "/runs/" + runID})
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	rr, err := s.backend.Run(r.PathValue("id"))
+	if err != nil {
+		httpjson.WriteError(w, err)
+		return
+	}
+	// only runs started by a hook are visible, and only to holders of
+	// that hook's secret
+	if rr == nil || rr.Trigger != Trigger {
+		httpjson.WriteError(w, &httpjson.StatusError{Code: http.StatusNotFound, Err: errors.New("run not found")})
+		return
+	}
+	if _, ok := s.authenticate(w, r, rr.SetName); !ok {
+		return
+	}
+	httpjson.WriteJSON(w, http.StatusOK, NewRunStatus(rr))
+}
+
+// authenticate reads the body and checks its signature against set's secret.
+// Sets that are not hook-enabled are reported as not found.
+func (s *Server) authenticate(w http.ResponseWriter, r *http.Request, set string) ([]byte, bool) {
+	secret, ok, err := s.backend.Secret(set)
+	if err != nil {
+		httpjson.WriteError(w, err)
+		return nil, false
+	}
+	if !ok {
+		httpjson.WriteError(w, &httpjson.StatusError{Code: http.StatusNotFound, Err: fmt.Errorf("no hook for %s", set)})
+		return nil, false
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBody))
+	if err != nil {
+		httpjson.WriteError(w, &httpjson.StatusError{Code: http.StatusRequestEntityTooLarge, Err: err})
+		return nil, false
+	}
+	if err := Verify(secret, body, r.Header.Get(TimestampHeader), r.Header.Get(SignatureHeader), s.now()); err != nil {
+		httpjson.WriteError(w, &httpjson.StatusError{Code: http.StatusUnauthorized, Err: err})
+		return nil, false
+	}
+	return body, true
+}
+
+// firstUse records sig and reports whether it had not been seen within the
+// replay window. Expired signatures are forgotten; their timestamps no
+// longer verify anyway.
+func (s *Server) firstUse(sig string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for k, at := range s.seen {
+		if now.Sub(at) > 2*MaxSkew {
+			delete(s.seen, k)
+		}
+	}
+	sig = strings.TrimSpace(sig)
+	if _, ok := s.seen[sig]; ok {
+		return false
+	}
+	s.seen[sig] = now
+	return true
+}
+
+// ParseParams decodes a JSON object of parameter values. Strings, numbers
+// and booleans are accepted; an empty body means no parameters.
+func ParseParams(body []byte) (map[string]string, error) {
+	params := map[string]string{}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return params, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var raw map[string]any
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("body must be a JSON object of parameter values: %v", err)
+	}
+	for k, v := range raw {
+		switch tv := v.(type) {
+		case string:
+			params[k] = tv
+		case json.Number:
+			params[k] = tv.String()
+		case bool:
+			params[k] = fmt.Sprint(tv)
+		default:
+			return nil, fmt.Errorf("parameter %q must be a string, number or boolean", k)
+		}
+	}
+	return params, nil
+}
@@ -0,0 +1,178 @@
+package hooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VoxDroid/krnr/internal/httpjson"
+	"github.com/VoxDroid/krnr/internal/registry"
+)
+
+type fakeBackend struct {
+	secrets map[string]string
+	runs    map[string]*registry.RunRecord
+	started map[string]string
+	err     error
+}
+
+func (f *fakeBackend) Secret(set string) (string, bool, error) {
+	s, ok := f.secrets[set]
+	return s, ok, nil
+}
+
+func (f *fakeBackend) Start(set string, params map[string]string) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	f.started = params
+	id := "run-" + set
+	f.runs[id] = &registry.RunRecord{RunID: id, SetName: set, Status: registry.RunStatusRunning, Trigger: Trigger}
+	return id, nil
+}
+
+func (f *fakeBackend) Run(runID string) (*registry.RunRecord, error) { return f.runs[runID], nil }
+
+func newTestServer() (*fakeBackend, *httptest.Server, *bytes.Buffer) {
+	b := &fakeBackend{secrets: map[string]string{"deploy": "s3cret"}, runs: map[string]*registry.RunRecord{
+		"manual-1": {RunID: "manual-1", SetName: "deploy", Status: registry.RunStatusOK, Trigger: "manual"},
+	}}
+	var log bytes.Buffer
+	return b, httptest.NewServer(NewServer(b, &log)), &log
+}
+
+func do(t *testing.T, method, url, secret, body string) (int, map[string]any) {
+	t.Helper()
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	if secret != "" {
+		SignRequest(req, secret, []byte(body))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var out map[string]any
+	_ = json.NewDecoder(resp.Body).Decode(&out)
+	return resp.StatusCode, out
+}
+
+func TestSignVerify(t *testing.T) {
+	now := time.Unix(1_800_000_000, 0)
+	ts := strconv.FormatInt(now.Unix(), 10)
+	sig := Sign("k", now.Unix(), []byte("body"))
+	if !strings.HasPrefix(sig, "sha256=") || Verify("k", []byte("body"), ts, sig, now) != nil {
+		t.Fatalf("signature %q does not verify", sig)
+	}
+	if Verify("other", []byte("body"), ts, sig, now) == nil || Verify("k", []byte("body2"), ts, sig, now) == nil || Verify("k", []byte("body"), ts, "", now) == nil {
+		t.Fatalf("expected mismatched signatures to fail")
+	}
+	if Verify("k", []byte("body"), "", sig, now) == nil || Verify("k", []byte("body"), "1800000001", sig, now) == nil {
+		t.Fatalf("the timestamp must be present and covered by the signature")
+	}
+	if err := Verify("k", []byte("body"), ts, sig, now.Add(MaxSkew+time.Second)); err == nil || !strings.Contains(err.Error(), "stale") {
+		t.Fatalf("expected a stale request to be rejected, got %v", err)
+	}
+	a, _ := NewSecret()
+	b, _ := NewSecret()
+	if len(a) != 64 || a == b {
+		t.Fatalf("unexpected secrets %q %q", a, b)
+	}
+}
+
+func TestStaleAndReplayedRequestsRejected(t *testing.T) {
+	b, srv, _ := newTestServer()
+	defer srv.Close()
+
+	send := func(ts int64, sig string) int {
+		req, _ := http.NewRequest("POST", srv.URL+"/hooks/deploy", strings.NewReader("{}"))
+		req.Header.Set(TimestampHeader, strconv.FormatInt(ts, 10))
+		req.Header.Set(SignatureHeader, sig)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST: %v", err)
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	stale := time.Now().Add(-MaxSkew - time.Minute).Unix()
+	if code := send(stale, Sign("s3cret", stale, []byte("{}"))); code != http.StatusUnauthorized {
+		t.Fatalf("stale request: got %d, want 401", code)
+	}
+	if b.started != nil {
+		t.Fatalf("a stale request must not start a run")
+	}
+
+	now := time.Now().Unix()
+	sig := Sign("s3cret", now, []byte("{}"))
+	if code := send(now, sig); code != http.StatusAccepted {
+		t.Fatalf("fresh request: got %d, want 202", code)
+	}
+	b.started = nil
+	if code := send(now, sig); code != http.StatusUnauthorized || b.started != nil {
+		t.Fatalf("replayed request: got %d (started %v), want 401", code, b.started)
+	}
+}
+
+func TestTriggerAndStatus(t *testing.T) {
+	b, srv, log := newTestServer()
+	defer srv.Close()
+
+	code, out := do(t, "POST", srv.URL+"/hooks/deploy", "s3cret", `{"branch":"main","replicas":3,"dry":true}`)
+	if code != http.StatusAccepted || out["run_id"] != "run-deploy" || out["status_url"] != "/runs/run-deploy" {
+		t.Fatalf("trigger = %d %v", code, out)
+	}
+	if b.started["branch"] != "main" || b.started["replicas"] != "3" || b.started["dry"] != "true" {
+		t.Fatalf("unexpected params %v", b.started)
+	}
+	code, out = do(t, "GET", srv.URL+"/runs/run-deploy", "s3cret", "")
+	if code != http.StatusOK || out["status"] != registry.RunStatusRunning || out["set"] != "deploy" {
+		t.Fatalf("status = %d %v", code, out)
+	}
+	if !strings.Contains(log.String(), "POST /hooks/deploy 202 run run-deploy") {
+		t.Fatalf("unexpected request log: %s", log.String())
+	}
+}
+
+func TestRejectedRequests(t *testing.T) {
+	b, srv, _ := newTestServer()
+	defer srv.Close()
+
+	cases := []struct {
+		name, method, path, secret, body string
+		want                             int
+	}{
+		{"unsigned", "POST", "/hooks/deploy", "", "{}", http.StatusUnauthorized},
+		{"wrong secret", "POST", "/hooks/deploy", "nope", "{}", http.StatusUnauthorized},
+		{"not hook-enabled", "POST", "/hooks/other", "s3cret", "{}", http.StatusNotFound},
+		{"not an object", "POST", "/hooks/deploy", "s3cret", "[1]", http.StatusBadRequest},
+		{"nested value", "POST", "/hooks/deploy", "s3cret", `{"a":{"b":1}}`, http.StatusBadRequest},
+		{"status unsigned", "GET", "/runs/run-x", "", "", http.StatusNotFound},
+		{"status of manual run", "GET", "/runs/manual-1", "s3cret", "", http.StatusNotFound},
+		{"wrong method", "GET", "/hooks/deploy", "s3cret", "", http.StatusMethodNotAllowed},
+	}
+	for _, c := range cases {
+		if code, out := do(t, c.method, srv.URL+c.path, c.secret, c.body); code != c.want {
+			t.Errorf("%s: got %d %v, want %d", c.name, code, out, c.want)
+		}
+	}
+	if b.started != nil {
+		t.Fatalf("no run should have started, got params %v", b.started)
+	}
+
+	b.err = &httpjson.StatusError{Code: http.StatusForbidden, Err: errors.New("refused")}
+	if code, out := do(t, "POST", srv.URL+"/hooks/deploy", "s3cret", ""); code != http.StatusForbidden || out["error"] != "refused" {
+		t.Fatalf("backend error = %d %v", code, out)
+	}
+	b.err = errors.New("boom")
+	if code, _ := do(t, "POST", srv.URL+"/hooks/deploy", "s3cret", "{}"); code != http.StatusInternalServerError {
+		t.Fatalf("expected 500 for plain errors, got %d", code)
+	}
+}
@@ -0,0 +1,85 @@
+// Package httpjson holds what krnr's HTTP servers (the webhook server and
+// the REST API) share: JSON responses, errors that choose their response
+// status and a request log.
+package httpjson
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// StatusError is an error carrying the HTTP status to answer with.
+type StatusError struct {
+	Code int
+	Err  error
+}
+
+func (e *StatusError) Error() string { return e.Err.Error() }
+func (e *StatusError) Unwrap() error { return e.Err }
+
+// HTTPStatus returns the response status for the error.
+func (e *StatusError) HTTPStatus() int { return e.Code }
+
+// Error is the body of every error response.
+type Error struct {
+	Error string `json:"error"`
+}
+
+// WriteJSON answers with code and v as indented JSON.
+func WriteJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+// WriteError answers with err as an Error body. The status is the one err
+// carries (see StatusError), 500 otherwise; the request log shows err.
+func WriteError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	var se interface{ HTTPStatus() int }
+	if errors.As(err, &se) {
+		code = se.HTTPStatus()
+	}
+	Note(w, ": "+err.Error())
+	WriteJSON(w, code, Error{Error: err.Error()})
+}
+
+// Note appends note to the request's line in the log of ServeLogged.
+func Note(w http.ResponseWriter, note string) {
+	if rec, ok := w.(*statusRecorder); ok {
+		rec.note = note
+	}
+}
+
+// ServeLogged serves r with h and writes a line with the request and its
+// response status to log.
+func ServeLogged(h http.Handler, log io.Writer, w http.ResponseWriter, r *http.Request) {
+	rec := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+	h.ServeHTTP(rec, r)
+	_, _ = fmt.Fprintf(log, "%s %s %s %d%s\n", time.Now().Format("2006-01-02 15:04:05"), r.Method, r.URL.Path, rec.code, rec.note)
+}
+
+// statusRecorder remembers the response status (and a short note) for the
+// request log. It passes flushes through for event streams.
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+	note string
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.code = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,48 @@
+package httpjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteErrorAndRequestLog(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /missing", func(w http.ResponseWriter, _ *http.Request) {
+		WriteError(w, fmt.Errorf("lookup: %w", &StatusError{Code: http.StatusNotFound, Err: errors.New("no such set")}))
+	})
+	mux.HandleFunc("GET /broken", func(w http.ResponseWriter, _ *http.Request) {
+		WriteError(w, errors.New("disk full"))
+	})
+	mux.HandleFunc("GET /ok", func(w http.ResponseWriter, _ *http.Request) {
+		Note(w, " run r1")
+		WriteJSON(w, http.StatusAccepted, map[string]string{"run_id": "r1"})
+	})
+	var log bytes.Buffer
+	for path, want := range map[string]struct {
+		code int
+		body string
+		log  string
+	}{
+		"/missing": {http.StatusNotFound, "lookup: no such set", "GET /missing 404: lookup: no such set"},
+		"/broken":  {http.StatusInternalServerError, "disk full", "GET /broken 500: disk full"},
+		"/ok":      {http.StatusAccepted, "", "GET /ok 202 run r1"},
+	} {
+		log.Reset()
+		rec := httptest.NewRecorder()
+		ServeLogged(mux, &log, rec, httptest.NewRequest(http.MethodGet, path, nil))
+		var body Error
+		_ = json.Unmarshal(rec.Body.Bytes(), &body)
+		if rec.Code != want.code || body.Error != want.body || rec.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("%s: got %d %q", path, rec.Code, rec.Body.String())
+		}
+		if !strings.HasSuffix(strings.TrimSpace(log.String()), want.log) {
+			t.Errorf("%s: unexpected log line %q", path, log.String())
+		}
+	}
+}
@@ -1,23 +1,28 @@
 package importer
 
-import "database/sql"
+import (
+	"database/sql"
 
-// copyCommands copies commands for a given source command set id into dst using new command set id
-func copyCommands(src *sql.DB, srcCommandSetID int64, dst *sql.DB, newID int64) error {
-	crows, err := src.Query("SELECT position, command FROM commands WHERE command_set_id = ? ORDER BY position ASC", srcCommandSetID)
+	"github.com/VoxDroid/krnr/internal/registry"
+)
+
+// scannedCommands returns the commands of a source command set that is
+// imported as name, passed through r's CommandScanner.
+func scannedCommands(r *registry.Repository, src *sql.DB, srcCommandSetID int64, name string) ([]string, error) {
+	cmds, err := getCommands(src, srcCommandSetID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer func() { _ = crows.Close() }()
-	for crows.Next() {
-		var pos int
-		var cmd string
-		if err := crows.Scan(&pos, &cmd); err != nil {
-			return err
-		}
-		if _, err := dst.Exec("INSERT INTO commands (command_set_id, position, command) VALUES (?, ?, ?)", newID, pos, cmd); err != nil {
+	return r.ScanCommands(name, cmds)
+}
+
+// insertCommands writes cmds as the commands of the set newID, imported as
+// name, in dst and records its provenance.
+func insertCommands(r *registry.Repository, dst *sql.DB, newID int64, name string, cmds []string, imp registry.Import) error {
+	for i, cmd := range cmds {
+		if _, err := dst.Exec("INSERT INTO commands (command_set_id, position, command) VALUES (?, ?, ?)", newID, i+1, cmd); err != nil {
 			return err
 		}
 	}
-	return nil
+	return r.RecordImport(name, imp)
 }
@@ -2,10 +2,16 @@
 package importer
 
 import (
+	"database/sql"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/VoxDroid/krnr/internal/config"
+	"github.com/VoxDroid/krnr/internal/crypt"
 	"github.com/VoxDroid/krnr/internal/db"
 	"github.com/VoxDroid/krnr/internal/exporter"
 	"github.com/VoxDroid/krnr/internal/registry"
@@ -73,6 +79,9 @@ func TestImportCommandSet(t *testing.T) {
 	if cs == nil {
 		t.Fatalf("expected imported command set 'imp-set'")
 	}
+	if p, err := r2.Untrusted("imp-set"); err != nil || p == nil || p.Source != dst || p.TrustedCommands != nil {
+		t.Fatalf("expected an untrusted set imported from %s, got %+v %v", dst, p, err)
+	}
 }
 
 func createExistingSet(t *testing.T, name string, cmds []string) {
@@ -124,4 +133,225 @@ func TestImportCommandSetMergeAndDedupe(t *testing.T) {
 	if len(cs.Commands) != 3 || cs.Commands[0].Command != "echo B" || cs.Commands[1].Command != "echo C" || cs.Commands[2].Command != "echo A" {
 		t.Fatalf("unexpected merged commands: %+v", cs.Commands)
 	}
+	// the merge added a command, so it needs trusting again
+	if p, _ := r3.Untrusted("imp-merge"); p == nil || strings.Join(p.TrustedCommands, ",") != "echo B,echo C" {
+		t.Fatalf("expected the merged set to be untrusted, got %+v", p)
+	}
+}
+
+func TestImportCommandSetScansCommands(t *testing.T) {
+	dst := exportTempDB(t, "imp-secret", []string{"echo ok", "login --password hunter22"})
+	prepareDestination(t)
+	t.Cleanup(func() { registry.SetDefaultCommandScanner(nil) })
+	registry.SetDefaultCommandScanner(func(_ string, cmds []string) ([]string, error) {
+		out := make([]string, len(cmds))
+		for i, c := range cmds {
+			out[i] = strings.ReplaceAll(c, "hunter22", "{{password}}")
+		}
+		return out, nil
+	})
+	if err := ImportCommandSet(dst, ImportOptions{}); err != nil {
+		t.Fatalf("ImportCommandSet: %v", err)
+	}
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = dbConn.Close() }()
+	cs, err := registry.NewRepository(dbConn).GetCommandSetByName("imp-secret")
+	if err != nil || cs == nil {
+		t.Fatalf("imported set: %v %v", cs, err)
+	}
+	if len(cs.Commands) != 2 || cs.Commands[1].Command != "login --password {{password}}" {
+		t.Fatalf("expected scanned commands, got %+v", cs.Commands)
+	}
+
+	// a refusing scanner imports nothing
+	registry.SetDefaultCommandScanner(func(string, []string) ([]string, error) { return nil, errors.New("refused") })
+	if err := ImportCommandSet(dst, ImportOptions{OnConflict: "overwrite"}); err == nil {
+		t.Fatal("expected the scanner to refuse the import")
+	}
+	if cs, _ := registry.NewRepository(dbConn).GetCommandSetByName("imp-secret"); cs == nil || len(cs.Commands) != 2 {
+		t.Fatalf("refused import changed the set: %+v", cs)
+	}
+}
+
+func TestImportEncryptedBundle(t *testing.T) {
+	origCost := crypt.Cost
+	crypt.Cost.LogN = 10
+	t.Cleanup(func() { crypt.Cost = origCost })
+	plain := exportTempDB(t, "enc-set", []string{"echo secret-host"})
+	enc := filepath.Join(filepath.Dir(plain), "enc.db")
+	if err := exporter.ExportEncrypted(enc, []byte("s3cret"), func(p string) error {
+		data, err := os.ReadFile(plain)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(p, data, 0o600)
+	}); err != nil {
+		t.Fatalf("ExportEncrypted: %v", err)
+	}
+	prepareDestination(t)
+
+	if err := ImportCommandSet(enc, ImportOptions{}); err == nil {
+		t.Fatal("expected an encrypted bundle to need a passphrase")
+	}
+	wrong := func() ([]byte, error) { return []byte("nope"), nil }
+	if err := ImportCommandSet(enc, ImportOptions{Passphrase: wrong}); !errors.Is(err, crypt.ErrDecrypt) {
+		t.Fatalf("expected ErrDecrypt, got %v", err)
+	}
+	right := func() ([]byte, error) { return []byte("s3cret"), nil }
+	if err := ImportCommandSet(enc, ImportOptions{Passphrase: right}); err != nil {
+		t.Fatalf("ImportCommandSet: %v", err)
+	}
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB(): %v", err)
+	}
+	defer func() { _ = dbConn.Close() }()
+	if p, err := registry.NewRepository(dbConn).Untrusted("enc-set"); err != nil || p == nil || p.Source != enc {
+		t.Fatalf("expected the set imported from %s, got %+v %v", enc, p, err)
+	}
+}
+
+func TestImportDatabaseOverwriteTakesOnlySignedSets(t *testing.T) {
+	bundle := exportTempDB(t, "ovr-set", []string{"echo from-bundle"})
+	b, err := sql.Open("sqlite", bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a bundle claiming its set was imported elsewhere and trusted there,
+	// carrying a webhook no signature covers
+	for _, q := range []string{
+		`INSERT INTO provenance (command_set_id, source, original_author, imported_at, trusted)
+		SELECT id, 'elsewhere', 'Mallory', datetime('now'), 1 FROM command_sets WHERE name = 'ovr-set'`,
+		`INSERT INTO webhooks (command_set_id, secret, created_at) SELECT id, 'known', datetime('now') FROM command_sets WHERE name = 'ovr-set'`,
+	} {
+		if _, err := b.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = b.Close()
+	prepareDestination(t)
+	createExistingSet(t, "local-only", []string{"echo local"})
+
+	if err := ImportDatabase(bundle, true, ImportOptions{}); err != nil {
+		t.Fatalf("ImportDatabase: %v", err)
+	}
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB(): %v", err)
+	}
+	defer func() { _ = dbConn.Close() }()
+	r := registry.NewRepository(dbConn)
+	p, err := r.Untrusted("ovr-set")
+	if err != nil || p == nil || p.Source != bundle || p.TrustedCommands != nil {
+		t.Fatalf("expected ovr-set to be untrusted and imported from %s, got %+v %v", bundle, p, err)
+	}
+	if cs, _ := r.GetCommandSetByName("ovr-set"); cs == nil || len(cs.Commands) != 1 || cs.Commands[0].Command != "echo from-bundle" {
+		t.Fatalf("expected ovr-set to be imported, got %+v", cs)
+	}
+	if cs, _ := r.GetCommandSetByName("local-only"); cs != nil {
+		t.Fatalf("expected the local sets to be replaced, found %+v", cs)
+	}
+	if hooks, err := r.ListWebhooks(); err != nil || len(hooks) != 0 {
+		t.Fatalf("expected no webhooks from the bundle, got %v %v", hooks, err)
+	}
+}
+
+func TestImportDatabaseOverwriteKeepsAuditLog(t *testing.T) {
+	bundle := exportTempDB(t, "ovr-set", []string{"echo from-bundle"})
+	prepareDestination(t)
+	createExistingSet(t, "local-only", []string{"echo local"})
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB(): %v", err)
+	}
+	r := registry.NewRepository(dbConn)
+	if err := r.StartRun("run-1", "local-only", "manual"); err != nil {
+		t.Fatalf("StartRun: %v", err)
+	}
+	if err := r.FinishRun("run-1", 0, time.Second, nil); err != nil {
+		t.Fatalf("FinishRun: %v", err)
+	}
+	before, err := r.ListAudit("", 0)
+	if err != nil || len(before) == 0 {
+		t.Fatalf("expected local audit entries, got %v %v", before, err)
+	}
+	_ = dbConn.Close()
+
+	if err := ImportDatabase(bundle, true, ImportOptions{}); err != nil {
+		t.Fatalf("ImportDatabase: %v", err)
+	}
+	dbConn, err = db.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB(): %v", err)
+	}
+	defer func() { _ = dbConn.Close() }()
+	r = registry.NewRepository(dbConn)
+	after, err := r.ListAudit("", 0)
+	if err != nil || len(after) <= len(before) {
+		t.Fatalf("expected the import to add to the audit log, got %d entries before and %d after (%v)", len(before), len(after), err)
+	}
+	// the old entries are still there, oldest first, unchanged
+	kept := after[len(after)-len(before):]
+	for i := range before {
+		if kept[i] != before[i] {
+			t.Fatalf("audit entry %d changed from %+v to %+v", before[i].ID, before[i], kept[i])
+		}
+	}
+	if runs, err := r.ListRuns("local-only", 0); err != nil || len(runs) != 1 || runs[0].RunID != "run-1" {
+		t.Fatalf("expected the local run history to be kept, got %+v %v", runs, err)
+	}
+	// the chain still verifies, including entries written afterwards
+	if _, err := r.CreateCommandSet("after-import", nil, nil, nil, []string{"echo after"}); err != nil {
+		t.Fatalf("CreateCommandSet: %v", err)
+	}
+	rep, err := r.VerifyAudit()
+	if err != nil || len(rep.Problems) != 0 || rep.Entries != len(after)+1 {
+		t.Fatalf("expected the audit log to verify, got %+v %v", rep, err)
+	}
+}
+
+func TestImportDatabaseOverwriteFailureKeepsLocalSets(t *testing.T) {
+	bundle := exportTempDB(t, "ovr-set", []string{"echo from-bundle"})
+	b, err := sql.Open("sqlite", bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a second set the import fails on, after the first one was imported
+	for _, q := range []string{
+		`DROP TRIGGER command_sets_check_name_insert`,
+		`INSERT INTO command_sets (name, created_at) VALUES ('   ', datetime('now'))`,
+	} {
+		if _, err := b.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = b.Close()
+	prepareDestination(t)
+	createExistingSet(t, "local-only", []string{"echo local"})
+
+	if err := ImportDatabase(bundle, true, ImportOptions{}); err == nil {
+		t.Fatalf("expected the import to fail")
+	}
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB(): %v", err)
+	}
+	defer func() { _ = dbConn.Close() }()
+	r := registry.NewRepository(dbConn)
+	if cs, _ := r.GetCommandSetByName("local-only"); cs == nil || len(cs.Commands) != 1 {
+		t.Fatalf("expected the local set to survive, got %+v", cs)
+	}
+	if cs, _ := r.GetCommandSetByName("ovr-set"); cs != nil {
+		t.Fatalf("expected none of the bundle's sets, found %+v", cs)
+	}
+	if rep, err := r.VerifyAudit(); err != nil || len(rep.Problems) != 0 {
+		t.Fatalf("expected the audit log to verify, got %+v %v", rep, err)
+	}
+	path, _ := config.DBPath()
+	if _, err := os.Stat(path + ".import"); !os.IsNotExist(err) {
+		t.Fatalf("expected the partial replacement to be removed, got %v", err)
+	}
 }
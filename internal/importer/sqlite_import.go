@@ -4,7 +4,6 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -13,26 +12,120 @@ import (
 	_ "modernc.org/sqlite"
 
 	"github.com/VoxDroid/krnr/internal/config"
+	"github.com/VoxDroid/krnr/internal/crypt"
 	dbpkg "github.com/VoxDroid/krnr/internal/db"
 	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/security"
+	"github.com/VoxDroid/krnr/internal/signing"
 )
 
 // ImportOptions controls per-set conflict behavior during import.
 type ImportOptions struct {
 	OnConflict string // rename|skip|overwrite|merge
 	Dedupe     bool   // dedupe identical commands when merging
+	// Verified, when set, is called with the bundle's signature once it
+	// was checked, before anything is imported.
+	Verified func(signing.Verification)
+	// Passphrase returns the passphrase of a bundle exported with
+	// --encrypt; without it encrypted bundles are refused.
+	Passphrase func() ([]byte, error)
 }
 
-// ImportDatabase copies srcPath into the default database location when overwrite
-// is true. If overwrite is false and opts.OnConflict == "rename" (default),
-// the function returns an error. If overwrite is false and a per-set policy is
-// provided in opts, the function will merge the command sets from src into the
-// existing DB applying the per-set policy.
+// OpenBundle returns the bundle at srcPath as a plain SQLite file: srcPath
+// itself, or for an encrypted bundle a temporary file only the user can
+// read holding it decrypted. cleanup removes the temporary file.
+func OpenBundle(srcPath string, passphrase func() ([]byte, error)) (string, func(), error) {
+	enc, err := crypt.IsEncryptedFile(srcPath)
+	if err != nil {
+		return "", nil, fmt.Errorf("open src: %w", err)
+	}
+	if !enc {
+		return srcPath, func() {}, nil
+	}
+	if passphrase == nil {
+		return "", nil, fmt.Errorf("%s is encrypted: a passphrase is required", srcPath)
+	}
+	p, err := passphrase()
+	if err != nil {
+		return "", nil, err
+	}
+	plain, cleanup, err := crypt.DecryptToTemp(srcPath, p)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %w", srcPath, err)
+	}
+	return plain, cleanup, nil
+}
+
+// VerifyBundle checks the signature of the bundle at srcPath, decrypted
+// with passphrase when needed, and looks its key up in the keyring.
+// Bundles modified after they were signed are refused, and so are bundles
+// not signed by a key in the keyring when the policy sets
+// require_signatures.
+func VerifyBundle(srcPath string, passphrase func() ([]byte, error)) (signing.Verification, error) {
+	plain, cleanup, err := OpenBundle(srcPath, passphrase)
+	if err != nil {
+		return signing.Verification{}, err
+	}
+	defer cleanup()
+	return verify(plain, srcPath)
+}
+
+// verify checks the signature of the plain bundle opened from srcPath.
+func verify(plainPath, srcPath string) (signing.Verification, error) {
+	src, err := sql.Open("sqlite", plainPath)
+	if err != nil {
+		return signing.Verification{}, fmt.Errorf("open src: %w", err)
+	}
+	defer func() { _ = src.Close() }()
+	k, err := signing.LoadKeyring()
+	if err != nil {
+		return signing.Verification{}, err
+	}
+	v, err := signing.VerifyWith(src, k)
+	if err != nil {
+		return v, fmt.Errorf("%s: %w", srcPath, err)
+	}
+	p, err := security.LoadPolicy()
+	if err != nil {
+		return v, fmt.Errorf("policy: %w", err)
+	}
+	if p.RequireSignatures && !v.Trusted() {
+		return v, fmt.Errorf("refusing to import %s: the policy requires bundles signed by a key in your keyring and it is %s", srcPath, v)
+	}
+	return v, nil
+}
+
+func verifyBundle(plainPath, srcPath string, opts ImportOptions) error {
+	v, err := verify(plainPath, srcPath)
+	if err != nil {
+		return err
+	}
+	if opts.Verified != nil {
+		opts.Verified(v)
+	}
+	return nil
+}
+
+// ImportDatabase replaces the sets of the active database with those of
+// srcPath when overwrite is true (see replaceCommandSets). If overwrite is
+// false and opts.OnConflict == "rename" (default), the function returns an
+// error. If overwrite is false and a per-set policy is provided in opts, the
+// function will merge the command sets from src into the existing DB
+// applying the per-set policy. Either way an encrypted bundle is decrypted
+// and the bundle's signature checked first (see VerifyBundle).
 func ImportDatabase(srcPath string, overwrite bool, opts ImportOptions) error {
 	dst, err := config.DBPath()
 	if err != nil {
 		return err
 	}
+	plain, cleanup, err := OpenBundle(srcPath, opts.Passphrase)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	if err := verifyBundle(plain, srcPath, opts); err != nil {
+		return err
+	}
 	if _, err := os.Stat(dst); err == nil && !overwrite {
 		// If the caller explicitly set a per-set policy, allow merging/importing
 		// into the existing DB; otherwise, reject to avoid accidental overwrite.
@@ -40,28 +133,98 @@ func ImportDatabase(srcPath string, overwrite bool, opts ImportOptions) error {
 			return errors.New("destination database exists; use overwrite=true to replace or specify --on-conflict to merge")
 		}
 		// proceed to per-set import from src into dst
-		return ImportCommandSet(srcPath, opts)
-	}
-	// overwrite path: copy file
-	in, err := os.Open(srcPath)
-	if err != nil {
-		return fmt.Errorf("open source: %w", err)
+		return importCommandSets(plain, srcPath, opts)
 	}
-	defer func() { _ = in.Close() }()
 	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
 		return fmt.Errorf("create dst dir: %w", err)
 	}
-	out, err := os.Create(dst)
+	return replaceCommandSets(plain, srcPath)
+}
+
+// replaceCommandSets deletes every set of the active database and imports
+// the sets of the plain bundle at plainPath, recording srcPath as where
+// they came from. Only what a bundle signature covers is taken over (see
+// signing.Payload); the bundle's schema and its other tables (history,
+// schedules, webhooks, watches, provenance, audit log, ...) never reach
+// the active database, whose own audit log and run history are kept.
+// Imported sets are untrusted until `krnr trust`.
+//
+// The replacement is built in a copy of the active database that is only
+// swapped in once every set was imported, so a failure leaves the active
+// database as it was.
+func replaceCommandSets(plainPath, srcPath string) error {
+	dstPath, err := config.DBPath()
+	if err != nil {
+		return err
+	}
+	tmp := dstPath + ".import"
+	discard := func() {
+		removeSidecars(tmp)
+		_ = os.Remove(tmp)
+	}
+	discard()
+	defer discard()
+	if err := snapshotDB(dstPath, tmp); err != nil {
+		return err
+	}
+	if err := rebuildCommandSets(plainPath, srcPath, tmp); err != nil {
+		return err
+	}
+	// the snapshot checkpointed the WAL; a leftover one must not be replayed
+	// into the new file
+	removeSidecars(dstPath)
+	if err := os.Rename(tmp, dstPath); err != nil {
+		return fmt.Errorf("replace database: %w", err)
+	}
+	return nil
+}
+
+// snapshotDB writes a consistent copy of the database at path to dst.
+func snapshotDB(path, dst string) error {
+	conn, err := sql.Open("sqlite", path)
 	if err != nil {
-		return fmt.Errorf("create dst: %w", err)
+		return fmt.Errorf("open dst: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+	if _, err := conn.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return fmt.Errorf("checkpoint dst: %w", err)
 	}
-	defer func() { _ = out.Close() }()
-	if _, err := io.Copy(out, in); err != nil {
-		return fmt.Errorf("copy db: %w", err)
+	if _, err := conn.Exec("VACUUM INTO ?", dst); err != nil {
+		return fmt.Errorf("copy dst: %w", err)
 	}
 	return nil
 }
 
+// removeSidecars removes the WAL and shared-memory files of the database at
+// path.
+func removeSidecars(path string) {
+	_ = os.Remove(path + "-wal")
+	_ = os.Remove(path + "-shm")
+}
+
+// rebuildCommandSets replaces the sets of the database at dstPath with
+// those of the plain bundle at plainPath.
+func rebuildCommandSets(plainPath, srcPath, dstPath string) error {
+	src, dst, rows, r, err := openImportResources(plainPath, dstPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = src.Close() }()
+	defer func() { _ = dst.Close() }()
+	defer func() { _ = rows.Close() }()
+
+	local, err := r.ListCommandSets()
+	if err != nil {
+		return err
+	}
+	for _, cs := range local {
+		if err := r.DeleteCommandSet(cs.Name); err != nil {
+			return fmt.Errorf("replace %s: %w", cs.Name, err)
+		}
+	}
+	return processImportRows(rows, createImportHandlers(dst, src, r, srcPath, ImportOptions{}), "skip")
+}
+
 func ensureUniqueName(dst *sql.DB, orig string) (string, error) {
 	name := strings.TrimSpace(orig)
 	si := 1
@@ -80,9 +243,28 @@ func ensureUniqueName(dst *sql.DB, orig string) (string, error) {
 }
 
 // ImportCommandSet imports all command sets from srcPath into the active DB.
-// Options control how name conflicts are handled.
+// Options control how name conflicts are handled. An encrypted bundle is
+// decrypted and the bundle's signature checked first (see VerifyBundle).
 func ImportCommandSet(srcPath string, opts ImportOptions) error {
-	src, dst, rows, r, err := openImportResources(srcPath)
+	plain, cleanup, err := OpenBundle(srcPath, opts.Passphrase)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	if err := verifyBundle(plain, srcPath, opts); err != nil {
+		return err
+	}
+	return importCommandSets(plain, srcPath, opts)
+}
+
+// importCommandSets imports the sets of the plain bundle at plainPath,
+// recording srcPath as where they came from.
+func importCommandSets(plainPath, srcPath string, opts ImportOptions) error {
+	dstPath, err := config.DBPath()
+	if err != nil {
+		return err
+	}
+	src, dst, rows, r, err := openImportResources(plainPath, dstPath)
 	if err != nil {
 		return err
 	}
@@ -90,7 +272,7 @@ func ImportCommandSet(srcPath string, opts ImportOptions) error {
 	defer func() { _ = dst.Close() }()
 	defer func() { _ = rows.Close() }()
 
-	handlers := createImportHandlers(dst, src, r, opts)
+	handlers := createImportHandlers(dst, src, r, srcPath, opts)
 
 	policy := opts.OnConflict
 	if policy == "" {
@@ -100,17 +282,12 @@ func ImportCommandSet(srcPath string, opts ImportOptions) error {
 	return processImportRows(rows, handlers, policy)
 }
 
-func openImportResources(srcPath string) (*sql.DB, *sql.DB, *sql.Rows, *registry.Repository, error) {
+func openImportResources(srcPath, dstPath string) (*sql.DB, *sql.DB, *sql.Rows, *registry.Repository, error) {
 	src, err := sql.Open("sqlite", srcPath)
 	if err != nil {
 		return nil, nil, nil, nil, fmt.Errorf("open src: %w", err)
 	}
 
-	dstPath, err := config.DBPath()
-	if err != nil {
-		_ = src.Close()
-		return nil, nil, nil, nil, err
-	}
 	dst, err := sql.Open("sqlite", dstPath)
 	if err != nil {
 		_ = src.Close()
@@ -156,23 +333,39 @@ func processImportRows(rows *sql.Rows, handlers map[string]func(int64, string, s
 	return nil
 }
 
-func createImportHandlers(dst *sql.DB, src *sql.DB, r *registry.Repository, opts ImportOptions) map[string]func(int64, string, sql.NullString, string, sql.NullString) error {
+func createImportHandlers(dst *sql.DB, src *sql.DB, r *registry.Repository, srcPath string, opts ImportOptions) map[string]func(int64, string, sql.NullString, string, sql.NullString) error {
+	imp := func(id int64) registry.Import {
+		return registry.Import{Source: srcPath, OriginalAuthor: originalAuthor(src, id)}
+	}
 	return map[string]func(int64, string, sql.NullString, string, sql.NullString) error{
 		"rename": func(id int64, name string, desc sql.NullString, created string, lastRun sql.NullString) error {
-			return importWithRename(dst, src, id, name, desc, created, lastRun)
+			return importWithRename(r, dst, src, id, name, desc, created, lastRun, imp(id))
 		},
 		"skip": func(id int64, name string, desc sql.NullString, created string, lastRun sql.NullString) error {
-			return importWithSkip(dst, src, id, name, desc, created, lastRun)
+			return importWithSkip(r, dst, src, id, name, desc, created, lastRun, imp(id))
 		},
 		"overwrite": func(id int64, name string, desc sql.NullString, created string, lastRun sql.NullString) error {
-			return importWithOverwrite(dst, src, id, name, desc, created, lastRun)
+			return importWithOverwrite(r, dst, src, id, name, desc, created, lastRun, imp(id))
 		},
 		"merge": func(id int64, name string, desc sql.NullString, created string, lastRun sql.NullString) error {
-			return importWithMerge(r, dst, src, id, name, desc, created, lastRun, opts)
+			return importWithMerge(r, dst, src, id, name, desc, created, lastRun, imp(id), opts)
 		},
 	}
 }
 
+// originalAuthor returns the author of a source set as "name <email>", or
+// "" when it has none or the source predates author columns.
+func originalAuthor(src *sql.DB, srcID int64) string {
+	var name, email sql.NullString
+	if err := src.QueryRow("SELECT author_name, author_email FROM command_sets WHERE id = ?", srcID).Scan(&name, &email); err != nil {
+		return ""
+	}
+	if email.String != "" {
+		return fmt.Sprintf("%s <%s>", name.String, email.String)
+	}
+	return name.String
+}
+
 func insertCommandSet(dst *sql.DB, name string, desc sql.NullString, created string, lastRun sql.NullString) (int64, error) {
 	// Defensive: trim and validate name
 	name = strings.TrimSpace(name)
@@ -234,6 +427,9 @@ func deleteCommandSetByName(dst *sql.DB, name string) error {
 	if _, err := trx.Exec("DELETE FROM commands WHERE command_set_id = ?", id); err != nil {
 		return err
 	}
+	if _, err := trx.Exec("DELETE FROM provenance WHERE command_set_id = ?", id); err != nil {
+		return err
+	}
 	if _, err := trx.Exec("DELETE FROM command_sets WHERE id = ?", id); err != nil {
 		return err
 	}
@@ -258,19 +454,23 @@ func getCommands(src *sql.DB, srcID int64) ([]string, error) {
 }
 
 // helper imports
-func importWithRename(dst *sql.DB, src *sql.DB, srcID int64, name string, desc sql.NullString, created string, lastRun sql.NullString) error {
+func importWithRename(r *registry.Repository, dst *sql.DB, src *sql.DB, srcID int64, name string, desc sql.NullString, created string, lastRun sql.NullString, imp registry.Import) error {
 	uName, err := ensureUniqueName(dst, name)
 	if err != nil {
 		return err
 	}
+	cmds, err := scannedCommands(r, src, srcID, uName)
+	if err != nil {
+		return err
+	}
 	newID, err := insertCommandSet(dst, uName, desc, created, lastRun)
 	if err != nil {
 		return err
 	}
-	return copyCommands(src, srcID, dst, newID)
+	return insertCommands(r, dst, newID, uName, cmds, imp)
 }
 
-func importWithSkip(dst *sql.DB, src *sql.DB, srcID int64, name string, desc sql.NullString, created string, lastRun sql.NullString) error {
+func importWithSkip(r *registry.Repository, dst *sql.DB, src *sql.DB, srcID int64, name string, desc sql.NullString, created string, lastRun sql.NullString, imp registry.Import) error {
 	exists, err := commandSetExists(dst, name)
 	if err != nil {
 		return err
@@ -278,14 +478,25 @@ func importWithSkip(dst *sql.DB, src *sql.DB, srcID int64, name string, desc sql
 	if exists {
 		return nil
 	}
+	cmds, err := scannedCommands(r, src, srcID, name)
+	if err != nil {
+		return err
+	}
 	newID, err := insertCommandSet(dst, name, desc, created, lastRun)
 	if err != nil {
 		return err
 	}
-	return copyCommands(src, srcID, dst, newID)
+	return insertCommands(r, dst, newID, name, cmds, imp)
 }
 
-func importWithOverwrite(dst *sql.DB, src *sql.DB, srcID int64, name string, desc sql.NullString, created string, lastRun sql.NullString) error {
+func importWithOverwrite(r *registry.Repository, dst *sql.DB, src *sql.DB, srcID int64, name string, desc sql.NullString, created string, lastRun sql.NullString, imp registry.Import) error {
+	cmds, err := scannedCommands(r, src, srcID, name)
+	if err != nil {
+		return err
+	}
+	if imp.Baseline, err = r.TrustBaseline(name); err != nil {
+		return err
+	}
 	if err := deleteCommandSetByName(dst, name); err != nil {
 		return err
 	}
@@ -293,29 +504,39 @@ func importWithOverwrite(dst *sql.DB, src *sql.DB, srcID int64, name string, des
 	if err != nil {
 		return err
 	}
-	return copyCommands(src, srcID, dst, newID)
+	return insertCommands(r, dst, newID, name, cmds, imp)
 }
 
-func importWithMerge(r *registry.Repository, dst *sql.DB, src *sql.DB, srcID int64, name string, desc sql.NullString, created string, lastRun sql.NullString, opts ImportOptions) error {
+func importWithMerge(r *registry.Repository, dst *sql.DB, src *sql.DB, srcID int64, name string, desc sql.NullString, created string, lastRun sql.NullString, imp registry.Import, opts ImportOptions) error {
 	// if set doesn't exist: insert directly
 	existing, err := r.GetCommandSetByName(name)
 	if err != nil {
 		return err
 	}
 	if existing == nil {
+		cmds, err := scannedCommands(r, src, srcID, name)
+		if err != nil {
+			return err
+		}
 		newID, err := insertCommandSet(dst, name, desc, created, lastRun)
 		if err != nil {
 			return err
 		}
-		return copyCommands(src, srcID, dst, newID)
+		return insertCommands(r, dst, newID, name, cmds, imp)
 	}
 
 	inc, err := getCommands(src, srcID)
 	if err != nil {
 		return err
 	}
+	if imp.Baseline, err = r.TrustBaseline(name); err != nil {
+		return err
+	}
 	merged := mergeCommands(existing.Commands, inc, opts.Dedupe)
-	return r.ReplaceCommands(existing.ID, merged)
+	if err := r.ReplaceCommands(existing.ID, merged); err != nil {
+		return err
+	}
+	return r.RecordImport(name, imp)
 }
 
 func mergeCommands(existing []registry.Command, incoming []string, dedupe bool) []string {
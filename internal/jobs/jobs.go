@@ -0,0 +1,146 @@
+// Package jobs runs command sets as supervised background processes
+// (`krnr run --detach`). Each job has a directory under KRNR_HOME/jobs/<id>
+// holding the combined console output and an append-only stdin file that
+// `krnr attach` writes to; the job's state is tracked in the registry.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/VoxDroid/krnr/internal/config"
+)
+
+// Paths locates the files of a job.
+type Paths struct {
+	Dir    string
+	Output string
+	Input  string
+}
+
+// NewID returns a short random job identifier.
+func NewID() string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// PathsFor returns the file locations for job id.
+func PathsFor(id string) (Paths, error) {
+	d, err := config.DataDir()
+	if err != nil {
+		return Paths{}, err
+	}
+	dir := filepath.Join(d, "jobs", id)
+	return Paths{Dir: dir, Output: filepath.Join(dir, "output.log"), Input: filepath.Join(dir, "stdin")}, nil
+}
+
+// Prepare creates the job directory and empty output and input files.
+func Prepare(id string) (Paths, error) {
+	p, err := PathsFor(id)
+	if err != nil {
+		return Paths{}, err
+	}
+	if err := os.MkdirAll(p.Dir, 0o700); err != nil {
+		return Paths{}, fmt.Errorf("create job dir: %w", err)
+	}
+	for _, f := range []string{p.Output, p.Input} {
+		fh, err := os.OpenFile(f, os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return Paths{}, err
+		}
+		_ = fh.Close()
+	}
+	return p, nil
+}
+
+// Spawn starts the krnr executable with args as a detached process whose
+// stdout and stderr go to the job's output file. The process is placed in
+// its own session (process group on Windows) so it outlives the launching
+// shell. It returns the PID.
+func Spawn(p Paths, args []string) (int, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, fmt.Errorf("locate krnr executable: %w", err)
+	}
+	out, err := os.OpenFile(p.Output, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = out.Close() }()
+	cmd := exec.Command(exe, args...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	cmd.SysProcAttr = detachAttr()
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("start background job: %w", err)
+	}
+	pid := cmd.Process.Pid
+	_ = cmd.Process.Release()
+	return pid, nil
+}
+
+// pollInterval controls how often Follow and FeedInput check for new data.
+var pollInterval = 200 * time.Millisecond
+
+// Follow copies the file at path to w and keeps copying appended data until
+// ctx is cancelled or running reports false (after a final drain).
+func Follow(ctx context.Context, path string, w io.Writer, running func() bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	for {
+		if _, err := io.Copy(w, f); err != nil {
+			return err
+		}
+		if !running() {
+			_, err := io.Copy(w, f)
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// FeedInput copies data appended to the job's input file into w until ctx is
+// cancelled. The job process uses it as its stdin.
+func FeedInput(ctx context.Context, path string, w io.Writer) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	for {
+		if _, err := io.Copy(w, f); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// WriteInput appends p to the job's input file, like RunHandle.WriteInput
+// does for TUI runs.
+func WriteInput(p Paths, data []byte) (int, error) {
+	f, err := os.OpenFile(p.Input, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = f.Close() }()
+	return f.Write(data)
+}
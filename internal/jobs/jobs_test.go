@@ -0,0 +1,61 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFollowAndInput(t *testing.T) {
+	t.Setenv("KRNR_HOME", t.TempDir())
+	pollInterval = 10 * time.Millisecond
+	p, err := Prepare("abc123")
+	if err != nil {
+		t.Fatalf("Prepare: %v", err)
+	}
+
+	// Follow picks up output appended while the job runs, then stops.
+	var running atomic.Bool
+	running.Store(true)
+	go func() {
+		f, _ := os.OpenFile(p.Output, os.O_WRONLY|os.O_APPEND, 0o600)
+		_, _ = f.WriteString("one\n")
+		time.Sleep(30 * time.Millisecond)
+		_, _ = f.WriteString("two\n")
+		_ = f.Close()
+		running.Store(false)
+	}()
+	var out bytes.Buffer
+	if err := Follow(context.Background(), p.Output, &out, running.Load); err != nil {
+		t.Fatalf("Follow: %v", err)
+	}
+	if out.String() != "one\ntwo\n" {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+
+	// Input written by attach is fed to the job's stdin.
+	ctx, cancel := context.WithCancel(context.Background())
+	pr, pw, _ := os.Pipe()
+	go func() { _ = FeedInput(ctx, p.Input, pw); _ = pw.Close() }()
+	if _, err := WriteInput(p, []byte("yes\n")); err != nil {
+		t.Fatalf("WriteInput: %v", err)
+	}
+	buf := make([]byte, 16)
+	n, err := pr.Read(buf)
+	cancel()
+	if err != nil || string(buf[:n]) != "yes\n" {
+		t.Fatalf("unexpected stdin %q (%v)", buf[:n], err)
+	}
+}
+
+func TestAliveOwnProcess(t *testing.T) {
+	if !Alive(os.Getpid()) {
+		t.Fatalf("expected own process to be alive")
+	}
+	if Alive(0) {
+		t.Fatalf("pid 0 must not be reported alive")
+	}
+}
@@ -0,0 +1,33 @@
+//go:build !windows
+
+package jobs
+
+import (
+	"errors"
+	"syscall"
+)
+
+func detachAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
+
+// Alive reports whether a process with the given PID exists.
+func Alive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// Terminate asks the job's process group to exit (SIGTERM), which also stops
+// the command it is currently executing.
+func Terminate(pid int) error {
+	if pid <= 0 {
+		return errors.New("job has no process")
+	}
+	if err := syscall.Kill(-pid, syscall.SIGTERM); err == nil {
+		return nil
+	}
+	return syscall.Kill(pid, syscall.SIGTERM)
+}
@@ -0,0 +1,346 @@
+// Package lifecycle runs the user-defined hooks configured in
+// KRNR_HOME/hooks.toml: shell commands or other krnr sets that fire around
+// runs and when sets are saved, deleted or imported. Each hook receives a
+// JSON Context on stdin; blocking hooks can refuse what is about to happen.
+package lifecycle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+
+	"github.com/VoxDroid/krnr/internal/config"
+	"github.com/VoxDroid/krnr/internal/executor"
+	"github.com/VoxDroid/krnr/internal/registry"
+)
+
+// Event names when a hook fires.
+type Event string
+
+// Hook events.
+const (
+	PreRun    Event = "pre-run"
+	PostRun   Event = "post-run"
+	OnFailure Event = "on-failure"
+	OnSave    Event = "on-save"
+	OnDelete  Event = "on-delete"
+	OnImport  Event = "on-import"
+)
+
+// Events lists every hook event.
+var Events = []Event{PreRun, PostRun, OnFailure, OnSave, OnDelete, OnImport}
+
+// FileName is the hooks file in KRNR_HOME.
+const FileName = "hooks.toml"
+
+// EnvInHook is set to the event for the processes a hook starts. Load
+// returns no hooks while it is set, so a hook that calls krnr does not fire
+// hooks in turn.
+const EnvInHook = "KRNR_HOOK"
+
+// DefaultTimeout bounds a hook without a timeout of its own.
+const DefaultTimeout = 30 * time.Second
+
+// ErrHookFailed wraps the failure of a blocking hook.
+var ErrHookFailed = errors.New("hook failed")
+
+// Hook is one [[hook]] entry of the hooks file.
+type Hook struct {
+	Event Event `toml:"event"`
+	// Name labels the hook in messages; it defaults to Run or Set.
+	Name string `toml:"name"`
+	// Run is a shell command; Set names a krnr set. Exactly one is given.
+	Run string `toml:"run"`
+	Set string `toml:"set"`
+	// Sets limits the hook to sets whose names match one of these globs.
+	Sets []string `toml:"sets"`
+	// Blocking hooks run before a save, delete, import or run and refuse
+	// it by failing; the failure of a blocking post-run or on-failure hook
+	// fails the run. Other hooks run afterwards and only warn.
+	Blocking bool `toml:"blocking"`
+	// Timeout is a duration such as "10s"; empty means DefaultTimeout.
+	Timeout string `toml:"timeout"`
+
+	timeout time.Duration
+}
+
+// Label names h in messages.
+func (h Hook) Label() string {
+	switch {
+	case h.Name != "":
+		return h.Name
+	case h.Set != "":
+		return "set " + h.Set
+	default:
+		return h.Run
+	}
+}
+
+func (h Hook) matches(ev Event, set string) bool {
+	if h.Event != ev {
+		return false
+	}
+	if len(h.Sets) == 0 {
+		return true
+	}
+	for _, g := range h.Sets {
+		if ok, _ := path.Match(g, set); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Context is the JSON document a hook reads on stdin.
+type Context struct {
+	Event Event  `json:"event"`
+	Set   string `json:"set,omitempty"`
+	// OldName is the previous name of a renamed set.
+	OldName string `json:"old_name,omitempty"`
+	// Op is the registry operation of on-save and on-delete: create,
+	// update, rollback, tag or delete.
+	Op       string `json:"op,omitempty"`
+	RunID    string `json:"run_id,omitempty"`
+	ExitCode *int   `json:"exit_code,omitempty"`
+	// Changed names the fields a save changes.
+	Changed     []string `json:"changed,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Commands    []string `json:"commands,omitempty"`
+	// Source is the file an import reads.
+	Source string    `json:"source,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// SetRunner runs the named set for a set hook with the given parameters,
+// extra environment and stdin, writing its output to out.
+type SetRunner func(ctx context.Context, name string, params map[string]string, env []string, stdin io.Reader, out io.Writer) error
+
+// Hooks are the configured hooks. The zero value and nil have none.
+type Hooks struct {
+	hooks []Hook
+	// RunSet runs set hooks; without it they fail.
+	RunSet SetRunner
+	// Out receives hook output and warnings; nil means os.Stderr.
+	Out io.Writer
+	// Shell runs script hooks; empty means the platform default.
+	Shell string
+}
+
+// Path returns KRNR_HOME/hooks.toml.
+func Path() (string, error) {
+	d, err := config.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(d, FileName), nil
+}
+
+// Load reads the hooks file. A missing file means no hooks, and so does
+// running inside a hook (see EnvInHook).
+func Load() (*Hooks, error) {
+	if os.Getenv(EnvInHook) != "" {
+		return &Hooks{}, nil
+	}
+	p, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Hooks{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	h, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p, err)
+	}
+	return h, nil
+}
+
+// Parse reads hooks in the hooks file format:
+//
+//	[[hook]]
+//	event = "on-save"
+//	run = "./check-conventions"
+//	sets = ["deploy-*"]
+//	blocking = true
+func Parse(data []byte) (*Hooks, error) {
+	var f struct {
+		Hooks []Hook `toml:"hook"`
+	}
+	dec := toml.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&f); err != nil {
+		var strict *toml.StrictMissingError
+		if errors.As(err, &strict) && len(strict.Errors) > 0 {
+			e := strict.Errors[0]
+			row, _ := e.Position()
+			return nil, fmt.Errorf("line %d: unknown field %q", row, strings.Join(e.Key(), "."))
+		}
+		return nil, err
+	}
+	for i := range f.Hooks {
+		if err := validate(&f.Hooks[i]); err != nil {
+			return nil, fmt.Errorf("hook %d: %w", i+1, err)
+		}
+	}
+	return &Hooks{hooks: f.Hooks}, nil
+}
+
+func validate(h *Hook) error {
+	known := false
+	for _, e := range Events {
+		known = known || h.Event == e
+	}
+	if !known {
+		names := make([]string, len(Events))
+		for i, e := range Events {
+			names[i] = string(e)
+		}
+		return fmt.Errorf("unknown event %q (want one of %s)", h.Event, strings.Join(names, ", "))
+	}
+	if (h.Run == "") == (h.Set == "") {
+		return errors.New("exactly one of run and set is required")
+	}
+	if h.Run != "" {
+		if err := executor.ValidateCommand(h.Run); err != nil {
+			return fmt.Errorf("run: %w", err)
+		}
+	}
+	for _, g := range h.Sets {
+		if _, err := path.Match(g, ""); err != nil {
+			return fmt.Errorf("sets: bad pattern %q", g)
+		}
+	}
+	h.timeout = DefaultTimeout
+	if h.Timeout != "" {
+		d, err := time.ParseDuration(h.Timeout)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("timeout: invalid duration %q", h.Timeout)
+		}
+		h.timeout = d
+	}
+	return nil
+}
+
+// List returns the hooks in file order.
+func (h *Hooks) List() []Hook {
+	if h == nil {
+		return nil
+	}
+	return append([]Hook(nil), h.hooks...)
+}
+
+// Fire runs every hook for c. The first failing blocking hook stops the
+// rest and its error is returned; other failures are reported to Out.
+func (h *Hooks) Fire(ctx context.Context, c Context) error {
+	return h.fire(ctx, c, func(Hook) bool { return true })
+}
+
+// FireBefore runs the blocking hooks for c, before it happens.
+func (h *Hooks) FireBefore(ctx context.Context, c Context) error {
+	return h.fire(ctx, c, func(k Hook) bool { return k.Blocking })
+}
+
+// FireAfter runs the non-blocking hooks for c, once it has happened.
+func (h *Hooks) FireAfter(ctx context.Context, c Context) {
+	_ = h.fire(ctx, c, func(k Hook) bool { return !k.Blocking })
+}
+
+// Before runs the blocking on-save or on-delete hooks for a registry
+// change, implementing registry.ChangeHook.
+func (h *Hooks) Before(ch registry.Change) error {
+	return h.FireBefore(context.Background(), changeContext(ch))
+}
+
+// After runs the non-blocking on-save or on-delete hooks for a committed
+// registry change.
+func (h *Hooks) After(ch registry.Change) {
+	h.FireAfter(context.Background(), changeContext(ch))
+}
+
+func changeContext(ch registry.Change) Context {
+	ev := OnSave
+	if ch.Event == registry.ChangeDelete {
+		ev = OnDelete
+	}
+	return Context{
+		Event: ev, Set: ch.Set, OldName: ch.OldName, Op: ch.Op, Changed: ch.Changed,
+		Description: ch.Description, Commands: ch.Commands,
+	}
+}
+
+func (h *Hooks) out() io.Writer {
+	if h.Out == nil {
+		return os.Stderr
+	}
+	return h.Out
+}
+
+func (h *Hooks) fire(ctx context.Context, c Context, want func(Hook) bool) error {
+	if h == nil {
+		return nil
+	}
+	if c.Time.IsZero() {
+		c.Time = time.Now().UTC()
+	}
+	for _, k := range h.hooks {
+		if !k.matches(c.Event, c.Set) || !want(k) {
+			continue
+		}
+		err := h.run(ctx, k, c)
+		if err == nil {
+			continue
+		}
+		if k.Blocking {
+			return fmt.Errorf("%w: %s hook %s: %v", ErrHookFailed, c.Event, k.Label(), err)
+		}
+		_, _ = fmt.Fprintf(h.out(), "warning: %s hook %s failed: %v\n", c.Event, k.Label(), err)
+	}
+	return nil
+}
+
+// run runs one hook with c as JSON on stdin. Set hooks also get the main
+// fields as parameters: hook_event, hook_set, hook_run_id, hook_exit_code.
+func (h *Hooks) run(ctx context.Context, k Hook, c Context) error {
+	body, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, k.timeout)
+	defer cancel()
+	env := []string{EnvInHook + "=" + string(c.Event)}
+	if k.Set != "" {
+		if h.RunSet == nil {
+			return errors.New("set hooks are not available here")
+		}
+		code := ""
+		if c.ExitCode != nil {
+			code = strconv.Itoa(*c.ExitCode)
+		}
+		params := map[string]string{
+			"hook_event": string(c.Event), "hook_set": c.Set, "hook_run_id": c.RunID, "hook_exit_code": code,
+		}
+		err = h.RunSet(ctx, k.Set, params, env, bytes.NewReader(body), h.out())
+	} else {
+		ex := &executor.Executor{Env: env, Shell: h.Shell}
+		err = ex.Execute(ctx, k.Run, "", bytes.NewReader(body), h.out(), h.out())
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s", k.timeout)
+	}
+	return err
+}
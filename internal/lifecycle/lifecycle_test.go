@@ -0,0 +1,224 @@
+package lifecycle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/VoxDroid/krnr/internal/config"
+	"github.com/VoxDroid/krnr/internal/registry"
+)
+
+func TestParseValidates(t *testing.T) {
+	cases := map[string]string{
+		`[[hook]]
+event = "before-run"
+run = "true"`: "unknown event",
+		`[[hook]]
+event = "pre-run"`: "exactly one of run and set",
+		`[[hook]]
+event = "pre-run"
+run = "true"
+set = "x"`: "exactly one of run and set",
+		`[[hook]]
+event = "pre-run"
+run = "true"
+timeout = "soon"`: "timeout",
+		`[[hook]]
+event = "pre-run"
+run = "true"
+sets = ["["]`: "bad pattern",
+		`[[hook]]
+event = "pre-run"
+run = "true"
+block = true`: "block",
+	}
+	for src, want := range cases {
+		if _, err := Parse([]byte(src)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse(%q): expected error containing %q, got %v", src, want, err)
+		}
+	}
+
+	h, err := Parse([]byte(`
+[[hook]]
+event = "on-save"
+run = "true"
+sets = ["deploy-*"]
+blocking = true
+timeout = "5s"
+
+[[hook]]
+event = "post-run"
+set = "notify"
+`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	hooks := h.List()
+	if len(hooks) != 2 || hooks[0].timeout.String() != "5s" || hooks[1].timeout != DefaultTimeout || hooks[1].Label() != "set notify" {
+		t.Fatalf("unexpected hooks %+v", hooks)
+	}
+	if !hooks[0].matches(OnSave, "deploy-web") || hooks[0].matches(OnSave, "build") || hooks[0].matches(OnDelete, "deploy-web") {
+		t.Fatalf("sets globs not applied")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv(config.EnvKRNRHome, home)
+	h, err := Load()
+	if err != nil || len(h.List()) != 0 {
+		t.Fatalf("missing file must mean no hooks, got %+v %v", h, err)
+	}
+	if err := os.WriteFile(filepath.Join(home, FileName), []byte("[[hook]]\nevent = \"on-delete\"\nrun = \"true\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if h, err = Load(); err != nil || len(h.List()) != 1 {
+		t.Fatalf("expected one hook, got %+v %v", h, err)
+	}
+	t.Setenv(EnvInHook, "on-save")
+	if h, err = Load(); err != nil || len(h.List()) != 0 {
+		t.Fatalf("hooks must not load inside a hook, got %+v %v", h, err)
+	}
+}
+
+func TestFireBlockingSemantics(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses POSIX shell commands")
+	}
+	ctxFile := filepath.Join(t.TempDir(), "ctx.json")
+	h, err := Parse([]byte(`
+[[hook]]
+event = "on-failure"
+run = "cat > ` + ctxFile + ` && echo \"$KRNR_HOOK\""
+
+[[hook]]
+event = "on-failure"
+name = "noisy"
+run = "exit 3"
+
+[[hook]]
+event = "on-failure"
+name = "gate"
+run = "exit 4"
+blocking = true
+
+[[hook]]
+event = "on-failure"
+name = "never"
+run = "echo unreachable"
+blocking = true
+`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	var out bytes.Buffer
+	h.Out = &out
+	code := 2
+	c := Context{Event: OnFailure, Set: "deploy", RunID: "r1", ExitCode: &code}
+
+	err = h.Fire(context.Background(), c)
+	if !errors.Is(err, ErrHookFailed) || !strings.Contains(err.Error(), "gate") {
+		t.Fatalf("expected the blocking hook's failure, got %v", err)
+	}
+	if !strings.Contains(out.String(), "on-failure\n") || !strings.Contains(out.String(), "warning: on-failure hook noisy failed") {
+		t.Fatalf("unexpected hook output %q", out.String())
+	}
+	if strings.Contains(out.String(), "unreachable") {
+		t.Fatalf("hooks after a failed blocking hook must not run")
+	}
+	data, err := os.ReadFile(ctxFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("context is not JSON: %v (%s)", err, data)
+	}
+	if got["event"] != "on-failure" || got["set"] != "deploy" || got["run_id"] != "r1" || got["exit_code"] != float64(2) || got["time"] == nil {
+		t.Fatalf("unexpected context %v", got)
+	}
+
+	// FireAfter runs only the non-blocking hooks and never fails
+	out.Reset()
+	h.FireAfter(context.Background(), c)
+	if !strings.Contains(out.String(), "noisy") || strings.Contains(out.String(), "unreachable") {
+		t.Fatalf("unexpected FireAfter output %q", out.String())
+	}
+}
+
+func TestSetHooksAndChanges(t *testing.T) {
+	h, err := Parse([]byte(`
+[[hook]]
+event = "on-save"
+set = "check"
+blocking = true
+
+[[hook]]
+event = "on-delete"
+set = "audit"
+`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if err := h.Before(registry.Change{Event: registry.ChangeSave, Set: "x"}); err == nil {
+		t.Fatalf("set hooks without a runner must fail")
+	}
+
+	type call struct {
+		name   string
+		params map[string]string
+		ctx    Context
+	}
+	var calls []call
+	h.RunSet = func(_ context.Context, name string, params map[string]string, env []string, stdin io.Reader, _ io.Writer) error {
+		var c Context
+		if err := json.NewDecoder(stdin).Decode(&c); err != nil {
+			return err
+		}
+		if len(env) != 1 || env[0] != EnvInHook+"="+string(c.Event) {
+			t.Errorf("unexpected env %v", env)
+		}
+		calls = append(calls, call{name, params, c})
+		if c.Set == "bad name" {
+			return errors.New("convention violated")
+		}
+		return nil
+	}
+
+	err = h.Before(registry.Change{Event: registry.ChangeSave, Op: "update", Set: "bad name", OldName: "old", Changed: []string{"name"}})
+	if !errors.Is(err, ErrHookFailed) || !strings.Contains(err.Error(), "convention violated") {
+		t.Fatalf("expected the save to be refused, got %v", err)
+	}
+	h.After(registry.Change{Event: registry.ChangeSave, Set: "good"})
+	h.After(registry.Change{Event: registry.ChangeDelete, Op: "delete", Set: "gone"})
+
+	if len(calls) != 2 {
+		t.Fatalf("expected blocking on-save then non-blocking on-delete, got %+v", calls)
+	}
+	if calls[0].name != "check" || calls[0].params["hook_event"] != "on-save" || calls[0].params["hook_set"] != "bad name" ||
+		calls[0].ctx.OldName != "old" || calls[0].ctx.Op != "update" || len(calls[0].ctx.Changed) != 1 {
+		t.Fatalf("unexpected on-save call %+v", calls[0])
+	}
+	if calls[1].name != "audit" || calls[1].ctx.Event != OnDelete || calls[1].params["hook_exit_code"] != "" {
+		t.Fatalf("unexpected on-delete call %+v", calls[1])
+	}
+}
+
+func TestNilHooks(t *testing.T) {
+	var h *Hooks
+	if err := h.Fire(context.Background(), Context{Event: PreRun}); err != nil {
+		t.Fatalf("nil hooks must do nothing, got %v", err)
+	}
+	h.FireAfter(context.Background(), Context{Event: PostRun})
+	if h.List() != nil {
+		t.Fatalf("nil hooks have no list")
+	}
+}
@@ -0,0 +1,372 @@
+// Package lint checks command sets for problems that only show up when they
+// run: parameters no run supplies, characters the executor rewrites,
+// commands for another platform, steps without effect, paths that do not
+// port to other machines and secrets stored in plain text.
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"mvdan.cc/sh/v3/syntax"
+
+	"github.com/VoxDroid/krnr/internal/executor"
+	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/security"
+)
+
+// Severity is how serious a finding is.
+type Severity string
+
+const (
+	// SeverityError marks a set that will fail or leak when it runs.
+	SeverityError Severity = "error"
+	// SeverityWarning marks a set that probably does not do what it says.
+	SeverityWarning Severity = "warning"
+)
+
+// Rule is a check lint performs.
+type Rule struct {
+	ID          string   `json:"id"`
+	Severity    Severity `json:"severity"`
+	Description string   `json:"description"`
+}
+
+// Rules lists every check.
+var Rules = []Rule{
+	{"param.unsupplied", SeverityError, "a schedule does not supply a parameter the set uses, so its runs fail"},
+	{"param.unused", SeverityWarning, "a schedule supplies a parameter the set does not use"},
+	{"param.malformed", SeverityError, "text that looks like a {{parameter}} but is not one runs literally"},
+	{"param.template", SeverityWarning, "a Go template such as {{.Name}} is taken for a parameter and asked for"},
+	{"quotes.smart", SeverityWarning, "smart quotes or invisible characters are silently rewritten before the command runs"},
+	{"platform.windows-only", SeverityWarning, "a Windows-only command in a set tagged for Linux or macOS"},
+	{"platform.unix-only", SeverityWarning, "a Unix-only command in a set tagged for Windows"},
+	{"cd.no-effect", SeverityWarning, "a step ends by changing directory, which later steps do not see"},
+	{"path.home", SeverityWarning, "an absolute path into a user's home directory does not port to other users"},
+	{"secret.literal", SeverityError, "a secret is stored in plain text instead of a parameter"},
+}
+
+func severityOf(rule string) Severity {
+	for _, r := range Rules {
+		if r.ID == rule {
+			return r.Severity
+		}
+	}
+	return SeverityWarning
+}
+
+// Set is a command set to check.
+type Set struct {
+	Name     string
+	Tags     []string
+	Commands []string
+	// Supplies are the parameters of the set's unattended runs, which
+	// cannot prompt for the others.
+	Supplies []Supply
+}
+
+// Supply is the parameters an unattended run passes to the set.
+type Supply struct {
+	// Source names the run, e.g. "schedule 3".
+	Source string
+	// Params are name=value pairs as passed to --param.
+	Params []string
+}
+
+// Finding is a problem in a set.
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	// Step is the 1-based step the finding is about; 0 for the whole set.
+	Step    int    `json:"step,omitempty"`
+	Message string `json:"message"`
+	// Command is the step's command and Start and End the byte offsets of
+	// the offending span in it.
+	Command string `json:"command,omitempty"`
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+}
+
+// Highlight returns the step's command with the offending span
+// underlined, indented for printing below the message; "" for findings
+// about the whole set.
+func (f Finding) Highlight() string {
+	return security.Underline(f.Command, f.Start, f.End)
+}
+
+func (f Finding) String() string {
+	if f.Step == 0 {
+		return fmt.Sprintf("%s: %s [%s]", f.Severity, f.Message, f.Rule)
+	}
+	return fmt.Sprintf("step %d: %s: %s [%s]", f.Step, f.Severity, f.Message, f.Rule)
+}
+
+// Check returns the problems in s ordered by step.
+func Check(s Set) []Finding {
+	c := &checker{set: s}
+	c.params()
+	for i, cmd := range s.Commands {
+		c.smartQuotes(i, cmd)
+		c.platform(i, cmd)
+		c.cd(i, cmd)
+		c.homePaths(i, cmd)
+		c.secrets(i, cmd)
+	}
+	sort.SliceStable(c.out, func(i, j int) bool { return c.out[i].Step < c.out[j].Step })
+	return c.out
+}
+
+// Errors counts the findings of error severity.
+func Errors(findings []Finding) int {
+	n := 0
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			n++
+		}
+	}
+	return n
+}
+
+type checker struct {
+	set Set
+	out []Finding
+}
+
+func (c *checker) add(rule string, step int, start, end int, format string, args ...any) {
+	f := Finding{Rule: rule, Severity: severityOf(rule), Step: step + 1, Message: fmt.Sprintf(format, args...)}
+	if step < 0 {
+		f.Step = 0
+	} else {
+		f.Command, f.Start, f.End = c.set.Commands[step], start, end
+	}
+	c.out = append(c.out, f)
+}
+
+func (c *checker) params() {
+	// firstUse is the step and span where each parameter first appears.
+	firstUse := map[string][3]int{}
+	var used []string
+	for i, cmd := range c.set.Commands {
+		starts := map[int]bool{}
+		for _, m := range registry.FindParamIndex(cmd) {
+			starts[m[0]] = true
+			name := cmd[m[2]:m[3]]
+			if _, ok := firstUse[name]; !ok {
+				firstUse[name] = [3]int{i, m[0], m[1]}
+				used = append(used, name)
+			}
+			if strings.HasPrefix(name, ".") {
+				c.add("param.template", i, m[0], m[1], "%s is a krnr parameter, so runs ask for a value of %q", cmd[m[0]:m[1]], name)
+			}
+		}
+		for off := 0; ; {
+			j := strings.Index(cmd[off:], "{{")
+			if j < 0 {
+				break
+			}
+			start := off + j
+			off = start + 2
+			if starts[start] {
+				continue
+			}
+			end := len(cmd)
+			if k := strings.Index(cmd[start:], "}"); k >= 0 {
+				end = start + k + 1
+				if strings.HasPrefix(cmd[end:], "}") {
+					end++
+				}
+			}
+			c.add("param.malformed", i, start, end, "%s is not a parameter and runs literally (parameter names are letters, digits, '_', '.' and '-' inside {{ }})", cmd[start:end])
+			off = end
+		}
+	}
+	for _, sup := range c.set.Supplies {
+		given := map[string]bool{}
+		for _, p := range sup.Params {
+			name, _, _ := strings.Cut(p, "=")
+			given[strings.TrimSpace(name)] = true
+			if _, ok := firstUse[strings.TrimSpace(name)]; !ok {
+				c.add("param.unused", -1, 0, 0, "%s supplies parameter %q, which no step uses", sup.Source, name)
+			}
+		}
+		for _, name := range used {
+			if !given[name] {
+				u := firstUse[name]
+				c.add("param.unsupplied", u[0], u[1], u[2], "%s does not supply parameter %q, so its runs fail (add --param %s=...)", sup.Source, name, name)
+			}
+		}
+	}
+}
+
+// smartQuotes reports the characters executor.Sanitize rewrites.
+func (c *checker) smartQuotes(i int, cmd string) {
+	first, n := -1, 0
+	for off, r := range cmd {
+		if executor.Sanitize(string(r)) != string(r) {
+			if first < 0 {
+				first = off
+			}
+			n++
+		}
+	}
+	if n == 0 {
+		return
+	}
+	r, size := utf8.DecodeRuneInString(cmd[first:])
+	what := "smart quotes or invisible characters"
+	if n == 1 {
+		what = "a smart quote or invisible character"
+	}
+	c.add("quotes.smart", i, first, first+size, "contains %s (first %U) that run as plain ASCII or not at all; retype them", what, r)
+}
+
+var (
+	unixTags    = map[string]bool{"linux": true, "unix": true, "posix": true, "macos": true, "mac": true, "osx": true, "darwin": true}
+	windowsTags = map[string]bool{"windows": true, "win": true, "win32": true, "win64": true}
+
+	windowsOnly = map[string]bool{
+		"cls": true, "findstr": true, "ipconfig": true, "systeminfo": true, "tasklist": true, "taskkill": true,
+		"robocopy": true, "xcopy": true, "netsh": true, "wmic": true, "reg": true, "sc": true, "icacls": true,
+		"powershell": true, "cmd": true, "del": true, "rd": true, "copy": true, "move": true, "ren": true,
+		"choco": true, "winget": true, "schtasks": true, "msiexec": true, "setx": true,
+	}
+	unixOnly = map[string]bool{
+		"grep": true, "sed": true, "awk": true, "chmod": true, "chown": true, "sudo": true, "apt": true,
+		"apt-get": true, "yum": true, "dnf": true, "brew": true, "systemctl": true, "ln": true, "uname": true,
+		"xargs": true, "export": true, "source": true, "crontab": true, "killall": true,
+	}
+	// cmdletRe matches PowerShell cmdlets such as Get-ChildItem.
+	cmdletRe     = regexp.MustCompile(`^(?:Get|Set|New|Remove|Select|Where|ForEach|Invoke|Start|Stop|Write|Out|Test|Copy|Move|Rename)-[A-Z][A-Za-z]+$`)
+	windowsExeRe = regexp.MustCompile(`(?i)\.(?:exe|bat|cmd|ps1)$`)
+)
+
+// platform reports commands for a platform the set's tags exclude.
+func (c *checker) platform(i int, cmd string) {
+	var unix, windows bool
+	for _, t := range c.set.Tags {
+		unix = unix || unixTags[strings.ToLower(t)]
+		windows = windows || windowsTags[strings.ToLower(t)]
+	}
+	if unix == windows {
+		return
+	}
+	for _, w := range commandWords(cmd) {
+		name := cmd[w[0]:w[1]]
+		switch {
+		case unix && (windowsOnly[strings.ToLower(name)] || cmdletRe.MatchString(name) || windowsExeRe.MatchString(name)):
+			c.add("platform.windows-only", i, w[0], w[1], "%s is a Windows command but the set is tagged %s", name, strings.Join(c.set.Tags, ", "))
+		case windows && unixOnly[name]:
+			c.add("platform.unix-only", i, w[0], w[1], "%s is a Unix command but the set is tagged %s", name, strings.Join(c.set.Tags, ", "))
+		}
+	}
+}
+
+// commandWords returns the spans of the command names in cmd: the first
+// word of each simple command when it parses as shell, else the first word
+// of each part between |, & and ;.
+func commandWords(cmd string) [][2]int {
+	var out [][2]int
+	if f, err := parse(cmd); err == nil {
+		syntax.Walk(f, func(n syntax.Node) bool {
+			if ce, ok := n.(*syntax.CallExpr); ok && len(ce.Args) > 0 {
+				w := ce.Args[0]
+				if lit := w.Lit(); lit != "" {
+					out = append(out, [2]int{int(w.Pos().Offset()), int(w.End().Offset())})
+				}
+			}
+			return true
+		})
+		return out
+	}
+	start := 0
+	for i := 0; i <= len(cmd); i++ {
+		if i < len(cmd) && !strings.ContainsRune("|&;", rune(cmd[i])) {
+			continue
+		}
+		part := cmd[start:i]
+		trimmed := strings.TrimLeft(part, " \t")
+		if f := strings.Fields(trimmed); len(f) > 0 {
+			s := start + len(part) - len(trimmed)
+			out = append(out, [2]int{s, s + len(f[0])})
+		}
+		start = i + 1
+	}
+	return out
+}
+
+func parse(cmd string) (*syntax.File, error) {
+	return syntax.NewParser(syntax.Variant(syntax.LangBash)).Parse(strings.NewReader(cmd), "")
+}
+
+var dirBuiltins = map[string]bool{"cd": true, "pushd": true, "popd": true, "chdir": true}
+
+// cd reports steps whose last command changes directory: every step runs
+// in a new shell, so the change is lost.
+func (c *checker) cd(i int, cmd string) {
+	f, err := parse(cmd)
+	if err != nil || len(f.Stmts) == 0 {
+		return
+	}
+	last := f.Stmts[len(f.Stmts)-1]
+	for {
+		b, ok := last.Cmd.(*syntax.BinaryCmd)
+		if !ok || (b.Op != syntax.AndStmt && b.Op != syntax.OrStmt) {
+			break
+		}
+		last = b.Y
+	}
+	ce, ok := last.Cmd.(*syntax.CallExpr)
+	if !ok || len(ce.Args) == 0 || !dirBuiltins[ce.Args[0].Lit()] {
+		return
+	}
+	start, end := int(last.Pos().Offset()), int(last.End().Offset())
+	hint := "chain the commands that need it in the same step"
+	if i+1 < len(c.set.Commands) {
+		hint = fmt.Sprintf("join it with step %d, e.g. '%s && %s'", i+2, cmd[start:end], c.set.Commands[i+1])
+	}
+	c.add("cd.no-effect", i, start, end, "'%s' has no effect because each step runs in a new shell; %s", cmd[start:end], hint)
+}
+
+// homeRe matches an absolute path into a user's home directory.
+var homeRe = regexp.MustCompile(`(?:^|[\s'"=:])((?:/home|/Users)/([A-Za-z0-9._-]+)|[A-Za-z]:\\Users\\([A-Za-z0-9._-]+))`)
+
+// sharedHomes are directories under /home, /Users or C:\Users that do not
+// belong to one user.
+var sharedHomes = map[string]bool{"shared": true, "public": true, "default": true, "all users": true}
+
+func (c *checker) homePaths(i int, cmd string) {
+	for _, m := range homeRe.FindAllStringSubmatchIndex(cmd, -1) {
+		user := ""
+		if m[4] >= 0 {
+			user = cmd[m[4]:m[5]]
+		} else {
+			user = cmd[m[6]:m[7]]
+		}
+		if sharedHomes[strings.ToLower(user)] {
+			continue
+		}
+		instead := "~ or $HOME"
+		if m[6] >= 0 {
+			instead = "%USERPROFILE%"
+		}
+		c.add("path.home", i, m[2], m[3], "%s is %s's home directory and will not exist for other users; use %s", cmd[m[2]:m[3]], user, instead)
+	}
+}
+
+// secrets reports credentials written into the command.
+func (c *checker) secrets(i int, cmd string) {
+	for _, sec := range security.FindSecrets(cmd) {
+		c.add("secret.literal", i, sec.Start, sec.End, "%s stored in plain text; use a parameter, e.g. {{%s}} run with --param %s=env:NAME", withArticle(sec.Kind), sec.ParamName(), sec.ParamName())
+	}
+}
+
+// withArticle prefixes a secret kind with "a" or "an".
+func withArticle(kind string) string {
+	if strings.ContainsRune("aeiouAEIOU", rune(kind[0])) {
+		return "an " + kind
+	}
+	return "a " + kind
+}
@@ -0,0 +1,324 @@
+// Package mcp implements `krnr mcp`: a Model Context Protocol server on
+// stdin/stdout that offers command sets as tools, so coding agents run
+// vetted workflows instead of arbitrary shell commands. Messages are
+// newline-delimited JSON-RPC 2.0, as the MCP stdio transport specifies.
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/VoxDroid/krnr/internal/rpc"
+	"github.com/VoxDroid/krnr/internal/version"
+)
+
+// ProtocolVersions are the MCP revisions the server speaks, newest first.
+var ProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// maxMessage bounds the size of one incoming message.
+const maxMessage = 4 << 20
+
+// Set is a command set the server may offer as a tool.
+type Set struct {
+	Name        string
+	Description string
+	Commands    []string
+	Params      []string
+}
+
+// Result is the outcome of a finished run.
+type Result struct {
+	Output   string
+	ExitCode int
+}
+
+// Backend lists the sets agents may use and runs them. Run must refuse
+// sets that Sets would not return.
+type Backend interface {
+	Sets() ([]Set, error)
+	Run(ctx context.Context, set string, params map[string]string) (Result, error)
+}
+
+// Tool is an entry of the tools/list result.
+type Tool struct {
+	Name        string         `json:"name"`
+	Title       string         `json:"title,omitempty"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+}
+
+// Content is a text content block of a tool result.
+type Content struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// CallResult is the result of tools/call. Failed runs and refused calls are
+// reported with IsError so the agent sees why.
+type CallResult struct {
+	Content []Content `json:"content"`
+	IsError bool      `json:"isError"`
+}
+
+var invalidToolChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// ToolName maps a set name to a valid MCP tool name.
+func ToolName(set string) string {
+	n := invalidToolChars.ReplaceAllString(set, "_")
+	if len(n) > 64 {
+		n = n[:64]
+	}
+	return n
+}
+
+// tools returns the offered tools keyed by name. When two sets map to the
+// same tool name the first one (by set name) wins.
+func tools(b Backend) ([]Tool, map[string]Set, error) {
+	sets, err := b.Sets()
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Slice(sets, func(i, j int) bool { return sets[i].Name < sets[j].Name })
+	out := []Tool{}
+	byName := map[string]Set{}
+	for _, s := range sets {
+		name := ToolName(s.Name)
+		if _, dup := byName[name]; dup || name == "" {
+			continue
+		}
+		byName[name] = s
+		out = append(out, Tool{Name: name, Title: s.Name, Description: describe(s), InputSchema: inputSchema(s.Params)})
+	}
+	return out, byName, nil
+}
+
+// describe uses the set's description and shows the commands it runs.
+func describe(s Set) string {
+	var b strings.Builder
+	if s.Description != "" {
+		b.WriteString(s.Description)
+	} else {
+		fmt.Fprintf(&b, "Run the krnr command set %q.", s.Name)
+	}
+	b.WriteString("\n\nRuns:")
+	for _, c := range s.Commands {
+		b.WriteString("\n  ")
+		b.WriteString(c)
+	}
+	return b.String()
+}
+
+// inputSchema requires a string for each parameter; runs never prompt.
+func inputSchema(params []string) map[string]any {
+	props := map[string]any{}
+	for _, p := range params {
+		props[p] = map[string]any{"type": "string", "description": "Value for {{" + p + "}}"}
+	}
+	required := append([]string{}, params...)
+	return map[string]any{"type": "object", "properties": props, "required": required, "additionalProperties": false}
+}
+
+// Server answers MCP requests using a Backend.
+type Server struct {
+	backend Backend
+
+	mu      sync.Mutex
+	out     io.Writer
+	pending map[string]context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewServer returns a server backed by b.
+func NewServer(b Backend) *Server {
+	return &Server{backend: b, pending: map[string]context.CancelFunc{}}
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpc.Error      `json:"error,omitempty"`
+}
+
+func (s *Server) reply(id json.RawMessage, result any, err error) {
+	resp := response{JSONRPC: "2.0", ID: id, Result: result}
+	if len(id) == 0 {
+		resp.ID = json.RawMessage("null")
+	}
+	if err != nil {
+		var re *rpc.Error
+		if !errors.As(err, &re) {
+			re = &rpc.Error{Code: rpc.CodeInternalError, Message: err.Error()}
+		}
+		resp.Result, resp.Error = nil, re
+	} else if result == nil {
+		resp.Result = struct{}{}
+	}
+	b, merr := json.Marshal(resp)
+	if merr != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, _ = s.out.Write(append(b, '\n'))
+}
+
+// Serve reads requests from in and writes responses to out until in is
+// exhausted. Tool calls run concurrently and are cancelled on return.
+func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer s.wg.Wait()
+	defer cancel()
+	s.out = out
+	sc := bufio.NewScanner(in)
+	sc.Buffer(make([]byte, 64*1024), maxMessage)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		var req request
+		if err := json.Unmarshal([]byte(line), &req); err != nil {
+			s.reply(nil, nil, &rpc.Error{Code: rpc.CodeParseError, Message: err.Error()})
+			continue
+		}
+		if req.JSONRPC != "2.0" || req.Method == "" {
+			if req.Method == "" && len(req.ID) > 0 {
+				// a response to a server request; the server sends none
+				continue
+			}
+			s.reply(req.ID, nil, &rpc.Error{Code: rpc.CodeInvalidRequest, Message: "not a JSON-RPC 2.0 request"})
+			continue
+		}
+		if len(req.ID) == 0 {
+			s.notification(req)
+			continue
+		}
+		switch req.Method {
+		case "initialize":
+			s.reply(req.ID, s.initialize(req.Params), nil)
+		case "ping":
+			s.reply(req.ID, nil, nil)
+		case "tools/list":
+			list, _, err := tools(s.backend)
+			s.reply(req.ID, map[string]any{"tools": list}, err)
+		case "tools/call":
+			s.call(ctx, req)
+		default:
+			s.reply(req.ID, nil, &rpc.Error{Code: rpc.CodeMethodNotFound, Message: "method not found: " + req.Method})
+		}
+	}
+	return sc.Err()
+}
+
+func (s *Server) initialize(raw json.RawMessage) any {
+	var p struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	_ = json.Unmarshal(raw, &p)
+	v := ProtocolVersions[0]
+	for _, sv := range ProtocolVersions {
+		if sv == p.ProtocolVersion {
+			v = sv
+		}
+	}
+	return map[string]any{
+		"protocolVersion": v,
+		"capabilities":    map[string]any{"tools": map[string]bool{"listChanged": false}},
+		"serverInfo":      map[string]string{"name": "krnr", "version": version.Version},
+		"instructions":    "Each tool runs a vetted krnr command set. Supply every listed parameter; commands are safety-checked before they run.",
+	}
+}
+
+// notification handles notifications/cancelled; others need no action.
+func (s *Server) notification(req request) {
+	if req.Method != "notifications/cancelled" {
+		return
+	}
+	var p struct {
+		RequestID json.RawMessage `json:"requestId"`
+	}
+	if json.Unmarshal(req.Params, &p) != nil {
+		return
+	}
+	s.mu.Lock()
+	cancel := s.pending[string(p.RequestID)]
+	s.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+func (s *Server) call(ctx context.Context, req request) {
+	var p struct {
+		Name      string         `json:"name"`
+		Arguments map[string]any `json:"arguments"`
+	}
+	if len(req.Params) == 0 || json.Unmarshal(req.Params, &p) != nil {
+		s.reply(req.ID, nil, &rpc.Error{Code: rpc.CodeInvalidParams, Message: "expected {name, arguments}"})
+		return
+	}
+	_, byName, err := tools(s.backend)
+	if err != nil {
+		s.reply(req.ID, nil, err)
+		return
+	}
+	set, ok := byName[p.Name]
+	if !ok {
+		s.reply(req.ID, nil, &rpc.Error{Code: rpc.CodeInvalidParams, Message: "unknown tool: " + p.Name})
+		return
+	}
+	params := map[string]string{}
+	for k, v := range p.Arguments {
+		switch v := v.(type) {
+		case string:
+			params[k] = v
+		case float64, bool:
+			params[k] = fmt.Sprint(v)
+		default:
+			s.reply(req.ID, nil, &rpc.Error{Code: rpc.CodeInvalidParams, Message: fmt.Sprintf("argument %q must be a string", k)})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	key := string(req.ID)
+	s.mu.Lock()
+	s.pending[key] = cancel
+	s.mu.Unlock()
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() {
+			s.mu.Lock()
+			delete(s.pending, key)
+			s.mu.Unlock()
+			cancel()
+		}()
+		res, err := s.backend.Run(ctx, set.Name, params)
+		if err != nil {
+			s.reply(req.ID, CallResult{Content: []Content{{Type: "text", Text: err.Error()}}, IsError: true}, nil)
+			return
+		}
+		text := res.Output
+		if res.ExitCode != 0 {
+			text += fmt.Sprintf("\n[exit code %d]", res.ExitCode)
+		}
+		s.reply(req.ID, CallResult{Content: []Content{{Type: "text", Text: text}}, IsError: res.ExitCode != 0}, nil)
+	}()
+}
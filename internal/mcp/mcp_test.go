@@ -0,0 +1,176 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/VoxDroid/krnr/internal/rpc"
+)
+
+type fakeBackend struct {
+	sets    []Set
+	release chan struct{}
+	params  map[string]string
+}
+
+func (f *fakeBackend) Sets() ([]Set, error) { return f.sets, nil }
+
+func (f *fakeBackend) Run(ctx context.Context, set string, params map[string]string) (Result, error) {
+	f.params = params
+	switch set {
+	case "wipe":
+		return Result{}, errors.New("run refused")
+	case "slow":
+		select {
+		case <-ctx.Done():
+			return Result{}, ctx.Err()
+		case <-f.release:
+		}
+	case "fail":
+		return Result{Output: "boom\n", ExitCode: 2}, nil
+	}
+	return Result{Output: "ok\n"}, nil
+}
+
+type message struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpc.Error      `json:"error"`
+}
+
+// session starts a server and returns functions to send a line and read a
+// message.
+func session(t *testing.T, b Backend) (func(string), func() message) {
+	t.Helper()
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		_ = NewServer(b).Serve(context.Background(), inR, outW)
+		_ = outW.Close()
+		close(done)
+	}()
+	t.Cleanup(func() { _ = inW.Close(); <-done })
+	out := bufio.NewReader(outR)
+	send := func(line string) {
+		if _, err := io.WriteString(inW, line+"\n"); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	read := func() message {
+		line, err := out.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		var m message
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("decode %s: %v", line, err)
+		}
+		return m
+	}
+	return send, read
+}
+
+func TestToolName(t *testing.T) {
+	if got := ToolName("deploy prod/eu"); got != "deploy_prod_eu" {
+		t.Fatalf("ToolName = %q", got)
+	}
+	if got := ToolName(strings.Repeat("a", 80)); len(got) != 64 {
+		t.Fatalf("expected names to be capped at 64 characters, got %d", len(got))
+	}
+}
+
+func TestInitializeAndListTools(t *testing.T) {
+	b := &fakeBackend{sets: []Set{
+		{Name: "greet", Description: "Say hello", Commands: []string{"echo {{who}}"}, Params: []string{"who"}},
+		{Name: "build", Commands: []string{"make"}, Params: []string{}},
+	}}
+	send, read := session(t, b)
+
+	send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}`)
+	var init struct {
+		ProtocolVersion string                     `json:"protocolVersion"`
+		Capabilities    map[string]json.RawMessage `json:"capabilities"`
+	}
+	_ = json.Unmarshal(read().Result, &init)
+	if init.ProtocolVersion != "2024-11-05" || init.Capabilities["tools"] == nil {
+		t.Fatalf("unexpected initialize result %+v", init)
+	}
+	send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	send(`{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+	if m := read(); string(m.ID) != "2" || m.Error != nil {
+		t.Fatalf("unexpected ping response %+v", m)
+	}
+
+	send(`{"jsonrpc":"2.0","id":3,"method":"tools/list"}`)
+	var list struct{ Tools []Tool }
+	_ = json.Unmarshal(read().Result, &list)
+	if len(list.Tools) != 2 || list.Tools[0].Name != "build" || list.Tools[1].Name != "greet" {
+		t.Fatalf("unexpected tools %+v", list.Tools)
+	}
+	greet := list.Tools[1]
+	if !strings.HasPrefix(greet.Description, "Say hello") || !strings.Contains(greet.Description, "echo {{who}}") {
+		t.Fatalf("unexpected description %q", greet.Description)
+	}
+	schema, _ := json.Marshal(greet.InputSchema)
+	if !strings.Contains(string(schema), `"required":["who"]`) || !strings.Contains(string(schema), `"who":{`) {
+		t.Fatalf("unexpected schema %s", schema)
+	}
+
+	send(`{"jsonrpc":"2.0","id":4,"method":"resources/list"}`)
+	if m := read(); m.Error == nil || m.Error.Code != rpc.CodeMethodNotFound {
+		t.Fatalf("expected method not found, got %+v", m)
+	}
+}
+
+func TestCallTool(t *testing.T) {
+	b := &fakeBackend{release: make(chan struct{}), sets: []Set{
+		{Name: "greet", Params: []string{"who"}}, {Name: "wipe"}, {Name: "fail"}, {Name: "slow"},
+	}}
+	send, read := session(t, b)
+	call := func(id, name, args string) CallResult {
+		t.Helper()
+		send(`{"jsonrpc":"2.0","id":` + id + `,"method":"tools/call","params":{"name":"` + name + `","arguments":` + args + `}}`)
+		m := read()
+		if m.Error != nil || string(m.ID) != id {
+			t.Fatalf("unexpected response %+v", m)
+		}
+		var res CallResult
+		_ = json.Unmarshal(m.Result, &res)
+		return res
+	}
+
+	if res := call("1", "greet", `{"who":"you","n":3}`); res.IsError || res.Content[0].Text != "ok\n" || b.params["who"] != "you" || b.params["n"] != "3" {
+		t.Fatalf("unexpected result %+v (params %v)", res, b.params)
+	}
+	if res := call("2", "wipe", `{}`); !res.IsError || res.Content[0].Text != "run refused" {
+		t.Fatalf("expected a refused call to be an error result, got %+v", res)
+	}
+	if res := call("3", "fail", `{}`); !res.IsError || !strings.Contains(res.Content[0].Text, "[exit code 2]") {
+		t.Fatalf("expected a failed run to be an error result, got %+v", res)
+	}
+
+	send(`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"missing"}}`)
+	if m := read(); m.Error == nil || m.Error.Code != rpc.CodeInvalidParams {
+		t.Fatalf("expected unknown tool error, got %+v", m)
+	}
+
+	// a slow call does not block other requests and can be cancelled
+	send(`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"slow","arguments":{}}}`)
+	send(`{"jsonrpc":"2.0","id":6,"method":"ping"}`)
+	if m := read(); string(m.ID) != "6" {
+		t.Fatalf("expected the ping response first, got %+v", m)
+	}
+	send(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":5}}`)
+	m := read()
+	var res CallResult
+	_ = json.Unmarshal(m.Result, &res)
+	if string(m.ID) != "5" || !res.IsError || !strings.Contains(res.Content[0].Text, "canceled") {
+		t.Fatalf("expected the cancelled call to fail, got %+v", m)
+	}
+}
@@ -0,0 +1,169 @@
+// Package plugins discovers and runs external krnr subcommands: executables
+// named krnr-<name> in KRNR_HOME/plugins or on PATH, which `krnr <name>`
+// dispatches to the way git and kubectl do.
+package plugins
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/VoxDroid/krnr/internal/config"
+	"github.com/VoxDroid/krnr/internal/version"
+)
+
+// Prefix starts the file name of every plugin executable.
+const Prefix = "krnr-"
+
+// Environment variables describing krnr to a plugin.
+const (
+	EnvDB      = config.EnvKRNRDB
+	EnvHome    = config.EnvKRNRHome
+	EnvVersion = "KRNR_VERSION"
+	// EnvBin is the path of the krnr executable that started the plugin.
+	EnvBin = "KRNR_BIN"
+)
+
+// Plugin is a discovered plugin executable.
+type Plugin struct {
+	// Name is the subcommand: the file name without Prefix and, on
+	// Windows, without its executable extension.
+	Name string
+	Path string
+	// Shadowed is set when an earlier plugin of the same name wins.
+	Shadowed bool
+}
+
+// Dir returns KRNR_HOME/plugins.
+func Dir() (string, error) {
+	d, err := config.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(d, "plugins"), nil
+}
+
+// searchPath lists the directories searched, in precedence order: the
+// plugins directory, then PATH. Empty and relative PATH entries are skipped
+// so a krnr-<name> in the working directory is never picked up, the way
+// exec.LookPath refuses them.
+func searchPath() []string {
+	var dirs []string
+	if d, err := Dir(); err == nil {
+		dirs = append(dirs, d)
+	}
+	for _, d := range filepath.SplitList(os.Getenv("PATH")) {
+		if d == "" || !filepath.IsAbs(d) {
+			continue
+		}
+		dirs = append(dirs, d)
+	}
+	return dirs
+}
+
+// pluginName returns the subcommand a file name provides, if any.
+func pluginName(file string) (string, bool) {
+	if !strings.HasPrefix(file, Prefix) {
+		return "", false
+	}
+	name := strings.TrimPrefix(file, Prefix)
+	if runtime.GOOS == "windows" {
+		ext := strings.ToLower(filepath.Ext(name))
+		if !isWindowsExecExt(ext) {
+			return "", false
+		}
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	if name == "" || strings.HasPrefix(name, "-") {
+		return "", false
+	}
+	return name, true
+}
+
+func isWindowsExecExt(ext string) bool {
+	exts := os.Getenv("PATHEXT")
+	if exts == "" {
+		exts = ".com;.exe;.bat;.cmd"
+	}
+	for _, e := range filepath.SplitList(strings.ToLower(exts)) {
+		if e == ext {
+			return true
+		}
+	}
+	return false
+}
+
+func isExecutable(path string) bool {
+	fi, err := os.Stat(path)
+	if err != nil || !fi.Mode().IsRegular() {
+		return false
+	}
+	return runtime.GOOS == "windows" || fi.Mode().Perm()&0o111 != 0
+}
+
+// Discover lists every plugin, in precedence order. Later plugins with the
+// name of an earlier one are included with Shadowed set.
+func Discover() []Plugin {
+	var out []Plugin
+	seen := map[string]bool{}
+	visited := map[string]bool{}
+	for _, dir := range searchPath() {
+		abs, err := filepath.Abs(dir)
+		if err != nil || visited[abs] {
+			continue
+		}
+		visited[abs] = true
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		names := make([]string, 0, len(entries))
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		sort.Strings(names)
+		for _, file := range names {
+			name, ok := pluginName(file)
+			if !ok {
+				continue
+			}
+			path := filepath.Join(dir, file)
+			if !isExecutable(path) {
+				continue
+			}
+			out = append(out, Plugin{Name: name, Path: path, Shadowed: seen[name]})
+			seen[name] = true
+		}
+	}
+	return out
+}
+
+// Env returns the environment a plugin runs with: the current one plus the
+// database path, data directory, version and krnr executable.
+func Env() []string {
+	env := os.Environ()
+	if p, err := config.DBPath(); err == nil {
+		env = append(env, EnvDB+"="+p)
+	}
+	if d, err := config.DataDir(); err == nil {
+		env = append(env, EnvHome+"="+d)
+	}
+	env = append(env, EnvVersion+"="+version.Version)
+	if bin, err := os.Executable(); err == nil {
+		env = append(env, EnvBin+"="+bin)
+	}
+	return env
+}
+
+// Run runs p with args and the given standard streams.
+func Run(ctx context.Context, p Plugin, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	c := exec.CommandContext(ctx, p.Path, args...)
+	c.Env = Env()
+	c.Stdin, c.Stdout, c.Stderr = stdin, stdout, stderr
+	return c.Run()
+}
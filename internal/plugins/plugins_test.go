@@ -0,0 +1,91 @@
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/VoxDroid/krnr/internal/config"
+)
+
+func writeScript(t *testing.T, path, body string, mode os.FileMode) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiscoverAndRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts")
+	}
+	home := t.TempDir()
+	t.Setenv(config.EnvKRNRHome, home)
+	t.Setenv(config.EnvKRNRDB, "")
+	pdir := filepath.Join(home, "plugins")
+	bin := t.TempDir()
+	_ = os.MkdirAll(pdir, 0o755)
+	writeScript(t, filepath.Join(pdir, "krnr-hello"), `echo "$KRNR_VERSION $KRNR_DB $*"`, 0o755)
+	writeScript(t, filepath.Join(bin, "krnr-hello"), "echo shadowed", 0o755)
+	writeScript(t, filepath.Join(bin, "krnr-audit"), "echo audit", 0o755)
+	writeScript(t, filepath.Join(bin, "krnr-notexec"), "echo no", 0o644)
+	writeScript(t, filepath.Join(bin, "other"), "echo no", 0o755)
+	t.Setenv("PATH", bin)
+
+	found := Discover()
+	if len(found) != 3 {
+		t.Fatalf("expected 3 plugins, got %+v", found)
+	}
+	if found[0].Name != "hello" || found[0].Shadowed || found[0].Path != filepath.Join(pdir, "krnr-hello") {
+		t.Fatalf("expected the plugins directory to come first, got %+v", found[0])
+	}
+	if found[1].Name != "audit" || found[1].Shadowed || found[2].Name != "hello" || !found[2].Shadowed {
+		t.Fatalf("unexpected PATH plugins %+v", found[1:])
+	}
+
+	var out bytes.Buffer
+	if err := Run(context.Background(), found[0], []string{"--flag", "x"}, nil, &out, &out); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	want := " " + filepath.Join(home, "krnr.db") + " --flag x"
+	if !strings.HasSuffix(strings.TrimSpace(out.String()), want) || !strings.HasPrefix(out.String(), "v") {
+		t.Fatalf("unexpected plugin output %q", out.String())
+	}
+}
+
+func TestPluginName(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("names carry extensions on Windows")
+	}
+	for file, want := range map[string]string{"krnr-hello": "hello", "krnr-": "", "krnr--x": "", "hello": ""} {
+		got, ok := pluginName(file)
+		if got != want || ok != (want != "") {
+			t.Errorf("pluginName(%q) = %q %v, want %q", file, got, ok, want)
+		}
+	}
+}
+
+func TestSearchPathSkipsRelativeEntries(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts")
+	}
+	t.Setenv(config.EnvKRNRHome, t.TempDir())
+	t.Setenv(config.EnvKRNRDB, "")
+	cwd := t.TempDir()
+	t.Chdir(cwd)
+	writeScript(t, filepath.Join(cwd, "krnr-local"), "echo local", 0o755)
+	_ = os.MkdirAll(filepath.Join(cwd, "bin"), 0o755)
+	writeScript(t, filepath.Join(cwd, "bin", "krnr-rel"), "echo rel", 0o755)
+	bin := t.TempDir()
+	writeScript(t, filepath.Join(bin, "krnr-abs"), "echo abs", 0o755)
+	t.Setenv("PATH", strings.Join([]string{"", ".", "bin", bin}, string(os.PathListSeparator)))
+
+	found := Discover()
+	if len(found) != 1 || found[0].Name != "abs" {
+		t.Fatalf("expected only the plugin from the absolute PATH entry, got %+v", found)
+	}
+}
@@ -0,0 +1,257 @@
+// Package progress renders live progress for command set runs: a step
+// counter, elapsed time, spinner and ETA on terminals, plain step lines
+// elsewhere, and a per-step summary table when the run ends.
+package progress
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/VoxDroid/krnr/internal/tui/adapters"
+)
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// tickInterval controls how often the TTY status line is redrawn.
+var tickInterval = 100 * time.Millisecond
+
+type stepState struct {
+	command  string
+	status   string // pending|running|ok|failed
+	duration time.Duration
+	exitCode int
+}
+
+// Renderer consumes run events and draws progress to w. On a TTY it keeps a
+// status line below command output; otherwise it prints plain lines.
+type Renderer struct {
+	w         io.Writer
+	tty       bool
+	total     int
+	estimates []time.Duration
+
+	mu        sync.Mutex
+	steps     []stepState
+	current   int
+	runStart  time.Time
+	stepStart time.Time
+	shown     bool // status line currently drawn
+	midLine   bool // output ended without a newline; don't draw over it
+	paused    bool // waiting for a step decision; keep the prompt visible
+	frame     int
+	stop      chan struct{}
+	done      chan struct{}
+	now       func() time.Time
+}
+
+// New returns a renderer for a run of commands. estimates, when non-nil,
+// holds the expected duration of each step and enables the ETA.
+func New(w io.Writer, tty bool, commands []string, estimates []time.Duration) *Renderer {
+	steps := make([]stepState, len(commands))
+	for i, c := range commands {
+		steps[i] = stepState{command: c, status: "pending", exitCode: -1}
+	}
+	if len(estimates) != len(commands) {
+		estimates = nil
+	}
+	return &Renderer{w: w, tty: tty, total: len(commands), estimates: estimates, steps: steps, now: time.Now}
+}
+
+// Handle consumes a run event. It is suitable as an adapters.EventEmitter sink.
+func (r *Renderer) Handle(ev adapters.RunEvent) {
+	switch ev.Type {
+	case adapters.EventRunStarted:
+		r.runStarted()
+	case adapters.EventStepPaused:
+		r.mu.Lock()
+		r.clearLocked()
+		r.paused = true
+		r.mu.Unlock()
+	case adapters.EventStepStarted:
+		r.stepStarted(ev.Step, ev.Command)
+	case adapters.EventStepFinished:
+		r.stepFinished(ev.Step, ev.ExitCode, ev.Duration)
+	case adapters.EventRunFinished:
+		r.runFinished(ev.Duration)
+	}
+}
+
+func (r *Renderer) runStarted() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runStart = r.now()
+	if r.tty && r.stop == nil {
+		r.stop, r.done = make(chan struct{}), make(chan struct{})
+		go r.tick(r.stop, r.done)
+	}
+}
+
+func (r *Renderer) stepStarted(step int, command string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if step < 1 || step > r.total {
+		return
+	}
+	r.current = step
+	r.paused = false
+	r.stepStart = r.now()
+	r.steps[step-1].status = "running"
+	r.steps[step-1].command = command
+	r.clearLocked()
+	_, _ = fmt.Fprintf(r.w, "[%d/%d] -> %s\n", step, r.total, command)
+	r.drawLocked()
+}
+
+func (r *Renderer) stepFinished(step, exitCode int, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if step < 1 || step > r.total {
+		return
+	}
+	st := &r.steps[step-1]
+	st.duration, st.exitCode, st.status = d, exitCode, "ok"
+	if exitCode != 0 {
+		st.status = "failed"
+	}
+	if !r.tty {
+		_, _ = fmt.Fprintf(r.w, "[%d/%d] %s in %s (exit %d)\n", step, r.total, st.status, fmtDuration(d), exitCode)
+	}
+}
+
+func (r *Renderer) runFinished(total time.Duration) {
+	r.mu.Lock()
+	stop, done := r.stop, r.done
+	r.stop = nil
+	r.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clearLocked()
+	r.writeSummaryLocked(total)
+}
+
+func (r *Renderer) tick(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	t := time.NewTicker(tickInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+			r.mu.Lock()
+			r.frame++
+			r.drawLocked()
+			r.mu.Unlock()
+		}
+	}
+}
+
+// Writer returns an io.Writer for command output that keeps the status line
+// below the output on terminals. On non-terminals it writes straight to w.
+func (r *Renderer) Writer() io.Writer {
+	if !r.tty {
+		return r.w
+	}
+	return writerFunc(func(p []byte) (int, error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.clearLocked()
+		n, err := r.w.Write(p)
+		if len(p) > 0 {
+			r.midLine = p[len(p)-1] != '\n'
+		}
+		r.drawLocked()
+		return n, err
+	})
+}
+
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
+func (r *Renderer) clearLocked() {
+	if r.shown {
+		_, _ = io.WriteString(r.w, "\r\x1b[2K")
+		r.shown = false
+	}
+}
+
+func (r *Renderer) drawLocked() {
+	if !r.tty || r.midLine || r.paused || r.current == 0 || r.stop == nil {
+		return
+	}
+	_, _ = io.WriteString(r.w, "\r\x1b[2K"+r.statusLocked())
+	r.shown = true
+}
+
+// statusLocked renders e.g. "⠹ step 3/8 · 0:12 elapsed · ETA 0:30".
+func (r *Renderer) statusLocked() string {
+	now := r.now()
+	parts := []string{
+		spinnerFrames[r.frame%len(spinnerFrames)] + fmt.Sprintf(" step %d/%d", r.current, r.total),
+		fmtClock(now.Sub(r.runStart)) + " elapsed",
+	}
+	if eta, ok := r.etaLocked(now); ok {
+		parts = append(parts, "ETA "+fmtClock(eta))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// etaLocked estimates the remaining time from historical step durations.
+func (r *Renderer) etaLocked(now time.Time) (time.Duration, bool) {
+	if r.estimates == nil || r.current == 0 {
+		return 0, false
+	}
+	remaining := r.estimates[r.current-1] - now.Sub(r.stepStart)
+	if remaining < 0 {
+		remaining = 0
+	}
+	for _, d := range r.estimates[r.current:] {
+		remaining += d
+	}
+	return remaining, true
+}
+
+func (r *Renderer) writeSummaryLocked(total time.Duration) {
+	_, _ = fmt.Fprintf(r.w, "\nSummary (%s):\n", fmtDuration(total))
+	tw := tabwriter.NewWriter(r.w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "STEP\tSTATUS\tDURATION\tEXIT\tCOMMAND")
+	for i, st := range r.steps {
+		status, dur, exit := st.status, "-", "-"
+		switch st.status {
+		case "pending":
+			status = "skipped"
+		case "running":
+			// never finished (e.g., refused or interrupted)
+			status = "aborted"
+		default:
+			dur, exit = fmtDuration(st.duration), fmt.Sprint(st.exitCode)
+		}
+		_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", i+1, status, dur, exit, st.command)
+	}
+	_ = tw.Flush()
+}
+
+func fmtDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
+
+func fmtClock(d time.Duration) string {
+	d = d.Round(time.Second)
+	h, m, s := int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
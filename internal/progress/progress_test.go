@@ -0,0 +1,66 @@
+package progress
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VoxDroid/krnr/internal/tui/adapters"
+)
+
+func TestPlainRendererAndSummary(t *testing.T) {
+	var buf bytes.Buffer
+	r := New(&buf, false, []string{"echo a", "false", "echo c"}, nil)
+	r.Handle(adapters.RunEvent{Type: adapters.EventRunStarted, Steps: 3})
+	r.Handle(adapters.RunEvent{Type: adapters.EventStepStarted, Step: 1, Command: "echo a"})
+	_, _ = r.Writer().Write([]byte("a\n"))
+	r.Handle(adapters.RunEvent{Type: adapters.EventStepFinished, Step: 1, Duration: 20 * time.Millisecond})
+	r.Handle(adapters.RunEvent{Type: adapters.EventStepStarted, Step: 2, Command: "false"})
+	r.Handle(adapters.RunEvent{Type: adapters.EventStepFinished, Step: 2, ExitCode: 1, Duration: 5 * time.Millisecond})
+	r.Handle(adapters.RunEvent{Type: adapters.EventRunFinished, ExitCode: 1, Duration: 25 * time.Millisecond})
+
+	out := buf.String()
+	for _, want := range []string{
+		"[1/3] -> echo a\na\n[1/3] ok in 20ms (exit 0)\n",
+		"[2/3] failed in 5ms (exit 1)",
+		"STEP  STATUS",
+		"3     skipped",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in output:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "\x1b[") {
+		t.Fatalf("plain output must not contain escape sequences:\n%s", out)
+	}
+}
+
+func TestTTYStatusLineAndETA(t *testing.T) {
+	var buf bytes.Buffer
+	r := New(&buf, true, []string{"a", "b"}, []time.Duration{10 * time.Second, 20 * time.Second})
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return clock }
+	r.Handle(adapters.RunEvent{Type: adapters.EventRunStarted, Steps: 2})
+	r.Handle(adapters.RunEvent{Type: adapters.EventStepStarted, Step: 1, Command: "a"})
+	clock = clock.Add(4 * time.Second)
+
+	r.mu.Lock()
+	status := r.statusLocked()
+	r.mu.Unlock()
+	if !strings.Contains(status, "step 1/2") || !strings.Contains(status, "0:04 elapsed") || !strings.Contains(status, "ETA 0:26") {
+		t.Fatalf("unexpected status line: %q", status)
+	}
+
+	// output clears the status line before writing and redraws it after
+	_, _ = r.Writer().Write([]byte("hello\n"))
+	if !strings.Contains(buf.String(), "\r\x1b[2Khello\n\r\x1b[2K") {
+		t.Fatalf("expected status line cleared around output, got %q", buf.String())
+	}
+	r.Handle(adapters.RunEvent{Type: adapters.EventRunFinished, Duration: 4 * time.Second})
+	out := buf.String()
+	summary := out[strings.LastIndex(out, "Summary"):]
+	if !strings.Contains(summary, "aborted") || strings.Contains(summary, "\x1b[") {
+		t.Fatalf("expected clean summary after run, got %q", summary)
+	}
+}
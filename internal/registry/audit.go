@@ -0,0 +1,242 @@
+package registry
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"os"
+	osuser "os/user"
+	"sync"
+	"time"
+)
+
+// Audit actions.
+const (
+	AuditCreate        = "create"
+	AuditUpdate        = "update"
+	AuditRollback      = "rollback"
+	AuditTag           = "tag"
+	AuditDelete        = "delete"
+	AuditDeleteVersion = "delete-version"
+	AuditRedact        = "redact-history"
+	AuditImport        = "import"
+	AuditTrust         = "trust"
+	AuditSettings      = "settings"
+	AuditRun           = "run"
+	AuditRunFinished   = "run-finished"
+)
+
+// Actor identifies who changes sets and starts runs, for the audit log.
+type Actor struct {
+	// Profile is the whoami profile ("name <email>"), if one is set.
+	Profile string `json:"profile,omitempty"`
+	OSUser  string `json:"os_user"`
+	Host    string `json:"host"`
+}
+
+// String renders a as "profile (user@host)".
+func (a Actor) String() string {
+	who := a.OSUser + "@" + a.Host
+	if a.Profile == "" {
+		return who
+	}
+	return a.Profile + " (" + who + ")"
+}
+
+var (
+	localOnce sync.Once
+	localUser string
+	localHost string
+)
+
+// LocalActor returns the OS user and host this process runs as, with the
+// given whoami profile.
+func LocalActor(profile string) Actor {
+	localOnce.Do(func() {
+		if u, err := osuser.Current(); err == nil {
+			localUser = u.Username
+		}
+		localHost, _ = os.Hostname()
+	})
+	return Actor{Profile: profile, OSUser: localUser, Host: localHost}
+}
+
+var defaultActor *Actor
+
+// SetDefaultActor makes a the actor of every Repository created afterwards.
+// Without one, repositories use LocalActor without a profile.
+func SetDefaultActor(a Actor) {
+	defaultHookMu.Lock()
+	defer defaultHookMu.Unlock()
+	defaultActor = &a
+}
+
+func currentDefaultActor() Actor {
+	defaultHookMu.Lock()
+	defer defaultHookMu.Unlock()
+	if defaultActor == nil {
+		return LocalActor("")
+	}
+	return *defaultActor
+}
+
+// SetActor makes a the actor r records in the audit log.
+func (r *Repository) SetActor(a Actor) { r.actor = a }
+
+// AuditEntry is a row of the audit log.
+type AuditEntry struct {
+	ID     int64  `json:"id"`
+	At     string `json:"at"`
+	Action string `json:"action"`
+	Set    string `json:"set,omitempty"`
+	Detail string `json:"detail,omitempty"`
+	Actor
+	PrevHash string `json:"prev_hash"`
+	Hash     string `json:"hash"`
+}
+
+// hash is the SHA-256 of the entry's fields and the previous entry's hash.
+func (e AuditEntry) hash() string {
+	h := sha256.New()
+	for _, f := range []string{e.PrevHash, e.At, e.Action, e.Set, e.Detail, e.Profile, e.OSUser, e.Host} {
+		_, _ = h.Write([]byte(f))
+		_, _ = h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// appendAuditTx appends an entry by r's actor to the audit log within trx,
+// chained to the last entry.
+func (r *Repository) appendAuditTx(trx *sql.Tx, action, set, detail string) error {
+	e := AuditEntry{At: time.Now().UTC().Format("2006-01-02 15:04:05"), Action: action, Set: set, Detail: detail, Actor: r.actor}
+	err := trx.QueryRow("SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1").Scan(&e.PrevHash)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("read audit log: %w", err)
+	}
+	e.Hash = e.hash()
+	if _, err := trx.Exec(`INSERT INTO audit_log (at, action, set_name, detail, profile, os_user, host, prev_hash, hash)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`, e.At, e.Action, e.Set, e.Detail, e.Profile, e.OSUser, e.Host, e.PrevHash, e.Hash); err != nil {
+		return fmt.Errorf("insert audit entry: %w", err)
+	}
+	return nil
+}
+
+// setNameTx returns the name of the set with the given ID, or "".
+func setNameTx(trx *sql.Tx, id int64) (string, error) {
+	var name string
+	if err := trx.QueryRow("SELECT name FROM command_sets WHERE id = ?", id).Scan(&name); err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+	return name, nil
+}
+
+func commandCount(commands []string) string {
+	return fmt.Sprintf("%d command(s)", len(commands))
+}
+
+// renamedFrom appends the old name to detail when a set was renamed.
+func renamedFrom(oldName, newName, detail string) string {
+	if oldName != "" && oldName != newName {
+		return detail + ", renamed from " + oldName
+	}
+	return detail
+}
+
+// audited runs write in a transaction that also records it in the audit log.
+func (r *Repository) audited(action, set, detail string, write func(trx *sql.Tx) error) error {
+	trx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = trx.Rollback() }()
+	if err := write(trx); err != nil {
+		return err
+	}
+	if err := r.appendAuditTx(trx, action, set, detail); err != nil {
+		return err
+	}
+	return trx.Commit()
+}
+
+// ListAudit returns up to limit audit entries, newest first, of the named
+// set or of everything when set is empty. A limit <= 0 returns all.
+func (r *Repository) ListAudit(set string, limit int) ([]AuditEntry, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := r.db.Query(`SELECT id, at, action, set_name, detail, profile, os_user, host, prev_hash, hash
+		FROM audit_log WHERE ? = '' OR set_name = ? ORDER BY id DESC LIMIT ?`, set, set, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var out []AuditEntry
+	for rows.Next() {
+		var e AuditEntry
+		if err := rows.Scan(&e.ID, &e.At, &e.Action, &e.Set, &e.Detail, &e.Profile, &e.OSUser, &e.Host, &e.PrevHash, &e.Hash); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// AuditProblem is an inconsistency VerifyAudit found at an entry.
+type AuditProblem struct {
+	ID      int64  `json:"id"`
+	Problem string `json:"problem"`
+}
+
+// AuditReport is the result of VerifyAudit.
+type AuditReport struct {
+	Entries int `json:"entries"`
+	// Head is the hash of the last entry; recording it elsewhere lets a later
+	// verify detect a log rewritten from that point on.
+	Head     string         `json:"head"`
+	Problems []AuditProblem `json:"problems"`
+}
+
+// VerifyAudit walks the audit log and reports entries whose hash does not
+// match their fields (edited), whose previous hash does not match the entry
+// before them (entries deleted in between or inserted), and entries deleted
+// from the end.
+func (r *Repository) VerifyAudit() (AuditReport, error) {
+	rep := AuditReport{Problems: []AuditProblem{}}
+	rows, err := r.db.Query(`SELECT id, at, action, set_name, detail, profile, os_user, host, prev_hash, hash
+		FROM audit_log ORDER BY id`)
+	if err != nil {
+		return rep, err
+	}
+	defer func() { _ = rows.Close() }()
+	var last int64
+	for rows.Next() {
+		var e AuditEntry
+		if err := rows.Scan(&e.ID, &e.At, &e.Action, &e.Set, &e.Detail, &e.Profile, &e.OSUser, &e.Host, &e.PrevHash, &e.Hash); err != nil {
+			return rep, err
+		}
+		rep.Entries++
+		if e.PrevHash != rep.Head {
+			p := fmt.Sprintf("does not follow entry %d: entries were deleted or inserted before it", last)
+			if last == 0 {
+				p = "is not the first entry: earlier entries were deleted"
+			}
+			rep.Problems = append(rep.Problems, AuditProblem{ID: e.ID, Problem: p})
+		}
+		if e.hash() != e.Hash {
+			rep.Problems = append(rep.Problems, AuditProblem{ID: e.ID, Problem: "was edited: its hash does not match its contents"})
+		}
+		rep.Head, last = e.Hash, e.ID
+	}
+	if err := rows.Err(); err != nil {
+		return rep, err
+	}
+	var seq sql.NullInt64
+	if err := r.db.QueryRow("SELECT seq FROM sqlite_sequence WHERE name = 'audit_log'").Scan(&seq); err != nil && err != sql.ErrNoRows {
+		return rep, err
+	}
+	if seq.Int64 > last {
+		rep.Problems = append(rep.Problems, AuditProblem{ID: last, Problem: fmt.Sprintf("entries %d to %d after it were deleted", last+1, seq.Int64)})
+	}
+	return rep, nil
+}
@@ -0,0 +1,114 @@
+package registry
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditRecordsMutationsAndRuns(t *testing.T) {
+	r := setupTestDB(t)
+	r.SetActor(Actor{Profile: "Ada <ada@example.com>", OSUser: "ada", Host: "box"})
+	id, err := r.CreateCommandSet("build", nil, nil, nil, []string{"echo a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ReplaceCommands(id, []string{"echo b"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ApplyVersionByName("build", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.AddTagToCommandSet(id, "ci"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.DeleteVersionByName("build", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.StartRun("r1", "build", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.FinishRun("r1", 0, time.Second, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.AddSchedule("build", "@daily", nil, 0, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.DeleteCommandSet("build"); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := r.ListAudit("build", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		got = append(got, e.Action+": "+e.Detail)
+		if e.Actor.String() != "Ada <ada@example.com> (ada@box)" {
+			t.Fatalf("entry %d attributed to %q", e.ID, e.Actor)
+		}
+	}
+	want := []string{
+		"create: 1 command(s)", "update: 1 command(s)", "rollback: to version 1", "tag: added ci",
+		"delete-version: version 2", "run: run r1 (manual)", "run-finished: run r1 ok (exit 0)",
+		"settings: added schedule @daily", "delete: 1 command(s)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected entries:\n%s", strings.Join(got, "\n"))
+	}
+	if limited, _ := r.ListAudit("", 2); len(limited) != 2 || limited[0].Action != AuditDelete {
+		t.Fatalf("unexpected limited list %+v", limited)
+	}
+	rep, err := r.VerifyAudit()
+	if err != nil || len(rep.Problems) != 0 || rep.Entries != len(want) || rep.Head != entries[0].Hash {
+		t.Fatalf("unexpected report %+v %v", rep, err)
+	}
+}
+
+func TestAuditIsAppendOnlyAndTamperEvident(t *testing.T) {
+	r := setupTestDB(t)
+	for _, name := range []string{"a", "b", "c", "d"} {
+		if _, err := r.CreateCommandSet(name, nil, nil, nil, []string{"echo " + name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := r.db.Exec("UPDATE audit_log SET detail = 'x' WHERE id = 1"); err == nil || !strings.Contains(err.Error(), "append-only") {
+		t.Fatalf("expected updates to be refused, got %v", err)
+	}
+	if _, err := r.db.Exec("DELETE FROM audit_log WHERE id = 1"); err == nil {
+		t.Fatal("expected deletes to be refused")
+	}
+
+	// Bypass the triggers the way someone editing the file would.
+	for _, q := range []string{
+		"DROP TRIGGER audit_log_no_update", "DROP TRIGGER audit_log_no_delete",
+		"UPDATE audit_log SET os_user = 'mallory' WHERE id = 1",
+		"DELETE FROM audit_log WHERE id = 3",
+		"DELETE FROM audit_log WHERE id = 4",
+	} {
+		if _, err := r.db.Exec(q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+	rep, err := r.VerifyAudit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var problems []string
+	for _, p := range rep.Problems {
+		problems = append(problems, p.Problem)
+	}
+	if len(problems) != 2 || !strings.Contains(problems[0], "edited") || !strings.Contains(problems[1], "entries 3 to 4 after it were deleted") {
+		t.Fatalf("unexpected problems %q", problems)
+	}
+
+	if _, err := r.db.Exec("DELETE FROM audit_log WHERE id = 1"); err != nil {
+		t.Fatal(err)
+	}
+	rep, _ = r.VerifyAudit()
+	if len(rep.Problems) != 2 || rep.Problems[0].ID != 2 || !strings.Contains(rep.Problems[0].Problem, "earlier entries were deleted") {
+		t.Fatalf("unexpected problems %+v", rep.Problems)
+	}
+}
@@ -0,0 +1,77 @@
+package registry
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// setIDAndSteps returns the id and number of commands of the named set.
+func (r *Repository) setIDAndSteps(name string) (int64, int, error) {
+	var id int64
+	var n int
+	err := r.db.QueryRow(`SELECT cs.id, (SELECT COUNT(*) FROM commands c WHERE c.command_set_id = cs.id)
+		FROM command_sets cs WHERE cs.name = ?`, name).Scan(&id, &n)
+	if err != nil {
+		return 0, 0, fmt.Errorf("command set not found: %s", name)
+	}
+	return id, n, nil
+}
+
+// SetBreakpoint marks step (1-based) of the named set so every run pauses
+// before executing it. Setting an existing breakpoint is a no-op.
+func (r *Repository) SetBreakpoint(name string, step int) error {
+	id, n, err := r.setIDAndSteps(name)
+	if err != nil {
+		return err
+	}
+	if step < 1 || step > n {
+		return fmt.Errorf("step %d out of range (set %s has %d steps)", step, name, n)
+	}
+	return r.audited(AuditSettings, name, fmt.Sprintf("set breakpoint on step %d", step), func(trx *sql.Tx) error {
+		_, err := trx.Exec("INSERT OR IGNORE INTO breakpoints (command_set_id, position) VALUES (?, ?)", id, step)
+		return err
+	})
+}
+
+// ClearBreakpoint removes the breakpoint on step of the named set. A step of
+// 0 clears every breakpoint of the set.
+func (r *Repository) ClearBreakpoint(name string, step int) error {
+	id, _, err := r.setIDAndSteps(name)
+	if err != nil {
+		return err
+	}
+	if step == 0 {
+		return r.audited(AuditSettings, name, "cleared breakpoints", func(trx *sql.Tx) error {
+			_, err := trx.Exec("DELETE FROM breakpoints WHERE command_set_id = ?", id)
+			return err
+		})
+	}
+	return r.audited(AuditSettings, name, fmt.Sprintf("cleared breakpoint on step %d", step), func(trx *sql.Tx) error {
+		_, err := trx.Exec("DELETE FROM breakpoints WHERE command_set_id = ? AND position = ?", id, step)
+		return err
+	})
+}
+
+// ListBreakpoints returns the breakpoint steps of the named set in ascending
+// order. Breakpoints beyond the current number of steps (left behind after
+// commands were removed) are omitted.
+func (r *Repository) ListBreakpoints(name string) ([]int, error) {
+	id, n, err := r.setIDAndSteps(name)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.Query("SELECT position FROM breakpoints WHERE command_set_id = ? AND position <= ? ORDER BY position ASC", id, n)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var out []int
+	for rows.Next() {
+		var p int
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,41 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBreakpoints(t *testing.T) {
+	r := setupTestDB(t)
+	id, err := r.CreateCommandSet("bp", nil, nil, nil, []string{"a", "b", "c"})
+	if err != nil {
+		t.Fatalf("CreateCommandSet: %v", err)
+	}
+	for _, s := range []int{3, 1, 3} {
+		if err := r.SetBreakpoint("bp", s); err != nil {
+			t.Fatalf("SetBreakpoint(%d): %v", s, err)
+		}
+	}
+	if err := r.SetBreakpoint("bp", 4); err == nil {
+		t.Fatalf("expected out-of-range error")
+	}
+	if got, _ := r.ListBreakpoints("bp"); !reflect.DeepEqual(got, []int{1, 3}) {
+		t.Fatalf("unexpected breakpoints: %v", got)
+	}
+	// steps removed by an edit are hidden
+	if err := r.ReplaceCommands(id, []string{"a", "b"}); err != nil {
+		t.Fatalf("ReplaceCommands: %v", err)
+	}
+	if got, _ := r.ListBreakpoints("bp"); !reflect.DeepEqual(got, []int{1}) {
+		t.Fatalf("unexpected breakpoints after edit: %v", got)
+	}
+	if err := r.ClearBreakpoint("bp", 0); err != nil {
+		t.Fatalf("ClearBreakpoint: %v", err)
+	}
+	if got, _ := r.ListBreakpoints("bp"); len(got) != 0 {
+		t.Fatalf("expected no breakpoints, got %v", got)
+	}
+	if _, err := r.ListBreakpoints("missing"); err == nil {
+		t.Fatalf("expected error for missing set")
+	}
+}
@@ -0,0 +1,208 @@
+package registry
+
+import (
+	"database/sql"
+	"sort"
+	"sync"
+)
+
+// Change events.
+const (
+	ChangeSave   = "save"
+	ChangeDelete = "delete"
+)
+
+// Change describes a change to a command set, for ChangeHooks.
+type Change struct {
+	// Event is ChangeSave or ChangeDelete.
+	Event string
+	// Op is the operation: create, update, rollback, tag or delete.
+	Op string
+	// Set is the set's name after the change; OldName is set on renames.
+	Set     string
+	OldName string
+	// Changed names the fields that change: name, description, author_name,
+	// author_email, tags, commands.
+	Changed []string
+	// Description and Commands are the set's after a save and before a delete.
+	Description string
+	Commands    []string
+}
+
+// ChangeHook is told about changes to command sets. Before is called before
+// a change is written and refuses it by returning an error; After is called
+// once it is committed.
+type ChangeHook interface {
+	Before(Change) error
+	After(Change)
+}
+
+// CommandScanner inspects the commands CreateCommandSet and
+// ReplaceCommands are about to write for a set. It returns the commands to
+// write instead, e.g. with secrets replaced by parameters, or an error to
+// refuse the write.
+type CommandScanner func(set string, commands []string) ([]string, error)
+
+var (
+	defaultHookMu  sync.Mutex
+	defaultHook    ChangeHook
+	defaultScanner CommandScanner
+)
+
+// SetDefaultChangeHook installs h on every Repository created afterwards;
+// nil removes it.
+func SetDefaultChangeHook(h ChangeHook) {
+	defaultHookMu.Lock()
+	defer defaultHookMu.Unlock()
+	defaultHook = h
+}
+
+func currentDefaultHook() ChangeHook {
+	defaultHookMu.Lock()
+	defer defaultHookMu.Unlock()
+	return defaultHook
+}
+
+// SetChangeHook installs h on r; nil removes it.
+func (r *Repository) SetChangeHook(h ChangeHook) { r.hook = h }
+
+// SetDefaultCommandScanner installs s on every Repository created
+// afterwards; nil removes it.
+func SetDefaultCommandScanner(s CommandScanner) {
+	defaultHookMu.Lock()
+	defer defaultHookMu.Unlock()
+	defaultScanner = s
+}
+
+func currentDefaultScanner() CommandScanner {
+	defaultHookMu.Lock()
+	defer defaultHookMu.Unlock()
+	return defaultScanner
+}
+
+// SetCommandScanner installs s on r; nil removes it.
+func (r *Repository) SetCommandScanner(s CommandScanner) { r.scan = s }
+
+// ScanCommands applies r's CommandScanner to commands about to be written
+// for set; without one it returns commands unchanged. Writers that bypass
+// CreateCommandSet and ReplaceCommands, such as the importer, call it
+// themselves.
+func (r *Repository) ScanCommands(set string, commands []string) ([]string, error) {
+	if r.scan == nil {
+		return commands, nil
+	}
+	return r.scan(set, commands)
+}
+
+// setByID returns the set with the given ID, or nil.
+func (r *Repository) setByID(id int64) (*CommandSet, error) {
+	var name string
+	if err := r.db.QueryRow("SELECT name FROM command_sets WHERE id = ?", id).Scan(&name); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return r.GetCommandSetByName(name)
+}
+
+// proposed is the state of a set a change would produce.
+type proposed struct {
+	name, description, authorName, authorEmail string
+	tags, commands                             []string
+}
+
+func proposedOf(cs *CommandSet) proposed {
+	p := proposed{
+		name: cs.Name, description: cs.Description.String, authorName: cs.AuthorName.String,
+		authorEmail: cs.AuthorEmail.String, tags: append([]string(nil), cs.Tags...),
+	}
+	for _, c := range cs.Commands {
+		p.commands = append(p.commands, c.Command)
+	}
+	return p
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func sameStrings(a, b []string, ordered bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	if !ordered {
+		a, b = append([]string(nil), a...), append([]string(nil), b...)
+		sort.Strings(a)
+		sort.Strings(b)
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// saveChange describes going from cur (nil when creating) to next.
+func saveChange(op string, cur *CommandSet, next proposed) Change {
+	var old proposed
+	if cur != nil {
+		old = proposedOf(cur)
+	}
+	c := Change{Event: ChangeSave, Op: op, Set: next.name, Description: next.description, Commands: next.commands}
+	if cur != nil && old.name != next.name {
+		c.OldName = old.name
+	}
+	if old.name != next.name {
+		c.Changed = append(c.Changed, "name")
+	}
+	if old.description != next.description {
+		c.Changed = append(c.Changed, "description")
+	}
+	if old.authorName != next.authorName {
+		c.Changed = append(c.Changed, "author_name")
+	}
+	if old.authorEmail != next.authorEmail {
+		c.Changed = append(c.Changed, "author_email")
+	}
+	if !sameStrings(old.tags, next.tags, false) {
+		c.Changed = append(c.Changed, "tags")
+	}
+	if !sameStrings(old.commands, next.commands, true) {
+		c.Changed = append(c.Changed, "commands")
+	}
+	return c
+}
+
+// beforeChange builds the change for the set with the given ID with edit
+// applied, and asks the hook whether it may happen. It returns ok=false
+// when there is no hook or no such set, in which case the caller just
+// writes.
+func (r *Repository) beforeChange(id int64, op string, edit func(*proposed)) (Change, bool, error) {
+	if r.hook == nil {
+		return Change{}, false, nil
+	}
+	cur, err := r.setByID(id)
+	if err != nil || cur == nil {
+		return Change{}, false, err
+	}
+	next := proposedOf(cur)
+	edit(&next)
+	c := saveChange(op, cur, next)
+	if err := r.hook.Before(c); err != nil {
+		return Change{}, false, err
+	}
+	return c, true, nil
+}
+
+// afterChange reports a committed change when beforeChange approved it.
+func (r *Repository) afterChange(c Change, ok bool, err error) error {
+	if err == nil && ok {
+		r.hook.After(c)
+	}
+	return err
+}
@@ -0,0 +1,156 @@
+package registry
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type recordingHook struct {
+	before, after []Change
+	refuse        error
+}
+
+func (h *recordingHook) Before(c Change) error {
+	h.before = append(h.before, c)
+	return h.refuse
+}
+
+func (h *recordingHook) After(c Change) { h.after = append(h.after, c) }
+
+func TestChangeHookSeesMutations(t *testing.T) {
+	r := setupTestDB(t)
+	h := &recordingHook{}
+	r.SetChangeHook(h)
+
+	desc := "deploy it"
+	id, err := r.CreateCommandSet("deploy", &desc, nil, nil, []string{"echo a", " "})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := r.AddTagToCommandSet(id, "prod"); err != nil {
+		t.Fatalf("tag: %v", err)
+	}
+	if err := r.UpdateCommandSetAndReplaceCommands(id, "ship", &desc, nil, nil, []string{"prod"}, []string{"echo b"}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if err := r.ApplyVersionByName("ship", 1); err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	if err := r.DeleteCommandSet("ship"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := r.DeleteCommandSet("missing"); err != nil {
+		t.Fatalf("delete missing: %v", err)
+	}
+
+	want := []Change{
+		{Event: ChangeSave, Op: "create", Set: "deploy", Changed: []string{"name", "description", "commands"}, Description: desc, Commands: []string{"echo a"}},
+		{Event: ChangeSave, Op: "tag", Set: "deploy", Changed: []string{"tags"}, Description: desc, Commands: []string{"echo a"}},
+		{Event: ChangeSave, Op: "update", Set: "ship", OldName: "deploy", Changed: []string{"name", "commands"}, Description: desc, Commands: []string{"echo b"}},
+		{Event: ChangeSave, Op: "rollback", Set: "ship", Changed: []string{"commands"}, Description: desc, Commands: []string{"echo a"}},
+		{Event: ChangeDelete, Op: "delete", Set: "ship", Description: desc, Commands: []string{"echo a"}},
+	}
+	if !reflect.DeepEqual(h.before, want) {
+		t.Fatalf("before:\n got %+v\nwant %+v", h.before, want)
+	}
+	if !reflect.DeepEqual(h.after, want) {
+		t.Fatalf("after:\n got %+v\nwant %+v", h.after, want)
+	}
+}
+
+func TestChangeHookRefusesChange(t *testing.T) {
+	r := setupTestDB(t)
+	id, err := r.CreateCommandSet("deploy", nil, nil, nil, []string{"echo a"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	refused := errors.New("no")
+	h := &recordingHook{refuse: refused}
+	r.SetChangeHook(h)
+
+	if _, err := r.CreateCommandSet("other", nil, nil, nil, nil); !errors.Is(err, refused) {
+		t.Fatalf("create: expected refusal, got %v", err)
+	}
+	if err := r.ReplaceCommands(id, []string{"echo b"}); !errors.Is(err, refused) {
+		t.Fatalf("replace: expected refusal, got %v", err)
+	}
+	if err := r.DeleteCommandSet("deploy"); !errors.Is(err, refused) {
+		t.Fatalf("delete: expected refusal, got %v", err)
+	}
+	if len(h.after) != 0 {
+		t.Fatalf("refused changes must not be reported as done: %+v", h.after)
+	}
+	cs, err := r.GetCommandSetByName("deploy")
+	if err != nil || cs == nil || len(cs.Commands) != 1 || cs.Commands[0].Command != "echo a" {
+		t.Fatalf("set must be unchanged, got %+v %v", cs, err)
+	}
+	if other, _ := r.GetCommandSetByName("other"); other != nil {
+		t.Fatalf("refused create must not write")
+	}
+}
+
+func TestDefaultChangeHook(t *testing.T) {
+	h := &recordingHook{}
+	SetDefaultChangeHook(h)
+	t.Cleanup(func() { SetDefaultChangeHook(nil) })
+	r := setupTestDB(t)
+	if _, err := r.CreateCommandSet("deploy", nil, nil, nil, nil); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if len(h.after) != 1 {
+		t.Fatalf("expected the default hook on new repositories, got %+v", h.after)
+	}
+}
+
+func TestCommandScannerRewritesAndRefuses(t *testing.T) {
+	r := setupTestDB(t)
+	var seen []string
+	r.SetCommandScanner(func(set string, commands []string) ([]string, error) {
+		seen = append(seen, set)
+		out := make([]string, len(commands))
+		for i, c := range commands {
+			if c == "refuse" {
+				return nil, errors.New("refused")
+			}
+			out[i] = strings.ReplaceAll(c, "hunter2", "{{password}}")
+		}
+		return out, nil
+	})
+
+	id, err := r.CreateCommandSet("login", nil, nil, nil, []string{"login -p hunter2"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := r.ReplaceCommands(id, []string{"echo ok", "login -p hunter2"}); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	cs, err := r.GetCommandSetByName("login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cs.Commands[1].Command; got != "login -p {{password}}" {
+		t.Fatalf("expected the scanned command to be stored, got %q", got)
+	}
+	versions, err := r.ListVersions(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range versions {
+		for _, c := range v.Commands {
+			if strings.Contains(c, "hunter2") {
+				t.Fatalf("version %d kept the unscanned command %q", v.Version, c)
+			}
+		}
+	}
+	if !reflect.DeepEqual(seen, []string{"login", "login"}) {
+		t.Fatalf("unexpected scanned sets %v", seen)
+	}
+	if err := r.ReplaceCommands(id, []string{"refuse"}); err == nil {
+		t.Fatal("expected the scanner to refuse the write")
+	}
+	if cs, _ := r.GetCommandSetByName("login"); len(cs.Commands) != 2 {
+		t.Fatalf("refused write changed the commands: %+v", cs.Commands)
+	}
+}
@@ -0,0 +1,116 @@
+package registry
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Job status values stored in the jobs table. JobStatusLost marks jobs whose
+// process disappeared without recording a result.
+const (
+	JobStatusRunning = "running"
+	JobStatusOK      = "ok"
+	JobStatusFailed  = "failed"
+	JobStatusKilled  = "killed"
+	JobStatusLost    = "lost"
+)
+
+// Job is a background run started with `krnr run --detach`.
+type Job struct {
+	ID         string
+	SetName    string
+	PID        int
+	Status     string
+	StartedAt  string
+	FinishedAt sql.NullString
+	ExitCode   sql.NullInt64
+	RunID      sql.NullString
+	OutputPath string
+}
+
+// CreateJob records a new running job before its process is started.
+func (r *Repository) CreateJob(id, setName, outputPath string) error {
+	_, err := r.db.Exec(`INSERT INTO jobs (id, set_name, status, started_at, output_path)
+		VALUES (?, ?, ?, datetime('now'), ?)`, id, setName, JobStatusRunning, outputPath)
+	if err != nil {
+		return fmt.Errorf("insert job: %w", err)
+	}
+	return nil
+}
+
+// SetJobPID stores the process ID of a started job.
+func (r *Repository) SetJobPID(id string, pid int) error {
+	_, err := r.db.Exec("UPDATE jobs SET pid = ? WHERE id = ?", pid, id)
+	return err
+}
+
+// SetJobRunID links a job to the run it is executing.
+func (r *Repository) SetJobRunID(id, runID string) error {
+	_, err := r.db.Exec("UPDATE jobs SET run_id = ? WHERE id = ?", runID, id)
+	return err
+}
+
+// FinishJob records the result of a running job. Jobs that were already
+// finished (e.g., killed) keep their status.
+func (r *Repository) FinishJob(id, status string, exitCode int) error {
+	_, err := r.db.Exec(`UPDATE jobs SET status = ?, exit_code = ?, finished_at = datetime('now')
+		WHERE id = ? AND status = ?`, status, exitCode, id, JobStatusRunning)
+	return err
+}
+
+// MarkJobFinished sets the status of a running job without an exit code and
+// reports whether the job was still running.
+func (r *Repository) MarkJobFinished(id, status string) (bool, error) {
+	res, err := r.db.Exec(`UPDATE jobs SET status = ?, finished_at = datetime('now')
+		WHERE id = ? AND status = ?`, status, id, JobStatusRunning)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+const jobColumns = "id, set_name, pid, status, started_at, finished_at, exit_code, run_id, output_path"
+
+func scanJob(sc interface{ Scan(...any) error }) (Job, error) {
+	var j Job
+	err := sc.Scan(&j.ID, &j.SetName, &j.PID, &j.Status, &j.StartedAt, &j.FinishedAt, &j.ExitCode, &j.RunID, &j.OutputPath)
+	return j, err
+}
+
+// GetJob returns the job with the given id, or nil when it does not exist.
+func (r *Repository) GetJob(id string) (*Job, error) {
+	j, err := scanJob(r.db.QueryRow("SELECT "+jobColumns+" FROM jobs WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// ListJobs returns running jobs and up to limit most recent finished jobs,
+// newest first. A limit <= 0 returns every job.
+func (r *Repository) ListJobs(limit int) ([]Job, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := r.db.Query(`SELECT `+jobColumns+` FROM jobs WHERE status = ?
+		UNION ALL
+		SELECT * FROM (SELECT `+jobColumns+` FROM jobs WHERE status != ? ORDER BY started_at DESC, rowid DESC LIMIT ?)
+		ORDER BY started_at DESC`, JobStatusRunning, JobStatusRunning, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var out []Job
+	for rows.Next() {
+		j, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, j)
+	}
+	return out, rows.Err()
+}
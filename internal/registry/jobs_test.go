@@ -0,0 +1,52 @@
+package registry
+
+import "testing"
+
+func TestJobLifecycle(t *testing.T) {
+	r := setupTestDB(t)
+	if err := r.CreateJob("a1", "nightly", "/tmp/a1.log"); err != nil {
+		t.Fatalf("CreateJob: %v", err)
+	}
+	if err := r.CreateJob("b2", "nightly", "/tmp/b2.log"); err != nil {
+		t.Fatalf("CreateJob: %v", err)
+	}
+	if err := r.SetJobPID("a1", 4242); err != nil {
+		t.Fatalf("SetJobPID: %v", err)
+	}
+	if err := r.SetJobRunID("a1", "run-1"); err != nil {
+		t.Fatalf("SetJobRunID: %v", err)
+	}
+	// a killed job keeps its status when the process reports its exit
+	if ok, err := r.MarkJobFinished("b2", JobStatusKilled); err != nil || !ok {
+		t.Fatalf("MarkJobFinished: %v %v", ok, err)
+	}
+	if err := r.FinishJob("b2", JobStatusFailed, 1); err != nil {
+		t.Fatalf("FinishJob: %v", err)
+	}
+	if ok, _ := r.MarkJobFinished("b2", JobStatusLost); ok {
+		t.Fatalf("finished job must not be marked again")
+	}
+
+	j, err := r.GetJob("a1")
+	if err != nil || j == nil || j.PID != 4242 || j.RunID.String != "run-1" || j.Status != JobStatusRunning {
+		t.Fatalf("unexpected job: %+v %v", j, err)
+	}
+	if j, _ := r.GetJob("b2"); j.Status != JobStatusKilled {
+		t.Fatalf("expected killed status, got %+v", j)
+	}
+	if j, _ := r.GetJob("missing"); j != nil {
+		t.Fatalf("expected nil for missing job")
+	}
+
+	// running jobs are always listed; finished ones are limited
+	list, err := r.ListJobs(0)
+	if err != nil || len(list) != 2 {
+		t.Fatalf("ListJobs: %v %v", list, err)
+	}
+	if err := r.FinishJob("a1", JobStatusOK, 0); err != nil {
+		t.Fatalf("FinishJob: %v", err)
+	}
+	if list, _ := r.ListJobs(1); len(list) != 1 {
+		t.Fatalf("expected limit to apply to finished jobs, got %v", list)
+	}
+}
@@ -0,0 +1,101 @@
+package registry
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Lease is the claim of a running `krnr run` on an exclusive command set.
+type Lease struct {
+	SetName string
+	// Token identifies the holder (its run ID) so only it can renew or
+	// release the lease.
+	Token       string
+	Owner       string
+	PID         int
+	Host        string
+	AcquiredAt  string
+	HeartbeatAt string
+}
+
+// SetExclusive marks the named set as exclusive (or not).
+func (r *Repository) SetExclusive(name string, exclusive bool) error {
+	detail := "exclusive off"
+	if exclusive {
+		detail = "exclusive on"
+	}
+	return r.audited(AuditSettings, name, detail, func(trx *sql.Tx) error {
+		res, err := trx.Exec("UPDATE command_sets SET exclusive = ? WHERE name = ?", exclusive, name)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return fmt.Errorf("command set not found: %s", name)
+		}
+		return nil
+	})
+}
+
+// sqliteOffset renders a duration as a SQLite datetime modifier.
+func sqliteOffset(d time.Duration) string {
+	return fmt.Sprintf("-%d seconds", int(d.Seconds()))
+}
+
+// AcquireLease claims the lease for l.SetName. A lease whose heartbeat is
+// older than staleAfter is taken over. When another holder has a live lease
+// it returns false and that holder.
+func (r *Repository) AcquireLease(l Lease, staleAfter time.Duration) (bool, *Lease, error) {
+	res, err := r.db.Exec(`INSERT INTO leases (set_name, token, owner, pid, host, acquired_at, heartbeat_at)
+		VALUES (?, ?, ?, ?, ?, datetime('now'), datetime('now'))
+		ON CONFLICT(set_name) DO UPDATE SET token = excluded.token, owner = excluded.owner, pid = excluded.pid,
+			host = excluded.host, acquired_at = excluded.acquired_at, heartbeat_at = excluded.heartbeat_at
+		WHERE leases.heartbeat_at < datetime('now', ?)`,
+		l.SetName, l.Token, l.Owner, l.PID, l.Host, sqliteOffset(staleAfter))
+	if err != nil {
+		return false, nil, fmt.Errorf("acquire lease: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return true, nil, nil
+	}
+	holder, err := r.GetLease(l.SetName)
+	if err != nil {
+		return false, nil, err
+	}
+	if holder == nil {
+		// released between the insert attempt and the lookup; try again
+		return r.AcquireLease(l, staleAfter)
+	}
+	return false, holder, nil
+}
+
+// GetLease returns the current lease of setName, or nil when it is free.
+func (r *Repository) GetLease(setName string) (*Lease, error) {
+	var l Lease
+	err := r.db.QueryRow(`SELECT set_name, token, owner, pid, host, acquired_at, heartbeat_at FROM leases WHERE set_name = ?`, setName).
+		Scan(&l.SetName, &l.Token, &l.Owner, &l.PID, &l.Host, &l.AcquiredAt, &l.HeartbeatAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// RenewLease refreshes the heartbeat of a held lease. It returns false when
+// the lease was lost (taken over after going stale, or broken).
+func (r *Repository) RenewLease(setName, token string) (bool, error) {
+	res, err := r.db.Exec(`UPDATE leases SET heartbeat_at = datetime('now') WHERE set_name = ? AND token = ?`, setName, token)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ReleaseLease removes the lease if it is still held with token.
+func (r *Repository) ReleaseLease(setName, token string) error {
+	_, err := r.db.Exec(`DELETE FROM leases WHERE set_name = ? AND token = ?`, setName, token)
+	return err
+}
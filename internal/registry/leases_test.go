@@ -0,0 +1,63 @@
+package registry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLeaseAcquireRenewRelease(t *testing.T) {
+	r := setupTestDB(t)
+	a := Lease{SetName: "deploy", Token: "t1", Owner: "alice", PID: 100, Host: "h1"}
+	b := Lease{SetName: "deploy", Token: "t2", Owner: "bob", PID: 200, Host: "h2"}
+
+	if ok, _, err := r.AcquireLease(a, time.Minute); err != nil || !ok {
+		t.Fatalf("first acquire: %v %v", ok, err)
+	}
+	ok, holder, err := r.AcquireLease(b, time.Minute)
+	if err != nil || ok || holder == nil || holder.Owner != "alice" || holder.PID != 100 || holder.Host != "h1" {
+		t.Fatalf("expected lease held by alice, got %v %+v %v", ok, holder, err)
+	}
+	if ok, _ := r.RenewLease("deploy", "t2"); ok {
+		t.Fatalf("non-holder must not renew")
+	}
+	if ok, _ := r.RenewLease("deploy", "t1"); !ok {
+		t.Fatalf("holder should renew")
+	}
+
+	// a stale lease is taken over and the old holder can no longer renew
+	if _, err := r.db.Exec("UPDATE leases SET heartbeat_at = datetime('now', '-10 minutes')"); err != nil {
+		t.Fatalf("age lease: %v", err)
+	}
+	if ok, _, err := r.AcquireLease(b, time.Minute); err != nil || !ok {
+		t.Fatalf("expected takeover of stale lease: %v %v", ok, err)
+	}
+	if ok, _ := r.RenewLease("deploy", "t1"); ok {
+		t.Fatalf("previous holder must have lost the lease")
+	}
+
+	// only the holder's token releases it
+	_ = r.ReleaseLease("deploy", "t1")
+	if l, _ := r.GetLease("deploy"); l == nil || l.Token != "t2" {
+		t.Fatalf("lease released by wrong token: %+v", l)
+	}
+	_ = r.ReleaseLease("deploy", "t2")
+	if l, _ := r.GetLease("deploy"); l != nil {
+		t.Fatalf("expected free lease, got %+v", l)
+	}
+}
+
+func TestSetExclusive(t *testing.T) {
+	r := setupTestDB(t)
+	if _, err := r.CreateCommandSet("deploy", nil, nil, nil, []string{"echo"}); err != nil {
+		t.Fatalf("CreateCommandSet: %v", err)
+	}
+	if err := r.SetExclusive("deploy", true); err != nil {
+		t.Fatalf("SetExclusive: %v", err)
+	}
+	if cs, _ := r.GetCommandSetByName("deploy"); !cs.Exclusive {
+		t.Fatalf("expected exclusive set")
+	}
+	if err := r.SetExclusive("missing", true); err == nil {
+		t.Fatalf("expected error for missing set")
+	}
+}
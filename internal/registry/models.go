@@ -14,6 +14,8 @@ type CommandSet struct {
 	LastRun     sql.NullString
 	Commands    []Command
 	Tags        []string
+	// Exclusive sets may only run once at a time (see leases.go).
+	Exclusive bool
 }
 
 // Command is a single shell command within a CommandSet.
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+
+	"github.com/kballard/go-shellquote"
 )
 
 var paramRe = regexp.MustCompile(`{{\s*([a-zA-Z0-9_.-]+)\s*}}`)
@@ -22,22 +24,51 @@ func FindParams(s string) []string {
 	return out
 }
 
+// FindParamIndex returns the byte offsets of the parameter placeholders in
+// s: for each, the start and end of the placeholder and of its name.
+func FindParamIndex(s string) [][]int {
+	return paramRe.FindAllStringSubmatchIndex(s, -1)
+}
+
 // ApplyParams replaces parameter placeholders in s using values from params.
 // If a parameter is missing, an error is returned listing missing keys.
 func ApplyParams(s string, params map[string]string) (string, error) {
+	return applyParams(s, params, nil)
+}
+
+// ApplyParamsQuoted is ApplyParams for values that must not be able to
+// change the command, such as values supplied over HTTP: each value is
+// quoted for a POSIX shell so it reaches the command as literal text,
+// whether its placeholder is unquoted, inside single quotes or inside
+// double quotes.
+func ApplyParamsQuoted(s string, params map[string]string) (string, error) {
+	return applyParams(s, params, quoteContexts(s))
+}
+
+// applyParams substitutes params into s. When quote is non-nil it maps the
+// byte offset of each placeholder to the quoting context it appears in, and
+// values are quoted for it.
+func applyParams(s string, params map[string]string, quote map[int]byte) (string, error) {
 	missing := []string{}
-	result := paramRe.ReplaceAllStringFunc(s, func(match string) string {
-		sub := paramRe.FindStringSubmatch(match)
-		if len(sub) < 2 {
-			return match
-		}
-		name := sub[1]
-		if v, ok := params[name]; ok {
-			return v
-		}
-		missing = append(missing, name)
-		return match
-	})
+	var b strings.Builder
+	last := 0
+	for _, m := range paramRe.FindAllStringSubmatchIndex(s, -1) {
+		b.WriteString(s[last:m[0]])
+		last = m[1]
+		name := s[m[2]:m[3]]
+		v, ok := params[name]
+		if !ok {
+			missing = append(missing, name)
+			b.WriteString(s[m[0]:m[1]])
+			continue
+		}
+		if quote != nil {
+			v = quoteIn(quote[m[0]], v)
+		}
+		b.WriteString(v)
+	}
+	b.WriteString(s[last:])
+	result := b.String()
 	if len(missing) > 0 {
 		// dedupe
 		uniq := map[string]bool{}
@@ -52,3 +83,51 @@ func ApplyParams(s string, params map[string]string) (string, error) {
 	}
 	return result, nil
 }
+
+// quoteContexts returns, for the offset of every placeholder in s, the
+// quote it is inside of: a single quote, a double quote, or 0 when unquoted.
+func quoteContexts(s string) map[int]byte {
+	starts := map[int]bool{}
+	for _, m := range paramRe.FindAllStringIndex(s, -1) {
+		starts[m[0]] = true
+	}
+	out := map[int]byte{}
+	var in byte
+	for i := 0; i < len(s); i++ {
+		if starts[i] {
+			out[i] = in
+		}
+		c := s[i]
+		switch {
+		case c == '\\' && in != '\'':
+			i++
+		case in == 0 && (c == '\'' || c == '"'):
+			in = c
+		case c == in:
+			in = 0
+		}
+	}
+	return out
+}
+
+// quoteIn quotes v for the given quoting context so the shell reads it as
+// literal text.
+func quoteIn(ctx byte, v string) string {
+	switch ctx {
+	case '\'':
+		return strings.ReplaceAll(v, "'", `'\''`)
+	case '"':
+		var b strings.Builder
+		for _, r := range v {
+			if strings.ContainsRune("\\\"$`", r) {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		}
+		return b.String()
+	}
+	if v == "" {
+		return "''"
+	}
+	return shellquote.Join(v)
+}
@@ -23,3 +23,25 @@ func TestFindAndApplyParams(t *testing.T) {
 		t.Fatalf("expected error for missing param")
 	}
 }
+
+func TestApplyParamsQuoted(t *testing.T) {
+	evil := map[string]string{"v": `x; touch pwned $(id) "q" 'q' \`}
+	cases := map[string]string{
+		"echo {{v}}":      `echo 'x; touch pwned $(id) "q" '\''q'\'' \'`,
+		"echo '{{v}}'":    `echo 'x; touch pwned $(id) "q" '\''q'\'' \'`,
+		`echo "{{v}}"`:    `echo "x; touch pwned \$(id) \"q\" 'q' \\"`,
+		`echo "a\"{{v}}"`: `echo "a\"x; touch pwned \$(id) \"q\" 'q' \\"`,
+	}
+	for tmpl, want := range cases {
+		got, err := ApplyParamsQuoted(tmpl, evil)
+		if err != nil || got != want {
+			t.Errorf("ApplyParamsQuoted(%q) = %q %v, want %q", tmpl, got, err, want)
+		}
+	}
+	if got, _ := ApplyParamsQuoted("echo {{v}} {{e}}", map[string]string{"v": "main", "e": ""}); got != "echo main ''" {
+		t.Errorf("safe values should stay readable, got %q", got)
+	}
+	if _, err := ApplyParamsQuoted("echo {{v}}", nil); err == nil {
+		t.Error("expected an error for a missing parameter")
+	}
+}
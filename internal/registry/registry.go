@@ -10,12 +10,16 @@ import (
 
 // Repository provides CRUD operations for command sets and commands.
 type Repository struct {
-	db *sql.DB
+	db    *sql.DB
+	hook  ChangeHook
+	scan  CommandScanner
+	actor Actor
 }
 
-// NewRepository creates a new Repository using db.
+// NewRepository creates a new Repository using db, with the default
+// ChangeHook, CommandScanner and Actor.
 func NewRepository(db *sql.DB) *Repository {
-	return &Repository{db: db}
+	return &Repository{db: db, hook: currentDefaultHook(), scan: currentDefaultScanner(), actor: currentDefaultActor()}
 }
 
 // CreateCommandSet inserts a new command set and returns its ID.
@@ -24,7 +28,22 @@ func (r *Repository) CreateCommandSet(name string, description *string, authorNa
 	if err := r.validateCreateName(&name); err != nil {
 		return 0, err
 	}
-	return r.createCommandSetTx(name, description, authorName, authorEmail, initialCommands)
+	initialCommands, err := r.ScanCommands(name, initialCommands)
+	if err != nil {
+		return 0, err
+	}
+	if r.hook == nil {
+		return r.createCommandSetTx(name, description, authorName, authorEmail, initialCommands)
+	}
+	c := saveChange("create", nil, proposed{
+		name: name, description: deref(description), authorName: deref(authorName),
+		authorEmail: deref(authorEmail), commands: nonBlank(initialCommands),
+	})
+	if err := r.hook.Before(c); err != nil {
+		return 0, err
+	}
+	id, err := r.createCommandSetTx(name, description, authorName, authorEmail, initialCommands)
+	return id, r.afterChange(c, true, err)
 }
 
 func (r *Repository) validateCreateName(name *string) error {
@@ -75,6 +94,9 @@ func (r *Repository) createCommandSetTx(name string, description *string, author
 	if err := r.recordVersionTx(trx, id, authorName, authorEmail, description, initialCommands, "create"); err != nil {
 		return 0, err
 	}
+	if err := r.appendAuditTx(trx, AuditCreate, name, commandCount(nonBlank(initialCommands))); err != nil {
+		return 0, err
+	}
 	if err := trx.Commit(); err != nil {
 		return 0, err
 	}
@@ -93,15 +115,20 @@ func (r *Repository) validateInsertedNameTx(trx *sql.Tx, id int64, name string)
 	return nil
 }
 
-func (r *Repository) insertInitialCommandsTx(trx *sql.Tx, id int64, initialCommands []string) error {
-	filtered := make([]string, 0, len(initialCommands))
-	for _, c := range initialCommands {
+// nonBlank drops empty and whitespace-only commands.
+func nonBlank(commands []string) []string {
+	filtered := make([]string, 0, len(commands))
+	for _, c := range commands {
 		if strings.TrimSpace(c) == "" {
 			continue
 		}
 		filtered = append(filtered, c)
 	}
-	for i, c := range filtered {
+	return filtered
+}
+
+func (r *Repository) insertInitialCommandsTx(trx *sql.Tx, id int64, initialCommands []string) error {
+	for i, c := range nonBlank(initialCommands) {
 		if _, err := trx.Exec("INSERT INTO commands (command_set_id, position, command) VALUES (?, ?, ?)", id, i+1, c); err != nil {
 			return fmt.Errorf("insert initial command: %w", err)
 		}
@@ -111,18 +138,27 @@ func (r *Repository) insertInitialCommandsTx(trx *sql.Tx, id int64, initialComma
 
 // AddCommand adds a command to a command set at the given position.
 func (r *Repository) AddCommand(commandSetID int64, position int, cmd string) (int64, error) {
-	res, err := r.db.Exec("INSERT INTO commands (command_set_id, position, command) VALUES (?, ?, ?)", commandSetID, position, cmd)
-	if err != nil {
-		return 0, fmt.Errorf("insert command: %w", err)
+	var name string
+	if err := r.db.QueryRow("SELECT name FROM command_sets WHERE id = ?", commandSetID).Scan(&name); err != nil && err != sql.ErrNoRows {
+		return 0, err
 	}
-	return res.LastInsertId()
+	var id int64
+	err := r.audited(AuditUpdate, name, fmt.Sprintf("added step %d", position), func(trx *sql.Tx) error {
+		res, err := trx.Exec("INSERT INTO commands (command_set_id, position, command) VALUES (?, ?, ?)", commandSetID, position, cmd)
+		if err != nil {
+			return fmt.Errorf("insert command: %w", err)
+		}
+		id, err = res.LastInsertId()
+		return err
+	})
+	return id, err
 }
 
 // GetCommandSetByName retrieves a command set and its commands by name.
 func (r *Repository) GetCommandSetByName(name string) (*CommandSet, error) {
-	row := r.db.QueryRow("SELECT id, name, description, author_name, author_email, created_at, last_run FROM command_sets WHERE name = ?", name)
+	row := r.db.QueryRow("SELECT id, name, description, author_name, author_email, created_at, last_run, exclusive FROM command_sets WHERE name = ?", name)
 	var cs CommandSet
-	if err := row.Scan(&cs.ID, &cs.Name, &cs.Description, &cs.AuthorName, &cs.AuthorEmail, &cs.CreatedAt, &cs.LastRun); err != nil {
+	if err := row.Scan(&cs.ID, &cs.Name, &cs.Description, &cs.AuthorName, &cs.AuthorEmail, &cs.CreatedAt, &cs.LastRun, &cs.Exclusive); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
@@ -174,6 +210,16 @@ func (r *Repository) ListCommandSets() ([]CommandSet, error) {
 // UpdateCommandSet updates a command set's metadata (name, description, author fields and tags).
 // It records an update version snapshot of the current commands.
 func (r *Repository) UpdateCommandSet(commandSetID int64, newName string, description *string, authorName *string, authorEmail *string, tags []string) error {
+	c, ok, err := r.beforeChange(commandSetID, "update", func(p *proposed) {
+		p.name, p.description, p.authorName, p.authorEmail, p.tags = newName, deref(description), deref(authorName), deref(authorEmail), tags
+	})
+	if err != nil {
+		return err
+	}
+	return r.afterChange(c, ok, r.updateCommandSet(commandSetID, newName, description, authorName, authorEmail, tags))
+}
+
+func (r *Repository) updateCommandSet(commandSetID int64, newName string, description *string, authorName *string, authorEmail *string, tags []string) error {
 	trx, err := r.db.Begin()
 	if err != nil {
 		return err
@@ -184,6 +230,10 @@ func (r *Repository) UpdateCommandSet(commandSetID int64, newName string, descri
 	if err := r.ensureNameNotTakenTx(trx, newName, commandSetID); err != nil {
 		return err
 	}
+	oldName, err := setNameTx(trx, commandSetID)
+	if err != nil {
+		return err
+	}
 
 	// perform update
 	if _, err := trx.Exec("UPDATE command_sets SET name = ?, description = ?, author_name = ?, author_email = ? WHERE id = ?", newName, description, authorName, authorEmail, commandSetID); err != nil {
@@ -204,6 +254,9 @@ func (r *Repository) UpdateCommandSet(commandSetID int64, newName string, descri
 	if err := r.recordVersionTx(trx, commandSetID, authorName, authorEmail, description, cmds, "update"); err != nil {
 		return err
 	}
+	if err := r.appendAuditTx(trx, AuditUpdate, newName, renamedFrom(oldName, newName, "details and tags")); err != nil {
+		return err
+	}
 
 	return trx.Commit()
 }
@@ -263,6 +316,17 @@ func (r *Repository) readCommandsTx(trx *sql.Tx, commandSetID int64) ([]string,
 // UpdateCommandSetAndReplaceCommands performs an atomic metadata+commands update
 // and records exactly one 'update' version representing the final state.
 func (r *Repository) UpdateCommandSetAndReplaceCommands(commandSetID int64, newName string, description *string, authorName *string, authorEmail *string, tags []string, commands []string) error {
+	c, ok, err := r.beforeChange(commandSetID, "update", func(p *proposed) {
+		p.name, p.description, p.authorName, p.authorEmail = newName, deref(description), deref(authorName), deref(authorEmail)
+		p.tags, p.commands = tags, nonBlank(commands)
+	})
+	if err != nil {
+		return err
+	}
+	return r.afterChange(c, ok, r.updateCommandSetAndReplaceCommands(commandSetID, newName, description, authorName, authorEmail, tags, commands))
+}
+
+func (r *Repository) updateCommandSetAndReplaceCommands(commandSetID int64, newName string, description *string, authorName *string, authorEmail *string, tags []string, commands []string) error {
 	trx, err := r.db.Begin()
 	if err != nil {
 		return err
@@ -274,8 +338,13 @@ func (r *Repository) UpdateCommandSetAndReplaceCommands(commandSetID int64, newN
 		return err
 	}
 
+	oldName, err := setNameTx(trx, commandSetID)
+	if err != nil {
+		return err
+	}
+
 	// perform update and finalize transaction
-	if err := r.updateMetadataAndFinalizeTx(trx, commandSetID, newName, description, authorName, authorEmail, tags, commands); err != nil {
+	if err := r.updateMetadataAndFinalizeTx(trx, commandSetID, oldName, newName, description, authorName, authorEmail, tags, commands); err != nil {
 		return err
 	}
 	return nil
@@ -300,7 +369,7 @@ func (r *Repository) replaceCommandsTx(trx *sql.Tx, commandSetID int64, commands
 	return filtered, nil
 }
 
-func (r *Repository) updateMetadataAndFinalizeTx(trx *sql.Tx, commandSetID int64, newName string, description *string, authorName *string, authorEmail *string, tags []string, commands []string) error {
+func (r *Repository) updateMetadataAndFinalizeTx(trx *sql.Tx, commandSetID int64, oldName, newName string, description *string, authorName *string, authorEmail *string, tags []string, commands []string) error {
 	if _, err := trx.Exec("UPDATE command_sets SET name = ?, description = ?, author_name = ?, author_email = ? WHERE id = ?", newName, description, authorName, authorEmail, commandSetID); err != nil {
 		return err
 	}
@@ -314,11 +383,33 @@ func (r *Repository) updateMetadataAndFinalizeTx(trx *sql.Tx, commandSetID int64
 	if err := r.recordVersionTx(trx, commandSetID, authorName, authorEmail, description, filtered, "update"); err != nil {
 		return err
 	}
+	if err := r.syncTrustTx(trx, commandSetID, filtered, false); err != nil {
+		return err
+	}
+	if err := r.appendAuditTx(trx, AuditUpdate, newName, renamedFrom(oldName, newName, commandCount(filtered))); err != nil {
+		return err
+	}
 	return trx.Commit()
 }
 
 // DeleteCommandSet removes a command set and its commands by name.
 func (r *Repository) DeleteCommandSet(name string) error {
+	if r.hook == nil {
+		return r.deleteCommandSet(name)
+	}
+	cs, err := r.GetCommandSetByName(name)
+	if err != nil || cs == nil {
+		return err
+	}
+	p := proposedOf(cs)
+	c := Change{Event: ChangeDelete, Op: "delete", Set: name, Description: p.description, Commands: p.commands}
+	if err := r.hook.Before(c); err != nil {
+		return err
+	}
+	return r.afterChange(c, true, r.deleteCommandSet(name))
+}
+
+func (r *Repository) deleteCommandSet(name string) error {
 	trx, err := r.db.Begin()
 	if err != nil {
 		return err
@@ -357,9 +448,27 @@ func (r *Repository) DeleteCommandSet(name string) error {
 	if _, err := trx.Exec("DELETE FROM commands WHERE command_set_id = ?", id); err != nil {
 		return err
 	}
+	if _, err := trx.Exec("DELETE FROM breakpoints WHERE command_set_id = ?", id); err != nil {
+		return err
+	}
+	if _, err := trx.Exec("DELETE FROM watches WHERE command_set_id = ?", id); err != nil {
+		return err
+	}
+	if _, err := trx.Exec("DELETE FROM webhooks WHERE command_set_id = ?", id); err != nil {
+		return err
+	}
+	if _, err := trx.Exec("DELETE FROM provenance WHERE command_set_id = ?", id); err != nil {
+		return err
+	}
+	if _, err := trx.Exec("DELETE FROM schedules WHERE command_set_id = ?", id); err != nil {
+		return err
+	}
 	if _, err := trx.Exec("DELETE FROM command_sets WHERE id = ?", id); err != nil {
 		return err
 	}
+	if err := r.appendAuditTx(trx, AuditDelete, name, commandCount(cmds)); err != nil {
+		return err
+	}
 	return trx.Commit()
 }
 
@@ -367,6 +476,24 @@ func (r *Repository) DeleteCommandSet(name string) error {
 // slice of command strings. Existing commands for the set are deleted and the
 // new commands are inserted with positions starting at 1.
 func (r *Repository) ReplaceCommands(commandSetID int64, commands []string) error {
+	if r.scan != nil {
+		var name string
+		if err := r.db.QueryRow("SELECT name FROM command_sets WHERE id = ?", commandSetID).Scan(&name); err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		var err error
+		if commands, err = r.ScanCommands(name, commands); err != nil {
+			return err
+		}
+	}
+	c, ok, err := r.beforeChange(commandSetID, "update", func(p *proposed) { p.commands = commands })
+	if err != nil {
+		return err
+	}
+	return r.afterChange(c, ok, r.replaceCommands(commandSetID, commands))
+}
+
+func (r *Repository) replaceCommands(commandSetID int64, commands []string) error {
 	trx, err := r.db.Begin()
 	if err != nil {
 		return err
@@ -381,6 +508,16 @@ func (r *Repository) ReplaceCommands(commandSetID int64, commands []string) erro
 			return err
 		}
 	}
+	name, err := setNameTx(trx, commandSetID)
+	if err != nil {
+		return err
+	}
+	if err := r.syncTrustTx(trx, commandSetID, commands, false); err != nil {
+		return err
+	}
+	if err := r.appendAuditTx(trx, AuditUpdate, name, commandCount(commands)); err != nil {
+		return err
+	}
 	if err := trx.Commit(); err != nil {
 		return err
 	}
@@ -408,6 +545,21 @@ func (r *Repository) attachTags(cs *CommandSet) error {
 
 // AddTagToCommandSet adds a tag (creating it if necessary) and associates it with the command set.
 func (r *Repository) AddTagToCommandSet(commandSetID int64, tag string) error {
+	c, ok, err := r.beforeChange(commandSetID, "tag", func(p *proposed) {
+		for _, t := range p.tags {
+			if t == tag {
+				return
+			}
+		}
+		p.tags = append(p.tags, tag)
+	})
+	if err != nil {
+		return err
+	}
+	return r.afterChange(c, ok, r.addTag(commandSetID, tag))
+}
+
+func (r *Repository) addTag(commandSetID int64, tag string) error {
 	trx, err := r.db.Begin()
 	if err != nil {
 		return err
@@ -424,14 +576,40 @@ func (r *Repository) AddTagToCommandSet(commandSetID int64, tag string) error {
 		return err
 	}
 	// associate
-	if _, err := trx.Exec("INSERT OR IGNORE INTO command_set_tags (command_set_id, tag_id) VALUES (?, ?)", commandSetID, tagID); err != nil {
+	res, err := trx.Exec("INSERT OR IGNORE INTO command_set_tags (command_set_id, tag_id) VALUES (?, ?)", commandSetID, tagID)
+	if err != nil {
 		return err
 	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		name, err := setNameTx(trx, commandSetID)
+		if err != nil {
+			return err
+		}
+		if err := r.appendAuditTx(trx, AuditTag, name, "added "+tag); err != nil {
+			return err
+		}
+	}
 	return trx.Commit()
 }
 
 // RemoveTagFromCommandSet removes an association between a tag and a command set.
 func (r *Repository) RemoveTagFromCommandSet(commandSetID int64, tag string) error {
+	c, ok, err := r.beforeChange(commandSetID, "tag", func(p *proposed) {
+		kept := p.tags[:0]
+		for _, t := range p.tags {
+			if t != tag {
+				kept = append(kept, t)
+			}
+		}
+		p.tags = kept
+	})
+	if err != nil {
+		return err
+	}
+	return r.afterChange(c, ok, r.removeTag(commandSetID, tag))
+}
+
+func (r *Repository) removeTag(commandSetID int64, tag string) error {
 	// find tag id
 	row := r.db.QueryRow("SELECT id FROM tags WHERE name = ?", tag)
 	var tagID int64
@@ -441,10 +619,25 @@ func (r *Repository) RemoveTagFromCommandSet(commandSetID int64, tag string) err
 		}
 		return err
 	}
-	if _, err := r.db.Exec("DELETE FROM command_set_tags WHERE command_set_id = ? AND tag_id = ?", commandSetID, tagID); err != nil {
+	trx, err := r.db.Begin()
+	if err != nil {
 		return err
 	}
-	return nil
+	defer func() { _ = trx.Rollback() }()
+	res, err := trx.Exec("DELETE FROM command_set_tags WHERE command_set_id = ? AND tag_id = ?", commandSetID, tagID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		name, err := setNameTx(trx, commandSetID)
+		if err != nil {
+			return err
+		}
+		if err := r.appendAuditTx(trx, AuditTag, name, "removed "+tag); err != nil {
+			return err
+		}
+	}
+	return trx.Commit()
 }
 
 // ListTagsForCommandSet returns all tag names associated with a command set.
@@ -0,0 +1,164 @@
+package registry
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Run status values stored in the runs table.
+const (
+	RunStatusRunning = "running"
+	RunStatusOK      = "ok"
+	RunStatusFailed  = "failed"
+)
+
+// RunRecord is a row of run history.
+type RunRecord struct {
+	RunID         string
+	SetName       string
+	StartedAt     string
+	FinishedAt    sql.NullString
+	Status        string
+	ExitCode      sql.NullInt64
+	Duration      time.Duration
+	StepDurations []time.Duration
+	Trigger       string
+}
+
+// StartRun records that a run of setName has started. trigger describes what
+// started it (e.g., "manual").
+func (r *Repository) StartRun(runID, setName, trigger string) error {
+	if trigger == "" {
+		trigger = "manual"
+	}
+	return r.audited(AuditRun, setName, fmt.Sprintf("run %s (%s)", runID, trigger), func(trx *sql.Tx) error {
+		_, err := trx.Exec(`INSERT INTO runs (run_id, set_name, started_at, status, triggered_by)
+		VALUES (?, ?, datetime('now'), ?, ?)`, runID, setName, RunStatusRunning, trigger)
+		if err != nil {
+			return fmt.Errorf("insert run: %w", err)
+		}
+		return nil
+	})
+}
+
+// FinishRun records the outcome of a run and updates the set's last_run time.
+func (r *Repository) FinishRun(runID string, exitCode int, total time.Duration, steps []time.Duration) error {
+	ms := make([]int64, len(steps))
+	for i, d := range steps {
+		ms[i] = d.Milliseconds()
+	}
+	stepJSON, err := json.Marshal(ms)
+	if err != nil {
+		return fmt.Errorf("marshal step durations: %w", err)
+	}
+	status := RunStatusOK
+	if exitCode != 0 {
+		status = RunStatusFailed
+	}
+	trx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = trx.Rollback() }()
+	if _, err := trx.Exec(`UPDATE runs SET finished_at = datetime('now'), status = ?, exit_code = ?, duration_ms = ?, step_durations = ?
+		WHERE run_id = ?`, status, exitCode, total.Milliseconds(), string(stepJSON), runID); err != nil {
+		return fmt.Errorf("update run: %w", err)
+	}
+	if _, err := trx.Exec(`UPDATE command_sets SET last_run = datetime('now')
+		WHERE name = (SELECT set_name FROM runs WHERE run_id = ?)`, runID); err != nil {
+		return err
+	}
+	var setName string
+	if err := trx.QueryRow("SELECT set_name FROM runs WHERE run_id = ?", runID).Scan(&setName); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if err := r.appendAuditTx(trx, AuditRunFinished, setName, fmt.Sprintf("run %s %s (exit %d)", runID, status, exitCode)); err != nil {
+		return err
+	}
+	return trx.Commit()
+}
+
+// ListRuns returns up to limit runs of setName, newest first. A limit <= 0
+// returns all runs.
+func (r *Repository) ListRuns(setName string, limit int) ([]RunRecord, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := r.db.Query(`SELECT `+runColumns+`
+		FROM runs WHERE set_name = ? ORDER BY started_at DESC, id DESC LIMIT ?`, setName, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var out []RunRecord
+	for rows.Next() {
+		rr, err := scanRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *rr)
+	}
+	return out, rows.Err()
+}
+
+// runColumns are the columns read by scanRun.
+const runColumns = `run_id, set_name, started_at, finished_at, status, exit_code, COALESCE(duration_ms, 0), COALESCE(step_durations, '[]'), triggered_by`
+
+func scanRun(sc interface{ Scan(...any) error }) (*RunRecord, error) {
+	var rr RunRecord
+	var durMS int64
+	var stepJSON string
+	if err := sc.Scan(&rr.RunID, &rr.SetName, &rr.StartedAt, &rr.FinishedAt, &rr.Status, &rr.ExitCode, &durMS, &stepJSON, &rr.Trigger); err != nil {
+		return nil, err
+	}
+	rr.Duration = time.Duration(durMS) * time.Millisecond
+	var ms []int64
+	if err := json.Unmarshal([]byte(stepJSON), &ms); err != nil {
+		return nil, fmt.Errorf("unmarshal step durations: %w", err)
+	}
+	for _, m := range ms {
+		rr.StepDurations = append(rr.StepDurations, time.Duration(m)*time.Millisecond)
+	}
+	return &rr, nil
+}
+
+// GetRun returns the run with the given ID, or nil when there is none.
+func (r *Repository) GetRun(runID string) (*RunRecord, error) {
+	rr, err := scanRun(r.db.QueryRow(`SELECT `+runColumns+` FROM runs WHERE run_id = ?`, runID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return rr, err
+}
+
+// EstimateStepDurations returns the average duration of each step over the
+// last (up to) 10 successful runs of setName that had exactly steps steps.
+// It returns nil when there is no usable history.
+func (r *Repository) EstimateStepDurations(setName string, steps int) ([]time.Duration, error) {
+	runs, err := r.ListRuns(setName, 50)
+	if err != nil {
+		return nil, err
+	}
+	sums := make([]time.Duration, steps)
+	n := 0
+	for _, rr := range runs {
+		if rr.Status != RunStatusOK || len(rr.StepDurations) != steps {
+			continue
+		}
+		for i, d := range rr.StepDurations {
+			sums[i] += d
+		}
+		if n++; n == 10 {
+			break
+		}
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	for i := range sums {
+		sums[i] /= time.Duration(n)
+	}
+	return sums, nil
+}
@@ -0,0 +1,75 @@
+package registry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRunHistoryAndEstimates(t *testing.T) {
+	r := setupTestDB(t)
+	if _, err := r.CreateCommandSet("build", nil, nil, nil, []string{"echo a", "echo b"}); err != nil {
+		t.Fatalf("CreateCommandSet: %v", err)
+	}
+	record := func(id string, exit int, steps ...time.Duration) {
+		t.Helper()
+		if err := r.StartRun(id, "build", ""); err != nil {
+			t.Fatalf("StartRun: %v", err)
+		}
+		var total time.Duration
+		for _, d := range steps {
+			total += d
+		}
+		if err := r.FinishRun(id, exit, total, steps); err != nil {
+			t.Fatalf("FinishRun: %v", err)
+		}
+	}
+	record("r1", 0, 100*time.Millisecond, 300*time.Millisecond)
+	record("r2", 0, 300*time.Millisecond, 500*time.Millisecond)
+	record("r3", 1, 10*time.Second)                              // failed: ignored
+	record("r4", 0, 1*time.Second, 1*time.Second, 1*time.Second) // step count differs: ignored
+
+	runs, err := r.ListRuns("build", 0)
+	if err != nil {
+		t.Fatalf("ListRuns: %v", err)
+	}
+	if len(runs) != 4 || runs[0].RunID != "r4" {
+		t.Fatalf("unexpected runs: %+v", runs)
+	}
+	if runs[1].Status != RunStatusFailed || runs[1].ExitCode.Int64 != 1 || runs[1].Trigger != "manual" {
+		t.Fatalf("unexpected failed run: %+v", runs[1])
+	}
+	est, err := r.EstimateStepDurations("build", 2)
+	if err != nil {
+		t.Fatalf("EstimateStepDurations: %v", err)
+	}
+	if len(est) != 2 || est[0] != 200*time.Millisecond || est[1] != 400*time.Millisecond {
+		t.Fatalf("unexpected estimates: %v", est)
+	}
+	if est, _ := r.EstimateStepDurations("other", 2); est != nil {
+		t.Fatalf("expected no estimates without history, got %v", est)
+	}
+	cs, err := r.GetCommandSetByName("build")
+	if err != nil || cs == nil || !cs.LastRun.Valid {
+		t.Fatalf("expected last_run to be set: %+v %v", cs, err)
+	}
+}
+
+func TestGetRun(t *testing.T) {
+	r := setupTestDB(t)
+	if err := r.StartRun("run-1", "deploy", "hook"); err != nil {
+		t.Fatalf("StartRun: %v", err)
+	}
+	rr, err := r.GetRun("run-1")
+	if err != nil || rr == nil || rr.Status != RunStatusRunning || rr.Trigger != "hook" || rr.SetName != "deploy" {
+		t.Fatalf("GetRun = %+v %v", rr, err)
+	}
+	if err := r.FinishRun("run-1", 0, time.Second, nil); err != nil {
+		t.Fatalf("FinishRun: %v", err)
+	}
+	if rr, _ := r.GetRun("run-1"); rr == nil || rr.Status != RunStatusOK || !rr.ExitCode.Valid {
+		t.Fatalf("unexpected finished run: %+v", rr)
+	}
+	if rr, err := r.GetRun("nope"); err != nil || rr != nil {
+		t.Fatalf("expected nil for unknown run, got %+v %v", rr, err)
+	}
+}
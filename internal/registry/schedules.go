@@ -0,0 +1,144 @@
+package registry
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// RunStatusMissed marks a scheduled run that did not happen (e.g., the
+// scheduler was not running at the time).
+const RunStatusMissed = "missed"
+
+// scheduleTimeLayout matches SQLite's datetime() format; schedule times are UTC.
+const scheduleTimeLayout = "2006-01-02 15:04:05"
+
+// Schedule is a cron schedule for a command set.
+type Schedule struct {
+	ID      int64
+	SetName string
+	Cron    string
+	// Params are name=value pairs passed as --param to the run.
+	Params    []string
+	Timeout   time.Duration
+	CreatedAt string
+	LastRunAt time.Time
+	NextRunAt time.Time
+}
+
+// AddSchedule stores a schedule for setName and returns its id. The
+// schedule follows the set when it is renamed and goes away with it.
+func (r *Repository) AddSchedule(setName, cron string, params []string, timeout time.Duration, next time.Time) (int64, error) {
+	setID, _, err := r.setIDAndSteps(setName)
+	if err != nil {
+		return 0, err
+	}
+	if params == nil {
+		params = []string{}
+	}
+	pj, err := json.Marshal(params)
+	if err != nil {
+		return 0, err
+	}
+	var id int64
+	err = r.audited(AuditSettings, setName, "added schedule "+cron, func(trx *sql.Tx) error {
+		res, err := trx.Exec(`INSERT INTO schedules (command_set_id, cron, params, timeout_ms, created_at, next_run_at)
+		VALUES (?, ?, ?, ?, datetime('now'), ?)`, setID, cron, string(pj), timeout.Milliseconds(), formatScheduleTime(next))
+		if err != nil {
+			return fmt.Errorf("insert schedule: %w", err)
+		}
+		id, err = res.LastInsertId()
+		return err
+	})
+	return id, err
+}
+
+// ListSchedules returns every schedule ordered by id.
+func (r *Repository) ListSchedules() ([]Schedule, error) {
+	rows, err := r.db.Query(`SELECT s.id, cs.name, s.cron, s.params, s.timeout_ms, s.created_at, s.last_run_at, s.next_run_at
+		FROM schedules s JOIN command_sets cs ON cs.id = s.command_set_id ORDER BY s.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var out []Schedule
+	for rows.Next() {
+		var s Schedule
+		var pj string
+		var timeoutMS int64
+		var last, next sql.NullString
+		if err := rows.Scan(&s.ID, &s.SetName, &s.Cron, &pj, &timeoutMS, &s.CreatedAt, &last, &next); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(pj), &s.Params); err != nil {
+			return nil, fmt.Errorf("schedule %d: bad params: %w", s.ID, err)
+		}
+		s.Timeout = time.Duration(timeoutMS) * time.Millisecond
+		s.LastRunAt = parseScheduleTime(last)
+		s.NextRunAt = parseScheduleTime(next)
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// RemoveSchedule deletes a schedule by id.
+func (r *Repository) RemoveSchedule(id int64) error {
+	var setName, cron string
+	if err := r.db.QueryRow(`SELECT cs.name, s.cron FROM schedules s JOIN command_sets cs ON cs.id = s.command_set_id
+		WHERE s.id = ?`, id).Scan(&setName, &cron); err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("schedule not found: %d", id)
+		}
+		return err
+	}
+	return r.audited(AuditSettings, setName, fmt.Sprintf("removed schedule %d (%s)", id, cron), func(trx *sql.Tx) error {
+		res, err := trx.Exec("DELETE FROM schedules WHERE id = ?", id)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return fmt.Errorf("schedule not found: %d", id)
+		}
+		return nil
+	})
+}
+
+// AdvanceSchedule records when a schedule last fired (zero to keep the
+// previous value) and when it is due next.
+func (r *Repository) AdvanceSchedule(id int64, lastRun, next time.Time) error {
+	if lastRun.IsZero() {
+		_, err := r.db.Exec("UPDATE schedules SET next_run_at = ? WHERE id = ?", formatScheduleTime(next), id)
+		return err
+	}
+	_, err := r.db.Exec("UPDATE schedules SET last_run_at = ?, next_run_at = ? WHERE id = ?", formatScheduleTime(lastRun), formatScheduleTime(next), id)
+	return err
+}
+
+// RecordMissedRun adds a run history entry for a scheduled run that was due
+// at scheduledAt but did not happen.
+func (r *Repository) RecordMissedRun(setName string, scheduledAt time.Time, trigger string) error {
+	runID := fmt.Sprintf("missed-%s-%s", scheduledAt.UTC().Format("20060102T150405Z"), setName)
+	_, err := r.db.Exec(`INSERT OR IGNORE INTO runs (run_id, set_name, started_at, status, triggered_by)
+		VALUES (?, ?, ?, ?, ?)`, runID, setName, formatScheduleTime(scheduledAt), RunStatusMissed, trigger)
+	return err
+}
+
+func formatScheduleTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format(scheduleTimeLayout)
+}
+
+func parseScheduleTime(s sql.NullString) time.Time {
+	if !s.Valid {
+		return time.Time{}
+	}
+	for _, layout := range []string{scheduleTimeLayout, time.RFC3339} {
+		if t, err := time.Parse(layout, s.String); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,99 @@
+package registry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedulesCRUDAndMissedRuns(t *testing.T) {
+	r := setupTestDB(t)
+	if _, err := r.CreateCommandSet("backup", nil, nil, nil, []string{"echo {{target}}"}); err != nil {
+		t.Fatalf("CreateCommandSet: %v", err)
+	}
+	next := time.Date(2026, 3, 15, 3, 0, 0, 0, time.UTC)
+	id, err := r.AddSchedule("backup", "0 3 * * *", []string{"target=/mnt"}, 5*time.Minute, next)
+	if err != nil {
+		t.Fatalf("AddSchedule: %v", err)
+	}
+	list, err := r.ListSchedules()
+	if err != nil || len(list) != 1 {
+		t.Fatalf("ListSchedules: %v %v", list, err)
+	}
+	s := list[0]
+	if s.ID != id || s.Cron != "0 3 * * *" || len(s.Params) != 1 || s.Params[0] != "target=/mnt" ||
+		s.Timeout != 5*time.Minute || !s.NextRunAt.Equal(next) || !s.LastRunAt.IsZero() {
+		t.Fatalf("unexpected schedule: %+v", s)
+	}
+
+	later := next.Add(24 * time.Hour)
+	if err := r.AdvanceSchedule(id, next, later); err != nil {
+		t.Fatalf("AdvanceSchedule: %v", err)
+	}
+	list, _ = r.ListSchedules()
+	if !list[0].LastRunAt.Equal(next) || !list[0].NextRunAt.Equal(later) {
+		t.Fatalf("schedule not advanced: %+v", list[0])
+	}
+
+	if err := r.RecordMissedRun("backup", next, "schedule"); err != nil {
+		t.Fatalf("RecordMissedRun: %v", err)
+	}
+	// recording the same occurrence twice is a no-op
+	if err := r.RecordMissedRun("backup", next, "schedule"); err != nil {
+		t.Fatalf("RecordMissedRun: %v", err)
+	}
+	runs, err := r.ListRuns("backup", 0)
+	if err != nil || len(runs) != 1 || runs[0].Status != RunStatusMissed || runs[0].Trigger != "schedule" {
+		t.Fatalf("unexpected runs: %+v %v", runs, err)
+	}
+
+	if err := r.RemoveSchedule(id); err != nil {
+		t.Fatalf("RemoveSchedule: %v", err)
+	}
+	if err := r.RemoveSchedule(id); err == nil {
+		t.Fatalf("expected error removing a missing schedule")
+	}
+}
+
+func TestDeleteCommandSetRemovesSchedules(t *testing.T) {
+	r := setupTestDB(t)
+	if _, err := r.CreateCommandSet("nightly", nil, nil, nil, []string{"echo hi"}); err != nil {
+		t.Fatalf("CreateCommandSet: %v", err)
+	}
+	if _, err := r.AddSchedule("nightly", "@daily", nil, time.Minute, time.Now()); err != nil {
+		t.Fatalf("AddSchedule: %v", err)
+	}
+	if err := r.DeleteCommandSet("nightly"); err != nil {
+		t.Fatalf("DeleteCommandSet: %v", err)
+	}
+	if list, _ := r.ListSchedules(); len(list) != 0 {
+		t.Fatalf("expected schedules to be removed, got %+v", list)
+	}
+}
+
+func TestSchedulesFollowRenamedSets(t *testing.T) {
+	r := setupTestDB(t)
+	id, err := r.CreateCommandSet("nightly", nil, nil, nil, []string{"echo hi"})
+	if err != nil {
+		t.Fatalf("CreateCommandSet: %v", err)
+	}
+	if _, err := r.AddSchedule("nightly", "@daily", nil, time.Minute, time.Now()); err != nil {
+		t.Fatalf("AddSchedule: %v", err)
+	}
+	if err := r.UpdateCommandSet(id, "nightly-build", nil, nil, nil, nil); err != nil {
+		t.Fatalf("UpdateCommandSet: %v", err)
+	}
+	if err := r.UpdateCommandSetAndReplaceCommands(id, "nightly-release", nil, nil, nil, nil, []string{"echo release"}); err != nil {
+		t.Fatalf("UpdateCommandSetAndReplaceCommands: %v", err)
+	}
+	// a new set under the old name does not inherit the schedule
+	if _, err := r.CreateCommandSet("nightly", nil, nil, nil, []string{"echo other"}); err != nil {
+		t.Fatalf("CreateCommandSet: %v", err)
+	}
+	list, err := r.ListSchedules()
+	if err != nil || len(list) != 1 || list[0].SetName != "nightly-release" {
+		t.Fatalf("expected the schedule to follow the renamed set, got %+v %v", list, err)
+	}
+	if _, err := r.AddSchedule("missing", "@daily", nil, 0, time.Now()); err == nil {
+		t.Fatalf("expected scheduling a missing set to fail")
+	}
+}
@@ -0,0 +1,169 @@
+package registry
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// Provenance records where an imported set came from and whether its
+// current commands are trusted.
+type Provenance struct {
+	SetName string
+	// Source is the file the set was imported from.
+	Source string
+	// OriginalAuthor is the author recorded in the source, if any.
+	OriginalAuthor string
+	ImportedAt     string
+	Trusted        bool
+	// TrustedCommands are the commands the user last trusted, nil when the
+	// set was never trusted.
+	TrustedCommands []string
+}
+
+// Import describes an import of a set, for RecordImport.
+type Import struct {
+	Source         string
+	OriginalAuthor string
+	// Baseline are the commands trusted before the import (see
+	// TrustBaseline); nil when the set is new or was never trusted.
+	Baseline []string
+}
+
+// GetProvenance returns the provenance of the named set, or nil when it was
+// created locally.
+func (r *Repository) GetProvenance(name string) (*Provenance, error) {
+	p := Provenance{SetName: name}
+	var cmds sql.NullString
+	err := r.db.QueryRow(`SELECT p.source, p.original_author, p.imported_at, p.trusted, p.trusted_commands
+		FROM provenance p JOIN command_sets cs ON cs.id = p.command_set_id WHERE cs.name = ?`, name).
+		Scan(&p.Source, &p.OriginalAuthor, &p.ImportedAt, &p.Trusted, &cmds)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cmds.Valid {
+		if err := json.Unmarshal([]byte(cmds.String), &p.TrustedCommands); err != nil {
+			return nil, fmt.Errorf("provenance of %s: %w", name, err)
+		}
+	}
+	return &p, nil
+}
+
+// Untrusted returns the provenance of the named set when it was imported
+// and its commands have not been trusted since, or nil.
+func (r *Repository) Untrusted(name string) (*Provenance, error) {
+	p, err := r.GetProvenance(name)
+	if err != nil || p == nil || p.Trusted {
+		return nil, err
+	}
+	return p, nil
+}
+
+// TrustBaseline returns the commands of the named set the user trusts,
+// for an import about to change the set: the current commands of a local
+// set, the last trusted commands of an imported one, and nil for a new or
+// never trusted set.
+func (r *Repository) TrustBaseline(name string) ([]string, error) {
+	p, err := r.GetProvenance(name)
+	if err != nil {
+		return nil, err
+	}
+	if p != nil {
+		return p.TrustedCommands, nil
+	}
+	cs, err := r.GetCommandSetByName(name)
+	if err != nil || cs == nil {
+		return nil, err
+	}
+	cmds := []string{}
+	for _, c := range cs.Commands {
+		cmds = append(cmds, c.Command)
+	}
+	return cmds, nil
+}
+
+// RecordImport records that the named set was imported, in the audit log
+// too. The set stays trusted only when its commands equal imp.Baseline;
+// otherwise it needs Trust before it runs.
+func (r *Repository) RecordImport(name string, imp Import) error {
+	cs, err := r.GetCommandSetByName(name)
+	if err != nil {
+		return err
+	}
+	if cs == nil {
+		return fmt.Errorf("command set not found: %s", name)
+	}
+	cur := []string{}
+	for _, c := range cs.Commands {
+		cur = append(cur, c.Command)
+	}
+	trusted := imp.Baseline != nil && sameStrings(imp.Baseline, cur, true)
+	var baseline interface{}
+	if imp.Baseline != nil {
+		b, err := json.Marshal(imp.Baseline)
+		if err != nil {
+			return err
+		}
+		baseline = string(b)
+	}
+	detail := "from " + imp.Source
+	if !trusted {
+		detail += ", untrusted"
+	}
+	return r.audited(AuditImport, name, detail, func(trx *sql.Tx) error {
+		_, err := trx.Exec(`INSERT INTO provenance (command_set_id, source, original_author, imported_at, trusted, trusted_commands)
+			VALUES (?, ?, ?, datetime('now'), ?, ?)
+			ON CONFLICT(command_set_id) DO UPDATE SET source = excluded.source, original_author = excluded.original_author,
+			imported_at = excluded.imported_at, trusted = excluded.trusted, trusted_commands = excluded.trusted_commands`,
+			cs.ID, imp.Source, imp.OriginalAuthor, trusted, baseline)
+		return err
+	})
+}
+
+// Trust marks the current commands of the named imported set as trusted.
+// It returns false when the set was already trusted.
+func (r *Repository) Trust(name string) (bool, error) {
+	p, err := r.GetProvenance(name)
+	if err != nil {
+		return false, err
+	}
+	if p == nil || p.Trusted {
+		if cs, err := r.GetCommandSetByName(name); err != nil || cs == nil {
+			if err == nil {
+				err = fmt.Errorf("command set not found: %s", name)
+			}
+			return false, err
+		}
+		return false, nil
+	}
+	err = r.audited(AuditTrust, name, "imported from "+p.Source, func(trx *sql.Tx) error {
+		var id int64
+		if err := trx.QueryRow("SELECT id FROM command_sets WHERE name = ?", name).Scan(&id); err != nil {
+			return err
+		}
+		cmds, err := r.readCommandsTx(trx, id)
+		if err != nil {
+			return err
+		}
+		return r.syncTrustTx(trx, id, cmds, true)
+	})
+	return err == nil, err
+}
+
+// syncTrustTx stores commands as the trusted commands of an imported set,
+// when it is trusted or force is set. Local edits of a trusted set keep it
+// trusted.
+func (r *Repository) syncTrustTx(trx *sql.Tx, id int64, commands []string, force bool) error {
+	if commands == nil {
+		commands = []string{}
+	}
+	b, err := json.Marshal(commands)
+	if err != nil {
+		return err
+	}
+	_, err = trx.Exec("UPDATE provenance SET trusted = 1, trusted_commands = ? WHERE command_set_id = ? AND (trusted = 1 OR ?)", string(b), id, force)
+	return err
+}
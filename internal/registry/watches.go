@@ -0,0 +1,83 @@
+package registry
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// Watch is a saved `krnr watch` definition for a command set.
+type Watch struct {
+	Paths    []string
+	Include  []string
+	Exclude  []string
+	Debounce time.Duration
+}
+
+// SaveWatch stores w as the watch definition of the named set, replacing any
+// previous one.
+func (r *Repository) SaveWatch(name string, w Watch) error {
+	id, _, err := r.setIDAndSteps(name)
+	if err != nil {
+		return err
+	}
+	var cols [3]string
+	for i, v := range [][]string{w.Paths, w.Include, w.Exclude} {
+		if v == nil {
+			v = []string{}
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		cols[i] = string(b)
+	}
+	return r.audited(AuditSettings, name, "saved watch", func(trx *sql.Tx) error {
+		_, err := trx.Exec(`INSERT INTO watches (command_set_id, paths, include, exclude, debounce_ms) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(command_set_id) DO UPDATE SET paths = excluded.paths, include = excluded.include,
+		exclude = excluded.exclude, debounce_ms = excluded.debounce_ms`,
+			id, cols[0], cols[1], cols[2], w.Debounce.Milliseconds())
+		return err
+	})
+}
+
+// GetWatch returns the saved watch definition of the named set, or nil when
+// it has none.
+func (r *Repository) GetWatch(name string) (*Watch, error) {
+	id, _, err := r.setIDAndSteps(name)
+	if err != nil {
+		return nil, err
+	}
+	var paths, include, exclude string
+	var debounceMS int64
+	err = r.db.QueryRow("SELECT paths, include, exclude, debounce_ms FROM watches WHERE command_set_id = ?", id).
+		Scan(&paths, &include, &exclude, &debounceMS)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	w := &Watch{Debounce: time.Duration(debounceMS) * time.Millisecond}
+	for _, c := range []struct {
+		raw string
+		dst *[]string
+	}{{paths, &w.Paths}, {include, &w.Include}, {exclude, &w.Exclude}} {
+		if err := json.Unmarshal([]byte(c.raw), c.dst); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// DeleteWatch removes the saved watch definition of the named set.
+func (r *Repository) DeleteWatch(name string) error {
+	id, _, err := r.setIDAndSteps(name)
+	if err != nil {
+		return err
+	}
+	return r.audited(AuditSettings, name, "deleted watch", func(trx *sql.Tx) error {
+		_, err := trx.Exec("DELETE FROM watches WHERE command_set_id = ?", id)
+		return err
+	})
+}
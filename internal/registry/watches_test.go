@@ -0,0 +1,38 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSaveGetDeleteWatch(t *testing.T) {
+	r := setupTestDB(t)
+	if _, err := r.CreateCommandSet("test", nil, nil, nil, []string{"go test ./..."}); err != nil {
+		t.Fatalf("CreateCommandSet: %v", err)
+	}
+	if w, err := r.GetWatch("test"); err != nil || w != nil {
+		t.Fatalf("expected no watch, got %+v %v", w, err)
+	}
+	want := Watch{Paths: []string{"src"}, Include: []string{"*.go"}, Exclude: []string{}, Debounce: 300 * time.Millisecond}
+	if err := r.SaveWatch("test", want); err != nil {
+		t.Fatalf("SaveWatch: %v", err)
+	}
+	want.Paths = []string{"src", "internal"}
+	if err := r.SaveWatch("test", want); err != nil {
+		t.Fatalf("SaveWatch (replace): %v", err)
+	}
+	got, err := r.GetWatch("test")
+	if err != nil || got == nil || !reflect.DeepEqual(*got, want) {
+		t.Fatalf("GetWatch = %+v %v, want %+v", got, err, want)
+	}
+	if err := r.DeleteWatch("test"); err != nil {
+		t.Fatalf("DeleteWatch: %v", err)
+	}
+	if w, _ := r.GetWatch("test"); w != nil {
+		t.Fatalf("expected watch to be deleted, got %+v", w)
+	}
+	if err := r.SaveWatch("missing", want); err == nil {
+		t.Fatalf("expected error for unknown set")
+	}
+}
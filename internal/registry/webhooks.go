@@ -0,0 +1,70 @@
+package registry
+
+import (
+	"database/sql"
+)
+
+// Webhook describes a hook-enabled command set.
+type Webhook struct {
+	SetName   string
+	CreatedAt string
+}
+
+// EnableWebhook marks the named set as triggerable by `krnr serve hooks`
+// with the given HMAC secret, replacing any previous secret.
+func (r *Repository) EnableWebhook(name, secret string) error {
+	id, _, err := r.setIDAndSteps(name)
+	if err != nil {
+		return err
+	}
+	return r.audited(AuditSettings, name, "enabled webhook", func(trx *sql.Tx) error {
+		_, err := trx.Exec(`INSERT INTO webhooks (command_set_id, secret, created_at) VALUES (?, ?, datetime('now'))
+		ON CONFLICT(command_set_id) DO UPDATE SET secret = excluded.secret, created_at = excluded.created_at`, id, secret)
+		return err
+	})
+}
+
+// DisableWebhook removes the named set's webhook.
+func (r *Repository) DisableWebhook(name string) error {
+	id, _, err := r.setIDAndSteps(name)
+	if err != nil {
+		return err
+	}
+	return r.audited(AuditSettings, name, "disabled webhook", func(trx *sql.Tx) error {
+		_, err := trx.Exec("DELETE FROM webhooks WHERE command_set_id = ?", id)
+		return err
+	})
+}
+
+// WebhookSecret returns the HMAC secret of the named set's webhook; ok is
+// false when the set does not exist, is not hook-enabled, or has no secret
+// (exports strip them).
+func (r *Repository) WebhookSecret(name string) (secret string, ok bool, err error) {
+	err = r.db.QueryRow(`SELECT w.secret FROM webhooks w JOIN command_sets cs ON cs.id = w.command_set_id
+		WHERE cs.name = ?`, name).Scan(&secret)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return secret, secret != "", nil
+}
+
+// ListWebhooks returns the hook-enabled sets ordered by name.
+func (r *Repository) ListWebhooks() ([]Webhook, error) {
+	rows, err := r.db.Query(`SELECT cs.name, w.created_at FROM webhooks w JOIN command_sets cs ON cs.id = w.command_set_id ORDER BY cs.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var out []Webhook
+	for rows.Next() {
+		var w Webhook
+		if err := rows.Scan(&w.SetName, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, w)
+	}
+	return out, rows.Err()
+}
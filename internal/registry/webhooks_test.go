@@ -0,0 +1,46 @@
+package registry
+
+import "testing"
+
+func TestEnableDisableWebhook(t *testing.T) {
+	r := setupTestDB(t)
+	for _, name := range []string{"deploy", "build"} {
+		if _, err := r.CreateCommandSet(name, nil, nil, nil, []string{"echo " + name}); err != nil {
+			t.Fatalf("CreateCommandSet: %v", err)
+		}
+	}
+	if _, ok, err := r.WebhookSecret("deploy"); err != nil || ok {
+		t.Fatalf("expected no webhook, got ok=%v err=%v", ok, err)
+	}
+	if err := r.EnableWebhook("deploy", "s1"); err != nil {
+		t.Fatalf("EnableWebhook: %v", err)
+	}
+	if err := r.EnableWebhook("deploy", "s2"); err != nil {
+		t.Fatalf("EnableWebhook (rotate): %v", err)
+	}
+	if err := r.EnableWebhook("build", "b"); err != nil {
+		t.Fatalf("EnableWebhook: %v", err)
+	}
+	if secret, ok, err := r.WebhookSecret("deploy"); err != nil || !ok || secret != "s2" {
+		t.Fatalf("WebhookSecret = %q %v %v", secret, ok, err)
+	}
+	list, err := r.ListWebhooks()
+	if err != nil || len(list) != 2 || list[0].SetName != "build" || list[1].SetName != "deploy" {
+		t.Fatalf("ListWebhooks = %+v %v", list, err)
+	}
+	if err := r.DisableWebhook("deploy"); err != nil {
+		t.Fatalf("DisableWebhook: %v", err)
+	}
+	if _, ok, _ := r.WebhookSecret("deploy"); ok {
+		t.Fatalf("expected webhook to be disabled")
+	}
+	if err := r.DeleteCommandSet("build"); err != nil {
+		t.Fatalf("DeleteCommandSet: %v", err)
+	}
+	if list, _ := r.ListWebhooks(); len(list) != 0 {
+		t.Fatalf("expected webhooks of deleted set to be removed, got %+v", list)
+	}
+	if err := r.EnableWebhook("missing", "x"); err == nil {
+		t.Fatalf("expected error for unknown set")
+	}
+}
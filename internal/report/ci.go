@@ -0,0 +1,125 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VoxDroid/krnr/internal/tui/adapters"
+)
+
+// CI providers understood by CIMarkers.
+const (
+	CIGitHub = "github"
+	CIGitLab = "gitlab"
+	CIPlain  = "plain"
+)
+
+// DetectCI returns the CI provider for the current environment, falling back
+// to CIPlain when neither GitHub Actions nor GitLab CI is detected.
+func DetectCI() string {
+	switch {
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		return CIGitHub
+	case os.Getenv("GITLAB_CI") == "true":
+		return CIGitLab
+	}
+	return CIPlain
+}
+
+// ResolveCI maps a --ci flag value (auto|github|gitlab|plain) to a provider.
+func ResolveCI(v string) (string, error) {
+	switch v {
+	case "auto":
+		return DetectCI(), nil
+	case CIGitHub, CIGitLab, CIPlain:
+		return v, nil
+	}
+	return "", fmt.Errorf("invalid --ci %q (expected auto, github, gitlab or plain)", v)
+}
+
+// CIMarkers writes collapsible group markers around each step and an error
+// annotation for failures, in the syntax of the selected CI provider.
+type CIMarkers struct {
+	Provider string
+	W        io.Writer
+	// Set names the command set in annotations.
+	Set string
+
+	mu     sync.Mutex
+	now    func() time.Time
+	failed bool
+}
+
+// NewCIMarkers returns a marker writer for provider.
+func NewCIMarkers(provider, set string, w io.Writer) *CIMarkers {
+	return &CIMarkers{Provider: provider, W: w, Set: set, now: time.Now}
+}
+
+// Handle consumes a run event. It is suitable as an adapters.EventEmitter sink.
+func (c *CIMarkers) Handle(ev adapters.RunEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch ev.Type {
+	case adapters.EventStepStarted:
+		c.groupStart(ev.Step, ev.Command)
+	case adapters.EventStepFinished:
+		c.groupEnd(ev.Step)
+		if ev.Err != nil {
+			c.failed = true
+			c.annotate(fmt.Sprintf("step %d failed (exit %d): %s", ev.Step, ev.ExitCode, firstLine(ev.Err.Error())))
+		}
+	case adapters.EventRunFinished:
+		if ev.Err != nil && !c.failed {
+			// the run stopped before a step failed (refused, missing param, ...)
+			c.annotate(firstLine(ev.Err.Error()))
+		}
+	}
+}
+
+func (c *CIMarkers) groupStart(step int, command string) {
+	title := fmt.Sprintf("step %d: %s", step, command)
+	switch c.Provider {
+	case CIGitHub:
+		_, _ = fmt.Fprintf(c.W, "::group::%s\n", escapeGitHub(title))
+	case CIGitLab:
+		_, _ = fmt.Fprintf(c.W, "\x1b[0Ksection_start:%d:%s[collapsed=true]\r\x1b[0K%s\n", c.now().Unix(), sectionName(step), title)
+	}
+}
+
+func (c *CIMarkers) groupEnd(step int) {
+	switch c.Provider {
+	case CIGitHub:
+		_, _ = fmt.Fprintln(c.W, "::endgroup::")
+	case CIGitLab:
+		_, _ = fmt.Fprintf(c.W, "\x1b[0Ksection_end:%d:%s\r\x1b[0K\n", c.now().Unix(), sectionName(step))
+	}
+}
+
+func (c *CIMarkers) annotate(msg string) {
+	switch c.Provider {
+	case CIGitHub:
+		_, _ = fmt.Fprintf(c.W, "::error title=krnr %s::%s\n", escapeGitHubProperty(c.Set), escapeGitHub(msg))
+	case CIGitLab:
+		// GitLab has no annotation syntax; a red ERROR line stands out in job logs
+		_, _ = fmt.Fprintf(c.W, "\x1b[31;1mERROR: krnr %s: %s\x1b[0m\n", c.Set, msg)
+	default:
+		_, _ = fmt.Fprintf(c.W, "ERROR: krnr %s: %s\n", c.Set, msg)
+	}
+}
+
+func sectionName(step int) string {
+	return fmt.Sprintf("krnr_step_%d", step)
+}
+
+// escapeGitHub escapes workflow command data per the GitHub Actions spec.
+func escapeGitHub(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
@@ -0,0 +1,207 @@
+// Package report turns run events into machine-readable reports (JUnit XML)
+// and CI-friendly console markers (GitHub Actions / GitLab CI).
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VoxDroid/krnr/internal/tui/adapters"
+)
+
+// tailLimit caps the captured output per step that ends up in the report.
+const tailLimit = 8 * 1024
+
+// JUnit collects run events and renders them as a JUnit XML report where
+// each step becomes a testcase.
+type JUnit struct {
+	mu       sync.Mutex
+	set      string
+	runID    string
+	started  time.Time
+	duration time.Duration
+	runErr   error
+	commands []string
+	cases    map[int]*stepResult
+}
+
+type stepResult struct {
+	command  string
+	ran      bool
+	exitCode int
+	duration time.Duration
+	err      error
+	output   []byte
+}
+
+// NewJUnit returns a collector for a run of set. commands are the set's
+// (unresolved) commands and name steps that never started.
+func NewJUnit(set string, commands []string) *JUnit {
+	return &JUnit{set: set, commands: commands, cases: map[int]*stepResult{}}
+}
+
+// Handle consumes a run event. It is suitable as an adapters.EventEmitter sink.
+func (j *JUnit) Handle(ev adapters.RunEvent) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	switch ev.Type {
+	case adapters.EventRunStarted:
+		j.runID, j.started = ev.RunID, ev.Time
+	case adapters.EventStepStarted:
+		j.step(ev.Step).command, j.step(ev.Step).ran = ev.Command, true
+	case adapters.EventOutput:
+		j.step(ev.Step).appendOutput(ev.Line)
+	case adapters.EventStepFinished:
+		sr := j.step(ev.Step)
+		sr.exitCode, sr.duration, sr.err = ev.ExitCode, ev.Duration, ev.Err
+	case adapters.EventRunFinished:
+		j.duration, j.runErr = ev.Duration, ev.Err
+	}
+}
+
+func (j *JUnit) step(n int) *stepResult {
+	sr, ok := j.cases[n]
+	if !ok {
+		sr = &stepResult{}
+		j.cases[n] = sr
+	}
+	return sr
+}
+
+func (sr *stepResult) appendOutput(s string) {
+	sr.output = append(sr.output, s...)
+	if len(sr.output) > tailLimit {
+		sr.output = append([]byte(nil), sr.output[len(sr.output)-tailLimit:]...)
+	}
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Errors   int          `xml:"errors,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr,omitempty"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Cases      []junitCase     `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Body    string `xml:",chardata"`
+}
+
+// build assembles the XML document from the collected results.
+func (j *JUnit) build() junitSuites {
+	suite := junitSuite{Name: j.set, Time: seconds(j.duration)}
+	if !j.started.IsZero() {
+		suite.Timestamp = j.started.Format(time.RFC3339)
+	}
+	if j.runID != "" {
+		suite.Properties = []junitProperty{{Name: "run_id", Value: j.runID}}
+	}
+	runErrReported := false
+	for i := 1; i <= len(j.commands); i++ {
+		tc := j.testcase(i)
+		switch {
+		case tc.Failure != nil:
+			suite.Failures++
+			runErrReported = true
+		case tc.Skipped != nil && j.runErr != nil && !runErrReported:
+			// the run stopped before this step could start (e.g. refused by
+			// a safety check or a missing parameter): report it as an error
+			tc.Skipped = nil
+			tc.Error = &junitMessage{Message: firstLine(j.runErr.Error()), Body: j.runErr.Error()}
+			suite.Errors++
+			runErrReported = true
+		case tc.Skipped != nil:
+			suite.Skipped++
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	suite.Tests = len(suite.Cases)
+	return junitSuites{Name: "krnr", Tests: suite.Tests, Failures: suite.Failures, Errors: suite.Errors, Time: suite.Time, Suites: []junitSuite{suite}}
+}
+
+func (j *JUnit) testcase(n int) junitCase {
+	sr := j.cases[n]
+	command := j.commands[n-1]
+	if sr != nil && sr.command != "" {
+		command = sr.command
+	}
+	tc := junitCase{Name: fmt.Sprintf("step %d: %s", n, command), Classname: "krnr." + j.set, Time: "0"}
+	if sr == nil || !sr.ran {
+		tc.Skipped = &junitMessage{Message: "not run"}
+		return tc
+	}
+	tc.Time = seconds(sr.duration)
+	out := strings.ToValidUTF8(string(sr.output), "�")
+	if sr.err != nil {
+		tc.Failure = &junitMessage{Message: firstLine(sr.err.Error()), Type: fmt.Sprintf("exit %d", sr.exitCode), Body: out}
+		return tc
+	}
+	tc.SystemOut = out
+	return tc
+}
+
+// WriteFile writes the JUnit XML report to path, creating parent directories.
+func (j *JUnit) WriteFile(path string) error {
+	j.mu.Lock()
+	doc := j.build()
+	j.mu.Unlock()
+	b, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode junit report: %w", err)
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("create report dir: %w", err)
+		}
+	}
+	data := append([]byte(xml.Header), b...)
+	data = append(data, '\n')
+	return os.WriteFile(path, data, 0o644)
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
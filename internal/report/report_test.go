@@ -0,0 +1,95 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VoxDroid/krnr/internal/tui/adapters"
+)
+
+func feed(h func(adapters.RunEvent), failStep int) {
+	h(adapters.RunEvent{Type: adapters.EventRunStarted, RunID: "r1", Time: time.Unix(0, 0).UTC(), Set: "smoke", Steps: 3})
+	for step := 1; step <= failStep; step++ {
+		h(adapters.RunEvent{Type: adapters.EventStepStarted, Step: step, Command: "check " + string(rune('0'+step))})
+		h(adapters.RunEvent{Type: adapters.EventOutput, Step: step, Stream: adapters.StreamStdout, Line: "out <&>\n"})
+		var err error
+		code := 0
+		if step == failStep {
+			err, code = errors.New("exit status 3"), 3
+		}
+		h(adapters.RunEvent{Type: adapters.EventStepFinished, Step: step, ExitCode: code, Duration: 250 * time.Millisecond, Err: err})
+	}
+	h(adapters.RunEvent{Type: adapters.EventRunFinished, Err: errors.New("exit status 3"), ExitCode: 3, Duration: time.Second})
+}
+
+func TestJUnitReportsFailureAndSkipped(t *testing.T) {
+	j := NewJUnit("smoke", []string{"check 1", "check 2", "check {{x}}"})
+	feed(j.Handle, 2)
+	path := filepath.Join(t.TempDir(), "out", "junit.xml")
+	if err := j.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	b, _ := os.ReadFile(path)
+	xml := string(b)
+	for _, want := range []string{
+		`<testsuite name="smoke" tests="3" failures="1" errors="0" skipped="1" time="1.000"`,
+		`<testcase name="step 1: check 1" classname="krnr.smoke" time="0.250">`,
+		`<failure message="exit status 3" type="exit 3">out &lt;&amp;&gt;`,
+		`<testcase name="step 3: check {{x}}" classname="krnr.smoke" time="0">`,
+		`<property name="run_id" value="r1">`,
+	} {
+		if !strings.Contains(xml, want) {
+			t.Fatalf("expected %q in report:\n%s", want, xml)
+		}
+	}
+}
+
+func TestJUnitReportsRunErrorBeforeAnyStep(t *testing.T) {
+	j := NewJUnit("smoke", []string{"rm -rf /"})
+	j.Handle(adapters.RunEvent{Type: adapters.EventRunStarted, Set: "smoke", Steps: 1})
+	j.Handle(adapters.RunEvent{Type: adapters.EventRunFinished, Err: errors.New("refusing to run"), ExitCode: -1})
+	doc := j.build()
+	if doc.Errors != 1 || doc.Suites[0].Cases[0].Error == nil {
+		t.Fatalf("expected refused step to be reported as error, got %+v", doc.Suites[0].Cases[0])
+	}
+}
+
+func TestCIMarkersGitHub(t *testing.T) {
+	var buf bytes.Buffer
+	c := NewCIMarkers(CIGitHub, "smoke", &buf)
+	feed(c.Handle, 1)
+	out := buf.String()
+	if !strings.Contains(out, "::group::step 1: check 1\n") || !strings.Contains(out, "::endgroup::\n") {
+		t.Fatalf("expected group markers, got %q", out)
+	}
+	if strings.Count(out, "::error title=krnr smoke::step 1 failed (exit 3): exit status 3") != 1 {
+		t.Fatalf("expected exactly one error annotation, got %q", out)
+	}
+}
+
+func TestCIMarkersGitLabSections(t *testing.T) {
+	var buf bytes.Buffer
+	c := NewCIMarkers(CIGitLab, "smoke", &buf)
+	c.now = func() time.Time { return time.Unix(42, 0) }
+	c.Handle(adapters.RunEvent{Type: adapters.EventStepStarted, Step: 1, Command: "echo"})
+	c.Handle(adapters.RunEvent{Type: adapters.EventStepFinished, Step: 1})
+	out := buf.String()
+	if !strings.Contains(out, "section_start:42:krnr_step_1[collapsed=true]") || !strings.Contains(out, "section_end:42:krnr_step_1") {
+		t.Fatalf("expected gitlab sections, got %q", out)
+	}
+}
+
+func TestResolveCI(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "true")
+	if p, _ := ResolveCI("auto"); p != CIGitHub {
+		t.Fatalf("expected github from env, got %q", p)
+	}
+	if _, err := ResolveCI("jenkins"); err == nil {
+		t.Fatalf("expected error for unknown provider")
+	}
+}
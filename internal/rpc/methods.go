@@ -0,0 +1,320 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/runner"
+	"github.com/VoxDroid/krnr/internal/tui/adapters"
+	"github.com/VoxDroid/krnr/internal/tui/model"
+	"github.com/VoxDroid/krnr/internal/version"
+)
+
+// RunEventMethod is the notification carrying run events.
+const RunEventMethod = "runs/event"
+
+// Server answers requests using a UIModel.
+type Server struct {
+	model    *model.UIModel
+	newRunID func() string
+	conn     *conn
+
+	mu   sync.Mutex
+	runs map[string]adapters.RunHandle
+	wg   sync.WaitGroup
+}
+
+// NewServer returns a server backed by m. newRunID generates run IDs.
+func NewServer(m *model.UIModel, newRunID func() string) *Server {
+	return &Server{model: m, newRunID: newRunID, runs: map[string]adapters.RunHandle{}}
+}
+
+type handler func(ctx context.Context, params json.RawMessage) (any, error)
+
+func (s *Server) methods() map[string]handler {
+	return map[string]handler{
+		"initialize":    s.initialize,
+		"shutdown":      func(context.Context, json.RawMessage) (any, error) { s.cancelAll(); return nil, nil },
+		"sets/list":     s.listSets,
+		"sets/get":      s.getSet,
+		"sets/params":   s.params,
+		"sets/versions": s.versions,
+		"sets/rollback": s.rollback,
+		"runs/start":    s.startRun,
+		"runs/cancel":   s.cancelRun,
+	}
+}
+
+// SetInfo describes a command set. Commands and Params are only filled by
+// sets/get and sets/rollback.
+type SetInfo struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	AuthorName  string   `json:"authorName,omitempty"`
+	AuthorEmail string   `json:"authorEmail,omitempty"`
+	Tags        []string `json:"tags"`
+	CreatedAt   string   `json:"createdAt,omitempty"`
+	LastRun     string   `json:"lastRun,omitempty"`
+	Commands    []string `json:"commands,omitempty"`
+	Params      []string `json:"params,omitempty"`
+}
+
+// VersionInfo is one entry of a set's history.
+type VersionInfo struct {
+	Version     int      `json:"version"`
+	CreatedAt   string   `json:"createdAt"`
+	Operation   string   `json:"operation"`
+	AuthorName  string   `json:"authorName,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Commands    []string `json:"commands"`
+}
+
+type nameParams struct {
+	Name string `json:"name"`
+}
+
+func infoOf(cs adapters.CommandSetSummary) SetInfo {
+	tags := cs.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return SetInfo{Name: cs.Name, Description: cs.Description, AuthorName: cs.AuthorName, AuthorEmail: cs.AuthorEmail,
+		Tags: tags, CreatedAt: cs.CreatedAt, LastRun: cs.LastRun, Commands: cs.Commands}
+}
+
+// modelError maps model errors to JSON-RPC errors.
+func modelError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, adapters.ErrNotFound), errors.Is(err, model.ErrNotFound):
+		return &Error{Code: CodeNotFound, Message: err.Error()}
+	case errors.Is(err, model.ErrMissingParams):
+		return &Error{Code: CodeInvalidParams, Message: err.Error()}
+	case errors.Is(err, model.ErrRefused):
+		return &Error{Code: CodeRefused, Message: err.Error()}
+	case errors.Is(err, model.ErrBusy):
+		return &Error{Code: CodeBusy, Message: err.Error()}
+	}
+	return err
+}
+
+func (s *Server) initialize(context.Context, json.RawMessage) (any, error) {
+	names := make([]string, 0, len(s.methods()))
+	for name := range s.methods() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return map[string]any{
+		"serverInfo":   map[string]string{"name": "krnr", "version": version.Version},
+		"capabilities": map[string]any{"methods": names, "notifications": []string{RunEventMethod}},
+	}, nil
+}
+
+func (s *Server) listSets(ctx context.Context, raw json.RawMessage) (any, error) {
+	var p struct {
+		Query string `json:"query"`
+		Tag   string `json:"tag"`
+	}
+	if err := decodeParams(raw, &p); err != nil {
+		return nil, err
+	}
+	if err := s.model.RefreshList(ctx); err != nil {
+		return nil, err
+	}
+	out := []SetInfo{}
+	for _, cs := range s.model.ListCached() {
+		if p.Tag != "" && !hasTag(cs.Tags, p.Tag) {
+			continue
+		}
+		if p.Query != "" && !registry.FuzzyMatch(cs.Name, p.Query) && !registry.FuzzyMatch(cs.Description, p.Query) {
+			continue
+		}
+		out = append(out, infoOf(cs))
+	}
+	return out, nil
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) fullSet(ctx context.Context, name string) (SetInfo, error) {
+	cs, err := s.model.GetCommandSet(ctx, name)
+	if err != nil {
+		return SetInfo{}, modelError(err)
+	}
+	params, err := s.model.Params(ctx, name)
+	if err != nil {
+		return SetInfo{}, modelError(err)
+	}
+	info := infoOf(cs)
+	if info.Commands == nil {
+		info.Commands = []string{}
+	}
+	info.Params = params
+	return info, nil
+}
+
+func (s *Server) getSet(ctx context.Context, raw json.RawMessage) (any, error) {
+	var p nameParams
+	if err := decodeParams(raw, &p); err != nil {
+		return nil, err
+	}
+	return s.fullSet(ctx, p.Name)
+}
+
+func (s *Server) params(ctx context.Context, raw json.RawMessage) (any, error) {
+	var p nameParams
+	if err := decodeParams(raw, &p); err != nil {
+		return nil, err
+	}
+	params, err := s.model.Params(ctx, p.Name)
+	if err != nil {
+		return nil, modelError(err)
+	}
+	return map[string][]string{"params": params}, nil
+}
+
+func (s *Server) versions(ctx context.Context, raw json.RawMessage) (any, error) {
+	var p nameParams
+	if err := decodeParams(raw, &p); err != nil {
+		return nil, err
+	}
+	if _, err := s.model.GetCommandSet(ctx, p.Name); err != nil {
+		return nil, modelError(err)
+	}
+	vs, err := s.model.ListVersions(ctx, p.Name)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]VersionInfo, 0, len(vs))
+	for _, v := range vs {
+		cmds := v.Commands
+		if cmds == nil {
+			cmds = []string{}
+		}
+		out = append(out, VersionInfo{Version: v.Version, CreatedAt: v.CreatedAt, Operation: v.Operation,
+			AuthorName: v.AuthorName, Description: v.Description, Commands: cmds})
+	}
+	return out, nil
+}
+
+func (s *Server) rollback(ctx context.Context, raw json.RawMessage) (any, error) {
+	var p struct {
+		Name    string `json:"name"`
+		Version int    `json:"version"`
+	}
+	if err := decodeParams(raw, &p); err != nil {
+		return nil, err
+	}
+	if _, err := s.model.GetCommandSet(ctx, p.Name); err != nil {
+		return nil, modelError(err)
+	}
+	if err := s.model.ApplyVersion(ctx, p.Name, p.Version); err != nil {
+		return nil, &Error{Code: CodeInvalidParams, Message: err.Error()}
+	}
+	return s.fullSet(ctx, p.Name)
+}
+
+// runStarted is the result of runs/start; once it has been sent the run's
+// events are streamed as runs/event notifications.
+type runStarted struct {
+	RunID string `json:"runId"`
+	start func()
+}
+
+func (r runStarted) afterResponse() { r.start() }
+
+// RunEventParams are the params of a runs/event notification. Event has the
+// same form as a line of `krnr run --output jsonl`.
+type RunEventParams struct {
+	RunID string            `json:"runId"`
+	Event adapters.RunEvent `json:"event"`
+}
+
+func (s *Server) startRun(ctx context.Context, raw json.RawMessage) (any, error) {
+	var p struct {
+		Name   string            `json:"name"`
+		Params map[string]string `json:"params"`
+	}
+	if err := decodeParams(raw, &p); err != nil {
+		return nil, err
+	}
+	h, err := s.model.RunWithParams(ctx, p.Name, p.Params)
+	if err != nil {
+		return nil, modelError(err)
+	}
+	id := s.newRunID()
+	// runs admitted through the pipeline already have an ID in history
+	if r, ok := h.(interface{ RunID() string }); ok {
+		id = r.RunID()
+	}
+	s.mu.Lock()
+	s.runs[id] = h
+	s.mu.Unlock()
+	s.wg.Add(1)
+	return runStarted{RunID: id, start: func() { go s.stream(id, p.Name, h) }}, nil
+}
+
+// stream forwards a run's events as notifications, framed by run_started
+// and run_finished.
+func (s *Server) stream(id, name string, h adapters.RunHandle) {
+	defer s.wg.Done()
+	em := adapters.NewEventEmitter(id, func(ev adapters.RunEvent) {
+		s.conn.notify(RunEventMethod, RunEventParams{RunID: id, Event: ev})
+	})
+	em.Emit(adapters.RunEvent{Type: adapters.EventRunStarted, Set: name})
+	started := time.Now()
+	var runErr error
+	for ev := range h.Events() {
+		if ev.Err != nil {
+			runErr = ev.Err
+		}
+		em.Emit(ev)
+	}
+	s.mu.Lock()
+	delete(s.runs, id)
+	s.mu.Unlock()
+	em.Emit(adapters.RunEvent{Type: adapters.EventRunFinished, Set: name, ExitCode: runner.ExitCode(runErr), Duration: time.Since(started), Err: runErr})
+}
+
+func (s *Server) cancelRun(_ context.Context, raw json.RawMessage) (any, error) {
+	var p struct {
+		RunID string `json:"runId"`
+	}
+	if err := decodeParams(raw, &p); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	h, ok := s.runs[p.RunID]
+	s.mu.Unlock()
+	if ok {
+		h.Cancel()
+	}
+	return map[string]bool{"cancelled": ok}, nil
+}
+
+func (s *Server) cancelAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, h := range s.runs {
+		h.Cancel()
+	}
+}
+
+// wait cancels runs still in progress and waits for their streams to end.
+func (s *Server) wait() {
+	s.cancelAll()
+	s.wg.Wait()
+}
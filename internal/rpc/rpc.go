@@ -0,0 +1,202 @@
+// Package rpc implements `krnr rpc --stdio`: a JSON-RPC 2.0 server using
+// LSP-style "Content-Length" framing, so editor extensions can list, preview
+// and run command sets. Operations go through the TUI's model.UIModel so the
+// business logic lives in one place.
+package rpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// JSON-RPC error codes. The -320xx range is reserved for implementation
+// defined server errors.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+	CodeNotFound       = -32001
+	CodeRefused        = -32002
+	CodeBusy           = -32003
+)
+
+// Error is a JSON-RPC error object.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+type notification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+// ReadMessage reads one framed message: headers ("Content-Length: N" is
+// required), an empty line, then N bytes of JSON.
+func ReadMessage(r *bufio.Reader) ([]byte, error) {
+	hdr, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		if errors.Is(err, io.EOF) && len(hdr) == 0 {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(hdr.Get("Content-Length")))
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid Content-Length header %q", hdr.Get("Content-Length"))
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// WriteMessage writes body as one framed message.
+func WriteMessage(w io.Writer, body []byte) error {
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err := w.Write(body)
+	return err
+}
+
+// conn serialises writes of responses and notifications.
+type conn struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func (c *conn) send(v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_ = WriteMessage(c.out, b)
+}
+
+func (c *conn) reply(id json.RawMessage, result any, err error) {
+	resp := response{JSONRPC: "2.0", ID: id}
+	if len(id) == 0 {
+		resp.ID = json.RawMessage("null")
+	}
+	if err != nil {
+		var re *Error
+		if !errors.As(err, &re) {
+			re = &Error{Code: CodeInternalError, Message: err.Error()}
+		}
+		resp.Error = re
+	} else {
+		b, merr := json.Marshal(result)
+		if merr != nil {
+			resp.Error = &Error{Code: CodeInternalError, Message: merr.Error()}
+		} else {
+			resp.Result = b
+		}
+	}
+	c.send(resp)
+}
+
+func (c *conn) notify(method string, params any) {
+	c.send(notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// afterResponder results run a follow-up once their response has been
+// written, so that notifications about them arrive after it.
+type afterResponder interface {
+	afterResponse()
+}
+
+// Serve reads requests from in and writes responses and notifications to out
+// until in is exhausted or an "exit" notification arrives. Runs still in
+// progress are then cancelled.
+func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer s.wait()
+	defer cancel()
+	s.conn = &conn{out: out}
+	r := bufio.NewReader(in)
+	for {
+		body, err := ReadMessage(r)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			s.conn.reply(nil, nil, &Error{Code: CodeParseError, Message: err.Error()})
+			continue
+		}
+		if req.JSONRPC != "2.0" || req.Method == "" {
+			s.conn.reply(req.ID, nil, &Error{Code: CodeInvalidRequest, Message: "not a JSON-RPC 2.0 request"})
+			continue
+		}
+		if req.Method == "exit" {
+			return nil
+		}
+		h, ok := s.methods()[req.Method]
+		if len(req.ID) == 0 {
+			// notifications get no response; unknown ones (e.g. LSP's
+			// $/cancelRequest) are ignored
+			if ok {
+				if result, err := h(ctx, req.Params); err == nil {
+					if ar, ok := result.(afterResponder); ok {
+						ar.afterResponse()
+					}
+				}
+			}
+			continue
+		}
+		if !ok {
+			s.conn.reply(req.ID, nil, &Error{Code: CodeMethodNotFound, Message: "method not found: " + req.Method})
+			continue
+		}
+		result, err := h(ctx, req.Params)
+		s.conn.reply(req.ID, result, err)
+		if ar, ok := result.(afterResponder); ok && err == nil {
+			ar.afterResponse()
+		}
+	}
+}
+
+// decodeParams unmarshals params into v, reporting invalid params.
+func decodeParams(raw json.RawMessage, v any) error {
+	if len(raw) == 0 {
+		raw = json.RawMessage("{}")
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return &Error{Code: CodeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
@@ -0,0 +1,300 @@
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VoxDroid/krnr/internal/config"
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/executor"
+	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/runner"
+	"github.com/VoxDroid/krnr/internal/tui/adapters"
+	"github.com/VoxDroid/krnr/internal/tui/model"
+)
+
+// echoRunner prints each command instead of running it.
+type echoRunner struct{}
+
+func (echoRunner) Execute(_ context.Context, command string, _ string, _ io.Reader, stdout io.Writer, _ io.Writer) error {
+	_, _ = fmt.Fprintf(stdout, "ran %s\n", command)
+	return nil
+}
+
+// client drives a Server over pipes.
+type client struct {
+	t      *testing.T
+	in     *io.PipeWriter
+	out    *bufio.Reader
+	done   chan error
+	nextID int
+}
+
+type message struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+}
+
+// blockingRunner runs every command until its context ends.
+type blockingRunner struct{}
+
+func (blockingRunner) Execute(ctx context.Context, _ string, _ string, _ io.Reader, _ io.Writer, _ io.Writer) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func newClient(t *testing.T) (*client, *registry.Repository) {
+	t.Helper()
+	return newGatedClient(t, echoRunner{}, 0)
+}
+
+// newGatedClient starts a server whose runs execute through ex and are
+// aborted after timeout (0 for none).
+func newGatedClient(t *testing.T, ex executor.Runner, timeout time.Duration) (*client, *registry.Repository) {
+	t.Helper()
+	_ = os.Setenv(config.EnvKRNRHome, t.TempDir())
+	old := os.Getenv(config.EnvKRNRDB)
+	_ = os.Setenv(config.EnvKRNRDB, filepath.Join(t.TempDir(), "krnr.db"))
+	t.Cleanup(func() { _ = os.Setenv(config.EnvKRNRDB, old) })
+	dbConn, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB(): %v", err)
+	}
+	t.Cleanup(func() { _ = dbConn.Close() })
+	r := registry.NewRepository(dbConn)
+
+	m := model.New(adapters.NewRegistryAdapter(r), adapters.NewExecutorAdapter(ex), nil, nil)
+	m.SetGate(&runner.Gate{Options: runner.Options{Trigger: "rpc"}, Timeout: timeout})
+	srv := NewServer(m, func() string { return "run-1" })
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c := &client{t: t, in: inW, out: bufio.NewReader(outR), done: make(chan error, 1)}
+	go func() {
+		err := srv.Serve(context.Background(), inR, outW)
+		_ = outW.Close()
+		c.done <- err
+	}()
+	t.Cleanup(func() { _ = inW.Close() })
+	return c, r
+}
+
+func (c *client) send(v any) {
+	c.t.Helper()
+	b, _ := json.Marshal(v)
+	if err := WriteMessage(c.in, b); err != nil {
+		c.t.Fatalf("write: %v", err)
+	}
+}
+
+func (c *client) read() message {
+	c.t.Helper()
+	body, err := ReadMessage(c.out)
+	if err != nil {
+		c.t.Fatalf("read: %v", err)
+	}
+	var msg message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		c.t.Fatalf("decode %s: %v", body, err)
+	}
+	return msg
+}
+
+// call sends a request and returns its response.
+func (c *client) call(method string, params any) message {
+	c.t.Helper()
+	c.nextID++
+	c.send(map[string]any{"jsonrpc": "2.0", "id": c.nextID, "method": method, "params": params})
+	msg := c.read()
+	if string(msg.ID) != fmt.Sprint(c.nextID) {
+		c.t.Fatalf("expected response %d, got %+v", c.nextID, msg)
+	}
+	return msg
+}
+
+func TestFraming(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteMessage(&buf, []byte(`{"a":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "Content-Length: 7\r\n\r\n{\"a\":1}" {
+		t.Fatalf("unexpected frame %q", buf.String())
+	}
+	r := bufio.NewReader(strings.NewReader(buf.String() + "Content-Type: application/json\r\nContent-Length: 2\r\n\r\n{}"))
+	for _, want := range []string{`{"a":1}`, `{}`} {
+		got, err := ReadMessage(r)
+		if err != nil || string(got) != want {
+			t.Fatalf("ReadMessage = %q %v, want %q", got, err, want)
+		}
+	}
+	if _, err := ReadMessage(r); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+	if _, err := ReadMessage(bufio.NewReader(strings.NewReader("Content-Length: x\r\n\r\n"))); err == nil {
+		t.Fatalf("expected error for a bad Content-Length")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	c, _ := newClient(t)
+	if err := WriteMessage(c.in, []byte("{not json")); err != nil {
+		t.Fatal(err)
+	}
+	if msg := c.read(); msg.Error == nil || msg.Error.Code != CodeParseError {
+		t.Fatalf("expected parse error, got %+v", msg)
+	}
+	if msg := c.call("nope", nil); msg.Error == nil || msg.Error.Code != CodeMethodNotFound {
+		t.Fatalf("expected method not found, got %+v", msg)
+	}
+	if msg := c.call("sets/get", []int{1}); msg.Error == nil || msg.Error.Code != CodeInvalidParams {
+		t.Fatalf("expected invalid params, got %+v", msg)
+	}
+	if msg := c.call("sets/get", map[string]string{"name": "missing"}); msg.Error == nil || msg.Error.Code != CodeNotFound {
+		t.Fatalf("expected not found, got %+v", msg)
+	}
+}
+
+func TestSetsMethods(t *testing.T) {
+	c, r := newClient(t)
+	desc := "deploy the app"
+	if _, err := r.CreateCommandSet("deploy", &desc, nil, nil, []string{"echo {{env}}"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.CreateCommandSet("other", nil, nil, nil, []string{"echo other"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.AddTagToCommandSet(1, "ops"); err != nil {
+		t.Fatal(err)
+	}
+
+	var sets []SetInfo
+	_ = json.Unmarshal(c.call("sets/list", map[string]string{"tag": "ops"}).Result, &sets)
+	if len(sets) != 1 || sets[0].Name != "deploy" || sets[0].Tags[0] != "ops" {
+		t.Fatalf("unexpected sets/list result %+v", sets)
+	}
+	_ = json.Unmarshal(c.call("sets/list", map[string]string{"query": "other"}).Result, &sets)
+	if len(sets) != 1 || sets[0].Name != "other" {
+		t.Fatalf("unexpected sets/list query result %+v", sets)
+	}
+
+	var set SetInfo
+	_ = json.Unmarshal(c.call("sets/get", map[string]string{"name": "deploy"}).Result, &set)
+	if set.Description != desc || set.Commands[0] != "echo {{env}}" || set.Params[0] != "env" {
+		t.Fatalf("unexpected sets/get result %+v", set)
+	}
+
+	if err := r.ReplaceCommands(1, []string{"echo v2"}); err != nil {
+		t.Fatal(err)
+	}
+	var versions []VersionInfo
+	_ = json.Unmarshal(c.call("sets/versions", map[string]string{"name": "deploy"}).Result, &versions)
+	if len(versions) < 2 || versions[0].Commands[0] != "echo v2" {
+		t.Fatalf("unexpected sets/versions result %+v", versions)
+	}
+	first := versions[len(versions)-1].Version
+	_ = json.Unmarshal(c.call("sets/rollback", map[string]any{"name": "deploy", "version": first}).Result, &set)
+	if set.Commands[0] != "echo {{env}}" {
+		t.Fatalf("unexpected sets/rollback result %+v", set)
+	}
+}
+
+func TestRunStreamsEventsAfterResponse(t *testing.T) {
+	c, r := newClient(t)
+	if _, err := r.CreateCommandSet("greet", nil, nil, nil, []string{"echo {{who}}"}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := c.call("runs/start", map[string]any{"name": "greet"}); msg.Error == nil || msg.Error.Code != CodeInvalidParams {
+		t.Fatalf("expected missing params error, got %+v", msg)
+	}
+
+	msg := c.call("runs/start", map[string]any{"name": "greet", "params": map[string]string{"who": "world"}})
+	var started struct {
+		RunID string `json:"runId"`
+	}
+	if msg.Error != nil || json.Unmarshal(msg.Result, &started) != nil || started.RunID == "" {
+		t.Fatalf("unexpected runs/start response %+v", msg)
+	}
+	var types []string
+	var output string
+	for {
+		n := c.read()
+		if n.Method != RunEventMethod {
+			t.Fatalf("expected %s notification, got %+v", RunEventMethod, n)
+		}
+		var p struct {
+			RunID string         `json:"runId"`
+			Event map[string]any `json:"event"`
+		}
+		_ = json.Unmarshal(n.Params, &p)
+		if p.RunID != started.RunID {
+			t.Fatalf("unexpected run id in %s", n.Params)
+		}
+		typ, _ := p.Event["type"].(string)
+		types = append(types, typ)
+		if typ == string(adapters.EventOutput) {
+			s, _ := p.Event["data"].(string)
+			output += s
+		}
+		if typ == string(adapters.EventRunFinished) {
+			if code, _ := p.Event["exit_code"].(float64); code != 0 {
+				t.Fatalf("unexpected exit code in %s", n.Params)
+			}
+			break
+		}
+	}
+	if types[0] != string(adapters.EventRunStarted) || !strings.Contains(output, "ran echo world") {
+		t.Fatalf("unexpected events %v (output %q)", types, output)
+	}
+	// the run is recorded under the ID its events carry
+	runs, err := r.ListRuns("greet", 0)
+	if err != nil || len(runs) != 1 || runs[0].RunID != started.RunID || runs[0].Trigger != "rpc" || runs[0].Status != registry.RunStatusOK {
+		t.Fatalf("unexpected run history %+v %v", runs, err)
+	}
+
+	c.send(map[string]string{"jsonrpc": "2.0", "method": "exit"})
+	if err := <-c.done; err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	c, r := newGatedClient(t, blockingRunner{}, 50*time.Millisecond)
+	if _, err := r.CreateCommandSet("hang", nil, nil, nil, []string{"sleep 60"}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := c.call("runs/start", map[string]any{"name": "hang"}); msg.Error != nil {
+		t.Fatalf("runs/start: %+v", msg.Error)
+	}
+	for {
+		var p struct {
+			Event struct {
+				Type     string  `json:"type"`
+				ExitCode float64 `json:"exit_code"`
+				Error    string  `json:"error"`
+			} `json:"event"`
+		}
+		_ = json.Unmarshal(c.read().Params, &p)
+		if p.Event.Type != string(adapters.EventRunFinished) {
+			continue
+		}
+		if !strings.Contains(p.Event.Error, "run timed out after 50ms") || p.Event.ExitCode != -1 {
+			t.Fatalf("expected the run to time out with exit code -1, got %+v", p.Event)
+		}
+		break
+	}
+	runs, err := r.ListRuns("hang", 0)
+	if err != nil || len(runs) != 1 || runs[0].Status != registry.RunStatusFailed {
+		t.Fatalf("expected a failed run in history, got %+v %v", runs, err)
+	}
+}
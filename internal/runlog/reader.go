@@ -0,0 +1,337 @@
+package runlog
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VoxDroid/krnr/internal/config"
+)
+
+// Run describes a stored run log.
+type Run struct {
+	Set     string
+	ID      string
+	Path    string
+	Size    int64
+	ModTime time.Time
+	// Active is true while the run is still in progress (uncompressed log).
+	Active bool
+}
+
+// ListRuns returns the stored runs for set, newest first.
+func ListRuns(set string) ([]Run, error) {
+	dir, err := SetDir(set)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var out []Run
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		r, ok := runFromEntry(set, dir, e)
+		if ok {
+			out = append(out, r)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID > out[j].ID })
+	return out, nil
+}
+
+func runFromEntry(set, dir string, e os.DirEntry) (Run, bool) {
+	name := e.Name()
+	r := Run{Set: set, Path: filepath.Join(dir, name)}
+	switch {
+	case strings.HasSuffix(name, finishedExt):
+		r.ID = strings.TrimSuffix(name, finishedExt)
+	case strings.HasSuffix(name, activeExt):
+		r.ID = strings.TrimSuffix(name, activeExt)
+		r.Active = true
+	default:
+		return Run{}, false
+	}
+	if info, err := e.Info(); err == nil {
+		r.Size = info.Size()
+		r.ModTime = info.ModTime()
+	}
+	return r, true
+}
+
+// FindRun returns the run with the given id, or the most recent run when id
+// is empty.
+func FindRun(set, id string) (Run, error) {
+	runs, err := ListRuns(set)
+	if err != nil {
+		return Run{}, err
+	}
+	if len(runs) == 0 {
+		return Run{}, fmt.Errorf("no logs for %s", set)
+	}
+	if id == "" {
+		return runs[0], nil
+	}
+	for _, r := range runs {
+		if r.ID == id {
+			return r, nil
+		}
+	}
+	return Run{}, fmt.Errorf("run %s not found for %s", id, set)
+}
+
+// Filter selects log lines when reading a run.
+type Filter struct {
+	// Step limits output to lines recorded for step N (1-based); 0 means all.
+	Step int
+	// Grep, when non-nil, keeps only lines whose text matches.
+	Grep *regexp.Regexp
+}
+
+// Match reports whether a raw log line passes the filter.
+func (f Filter) Match(line string) bool {
+	tag, text := splitLine(line)
+	if f.Step > 0 && tag != stepTag(f.Step) {
+		return false
+	}
+	if f.Grep != nil && !f.Grep.MatchString(text) {
+		return false
+	}
+	return true
+}
+
+// splitLine returns the bracketed tag and message of a log line.
+func splitLine(line string) (string, string) {
+	open := strings.IndexByte(line, '[')
+	if open < 0 {
+		return "", line
+	}
+	end := strings.IndexByte(line[open:], ']')
+	if end < 0 {
+		return "", line
+	}
+	tag := line[open+1 : open+end]
+	return tag, strings.TrimPrefix(line[open+end+1:], " ")
+}
+
+// Copy writes the lines of run r that pass f to w.
+func Copy(w io.Writer, r Run, f Filter) error {
+	rc, err := open(r)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rc.Close() }()
+	sc := bufio.NewScanner(rc)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		if f.Match(sc.Text()) {
+			if _, err := fmt.Fprintln(w, sc.Text()); err != nil {
+				return err
+			}
+		}
+	}
+	return sc.Err()
+}
+
+func open(r Run) (io.ReadCloser, error) {
+	f, err := os.Open(r.Path)
+	if err != nil {
+		return nil, err
+	}
+	if r.Active {
+		return f, nil
+	}
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("open compressed log: %w", err)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{zr, f}, nil
+}
+
+// pollInterval controls how often Follow checks for new output.
+var pollInterval = 250 * time.Millisecond
+
+// Follow streams an in-progress run to w until the run finishes or ctx is
+// cancelled. Finished runs are copied in full.
+func Follow(ctx context.Context, w io.Writer, r Run, f Filter) error {
+	if !r.Active {
+		return Copy(w, r, f)
+	}
+	fh, err := os.Open(r.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// finished between listing and opening
+			return Copy(w, finishedRun(r), f)
+		}
+		return err
+	}
+	defer func() { _ = fh.Close() }()
+	fl := &follower{br: bufio.NewReader(fh), w: w, f: f}
+	for {
+		if err := fl.drain(); err != nil {
+			return err
+		}
+		if _, statErr := os.Stat(r.Path); os.IsNotExist(statErr) {
+			// the writer compressed and removed the active file; our handle
+			// still sees anything written before removal
+			return fl.drain()
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// follower incrementally copies complete lines from a growing file.
+type follower struct {
+	br      *bufio.Reader
+	w       io.Writer
+	f       Filter
+	pending string
+}
+
+// drain copies every complete line currently available and keeps any partial
+// trailing line for the next call.
+func (fl *follower) drain() error {
+	for {
+		line, err := fl.br.ReadString('\n')
+		fl.pending += line
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if fl.f.Match(strings.TrimRight(fl.pending, "\n")) {
+			if _, werr := io.WriteString(fl.w, fl.pending); werr != nil {
+				return werr
+			}
+		}
+		fl.pending = ""
+	}
+}
+
+func finishedRun(r Run) Run {
+	r.Active = false
+	r.Path = strings.TrimSuffix(r.Path, activeExt) + finishedExt
+	return r
+}
+
+// Retention bounds how many finished run logs are kept per set. Zero values
+// disable the corresponding limit.
+type Retention struct {
+	MaxAge   time.Duration
+	MaxRuns  int
+	MaxBytes int64
+}
+
+// Configured returns the retention of the log_retention and log_keep
+// settings, which is applied after every run.
+func Configured(s *config.Settings) Retention {
+	return Retention{MaxAge: s.LogRetention, MaxRuns: s.LogKeep}
+}
+
+// Prune deletes finished run logs for set that fall outside ret and returns
+// the removed run IDs. In-progress runs are kept and do not count towards
+// the limits, unless their log was not written to for MaxAge: the run was
+// then killed before it could finish it.
+func Prune(set string, ret Retention) ([]string, error) {
+	runs, err := ListRuns(set)
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	var kept int
+	var total int64
+	cutoff := now().Add(-ret.MaxAge)
+	for _, r := range runs {
+		stale := ret.MaxAge > 0 && r.ModTime.Before(cutoff)
+		if r.Active && !stale {
+			continue
+		}
+		drop := stale ||
+			(ret.MaxRuns > 0 && kept >= ret.MaxRuns) ||
+			(ret.MaxBytes > 0 && total+r.Size > ret.MaxBytes)
+		if !drop {
+			kept++
+			total += r.Size
+			continue
+		}
+		if err := os.Remove(r.Path); err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+		removed = append(removed, r.ID)
+	}
+	return removed, nil
+}
+
+// PruneAll applies ret to every set that has stored logs.
+func PruneAll(ret Retention) (map[string][]string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	out := map[string][]string{}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		set := setName(e.Name())
+		removed, err := Prune(set, ret)
+		if err != nil {
+			return out, err
+		}
+		if len(removed) > 0 {
+			out[set] = removed
+		}
+	}
+	return out, nil
+}
+
+// ParseSize parses human-friendly sizes such as 512K, 10MB or 1G.
+func ParseSize(s string) (int64, error) {
+	t := strings.ToUpper(strings.TrimSpace(s))
+	t = strings.TrimSuffix(t, "B")
+	mult := int64(1)
+	switch {
+	case strings.HasSuffix(t, "K"):
+		mult, t = 1<<10, strings.TrimSuffix(t, "K")
+	case strings.HasSuffix(t, "M"):
+		mult, t = 1<<20, strings.TrimSuffix(t, "M")
+	case strings.HasSuffix(t, "G"):
+		mult, t = 1<<30, strings.TrimSuffix(t, "G")
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(t), 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q (expected e.g. 512K, 10MB, 1G)", s)
+	}
+	return n * mult, nil
+}
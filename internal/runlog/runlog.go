@@ -0,0 +1,262 @@
+// Package runlog persists the combined output of command set runs under
+// KRNR_HOME/logs/<set>/<run-id> so it can be inspected after the run ends.
+//
+// While a run is in progress its log is written as plain text to
+// `<run-id>.log` (so it can be followed); when the run finishes the file is
+// compressed to `<run-id>.log.gz` and the plain file is removed.
+package runlog
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VoxDroid/krnr/internal/config"
+)
+
+const (
+	activeExt   = ".log"
+	finishedExt = ".log.gz"
+	// timeLayout is fixed-width so log lines align and sort lexically.
+	timeLayout = "2006-01-02T15:04:05.000Z07:00"
+)
+
+// now is a package-level variable so tests can control timestamps.
+var now = func() time.Time { return time.Now().UTC() }
+
+// Dir returns the root directory holding run logs (KRNR_HOME/logs).
+func Dir() (string, error) {
+	d, err := config.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(d, "logs"), nil
+}
+
+// SetDir returns the directory holding run logs for the named set.
+func SetDir(set string) (string, error) {
+	d, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(d, dirName(set)), nil
+}
+
+// dirName maps a set name to a single safe path element. Bytes outside
+// [A-Za-z0-9._-] are percent-encoded, as are the dots of an all-dot name, so
+// names can never escape the logs directory and distinct names never share a
+// directory ("deploy prod" and "deploy_prod" stay apart). setName reverses it.
+func dirName(set string) string {
+	dots := strings.Trim(set, ".") == ""
+	var b strings.Builder
+	for i := 0; i < len(set); i++ {
+		c := set[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+			b.WriteByte(c)
+		case c == '-' || c == '_':
+			b.WriteByte(c)
+		case c == '.' && !dots:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	if b.Len() == 0 {
+		return "%"
+	}
+	return b.String()
+}
+
+// setName returns the set name stored in the logs directory entry dir.
+func setName(dir string) string {
+	if dir == "%" {
+		return ""
+	}
+	if name, err := url.PathUnescape(dir); err == nil {
+		return name
+	}
+	return dir
+}
+
+// NewRunID returns a sortable, unique run identifier such as
+// 20261018T030000Z-1a2b3c.
+func NewRunID() string {
+	b := make([]byte, 3)
+	_, _ = rand.Read(b)
+	return now().Format("20060102T150405Z") + "-" + hex.EncodeToString(b)
+}
+
+// Writer records a single run. It implements io.Writer for raw command output;
+// output is split into lines and each line is timestamped and tagged with the
+// step currently executing. Writer is safe for concurrent use so it can
+// receive both stdout and stderr.
+type Writer struct {
+	Set   string
+	RunID string
+
+	mu      sync.Mutex
+	f       *os.File
+	bw      *bufio.Writer
+	path    string
+	step    int
+	partial []byte
+	closed  bool
+}
+
+// Create starts a new run log for set and writes the run header.
+func Create(set string) (*Writer, error) {
+	return CreateWithID(set, NewRunID())
+}
+
+// CreateWithID is like Create for a run whose ID was already assigned, so
+// that the log and the run's history entry share it.
+func CreateWithID(set, id string) (*Writer, error) {
+	dir, err := SetDir(set)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create log dir: %w", err)
+	}
+	path := filepath.Join(dir, id+activeExt)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("create run log: %w", err)
+	}
+	w := &Writer{Set: set, RunID: id, f: f, bw: bufio.NewWriter(f), path: path}
+	w.writeLine("run", fmt.Sprintf("start %s (run %s)", set, id))
+	return w, nil
+}
+
+// StepStart marks the beginning of step n (1-based) running cmd. Callers
+// should pass an already redacted command so secrets are not persisted.
+func (w *Writer) StepStart(n int, cmd string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flushPartialLocked()
+	w.step = n
+	w.writeLine(stepTag(n), "-> "+cmd)
+}
+
+// StepEnd records the result of the current step.
+func (w *Writer) StepEnd(exitCode int, d time.Duration, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flushPartialLocked()
+	msg := fmt.Sprintf("exit %d (%s)", exitCode, d.Round(time.Millisecond))
+	if err != nil {
+		msg += ": " + firstLine(err.Error())
+	}
+	w.writeLine(stepTag(w.step), msg)
+}
+
+// Write implements io.Writer.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	data := append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		w.writeLine(stepTag(w.step), strings.TrimRight(string(data[:i]), "\r"))
+		data = data[i+1:]
+	}
+	w.partial = append([]byte(nil), data...)
+	// flush so followers see output as it is produced
+	_ = w.bw.Flush()
+	return len(p), nil
+}
+
+// Close writes the run trailer, compresses the log and removes the plain
+// in-progress file. runErr is the overall run result (nil on success).
+func (w *Writer) Close(runErr error) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.flushPartialLocked()
+	if runErr != nil {
+		w.writeLine("run", "finished with error: "+firstLine(runErr.Error()))
+	} else {
+		w.writeLine("run", "finished ok")
+	}
+	w.closed = true
+	if err := w.bw.Flush(); err != nil {
+		_ = w.f.Close()
+		return err
+	}
+	if err := w.f.Close(); err != nil {
+		return err
+	}
+	return compressFile(w.path, strings.TrimSuffix(w.path, activeExt)+finishedExt)
+}
+
+func (w *Writer) flushPartialLocked() {
+	if len(w.partial) == 0 {
+		return
+	}
+	w.writeLine(stepTag(w.step), strings.TrimRight(string(w.partial), "\r"))
+	w.partial = nil
+}
+
+func (w *Writer) writeLine(tag, text string) {
+	_, _ = fmt.Fprintf(w.bw, "%s [%s] %s\n", now().Format(timeLayout), tag, text)
+	_ = w.bw.Flush()
+}
+
+func stepTag(n int) string {
+	if n <= 0 {
+		return "run"
+	}
+	return fmt.Sprintf("step %d", n)
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+func compressFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("create compressed log: %w", err)
+	}
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	_ = in.Close()
+	return os.Remove(src)
+}
@@ -0,0 +1,193 @@
+package runlog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VoxDroid/krnr/internal/config"
+)
+
+func setupHome(t *testing.T) {
+	t.Helper()
+	old := os.Getenv(config.EnvKRNRHome)
+	_ = os.Setenv(config.EnvKRNRHome, t.TempDir())
+	t.Cleanup(func() { _ = os.Setenv(config.EnvKRNRHome, old) })
+}
+
+func writeRun(t *testing.T, set string) Run {
+	t.Helper()
+	w, err := Create(set)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	w.StepStart(1, "echo one")
+	_, _ = w.Write([]byte("one\npartial"))
+	w.StepEnd(0, 5*time.Millisecond, nil)
+	w.StepStart(2, "false")
+	_, _ = w.Write([]byte("error: boom\n"))
+	w.StepEnd(1, time.Millisecond, errors.New("exit status 1"))
+	if err := w.Close(errors.New("exit status 1")); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	r, err := FindRun(set, w.RunID)
+	if err != nil {
+		t.Fatalf("FindRun: %v", err)
+	}
+	return r
+}
+
+func TestWriterCompressesAndTagsSteps(t *testing.T) {
+	setupHome(t)
+	r := writeRun(t, "demo")
+	if r.Active || !strings.HasSuffix(r.Path, ".log.gz") {
+		t.Fatalf("expected finished compressed log, got %+v", r)
+	}
+
+	var buf bytes.Buffer
+	if err := Copy(&buf, r, Filter{}); err != nil {
+		t.Fatalf("Copy: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"[run] start demo", "[step 1] -> echo one", "[step 1] one", "[step 1] partial", "[step 2] exit 1", "[run] finished with error"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in log, got:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	if err := Copy(&buf, r, Filter{Step: 2, Grep: regexp.MustCompile("boom")}); err != nil {
+		t.Fatalf("Copy filtered: %v", err)
+	}
+	if got := strings.TrimSpace(buf.String()); !strings.HasSuffix(got, "[step 2] error: boom") || strings.Count(got, "\n") != 0 {
+		t.Fatalf("unexpected filtered output: %q", got)
+	}
+}
+
+func TestSetDirCannotEscapeLogsDir(t *testing.T) {
+	for _, name := range []string{"..", "../etc", "a/b", ".", ""} {
+		d := dirName(name)
+		if strings.ContainsAny(d, `/\`) || strings.Trim(d, ".") == "" {
+			t.Fatalf("dirName(%q) = %q is unsafe", name, d)
+		}
+	}
+}
+
+func TestSetDirIsReversible(t *testing.T) {
+	seen := map[string]string{}
+	for _, name := range []string{"deploy prod", "deploy_prod", "deploy%20prod", "a/b", "a_b", "..", "v1.2", "日本"} {
+		d := dirName(name)
+		if other, ok := seen[d]; ok {
+			t.Fatalf("%q and %q share log directory %q", name, other, d)
+		}
+		seen[d] = name
+		if got := setName(d); got != name {
+			t.Fatalf("setName(dirName(%q)) = %q", name, got)
+		}
+	}
+}
+
+func TestPruneByCount(t *testing.T) {
+	setupHome(t)
+	for i := 0; i < 3; i++ {
+		writeRun(t, "p")
+	}
+	runs, _ := ListRuns("p")
+	if len(runs) != 3 {
+		t.Fatalf("expected 3 runs, got %d", len(runs))
+	}
+	removed, err := Prune("p", Retention{MaxRuns: 1})
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if len(removed) != 2 {
+		t.Fatalf("expected 2 removed, got %v", removed)
+	}
+	runs, _ = ListRuns("p")
+	if len(runs) != 1 {
+		t.Fatalf("expected 1 run left, got %d", len(runs))
+	}
+}
+
+func TestPruneRemovesStaleActiveLogs(t *testing.T) {
+	setupHome(t)
+	stale, err := Create("p")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	defer func() { _ = stale.Close(nil) }()
+	live, err := Create("p")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	defer func() { _ = live.Close(nil) }()
+	run, err := FindRun("p", stale.RunID)
+	if err != nil || !run.Active {
+		t.Fatalf("expected an active run, got %+v %v", run, err)
+	}
+	// the process that wrote it was killed long ago
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(run.Path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	removed, err := Prune("p", Retention{MaxAge: 24 * time.Hour, MaxRuns: 1})
+	if err != nil || len(removed) != 1 || removed[0] != stale.RunID {
+		t.Fatalf("expected only the stale log to be removed, got %v %v", removed, err)
+	}
+	if _, err := FindRun("p", live.RunID); err != nil {
+		t.Fatalf("the live run's log must be kept: %v", err)
+	}
+}
+
+func TestFollowStopsWhenRunFinishes(t *testing.T) {
+	setupHome(t)
+	old := pollInterval
+	pollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { pollInterval = old })
+
+	w, err := Create("live")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	r, err := FindRun("live", "")
+	if err != nil || !r.Active {
+		t.Fatalf("expected active run, got %+v err=%v", r, err)
+	}
+	done := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		_ = Follow(context.Background(), &buf, r, Filter{})
+		done <- buf.String()
+	}()
+	w.StepStart(1, "echo hi")
+	_, _ = w.Write([]byte("hi\n"))
+	time.Sleep(30 * time.Millisecond)
+	_ = w.Close(nil)
+
+	select {
+	case out := <-done:
+		if !strings.Contains(out, "[step 1] hi") || !strings.Contains(out, "[run] finished ok") {
+			t.Fatalf("expected followed output, got %q", out)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Follow did not return after run finished")
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	cases := map[string]int64{"10": 10, "2K": 2048, "1MB": 1 << 20, "1g": 1 << 30}
+	for in, want := range cases {
+		got, err := ParseSize(in)
+		if err != nil || got != want {
+			t.Fatalf("ParseSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	if _, err := ParseSize("lots"); err == nil {
+		t.Fatalf("expected error for invalid size")
+	}
+}
@@ -0,0 +1,137 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/VoxDroid/krnr/internal/executor"
+	"github.com/VoxDroid/krnr/internal/runlog"
+	"github.com/VoxDroid/krnr/internal/tui/adapters"
+)
+
+// Stepper pauses a run before steps for a decision.
+type Stepper interface {
+	// ShouldPause reports whether to pause before step.
+	ShouldPause(step int) bool
+	// Pause waits for the decision on step of total and returns the command
+	// to run and its shown form (changed when edited) and whether to skip
+	// the step.
+	Pause(step, total int, command, shown string) (string, string, bool, error)
+}
+
+// ExecOptions configure Exec.
+type ExecOptions struct {
+	// Runner executes the commands.
+	Runner executor.Runner
+	// Events receives the run's events; nil means a new emitter.
+	Events *adapters.EventEmitter
+	// Dir is the working directory; empty means the current one.
+	Dir string
+	// Stdin is given to every command.
+	Stdin io.Reader
+	// Log, when set, marks the steps in the run log; Writers decide which
+	// output reaches it.
+	Log *runlog.Writer
+	// Writers returns the stdout and stderr of a step; nil sends both to
+	// output events only.
+	Writers func(step int) (io.Writer, io.Writer)
+	// Stepper, when set, pauses before steps.
+	Stepper Stepper
+	// Timeout bounds the time the commands run in total; time spent paused
+	// or prompting between them does not count. Zero means no limit.
+	Timeout time.Duration
+}
+
+// Exec runs the steps of a started run in order, stopping at the first
+// error, and finishes the run. Steps are resolved and checked as they come
+// unless Commands resolved them up front. Events from run_started to
+// run_finished go to eo.Events.
+func (r *Run) Exec(ctx context.Context, eo ExecOptions) error {
+	em := eo.Events
+	if em == nil {
+		em = adapters.NewEventEmitter(r.ID)
+	}
+	em.Emit(adapters.RunEvent{Type: adapters.EventRunStarted, Set: r.Set.Name, Steps: len(r.Set.Commands)})
+	var err error
+	var steps []time.Duration
+	var used time.Duration
+	for i := range r.Set.Commands {
+		var d time.Duration
+		var ran bool
+		if d, ran, err = r.step(ctx, eo, em, i+1, eo.Timeout-used); ran {
+			steps = append(steps, d)
+			used += d
+		}
+		if err != nil {
+			break
+		}
+	}
+	if steps == nil {
+		steps = []time.Duration{}
+	}
+	// on-failure hooks still fire when the run was cancelled or timed out
+	err = r.Finish(context.WithoutCancel(ctx), err, steps)
+	em.Emit(adapters.RunEvent{Type: adapters.EventRunFinished, Set: r.Set.Name, ExitCode: ExitCode(err), Duration: r.Elapsed(), Err: err})
+	return err
+}
+
+// step resolves, checks and executes a single step, giving the command what
+// is left of eo.Timeout. It returns how long the command ran and whether it
+// ran at all.
+func (r *Run) step(ctx context.Context, eo ExecOptions, em *adapters.EventEmitter, step int, left time.Duration) (time.Duration, bool, error) {
+	var command, shown string
+	var err error
+	if r.cmds != nil {
+		command, shown = r.cmds[step-1], r.shown[step-1]
+	} else if command, shown, err = r.Resolve(r.Set.Commands[step-1].Command); err != nil {
+		return 0, false, err
+	}
+	checked := r.cmds != nil
+	if eo.Stepper != nil && eo.Stepper.ShouldPause(step) {
+		em.Emit(adapters.RunEvent{Type: adapters.EventStepPaused, Step: step, Command: shown})
+		edited := command
+		var skip bool
+		command, shown, skip, err = eo.Stepper.Pause(step, len(r.Set.Commands), command, shown)
+		if err != nil || skip {
+			return 0, false, err
+		}
+		checked = checked && command == edited
+	}
+	if !checked {
+		if err := r.Check(command, shown); err != nil {
+			return 0, false, err
+		}
+	}
+	em.Emit(adapters.RunEvent{Type: adapters.EventStepStarted, Step: step, Command: shown})
+	if eo.Log != nil {
+		eo.Log.StepStart(step, shown)
+	}
+	stdout, stderr := em.OutputWriter(step, adapters.StreamStdout), em.OutputWriter(step, adapters.StreamStderr)
+	if eo.Writers != nil {
+		stdout, stderr = eo.Writers(step)
+	}
+	// dry runs only show the commands; keep their secrets out of it
+	if r.opts.DryRun {
+		command = shown
+	}
+	cctx := ctx
+	if eo.Timeout > 0 {
+		var cancel context.CancelFunc
+		cctx, cancel = context.WithTimeout(ctx, left)
+		defer cancel()
+	}
+	started := time.Now()
+	err = eo.Runner.Execute(cctx, command, eo.Dir, eo.Stdin, stdout, stderr)
+	elapsed := time.Since(started)
+	if err != nil && ctx.Err() == nil && errors.Is(cctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("run timed out after %s: %w", eo.Timeout, err)
+	}
+	if eo.Log != nil {
+		eo.Log.StepEnd(ExitCode(err), elapsed, err)
+	}
+	em.Emit(adapters.RunEvent{Type: adapters.EventStepFinished, Step: step, ExitCode: ExitCode(err), Duration: elapsed, Err: err})
+	return elapsed, true, err
+}
@@ -0,0 +1,65 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/VoxDroid/krnr/internal/registry"
+)
+
+// Gate starts runs for front ends that execute the commands themselves,
+// such as the TUI and the RPC server. They call Finish on the run once its
+// commands have ended.
+type Gate struct {
+	// Options apply to every run; Params and Confirm are set per run.
+	Options Options
+	// Timeout bounds how long the commands of each run may take, pauses
+	// included; 0 means no limit. Front ends apply it with Run.Bound.
+	Timeout time.Duration
+}
+
+// Admit prepares a run of the named set in repo with params, resolves and
+// checks all of its commands and starts it. confirmed answers the policy's
+// confirm rules; without it they refuse the run with ErrNeedsConfirm.
+func (g *Gate) Admit(ctx context.Context, repo *registry.Repository, name string, params map[string]string, confirmed bool) (*Run, error) {
+	opts := g.Options
+	opts.Params = map[string]string{}
+	for k, v := range params {
+		opts.Params[k] = v
+	}
+	if confirmed {
+		opts.Confirm = func(string) bool { return true }
+	}
+	run, err := Prepare(repo, name, opts)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := run.Commands(); err != nil {
+		return nil, err
+	}
+	if err := run.Start(ctx); err != nil {
+		return nil, err
+	}
+	run.timeout = g.Timeout
+	return run, nil
+}
+
+// Bound returns ctx bounded by the timeout the run was admitted with and a
+// func to call with the run's error once its commands have ended. It
+// releases the context and returns the error, explained when the timeout
+// ended the run.
+func (r *Run) Bound(ctx context.Context) (context.Context, func(runErr error) error) {
+	if r.timeout <= 0 {
+		return ctx, func(runErr error) error { return runErr }
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	return ctx, func(runErr error) error {
+		defer cancel()
+		if runErr != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("run timed out after %s: %w", r.timeout, runErr)
+		}
+		return runErr
+	}
+}
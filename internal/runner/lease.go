@@ -0,0 +1,113 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	osuser "os/user"
+	"time"
+
+	"github.com/VoxDroid/krnr/internal/jobs"
+	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/user"
+)
+
+// Lease timing: holders renew every LeaseHeartbeat; a lease not renewed for
+// LeaseStaleAfter is considered abandoned and may be taken over. LeaseRetry
+// is the polling interval of Options.Wait. Variables so tests can shorten
+// them.
+var (
+	LeaseHeartbeat  = 10 * time.Second
+	LeaseStaleAfter = 45 * time.Second
+	LeaseRetry      = 2 * time.Second
+)
+
+// leaseOwner identifies the person running krnr: the whoami profile when
+// set, otherwise the OS user.
+func leaseOwner() string {
+	if p := user.Label(); p != "" {
+		return p
+	}
+	if u, err := osuser.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return "unknown"
+}
+
+// DescribeLease renders the holder of a lease for messages.
+func DescribeLease(l *registry.Lease) string {
+	return fmt.Sprintf("%s (pid %d on %s) since %s, last heartbeat %s", l.Owner, l.PID, l.Host, l.AcquiredAt, l.HeartbeatAt)
+}
+
+// lease is a held lease that is renewed in the background until released.
+type lease struct {
+	r     *registry.Repository
+	set   string
+	token string
+	out   io.Writer
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// acquireLease claims the lease of an exclusive set. Without wait it fails
+// fast with ErrBusy naming the holder; with wait it polls until the lease
+// is free or ctx is cancelled, reporting the holder once on out. Leases
+// left behind by dead processes on this host are reclaimed immediately.
+func acquireLease(ctx context.Context, r *registry.Repository, set, token string, wait bool, out io.Writer) (*lease, error) {
+	host, _ := os.Hostname()
+	l := registry.Lease{SetName: set, Token: token, Owner: leaseOwner(), PID: os.Getpid(), Host: host}
+	announced := false
+	for {
+		ok, holder, err := r.AcquireLease(l, LeaseStaleAfter)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			rl := &lease{r: r, set: set, token: token, out: out, stop: make(chan struct{}), done: make(chan struct{})}
+			go rl.heartbeat()
+			return rl, nil
+		}
+		if holder.Host == host && holder.PID != os.Getpid() && !jobs.Alive(holder.PID) {
+			_ = r.ReleaseLease(set, holder.Token)
+			continue
+		}
+		if !wait {
+			msg := fmt.Sprintf("set %s is exclusive and already running: lease held by %s", set, DescribeLease(holder))
+			return nil, &refusal{msg: msg, kinds: []error{ErrBusy}}
+		}
+		if !announced {
+			_, _ = fmt.Fprintf(out, "waiting for lease on %s held by %s\n", set, DescribeLease(holder))
+			announced = true
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("gave up waiting for lease on %s: %w", set, ctx.Err())
+		case <-time.After(LeaseRetry):
+		}
+	}
+}
+
+func (l *lease) heartbeat() {
+	defer close(l.done)
+	t := time.NewTicker(LeaseHeartbeat)
+	defer t.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-t.C:
+			if ok, err := l.r.RenewLease(l.set, l.token); err == nil && !ok {
+				_, _ = fmt.Fprintf(l.out, "warning: lost lease on %s (it went stale and was taken over)\n", l.set)
+				return
+			}
+		}
+	}
+}
+
+// release stops the heartbeat and frees the lease.
+func (l *lease) release() {
+	close(l.stop)
+	<-l.done
+	_ = l.r.ReleaseLease(l.set, l.token)
+}
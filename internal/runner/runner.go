@@ -0,0 +1,349 @@
+// Package runner is the run pipeline shared by every way krnr runs a set:
+// `krnr run`, `krnr watch`, the hook, API and MCP servers, the TUI, the RPC
+// server and the Go SDK.
+//
+// A run is prepared (the set is looked up and must be trusted), its
+// commands are resolved and checked against the security policy, then it is
+// started (the lease of an exclusive set is taken, the run is recorded in
+// history and the pre-run hooks fire) and finished (the post-run or
+// on-failure hooks fire, the lease is released and the result recorded).
+// Exec runs the steps in between; front ends that execute commands
+// themselves call Start and Finish around their own execution.
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/VoxDroid/krnr/internal/lifecycle"
+	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/runlog"
+	"github.com/VoxDroid/krnr/internal/security"
+)
+
+// Reasons a run does not start. Every refusal matches ErrRefused; refusals
+// for an untrusted set also match ErrUntrusted and those a confirmation
+// could lift also match ErrNeedsConfirm.
+var (
+	ErrNotFound     = errors.New("command set not found")
+	ErrRefused      = errors.New("refusing to run potentially dangerous command")
+	ErrUntrusted    = errors.New("refusing to run untrusted set")
+	ErrNeedsConfirm = errors.New("needs confirmation")
+	ErrBusy         = errors.New("exclusive set is already running")
+)
+
+// refusal is an error with a message of its own that matches kinds with
+// errors.Is.
+type refusal struct {
+	msg   string
+	kinds []error
+}
+
+func (e *refusal) Error() string   { return e.msg }
+func (e *refusal) Unwrap() []error { return e.kinds }
+
+// Options configure a run.
+type Options struct {
+	// Trigger is what started the run as recorded in run history (e.g.
+	// "manual"); empty records nothing.
+	Trigger string
+	// RunID identifies the run in history, hooks and its lease; empty means
+	// a new one. Callers that keep a run log pass its ID.
+	RunID string
+	// Params are the parameter values. Values prompted for are added.
+	Params map[string]string
+	// Secret names parameters bound from the environment; they are redacted
+	// from shown commands like parameters whose names look secret.
+	Secret map[string]bool
+	// Quote shell-quotes values (see registry.ApplyParamsQuoted); set for
+	// values that come from outside, such as HTTP requests.
+	Quote bool
+	// Prompt asks for the value of a missing parameter; nil makes missing
+	// parameters an error.
+	Prompt func(name string) string
+	// Policy decides which commands may run; nil is the default policy.
+	Policy *security.Policy
+	// Force overrides the policy's confirm and block rules.
+	Force bool
+	// ForceFlag names the flag that sets Force, for refusal messages.
+	ForceFlag string
+	// Confirm asks whether to run a command a policy rule asks
+	// confirmation for; nil refuses it.
+	Confirm func(prompt string) bool
+	// DryRun skips the trust check, the lease, history and hooks.
+	DryRun bool
+	// Hooks fire around the run; nil fires none.
+	Hooks *lifecycle.Hooks
+	// Wait queues for the lease of an exclusive set instead of failing
+	// with ErrBusy.
+	Wait bool
+	// Stderr receives what the person running the set should see: the
+	// commands of an untrusted set, policy warnings, lease waits and
+	// history failures. Nil discards them.
+	Stderr io.Writer
+}
+
+// Run is a run of a set through the pipeline.
+type Run struct {
+	ID  string
+	Set *registry.CommandSet
+
+	repo     *registry.Repository
+	opts     Options
+	cmds     []string
+	shown    []string
+	warnings []string
+	lease    *lease
+	// timeout is the Gate's Timeout, applied by Bound.
+	timeout time.Duration
+	// recording is set once the run is in history.
+	recording bool
+	started   time.Time
+	steps     []time.Duration
+}
+
+// Prepare looks up the named set and refuses it when it was imported and
+// not trusted since, showing its commands on Stderr for review.
+func Prepare(repo *registry.Repository, name string, opts Options) (*Run, error) {
+	cs, err := repo.GetCommandSetByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if cs == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if opts.Stderr == nil {
+		opts.Stderr = io.Discard
+	}
+	if !opts.DryRun {
+		if err := checkTrusted(repo, cs, opts.Stderr); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Params == nil {
+		opts.Params = map[string]string{}
+	}
+	id := opts.RunID
+	if id == "" {
+		id = runlog.NewRunID()
+	}
+	return &Run{ID: id, Set: cs, repo: repo, opts: opts}, nil
+}
+
+// Resolve substitutes the parameters into command, prompting for missing
+// ones when the run may. It returns the command to run and the form to
+// show, with secret values redacted.
+func (r *Run) Resolve(command string) (string, string, error) {
+	required := registry.FindParams(command)
+	if len(required) == 0 {
+		return command, command, nil
+	}
+	for _, name := range required {
+		if _, ok := r.opts.Params[name]; ok {
+			continue
+		}
+		if r.opts.Prompt == nil {
+			return "", "", fmt.Errorf("missing value for parameter %s (pass --param %s=value)", name, name)
+		}
+		val := r.opts.Prompt(name)
+		if val == "" {
+			return "", "", fmt.Errorf("missing value for parameter %s", name)
+		}
+		r.opts.Params[name] = val
+	}
+	apply := registry.ApplyParams
+	if r.opts.Quote {
+		apply = registry.ApplyParamsQuoted
+	}
+	sub, err := apply(command, r.opts.Params)
+	if err != nil {
+		return "", "", err
+	}
+	redacted := map[string]string{}
+	for k, v := range r.opts.Params {
+		if security.IsSecretParamName(k) || r.opts.Secret[k] {
+			v = "<redacted>"
+		}
+		redacted[k] = v
+	}
+	shown := sub
+	if s, err := apply(command, redacted); err == nil {
+		shown = s
+	}
+	return sub, shown, nil
+}
+
+// Commands resolves and checks every command of the set up front, for runs
+// that cannot stop between steps to ask. It returns the commands to run
+// and their shown forms; Exec then runs them as resolved.
+func (r *Run) Commands() ([]string, []string, error) {
+	if r.cmds != nil {
+		return r.cmds, r.shown, nil
+	}
+	cmds := make([]string, len(r.Set.Commands))
+	shown := make([]string, len(r.Set.Commands))
+	for i, c := range r.Set.Commands {
+		var err error
+		if cmds[i], shown[i], err = r.Resolve(c.Command); err != nil {
+			return nil, nil, err
+		}
+		if err := r.Check(cmds[i], shown[i]); err != nil {
+			return nil, nil, err
+		}
+	}
+	r.cmds, r.shown = cmds, shown
+	return cmds, shown, nil
+}
+
+// Warnings returns the policy warnings of the commands checked so far.
+func (r *Run) Warnings() []string { return r.warnings }
+
+// Start takes the lease of an exclusive set, records the run in history
+// and fires the pre-run hooks. A failing blocking hook fails the run.
+func (r *Run) Start(ctx context.Context) error {
+	r.started = time.Now()
+	if r.opts.DryRun {
+		return nil
+	}
+	if r.Set.Exclusive {
+		l, err := acquireLease(ctx, r.repo, r.Set.Name, r.ID, r.opts.Wait, r.opts.Stderr)
+		if err != nil {
+			return err
+		}
+		r.lease = l
+	}
+	if r.opts.Trigger != "" {
+		if err := r.repo.StartRun(r.ID, r.Set.Name, r.opts.Trigger); err != nil {
+			_, _ = fmt.Fprintf(r.opts.Stderr, "warning: run history disabled: %v\n", err)
+		} else {
+			r.recording = true
+		}
+	}
+	if err := r.opts.Hooks.Fire(ctx, r.hookContext(lifecycle.PreRun)); err != nil {
+		r.end(err)
+		return err
+	}
+	return nil
+}
+
+// Finish fires the post-run or on-failure hooks of the run that ended with
+// runErr, releases the lease and records the result. A failing blocking
+// hook fails a run that succeeded and is added to the error of one that
+// did not. steps are the durations of the steps that ran, when known.
+func (r *Run) Finish(ctx context.Context, runErr error, steps []time.Duration) error {
+	if steps != nil {
+		r.steps = steps
+	}
+	if !r.opts.DryRun {
+		c := r.hookContext(lifecycle.PostRun)
+		if runErr != nil {
+			c.Event = lifecycle.OnFailure
+		}
+		code := ExitCode(runErr)
+		c.ExitCode = &code
+		if err := r.opts.Hooks.Fire(ctx, c); err != nil {
+			if runErr == nil {
+				runErr = err
+			} else {
+				runErr = errors.Join(runErr, err)
+			}
+		}
+	}
+	r.end(runErr)
+	return runErr
+}
+
+// Elapsed returns how long the run has been going since Start.
+func (r *Run) Elapsed() time.Duration { return time.Since(r.started) }
+
+// end releases the lease and records the result of the run.
+func (r *Run) end(runErr error) {
+	if r.lease != nil {
+		r.lease.release()
+		r.lease = nil
+	}
+	if !r.recording {
+		return
+	}
+	r.recording = false
+	if err := r.repo.FinishRun(r.ID, ExitCode(runErr), r.Elapsed(), r.steps); err != nil {
+		_, _ = fmt.Fprintf(r.opts.Stderr, "warning: record run history: %v\n", err)
+	}
+}
+
+func (r *Run) hookContext(ev lifecycle.Event) lifecycle.Context {
+	return lifecycle.Context{Event: ev, Set: r.Set.Name, RunID: r.ID}
+}
+
+// ExitCode extracts a process exit code from an execution error. It returns
+// 0 for nil and -1 when the error did not come from a process exit.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var ee *exec.ExitError
+	if errors.As(err, &ee) {
+		return ee.ExitCode()
+	}
+	return -1
+}
+
+// Subject describes a resolved command of cs for the policy.
+func Subject(cs *registry.CommandSet, command string) security.Subject {
+	return security.Subject{
+		Command:     command,
+		Set:         cs.Name,
+		Tags:        cs.Tags,
+		Author:      cs.AuthorName.String,
+		AuthorEmail: cs.AuthorEmail.String,
+	}
+}
+
+// Check applies the policy to a resolved command of the set; shown is the
+// command with secrets redacted. Warnings go to Stderr and Warnings.
+// Confirm rules call Confirm and are refused when it is nil. Force
+// overrides confirm and block rules. The offending span is highlighted
+// unless that would reveal a secret.
+func (r *Run) Check(command, shown string) error {
+	d := r.opts.Policy.Evaluate(Subject(r.Set, command))
+	if d.Action == security.ActionAllow {
+		return nil
+	}
+	hl := ""
+	if command == shown {
+		hl = "\n" + d.Highlight()
+	}
+	out := r.opts.Stderr
+	switch {
+	case d.Action == security.ActionWarn:
+		r.warnings = append(r.warnings, fmt.Sprintf("'%s' %s", shown, d.Reason()))
+		_, _ = fmt.Fprintf(out, "warning: '%s' %s%s\n", shown, d.Reason(), hl)
+		return nil
+	case r.opts.Force:
+		_, _ = fmt.Fprintf(out, "warning: forced: '%s' %s%s\n", shown, d.Reason(), hl)
+		return nil
+	case d.Action == security.ActionConfirm && r.opts.Confirm != nil:
+		if hl != "" {
+			_, _ = fmt.Fprintln(out, hl[1:])
+		}
+		if r.opts.Confirm(fmt.Sprintf("'%s' %s. Run it anyway?", shown, d.Reason())) {
+			return nil
+		}
+		return &refusal{msg: fmt.Sprintf("refusing to run '%s': not confirmed", shown), kinds: []error{ErrRefused}}
+	}
+	hint := ""
+	if r.opts.ForceFlag != "" {
+		hint = " (use " + r.opts.ForceFlag + " to override)"
+	}
+	kinds := []error{ErrRefused}
+	if d.Action == security.ActionConfirm {
+		kinds = append(kinds, ErrNeedsConfirm)
+	}
+	msg := fmt.Sprintf("refusing to run potentially dangerous command '%s': %v%s%s", shown, d.Err(), hint, strings.TrimRight(hl, "\n"))
+	return &refusal{msg: msg, kinds: kinds}
+}
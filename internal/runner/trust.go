@@ -0,0 +1,80 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/VoxDroid/krnr/internal/registry"
+)
+
+// checkTrusted refuses to run cs when it was imported and its commands have
+// not been trusted since. It shows out where the set came from and its
+// commands, or what changed since they were last trusted, for review.
+func checkTrusted(r *registry.Repository, cs *registry.CommandSet, out io.Writer) error {
+	p, err := r.Untrusted(cs.Name)
+	if err != nil || p == nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(out, "'%s' was imported from %s%s on %s and has not been trusted.\n", cs.Name, p.Source, byAuthor(p.OriginalAuthor), p.ImportedAt)
+	cmds := make([]string, len(cs.Commands))
+	for i, c := range cs.Commands {
+		cmds[i] = c.Command
+	}
+	if p.TrustedCommands == nil {
+		_, _ = fmt.Fprintln(out, "Commands:")
+		for i, c := range cmds {
+			_, _ = fmt.Fprintf(out, "  %d. %s\n", i+1, c)
+		}
+	} else {
+		_, _ = fmt.Fprintln(out, "Changes since you last trusted it:")
+		for _, l := range diffLines(p.TrustedCommands, cmds) {
+			_, _ = fmt.Fprintf(out, "  %s\n", l)
+		}
+	}
+	msg := fmt.Sprintf("refusing to run untrusted set '%s': review its commands, then run 'krnr trust %s'", cs.Name, cs.Name)
+	return &refusal{msg: msg, kinds: []error{ErrUntrusted, ErrRefused}}
+}
+
+func byAuthor(author string) string {
+	if author == "" {
+		return ""
+	}
+	return " (author " + author + ")"
+}
+
+// diffLines is a line diff of a and b: unchanged lines are prefixed with
+// "  ", removed ones with "- " and added ones with "+ ".
+func diffLines(a, b []string) []string {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var out []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			out = append(out, "  "+a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			out = append(out, "- "+a[i])
+			i++
+		default:
+			out = append(out, "+ "+b[j])
+			j++
+		}
+	}
+	return out
+}
@@ -0,0 +1,14 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	got := diffLines([]string{"a", "b", "c"}, []string{"a", "x", "c", "d"})
+	want := []string{"  a", "- b", "+ x", "  c", "+ d"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("diffLines = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,66 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronRejectsInvalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "@sometimes", "* * * foo *"} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("expected error for %q", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	base := time.Date(2026, 3, 14, 10, 17, 30, 0, time.UTC) // a Saturday
+	cases := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 3, 14, 10, 18, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2026, 3, 15, 3, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 3, 14, 10, 30, 0, 0, time.UTC)},
+		{"0 9-17 * * mon-fri", time.Date(2026, 3, 16, 9, 0, 0, 0, time.UTC)},
+		{"30 12 1,15 * *", time.Date(2026, 3, 15, 12, 30, 0, 0, time.UTC)},
+		{"0 0 * feb *", time.Date(2027, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)},
+		// day-of-month and day-of-week both restricted: either matches
+		{"0 0 20 * sat", time.Date(2026, 3, 20, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, 3, 14, 11, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		cr, err := ParseCron(c.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q): %v", c.expr, err)
+		}
+		if got := cr.Next(base); !got.Equal(c.want) {
+			t.Errorf("%q: Next = %s, want %s", c.expr, got, c.want)
+		}
+	}
+}
+
+func TestCronNextImpossibleDate(t *testing.T) {
+	cr, err := ParseCron("0 0 31 2 *")
+	if err != nil {
+		t.Fatalf("ParseCron: %v", err)
+	}
+	if got := cr.Next(time.Now()); !got.IsZero() {
+		t.Fatalf("expected no match, got %s", got)
+	}
+}
+
+func TestCronNextHalfHourZone(t *testing.T) {
+	loc := time.FixedZone("IST", 5*3600+1800)
+	cr, err := ParseCron("0 * * * *")
+	if err != nil {
+		t.Fatalf("ParseCron: %v", err)
+	}
+	got := cr.Next(time.Date(2026, 1, 1, 10, 20, 0, 0, loc))
+	if want := time.Date(2026, 1, 1, 11, 0, 0, 0, loc); !got.Equal(want) {
+		t.Fatalf("Next = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,179 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/VoxDroid/krnr/internal/registry"
+)
+
+// Trigger is recorded in run history for runs started by the scheduler.
+const Trigger = "schedule"
+
+// maxMissed bounds how many missed occurrences are recorded per schedule in
+// one pass, so a scheduler started after a long outage does not flood the
+// run history of a per-minute schedule.
+const maxMissed = 100
+
+// Store is the subset of the registry used by the daemon.
+type Store interface {
+	ListSchedules() ([]registry.Schedule, error)
+	AdvanceSchedule(id int64, lastRun, next time.Time) error
+	RecordMissedRun(setName string, scheduledAt time.Time, trigger string) error
+}
+
+// Daemon starts scheduled runs when they are due. Occurrences that are more
+// than Grace overdue (e.g., because the scheduler was not running) are
+// recorded as missed runs instead of being started late.
+type Daemon struct {
+	Store Store
+	// Launch executes one scheduled run and blocks until it finishes.
+	Launch func(ctx context.Context, s registry.Schedule) error
+	// Log receives one line per scheduler decision; nil discards them.
+	Log io.Writer
+	// Grace is how late a run may still be started.
+	Grace time.Duration
+	// Now returns the current time; the zone it carries is the one cron
+	// expressions are evaluated in.
+	Now func() time.Time
+
+	mu      sync.Mutex
+	running map[int64]bool
+	wg      sync.WaitGroup
+}
+
+// Run ticks every interval until ctx is cancelled, then waits for in-flight
+// runs to finish.
+func (d *Daemon) Run(ctx context.Context, interval time.Duration) error {
+	defer d.wg.Wait()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if err := d.Tick(ctx); err != nil {
+			d.logf("error: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// Wait blocks until every run started by Tick has finished.
+func (d *Daemon) Wait() { d.wg.Wait() }
+
+// Tick starts every schedule that is due, records missed occurrences and
+// advances each schedule's next run time.
+func (d *Daemon) Tick(ctx context.Context) error {
+	list, err := d.Store.ListSchedules()
+	if err != nil {
+		return err
+	}
+	now := d.now()
+	for _, s := range list {
+		if err := d.tickOne(ctx, s, now); err != nil {
+			d.logf("schedule #%d %s: %v", s.ID, s.SetName, err)
+		}
+	}
+	return nil
+}
+
+func (d *Daemon) tickOne(ctx context.Context, s registry.Schedule, now time.Time) error {
+	c, err := ParseCron(s.Cron)
+	if err != nil {
+		return err
+	}
+	if s.NextRunAt.IsZero() {
+		return d.Store.AdvanceSchedule(s.ID, time.Time{}, c.Next(now))
+	}
+	due := s.NextRunAt.In(now.Location())
+	if now.Before(due) {
+		return nil
+	}
+	// collect every occurrence up to now; only the latest may still run
+	var occurrences []time.Time
+	for t := due; !t.IsZero() && !t.After(now) && len(occurrences) < maxMissed; t = c.Next(t) {
+		occurrences = append(occurrences, t)
+	}
+	latest := occurrences[len(occurrences)-1]
+	missed := occurrences[:len(occurrences)-1]
+	var launched time.Time
+	switch {
+	case now.Sub(latest) > d.Grace:
+		missed = occurrences
+	case d.isRunning(s.ID):
+		d.logf("schedule #%d %s: previous run still in progress, skipping %s", s.ID, s.SetName, latest.Format(time.RFC3339))
+		missed = occurrences
+	default:
+		launched = latest
+	}
+	for _, t := range missed {
+		if err := d.Store.RecordMissedRun(s.SetName, t, Trigger); err != nil {
+			return err
+		}
+		d.logf("schedule #%d %s: missed run at %s", s.ID, s.SetName, t.Format(time.RFC3339))
+	}
+	if err := d.Store.AdvanceSchedule(s.ID, launched, c.Next(now)); err != nil {
+		return err
+	}
+	if !launched.IsZero() {
+		d.start(ctx, s)
+	}
+	return nil
+}
+
+func (d *Daemon) start(ctx context.Context, s registry.Schedule) {
+	d.setRunning(s.ID, true)
+	d.wg.Add(1)
+	d.logf("schedule #%d %s: started", s.ID, s.SetName)
+	go func() {
+		defer d.wg.Done()
+		defer d.setRunning(s.ID, false)
+		// in-flight runs outlive the daemon's context; they are bounded by
+		// their own timeout instead
+		if err := d.Launch(context.WithoutCancel(ctx), s); err != nil {
+			d.logf("schedule #%d %s: failed: %v", s.ID, s.SetName, err)
+			return
+		}
+		d.logf("schedule #%d %s: finished ok", s.ID, s.SetName)
+	}()
+}
+
+func (d *Daemon) isRunning(id int64) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.running[id]
+}
+
+func (d *Daemon) setRunning(id int64, on bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.running == nil {
+		d.running = map[int64]bool{}
+	}
+	if on {
+		d.running[id] = true
+	} else {
+		delete(d.running, id)
+	}
+}
+
+func (d *Daemon) now() time.Time {
+	if d.Now != nil {
+		return d.Now()
+	}
+	return time.Now()
+}
+
+func (d *Daemon) logf(format string, args ...any) {
+	if d.Log == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, _ = fmt.Fprintf(d.Log, "%s %s\n", d.now().Format("2006-01-02 15:04:05"), fmt.Sprintf(format, args...))
+}
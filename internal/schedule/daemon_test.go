@@ -0,0 +1,94 @@
+package schedule
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/VoxDroid/krnr/internal/registry"
+)
+
+type fakeStore struct {
+	mu        sync.Mutex
+	schedules []registry.Schedule
+	missed    []time.Time
+}
+
+func (f *fakeStore) ListSchedules() ([]registry.Schedule, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]registry.Schedule(nil), f.schedules...), nil
+}
+
+func (f *fakeStore) AdvanceSchedule(id int64, lastRun, next time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.schedules {
+		if f.schedules[i].ID == id {
+			if !lastRun.IsZero() {
+				f.schedules[i].LastRunAt = lastRun
+			}
+			f.schedules[i].NextRunAt = next
+		}
+	}
+	return nil
+}
+
+func (f *fakeStore) RecordMissedRun(_ string, at time.Time, _ string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.missed = append(f.missed, at)
+	return nil
+}
+
+func TestDaemonTickLaunchesDueAndRecordsMissed(t *testing.T) {
+	now := time.Date(2026, 3, 14, 10, 0, 20, 0, time.UTC)
+	store := &fakeStore{schedules: []registry.Schedule{
+		// due 20s ago: launched
+		{ID: 1, SetName: "fresh", Cron: "0 * * * *", NextRunAt: now.Add(-20 * time.Second)},
+		// the scheduler was down for three hours: 07:00-09:00 were missed
+		// and the 10:00 run still starts
+		{ID: 2, SetName: "stale", Cron: "0 * * * *", NextRunAt: now.Add(-3*time.Hour - 20*time.Second)},
+		// overdue beyond the grace period: only recorded as missed
+		{ID: 4, SetName: "daily", Cron: "0 3 * * *", NextRunAt: time.Date(2026, 3, 14, 3, 0, 0, 0, time.UTC)},
+		// not due yet
+		{ID: 3, SetName: "later", Cron: "0 * * * *", NextRunAt: now.Add(time.Hour)},
+	}}
+	var mu sync.Mutex
+	var launched []string
+	d := &Daemon{
+		Store: store,
+		Grace: time.Minute,
+		Now:   func() time.Time { return now },
+		Launch: func(_ context.Context, s registry.Schedule) error {
+			mu.Lock()
+			defer mu.Unlock()
+			launched = append(launched, s.SetName)
+			return nil
+		},
+	}
+	if err := d.Tick(context.Background()); err != nil {
+		t.Fatalf("Tick: %v", err)
+	}
+	d.Wait()
+
+	sort.Strings(launched)
+	if strings.Join(launched, ",") != "fresh,stale" {
+		t.Fatalf("unexpected launches: %v", launched)
+	}
+	if len(store.missed) != 4 {
+		t.Fatalf("expected 3 missed runs, got %v", store.missed)
+	}
+	next := time.Date(2026, 3, 14, 11, 0, 0, 0, time.UTC)
+	for _, s := range store.schedules[:2] {
+		if !s.NextRunAt.Equal(next) {
+			t.Fatalf("schedule %d not advanced: %s", s.ID, s.NextRunAt)
+		}
+	}
+	if daily := store.schedules[2]; !daily.LastRunAt.IsZero() || !daily.NextRunAt.Equal(time.Date(2026, 3, 15, 3, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected daily schedule: %+v", daily)
+	}
+}
@@ -0,0 +1,476 @@
+package security
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// Finding is a dangerous construct found by Analyze.
+type Finding struct {
+	// Rule is the ID of the built-in rule the construct falls under.
+	Rule string
+	// Start and End are the byte offsets of the offending span.
+	Start, End int
+}
+
+// maxDepth bounds how deeply sh -c and eval strings are followed.
+const maxDepth = 4
+
+// Analyzed are the built-in rules Analyze reports findings for.
+var Analyzed = map[string]bool{
+	"core.rm-root": true, "core.rm-empty-var": true, "core.find-delete": true,
+	"core.mkfs": true, "core.dd-device": true, "core.device-redirect": true,
+	"core.wipefs": true, "core.fork-bomb": true, "core.recursive-perms": true,
+	"git.force-push": true,
+}
+
+// Analyze parses command as a bash script and reports the constructs of
+// built-in rules that their patterns cannot see reliably: commands wrapped
+// in sudo, env, xargs, nice or timeout, nested in sh -c or eval strings,
+// paths built from variables and writes through redirections. ok is false
+// when the command does not parse; scripts nested in it that do not parse
+// are skipped.
+func Analyze(command string) (findings []Finding, ok bool) {
+	a := &analyzer{vars: map[string]string{}}
+	ok = a.run(command)
+	return a.findings, ok
+}
+
+type analyzer struct {
+	// base is the offset of the parsed script in the analysed command.
+	base int
+	// span, when set, replaces the spans of findings in a nested script
+	// whose offsets cannot be mapped back onto the command.
+	span  *Finding
+	depth int
+	// vars are the literal values of variables assigned so far.
+	vars     map[string]string
+	findings []Finding
+}
+
+func (a *analyzer) run(script string) bool {
+	f, err := syntax.NewParser(syntax.Variant(syntax.LangBash)).Parse(strings.NewReader(script), "")
+	if err != nil {
+		return false
+	}
+	syntax.Walk(f, func(node syntax.Node) bool {
+		switch n := node.(type) {
+		case *syntax.CallExpr:
+			if len(n.Args) == 0 {
+				a.assign(n.Assigns)
+				return true
+			}
+			args := make([]arg, len(n.Args))
+			for i, w := range n.Args {
+				args[i] = a.eval(w)
+			}
+			a.command(args)
+		case *syntax.DeclClause:
+			a.assign(n.Args)
+		case *syntax.Redirect:
+			switch n.Op {
+			case syntax.RdrOut, syntax.AppOut, syntax.ClbOut, syntax.RdrAll, syntax.AppAll:
+				if n.Word != nil && isDevice(a.eval(n.Word).val) {
+					a.add("core.device-redirect", a.off(n.Pos()), a.off(n.Word.End()))
+				}
+			}
+		case *syntax.FuncDecl:
+			if callsItselfInPipe(n) {
+				a.add("core.fork-bomb", a.off(n.Pos()), a.off(n.End()))
+			}
+		}
+		return true
+	})
+	return true
+}
+
+func (a *analyzer) off(p syntax.Pos) int { return a.base + int(p.Offset()) }
+
+func (a *analyzer) add(rule string, start, end int) {
+	if a.span != nil {
+		start, end = a.span.Start, a.span.End
+	}
+	a.findings = append(a.findings, Finding{Rule: rule, Start: start, End: end})
+}
+
+func (a *analyzer) assign(assigns []*syntax.Assign) {
+	for _, as := range assigns {
+		if as.Name == nil || as.Value == nil || as.Append || as.Index != nil {
+			continue
+		}
+		v := a.eval(as.Value)
+		if v.emptyVar {
+			// the value is only partly known; forget the variable so its
+			// uses are not mistaken for an empty value
+			delete(a.vars, as.Name.Value)
+			continue
+		}
+		a.vars[as.Name.Value] = v.val
+	}
+}
+
+// arg is an evaluated command word.
+type arg struct {
+	// val is the word's value. Command substitutions and other values that
+	// cannot be known are a NUL byte; unknown variables are empty.
+	val string
+	// emptyVar is set when val took an unknown variable as empty.
+	emptyVar   bool
+	start, end int
+	word       *syntax.Word
+}
+
+func (a *analyzer) eval(w *syntax.Word) arg {
+	out := arg{start: a.off(w.Pos()), end: a.off(w.End()), word: w}
+	var b strings.Builder
+	a.evalParts(w.Parts, &b, &out)
+	out.val = b.String()
+	return out
+}
+
+func (a *analyzer) evalParts(parts []syntax.WordPart, b *strings.Builder, out *arg) {
+	for _, p := range parts {
+		switch p := p.(type) {
+		case *syntax.Lit:
+			b.WriteString(p.Value)
+		case *syntax.SglQuoted:
+			b.WriteString(p.Value)
+		case *syntax.DblQuoted:
+			a.evalParts(p.Parts, b, out)
+		case *syntax.ParamExp:
+			b.WriteString(a.param(p, out))
+		default:
+			b.WriteString("\x00")
+		}
+	}
+}
+
+func (a *analyzer) param(p *syntax.ParamExp, out *arg) string {
+	if p.Param == nil || p.Excl || p.Length || p.Width || p.Index != nil || p.Slice != nil || p.Repl != nil {
+		return "\x00"
+	}
+	name := p.Param.Value
+	v, known := a.vars[name]
+	if name == "HOME" {
+		v, known = "~", true
+	}
+	if p.Exp != nil {
+		switch p.Exp.Op {
+		case syntax.DefaultUnset, syntax.DefaultUnsetOrNull:
+			if known && v != "" || p.Exp.Word == nil {
+				break
+			}
+			d := a.eval(p.Exp.Word)
+			out.emptyVar = out.emptyVar || d.emptyVar
+			return d.val
+		default:
+			// ${v:?} and the other operators never expand to an empty
+			// value by accident
+			return "\x00"
+		}
+	}
+	if !known {
+		out.emptyVar = true
+	}
+	return v
+}
+
+// sudoOptions and friends are the options of wrapper commands that take a
+// value as the next argument.
+const (
+	sudoOptions    = "CDghpRrTtUu"
+	envOptions     = "CSu"
+	niceOptions    = "n"
+	ioniceOptions  = "cnpP"
+	timeoutOptions = "ks"
+	xargsOptions   = "adEeIiLlnPs"
+	execOptions    = "a"
+	timeOptions    = "fo"
+)
+
+// command analyses a simple command, looking through wrapper commands.
+func (a *analyzer) command(args []arg) {
+	for len(args) > 0 {
+		name := path.Base(args[0].val)
+		rest := args[1:]
+		switch name {
+		case "sudo", "doas":
+			args = skipOptions(rest, sudoOptions)
+		case "env":
+			args = skipOptions(rest, envOptions)
+			for len(args) > 0 && strings.Contains(args[0].val, "=") {
+				args = args[1:]
+			}
+		case "nice", "nohup", "command", "builtin", "stdbuf", "chronic", "unbuffer":
+			args = skipOptions(rest, niceOptions)
+		case "ionice":
+			args = skipOptions(rest, ioniceOptions)
+		case "exec":
+			args = skipOptions(rest, execOptions)
+		case "time":
+			args = skipOptions(rest, timeOptions)
+		case "timeout":
+			if args = skipOptions(rest, timeoutOptions); len(args) > 0 {
+				args = args[1:] // the duration
+			}
+		case "xargs":
+			args = skipOptions(rest, xargsOptions)
+		case "sh", "bash", "zsh", "dash", "ksh", "ash":
+			if script, ok := shellScript(rest); ok {
+				a.nested(script)
+			}
+			return
+		case "eval":
+			if len(rest) > 0 {
+				vals := make([]string, len(rest))
+				for i, r := range rest {
+					vals[i] = r.val
+				}
+				a.nestedText(strings.Join(vals, " "), rest[0].start, rest[len(rest)-1].end)
+			}
+			return
+		default:
+			a.check(name, args[0], rest)
+			return
+		}
+	}
+}
+
+// skipOptions drops leading options, and the values of those in withValue.
+func skipOptions(args []arg, withValue string) []arg {
+	for len(args) > 0 {
+		v := args[0].val
+		if v == "--" {
+			return args[1:]
+		}
+		if len(v) < 2 || v[0] != '-' {
+			return args
+		}
+		args = args[1:]
+		if len(v) == 2 && strings.IndexByte(withValue, v[1]) >= 0 && len(args) > 0 {
+			args = args[1:]
+		}
+	}
+	return args
+}
+
+// shellScript returns the script of `sh -c script`: the first operand after
+// an option cluster containing c.
+func shellScript(args []arg) (arg, bool) {
+	c := false
+	for _, r := range args {
+		if strings.HasPrefix(r.val, "-") && !strings.HasPrefix(r.val, "--") {
+			c = c || strings.Contains(r.val[1:], "c")
+			continue
+		}
+		return r, c
+	}
+	return arg{}, false
+}
+
+// nested analyses a script given as a single argument. Offsets map back
+// exactly when the argument is a plain or quoted literal.
+func (a *analyzer) nested(script arg) {
+	base := -1
+	if len(script.word.Parts) == 1 {
+		raw := script.end - script.start
+		switch p := script.word.Parts[0].(type) {
+		case *syntax.Lit:
+			if len(p.Value) == raw {
+				base = script.start
+			}
+		case *syntax.SglQuoted:
+			if !p.Dollar && len(p.Value) == raw-2 {
+				base = script.start + 1
+			}
+		case *syntax.DblQuoted:
+			if len(script.val) == raw-2 && !strings.Contains(script.val, "\x00") {
+				base = script.start + 1
+			}
+		}
+	}
+	if base < 0 {
+		a.nestedText(script.val, script.start, script.end)
+		return
+	}
+	a.runChild(script.val, base, a.span)
+}
+
+// nestedText analyses a script whose findings are reported on the span
+// start..end.
+func (a *analyzer) nestedText(script string, start, end int) {
+	span := a.span
+	if span == nil {
+		span = &Finding{Start: start, End: end}
+	}
+	a.runChild(script, 0, span)
+}
+
+func (a *analyzer) runChild(script string, base int, span *Finding) {
+	if a.depth >= maxDepth {
+		return
+	}
+	c := &analyzer{base: base, span: span, depth: a.depth + 1, vars: map[string]string{}}
+	for k, v := range a.vars {
+		c.vars[k] = v
+	}
+	c.run(script)
+	a.findings = append(a.findings, c.findings...)
+}
+
+// check analyses an unwrapped command.
+func (a *analyzer) check(name string, cmd arg, args []arg) {
+	switch {
+	case name == "rm":
+		recursive, targets := operands(args, "rR", "--recursive")
+		for _, t := range targets {
+			if !isCritical(t.val) || !recursive && !strings.HasSuffix(t.val, "*") {
+				continue
+			}
+			rule := "core.rm-root"
+			if t.emptyVar {
+				rule = "core.rm-empty-var"
+			}
+			a.add(rule, cmd.start, t.end)
+		}
+	case name == "find":
+		if len(args) == 0 || !findDeletes(args) {
+			return
+		}
+		for _, r := range args {
+			if strings.HasPrefix(r.val, "-") || r.val == "(" || r.val == "!" {
+				break
+			}
+			if isCritical(r.val) {
+				a.add("core.find-delete", cmd.start, args[len(args)-1].end)
+				return
+			}
+		}
+	case name == "chmod" || name == "chown" || name == "chgrp":
+		recursive, targets := operands(args, "R", "--recursive")
+		for _, t := range targets {
+			if recursive && isCritical(t.val) {
+				a.add("core.recursive-perms", cmd.start, t.end)
+			}
+		}
+	case name == "dd":
+		for _, r := range args {
+			if strings.HasPrefix(r.val, "of=") && isDevice(r.val[3:]) {
+				a.add("core.dd-device", cmd.start, r.end)
+			}
+		}
+	case name == "shred" || name == "tee":
+		_, targets := operands(args, "", "")
+		for _, t := range targets {
+			if isDevice(t.val) {
+				a.add("core.device-redirect", cmd.start, t.end)
+			}
+		}
+	case strings.HasPrefix(name, "mkfs") || name == "mke2fs":
+		a.add("core.mkfs", cmd.start, cmd.end)
+	case name == "wipefs":
+		a.add("core.wipefs", cmd.start, cmd.end)
+	case name == "git":
+		a.checkGit(cmd, args)
+	}
+}
+
+// checkGit reports force pushes.
+func (a *analyzer) checkGit(cmd arg, args []arg) {
+	push := false
+	for _, r := range args {
+		switch {
+		case !push:
+			push = r.val == "push"
+		case r.val == "--force" || r.val == "-f",
+			len(r.val) > 1 && r.val[0] == '+',
+			len(r.val) > 2 && r.val[0] == '-' && r.val[1] != '-' && strings.Contains(r.val, "f"):
+			a.add("git.force-push", cmd.start, r.end)
+			return
+		}
+	}
+}
+
+// operands splits a command's arguments into whether one of the short
+// options or the long option is given and the operands.
+func operands(args []arg, short, long string) (bool, []arg) {
+	found := false
+	var out []arg
+	for i, r := range args {
+		switch {
+		case r.val == "--":
+			return found, append(out, args[i+1:]...)
+		case long != "" && r.val == long:
+			found = true
+		case strings.HasPrefix(r.val, "--"):
+		case len(r.val) > 1 && r.val[0] == '-':
+			found = found || short != "" && strings.ContainsAny(r.val[1:], short)
+		default:
+			out = append(out, r)
+		}
+	}
+	return found, out
+}
+
+// findDeletes reports whether find's expression deletes what it finds.
+func findDeletes(args []arg) bool {
+	for i, r := range args {
+		switch r.val {
+		case "-delete":
+			return true
+		case "-exec", "-execdir", "-ok", "-okdir":
+			if i+1 < len(args) {
+				switch path.Base(args[i+1].val) {
+				case "rm", "shred", "unlink":
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// criticalPaths are directories whose deletion wrecks the system or the
+// user's files.
+var criticalPaths = map[string]bool{
+	"/": true, "~": true, "/home": true, "/root": true, "/etc": true, "/usr": true,
+	"/var": true, "/bin": true, "/sbin": true, "/lib": true, "/lib64": true,
+	"/boot": true, "/opt": true, "/srv": true, "/dev": true, "/sys": true,
+	"/proc": true, "/Users": true, "/System": true,
+}
+
+// isCritical reports whether p is, or is every entry of, a critical
+// directory.
+func isCritical(p string) bool {
+	p = strings.TrimSuffix(p, "*")
+	for len(p) > 1 && strings.HasSuffix(p, "/") {
+		p = p[:len(p)-1]
+	}
+	return criticalPaths[p]
+}
+
+var diskDeviceRe = regexp.MustCompile(`^/dev/(?:sd[a-z]|hd[a-z]|vd[a-z]|xvd[a-z]|nvme\d|mmcblk\d|disk\d|md\d|dm-\d|mapper/)`)
+
+// isDevice reports whether p is a disk device.
+func isDevice(p string) bool { return diskDeviceRe.MatchString(p) }
+
+// callsItselfInPipe reports whether a function pipes into itself, the
+// shape of a fork bomb.
+func callsItselfInPipe(fd *syntax.FuncDecl) bool {
+	name := fd.Name.Value
+	calls := func(s *syntax.Stmt) bool {
+		c, ok := s.Cmd.(*syntax.CallExpr)
+		return ok && len(c.Args) > 0 && c.Args[0].Lit() == name
+	}
+	found := false
+	syntax.Walk(fd.Body, func(node syntax.Node) bool {
+		if b, ok := node.(*syntax.BinaryCmd); ok && (b.Op == syntax.Pipe || b.Op == syntax.PipeAll) {
+			found = found || calls(b.X) || calls(b.Y)
+		}
+		return !found
+	})
+	return found
+}
@@ -0,0 +1,79 @@
+package security
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	cases := []struct {
+		command, rule, span string
+	}{
+		{"sudo -u root rm -rf /", "core.rm-root", "rm -rf /"},
+		{`bash -c "rm -rf /"`, "core.rm-root", "rm -rf /"},
+		{`sh -ec 'cd /tmp && rm -fr ~/*'`, "core.rm-root", "rm -fr ~/*"},
+		{`eval "rm -rf" /`, "core.rm-root", `"rm -rf" /`},
+		{"env FOO=1 nice -n 5 rm -r $HOME", "core.rm-root", "rm -r $HOME"},
+		{"D=/etc; rm -rf $D", "core.rm-root", "rm -rf $D"},
+		{`rm -rf "$BUILD_DIR"/`, "core.rm-empty-var", `rm -rf "$BUILD_DIR"/`},
+		{"find / -name '*.log' -delete", "core.find-delete", "find / -name '*.log' -delete"},
+		{"find ~ -type f -exec rm {} +", "core.find-delete", "find ~ -type f -exec rm {} +"},
+		{"echo / | xargs -I{} sudo chmod -R 777 /", "core.recursive-perms", "chmod -R 777 /"},
+		{"cat disk.img > /dev/sda", "core.device-redirect", "> /dev/sda"},
+		{"echo x | sudo tee /dev/nvme0n1", "core.device-redirect", "tee /dev/nvme0n1"},
+		{"timeout 5 dd if=/dev/zero of=/dev/sdb", "core.dd-device", "dd if=/dev/zero of=/dev/sdb"},
+		{"git -C repo push -uf origin main", "git.force-push", "git -C repo push -uf"},
+		{"bomb(){ bomb | bomb & }; bomb", "core.fork-bomb", "bomb(){ bomb | bomb & }"},
+	}
+	for _, c := range cases {
+		findings, ok := Analyze(c.command)
+		if !ok || len(findings) == 0 {
+			t.Errorf("%q: expected a %s finding, got %v (parsed %t)", c.command, c.rule, findings, ok)
+			continue
+		}
+		f := findings[0]
+		if f.Rule != c.rule || c.command[f.Start:f.End] != c.span {
+			t.Errorf("%q: expected %s on %q, got %s on %q", c.command, c.rule, c.span, f.Rule, c.command[f.Start:f.End])
+		}
+	}
+
+	safe := []string{
+		`echo "rm -rf /"`,
+		"rm -rf /tmp/build ./dist",
+		`rm -rf "${BUILD_DIR:?}"/`,
+		"D=/tmp/x; rm -rf $D",
+		"find /tmp -delete",
+		"find / -name core",
+		"chmod 755 /",
+		"dd if=/dev/zero of=disk.img",
+		"echo ok > /dev/null",
+		"git push --force-with-lease",
+		"rm -rf {{dir}}",
+	}
+	for _, s := range safe {
+		if findings, _ := Analyze(s); len(findings) > 0 {
+			t.Errorf("%q: expected no findings, got %v", s, findings)
+		}
+	}
+	if _, ok := Analyze("if then fi ((("); ok {
+		t.Fatalf("expected a parse failure")
+	}
+}
+
+func TestDecisionHighlight(t *testing.T) {
+	d := DefaultPolicy().Evaluate(Subject{Command: "echo start\n\tsudo rm -rf / # oops"})
+	if d.Action != ActionBlock {
+		t.Fatalf("expected a block, got %+v", d)
+	}
+	want := "  \tsudo rm -rf / # oops\n  \t     ^^^^^^^^"
+	if got := d.Highlight(); got != want {
+		t.Fatalf("unexpected highlight:\n%s\nwant:\n%s", got, want)
+	}
+	// a regex-only match is highlighted too
+	if d := DefaultPolicy().Evaluate(Subject{Command: "git reset --hard"}); !strings.HasSuffix(d.Highlight(), "^^^^^^^^^^^^^^^^") {
+		t.Fatalf("unexpected highlight %q", d.Highlight())
+	}
+	if d := DefaultPolicy().Evaluate(Subject{Command: "echo hi"}); d.Highlight() != "" {
+		t.Fatalf("expected no highlight, got %q", d.Highlight())
+	}
+}
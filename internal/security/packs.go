@@ -0,0 +1,76 @@
+package security
+
+// Pack is a named, built-in set of policy rules. Besides its pattern, a
+// pack rule matches the constructs Analyze reports under its ID; rules
+// without a pattern match those only.
+type Pack struct {
+	Name        string
+	Description string
+	Rules       []Rule
+}
+
+// DefaultPacks are the packs enabled when the policy file does not list
+// any.
+var DefaultPacks = []string{"core", "git", "packages"}
+
+// target matches the root or home directory as a command argument.
+const target = `(?:/|/\*|~|~/|~/\*|\$HOME/?\*?|\$\{HOME\}/?\*?)(?:\s|$|[;&|)])`
+
+// Packs are the built-in rule packs.
+var Packs = []Pack{
+	{Name: "core", Description: "commands that destroy the system, disks or the home directory", Rules: []Rule{
+		{ID: "core.rm-root", Action: ActionBlock, Reason: "deletes the root, home or a system directory",
+			Match: `\brm\s+(?:-\S+\s+)*` + target},
+		{ID: "core.rm-empty-var", Action: ActionConfirm, Reason: "deletes the root or a system directory when a variable is empty or unset (use ${VAR:?})"},
+		{ID: "core.find-delete", Action: ActionBlock, Reason: "deletes files found under the root, home or a system directory",
+			Match: `\bfind\s+` + target + `[^;&|]*(?:-delete\b|-exec(?:dir)?\s+rm\b)`},
+		{ID: "core.mkfs", Action: ActionBlock, Reason: "formats a filesystem",
+			Match: `(?i)\bmkfs\b`},
+		{ID: "core.dd-device", Action: ActionBlock, Reason: "writes raw data to a device",
+			Match: `\bdd\b[^;&|]*\bof=/dev/`},
+		{ID: "core.device-redirect", Action: ActionBlock, Reason: "overwrites a disk device",
+			Match: `>\s*/dev/(?:sd[a-z]|hd[a-z]|vd[a-z]|xvd[a-z]|nvme\d|mmcblk\d|disk\d)`},
+		{ID: "core.wipefs", Action: ActionBlock, Reason: "erases filesystem signatures",
+			Match: `(?i)\bwipefs\b`},
+		{ID: "core.fork-bomb", Action: ActionBlock, Reason: "is a fork bomb",
+			Match: `:\(\)\s*\{`},
+		{ID: "core.recursive-perms", Action: ActionBlock, Reason: "recursively changes permissions or ownership of the root or home directory",
+			Match: `\b(?:chmod|chown|chgrp)\b[^;&|]*\s(?:-[a-zA-Z]*R[a-zA-Z]*|--recursive)\s[^;&|]*?` + target},
+	}},
+	{Name: "git", Description: "git commands that lose work or rewrite shared history", Rules: []Rule{
+		{ID: "git.force-push", Action: ActionConfirm, Reason: "rewrites remote history (prefer --force-with-lease)",
+			Match: `\bgit\b[^;&|]*\bpush\b[^;&|]*(?:\s--force(?:\s|$)|\s-f(?:\s|$)|\s\+\S)`},
+		{ID: "git.reset-hard", Action: ActionWarn, Reason: "discards uncommitted changes",
+			Match: `\bgit\b[^;&|]*\breset\b[^;&|]*\s--hard\b`},
+		{ID: "git.clean", Action: ActionWarn, Reason: "deletes untracked files",
+			Match: `\bgit\b[^;&|]*\bclean\b[^;&|]*\s-[a-zA-Z]*f`},
+	}},
+	{Name: "packages", Description: "package manager removals", Rules: []Rule{
+		{ID: "packages.remove", Action: ActionWarn, Reason: "removes installed packages",
+			Match: `(?i)\b(?:apt-get|apt|yum|dnf|zypper|pacman)\s+(?:-\S+\s+)*(?:remove|purge|autoremove|erase|-R\w*)\b`},
+	}},
+	{Name: "strict", Description: "opt-in: piping downloads into a shell and sudo", Rules: []Rule{
+		{ID: "strict.pipe-to-shell", Action: ActionConfirm, Reason: "runs a downloaded script without review",
+			Match: `\b(?:curl|wget)\b[^|;&]*\|\s*(?:sudo\s+)?(?:ba|z|da|k)?sh\b`},
+		{ID: "strict.sudo", Action: ActionWarn, Reason: "runs with root privileges",
+			Match: `(?:^|[;&|]\s*)sudo\b`},
+	}},
+}
+
+// PackNames returns the names of the built-in packs.
+func PackNames() []string {
+	names := make([]string, len(Packs))
+	for i, p := range Packs {
+		names[i] = p.Name
+	}
+	return names
+}
+
+func packByName(name string) (Pack, bool) {
+	for _, p := range Packs {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Pack{}, false
+}
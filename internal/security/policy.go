@@ -0,0 +1,379 @@
+package security
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pelletier/go-toml/v2"
+
+	"github.com/VoxDroid/krnr/internal/config"
+)
+
+// PolicyFileName is the policy file in the data directory.
+const PolicyFileName = "policy.toml"
+
+// Action is what a policy rule does with a matching command.
+type Action string
+
+// Policy actions, least severe first.
+const (
+	// ActionAllow runs the command; it exempts it from later rules.
+	ActionAllow Action = "allow"
+	// ActionWarn runs the command after printing the rule's reason.
+	ActionWarn Action = "warn"
+	// ActionConfirm asks before running the command. Runs that cannot ask
+	// (CI, background runs, the SDK) refuse it unless forced.
+	ActionConfirm Action = "confirm"
+	// ActionBlock refuses the command unless forced.
+	ActionBlock Action = "block"
+)
+
+// Actions lists every action.
+var Actions = []Action{ActionAllow, ActionWarn, ActionConfirm, ActionBlock}
+
+// Outcome describes what happens to a command the action applies to when
+// it runs, e.g. "will be refused".
+func (a Action) Outcome() string {
+	switch a {
+	case ActionWarn:
+		return "will warn"
+	case ActionConfirm:
+		return "will need confirmation"
+	case ActionBlock:
+		return "will be refused"
+	}
+	return "will run"
+}
+
+// Rule matches commands and decides what happens to them.
+type Rule struct {
+	ID     string `toml:"id"`
+	Action Action `toml:"action"`
+	// Match is a regular expression matched against the command after
+	// parameter substitution.
+	Match  string `toml:"match"`
+	Reason string `toml:"reason"`
+	// Tags, Sets and Authors scope the rule: it only applies to sets with
+	// one of the tags, whose name matches one of the globs, or whose author
+	// name or email matches one of the globs. Empty means any.
+	Tags    []string `toml:"tags"`
+	Sets    []string `toml:"sets"`
+	Authors []string `toml:"authors"`
+	// Pack is the built-in pack the rule comes from; empty for rules from
+	// the policy file.
+	Pack string `toml:"-"`
+
+	re *regexp.Regexp
+}
+
+// Source describes where the rule comes from.
+func (r *Rule) Source() string {
+	if r.Pack != "" {
+		return "pack " + r.Pack
+	}
+	return "policy file"
+}
+
+// Subject is a command to decide on, with the set it belongs to.
+type Subject struct {
+	Command     string
+	Set         string
+	Tags        []string
+	Author      string
+	AuthorEmail string
+}
+
+// match reports whether the rule applies to s and the span of the command
+// it matched. Pack rules that Analyze covers match its findings when the
+// command parses and their pattern otherwise.
+func (r *Rule) match(s Subject, findings []Finding, parsed bool) (int, int, bool) {
+	if len(r.Tags) > 0 && !anyTag(r.Tags, s.Tags) {
+		return 0, 0, false
+	}
+	if len(r.Sets) > 0 && !anyGlob(r.Sets, s.Set) {
+		return 0, 0, false
+	}
+	if len(r.Authors) > 0 && !anyGlob(r.Authors, s.Author, s.AuthorEmail) {
+		return 0, 0, false
+	}
+	if r.Pack != "" && Analyzed[r.ID] && parsed {
+		for _, f := range findings {
+			if f.Rule == r.ID {
+				return f.Start, f.End, true
+			}
+		}
+		return 0, 0, false
+	}
+	if r.re == nil {
+		return 0, 0, false
+	}
+	loc := r.re.FindStringIndex(s.Command)
+	if loc == nil {
+		return 0, 0, false
+	}
+	end := loc[0] + len(strings.TrimRight(s.Command[loc[0]:loc[1]], " \t;&|)"))
+	return loc[0], end, true
+}
+
+func anyTag(want, have []string) bool {
+	for _, w := range want {
+		for _, h := range have {
+			if strings.EqualFold(w, h) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func anyGlob(globs []string, values ...string) bool {
+	for _, g := range globs {
+		for _, v := range values {
+			if v == "" {
+				continue
+			}
+			if ok, _ := path.Match(g, v); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Decision is the outcome of evaluating a command against a policy.
+type Decision struct {
+	Action Action
+	// Rule is the rule that decided; nil when no rule matched.
+	Rule *Rule
+	// Command is the evaluated command and Start and End the byte offsets
+	// of the span the rule matched.
+	Command    string
+	Start, End int
+	// Shadowed are later rules that also matched but did not decide.
+	Shadowed []*Rule
+}
+
+// Highlight returns the line of the command holding the matched span with
+// the span underlined, indented for printing below a message; "" when no
+// rule matched.
+func (d Decision) Highlight() string {
+	if d.Rule == nil {
+		return ""
+	}
+	return Underline(d.Command, d.Start, d.End)
+}
+
+// Underline returns the line of text holding the byte span [start, end)
+// with the span underlined, indented for printing below a message; "" when
+// the span is empty or out of range.
+func Underline(text string, start, end int) string {
+	if start < 0 || end <= start || end > len(text) {
+		return ""
+	}
+	lineStart := strings.LastIndexByte(text[:start], '\n') + 1
+	lineEnd := len(text)
+	if i := strings.IndexByte(text[start:], '\n'); i >= 0 {
+		lineEnd = start + i
+	}
+	end = min(end, lineEnd)
+	pad := []rune(text[lineStart:start])
+	for i, r := range pad {
+		if r != '\t' {
+			pad[i] = ' '
+		}
+	}
+	return fmt.Sprintf("  %s\n  %s%s", text[lineStart:lineEnd], string(pad), strings.Repeat("^", max(1, utf8.RuneCountInString(text[start:end]))))
+}
+
+// Reason explains the decision.
+func (d Decision) Reason() string {
+	if d.Rule == nil {
+		return "no rule matched"
+	}
+	reason := d.Rule.Reason
+	if reason == "" {
+		reason = "matches " + d.Rule.Match
+	}
+	return fmt.Sprintf("%s [%s]", reason, d.Rule.ID)
+}
+
+// Err returns an error describing a confirm or block decision and nil for
+// the others.
+func (d Decision) Err() error {
+	switch d.Action {
+	case ActionBlock:
+		return fmt.Errorf("blocked by policy: %s", d.Reason())
+	case ActionConfirm:
+		return fmt.Errorf("needs confirmation by policy: %s", d.Reason())
+	}
+	return nil
+}
+
+// Policy is an ordered list of rules: the policy file's rules, then the
+// rules of the enabled built-in packs. The first matching rule decides.
+type Policy struct {
+	// Path is the policy file the policy was loaded from, if any.
+	Path  string
+	Packs []string
+	Rules []*Rule
+	// RequireSignatures refuses imports of bundles not signed by a key in
+	// the keyring.
+	RequireSignatures bool
+}
+
+// policyFile is the layout of the policy file.
+type policyFile struct {
+	Packs             *[]string `toml:"packs"`
+	RequireSignatures bool      `toml:"require_signatures"`
+	Rule              []*Rule   `toml:"rule"`
+}
+
+var defaultPolicy, _ = newPolicy("", DefaultPacks, nil)
+
+// DefaultPolicy returns the policy used without a policy file: the default
+// packs only.
+func DefaultPolicy() *Policy { return defaultPolicy }
+
+func newPolicy(file string, packs []string, rules []*Rule) (*Policy, error) {
+	p := &Policy{Path: file, Packs: packs, Rules: rules}
+	for _, name := range packs {
+		pack, ok := packByName(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown rule pack %q (known packs: %s)", name, strings.Join(PackNames(), ", "))
+		}
+		for _, r := range pack.Rules {
+			r := r
+			r.Pack = pack.Name
+			if r.Match != "" {
+				r.re = regexp.MustCompile(r.Match)
+			}
+			p.Rules = append(p.Rules, &r)
+		}
+	}
+	return p, nil
+}
+
+// PolicyPath returns the policy file in the data directory.
+func PolicyPath() (string, error) {
+	d, err := config.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(d, PolicyFileName), nil
+}
+
+// LoadPolicy reads the policy file, falling back to DefaultPolicy when
+// there is none.
+func LoadPolicy() (*Policy, error) {
+	p, err := PolicyPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return DefaultPolicy(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParsePolicy(p, data)
+}
+
+// ParsePolicy parses and validates a policy file.
+func ParsePolicy(file string, data []byte) (*Policy, error) {
+	var pf policyFile
+	dec := toml.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&pf); err != nil {
+		var strict *toml.StrictMissingError
+		if errors.As(err, &strict) && len(strict.Errors) > 0 {
+			e := strict.Errors[0]
+			row, _ := e.Position()
+			return nil, fmt.Errorf("%s: line %d: unknown field %q", file, row, strings.Join(e.Key(), "."))
+		}
+		var de *toml.DecodeError
+		if errors.As(err, &de) {
+			row, col := de.Position()
+			return nil, fmt.Errorf("%s:%d:%d: %v", file, row, col, de)
+		}
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	seen := map[string]bool{}
+	for i, r := range pf.Rule {
+		where := fmt.Sprintf("%s: rule %d", file, i+1)
+		if r.ID == "" {
+			r.ID = fmt.Sprintf("rule-%d", i+1)
+		} else {
+			where = fmt.Sprintf("%s: rule %q", file, r.ID)
+		}
+		if seen[r.ID] {
+			return nil, fmt.Errorf("%s: duplicate id", where)
+		}
+		seen[r.ID] = true
+		if !validAction(r.Action) {
+			return nil, fmt.Errorf("%s: action %q is not one of allow, warn, confirm, block", where, r.Action)
+		}
+		if r.Match == "" {
+			return nil, fmt.Errorf("%s: match is required", where)
+		}
+		re, err := regexp.Compile(r.Match)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid match: %v", where, err)
+		}
+		r.re = re
+		for _, g := range append(append([]string{}, r.Sets...), r.Authors...) {
+			if _, err := path.Match(g, ""); err != nil {
+				return nil, fmt.Errorf("%s: invalid glob %q", where, g)
+			}
+		}
+	}
+	packs := DefaultPacks
+	if pf.Packs != nil {
+		packs = *pf.Packs
+	}
+	p, err := newPolicy(file, packs, pf.Rule)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	p.RequireSignatures = pf.RequireSignatures
+	return p, nil
+}
+
+func validAction(a Action) bool {
+	for _, v := range Actions {
+		if a == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate decides on a command. The first matching rule decides; a
+// command no rule matches is allowed. The command is analysed as a shell
+// script (see Analyze) so that pack rules also see through wrappers. A nil policy is the default policy.
+func (p *Policy) Evaluate(s Subject) Decision {
+	if p == nil {
+		p = DefaultPolicy()
+	}
+	d := Decision{Action: ActionAllow, Command: s.Command}
+	findings, parsed := Analyze(s.Command)
+	for _, r := range p.Rules {
+		start, end, ok := r.match(s, findings, parsed)
+		if !ok {
+			continue
+		}
+		if d.Rule == nil {
+			d.Action, d.Rule, d.Start, d.End = r.Action, r, start, end
+			continue
+		}
+		d.Shadowed = append(d.Shadowed, r)
+	}
+	return d
+}
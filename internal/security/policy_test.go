@@ -0,0 +1,111 @@
+package security
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDefaultPolicyPacks(t *testing.T) {
+	p := DefaultPolicy()
+	cases := map[string]Action{
+		"rm -rf ~":                            ActionBlock,
+		"rm -rf $HOME/*":                      ActionBlock,
+		"sudo rm -fr / --no-preserve-root":    ActionBlock,
+		"chmod -R 777 /":                      ActionBlock,
+		"chown -R nobody ~/":                  ActionBlock,
+		"cat image > /dev/sda":                ActionBlock,
+		"git push --force origin main":        ActionConfirm,
+		"git push -f":                         ActionConfirm,
+		"git push origin +main":               ActionConfirm,
+		"git push --force-with-lease":         ActionAllow,
+		"git reset --hard HEAD~1":             ActionWarn,
+		"apt-get remove -y nginx":             ActionWarn,
+		"rm -rf /tmp/build":                   ActionAllow,
+		"rm -rf ./dist":                       ActionAllow,
+		"dd if=/dev/zero of=disk.img count=1": ActionAllow,
+		"chmod -R 755 ./bin":                  ActionAllow,
+		"curl -fsSL https://x.sh | sh":        ActionAllow,
+	}
+	for cmd, want := range cases {
+		if d := p.Evaluate(Subject{Command: cmd}); d.Action != want {
+			t.Errorf("%q: expected %s, got %s (%s)", cmd, want, d.Action, d.Reason())
+		}
+	}
+}
+
+func TestPolicyFileRules(t *testing.T) {
+	p, err := ParsePolicy("policy.toml", []byte(`
+packs = ["core", "git", "strict"]
+
+[[rule]]
+id = "ops-force-push"
+action = "allow"
+match = 'git push'
+tags = ["ops"]
+reason = "the ops team force-pushes release branches"
+
+[[rule]]
+id = "no-prod"
+action = "block"
+match = 'kubectl .*--context[= ]prod'
+authors = ["*@contractor.example"]
+reason = "contractors may not touch prod"
+`))
+	if err != nil {
+		t.Fatalf("ParsePolicy: %v", err)
+	}
+	d := p.Evaluate(Subject{Command: "git push --force", Tags: []string{"ops"}})
+	if d.Action != ActionAllow || d.Rule.ID != "ops-force-push" || len(d.Shadowed) != 1 || d.Shadowed[0].ID != "git.force-push" {
+		t.Fatalf("expected the allow rule to decide and shadow the pack rule, got %+v", d)
+	}
+	if d := p.Evaluate(Subject{Command: "git push --force", Tags: []string{"dev"}}); d.Action != ActionConfirm {
+		t.Fatalf("expected the pack rule outside the tag scope, got %+v", d)
+	}
+	d = p.Evaluate(Subject{Command: "kubectl apply --context prod -f x", AuthorEmail: "jo@contractor.example"})
+	if d.Action != ActionBlock || !strings.Contains(d.Err().Error(), "contractors may not touch prod [no-prod]") {
+		t.Fatalf("expected the author-scoped block, got %+v", d)
+	}
+	if d := p.Evaluate(Subject{Command: "curl https://x | bash"}); d.Action != ActionConfirm {
+		t.Fatalf("expected the strict pack to apply, got %+v", d)
+	}
+	if d := p.Evaluate(Subject{Command: "apt-get remove nginx"}); d.Action != ActionAllow {
+		t.Fatalf("expected the packages pack to be disabled, got %+v", d)
+	}
+
+	bad := map[string]string{
+		"[[rule]]\naction = \"deny\"\nmatch = \"x\"":          `action "deny" is not one of`,
+		"[[rule]]\naction = \"warn\"":                         "match is required",
+		"[[rule]]\naction = \"warn\"\nmatch = \"(\"":          "invalid match",
+		"[[rule]]\naction = \"warn\"\nmatch = \"x\"\nwho = 1": "line 4: unknown field",
+		"packs = [\"nope\"]":                                  `unknown rule pack "nope"`,
+		"[[rule]]\nid = \"a\"\naction = \"warn\"\nmatch = \"x\"\n[[rule]]\nid = \"a\"\naction = \"warn\"\nmatch = \"y\"": "duplicate id",
+	}
+	for content, want := range bad {
+		if _, err := ParsePolicy("policy.toml", []byte(content)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected error containing %q, got %v", content, want, err)
+		}
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("KRNR_HOME", home)
+	p, err := LoadPolicy()
+	if err != nil || p != DefaultPolicy() {
+		t.Fatalf("expected the default policy without a file, got %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(home, PolicyFileName), []byte("packs = []\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if p, err = LoadPolicy(); err != nil || len(p.Rules) != 0 || p.Path != filepath.Join(home, PolicyFileName) || p.RequireSignatures {
+		t.Fatalf("unexpected policy %+v %v", p, err)
+	}
+	if err := os.WriteFile(filepath.Join(home, PolicyFileName), []byte("require_signatures = true\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if p, err = LoadPolicy(); err != nil || !p.RequireSignatures || len(p.Packs) != len(DefaultPacks) {
+		t.Fatalf("unexpected policy %+v %v", p, err)
+	}
+}
@@ -0,0 +1,171 @@
+package security
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Secret is a credential found in a command.
+type Secret struct {
+	// Kind describes the secret, e.g. "GitHub token".
+	Kind string
+	// Name is the variable, option or header the secret is assigned to,
+	// if any.
+	Name string
+	// Start and End are the byte offsets of the secret in the command.
+	Start, End int
+}
+
+// secretFormats are well-known credential formats.
+var secretFormats = []struct {
+	kind, param string
+	re          *regexp.Regexp
+}{
+	{"private key", "private_key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+	{"GitHub token", "github_token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{"GitLab token", "gitlab_token", regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20}\b`)},
+	{"AWS access key", "aws_access_key_id", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"Slack token", "slack_token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`)},
+	{"Stripe key", "stripe_key", regexp.MustCompile(`\b[rs]k_(?:live|test)_[A-Za-z0-9]{16,}\b`)},
+	{"Google API key", "google_api_key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"npm token", "npm_token", regexp.MustCompile(`\bnpm_[A-Za-z0-9]{36}\b`)},
+	{"API key", "api_key", regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}\b`)},
+	{"JSON web token", "token", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+}
+
+var (
+	// authHeaderRe matches credentials in HTTP headers.
+	authHeaderRe = regexp.MustCompile(`(?i)\b(authorization|proxy-authorization|x-api-key|api-key|private-token|x-auth-token)\s*:\s*(?:(?:bearer|token|basic)\s+)?([^\s"'$]+)`)
+	// urlCredRe matches a password in a URL.
+	urlCredRe = regexp.MustCompile(`://[^\s/:@]+:([^\s/@$]+)@`)
+	// secretNameRe matches names of variables and options that hold
+	// secrets.
+	secretNameRe = regexp.MustCompile(`(?i)(?:^|[_-])(?:pass(?:word)?|passwd|pwd|token|secret|api[_-]?key|access[_-]?key|private[_-]?key)$`)
+	// assignRe matches NAME=value, --name=value and --name value.
+	assignRe = regexp.MustCompile(`(?:^|[\s;&|?])(?:--?([A-Za-z][A-Za-z0-9_-]*)[= ]|([A-Za-z_][A-Za-z0-9_]*)=)(?:"([^"$]+)"|'([^']+)'|([^\s"'$;&|]+))`)
+	// wordRe matches candidates for high-entropy strings.
+	wordRe = regexp.MustCompile(`[A-Za-z0-9+/=_-]{20,}`)
+)
+
+// FindSecrets returns the credentials written into command, ordered by
+// position: well-known token formats, HTTP authorization headers, URL
+// passwords, values of options and variables named like secrets, and
+// random-looking strings. Parameters ({{name}}) and shell variables are
+// never reported.
+func FindSecrets(command string) []Secret {
+	var out []Secret
+	add := func(s Secret) {
+		v := command[s.Start:s.End]
+		if strings.Contains(v, "{{") || strings.HasPrefix(v, "$") || strings.HasPrefix(v, "<") {
+			return
+		}
+		for _, o := range out {
+			if s.Start < o.End && o.Start < s.End {
+				return
+			}
+		}
+		out = append(out, s)
+	}
+	for _, f := range secretFormats {
+		for _, loc := range f.re.FindAllStringIndex(command, -1) {
+			add(Secret{Kind: f.kind, Start: loc[0], End: loc[1]})
+		}
+	}
+	for _, m := range authHeaderRe.FindAllStringSubmatchIndex(command, -1) {
+		if v := strings.ToLower(command[m[4]:m[5]]); v == "bearer" || v == "token" || v == "basic" {
+			continue // the scheme of a header whose credential is a variable
+		}
+		add(Secret{Kind: "credential in an HTTP header", Name: command[m[2]:m[3]], Start: m[4], End: m[5]})
+	}
+	for _, m := range urlCredRe.FindAllStringSubmatchIndex(command, -1) {
+		add(Secret{Kind: "password in a URL", Name: "password", Start: m[2], End: m[3]})
+	}
+	for _, m := range assignRe.FindAllStringSubmatchIndex(command, -1) {
+		name := ""
+		if m[2] >= 0 {
+			name = command[m[2]:m[3]]
+		} else {
+			name = command[m[4]:m[5]]
+		}
+		var start, end int
+		for g := 6; g <= 10; g += 2 {
+			if m[g] >= 0 {
+				start, end = m[g], m[g+1]
+			}
+		}
+		if secretNameRe.MatchString(name) && end-start >= 4 {
+			add(Secret{Kind: "literal value of " + name, Name: name, Start: start, End: end})
+		}
+	}
+	for _, loc := range wordRe.FindAllStringIndex(command, -1) {
+		if highEntropy(command[loc[0]:loc[1]]) {
+			add(Secret{Kind: "random-looking string", Start: loc[0], End: loc[1]})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Start < out[j].Start })
+	return out
+}
+
+// highEntropy reports whether w looks like a random key rather than a
+// word, path, hash or identifier: mixed case and digits, and at least 4
+// bits of entropy per character. Hex strings (hashes, UUIDs) and absolute
+// paths are skipped.
+func highEntropy(w string) bool {
+	var upper, lower, digit bool
+	for _, r := range w {
+		switch {
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= '0' && r <= '9':
+			digit = true
+		}
+	}
+	if !upper || !lower || !digit || strings.Trim(w, "0123456789abcdefABCDEF-") == "" || strings.HasPrefix(w, "/") {
+		return false
+	}
+	counts := map[rune]int{}
+	for _, r := range w {
+		counts[r]++
+	}
+	var h float64
+	for _, n := range counts {
+		p := float64(n) / float64(len(w))
+		h -= p * math.Log2(p)
+	}
+	return h >= 4
+}
+
+// ParamName suggests a parameter name for the secret, named so that its
+// values are redacted (see IsSecretParamName).
+func (s Secret) ParamName() string {
+	if s.Name != "" {
+		name := strings.ToLower(strings.Trim(strings.ReplaceAll(s.Name, "-", "_"), "_"))
+		switch {
+		case name == "authorization" || name == "proxy_authorization":
+			return "token"
+		case IsSecretParamName(name):
+			return name
+		}
+		return name + "_secret"
+	}
+	for _, f := range secretFormats {
+		if f.kind == s.Kind {
+			return f.param
+		}
+	}
+	return "secret"
+}
+
+// RedactSecrets replaces the secrets FindSecrets reports in command with
+// "<redacted>" and returns how many it replaced.
+func RedactSecrets(command string) (string, int) {
+	secrets := FindSecrets(command)
+	for i := len(secrets) - 1; i >= 0; i-- {
+		command = command[:secrets[i].Start] + "<redacted>" + command[secrets[i].End:]
+	}
+	return command, len(secrets)
+}
@@ -7,33 +7,16 @@ import (
 	"strings"
 )
 
-var dangerousPatterns = []*regexp.Regexp{
-	// Destructive filesystem ops
-	regexp.MustCompile(`(?i)\brm\s+-rf\s+/?$`),
-	regexp.MustCompile(`(?i)\brm\s+-rf\s+/`),
-	regexp.MustCompile(`(?i)\bmkfs\b`),
-	regexp.MustCompile(`(?i)\bdd\s+if=`),
-	// fork bombs (e.g. :(){ :|:& };:)
-	regexp.MustCompile(`:\(\)\s*\{`),
-	// package managers removing all packages
-	regexp.MustCompile(`(?i)\bapt\-get\s+remove\s+`),
-	regexp.MustCompile(`(?i)\byum\s+remove\s+`),
-	// wipe disk
-	regexp.MustCompile(`(?i)\bwipefs\b`),
-	// dangerous use of sudo combined with other dangerous patterns
-}
-
 // CheckAllowed returns nil if the command is allowed to run, or an error
-// describing why it's blocked. Checking is conservative and not exhaustive.
+// describing why it's blocked by the default policy's block rules. Callers
+// that know the set and honour warn and confirm rules use Policy.Evaluate.
 func CheckAllowed(command string) error {
 	cmd := strings.TrimSpace(command)
 	if cmd == "" {
 		return errors.New("empty command")
 	}
-	for _, re := range dangerousPatterns {
-		if re.MatchString(cmd) {
-			return errors.New("command appears destructive or unsafe")
-		}
+	if d := DefaultPolicy().Evaluate(Subject{Command: cmd}); d.Action == ActionBlock {
+		return d.Err()
 	}
 	return nil
 }
@@ -10,6 +10,8 @@ func TestCheckAllowed(t *testing.T) {
 		"dd if=/dev/zero of=/dev/sda bs=4096",
 		":(){ :|:& };:",
 		"wipefs -a /dev/sda",
+		"rm -rf ~",
+		"chmod -R 777 /",
 	}
 	for _, s := range bad {
 		if err := CheckAllowed(s); err == nil {
@@ -21,6 +23,8 @@ func TestCheckAllowed(t *testing.T) {
 		"echo hello",
 		"ls -la",
 		"bash -c 'echo safe'",
+		"apt-get remove nginx",
+		"rm -rf /tmp/build",
 	}
 	for _, s := range good {
 		if err := CheckAllowed(s); err != nil {
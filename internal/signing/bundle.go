@@ -0,0 +1,210 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// signatureTable holds the signature of a signed bundle. It only exists in
+// exported files, never in the active database.
+const signatureTable = "bundle_signature"
+
+// payloadFormat versions the canonical serialization.
+const payloadFormat = "krnr-bundle-v1"
+
+// ErrInvalidSignature is returned for bundles modified after they were
+// signed.
+var ErrInvalidSignature = errors.New("invalid bundle signature: the bundle was modified after it was signed")
+
+// Signature is the signature embedded in a bundle.
+type Signature struct {
+	PublicKey ed25519.PublicKey
+	// Signer is who signed, as they described themselves; SignedAt is in UTC.
+	Signer   string
+	SignedAt string
+}
+
+// KeyID is the fingerprint of the signing key.
+func (s Signature) KeyID() string { return KeyID(s.PublicKey) }
+
+type payloadSet struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	AuthorName  string   `json:"author_name"`
+	AuthorEmail string   `json:"author_email"`
+	Tags        []string `json:"tags"`
+	Commands    []string `json:"commands"`
+}
+
+type payload struct {
+	Format   string       `json:"format"`
+	Signer   string       `json:"signer"`
+	SignedAt string       `json:"signed_at"`
+	Sets     []payloadSet `json:"sets"`
+}
+
+// Payload returns the canonical serialization of a bundle that its
+// signature covers: the signer, the signing time and the name,
+// description, author, tags and commands of every set, ordered by name.
+func Payload(db *sql.DB, signer, signedAt string) ([]byte, error) {
+	p := payload{Format: payloadFormat, Signer: signer, SignedAt: signedAt, Sets: []payloadSet{}}
+	rows, err := db.Query(`SELECT id, name, COALESCE(description, ''), COALESCE(author_name, ''), COALESCE(author_email, '')
+		FROM command_sets ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("read sets: %w", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		var s payloadSet
+		if err := rows.Scan(&id, &s.Name, &s.Description, &s.AuthorName, &s.AuthorEmail); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+		p.Sets = append(p.Sets, s)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i, id := range ids {
+		s := &p.Sets[i]
+		if s.Tags, err = queryStrings(db, `SELECT t.name FROM tags t JOIN command_set_tags ct ON ct.tag_id = t.id
+			WHERE ct.command_set_id = ?`, id); err != nil {
+			return nil, err
+		}
+		sort.Strings(s.Tags)
+		if s.Commands, err = queryStrings(db, "SELECT command FROM commands WHERE command_set_id = ? ORDER BY position", id); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(p)
+}
+
+func queryStrings(db *sql.DB, query string, args ...interface{}) ([]string, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	out := []string{}
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// Sign embeds a signature of the bundle db made with key, replacing any
+// previous one.
+func Sign(db *sql.DB, key Key, signer string) (Signature, error) {
+	if !key.Own() {
+		return Signature{}, fmt.Errorf("key %q has no private key", key.Name)
+	}
+	s := Signature{PublicKey: key.PublicKey, Signer: signer, SignedAt: time.Now().UTC().Format("2006-01-02 15:04:05")}
+	data, err := Payload(db, s.Signer, s.SignedAt)
+	if err != nil {
+		return Signature{}, err
+	}
+	sig := ed25519.Sign(key.PrivateKey, data)
+	if err := Unsign(db); err != nil {
+		return Signature{}, err
+	}
+	if _, err := db.Exec(`CREATE TABLE ` + signatureTable + ` (public_key TEXT NOT NULL, signer TEXT NOT NULL,
+		signed_at TEXT NOT NULL, signature TEXT NOT NULL)`); err != nil {
+		return Signature{}, fmt.Errorf("sign bundle: %w", err)
+	}
+	if _, err := db.Exec("INSERT INTO "+signatureTable+" VALUES (?, ?, ?, ?)", EncodePublicKey(s.PublicKey), s.Signer, s.SignedAt,
+		base64.StdEncoding.EncodeToString(sig)); err != nil {
+		return Signature{}, fmt.Errorf("sign bundle: %w", err)
+	}
+	return s, nil
+}
+
+// Unsign removes the signature of a bundle, if any.
+func Unsign(db *sql.DB) error {
+	_, err := db.Exec("DROP TABLE IF EXISTS " + signatureTable)
+	return err
+}
+
+// Verify checks the signature of the bundle db. It returns nil for an
+// unsigned bundle and ErrInvalidSignature when the bundle does not match
+// its signature.
+func Verify(db *sql.DB) (*Signature, error) {
+	var n int
+	if err := db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?", signatureTable).Scan(&n); err != nil {
+		return nil, fmt.Errorf("read bundle: %w", err)
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	var pub, sig string
+	var s Signature
+	if err := db.QueryRow("SELECT public_key, signer, signed_at, signature FROM "+signatureTable).Scan(&pub, &s.Signer, &s.SignedAt, &sig); err != nil {
+		return nil, ErrInvalidSignature
+	}
+	var err error
+	if s.PublicKey, err = ParsePublicKey(pub); err != nil {
+		return nil, ErrInvalidSignature
+	}
+	rawSig, err := base64.StdEncoding.DecodeString(sig)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	data, err := Payload(db, s.Signer, s.SignedAt)
+	if err != nil {
+		return nil, err
+	}
+	if !ed25519.Verify(s.PublicKey, data, rawSig) {
+		return nil, ErrInvalidSignature
+	}
+	return &s, nil
+}
+
+// Verification is a bundle's signature checked against the keyring.
+type Verification struct {
+	// Signature is nil for an unsigned bundle.
+	Signature *Signature
+	// Key is the keyring key that signed, nil when it is not in the
+	// keyring.
+	Key *Key
+}
+
+// Trusted reports whether the bundle was signed by a key in the keyring.
+func (v Verification) Trusted() bool { return v.Signature != nil && v.Key != nil }
+
+// String describes the signer for display.
+func (v Verification) String() string {
+	if v.Signature == nil {
+		return "unsigned"
+	}
+	s := fmt.Sprintf("signed by %s on %s with key %s", v.Signature.Signer, v.Signature.SignedAt, v.Signature.KeyID())
+	if v.Key == nil {
+		return s + ", which is not in your keyring"
+	}
+	return fmt.Sprintf("%s (trusted key %q)", s, v.Key.Name)
+}
+
+// VerifyWith checks the signature of the bundle db and looks its key up in
+// the keyring.
+func VerifyWith(db *sql.DB, k *Keyring) (Verification, error) {
+	s, err := Verify(db)
+	if err != nil || s == nil {
+		return Verification{}, err
+	}
+	v := Verification{Signature: s}
+	if key, ok := k.Lookup(s.PublicKey); ok {
+		v.Key = &key
+	}
+	return v, nil
+}
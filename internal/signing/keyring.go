@@ -0,0 +1,188 @@
+// Package signing signs exported bundles with Ed25519 keys and verifies
+// them against a local keyring.
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/VoxDroid/krnr/internal/config"
+)
+
+// KeyringFileName is the keyring file in the data directory.
+const KeyringFileName = "keyring.json"
+
+// Key is a key in the keyring: one of the user's own signing keys, which
+// has a private key, or a trusted public key of someone else.
+type Key struct {
+	Name       string             `json:"name"`
+	PublicKey  ed25519.PublicKey  `json:"public_key"`
+	PrivateKey ed25519.PrivateKey `json:"private_key,omitempty"`
+	Added      string             `json:"added"`
+}
+
+// Own reports whether the key can sign.
+func (k Key) Own() bool { return len(k.PrivateKey) == ed25519.PrivateKeySize }
+
+// ID is the short fingerprint of the key.
+func (k Key) ID() string { return KeyID(k.PublicKey) }
+
+// KeyID returns the short fingerprint of a public key: the first 16 hex
+// digits of its SHA-256.
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// EncodePublicKey renders a public key the way 'krnr keys list' shows it
+// and 'krnr keys trust' reads it.
+func EncodePublicKey(pub ed25519.PublicKey) string {
+	return base64.StdEncoding.EncodeToString(pub)
+}
+
+// ParsePublicKey parses a public key rendered by EncodePublicKey.
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(b) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key %q: expected %d base64-encoded bytes", s, ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(b), nil
+}
+
+// Keyring holds the user's own keys and the public keys they trust. It is
+// stored with mode 0600 since it holds private keys.
+type Keyring struct {
+	Path string `json:"-"`
+	Keys []Key  `json:"keys"`
+}
+
+// KeyringPath returns the keyring file in the data directory.
+func KeyringPath() (string, error) {
+	d, err := config.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(d, KeyringFileName), nil
+}
+
+// LoadKeyring reads the keyring, which is empty when the file does not
+// exist yet.
+func LoadKeyring() (*Keyring, error) {
+	p, err := KeyringPath()
+	if err != nil {
+		return nil, err
+	}
+	k := &Keyring{Path: p}
+	data, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return k, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, k); err != nil {
+		return nil, fmt.Errorf("%s: %w", p, err)
+	}
+	for _, key := range k.Keys {
+		if len(key.PublicKey) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%s: key %q has an invalid public key", p, key.Name)
+		}
+	}
+	return k, nil
+}
+
+// Save writes the keyring.
+func (k *Keyring) Save() error {
+	data, err := json.MarshalIndent(k, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(k.Path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(k.Path, append(data, '\n'), 0o600)
+}
+
+// Get returns the key named name.
+func (k *Keyring) Get(name string) (Key, bool) {
+	for _, key := range k.Keys {
+		if key.Name == name {
+			return key, true
+		}
+	}
+	return Key{}, false
+}
+
+// Lookup returns the key with the given public key.
+func (k *Keyring) Lookup(pub ed25519.PublicKey) (Key, bool) {
+	for _, key := range k.Keys {
+		if key.PublicKey.Equal(pub) {
+			return key, true
+		}
+	}
+	return Key{}, false
+}
+
+func (k *Keyring) add(key Key) (Key, error) {
+	if strings.TrimSpace(key.Name) == "" {
+		return Key{}, errors.New("key name cannot be empty")
+	}
+	if _, ok := k.Get(key.Name); ok {
+		return Key{}, fmt.Errorf("a key named %q already exists", key.Name)
+	}
+	if other, ok := k.Lookup(key.PublicKey); ok {
+		return Key{}, fmt.Errorf("key %s is already in the keyring as %q", key.ID(), other.Name)
+	}
+	key.Added = time.Now().UTC().Format("2006-01-02 15:04:05")
+	k.Keys = append(k.Keys, key)
+	return key, nil
+}
+
+// Generate adds a new signing key named name.
+func (k *Keyring) Generate(name string) (Key, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return Key{}, err
+	}
+	return k.add(Key{Name: name, PublicKey: pub, PrivateKey: priv})
+}
+
+// Trust adds someone else's public key under name.
+func (k *Keyring) Trust(name string, pub ed25519.PublicKey) (Key, error) {
+	return k.add(Key{Name: name, PublicKey: pub})
+}
+
+// SigningKey returns the own key named name, or the only own key when name
+// is empty.
+func (k *Keyring) SigningKey(name string) (Key, error) {
+	if name != "" {
+		key, ok := k.Get(name)
+		if !ok || !key.Own() {
+			return Key{}, fmt.Errorf("no signing key named %q (see 'krnr keys list')", name)
+		}
+		return key, nil
+	}
+	var own []Key
+	for _, key := range k.Keys {
+		if key.Own() {
+			own = append(own, key)
+		}
+	}
+	switch len(own) {
+	case 0:
+		return Key{}, errors.New("no signing key: create one with 'krnr keys generate <name>'")
+	case 1:
+		return own[0], nil
+	}
+	return Key{}, errors.New("several signing keys: choose one with --key")
+}
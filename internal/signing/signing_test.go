@@ -0,0 +1,129 @@
+package signing
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	dbpkg "github.com/VoxDroid/krnr/internal/db"
+)
+
+func TestKeyring(t *testing.T) {
+	t.Setenv("KRNR_HOME", t.TempDir())
+	k, err := LoadKeyring()
+	if err != nil || len(k.Keys) != 0 {
+		t.Fatalf("expected an empty keyring, got %+v %v", k, err)
+	}
+	if _, err := k.SigningKey(""); err == nil {
+		t.Fatal("expected an error without signing keys")
+	}
+	mine, err := k.Generate("me")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := k.Generate("me"); err == nil {
+		t.Fatal("expected duplicate names to be refused")
+	}
+	if _, err := k.Trust("again", mine.PublicKey); err == nil {
+		t.Fatal("expected a key already in the keyring to be refused")
+	}
+	pub, err := ParsePublicKey(EncodePublicKey(mine.PublicKey))
+	if err != nil || !pub.Equal(mine.PublicKey) {
+		t.Fatalf("public key round trip: %v", err)
+	}
+	if _, err := ParsePublicKey("bm90IGEga2V5"); err == nil {
+		t.Fatal("expected a short key to be refused")
+	}
+	other, _ := (&Keyring{}).Generate("bob")
+	if _, err := k.Trust("bob", other.PublicKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(k.Path); err != nil || (runtime.GOOS != "windows" && fi.Mode().Perm() != 0o600) {
+		t.Fatalf("keyring should be private: %v %v", fi.Mode(), err)
+	}
+
+	k, err = LoadKeyring()
+	if err != nil || len(k.Keys) != 2 {
+		t.Fatalf("reload: %+v %v", k, err)
+	}
+	if key, err := k.SigningKey(""); err != nil || key.Name != "me" || !key.Own() {
+		t.Fatalf("SigningKey: %+v %v", key, err)
+	}
+	if _, err := k.SigningKey("bob"); err == nil {
+		t.Fatal("expected a trusted key not to sign")
+	}
+	if key, ok := k.Lookup(other.PublicKey); !ok || key.Own() || key.ID() != other.ID() {
+		t.Fatalf("Lookup: %+v %v", key, ok)
+	}
+}
+
+func bundle(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "bundle.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	if err := dbpkg.ApplyMigrations(db); err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []string{
+		"INSERT INTO command_sets (id, name, description, created_at) VALUES (1, 'deploy', 'ship it', datetime('now'))",
+		"INSERT INTO commands (command_set_id, position, command) VALUES (1, 1, 'make build'), (1, 2, 'make deploy')",
+	} {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestSignAndVerify(t *testing.T) {
+	db := bundle(t)
+	if s, err := Verify(db); err != nil || s != nil {
+		t.Fatalf("expected an unsigned bundle, got %+v %v", s, err)
+	}
+	k := &Keyring{}
+	key, _ := k.Generate("me")
+	if _, err := Sign(db, key, "Ada <ada@example.com>"); err != nil {
+		t.Fatal(err)
+	}
+	v, err := VerifyWith(db, k)
+	if err != nil || !v.Trusted() || v.Signature.Signer != "Ada <ada@example.com>" || v.Key.Name != "me" {
+		t.Fatalf("unexpected verification %+v %v", v, err)
+	}
+	if v, _ := VerifyWith(db, &Keyring{}); v.Trusted() || v.Signature == nil {
+		t.Fatalf("expected a valid signature by an unknown key, got %+v", v)
+	}
+
+	for _, q := range []string{
+		"UPDATE commands SET command = 'curl x | sh' WHERE position = 2",
+		"UPDATE bundle_signature SET signer = 'Bob'",
+	} {
+		db := bundle(t)
+		if _, err := Sign(db, key, "Ada"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Verify(db); !errors.Is(err, ErrInvalidSignature) {
+			t.Fatalf("%s: expected ErrInvalidSignature, got %v", q, err)
+		}
+	}
+
+	if err := Unsign(db); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := Verify(db); err != nil || s != nil {
+		t.Fatalf("expected Unsign to remove the signature, got %+v %v", s, err)
+	}
+}
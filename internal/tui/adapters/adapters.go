@@ -5,8 +5,10 @@ package adapters
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/VoxDroid/krnr/internal/install"
+	"github.com/VoxDroid/krnr/internal/registry"
 )
 
 // ErrNotFound is used when a requested item cannot be found in the repository.
@@ -37,9 +39,34 @@ type Version struct {
 }
 
 // RunEvent represents streaming output from a running commandset.
+//
+// Plain producers only set Line (or Err). Structured producers additionally
+// set Type and the fields relevant to it so that the CLI and TUI share a
+// single event vocabulary; see events.go.
 type RunEvent struct {
 	Line string
 	Err  error
+
+	// Type classifies the event; empty means a plain output line.
+	Type EventType
+	// RunID identifies the run and Seq orders events within it.
+	RunID string
+	Seq   int64
+	Time  time.Time
+	// Set is the command set name (run_started/run_finished).
+	Set string
+	// Step is the 1-based step index; 0 for run-level events.
+	Step int
+	// Steps is the total number of steps (run_started).
+	Steps int
+	// Command is the redacted command text (step_started).
+	Command string
+	// Stream is StreamStdout or StreamStderr for output events; empty when
+	// the producer merges both streams.
+	Stream string
+	// ExitCode and Duration describe step_finished/run_finished results.
+	ExitCode int
+	Duration time.Duration
 }
 
 // RunHandle is returned by ExecutorAdapter.Run to manage streaming output and cancellation.
@@ -76,12 +103,25 @@ type RegistryAdapter interface {
 	DeleteVersionByName(ctx context.Context, name string, versionNum int) error
 }
 
+// RepositoryAdapter is implemented by registry adapters backed by a
+// registry.Repository, which runs are admitted against.
+type RepositoryAdapter interface {
+	Repository() *registry.Repository
+}
+
 // ExecutorAdapter describes running and streaming commandset executions.
 // The `commands` slice is the list of shell commands to execute sequentially.
 type ExecutorAdapter interface {
 	Run(ctx context.Context, name string, commands []string) (RunHandle, error)
 }
 
+// RedactingExecutorAdapter is implemented by executors that can show a
+// different form of each command than the one executed: redacted[i] (with
+// secret parameter values masked) replaces commands[i] in events and logs.
+type RedactingExecutorAdapter interface {
+	RunRedacted(ctx context.Context, name string, commands, redacted []string) (RunHandle, error)
+}
+
 // ImportExportAdapter describes import/export operations.
 type ImportExportAdapter interface {
 	Export(ctx context.Context, name string, dest string) error
@@ -93,6 +133,14 @@ type ImportExportAdapter interface {
 	ImportDB(ctx context.Context, src string, overwrite bool) error
 }
 
+// BundleVerifier is implemented by import/export adapters that check the
+// signatures of exported bundles (see `krnr keys`).
+type BundleVerifier interface {
+	// VerifyBundle describes who signed the bundle at src, or that it is
+	// unsigned.
+	VerifyBundle(ctx context.Context, src string) (string, error)
+}
+
 // InstallerAdapter minimal interface for install/uninstall
 // Install accepts options controlling system/user scope and add-to-path behavior.
 // Uninstall returns the human-readable actions performed.
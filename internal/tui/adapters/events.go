@@ -0,0 +1,139 @@
+package adapters
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// EventType names a structured run event.
+type EventType string
+
+// Run event types shared by the CLI (`krnr run --output jsonl`) and the TUI.
+const (
+	EventRunStarted   EventType = "run_started"
+	EventStepStarted  EventType = "step_started"
+	EventOutput       EventType = "output"
+	EventStepFinished EventType = "step_finished"
+	EventRunFinished  EventType = "run_finished"
+	// EventStepPaused is emitted before a step when the run waits for a
+	// step-mode or breakpoint decision.
+	EventStepPaused EventType = "step_paused"
+)
+
+// Output streams carried by EventOutput events.
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// ID returns a stable identifier for the event within its run.
+func (e RunEvent) ID() string {
+	return fmt.Sprintf("%s-%d", e.RunID, e.Seq)
+}
+
+// jsonEvent is the wire form of a RunEvent. Field names are part of the
+// public `--output jsonl` contract; only add fields, never rename them.
+type jsonEvent struct {
+	ID         string    `json:"id"`
+	Type       EventType `json:"type"`
+	RunID      string    `json:"run_id"`
+	Seq        int64     `json:"seq"`
+	Time       time.Time `json:"time"`
+	Set        string    `json:"set,omitempty"`
+	Step       int       `json:"step,omitempty"`
+	Steps      int       `json:"steps,omitempty"`
+	Command    string    `json:"command,omitempty"`
+	Stream     string    `json:"stream,omitempty"`
+	Data       string    `json:"data,omitempty"`
+	ExitCode   *int      `json:"exit_code,omitempty"`
+	DurationMS *int64    `json:"duration_ms,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// MarshalJSON encodes the event in its stable wire form.
+func (e RunEvent) MarshalJSON() ([]byte, error) {
+	t := e.Type
+	if t == "" {
+		t = EventOutput
+	}
+	je := jsonEvent{
+		ID: e.ID(), Type: t, RunID: e.RunID, Seq: e.Seq, Time: e.Time,
+		Set: e.Set, Step: e.Step, Steps: e.Steps, Command: e.Command,
+		Stream: e.Stream, Data: e.Line,
+	}
+	if t == EventStepFinished || t == EventRunFinished {
+		code := e.ExitCode
+		ms := e.Duration.Milliseconds()
+		je.ExitCode = &code
+		je.DurationMS = &ms
+	}
+	if e.Err != nil {
+		je.Error = e.Err.Error()
+	}
+	return json.Marshal(je)
+}
+
+// EventEmitter stamps events with a run ID, sequence number and timestamp
+// and fans them out to sinks. It is safe for concurrent use; sinks are called
+// with the emitter's lock held and must not call Emit themselves.
+type EventEmitter struct {
+	RunID string
+
+	mu    sync.Mutex
+	seq   int64
+	sinks []func(RunEvent)
+	now   func() time.Time
+}
+
+// NewEventEmitter returns an emitter for the given run.
+func NewEventEmitter(runID string, sinks ...func(RunEvent)) *EventEmitter {
+	return &EventEmitter{RunID: runID, sinks: sinks, now: func() time.Time { return time.Now().UTC() }}
+}
+
+// AddSink registers an additional consumer.
+func (em *EventEmitter) AddSink(s func(RunEvent)) {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+	em.sinks = append(em.sinks, s)
+}
+
+// Emit stamps ev and delivers it to every sink in registration order.
+func (em *EventEmitter) Emit(ev RunEvent) {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+	em.seq++
+	ev.RunID = em.RunID
+	ev.Seq = em.seq
+	ev.Time = em.now()
+	for _, s := range em.sinks {
+		s(ev)
+	}
+}
+
+// OutputWriter returns an io.Writer that emits each write as an output event
+// for the given step and stream.
+func (em *EventEmitter) OutputWriter(step int, stream string) io.Writer {
+	return &eventWriter{em: em, step: step, stream: stream}
+}
+
+type eventWriter struct {
+	em     *EventEmitter
+	step   int
+	stream string
+}
+
+func (w *eventWriter) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		w.em.Emit(RunEvent{Type: EventOutput, Step: w.step, Stream: w.stream, Line: string(p)})
+	}
+	return len(p), nil
+}
+
+// JSONLSink returns a sink that writes one JSON object per line to w.
+func JSONLSink(w io.Writer) func(RunEvent) {
+	enc := json.NewEncoder(w)
+	return func(ev RunEvent) { _ = enc.Encode(ev) }
+}
@@ -0,0 +1,54 @@
+package adapters
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventEmitterStampsAndEncodesJSONL(t *testing.T) {
+	var buf bytes.Buffer
+	em := NewEventEmitter("run-1", JSONLSink(&buf))
+	em.now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	em.Emit(RunEvent{Type: EventRunStarted, Set: "demo", Steps: 1})
+	em.Emit(RunEvent{Type: EventStepStarted, Step: 1, Command: "echo hi"})
+	_, _ = fmt.Fprint(em.OutputWriter(1, StreamStderr), "oops\n")
+	em.Emit(RunEvent{Type: EventStepFinished, Step: 1, ExitCode: 2, Duration: 1500 * time.Millisecond, Err: errors.New("exit status 2")})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 events, got %d: %q", len(lines), buf.String())
+	}
+	var out map[string]any
+	if err := json.Unmarshal([]byte(lines[2]), &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if out["id"] != "run-1-3" || out["type"] != "output" || out["stream"] != "stderr" || out["data"] != "oops\n" || out["step"] != float64(1) {
+		t.Fatalf("unexpected output event: %v", out)
+	}
+	if _, ok := out["exit_code"]; ok {
+		t.Fatalf("output events must not carry exit_code: %v", out)
+	}
+	out = nil
+	if err := json.Unmarshal([]byte(lines[3]), &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if out["exit_code"] != float64(2) || out["duration_ms"] != float64(1500) || out["error"] != "exit status 2" || out["time"] != "2026-01-02T03:04:05Z" {
+		t.Fatalf("unexpected step_finished event: %v", out)
+	}
+}
+
+func TestPlainRunEventEncodesAsOutput(t *testing.T) {
+	b, err := json.Marshal(RunEvent{Line: "hello"})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if !strings.Contains(string(b), `"type":"output"`) || !strings.Contains(string(b), `"data":"hello"`) {
+		t.Fatalf("unexpected encoding: %s", b)
+	}
+}
@@ -2,19 +2,29 @@ package adapters
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/exec"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/VoxDroid/krnr/internal/executor"
+	"github.com/VoxDroid/krnr/internal/runlog"
 	"github.com/VoxDroid/krnr/internal/tui/sanitize"
 	"golang.org/x/term"
 )
 
 // executorAdapter implements ExecutorAdapter using an executor.Runner.
-type executorAdapter struct{ runner executor.Runner }
+// When logRuns is set each run is also persisted via the runlog package and
+// the set's logs are pruned to retention afterwards.
+type executorAdapter struct {
+	runner    executor.Runner
+	logRuns   bool
+	retention runlog.Retention
+}
 
 // hostIsTerminal determines whether the provided fd refers to a terminal on
 // the host. It is a package-level variable so unit tests can override it to
@@ -24,6 +34,13 @@ var hostIsTerminal = func(fd int) bool { return term.IsTerminal(fd) }
 // NewExecutorAdapter constructs an ExecutorAdapter backed by the provided Runner.
 func NewExecutorAdapter(r executor.Runner) ExecutorAdapter { return &executorAdapter{runner: r} }
 
+// NewLoggingExecutorAdapter is like NewExecutorAdapter but also records each
+// run's output under KRNR_HOME/logs so it can be viewed with `krnr logs`,
+// keeping the set's logs within ret.
+func NewLoggingExecutorAdapter(r executor.Runner, ret runlog.Retention) ExecutorAdapter {
+	return &executorAdapter{runner: r, logRuns: true, retention: ret}
+}
+
 // fdReader wraps an io.Reader and exposes a Fd() method so the executor's
 // PTY detection recognises it as terminal-backed. The fd reports the host
 // stdin file descriptor; the actual reads come from the wrapped pipe reader.
@@ -35,17 +52,75 @@ type fdReader struct {
 func (f *fdReader) Read(p []byte) (int, error) { return f.r.Read(p) }
 func (f *fdReader) Fd() uintptr                { return f.fd }
 
-func (e *executorAdapter) Run(ctx context.Context, _ string, commands []string) (RunHandle, error) {
+func (e *executorAdapter) Run(ctx context.Context, name string, commands []string) (RunHandle, error) {
+	return e.RunStepped(ctx, name, commands, nil)
+}
+
+// RunStepped is like Run but pauses before every step for which pause
+// returns true, emitting EventStepPaused and waiting for a StepDecision via
+// the handle's Decide method.
+func (e *executorAdapter) RunStepped(ctx context.Context, name string, commands []string, pause func(step int) bool) (RunHandle, error) {
+	return e.start(ctx, name, commands, nil, pause)
+}
+
+// RunRedacted is like Run but reports redacted[i] instead of commands[i] in
+// step events and the run log, so secret parameter values stay hidden.
+func (e *executorAdapter) RunRedacted(ctx context.Context, name string, commands, redacted []string) (RunHandle, error) {
+	if len(redacted) != len(commands) {
+		return nil, fmt.Errorf("redacted commands do not match commands (%d vs %d)", len(redacted), len(commands))
+	}
+	return e.start(ctx, name, commands, redacted, nil)
+}
+
+type runIDKey struct{}
+
+// WithRunID returns a context for starting a run whose ID was already
+// assigned, so that its run log and history entry share it.
+func WithRunID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, runIDKey{}, id)
+}
+
+func (e *executorAdapter) start(ctx context.Context, name string, commands, redacted []string, pause func(step int) bool) (RunHandle, error) {
 	ctx, cancel := context.WithCancel(ctx)
 	rchan := make(chan RunEvent)
-	run := &runHandleImpl{ch: rchan, cancel: cancel}
+	run := &runHandleImpl{ch: rchan, cancel: cancel, decisions: make(chan StepDecision, 1)}
+	lw := e.openRunLog(ctx, name)
 
 	go func() {
 		defer close(rchan)
-		for _, cmdText := range commands {
-			rchan <- RunEvent{Line: fmt.Sprintf("-> %s", cmdText)}
-			if err := e.execAndStream(ctx, cmdText, rchan, run); err != nil {
-				rchan <- RunEvent{Err: fmt.Errorf("exec: %w", err)}
+		var runErr error
+		defer func() {
+			if lw != nil {
+				_ = lw.Close(runErr)
+				_, _ = runlog.Prune(name, e.retention)
+			}
+		}()
+		for i, cmdText := range commands {
+			if pause != nil && pause(i+1) {
+				var skip bool
+				if cmdText, skip, runErr = waitForDecision(ctx, i+1, cmdText, rchan, run); runErr != nil {
+					rchan <- RunEvent{Err: runErr}
+					return
+				}
+				if skip {
+					continue
+				}
+			}
+			shown := cmdText
+			if redacted != nil {
+				shown = redacted[i]
+			}
+			rchan <- RunEvent{Type: EventStepStarted, Step: i + 1, Command: shown, Line: fmt.Sprintf("-> %s", shown)}
+			start := time.Now()
+			if lw != nil {
+				lw.StepStart(i+1, shown)
+			}
+			runErr = e.execAndStream(ctx, i+1, cmdText, rchan, run, lw)
+			if lw != nil {
+				lw.StepEnd(exitCodeOf(runErr), time.Since(start), runErr)
+			}
+			if runErr != nil {
+				rchan <- RunEvent{Err: fmt.Errorf("exec: %w", runErr)}
 				return
 			}
 		}
@@ -54,27 +129,89 @@ func (e *executorAdapter) Run(ctx context.Context, _ string, commands []string)
 	return run, nil
 }
 
+// waitForDecision announces a paused step and blocks until the front end
+// decides. It returns the (possibly edited) command and whether to skip it.
+func waitForDecision(ctx context.Context, step int, cmdText string, rchan chan<- RunEvent, run *runHandleImpl) (string, bool, error) {
+	rchan <- RunEvent{Type: EventStepPaused, Step: step, Command: cmdText, Line: fmt.Sprintf("|| paused before step %d: %s", step, cmdText)}
+	var d StepDecision
+	select {
+	case d = <-run.decisions:
+	case <-ctx.Done():
+		return "", false, ctx.Err()
+	}
+	switch d.Action {
+	case StepSkip:
+		rchan <- RunEvent{Line: fmt.Sprintf("skipped step %d", step)}
+		return "", true, nil
+	case StepAbort:
+		return "", false, fmt.Errorf("%w at step %d", ErrRunAborted, step)
+	case StepEdit:
+		if c := strings.TrimSpace(d.Command); c != "" {
+			return c, false, nil
+		}
+	}
+	return cmdText, false, nil
+}
+
+// openRunLog starts a run log when logging is enabled, under the run ID of
+// ctx when it has one. Failures only disable logging; they never prevent
+// the run itself.
+func (e *executorAdapter) openRunLog(ctx context.Context, name string) *runlog.Writer {
+	if !e.logRuns || name == "" {
+		return nil
+	}
+	id, _ := ctx.Value(runIDKey{}).(string)
+	if id == "" {
+		id = runlog.NewRunID()
+	}
+	lw, err := runlog.CreateWithID(name, id)
+	if err != nil {
+		return nil
+	}
+	return lw
+}
+
+// exitCodeOf extracts a process exit code from an execution error (0 for nil,
+// -1 when unknown).
+func exitCodeOf(err error) int {
+	if err == nil {
+		return 0
+	}
+	var ee *exec.ExitError
+	if errors.As(err, &ee) {
+		return ee.ExitCode()
+	}
+	return -1
+}
+
 // execAndStream launches a single command, streams its output to rchan, and
 // returns the command error (if any). It wires up stdin/stdout pipes and
 // the escape-sequence buffering loop.
-func (e *executorAdapter) execAndStream(ctx context.Context, cmdText string, rchan chan<- RunEvent, run *runHandleImpl) error {
+func (e *executorAdapter) execAndStream(ctx context.Context, step int, cmdText string, rchan chan<- RunEvent, run *runHandleImpl, lw *runlog.Writer) error {
 	rOut, wOut := io.Pipe()
+	var out io.Writer = wOut
+	if lw != nil {
+		out = io.MultiWriter(wOut, lw)
+	}
 	rIn, wIn := io.Pipe()
+	// Wrap stdin so the executor detects a terminal fd and activates
+	// hybrid PTY mode (stdin/ctty via PTY, stdout/stderr via pipes).
+	// This lets interactive prompts (sudo password) work while keeping
+	// stdout as a pipe so programs like fastfetch use simple output.
+	stdinReader := prepareStdin(rIn)
 	run.stdin = wIn
+	if stdinReader == nil {
+		run.stdin = nil
+	}
 
 	execErr := make(chan error, 1)
 	go func() {
-		// Wrap stdin so the executor detects a terminal fd and activates
-		// hybrid PTY mode (stdin/ctty via PTY, stdout/stderr via pipes).
-		// This lets interactive prompts (sudo password) work while keeping
-		// stdout as a pipe so programs like fastfetch use simple output.
-		stdinReader := prepareStdin(rIn)
-		execErr <- e.runner.Execute(ctx, cmdText, "", stdinReader, wOut, wOut)
+		execErr <- e.runner.Execute(ctx, cmdText, "", stdinReader, out, out)
 		_ = wOut.Close()
 		_ = wIn.Close()
 	}()
 
-	streamOutput(ctx, rOut, rchan)
+	streamOutput(ctx, step, rOut, rchan)
 
 	err := <-execErr
 	_ = rOut.Close()
@@ -83,36 +220,38 @@ func (e *executorAdapter) execAndStream(ctx context.Context, cmdText string, rch
 
 // prepareStdin wraps rIn with an Fd() accessor when the host stdin is a
 // terminal so the executor detects it and runs the child with hybrid PTY
-// (stdin/ctty via PTY, stdout/stderr as pipes).
+// (stdin/ctty via PTY, stdout/stderr as pipes). Without a host terminal it
+// returns nil: nobody can type input, and exec would otherwise keep waiting
+// for its stdin copy loop on rIn after the command exits.
 func prepareStdin(rIn io.Reader) io.Reader {
 	if hostIsTerminal(int(os.Stdin.Fd())) {
 		return &fdReader{r: rIn, fd: os.Stdin.Fd()}
 	}
-	return rIn
+	return nil
 }
 
 // streamOutput reads from rOut in chunks, buffers incomplete escape sequences
-// across reads, and emits sanitized lines to rchan.
-func streamOutput(ctx context.Context, rOut io.ReadCloser, rchan chan<- RunEvent) {
+// across reads, and emits sanitized lines for the given step to rchan.
+func streamOutput(ctx context.Context, step int, rOut io.ReadCloser, rchan chan<- RunEvent) {
 	buf := make([]byte, 4096)
 	escBuf := ""
 	for {
 		select {
 		case <-ctx.Done():
 			_ = rOut.Close()
-			flushEscBuf(escBuf, rchan)
+			flushEscBuf(escBuf, step, rchan)
 			return
 		default:
 			n, err := rOut.Read(buf)
 			if n > 0 {
-				escBuf = emitChunkLines(escBuf+string(buf[:n]), rchan)
+				escBuf = emitChunkLines(escBuf+string(buf[:n]), step, rchan)
 			}
 			if err != nil {
 				if err != io.EOF {
 					rchan <- RunEvent{Err: err}
 				}
 				_ = rOut.Close()
-				flushEscBuf(escBuf, rchan)
+				flushEscBuf(escBuf, step, rchan)
 				return
 			}
 		}
@@ -122,7 +261,7 @@ func streamOutput(ctx context.Context, rOut io.ReadCloser, rchan chan<- RunEvent
 // flushEscBuf emits any remaining data held in the escape-sequence carry
 // buffer. This is called when the stream ends (EOF or cancellation) to
 // ensure no trailing output is silently dropped.
-func flushEscBuf(escBuf string, rchan chan<- RunEvent) {
+func flushEscBuf(escBuf string, step int, rchan chan<- RunEvent) {
 	if escBuf == "" {
 		return
 	}
@@ -130,13 +269,19 @@ func flushEscBuf(escBuf string, rchan chan<- RunEvent) {
 	if line == "" {
 		return
 	}
-	rchan <- RunEvent{Line: sanitize.RunOutput(line)}
+	rchan <- outputEvent(step, line)
+}
+
+// outputEvent builds a sanitized output event. The TUI merges stdout and
+// stderr into one pipe, so Stream is left empty.
+func outputEvent(step int, line string) RunEvent {
+	return RunEvent{Type: EventOutput, Step: step, Line: sanitize.RunOutput(line)}
 }
 
 // emitChunkLines splits a chunk into lines, sanitizes each, and sends them
 // to rchan. It returns any trailing incomplete escape sequence that should
 // be carried into the next read.
-func emitChunkLines(chunk string, rchan chan<- RunEvent) string {
+func emitChunkLines(chunk string, step int, rchan chan<- RunEvent) string {
 	tail := trailingIncompleteEscape(chunk)
 	if tail != "" {
 		chunk = chunk[:len(chunk)-len(tail)]
@@ -153,20 +298,31 @@ func emitChunkLines(chunk string, rchan chan<- RunEvent) string {
 		if line == "" {
 			continue
 		}
-		rchan <- RunEvent{Line: sanitize.RunOutput(line)}
+		rchan <- outputEvent(step, line)
 	}
 	return tail
 }
 
 type runHandleImpl struct {
-	ch     <-chan RunEvent
-	cancel context.CancelFunc
-	stdin  io.WriteCloser
+	ch        <-chan RunEvent
+	cancel    context.CancelFunc
+	stdin     io.WriteCloser
+	decisions chan StepDecision
 }
 
 func (r *runHandleImpl) Events() <-chan RunEvent { return r.ch }
 func (r *runHandleImpl) Cancel()                 { r.cancel() }
 
+// Decide answers the pending EventStepPaused event.
+func (r *runHandleImpl) Decide(d StepDecision) error {
+	select {
+	case r.decisions <- d:
+		return nil
+	default:
+		return fmt.Errorf("a decision is already pending")
+	}
+}
+
 func (r *runHandleImpl) WriteInput(p []byte) (int, error) {
 	if r.stdin == nil {
 		return 0, fmt.Errorf("run does not accept input")
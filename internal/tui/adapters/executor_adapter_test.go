@@ -1,11 +1,16 @@
 package adapters
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"io"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/VoxDroid/krnr/internal/config"
+	"github.com/VoxDroid/krnr/internal/runlog"
 	"github.com/VoxDroid/krnr/internal/tui/sanitize"
 )
 
@@ -124,3 +129,62 @@ func TestExecutorAdapter_StreamsPromptWithoutNewline(t *testing.T) {
 		t.Fatalf("expected prompt to be streamed without newline")
 	}
 }
+
+func TestExecutorAdapter_RunSteppedDecisions(t *testing.T) {
+	a := NewExecutorAdapter(&fakeRunner{lines: []string{"out"}}).(StepExecutorAdapter)
+	h, err := a.RunStepped(context.Background(), "set", []string{"one", "two", "three"}, func(step int) bool { return step >= 2 })
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	decisions := []StepDecision{{Action: StepEdit, Command: "edited"}, {Action: StepAbort}}
+	var started []string
+	var runErr error
+	for ev := range h.Events() {
+		switch {
+		case ev.Err != nil:
+			runErr = ev.Err
+		case ev.Type == EventStepPaused:
+			if err := h.(StepController).Decide(decisions[0]); err != nil {
+				t.Fatalf("Decide: %v", err)
+			}
+			decisions = decisions[1:]
+		case ev.Type == EventStepStarted:
+			started = append(started, ev.Command)
+		}
+	}
+	if len(started) != 2 || started[0] != "one" || started[1] != "edited" {
+		t.Fatalf("unexpected started steps: %v", started)
+	}
+	if !errors.Is(runErr, ErrRunAborted) {
+		t.Fatalf("expected aborted run, got %v", runErr)
+	}
+}
+
+func TestExecutorAdapter_RunRedactedHidesSecretsInEventsAndLog(t *testing.T) {
+	t.Setenv(config.EnvKRNRHome, t.TempDir())
+	a := NewLoggingExecutorAdapter(&fakeRunner{lines: []string{"ok"}}, runlog.Retention{}).(RedactingExecutorAdapter)
+	h, err := a.RunRedacted(context.Background(), "deploy", []string{"curl -H 'token: s3cret'"}, []string{"curl -H 'token: <redacted>'"})
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	for ev := range h.Events() {
+		if ev.Type == EventStepStarted && (ev.Command != "curl -H 'token: <redacted>'" || strings.Contains(ev.Line, "s3cret")) {
+			t.Fatalf("secret leaked in event: %+v", ev)
+		}
+	}
+	run, err := runlog.FindRun("deploy", "")
+	if err != nil {
+		t.Fatalf("FindRun: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := runlog.Copy(&buf, run, runlog.Filter{}); err != nil {
+		t.Fatalf("Copy: %v", err)
+	}
+	if strings.Contains(buf.String(), "s3cret") || !strings.Contains(buf.String(), "<redacted>") {
+		t.Fatalf("unexpected log:\n%s", buf.String())
+	}
+
+	if _, err := a.RunRedacted(context.Background(), "deploy", []string{"a", "b"}, []string{"a"}); err == nil {
+		t.Fatalf("expected mismatched redacted commands to fail")
+	}
+}
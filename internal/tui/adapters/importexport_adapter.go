@@ -9,6 +9,30 @@ import (
 	"github.com/VoxDroid/krnr/internal/importer"
 )
 
+type passphraseKey struct{}
+
+// WithPassphrase returns a context under which exports are encrypted with
+// passphrase and encrypted bundles are imported with it.
+func WithPassphrase(ctx context.Context, passphrase []byte) context.Context {
+	return context.WithValue(ctx, passphraseKey{}, passphrase)
+}
+
+// Passphrase returns the passphrase set with WithPassphrase, or nil.
+func Passphrase(ctx context.Context) []byte {
+	p, _ := ctx.Value(passphraseKey{}).([]byte)
+	return p
+}
+
+// importPassphrase supplies the context's passphrase to importer options.
+func importPassphrase(ctx context.Context) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		if p := Passphrase(ctx); len(p) > 0 {
+			return p, nil
+		}
+		return nil, fmt.Errorf("the bundle is encrypted: enter its passphrase")
+	}
+}
+
 // ImportExportAdapterImpl adapts exporter/importer package functions to the UI adapter.
 // ImportExportAdapterImpl adapts exporter/importer package functions to the UI adapter.
 type ImportExportAdapterImpl struct{ db *sql.DB }
@@ -19,8 +43,14 @@ func NewImportExportAdapter(db *sql.DB) *ImportExportAdapterImpl {
 }
 
 // Export exports either a single command set (when name is non-empty) or the
-// entire database to dest.
-func (i *ImportExportAdapterImpl) Export(_ context.Context, name string, dest string) error {
+// entire database to dest, encrypted when ctx carries a passphrase (see
+// WithPassphrase).
+func (i *ImportExportAdapterImpl) Export(ctx context.Context, name string, dest string) error {
+	if p := Passphrase(ctx); len(p) > 0 {
+		return exporter.ExportEncrypted(dest, p, func(path string) error {
+			return i.Export(context.Background(), name, path)
+		})
+	}
 	if name == "" {
 		// export entire database
 		return exporter.ExportDatabase(dest)
@@ -33,12 +63,21 @@ func (i *ImportExportAdapterImpl) Export(_ context.Context, name string, dest st
 
 // ImportSet imports a command set file from src using the given policy and
 // dedupe options.
-func (i *ImportExportAdapterImpl) ImportSet(_ context.Context, src string, policy string, dedupe bool) error {
-	opts := importer.ImportOptions{OnConflict: policy, Dedupe: dedupe}
+func (i *ImportExportAdapterImpl) ImportSet(ctx context.Context, src string, policy string, dedupe bool) error {
+	opts := importer.ImportOptions{OnConflict: policy, Dedupe: dedupe, Passphrase: importPassphrase(ctx)}
 	return importer.ImportCommandSet(src, opts)
 }
 
+// VerifyBundle describes who signed the bundle at src.
+func (i *ImportExportAdapterImpl) VerifyBundle(ctx context.Context, src string) (string, error) {
+	v, err := importer.VerifyBundle(src, importPassphrase(ctx))
+	if err != nil {
+		return "", err
+	}
+	return v.String(), nil
+}
+
 // ImportDB imports a database file into the active DB, overwriting if requested.
-func (i *ImportExportAdapterImpl) ImportDB(_ context.Context, src string, overwrite bool) error {
-	return importer.ImportDatabase(src, overwrite, importer.ImportOptions{})
+func (i *ImportExportAdapterImpl) ImportDB(ctx context.Context, src string, overwrite bool) error {
+	return importer.ImportDatabase(src, overwrite, importer.ImportOptions{Passphrase: importPassphrase(ctx)})
 }
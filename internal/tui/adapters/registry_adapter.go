@@ -195,3 +195,11 @@ func (r *RegistryAdapterImpl) Close() error {
 	}
 	return r.repo.Close()
 }
+
+// Repository returns the wrapped repository.
+func (r *RegistryAdapterImpl) Repository() *registry.Repository { return r.repo }
+
+// ListBreakpoints returns the stored breakpoint steps of the named set.
+func (r *RegistryAdapterImpl) ListBreakpoints(_ context.Context, name string) ([]int, error) {
+	return r.repo.ListBreakpoints(name)
+}
@@ -0,0 +1,65 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// StepAction is the decision taken for a paused step.
+type StepAction string
+
+// Step actions offered by step-through mode and breakpoints. StepShell is
+// handled by the front end (it opens a shell and pauses again) and never
+// reaches the executor.
+const (
+	StepRun   StepAction = "run"
+	StepSkip  StepAction = "skip"
+	StepEdit  StepAction = "edit"
+	StepShell StepAction = "shell"
+	StepAbort StepAction = "abort"
+)
+
+// ErrRunAborted is returned when a run is aborted at a paused step.
+var ErrRunAborted = errors.New("run aborted")
+
+// ParseStepAction maps user input such as "r", "skip" or "h" to an action.
+func ParseStepAction(s string) (StepAction, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "r", "run":
+		return StepRun, true
+	case "s", "skip":
+		return StepSkip, true
+	case "e", "edit":
+		return StepEdit, true
+	case "h", "shell":
+		return StepShell, true
+	case "a", "abort", "q":
+		return StepAbort, true
+	}
+	return "", false
+}
+
+// StepDecision answers an EventStepPaused event. Command replaces the step's
+// command for this run only when Action is StepEdit.
+type StepDecision struct {
+	Action  StepAction
+	Command string
+}
+
+// StepController is implemented by run handles that support pausing. The
+// front end calls Decide after receiving an EventStepPaused event.
+type StepController interface {
+	Decide(d StepDecision) error
+}
+
+// StepExecutorAdapter is implemented by executors that can pause before
+// steps. pause reports whether the given 1-based step should pause.
+type StepExecutorAdapter interface {
+	RunStepped(ctx context.Context, name string, commands []string, pause func(step int) bool) (RunHandle, error)
+}
+
+// BreakpointLister is implemented by registry adapters that store breakpoints.
+type BreakpointLister interface {
+	ListBreakpoints(ctx context.Context, name string) ([]int, error)
+}
@@ -14,12 +14,34 @@ import (
 	"github.com/VoxDroid/krnr/internal/install"
 	"github.com/VoxDroid/krnr/internal/nameutil"
 	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/runner"
+	"github.com/VoxDroid/krnr/internal/security"
 	"github.com/VoxDroid/krnr/internal/tui/adapters"
 )
 
 // ErrNotFound is returned when a requested command set cannot be found.
 var ErrNotFound = errors.New("not found")
 
+// Errors returned by RunWithParams before anything runs.
+var (
+	ErrMissingParams = errors.New("missing parameters")
+	ErrRefused       = runner.ErrRefused
+	// ErrNeedsConfirm is returned when a policy rule asks for confirmation;
+	// start the run again with a context from Confirmed to go ahead.
+	ErrNeedsConfirm = runner.ErrNeedsConfirm
+	// ErrBusy is returned when another run holds the lease of an exclusive
+	// set.
+	ErrBusy = runner.ErrBusy
+)
+
+// Gate admits runs into the run pipeline (see runner.Gate): it checks the
+// set is trusted and its commands against the policy, takes the lease of an
+// exclusive set (failing with ErrBusy while another run holds it), records
+// the run and fires the pre-run hooks.
+type Gate interface {
+	Admit(ctx context.Context, repo *registry.Repository, name string, params map[string]string, confirmed bool) (*runner.Run, error)
+}
+
 // UIModel is a framework-agnostic model for screens and actions.
 // It depends only on adapter interfaces.
 type UIModel struct {
@@ -29,6 +51,12 @@ type UIModel struct {
 	installer adapters.InstallerAdapter
 
 	cache []adapters.CommandSetSummary
+	// policy is the policy PolicyFindings reports on; nil is the default.
+	policy *security.Policy
+	// gate admits runs; without one they start unchecked.
+	gate Gate
+	// warnings are the policy warnings of the last run started.
+	warnings []string
 	// serialize Save/Update operations to avoid races when multiple
 	// concurrent saves are attempted (defensive, DB-enforced as well)
 	saveMu sync.Mutex
@@ -39,6 +67,68 @@ func New(reg adapters.RegistryAdapter, ex adapters.ExecutorAdapter, ie adapters.
 	return &UIModel{registry: reg, executor: ex, impExp: ie, installer: inst}
 }
 
+// SetPolicy sets the security policy PolicyFindings reports on.
+func (m *UIModel) SetPolicy(p *security.Policy) { m.policy = p }
+
+// SetGate sets the gate runs are admitted through. It is only used with
+// registry adapters backed by a repository (adapters.RepositoryAdapter).
+func (m *UIModel) SetGate(g Gate) { m.gate = g }
+
+type confirmedKey struct{}
+
+// Confirmed returns a context that starts runs whose commands a policy
+// rule asks confirmation for, after the user confirmed them.
+func Confirmed(ctx context.Context) context.Context {
+	return context.WithValue(ctx, confirmedKey{}, true)
+}
+
+// Warnings returns the policy warnings of the last run started.
+func (m *UIModel) Warnings() []string { return m.warnings }
+
+// admit admits a run of the named set through the gate. It returns a nil
+// run when there is no gate to go through.
+func (m *UIModel) admit(ctx context.Context, name string, params map[string]string) (*runner.Run, error) {
+	m.warnings = nil
+	ra, ok := m.registry.(adapters.RepositoryAdapter)
+	if m.gate == nil || !ok {
+		return nil, nil
+	}
+	confirmed, _ := ctx.Value(confirmedKey{}).(bool)
+	run, err := m.gate.Admit(ctx, ra.Repository(), name, params, confirmed)
+	if err != nil {
+		return nil, err
+	}
+	m.warnings = run.Warnings()
+	return run, nil
+}
+
+// started wraps the handle of an admitted run so the run is finished in the
+// pipeline once its events end; done is the func from run.Bound. A handle
+// that failed to start finishes the run right away.
+func started(ctx context.Context, run *runner.Run, done func(error) error, h adapters.RunHandle, err error) (adapters.RunHandle, error) {
+	if run == nil {
+		return h, err
+	}
+	if err != nil {
+		_ = run.Finish(context.WithoutCancel(ctx), done(err), nil)
+		return nil, err
+	}
+	return finishing(ctx, run, done, h), nil
+}
+
+// PolicyFindings describes what the policy will do with the commands of cs
+// that it does not simply allow, for feedback when a set is saved.
+func (m *UIModel) PolicyFindings(cs adapters.CommandSetSummary) []string {
+	var out []string
+	for i, c := range cs.Commands {
+		d := m.policy.Evaluate(security.Subject{Command: c, Set: cs.Name, Tags: cs.Tags, Author: cs.AuthorName, AuthorEmail: cs.AuthorEmail})
+		if d.Action != security.ActionAllow {
+			out = append(out, fmt.Sprintf("step %d %s: %s", i+1, d.Action.Outcome(), d.Reason()))
+		}
+	}
+	return out
+}
+
 // RefreshList fetches the commandset list and caches it.
 func (m *UIModel) RefreshList(ctx context.Context) error {
 	list, err := m.registry.ListCommandSets(ctx)
@@ -69,16 +159,147 @@ func (m *UIModel) GetCommandSet(ctx context.Context, name string) (adapters.Comm
 	return m.registry.GetCommandSet(ctx, name)
 }
 
-// Run starts execution and returns a handle for streaming events.
-// Run starts execution and returns a handle for streaming events.
+// Run starts execution and returns a handle for streaming events. The run
+// pauses at the set's stored breakpoints when the adapters support it and
+// is checked against the policy first (see RunStepped).
 func (m *UIModel) Run(ctx context.Context, name string, _ []string) (adapters.RunHandle, error) {
-	// fetch commands for the set
+	// params/args handling is TODO — for now, ignore args and run the commands
+	return m.RunStepped(ctx, name, false)
+}
+
+// RunStepped starts a run that pauses before every step when stepAll is set
+// and otherwise only at stored breakpoints. Paused steps are reported as
+// EventStepPaused and resumed through the handle's adapters.StepController.
+// With a gate, the run goes through the run pipeline: commands the policy
+// blocks refuse it with ErrRefused and those it asks confirmation for with
+// ErrNeedsConfirm unless ctx is Confirmed.
+func (m *UIModel) RunStepped(ctx context.Context, name string, stepAll bool) (adapters.RunHandle, error) {
 	cmds, err := m.registry.GetCommands(ctx, name)
 	if err != nil {
 		return nil, err
 	}
-	// params/args handling is TODO — for now, ignore args and run the commands
-	return m.executor.Run(ctx, name, cmds)
+	run, err := m.admit(ctx, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	var done func(error) error
+	if run != nil {
+		ctx, done = run.Bound(adapters.WithRunID(ctx, run.ID))
+	}
+	h, err := m.runStepped(ctx, name, cmds, stepAll)
+	return started(ctx, run, done, h, err)
+}
+
+func (m *UIModel) runStepped(ctx context.Context, name string, cmds []string, stepAll bool) (adapters.RunHandle, error) {
+	breakpoints := map[int]bool{}
+	if bl, ok := m.registry.(adapters.BreakpointLister); ok {
+		steps, err := bl.ListBreakpoints(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range steps {
+			breakpoints[s] = true
+		}
+	}
+	se, ok := m.executor.(adapters.StepExecutorAdapter)
+	if !ok {
+		if stepAll {
+			return nil, fmt.Errorf("step-through runs are not supported by this executor")
+		}
+		return m.executor.Run(ctx, name, cmds)
+	}
+	if !stepAll && len(breakpoints) == 0 {
+		return m.executor.Run(ctx, name, cmds)
+	}
+	return se.RunStepped(ctx, name, cmds, func(step int) bool { return stepAll || breakpoints[step] })
+}
+
+// Params returns the parameters referenced by the named set's commands, in
+// order of first appearance.
+func (m *UIModel) Params(ctx context.Context, name string) ([]string, error) {
+	cmds, err := m.registry.GetCommands(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return paramsOf(cmds), nil
+}
+
+// RunWithParams substitutes params into the named set's commands and starts
+// the run through the gate as RunStepped does. Every parameter must have a
+// value; values of secret-looking parameters are redacted from events and
+// logs when the executor supports it.
+func (m *UIModel) RunWithParams(ctx context.Context, name string, params map[string]string) (adapters.RunHandle, error) {
+	cmds, err := m.registry.GetCommands(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, p := range paramsOf(cmds) {
+		if _, ok := params[p]; !ok {
+			missing = append(missing, p)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrMissingParams, strings.Join(missing, ", "))
+	}
+	run, err := m.admit(ctx, name, params)
+	if err != nil {
+		return nil, err
+	}
+	var resolved, redacted []string
+	var done func(error) error
+	if run != nil {
+		if resolved, redacted, err = run.Commands(); err != nil {
+			_ = run.Finish(context.WithoutCancel(ctx), err, nil)
+			return nil, err
+		}
+		ctx, done = run.Bound(adapters.WithRunID(ctx, run.ID))
+	} else if resolved, redacted, err = resolveParams(cmds, params); err != nil {
+		return nil, err
+	}
+	var h adapters.RunHandle
+	if ra, ok := m.executor.(adapters.RedactingExecutorAdapter); ok {
+		h, err = ra.RunRedacted(ctx, name, resolved, redacted)
+	} else {
+		h, err = m.executor.Run(ctx, name, resolved)
+	}
+	return started(ctx, run, done, h, err)
+}
+
+// resolveParams substitutes params into cmds, returning the commands to run
+// and the commands with secret values redacted.
+func resolveParams(cmds []string, params map[string]string) ([]string, []string, error) {
+	shown := map[string]string{}
+	for k, v := range params {
+		if security.IsSecretParamName(k) {
+			v = "<redacted>"
+		}
+		shown[k] = v
+	}
+	resolved := make([]string, len(cmds))
+	redacted := make([]string, len(cmds))
+	for i, c := range cmds {
+		var err error
+		if resolved[i], err = registry.ApplyParams(c, params); err != nil {
+			return nil, nil, err
+		}
+		redacted[i], _ = registry.ApplyParams(c, shown)
+	}
+	return resolved, redacted, nil
+}
+
+func paramsOf(cmds []string) []string {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, c := range cmds {
+		for _, p := range registry.FindParams(c) {
+			if !seen[p] {
+				seen[p] = true
+				out = append(out, p)
+			}
+		}
+	}
+	return out
 }
 
 // ReplaceCommands replaces the commands for an existing command set by name.
@@ -148,6 +369,21 @@ func (m *UIModel) ImportSet(ctx context.Context, src string, policy string, dedu
 	return m.impExp.ImportSet(ctx, src, policy, dedupe)
 }
 
+// BundleSigner describes who signed the bundle at src, or returns "" when
+// the adapter does not check signatures. Imports check them themselves;
+// this only serves to show the signer.
+func (m *UIModel) BundleSigner(ctx context.Context, src string) string {
+	bv, ok := m.impExp.(adapters.BundleVerifier)
+	if !ok {
+		return ""
+	}
+	s, err := bv.VerifyBundle(ctx, src)
+	if err != nil {
+		return ""
+	}
+	return s
+}
+
 // ImportDB imports a database file into the active DB. If overwrite is true
 // it replaces the active DB file.
 func (m *UIModel) ImportDB(ctx context.Context, src string, overwrite bool) error {
@@ -241,6 +477,47 @@ func (m *UIModel) Save(ctx context.Context, cs adapters.CommandSetSummary) error
 	return m.registry.SaveCommandSet(ctx, cs)
 }
 
+// finishingHandle forwards the events of a run admitted through the gate
+// and finishes the run in the pipeline once they end.
+type finishingHandle struct {
+	adapters.RunHandle
+	id string
+	ch chan adapters.RunEvent
+}
+
+func finishing(ctx context.Context, run *runner.Run, done func(error) error, h adapters.RunHandle) *finishingHandle {
+	f := &finishingHandle{RunHandle: h, id: run.ID, ch: make(chan adapters.RunEvent)}
+	go func() {
+		defer close(f.ch)
+		var runErr error
+		for ev := range h.Events() {
+			if ev.Err != nil {
+				runErr = ev.Err
+			}
+			f.ch <- ev
+		}
+		// post-run and on-failure hooks fire even when the run was cancelled
+		if err := run.Finish(context.WithoutCancel(ctx), done(runErr), nil); err != runErr {
+			f.ch <- adapters.RunEvent{Err: err}
+		}
+	}()
+	return f
+}
+
+func (f *finishingHandle) Events() <-chan adapters.RunEvent { return f.ch }
+
+// RunID returns the ID of the run in history, hooks and its run log.
+func (f *finishingHandle) RunID() string { return f.id }
+
+// Decide forwards step decisions to the wrapped handle.
+func (f *finishingHandle) Decide(d adapters.StepDecision) error {
+	sc, ok := f.RunHandle.(adapters.StepController)
+	if !ok {
+		return fmt.Errorf("run does not support step decisions")
+	}
+	return sc.Decide(d)
+}
+
 // FakeRunHandle simulates a streaming RunHandle for tests.
 func FakeRunHandle(lines []string, delay time.Duration) adapters.RunHandle {
 	events := make(chan adapters.RunEvent)
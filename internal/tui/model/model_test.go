@@ -2,16 +2,22 @@ package model
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/VoxDroid/krnr/internal/config"
 	"github.com/VoxDroid/krnr/internal/db"
 	"github.com/VoxDroid/krnr/internal/install"
+	"github.com/VoxDroid/krnr/internal/lifecycle"
 	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/runner"
+	"github.com/VoxDroid/krnr/internal/security"
 	"github.com/VoxDroid/krnr/internal/tui/adapters"
 )
 
@@ -195,3 +201,234 @@ func (t *testInstaller) Install(_ context.Context, _ install.Options) ([]string,
 func (t *testInstaller) Uninstall(_ context.Context) ([]string, error) {
 	return []string{"uninstalled (test)"}, nil
 }
+
+// paramRegistry serves fixed commands that reference parameters.
+type paramRegistry struct {
+	testRegistry
+	cmds []string
+}
+
+func (p *paramRegistry) GetCommands(_ context.Context, _ string) ([]string, error) {
+	return p.cmds, nil
+}
+
+// redactingExecutor records what it was asked to run and show.
+type redactingExecutor struct {
+	testExecutor
+	commands, redacted []string
+}
+
+func (r *redactingExecutor) RunRedacted(_ context.Context, _ string, commands, redacted []string) (adapters.RunHandle, error) {
+	r.commands, r.redacted = commands, redacted
+	return FakeRunHandle(nil, 0), nil
+}
+
+func TestParamsAndRunWithParams(t *testing.T) {
+	reg := &paramRegistry{cmds: []string{"deploy {{env}} --token {{api_token}}", "notify {{env}}"}}
+	ex := &redactingExecutor{}
+	m := New(reg, ex, nil, nil)
+	ctx := context.Background()
+
+	params, err := m.Params(ctx, "deploy")
+	if err != nil || strings.Join(params, ",") != "env,api_token" {
+		t.Fatalf("Params = %v %v", params, err)
+	}
+	if _, err := m.RunWithParams(ctx, "deploy", map[string]string{"env": "prod"}); !errors.Is(err, ErrMissingParams) {
+		t.Fatalf("expected ErrMissingParams, got %v", err)
+	}
+	if _, err := m.RunWithParams(ctx, "deploy", map[string]string{"env": "prod", "api_token": "s3cret"}); err != nil {
+		t.Fatalf("RunWithParams: %v", err)
+	}
+	if ex.commands[0] != "deploy prod --token s3cret" || ex.redacted[0] != "deploy prod --token <redacted>" || ex.redacted[1] != "notify prod" {
+		t.Fatalf("unexpected commands %q / %q", ex.commands, ex.redacted)
+	}
+}
+
+// gateModel returns a model over a temporary database whose runs go
+// through a gate with policy p and hooks h.
+func gateModel(t *testing.T, p *security.Policy, h *lifecycle.Hooks) (*UIModel, *registry.Repository, *redactingExecutor) {
+	t.Helper()
+	t.Setenv(config.EnvKRNRDB, filepath.Join(t.TempDir(), "krnr.db"))
+	conn, err := db.InitDB()
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	repo := registry.NewRepository(conn)
+	ex := &redactingExecutor{}
+	m := New(adapters.NewRegistryAdapter(repo), ex, nil, nil)
+	m.SetGate(&runner.Gate{Options: runner.Options{Trigger: "tui", Policy: p, Hooks: h}})
+	return m, repo, ex
+}
+
+func createSet(t *testing.T, repo *registry.Repository, name string, tags []string, cmds ...string) {
+	t.Helper()
+	id, err := repo.CreateCommandSet(name, nil, nil, nil, cmds)
+	if err != nil {
+		t.Fatalf("CreateCommandSet: %v", err)
+	}
+	for _, tag := range tags {
+		if err := repo.AddTagToCommandSet(id, tag); err != nil {
+			t.Fatalf("AddTagToCommandSet: %v", err)
+		}
+	}
+}
+
+func drain(h adapters.RunHandle) error {
+	var err error
+	for ev := range h.Events() {
+		if ev.Err != nil {
+			err = ev.Err
+		}
+	}
+	return err
+}
+
+func TestRunPolicy(t *testing.T) {
+	p, err := security.ParsePolicy("policy.toml", []byte("[[rule]]\nid = \"ops-reset\"\naction = \"allow\"\nmatch = 'reset --hard'\ntags = [\"ops\"]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, repo, ex := gateModel(t, p, nil)
+	createSet(t, repo, "push", []string{"ops"}, "git push --force")
+	createSet(t, repo, "reset", []string{"ops"}, "git reset --hard", "git clean -fd")
+	createSet(t, repo, "mixed", nil, "echo ok", "rm -rf ~")
+	createSet(t, repo, "wipe", nil, "rm -rf {{dir}}")
+	createSet(t, repo, "deploy", nil, "deploy {{env}} --token {{api_token}}")
+	ctx := context.Background()
+
+	if _, err := m.RunStepped(ctx, "push", false); !errors.Is(err, ErrNeedsConfirm) || !strings.Contains(err.Error(), "git.force-push") {
+		t.Fatalf("expected ErrNeedsConfirm, got %v", err)
+	}
+	h, err := m.RunStepped(Confirmed(ctx), "push", false)
+	if err != nil {
+		t.Fatalf("expected the confirmed run to start, got %v", err)
+	}
+	_ = drain(h)
+
+	h, err = m.RunStepped(ctx, "reset", false)
+	if err != nil {
+		t.Fatalf("RunStepped: %v", err)
+	}
+	_ = drain(h)
+	if w := m.Warnings(); len(w) != 1 || !strings.Contains(w[0], "git clean -fd") {
+		t.Fatalf("expected only the unscoped warning, got %q", w)
+	}
+
+	if _, err := m.RunStepped(Confirmed(ctx), "mixed", false); !errors.Is(err, ErrRefused) {
+		t.Fatalf("expected block rules to refuse even confirmed runs, got %v", err)
+	}
+	if _, err := m.RunWithParams(ctx, "wipe", map[string]string{"dir": "/"}); !errors.Is(err, ErrRefused) {
+		t.Fatalf("expected ErrRefused, got %v", err)
+	}
+
+	h, err = m.RunWithParams(ctx, "deploy", map[string]string{"env": "prod", "api_token": "s3cret"})
+	if err != nil {
+		t.Fatalf("RunWithParams: %v", err)
+	}
+	_ = drain(h)
+	if ex.commands[0] != "deploy prod --token s3cret" || ex.redacted[0] != "deploy prod --token <redacted>" {
+		t.Fatalf("unexpected commands %q / %q", ex.commands, ex.redacted)
+	}
+}
+
+func TestRunFiresHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses POSIX shell commands")
+	}
+	marker := filepath.Join(t.TempDir(), "post-run")
+	h, err := lifecycle.Parse([]byte("[[hook]]\nevent = \"post-run\"\nrun = \"cat > " + marker + "\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, repo, _ := gateModel(t, nil, h)
+	createSet(t, repo, "build", nil, "make")
+
+	rh, err := m.RunStepped(context.Background(), "build", false)
+	if err != nil {
+		t.Fatalf("RunStepped: %v", err)
+	}
+	if err := drain(rh); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	id := rh.(interface{ RunID() string }).RunID()
+	if body, err := os.ReadFile(marker); err != nil || !strings.Contains(string(body), id) {
+		t.Fatalf("expected the post-run hook to get run %s, got %q %v", id, body, err)
+	}
+}
+
+func TestRunTakesExclusiveLease(t *testing.T) {
+	m, repo, _ := gateModel(t, nil, nil)
+	m.executor = &testExecutor{}
+	createSet(t, repo, "deploy", nil, "echo deploy")
+	if err := repo.SetExclusive("deploy", true); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	other := registry.Lease{SetName: "deploy", Token: "other", Owner: "alice", PID: 4242, Host: "build-01"}
+	if ok, _, err := repo.AcquireLease(other, time.Minute); err != nil || !ok {
+		t.Fatalf("AcquireLease: %v %v", ok, err)
+	}
+	if _, err := m.RunStepped(ctx, "deploy", false); !errors.Is(err, ErrBusy) || !strings.Contains(err.Error(), "alice") {
+		t.Fatalf("expected ErrBusy naming the holder, got %v", err)
+	}
+	_ = repo.ReleaseLease("deploy", "other")
+
+	h, err := m.RunStepped(ctx, "deploy", false)
+	if err != nil {
+		t.Fatalf("RunStepped: %v", err)
+	}
+	// the run holds the lease until its events end
+	if l, _ := repo.GetLease("deploy"); l == nil || l.Token != h.(interface{ RunID() string }).RunID() {
+		t.Fatalf("expected the run to hold the lease, got %+v", l)
+	}
+	_ = drain(h)
+	if l, _ := repo.GetLease("deploy"); l != nil {
+		t.Fatalf("expected the lease to be released, got %+v", l)
+	}
+}
+
+// failingExecutor starts runs that fail with err.
+type failingExecutor struct {
+	testExecutor
+	err error
+}
+
+func (f *failingExecutor) Run(_ context.Context, _ string, _ []string) (adapters.RunHandle, error) {
+	ch := make(chan adapters.RunEvent, 1)
+	ch <- adapters.RunEvent{Err: f.err}
+	close(ch)
+	return &fakeRunHandle{ch: ch}, nil
+}
+
+func TestRunIsRecorded(t *testing.T) {
+	m, repo, _ := gateModel(t, nil, nil)
+	m.executor = &testExecutor{}
+	createSet(t, repo, "build", nil, "make")
+	ctx := context.Background()
+
+	h, err := m.RunStepped(ctx, "build", false)
+	if err != nil {
+		t.Fatalf("RunStepped: %v", err)
+	}
+	_ = drain(h)
+	m.executor = &failingExecutor{err: errors.New("exec: boom")}
+	h2, err := m.RunStepped(ctx, "build", false)
+	if err != nil {
+		t.Fatalf("RunStepped: %v", err)
+	}
+	_ = drain(h2)
+
+	runs, err := repo.ListRuns("build", 0)
+	if err != nil || len(runs) != 2 {
+		t.Fatalf("expected 2 recorded runs, got %+v %v", runs, err)
+	}
+	ok, failed := runs[1], runs[0]
+	if ok.RunID != h.(interface{ RunID() string }).RunID() || ok.Trigger != "tui" || ok.Status != registry.RunStatusOK {
+		t.Fatalf("unexpected record of the successful run: %+v", ok)
+	}
+	if failed.Status == registry.RunStatusOK || failed.ExitCode.Int64 != -1 {
+		t.Fatalf("unexpected record of the failed run: %+v", failed)
+	}
+}
@@ -3,6 +3,7 @@ package user
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 
@@ -59,6 +60,19 @@ func GetProfile() (Profile, bool, error) {
 	return p, true, nil
 }
 
+// Label renders the stored profile as "name <email>", or "" when none is
+// set.
+func Label() string {
+	p, ok, err := GetProfile()
+	if err != nil || !ok || p.Name == "" {
+		return ""
+	}
+	if p.Email != "" {
+		return fmt.Sprintf("%s <%s>", p.Name, p.Email)
+	}
+	return p.Name
+}
+
 // ClearProfile removes the persisted profile.
 func ClearProfile() error {
 	pfile, err := profilePath()
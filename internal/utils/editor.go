@@ -5,13 +5,23 @@ import (
 	"os"
 	"os/exec"
 	"runtime"
+
+	"github.com/kballard/go-shellquote"
 )
 
 // OpenEditor opens the given file in the user's preferred editor.
 // It respects the $EDITOR environment variable. On Windows if $EDITOR is not set,
 // it falls back to notepad; on Unix it falls back to vi.
 func OpenEditor(path string) error {
-	editor := os.Getenv("EDITOR")
+	return OpenEditorWith("", path)
+}
+
+// OpenEditorWith opens path in editor, a command line such as
+// "code --wait". An empty editor behaves like OpenEditor.
+func OpenEditorWith(editor, path string) error {
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
 	if editor == "" {
 		if runtime.GOOS == "windows" {
 			editor = "notepad"
@@ -19,7 +29,15 @@ func OpenEditor(path string) error {
 			editor = "vi"
 		}
 	}
-	cmd := exec.Command(editor, path)
+	// a program path may contain spaces (C:\Program Files\...); anything
+	// else is a command line such as "code --wait"
+	argv := []string{editor}
+	if _, err := exec.LookPath(editor); err != nil {
+		if words, err := shellquote.Split(editor); err == nil && len(words) > 0 {
+			argv = words
+		}
+	}
+	cmd := exec.Command(argv[0], append(argv[1:], path)...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
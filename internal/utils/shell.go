@@ -0,0 +1,33 @@
+package interactive
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// ShellCommand returns an interactive shell command for the user. It respects
+// $SHELL and falls back to %COMSPEC% (or cmd) on Windows and /bin/sh elsewhere.
+// Stdio is not attached so callers can decide how to run it.
+func ShellCommand() *exec.Cmd {
+	if sh := os.Getenv("SHELL"); sh != "" {
+		return exec.Command(sh)
+	}
+	if runtime.GOOS == "windows" {
+		if cs := os.Getenv("COMSPEC"); cs != "" {
+			return exec.Command(cs)
+		}
+		return exec.Command("cmd")
+	}
+	return exec.Command("/bin/sh")
+}
+
+// OpenShell runs an interactive shell attached to the current terminal and
+// returns when the user exits it.
+func OpenShell() error {
+	cmd := ShellCommand()
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,214 @@
+// Package watch detects file changes for `krnr watch` by polling the watched
+// trees, which behaves the same on every platform and needs no OS-specific
+// notification API.
+package watch
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Spec describes which files to watch.
+type Spec struct {
+	// Paths are files or directories to watch (recursively); empty means ".".
+	Paths []string
+	// Include keeps only files matching one of these globs; empty keeps all.
+	Include []string
+	// Exclude drops files and directories matching one of these globs.
+	Exclude []string
+	// Debounce is how long changes must settle before they are reported.
+	Debounce time.Duration
+}
+
+// DefaultDebounce is used when a Spec has no debounce.
+const DefaultDebounce = 300 * time.Millisecond
+
+// pollInterval controls how often the watched trees are scanned.
+var pollInterval = 200 * time.Millisecond
+
+type fileState struct {
+	size    int64
+	modTime time.Time
+	mode    fs.FileMode
+}
+
+// Watcher reports changes to the files selected by a Spec.
+type Watcher struct {
+	spec  Spec
+	files map[string]fileState
+}
+
+// New returns a watcher whose baseline is the current state of the files.
+func New(spec Spec) (*Watcher, error) {
+	if len(spec.Paths) == 0 {
+		spec.Paths = []string{"."}
+	}
+	if spec.Debounce <= 0 {
+		spec.Debounce = DefaultDebounce
+	}
+	for _, p := range append(append([]string{}, spec.Include...), spec.Exclude...) {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return nil, err
+		}
+	}
+	for _, p := range spec.Paths {
+		if _, err := os.Stat(p); err != nil {
+			return nil, err
+		}
+	}
+	w := &Watcher{spec: spec}
+	w.files = w.scan()
+	return w, nil
+}
+
+// Spec returns the effective spec (with defaults applied).
+func (w *Watcher) Spec() Spec { return w.spec }
+
+// Len returns the number of files currently watched.
+func (w *Watcher) Len() int { return len(w.files) }
+
+// Poll rescans the watched files and returns the paths that were created,
+// modified or removed since the previous scan, sorted.
+func (w *Watcher) Poll() []string {
+	cur := w.scan()
+	var changed []string
+	for p, st := range cur {
+		if old, ok := w.files[p]; !ok || old != st {
+			changed = append(changed, p)
+		}
+	}
+	for p := range w.files {
+		if _, ok := cur[p]; !ok {
+			changed = append(changed, p)
+		}
+	}
+	w.files = cur
+	sort.Strings(changed)
+	return changed
+}
+
+// Wait blocks until files change and then keeps collecting changes until
+// none happened for the debounce period. It returns the changed paths, or
+// ctx's error when cancelled first.
+func (w *Watcher) Wait(ctx context.Context) ([]string, error) {
+	seen := map[string]bool{}
+	var changed []string
+	add := func(ps []string) bool {
+		for _, p := range ps {
+			if !seen[p] {
+				seen[p] = true
+				changed = append(changed, p)
+			}
+		}
+		return len(ps) > 0
+	}
+	for !add(w.Poll()) {
+		if err := sleep(ctx, pollInterval); err != nil {
+			return nil, err
+		}
+	}
+	for {
+		if err := sleep(ctx, w.spec.Debounce); err != nil {
+			return nil, err
+		}
+		if !add(w.Poll()) {
+			sort.Strings(changed)
+			return changed, nil
+		}
+	}
+}
+
+// Changes runs Wait in a loop and delivers each batch of changes until ctx
+// is cancelled, when the channel is closed.
+func (w *Watcher) Changes(ctx context.Context) <-chan []string {
+	ch := make(chan []string)
+	go func() {
+		defer close(ch)
+		for {
+			changed, err := w.Wait(ctx)
+			if err != nil {
+				return
+			}
+			select {
+			case ch <- changed:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// scan walks every watched path. Unreadable entries are skipped; hidden
+// directories (.git, .cache, ...) below a watched path are not descended.
+func (w *Watcher) scan() map[string]fileState {
+	out := map[string]fileState{}
+	for _, root := range w.spec.Paths {
+		_ = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			rel := relPath(root, p)
+			if d.IsDir() {
+				if p != root && (strings.HasPrefix(d.Name(), ".") || matchAny(w.spec.Exclude, d.Name(), rel)) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if matchAny(w.spec.Exclude, d.Name(), rel) {
+				return nil
+			}
+			if len(w.spec.Include) > 0 && !matchAny(w.spec.Include, d.Name(), rel) {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			out[p] = fileState{size: info.Size(), modTime: info.ModTime(), mode: info.Mode()}
+			return nil
+		})
+	}
+	return out
+}
+
+// relPath returns p relative to root with forward slashes, so patterns such
+// as "internal/*.go" work the same on every platform.
+func relPath(root, p string) string {
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		rel = p
+	}
+	return filepath.ToSlash(rel)
+}
+
+// matchAny reports whether a glob matches the base name or the path relative
+// to the watched root.
+func matchAny(patterns []string, base, rel string) bool {
+	for _, pat := range patterns {
+		if ok, _ := filepath.Match(pat, base); ok {
+			return true
+		}
+		if ok, _ := path.Match(filepath.ToSlash(pat), rel); ok {
+			return true
+		}
+	}
+	return false
+}
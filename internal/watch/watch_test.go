@@ -0,0 +1,103 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, content string, mod time.Time) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mod, mod); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPollReportsMatchingChanges(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Now().Add(-time.Hour)
+	writeFile(t, filepath.Join(dir, "main.go"), "package main", base)
+	writeFile(t, filepath.Join(dir, "README.md"), "hi", base)
+	writeFile(t, filepath.Join(dir, "vendor", "dep.go"), "package dep", base)
+	writeFile(t, filepath.Join(dir, ".git", "HEAD"), "ref", base)
+
+	w, err := New(Spec{Paths: []string{dir}, Include: []string{"*.go"}, Exclude: []string{"vendor"}})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if w.Len() != 1 {
+		t.Fatalf("expected 1 watched file, got %d", w.Len())
+	}
+	if got := w.Poll(); len(got) != 0 {
+		t.Fatalf("unexpected changes: %v", got)
+	}
+
+	later := base.Add(time.Minute)
+	writeFile(t, filepath.Join(dir, "main.go"), "package main // edited", later)
+	writeFile(t, filepath.Join(dir, "pkg", "util.go"), "package pkg", later)
+	writeFile(t, filepath.Join(dir, "README.md"), "edited", later)
+	writeFile(t, filepath.Join(dir, "vendor", "dep.go"), "edited", later)
+	writeFile(t, filepath.Join(dir, ".git", "HEAD"), "edited", later)
+	want := []string{filepath.Join(dir, "main.go"), filepath.Join(dir, "pkg", "util.go")}
+	if got := w.Poll(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Poll = %v, want %v", got, want)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "main.go")); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.Poll(); !reflect.DeepEqual(got, want[:1]) {
+		t.Fatalf("Poll after remove = %v", got)
+	}
+}
+
+func TestWaitDebouncesBursts(t *testing.T) {
+	old := pollInterval
+	pollInterval = 5 * time.Millisecond
+	defer func() { pollInterval = old }()
+
+	dir := t.TempDir()
+	base := time.Now().Add(-time.Hour)
+	w, err := New(Spec{Paths: []string{dir}, Debounce: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	go func() {
+		for i, name := range []string{"a.txt", "b.txt", "c.txt"} {
+			writeFile(t, filepath.Join(dir, name), "x", base.Add(time.Duration(i)*time.Second))
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	got, err := w.Wait(ctx)
+	if err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("expected one batch of 3 changes, got %v", got)
+	}
+
+	cancel()
+	if _, err := w.Wait(ctx); err == nil {
+		t.Fatalf("expected Wait to fail on a cancelled context")
+	}
+}
+
+func TestNewRejectsMissingPathAndBadGlob(t *testing.T) {
+	if _, err := New(Spec{Paths: []string{filepath.Join(t.TempDir(), "missing")}}); err == nil {
+		t.Fatalf("expected error for missing path")
+	}
+	if _, err := New(Spec{Paths: []string{t.TempDir()}, Include: []string{"[a-"}}); err == nil {
+		t.Fatalf("expected error for malformed glob")
+	}
+}
@@ -0,0 +1,27 @@
+// Package krnr embeds the krnr command-set registry in Go programs.
+//
+// Open a registry, query and change its command sets, resolve parameters
+// and run sets while receiving the same events `krnr run --output jsonl`
+// prints:
+//
+//	reg, err := krnr.Open(krnr.Options{Path: "/srv/ops/krnr.db"})
+//	if err != nil {
+//		return err
+//	}
+//	defer reg.Close()
+//	res, err := reg.Run(ctx, "deploy", krnr.RunOptions{
+//		Params:  map[string]string{"branch": "main"},
+//		OnEvent: func(ev krnr.Event) { log.Println(ev.Type, ev.Data) },
+//	})
+//
+// A registry opened by this package is the same SQLite database the krnr
+// CLI uses, so both can work on it at the same time.
+//
+// # Compatibility
+//
+// This package follows semantic versioning together with the krnr module:
+// within a major version, exported identifiers are not removed or changed
+// incompatibly. Option and result structs may gain fields, so build them
+// with field names (krnr.RunOptions{Params: p}), and the Event type set may
+// grow, so ignore event types you do not know.
+package krnr
@@ -0,0 +1,65 @@
+package krnr_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/VoxDroid/krnr/pkg/krnr"
+)
+
+func ExampleOpen() {
+	dir, _ := os.MkdirTemp("", "krnr-example")
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	reg, err := krnr.Open(krnr.Options{Path: filepath.Join(dir, "krnr.db")})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() { _ = reg.Close() }()
+
+	if _, err := reg.Create(krnr.Set{Name: "greet", Description: "Say hello", Commands: []string{"echo hello {{who}}"}}); err != nil {
+		log.Fatal(err)
+	}
+	sets, _ := reg.List(krnr.ListOptions{})
+	for _, s := range sets {
+		params, _ := reg.Params(s.Name)
+		fmt.Println(s.Name, "-", s.Description, params)
+	}
+	// Output: greet - Say hello [who]
+}
+
+func ExampleResolveParams() {
+	cmds, err := krnr.ResolveParams([]string{"git checkout {{branch}}"}, map[string]string{"branch": "main"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(cmds[0])
+	// Output: git checkout main
+}
+
+func ExampleRegistry_Run() {
+	reg, err := krnr.Open(krnr.Options{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() { _ = reg.Close() }()
+
+	res, err := reg.Run(context.Background(), "deploy", krnr.RunOptions{
+		Params: map[string]string{"branch": "main"},
+		OnEvent: func(ev krnr.Event) {
+			switch ev.Type {
+			case krnr.EventStepStarted:
+				log.Printf("step %d: %s", ev.Step, ev.Command)
+			case krnr.EventOutput:
+				log.Print(ev.Data)
+			}
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("run", res.RunID, "exited with", res.ExitCode)
+}
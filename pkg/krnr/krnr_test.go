@@ -0,0 +1,241 @@
+package krnr_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/registry"
+	"github.com/VoxDroid/krnr/internal/runlog"
+	"github.com/VoxDroid/krnr/pkg/krnr"
+)
+
+func openTemp(t *testing.T) *krnr.Registry {
+	t.Helper()
+	t.Setenv("KRNR_HOME", t.TempDir())
+	reg, err := krnr.Open(krnr.Options{Path: filepath.Join(t.TempDir(), "krnr.db")})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { _ = reg.Close() })
+	return reg
+}
+
+func TestSetLifecycle(t *testing.T) {
+	reg := openTemp(t)
+	s, err := reg.Create(krnr.Set{Name: "deploy", Description: "ship it", Tags: []string{"ops"}, Commands: []string{"echo one"}, Exclusive: true})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if s.Description != "ship it" || !s.Exclusive || len(s.Tags) != 1 || s.Commands[0] != "echo one" {
+		t.Fatalf("unexpected set %+v", s)
+	}
+	if _, err := reg.Create(krnr.Set{Name: "deploy", Commands: []string{"echo"}}); !errors.Is(err, krnr.ErrExists) {
+		t.Fatalf("expected ErrExists, got %v", err)
+	}
+	if _, err := reg.Get("missing"); !errors.Is(err, krnr.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	cmds := []string{"echo two"}
+	name := "release"
+	if s, err = reg.Update("deploy", krnr.UpdateOptions{Name: &name, Commands: &cmds}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if s.Name != "release" || s.Description != "ship it" || s.Commands[0] != "echo two" {
+		t.Fatalf("unexpected updated set %+v", s)
+	}
+	if err := reg.AddTag("release", "prod"); err != nil {
+		t.Fatalf("AddTag: %v", err)
+	}
+	if sets, err := reg.List(krnr.ListOptions{Tag: "prod"}); err != nil || len(sets) != 1 || sets[0].Name != "release" {
+		t.Fatalf("List by tag = %+v %v", sets, err)
+	}
+	if sets, err := reg.List(krnr.ListOptions{Query: "two"}); err != nil || len(sets) != 1 {
+		t.Fatalf("List by query = %+v %v", sets, err)
+	}
+	if err := reg.RemoveTag("release", "prod"); err != nil {
+		t.Fatalf("RemoveTag: %v", err)
+	}
+	if tags, _ := reg.Tags("release"); len(tags) != 1 || tags[0] != "ops" {
+		t.Fatalf("unexpected tags %v", tags)
+	}
+
+	vs, err := reg.Versions("release")
+	if err != nil || len(vs) < 2 {
+		t.Fatalf("Versions = %+v %v", vs, err)
+	}
+	if s, err = reg.Rollback("release", vs[len(vs)-1].Version); err != nil || s.Commands[0] != "echo one" {
+		t.Fatalf("Rollback = %+v %v", s, err)
+	}
+
+	if err := reg.Export("release", filepath.Join(t.TempDir(), "release.db")); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if err := reg.Delete("release"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := reg.Delete("release"); !errors.Is(err, krnr.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound deleting twice, got %v", err)
+	}
+}
+
+func TestResolveParams(t *testing.T) {
+	cmds := []string{"git checkout {{branch}}", "deploy --to {{ env }} --from {{branch}}"}
+	if got := krnr.Params(cmds); strings.Join(got, ",") != "branch,env" {
+		t.Fatalf("Params = %v", got)
+	}
+	_, err := krnr.ResolveParams(cmds, map[string]string{"branch": "main"})
+	var missing *krnr.MissingParamsError
+	if !errors.As(err, &missing) || len(missing.Names) != 1 || missing.Names[0] != "env" {
+		t.Fatalf("expected env to be missing, got %v", err)
+	}
+	got, err := krnr.ResolveParams(cmds, map[string]string{"branch": "main", "env": "prod"})
+	if err != nil || got[1] != "deploy --to prod --from main" {
+		t.Fatalf("ResolveParams = %v %v", got, err)
+	}
+}
+
+func TestRunEmitsEvents(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses POSIX shell commands")
+	}
+	reg := openTemp(t)
+	if _, err := reg.Create(krnr.Set{Name: "greet", Commands: []string{"echo hello {{who}}", "echo {{api_token}} >/dev/null"}}); err != nil {
+		t.Fatal(err)
+	}
+	var events []krnr.Event
+	var out strings.Builder
+	res, err := reg.Run(context.Background(), "greet", krnr.RunOptions{
+		Params:  map[string]string{"who": "sdk", "api_token": "s3cret"},
+		OnEvent: func(ev krnr.Event) { events = append(events, ev) },
+		Stdout:  &out,
+	})
+	if err != nil || res.ExitCode != 0 || res.RunID == "" {
+		t.Fatalf("Run = %+v %v", res, err)
+	}
+	if out.String() != "hello sdk\n" {
+		t.Fatalf("unexpected stdout %q", out.String())
+	}
+	if events[0].Type != krnr.EventRunStarted || events[0].Steps != 2 || events[len(events)-1].Type != krnr.EventRunFinished {
+		t.Fatalf("unexpected events %+v", events)
+	}
+	for i, ev := range events {
+		if ev.Seq != int64(i+1) || ev.RunID != res.RunID {
+			t.Fatalf("unexpected event stamp %+v", ev)
+		}
+		if strings.Contains(ev.Command, "s3cret") {
+			t.Fatalf("secret leaked in %+v", ev)
+		}
+	}
+
+	if _, err := reg.Run(context.Background(), "greet", krnr.RunOptions{}); err == nil {
+		t.Fatalf("expected missing params to fail")
+	}
+	if _, err := reg.Create(krnr.Set{Name: "wipe", Commands: []string{"rm -rf /"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reg.Run(context.Background(), "wipe", krnr.RunOptions{}); !errors.Is(err, krnr.ErrRefused) {
+		t.Fatalf("expected ErrRefused, got %v", err)
+	}
+	if _, err := reg.Create(krnr.Set{Name: "fail", Commands: []string{"exit 3", "echo unreachable"}}); err != nil {
+		t.Fatal(err)
+	}
+	res, err = reg.Run(context.Background(), "fail", krnr.RunOptions{})
+	if err == nil || res.ExitCode != 3 {
+		t.Fatalf("expected exit code 3, got %+v %v", res, err)
+	}
+}
+
+func TestRunGoesThroughPipeline(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses POSIX shell commands")
+	}
+	home := t.TempDir()
+	t.Setenv("KRNR_HOME", home)
+	path := filepath.Join(home, "krnr.db")
+	reg, err := krnr.Open(krnr.Options{Path: path})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = reg.Close() }()
+	marker := filepath.Join(t.TempDir(), "post-run")
+	hooks := "[[hook]]\nevent = \"post-run\"\nrun = \"cat > " + marker + "\"\n"
+	if err := os.WriteFile(filepath.Join(home, "hooks.toml"), []byte(hooks), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reg.Create(krnr.Set{Name: "deploy", Commands: []string{"echo shipped"}, Exclusive: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a lease left behind by a dead process on this host is reclaimed
+	conn, err := db.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	host, _ := os.Hostname()
+	dead := registry.Lease{SetName: "deploy", Token: "dead", Owner: "bob", PID: 999999, Host: host}
+	if ok, _, err := registry.NewRepository(conn).AcquireLease(dead, time.Minute); err != nil || !ok {
+		t.Fatalf("AcquireLease: %v %v", ok, err)
+	}
+
+	res, err := reg.Run(context.Background(), "deploy", krnr.RunOptions{})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if r, err := runlog.FindRun("deploy", res.RunID); err != nil || r.ID != res.RunID {
+		t.Fatalf("expected a run log for %s, got %+v %v", res.RunID, r, err)
+	}
+	if body, err := os.ReadFile(marker); err != nil || !strings.Contains(string(body), res.RunID) {
+		t.Fatalf("expected the post-run hook to get the run, got %q %v", body, err)
+	}
+	if _, err := reg.Run(context.Background(), "deploy", krnr.RunOptions{NoHooks: true}); err != nil {
+		t.Fatalf("expected the lease to be released after the run, got %v", err)
+	}
+}
+
+func TestRunUsesConfiguredShellAndTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses POSIX shell commands")
+	}
+	reg := openTemp(t)
+	shell := filepath.Join(t.TempDir(), "fakesh")
+	if err := os.WriteFile(shell, []byte("#!/bin/sh\necho \"fakesh: $2\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KRNR_SHELL", shell)
+	if _, err := reg.Create(krnr.Set{Name: "greet", Commands: []string{"echo hi"}}); err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	if _, err := reg.Run(context.Background(), "greet", krnr.RunOptions{Stdout: &out}); err != nil || out.String() != "fakesh: echo hi\n" {
+		t.Fatalf("expected the configured shell, got %q %v", out.String(), err)
+	}
+	out.Reset()
+	if _, err := reg.Run(context.Background(), "greet", krnr.RunOptions{Stdout: &out, Shell: "sh"}); err != nil || out.String() != "hi\n" {
+		t.Fatalf("expected Shell to override the setting, got %q %v", out.String(), err)
+	}
+
+	t.Setenv("KRNR_SHELL", "")
+	t.Setenv("KRNR_TIMEOUT", "100ms")
+	if _, err := reg.Create(krnr.Set{Name: "slow", Commands: []string{"sleep 5"}}); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if _, err := reg.Run(context.Background(), "slow", krnr.RunOptions{}); err == nil || !strings.Contains(err.Error(), "timed out after 100ms") {
+		t.Fatalf("expected the configured timeout, got %v", err)
+	}
+	if _, err := reg.Run(context.Background(), "slow", krnr.RunOptions{Timeout: 200 * time.Millisecond}); err == nil || !strings.Contains(err.Error(), "timed out after 200ms") {
+		t.Fatalf("expected Timeout to override the setting, got %v", err)
+	}
+	if time.Since(start) > 4*time.Second {
+		t.Fatal("the runs were not stopped at their timeout")
+	}
+}
@@ -0,0 +1,63 @@
+package krnr
+
+import (
+	"strings"
+
+	"github.com/VoxDroid/krnr/internal/registry"
+)
+
+// MissingParamsError reports parameters that have no value.
+type MissingParamsError struct {
+	Names []string
+}
+
+func (e *MissingParamsError) Error() string {
+	return "missing value for parameter(s) " + strings.Join(e.Names, ", ")
+}
+
+// Params returns the {{name}} parameters the commands reference, in order
+// of first appearance.
+func Params(commands []string) []string {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, c := range commands {
+		for _, p := range registry.FindParams(c) {
+			if !seen[p] {
+				seen[p] = true
+				out = append(out, p)
+			}
+		}
+	}
+	return out
+}
+
+// Params returns the parameters the named set references.
+func (r *Registry) Params(name string) ([]string, error) {
+	s, err := r.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return Params(s.Commands), nil
+}
+
+// ResolveParams substitutes values into the commands. Every parameter must
+// have a value; otherwise a *MissingParamsError names the missing ones.
+func ResolveParams(commands []string, values map[string]string) ([]string, error) {
+	var missing []string
+	for _, p := range Params(commands) {
+		if _, ok := values[p]; !ok {
+			missing = append(missing, p)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, &MissingParamsError{Names: missing}
+	}
+	out := make([]string, len(commands))
+	for i, c := range commands {
+		var err error
+		if out[i], err = registry.ApplyParams(c, values); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
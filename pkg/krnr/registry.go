@@ -0,0 +1,311 @@
+package krnr
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/VoxDroid/krnr/internal/config"
+	"github.com/VoxDroid/krnr/internal/db"
+	"github.com/VoxDroid/krnr/internal/exporter"
+	"github.com/VoxDroid/krnr/internal/registry"
+)
+
+// ErrNotFound is returned for a command set that does not exist.
+var ErrNotFound = errors.New("command set not found")
+
+// ErrExists is returned when creating or renaming to a name already in use.
+var ErrExists = errors.New("command set already exists")
+
+// Options configure Open.
+type Options struct {
+	// Path of the registry database. Empty means the CLI's registry:
+	// $KRNR_DB, otherwise krnr.db in $KRNR_HOME or ~/.krnr.
+	Path string
+}
+
+// Registry is an open krnr registry. It is safe for concurrent use.
+type Registry struct {
+	db   *sql.DB
+	repo *registry.Repository
+}
+
+// Open opens the registry at opts.Path, creating the database and its
+// schema when they do not exist yet.
+func Open(opts Options) (*Registry, error) {
+	path := opts.Path
+	if path == "" {
+		var err error
+		if path, err = config.DBPath(); err != nil {
+			return nil, err
+		}
+	}
+	conn, err := db.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Registry{db: conn, repo: registry.NewRepository(conn)}, nil
+}
+
+// Close closes the registry.
+func (r *Registry) Close() error { return r.db.Close() }
+
+// Set is a command set.
+type Set struct {
+	Name        string
+	Description string
+	AuthorName  string
+	AuthorEmail string
+	Tags        []string
+	// Commands are run in order. List leaves them empty; use Get.
+	Commands []string
+	// Exclusive sets only run once at a time.
+	Exclusive bool
+	CreatedAt string
+	LastRun   string
+}
+
+func setOf(cs *registry.CommandSet) Set {
+	s := Set{
+		Name: cs.Name, Description: cs.Description.String, AuthorName: cs.AuthorName.String,
+		AuthorEmail: cs.AuthorEmail.String, Tags: cs.Tags, Exclusive: cs.Exclusive,
+		CreatedAt: cs.CreatedAt, LastRun: cs.LastRun.String,
+	}
+	for _, c := range cs.Commands {
+		s.Commands = append(s.Commands, c.Command)
+	}
+	return s
+}
+
+// ListOptions filter List. At most one of Tag and Query is used, Tag first.
+type ListOptions struct {
+	// Tag lists only sets with this tag.
+	Tag string
+	// Query lists sets whose name, description or commands contain it.
+	Query string
+	// Fuzzy matches Query as in `krnr list --fuzzy`, including tags.
+	Fuzzy bool
+}
+
+// List returns command sets, newest first.
+func (r *Registry) List(opts ListOptions) ([]Set, error) {
+	var sets []registry.CommandSet
+	var err error
+	switch {
+	case opts.Tag != "":
+		sets, err = r.repo.ListCommandSetsByTag(opts.Tag)
+	case opts.Query != "" && opts.Fuzzy:
+		sets, err = r.repo.FuzzySearchCommandSets(opts.Query)
+	case opts.Query != "":
+		sets, err = r.repo.SearchCommandSets(opts.Query)
+	default:
+		sets, err = r.repo.ListCommandSets()
+	}
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Set, 0, len(sets))
+	for i := range sets {
+		s := setOf(&sets[i])
+		s.Commands = nil
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+func (r *Registry) lookup(name string) (*registry.CommandSet, error) {
+	cs, err := r.repo.GetCommandSetByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if cs == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return cs, nil
+}
+
+// Get returns the named set with its commands.
+func (r *Registry) Get(name string) (*Set, error) {
+	cs, err := r.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	s := setOf(cs)
+	return &s, nil
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// Create saves a new set from s; CreatedAt and LastRun are ignored. The
+// initial version is recorded as by `krnr save`.
+func (r *Registry) Create(s Set) (*Set, error) {
+	name := strings.TrimSpace(s.Name)
+	if existing, err := r.repo.GetCommandSetByName(name); err != nil {
+		return nil, err
+	} else if existing != nil {
+		return nil, fmt.Errorf("%w: %s", ErrExists, name)
+	}
+	id, err := r.repo.CreateCommandSet(s.Name, optional(s.Description), optional(s.AuthorName), optional(s.AuthorEmail), s.Commands)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range s.Tags {
+		if err := r.repo.AddTagToCommandSet(id, t); err != nil {
+			return nil, err
+		}
+	}
+	if s.Exclusive {
+		if err := r.repo.SetExclusive(name, true); err != nil {
+			return nil, err
+		}
+	}
+	return r.Get(name)
+}
+
+// UpdateOptions change a set; nil fields are kept.
+type UpdateOptions struct {
+	Name        *string
+	Description *string
+	AuthorName  *string
+	AuthorEmail *string
+	Tags        *[]string
+	Commands    *[]string
+	Exclusive   *bool
+}
+
+// Update applies opts to the named set, recording one version, and returns
+// the updated set.
+func (r *Registry) Update(name string, opts UpdateOptions) (*Set, error) {
+	cs, err := r.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	cur := setOf(cs)
+	newName, desc, author, email, tags, cmds := cur.Name, cur.Description, cur.AuthorName, cur.AuthorEmail, cur.Tags, cur.Commands
+	if opts.Name != nil {
+		if newName = strings.TrimSpace(*opts.Name); newName == "" {
+			return nil, errors.New("invalid name: name cannot be empty")
+		}
+	}
+	if opts.Description != nil {
+		desc = *opts.Description
+	}
+	if opts.AuthorName != nil {
+		author = *opts.AuthorName
+	}
+	if opts.AuthorEmail != nil {
+		email = *opts.AuthorEmail
+	}
+	if opts.Tags != nil {
+		tags = *opts.Tags
+	}
+	if opts.Commands != nil {
+		cmds = *opts.Commands
+	}
+	if newName != cur.Name {
+		if existing, err := r.repo.GetCommandSetByName(newName); err != nil {
+			return nil, err
+		} else if existing != nil {
+			return nil, fmt.Errorf("%w: %s", ErrExists, newName)
+		}
+	}
+	if err := r.repo.UpdateCommandSetAndReplaceCommands(cs.ID, newName, optional(desc), optional(author), optional(email), tags, cmds); err != nil {
+		return nil, err
+	}
+	if opts.Exclusive != nil && *opts.Exclusive != cs.Exclusive {
+		if err := r.repo.SetExclusive(newName, *opts.Exclusive); err != nil {
+			return nil, err
+		}
+	}
+	return r.Get(newName)
+}
+
+// Delete removes the named set. Its history is kept as a "delete" version.
+func (r *Registry) Delete(name string) error {
+	if _, err := r.lookup(name); err != nil {
+		return err
+	}
+	return r.repo.DeleteCommandSet(name)
+}
+
+// AddTag tags the named set.
+func (r *Registry) AddTag(name, tag string) error {
+	cs, err := r.lookup(name)
+	if err != nil {
+		return err
+	}
+	return r.repo.AddTagToCommandSet(cs.ID, tag)
+}
+
+// RemoveTag removes a tag from the named set.
+func (r *Registry) RemoveTag(name, tag string) error {
+	cs, err := r.lookup(name)
+	if err != nil {
+		return err
+	}
+	return r.repo.RemoveTagFromCommandSet(cs.ID, tag)
+}
+
+// Tags returns the named set's tags.
+func (r *Registry) Tags(name string) ([]string, error) {
+	cs, err := r.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	return r.repo.ListTagsForCommandSet(cs.ID)
+}
+
+// Version is a recorded snapshot of a set.
+type Version struct {
+	Version     int
+	CreatedAt   string
+	Operation   string
+	AuthorName  string
+	AuthorEmail string
+	Description string
+	Commands    []string
+}
+
+// Versions returns the named set's history, newest first. History outlives
+// the set, so versions of a deleted set are still returned.
+func (r *Registry) Versions(name string) ([]Version, error) {
+	vs, err := r.repo.ListVersionsByName(name)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Version, 0, len(vs))
+	for _, v := range vs {
+		out = append(out, Version{
+			Version: v.Version, CreatedAt: v.CreatedAt, Operation: v.Operation, AuthorName: v.AuthorName.String,
+			AuthorEmail: v.AuthorEmail.String, Description: v.Description.String, Commands: v.Commands,
+		})
+	}
+	return out, nil
+}
+
+// Rollback restores the named set's commands to a recorded version,
+// recording a "rollback" version.
+func (r *Registry) Rollback(name string, version int) (*Set, error) {
+	if _, err := r.lookup(name); err != nil {
+		return nil, err
+	}
+	if err := r.repo.ApplyVersionByName(name, version); err != nil {
+		return nil, err
+	}
+	return r.Get(name)
+}
+
+// Export writes the named set to a standalone SQLite file at path, in the
+// format `krnr export set` produces and `krnr import set` reads.
+func (r *Registry) Export(name, path string) error {
+	if _, err := r.lookup(name); err != nil {
+		return err
+	}
+	return exporter.ExportCommandSet(r.db, name, path)
+}
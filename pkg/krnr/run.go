@@ -0,0 +1,223 @@
+package krnr
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/VoxDroid/krnr/internal/config"
+	"github.com/VoxDroid/krnr/internal/executor"
+	"github.com/VoxDroid/krnr/internal/lifecycle"
+	"github.com/VoxDroid/krnr/internal/runlog"
+	"github.com/VoxDroid/krnr/internal/runner"
+	"github.com/VoxDroid/krnr/internal/security"
+	"github.com/VoxDroid/krnr/internal/tui/adapters"
+)
+
+// ErrRefused is returned when a run is refused: a command fails the
+// safety checks that `krnr run` applies (RunOptions.Force skips them) or
+// the set is untrusted.
+var ErrRefused = runner.ErrRefused
+
+// ErrUntrusted is returned for a set imported from another machine whose
+// commands were not trusted with `krnr trust` since; Force does not skip it.
+var ErrUntrusted = runner.ErrUntrusted
+
+// ErrBusy is returned when an exclusive set is already running.
+var ErrBusy = runner.ErrBusy
+
+// Trigger is recorded in run history for runs started through this package.
+const Trigger = "sdk"
+
+// EventType names the kind of an Event.
+type EventType string
+
+// Event types, in the order a run produces them.
+const (
+	EventRunStarted   EventType = "run_started"
+	EventStepStarted  EventType = "step_started"
+	EventOutput       EventType = "output"
+	EventStepFinished EventType = "step_finished"
+	EventRunFinished  EventType = "run_finished"
+)
+
+// Event is one step of a run's progress; it carries the fields of a line of
+// `krnr run --output jsonl`.
+type Event struct {
+	Type  EventType
+	RunID string
+	// Seq numbers the run's events from 1.
+	Seq  int64
+	Time time.Time
+	// Set is filled for run events.
+	Set string
+	// Step is the 1-based step of step and output events.
+	Step int
+	// Steps is the number of steps, on run_started.
+	Steps int
+	// Command is the step's command with secret parameters redacted.
+	Command string
+	// Stream ("stdout" or "stderr") and Data are filled for output.
+	Stream string
+	Data   string
+	// ExitCode and Duration are filled for step_finished and run_finished.
+	ExitCode int
+	Duration time.Duration
+	// Err is the failure of a finished step or run.
+	Err error
+}
+
+func eventOf(ev adapters.RunEvent) Event {
+	return Event{
+		Type: EventType(ev.Type), RunID: ev.RunID, Seq: ev.Seq, Time: ev.Time, Set: ev.Set, Step: ev.Step,
+		Steps: ev.Steps, Command: ev.Command, Stream: ev.Stream, Data: ev.Line, ExitCode: ev.ExitCode,
+		Duration: ev.Duration, Err: ev.Err,
+	}
+}
+
+// RunOptions configure Run.
+type RunOptions struct {
+	// Params supply every parameter the set references; runs never prompt.
+	Params map[string]string
+	// OnEvent, when set, receives the run's events in order. It is called
+	// from the running goroutine and should return quickly.
+	OnEvent func(Event)
+	// Stdout and Stderr, when set, also receive the commands' output.
+	Stdout, Stderr io.Writer
+	// Stdin is given to every command; nil means no input.
+	Stdin io.Reader
+	// Dir is the working directory; empty means the current one.
+	Dir string
+	// Shell is the shell commands run in (e.g. "pwsh", "bash"); empty means
+	// the `shell` setting, then the platform default.
+	Shell string
+	// Timeout bounds how long the commands may take in total; 0 means the
+	// `timeout` setting (30s by default) and a negative value no limit.
+	Timeout time.Duration
+	// Env holds extra KEY=VALUE variables for the commands.
+	Env []string
+	// DryRun prints commands instead of running them and records nothing.
+	DryRun bool
+	// Force skips the security policy (KRNR_HOME/policy.toml), which
+	// otherwise refuses commands it blocks or needs confirmation for.
+	Force bool
+	// NoHooks does not fire the lifecycle hooks of KRNR_HOME/hooks.toml.
+	NoHooks bool
+	// NoLog does not keep the run's output under KRNR_HOME/logs.
+	NoLog bool
+}
+
+// RunResult describes a finished run.
+type RunResult struct {
+	RunID    string
+	ExitCode int
+	Duration time.Duration
+}
+
+// Run runs the named set's commands in order, stopping at the first that
+// fails, like `krnr run`: imported sets must be trusted, the policy is
+// applied, exclusive sets are leased, the lifecycle hooks fire and the run
+// is logged (pruned to the log_retention and log_keep settings) and
+// recorded in history with trigger "sdk". The shell and timeout default to
+// the settings of the user and project config, as for `krnr run`. The
+// returned error is the failing step's; the result is filled whenever the
+// run started. Cancelling ctx stops the run.
+func (r *Registry) Run(ctx context.Context, name string, opts RunOptions) (*RunResult, error) {
+	s, err := r.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ResolveParams(s.Commands, opts.Params); err != nil {
+		return nil, err
+	}
+	settings, err := config.LoadSettings()
+	if err != nil {
+		return nil, err
+	}
+	if opts.Shell == "" {
+		opts.Shell = settings.Shell
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = settings.Timeout
+	}
+	ro := runner.Options{Params: map[string]string{}, Force: opts.Force, DryRun: opts.DryRun}
+	for k, v := range opts.Params {
+		ro.Params[k] = v
+	}
+	if !opts.DryRun {
+		ro.Trigger = Trigger
+	}
+	if !opts.Force {
+		if ro.Policy, err = security.LoadPolicy(); err != nil {
+			return nil, err
+		}
+	}
+	if !opts.NoHooks && !opts.DryRun {
+		if ro.Hooks, err = r.hooks(opts); err != nil {
+			return nil, err
+		}
+	}
+	run, err := runner.Prepare(r.repo, s.Name, ro)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := run.Commands(); err != nil {
+		return nil, err
+	}
+	if err := run.Start(ctx); err != nil {
+		return nil, err
+	}
+
+	var lw *runlog.Writer
+	if !opts.DryRun && !opts.NoLog {
+		// the log is best effort; it never prevents the run
+		lw, _ = runlog.CreateWithID(s.Name, run.ID)
+	}
+	em := adapters.NewEventEmitter(run.ID)
+	if opts.OnEvent != nil {
+		em.AddSink(func(ev adapters.RunEvent) { opts.OnEvent(eventOf(ev)) })
+	}
+	eo := runner.ExecOptions{
+		Runner: &executor.Executor{DryRun: opts.DryRun, Shell: opts.Shell, Env: opts.Env},
+		Events: em, Dir: opts.Dir, Stdin: opts.Stdin, Log: lw, Timeout: max(opts.Timeout, 0),
+		Writers: func(step int) (io.Writer, io.Writer) {
+			stdout := []io.Writer{em.OutputWriter(step, adapters.StreamStdout)}
+			stderr := []io.Writer{em.OutputWriter(step, adapters.StreamStderr)}
+			if opts.Stdout != nil {
+				stdout = append(stdout, opts.Stdout)
+			}
+			if opts.Stderr != nil {
+				stderr = append(stderr, opts.Stderr)
+			}
+			if lw != nil {
+				stdout, stderr = append(stdout, lw), append(stderr, lw)
+			}
+			return io.MultiWriter(stdout...), io.MultiWriter(stderr...)
+		},
+	}
+	runErr := run.Exec(ctx, eo)
+	if lw != nil {
+		_ = lw.Close(runErr)
+		_, _ = runlog.Prune(s.Name, runlog.Configured(settings))
+	}
+	return &RunResult{RunID: run.ID, ExitCode: runner.ExitCode(runErr), Duration: run.Elapsed()}, runErr
+}
+
+// hooks loads the lifecycle hooks for a run with opts. Set hooks run
+// through Run without firing hooks themselves.
+func (r *Registry) hooks(opts RunOptions) (*lifecycle.Hooks, error) {
+	h, err := lifecycle.Load()
+	if err != nil {
+		return nil, err
+	}
+	h.Shell = opts.Shell
+	h.Out = opts.Stderr
+	if h.Out == nil {
+		h.Out = io.Discard
+	}
+	h.RunSet = func(ctx context.Context, name string, params map[string]string, env []string, stdin io.Reader, out io.Writer) error {
+		_, err := r.Run(ctx, name, RunOptions{Params: params, Env: env, Stdin: stdin, Stdout: out, Stderr: out, Shell: opts.Shell, NoHooks: true})
+		return err
+	}
+	return h, nil
+}